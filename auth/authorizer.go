@@ -129,6 +129,8 @@ func (a *Authorizer) acquireRateLimitPermit(req *common.NormalizedRequest) error
 					string(a.cfg.Type),
 					a.cfg.RateLimitBudget,
 					fmt.Sprintf("%+v", rule.Config),
+					rule.Config.MaxCount,
+					rule.ResetIn(),
 				)
 			} else {
 				lg.Debug().Object("rateLimitRule", rule.Config).Msgf("auth-level rate limit passed")
@@ -22,8 +22,11 @@ func main() {
 
 	logger.Info().Msgf("starting eRPC version: %s, commit: %s", version, commitSHA)
 
-	err := erpc.Init(
-		context.Background(),
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	shutdown, err := erpc.Init(
+		ctx,
 		logger,
 		afero.NewOsFs(),
 		os.Args,
@@ -37,4 +40,9 @@ func main() {
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 	recvSig := <-sig
 	logger.Warn().Msgf("caught signal: %v", recvSig)
+
+	// Stop serving first so that in-flight requests complete, shutdown returns once the cache was closed
+	// (e.g. persisted to disk) as well, then stop the rest of background components (e.g. pollers)
+	shutdown()
+	cancel()
 }
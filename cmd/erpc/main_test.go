@@ -197,7 +197,7 @@ projects:
 	args := []string{"erpc-test", cfg.Name()}
 
 	logger := log.With().Logger()
-	err = erpc.Init(context.Background(), logger, fs, args)
+	_, err = erpc.Init(context.Background(), logger, fs, args)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -257,7 +257,7 @@ func TestInit_InvalidConfig(t *testing.T) {
 	args := []string{"erpc-test", cfg.Name()}
 
 	logger := log.With().Logger()
-	err = erpc.Init(context.Background(), logger, fs, args)
+	_, err = erpc.Init(context.Background(), logger, fs, args)
 	if err == nil {
 		t.Fatal("expected an error, got nil")
 	}
@@ -275,7 +275,7 @@ func TestInit_ConfigFileDoesNotExist(t *testing.T) {
 	args := []string{"erpc-test", "non-existent-file.yaml"}
 
 	logger := log.With().Logger()
-	err := erpc.Init(context.Background(), logger, fs, args)
+	_, err := erpc.Init(context.Background(), logger, fs, args)
 
 	if err == nil {
 		t.Fatal("expected an error, got nil")
@@ -0,0 +1,338 @@
+package common
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// CacheEntry is a cached response along with what is needed to invalidate it or tell its age.
+type CacheEntry struct {
+	// Raw json result of the response
+	Result []byte
+	// Block the result belongs to (e.g. block hash or number), used to invalidate entries of reorged blocks
+	BlockRef string
+	CachedAt time.Time
+	// Id of the upstream that served the response
+	Upstream string
+}
+
+// CacheEntryCodec serializes cache entries so that all drivers store them the same way.
+type CacheEntryCodec interface {
+	Name() string
+	Encode(entry *CacheEntry) ([]byte, error)
+	Decode(data []byte) (*CacheEntry, error)
+}
+
+// GetCacheEntryCodec returns the codec by name: "json" (default), "msgpack" or "gob".
+func GetCacheEntryCodec(name string) (CacheEntryCodec, error) {
+	switch name {
+	case "", "json":
+		return jsonCacheEntryCodec{}, nil
+	case "msgpack":
+		return msgpackCacheEntryCodec{}, nil
+	case "gob":
+		return gobCacheEntryCodec{}, nil
+	}
+	return nil, NewErrInvalidConfig(fmt.Sprintf("unknown cache entry codec: %s (must be json, msgpack or gob)", name))
+}
+
+type jsonCacheEntry struct {
+	Result   json.RawMessage `json:"result"`
+	BlockRef string          `json:"blockRef,omitempty"`
+	// Unix milliseconds, zero when unknown
+	CachedAt int64  `json:"cachedAt,omitempty"`
+	Upstream string `json:"upstream,omitempty"`
+}
+
+// jsonCacheEntryCodec keeps the result as-is within the entry, which keeps entries readable in the underlying store.
+type jsonCacheEntryCodec struct{}
+
+func (jsonCacheEntryCodec) Name() string { return "json" }
+
+func (jsonCacheEntryCodec) Encode(entry *CacheEntry) ([]byte, error) {
+	je := jsonCacheEntry{
+		Result:   entry.Result,
+		BlockRef: entry.BlockRef,
+		CachedAt: cacheEntryMillis(entry.CachedAt),
+		Upstream: entry.Upstream,
+	}
+	if len(je.Result) == 0 {
+		je.Result = json.RawMessage("null")
+	}
+	return json.Marshal(je)
+}
+
+func (jsonCacheEntryCodec) Decode(data []byte) (*CacheEntry, error) {
+	var je jsonCacheEntry
+	if err := json.Unmarshal(data, &je); err != nil {
+		return nil, err
+	}
+	return &CacheEntry{
+		Result:   je.Result,
+		BlockRef: je.BlockRef,
+		CachedAt: cacheEntryTime(je.CachedAt),
+		Upstream: je.Upstream,
+	}, nil
+}
+
+// msgpackCacheEntryCodec encodes entries as a msgpack map of "result" (bin), "blockRef", "upstream" (str)
+// and "cachedAt" (unix milliseconds), unknown keys are skipped when decoding.
+type msgpackCacheEntryCodec struct{}
+
+func (msgpackCacheEntryCodec) Name() string { return "msgpack" }
+
+func (msgpackCacheEntryCodec) Encode(entry *CacheEntry) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, len(entry.Result)+64))
+	writeMsgpackHeader(buf, 4, 0x80, 0xde, 0xdf)
+	writeMsgpackString(buf, "result")
+	writeMsgpackBin(buf, entry.Result)
+	writeMsgpackString(buf, "blockRef")
+	writeMsgpackString(buf, entry.BlockRef)
+	writeMsgpackString(buf, "cachedAt")
+	buf.WriteByte(0xd3)
+	buf.Write(binary.BigEndian.AppendUint64(nil, uint64(cacheEntryMillis(entry.CachedAt))))
+	writeMsgpackString(buf, "upstream")
+	writeMsgpackString(buf, entry.Upstream)
+	return buf.Bytes(), nil
+}
+
+func (msgpackCacheEntryCodec) Decode(data []byte) (*CacheEntry, error) {
+	r := &msgpackReader{data: data}
+	n, err := r.mapHeader()
+	if err != nil {
+		return nil, err
+	}
+	entry := &CacheEntry{}
+	for i := 0; i < n; i++ {
+		key, err := r.str()
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "result":
+			entry.Result, err = r.bin()
+		case "blockRef":
+			entry.BlockRef, err = r.str()
+		case "upstream":
+			entry.Upstream, err = r.str()
+		case "cachedAt":
+			var ms int64
+			ms, err = r.int()
+			entry.CachedAt = cacheEntryTime(ms)
+		default:
+			err = r.skip()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid msgpack cache entry field %s: %w", key, err)
+		}
+	}
+	return entry, nil
+}
+
+// gobCacheEntryCodec is the most compact option for Go-only consumers of the store.
+type gobCacheEntryCodec struct{}
+
+func (gobCacheEntryCodec) Name() string { return "gob" }
+
+func (gobCacheEntryCodec) Encode(entry *CacheEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entry); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCacheEntryCodec) Decode(data []byte) (*CacheEntry, error) {
+	entry := &CacheEntry{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func cacheEntryMillis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func cacheEntryTime(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+func writeMsgpackBin(buf *bytes.Buffer, b []byte) {
+	switch l := len(b); {
+	case l <= math.MaxUint8:
+		buf.WriteByte(0xc4)
+		buf.WriteByte(byte(l))
+	case l <= math.MaxUint16:
+		buf.WriteByte(0xc5)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(l)))
+	default:
+		buf.WriteByte(0xc6)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(l)))
+	}
+	buf.Write(b)
+}
+
+var errMsgpackTruncated = errors.New("truncated msgpack data")
+
+// msgpackReader decodes the subset of msgpack written by cache entry codecs.
+type msgpackReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *msgpackReader) next(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, errMsgpackTruncated
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *msgpackReader) byte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *msgpackReader) length(size int) (int, error) {
+	b, err := r.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return int(b[0]), nil
+	case 2:
+		return int(binary.BigEndian.Uint16(b)), nil
+	default:
+		return int(binary.BigEndian.Uint32(b)), nil
+	}
+}
+
+func (r *msgpackReader) mapHeader() (int, error) {
+	t, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case t&0xf0 == 0x80:
+		return int(t & 0x0f), nil
+	case t == 0xde:
+		return r.length(2)
+	case t == 0xdf:
+		return r.length(4)
+	}
+	return 0, fmt.Errorf("expected msgpack map, got 0x%02x", t)
+}
+
+func (r *msgpackReader) str() (string, error) {
+	t, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	var l int
+	switch {
+	case t&0xe0 == 0xa0:
+		l = int(t & 0x1f)
+	case t == 0xd9:
+		l, err = r.length(1)
+	case t == 0xda:
+		l, err = r.length(2)
+	case t == 0xdb:
+		l, err = r.length(4)
+	default:
+		return "", fmt.Errorf("expected msgpack string, got 0x%02x", t)
+	}
+	if err != nil {
+		return "", err
+	}
+	b, err := r.next(l)
+	return string(b), err
+}
+
+func (r *msgpackReader) bin() ([]byte, error) {
+	t, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	var l int
+	switch t {
+	case 0xc0:
+		return nil, nil
+	case 0xc4:
+		l, err = r.length(1)
+	case 0xc5:
+		l, err = r.length(2)
+	case 0xc6:
+		l, err = r.length(4)
+	default:
+		return nil, fmt.Errorf("expected msgpack bin, got 0x%02x", t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.next(l)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), b...), nil
+}
+
+func (r *msgpackReader) int() (int64, error) {
+	t, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case t <= 0x7f:
+		return int64(t), nil
+	case t >= 0xe0:
+		return int64(int8(t)), nil
+	case t == 0xd3:
+		b, err := r.next(8)
+		if err != nil {
+			return 0, err
+		}
+		return int64(binary.BigEndian.Uint64(b)), nil
+	}
+	return 0, fmt.Errorf("expected msgpack integer, got 0x%02x", t)
+}
+
+// skip steps over a value of an unknown key, only the types cache entry codecs write are supported.
+func (r *msgpackReader) skip() error {
+	if r.pos >= len(r.data) {
+		return errMsgpackTruncated
+	}
+	switch t := r.data[r.pos]; {
+	case t&0xe0 == 0xa0 || t == 0xd9 || t == 0xda || t == 0xdb:
+		_, err := r.str()
+		return err
+	case t == 0xc0 || t == 0xc4 || t == 0xc5 || t == 0xc6:
+		_, err := r.bin()
+		return err
+	case t <= 0x7f || t >= 0xe0 || t == 0xd3:
+		_, err := r.int()
+		return err
+	case t == 0xc2 || t == 0xc3:
+		r.pos++
+		return nil
+	default:
+		return fmt.Errorf("unsupported msgpack type 0x%02x", t)
+	}
+}
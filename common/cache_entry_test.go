@@ -0,0 +1,81 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheEntryCodec(t *testing.T) {
+	entries := map[string]*CacheEntry{
+		"Full": {
+			Result:   []byte(`{"hash":"0xabc","number":"0x10","transactions":[]}`),
+			BlockRef: "0xabc",
+			CachedAt: time.UnixMilli(1718000000123),
+			Upstream: "alchemy",
+		},
+		"Empty": {},
+		"LargeResult": {
+			Result:   []byte(`"0x` + strings.Repeat("ab", 70000) + `"`),
+			BlockRef: "16",
+			CachedAt: time.UnixMilli(1718000000123),
+			Upstream: strings.Repeat("u", 300),
+		},
+	}
+
+	for _, name := range []string{"json", "msgpack", "gob"} {
+		codec, err := GetCacheEntryCodec(name)
+		assert.NoError(t, err)
+		assert.Equal(t, name, codec.Name())
+
+		for entryName, entry := range entries {
+			t.Run(name+"_RoundTrip"+entryName, func(t *testing.T) {
+				data, err := codec.Encode(entry)
+				assert.NoError(t, err)
+
+				decoded, err := codec.Decode(data)
+				assert.NoError(t, err)
+				assert.Equal(t, entry.BlockRef, decoded.BlockRef)
+				assert.Equal(t, entry.Upstream, decoded.Upstream)
+				assert.True(t, entry.CachedAt.Equal(decoded.CachedAt), "%v != %v", entry.CachedAt, decoded.CachedAt)
+				if len(entry.Result) > 0 {
+					assert.Equal(t, string(entry.Result), string(decoded.Result))
+				}
+			})
+		}
+
+		t.Run(name+"_RejectsGarbage", func(t *testing.T) {
+			_, err := codec.Decode([]byte{0x01, 0x02})
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("JsonKeepsResultReadable", func(t *testing.T) {
+		codec, _ := GetCacheEntryCodec("json")
+		data, err := codec.Encode(entries["Full"])
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"result":{"hash":"0xabc"`)
+	})
+
+	t.Run("MsgpackSkipsUnknownKeys", func(t *testing.T) {
+		// {"v":1,"upstream":"a","extra":"x"}
+		data := []byte{0x83, 0xa1, 'v', 0x01, 0xa8, 'u', 'p', 's', 't', 'r', 'e', 'a', 'm', 0xa1, 'a', 0xa5, 'e', 'x', 't', 'r', 'a', 0xa1, 'x'}
+		codec, _ := GetCacheEntryCodec("msgpack")
+		decoded, err := codec.Decode(data)
+		assert.NoError(t, err)
+		assert.Equal(t, "a", decoded.Upstream)
+	})
+
+	t.Run("DefaultsToJson", func(t *testing.T) {
+		codec, err := GetCacheEntryCodec("")
+		assert.NoError(t, err)
+		assert.Equal(t, "json", codec.Name())
+	})
+
+	t.Run("UnknownCodecIsRejected", func(t *testing.T) {
+		_, err := GetCacheEntryCodec("protobuf")
+		assert.True(t, HasErrorCode(err, ErrCodeInvalidConfig))
+	})
+}
@@ -22,6 +22,14 @@ type Config struct {
 	RateLimiters *RateLimiterConfig `yaml:"rateLimiters" json:"rateLimiters"`
 	Metrics      *MetricsConfig     `yaml:"metrics" json:"metrics"`
 	Admin        *AdminConfig       `yaml:"admin" json:"admin"`
+	Tracing      *TracingConfig     `yaml:"tracing" json:"tracing"`
+
+	// Honors faultInjection of upstreams, must only be enabled in non-production environments (e.g. staging)
+	AllowFaultInjection bool `yaml:"allowFaultInjection" json:"allowFaultInjection"`
+
+	// How ids of requests originated by eRPC itself (state polling, health checks, probes) are generated,
+	// "counter" (default) or "uuid". They are always prefixed with "erpc-" so they never collide with client ids.
+	InternalRequestIds InternalRequestIdStrategy `yaml:"internalRequestIds" json:"internalRequestIds"`
 }
 
 type ServerConfig struct {
@@ -31,6 +39,43 @@ type ServerConfig struct {
 	HttpHostV6 string `yaml:"httpHostV6" json:"httpHostV6"`
 	HttpPort   int    `yaml:"httpPort" json:"httpPort"`
 	MaxTimeout string `yaml:"maxTimeout" json:"maxTimeout"`
+
+	// Per-method overrides of MaxTimeout, keys can use wildcards (e.g. "trace_*": "60s"). Exact method names win
+	// over patterns and longer patterns over shorter ones. Network and upstream failsafe timeouts shorter than an
+	// override are extended for the matching requests.
+	MethodTimeouts map[string]string `yaml:"methodTimeouts" json:"methodTimeouts"`
+
+	// Upper bound for per-request timeouts sent by clients via X-ERPC-Timeout header, the header is ignored when empty
+	MaxTimeoutOverride string `yaml:"maxTimeoutOverride" json:"maxTimeoutOverride"`
+
+	// Reject POST requests whose Content-Type is not application/json
+	RequireJsonContentType bool `yaml:"requireJsonContentType" json:"requireJsonContentType"`
+
+	// Reject requests containing duplicate object keys (e.g. {"to":"a","to":"b"}) instead of silently using one of them
+	StrictJsonParsing bool `yaml:"strictJsonParsing" json:"strictJsonParsing"`
+
+	// Extra request paths mapped to a project and network, e.g. "/v1/{project}/{network}" for /v1/main/evm:1.
+	// Placeholders are {project}, {network} or {architecture} and {chainId}, templates are tried in order
+	// before the default /<project>/<architecture>/<chainId> paths.
+	PathTemplates []string `yaml:"pathTemplates" json:"pathTemplates"`
+
+	// Return the upstreams tried for a request in X-ERPC-Trace response header when the client sends "X-ERPC-Trace: true",
+	// disabled by default since it exposes upstream ids and errors
+	TraceHeader bool `yaml:"traceHeader" json:"traceHeader"`
+
+	ResponseCompression *ResponseCompressionConfig `yaml:"responseCompression" json:"responseCompression"`
+
+	// How long in-flight requests may take to complete once shutdown starts (e.g. on SIGTERM), defaults to 30s.
+	// Meanwhile the health check reports the server as unready and new requests are rejected.
+	ShutdownGracePeriod string `yaml:"shutdownGracePeriod" json:"shutdownGracePeriod"`
+}
+
+// ResponseCompressionConfig controls gzip compression of responses for clients sending "Accept-Encoding: gzip".
+type ResponseCompressionConfig struct {
+	// Defaults to true
+	Enabled *bool `yaml:"enabled" json:"enabled"`
+	// Responses smaller than this many bytes are sent as-is, defaults to 1024
+	MinSize int `yaml:"minSize" json:"minSize"`
 }
 
 type AdminConfig struct {
@@ -42,16 +87,30 @@ type DatabaseConfig struct {
 }
 
 type ConnectorConfig struct {
-	Driver     string                     `yaml:"driver" json:"driver"`
-	Memory     *MemoryConnectorConfig     `yaml:"memory" json:"memory"`
-	Redis      *RedisConnectorConfig      `yaml:"redis" json:"redis"`
-	DynamoDB   *DynamoDBConnectorConfig   `yaml:"dynamodb" json:"dynamodb"`
-	PostgreSQL *PostgreSQLConnectorConfig `yaml:"postgresql" json:"postgresql"`
-	Methods    []*MethodCacheConfig       `yaml:"methods" json:"methods"`
+	Driver      string                     `yaml:"driver" json:"driver"`
+	Memory      *MemoryConnectorConfig     `yaml:"memory" json:"memory"`
+	Redis       *RedisConnectorConfig      `yaml:"redis" json:"redis"`
+	DynamoDB    *DynamoDBConnectorConfig   `yaml:"dynamodb" json:"dynamodb"`
+	PostgreSQL  *PostgreSQLConnectorConfig `yaml:"postgresql" json:"postgresql"`
+	Methods     []*MethodCacheConfig       `yaml:"methods" json:"methods"`
+	Compression *CompressionConfig         `yaml:"compression" json:"compression"`
+	// When set to "json", "msgpack" or "gob", entries are stored as a CacheEntry carrying the block ref
+	// and origin upstream along with the result, instead of the result alone.
+	EntryCodec string `yaml:"entryCodec" json:"entryCodec"`
+}
+
+type CompressionConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Only "gzip" is supported at the moment
+	Algorithm string `yaml:"algorithm" json:"algorithm"`
+	// Results smaller than this many bytes are stored uncompressed
+	Threshold int `yaml:"threshold" json:"threshold"`
 }
 
 type MemoryConnectorConfig struct {
 	MaxItems int `yaml:"maxItems" json:"maxItems"`
+	// When set, entries are written to this file on shutdown and loaded back on startup.
+	PersistPath string `yaml:"persistPath" json:"persistPath"`
 }
 
 type TLSConfig struct {
@@ -60,6 +119,28 @@ type TLSConfig struct {
 	KeyFile            string `yaml:"keyFile" json:"keyFile"`
 	CAFile             string `yaml:"caFile" json:"caFile"`
 	InsecureSkipVerify bool   `yaml:"insecureSkipVerify" json:"insecureSkipVerify"`
+
+	// PEM encoded alternatives to the file paths above, any of these values (and the file paths)
+	// can be read from an environment variable by using "env:VARIABLE_NAME" as the value.
+	CertPem string `yaml:"certPem" json:"certPem"`
+	KeyPem  string `yaml:"keyPem" json:"keyPem"`
+	CAPem   string `yaml:"caPem" json:"caPem"`
+}
+
+// redact private key
+func (t *TLSConfig) MarshalJSON() ([]byte, error) {
+	type Alias TLSConfig
+	keyPem := ""
+	if t.KeyPem != "" {
+		keyPem = "REDACTED"
+	}
+	return sonic.Marshal(&struct {
+		KeyPem string `json:"keyPem"`
+		*Alias
+	}{
+		KeyPem: keyPem,
+		Alias:  (*Alias)(t),
+	})
 }
 
 type RedisConnectorConfig struct {
@@ -105,11 +186,13 @@ func (p *PostgreSQLConnectorConfig) MarshalJSON() ([]byte, error) {
 }
 
 type AwsAuthConfig struct {
-	Mode            string `yaml:"mode" json:"mode"` // "file", "env", "secret"
+	Mode            string `yaml:"mode" json:"mode"` // "file", "env", "secret", "role"
 	CredentialsFile string `yaml:"credentialsFile" json:"credentialsFile"`
 	Profile         string `yaml:"profile" json:"profile"`
 	AccessKeyID     string `yaml:"accessKeyID" json:"accessKeyID"`
 	SecretAccessKey string `yaml:"secretAccessKey" json:"secretAccessKey"`
+	// Role to assume via STS in "role" mode, when empty the default chain (e.g. instance or task role) is used
+	RoleArn string `yaml:"roleArn" json:"roleArn"`
 }
 
 func (a *AwsAuthConfig) MarshalJSON() ([]byte, error) {
@@ -119,18 +202,44 @@ func (a *AwsAuthConfig) MarshalJSON() ([]byte, error) {
 		"profile":         a.Profile,
 		"accessKeyID":     a.AccessKeyID,
 		"secretAccessKey": "REDACTED",
+		"roleArn":         a.RoleArn,
 	})
 }
 
 type ProjectConfig struct {
-	Id              string             `yaml:"id" json:"id"`
-	Admin           *AdminConfig       `yaml:"admin" json:"admin"`
-	Auth            *AuthConfig        `yaml:"auth" json:"auth"`
-	CORS            *CORSConfig        `yaml:"cors" json:"cors"`
-	Upstreams       []*UpstreamConfig  `yaml:"upstreams" json:"upstreams"`
-	Networks        []*NetworkConfig   `yaml:"networks" json:"networks"`
-	RateLimitBudget string             `yaml:"rateLimitBudget" json:"rateLimitBudget"`
-	HealthCheck     *HealthCheckConfig `yaml:"healthCheck" json:"healthCheck"`
+	Id              string               `yaml:"id" json:"id"`
+	Admin           *AdminConfig         `yaml:"admin" json:"admin"`
+	Auth            *AuthConfig          `yaml:"auth" json:"auth"`
+	CORS            *CORSConfig          `yaml:"cors" json:"cors"`
+	Upstreams       []*UpstreamConfig    `yaml:"upstreams" json:"upstreams"`
+	Networks        []*NetworkConfig     `yaml:"networks" json:"networks"`
+	RateLimitBudget string               `yaml:"rateLimitBudget" json:"rateLimitBudget"`
+	HealthCheck     *HealthCheckConfig   `yaml:"healthCheck" json:"healthCheck"`
+	CachePolicies   []*CachePolicyConfig `yaml:"cachePolicies" json:"cachePolicies"`
+	// Region this eRPC instance runs in (e.g. "${ERPC_REGION}"), upstreams of the same region are tried before others
+	LocalRegion string `yaml:"localRegion" json:"localRegion"`
+	// Sets of upstreams sharing a rate limit budget, referenced by the "group" of upstreams
+	UpstreamGroups []*UpstreamGroupConfig `yaml:"upstreamGroups" json:"upstreamGroups"`
+}
+
+// UpstreamGroupConfig makes upstreams share one rate limit budget, e.g. several endpoints of the same provider
+// account, members check the group budget on top of their own rateLimitBudget.
+type UpstreamGroupConfig struct {
+	Id                string                   `yaml:"id" json:"id"`
+	RateLimitBudget   string                   `yaml:"rateLimitBudget" json:"rateLimitBudget"`
+	RateLimitAutoTune *RateLimitAutoTuneConfig `yaml:"rateLimitAutoTune" json:"rateLimitAutoTune"`
+}
+
+// CachePolicyConfig overrides caching of matching methods for a project, the first matching policy wins.
+type CachePolicyConfig struct {
+	// Method name or pattern (e.g. "eth_getLogs", "trace_*")
+	Method string `yaml:"method" json:"method"`
+	// Set to false to never read or write cache for matching methods (defaults to true)
+	Enabled *bool `yaml:"enabled" json:"enabled"`
+	// Treats entries older than this as missing and expires them in the cache database
+	TTL string `yaml:"ttl" json:"ttl"`
+	// Caches responses even if their block is not finalized (or unknown), requires a ttl
+	AllowUnfinalized bool `yaml:"allowUnfinalized" json:"allowUnfinalized"`
 }
 
 type CORSConfig struct {
@@ -155,6 +264,72 @@ type UpstreamConfig struct {
 	Failsafe                     *FailsafeConfig          `yaml:"failsafe" json:"failsafe"`
 	RateLimitBudget              string                   `yaml:"rateLimitBudget" json:"rateLimitBudget"`
 	RateLimitAutoTune            *RateLimitAutoTuneConfig `yaml:"rateLimitAutoTune" json:"rateLimitAutoTune"`
+	TLS                          *TLSConfig               `yaml:"tls" json:"tls"`
+	Concurrency                  *ConcurrencyConfig       `yaml:"concurrency" json:"concurrency"`
+	Shadow                       *ShadowConfig            `yaml:"shadow" json:"shadow"`
+	TrustWeight                  float64                  `yaml:"trustWeight" json:"trustWeight"` // consensus vote weight, defaults to 1
+	CapabilityProbe              *CapabilityProbeConfig   `yaml:"capabilityProbe" json:"capabilityProbe"`
+	CostWeights                  map[string]float64       `yaml:"costWeights" json:"costWeights"` // method (or wildcard) -> billing cost per request, defaults to 1
+	DebugBundle                  *DebugBundleConfig       `yaml:"debugBundle" json:"debugBundle"`
+	CaptureSample                *CaptureSampleConfig     `yaml:"captureSample" json:"captureSample"`
+	FaultInjection               *FaultInjectionConfig    `yaml:"faultInjection" json:"faultInjection"`
+	Region                       string                   `yaml:"region" json:"region"` // e.g. "us-east", preferred when it is the project's localRegion
+	Group                        string                   `yaml:"group" json:"group"`   // id of one of the project's upstreamGroups
+}
+
+// FaultInjectionConfig makes an upstream misbehave on purpose to validate failover config (chaos testing).
+// It is ignored unless the root "allowFaultInjection" flag is enabled.
+type FaultInjectionConfig struct {
+	// Latency added before each request is sent (e.g. "200ms")
+	Latency string `yaml:"latency" json:"latency"`
+	// Fraction of requests between 0 and 1 failing with a server-side error without reaching the upstream
+	ErrorRate float64 `yaml:"errorRate" json:"errorRate"`
+	// Fraction of requests between 0 and 1 failing as if the connection was dropped
+	DropRate float64 `yaml:"dropRate" json:"dropRate"`
+}
+
+// DebugBundleConfig captures the raw exchange with an upstream (request, response, headers, status and timing)
+// once a method fails a number of times in a row, so it can be attached to provider support tickets.
+// Credentials in url and headers are redacted before a bundle is logged or kept.
+type DebugBundleConfig struct {
+	// Consecutive failures of the same method before a bundle is captured, defaults to 3
+	FailureThreshold int `yaml:"failureThreshold" json:"failureThreshold"`
+	// How many of the latest bundles are kept in memory, defaults to 10
+	MaxBundles int `yaml:"maxBundles" json:"maxBundles"`
+}
+
+// CaptureSampleConfig logs a random share of full exchanges with an upstream (request, response, headers, status
+// and timing) at info level, to debug production issues without the cost of logging everything.
+// Credentials in url and headers are redacted the same way as for debug bundles.
+type CaptureSampleConfig struct {
+	// Fraction of requests between 0 and 1 whose exchange is logged, e.g. 0.001 for 0.1%
+	Rate float64 `yaml:"rate" json:"rate"`
+}
+
+// CapabilityProbeConfig periodically probes which optional capabilities (trace, debug, archive, blockReceipts) an upstream
+// supports, results are kept for the ttl and used to avoid routing requests to upstreams lacking them.
+type CapabilityProbeConfig struct {
+	// How long a probed result is trusted before it is probed again, defaults to 1h
+	Ttl string `yaml:"ttl" json:"ttl"`
+	// Capabilities to probe, defaults to all of "trace", "debug", "archive" and "blockReceipts"
+	Capabilities []string `yaml:"capabilities" json:"capabilities"`
+}
+
+// ShadowConfig samples successful responses of an upstream and re-sends the same request to a trusted
+// reference upstream, mismatches are only logged and counted so served responses are never affected.
+type ShadowConfig struct {
+	// Fraction of successful responses to compare, between 0 and 1 (e.g. 0.01 for 1%)
+	SampleRate float64 `yaml:"sampleRate" json:"sampleRate"`
+	// Id of the upstream whose responses are considered correct
+	ReferenceUpstream string `yaml:"referenceUpstream" json:"referenceUpstream"`
+}
+
+// ConcurrencyConfig caps in-flight requests towards an upstream, requests above the cap wait
+// in a queue where higher priority requests (see X-ERPC-Priority header) are dispatched first.
+type ConcurrencyConfig struct {
+	MaxConcurrent int `yaml:"maxConcurrent" json:"maxConcurrent"`
+	// Requests arriving when this many are already waiting are rejected, defaults to 1000
+	MaxQueueSize int `yaml:"maxQueueSize" json:"maxQueueSize"`
 }
 
 // redact Endpoint
@@ -183,6 +358,45 @@ type JsonRpcUpstreamConfig struct {
 	SupportsBatch *bool  `yaml:"supportsBatch" json:"supportsBatch"`
 	BatchMaxSize  int    `yaml:"batchMaxSize" json:"batchMaxSize"`
 	BatchMaxWait  string `yaml:"batchMaxWait" json:"batchMaxWait"`
+
+	// Number of connections to pre-establish towards the endpoint on startup, so that the first
+	// real requests do not pay for TCP/TLS handshakes. Disabled (0) by default.
+	WarmupConnections int `yaml:"warmupConnections" json:"warmupConnections"`
+
+	Transport *HttpTransportConfig `yaml:"transport" json:"transport"`
+
+	// Rewrites json-rpc error codes returned by the upstream to canonical ones (e.g. 3 -> -32000)
+	// before errors are classified, the original code is kept under "originalCode" of error data.
+	ErrorCodeRemap map[int]int `yaml:"errorCodeRemap" json:"errorCodeRemap"`
+
+	// Max size in bytes of a single json-rpc response body (multiplied by number of items for batches),
+	// reading larger responses is aborted once the limit is exceeded. Unlimited (0) by default.
+	MaxResponseSize int64 `yaml:"maxResponseSize" json:"maxResponseSize"`
+
+	// Signs every outbound request with AWS SigV4, e.g. for AWS Managed Blockchain endpoints
+	SigV4 *SigV4Config `yaml:"sigv4" json:"sigv4"`
+}
+
+// SigV4Config configures AWS Signature Version 4 signing of requests sent to an upstream.
+type SigV4Config struct {
+	Region string `yaml:"region" json:"region"`
+	// Service name the signature is scoped to (default "managedblockchain")
+	Service string         `yaml:"service" json:"service"`
+	Auth    *AwsAuthConfig `yaml:"auth" json:"auth"`
+}
+
+// HttpTransportConfig tunes the connection pool used towards an upstream, zero values keep the defaults.
+type HttpTransportConfig struct {
+	// Max idle connections kept in the pool (default 1024)
+	MaxIdleConns int `yaml:"maxIdleConns" json:"maxIdleConns"`
+	// Max idle connections kept per host (default 256)
+	MaxIdleConnsPerHost int `yaml:"maxIdleConnsPerHost" json:"maxIdleConnsPerHost"`
+	// Max connections per host including active ones, requests wait for a free connection when reached (default unlimited)
+	MaxConnsPerHost int `yaml:"maxConnsPerHost" json:"maxConnsPerHost"`
+	// How long an idle connection stays in the pool (default 90s)
+	IdleConnTimeout string `yaml:"idleConnTimeout" json:"idleConnTimeout"`
+	// Only use HTTP/1.1 towards the upstream, for providers misbehaving with HTTP/2
+	DisableHttp2 bool `yaml:"disableHttp2" json:"disableHttp2"`
 }
 
 type EvmUpstreamConfig struct {
@@ -192,11 +406,49 @@ type EvmUpstreamConfig struct {
 	GetLogsMaxBlockRange int         `yaml:"getLogsMaxBlockRange" json:"getLogsMaxBlockRange"`
 	StatePollerInterval  string      `yaml:"statePollerInterval" json:"statePollerInterval"`
 
+	// Split eth_getLogs requests over getLogsMaxBlockRange into sub-range queries sent to this upstream,
+	// disabled by default so that getLogsMaxBlockRange alone does not multiply requests
+	GetLogsSplitEnabled bool `yaml:"getLogsSplitEnabled" json:"getLogsSplitEnabled"`
+
+	// How many sub-range queries of a single eth_getLogs request (split over getLogsMaxBlockRange)
+	// run concurrently on this upstream, defaults to 2
+	GetLogsSplitConcurrency int `yaml:"getLogsSplitConcurrency" json:"getLogsSplitConcurrency"`
+
+	// Max number of sub-range queries a single eth_getLogs request is split into, wider ranges are
+	// rejected on this upstream instead of being split, defaults to 100
+	GetLogsSplitMaxSubRanges int `yaml:"getLogsSplitMaxSubRanges" json:"getLogsSplitMaxSubRanges"`
+
+	// Max number of logs a single eth_getLogs response may contain, larger results are aborted while
+	// streaming from the upstream and rejected as too broad a filter. Unlimited (0) by default.
+	GetLogsMaxResults int `yaml:"getLogsMaxResults" json:"getLogsMaxResults"`
+
 	// By default "Syncing" is marked as unknown (nil) and that means we will be retrying empty responses
 	// from such upstream, unless we explicitly know that the upstream is fully synced (false).
 	Syncing *bool `yaml:"syncing" json:"syncing"`
+
+	// When enabled, results of critical methods (e.g. eth_blockNumber, eth_getBlockByNumber) are checked
+	// against a lightweight schema and malformed responses are treated as retryable upstream errors.
+	ValidateResponses bool `yaml:"validateResponses" json:"validateResponses"`
+
+	// Marks the upstream as a read replica that may lag behind the head, its responses are only cached
+	// at finalized blocks and never under a latest/head key.
+	Replica bool `yaml:"replica" json:"replica"`
+	// How many blocks a replica may lag behind the network head, beyond it the replica is considered
+	// still syncing and none of its responses are cached (0 means lag is not checked)
+	ReplicaMaxLag int64 `yaml:"replicaMaxLag" json:"replicaMaxLag"`
+
+	// Rewrites the address param of account state methods (e.g. eth_getBalance, eth_getCode) before it is
+	// sent to this upstream, "lowercase" or "checksum" (EIP-55). Addresses are sent as received when empty.
+	AddressFormat EvmAddressFormat `yaml:"addressFormat" json:"addressFormat"`
 }
 
+type EvmAddressFormat string
+
+const (
+	EvmAddressFormatLowercase EvmAddressFormat = "lowercase"
+	EvmAddressFormatChecksum  EvmAddressFormat = "checksum"
+)
+
 type FailsafeConfig struct {
 	Retry          *RetryPolicyConfig          `yaml:"retry" json:"retry"`
 	CircuitBreaker *CircuitBreakerPolicyConfig `yaml:"circuitBreaker" json:"circuitBreaker"`
@@ -218,6 +470,9 @@ type CircuitBreakerPolicyConfig struct {
 	HalfOpenAfter            string `yaml:"halfOpenAfter" json:"halfOpenAfter"`
 	SuccessThresholdCount    uint   `yaml:"successThresholdCount" json:"successThresholdCount"`
 	SuccessThresholdCapacity uint   `yaml:"successThresholdCapacity" json:"successThresholdCapacity"`
+	// After the circuit closes again (from half-open) the upstream's share of traffic ramps up linearly
+	// over this window instead of getting all of it right away (e.g. "1m"). Disabled by default.
+	SlowStartWindow string `yaml:"slowStartWindow" json:"slowStartWindow"`
 }
 
 type TimeoutPolicyConfig struct {
@@ -253,13 +508,222 @@ type NetworkConfig struct {
 	Architecture    NetworkArchitecture `yaml:"architecture" json:"architecture"`
 	RateLimitBudget string              `yaml:"rateLimitBudget" json:"rateLimitBudget"`
 	Failsafe        *FailsafeConfig     `yaml:"failsafe" json:"failsafe"`
+	RetryBudget     *RetryBudgetConfig  `yaml:"retryBudget" json:"retryBudget"`
+	Consensus       *ConsensusConfig    `yaml:"consensus" json:"consensus"`
 	Evm             *EvmNetworkConfig   `yaml:"evm" json:"evm"`
+
+	StripResultFields []*StripResultFieldsConfig `yaml:"stripResultFields" json:"stripResultFields"`
+	RoutingRules      []*RoutingRuleConfig       `yaml:"routingRules" json:"routingRules"`
+	ServeStaleOnError *ServeStaleOnErrorConfig   `yaml:"serveStaleOnError" json:"serveStaleOnError"`
+	DeprecatedMethods []*DeprecatedMethodConfig  `yaml:"deprecatedMethods" json:"deprecatedMethods"`
+}
+
+// DeprecatedMethodConfig flags a method as deprecated on a network to help migrating clients away from it, calls are
+// still served (unless "reject" is set) but counted in erpc_network_deprecated_method_calls_total metric and logged.
+type DeprecatedMethodConfig struct {
+	// Method name or pattern (e.g. "eth_getStorageAt", "shh_*")
+	Method string `yaml:"method" json:"method"`
+	// Shown in logs and rejection errors, e.g. what to use instead
+	Message string `yaml:"message" json:"message"`
+	// Fail calls with an invalid request error instead of serving them, defaults to false
+	Reject bool `yaml:"reject" json:"reject"`
+}
+
+// ServeStaleOnErrorConfig responds with the most recent (even expired) cache entry when all upstreams fail,
+// such responses carry "X-ERPC-Stale: true" header. Writes and realtime methods (e.g. eth_blockNumber) are never served stale.
+type ServeStaleOnErrorConfig struct {
+	// Method names or patterns eligible for stale responses (defaults to all methods)
+	Methods []string `yaml:"methods" json:"methods"`
+	// Entries stored longer ago than this are not served (defaults to no limit)
+	MaxAge string `yaml:"maxAge" json:"maxAge"`
+}
+
+// RoutingRuleConfig pins requests matching a method and all param predicates to specific upstream(s),
+// rules are evaluated in order and the first match wins. Clients' own X-ERPC-Use-Upstream takes precedence.
+type RoutingRuleConfig struct {
+	// Method name or pattern (e.g. "eth_call", "eth_*")
+	Method string                `yaml:"method" json:"method"`
+	Params []*RoutingParamConfig `yaml:"params" json:"params"`
+	// Upstream id or pattern to pin matching requests to (e.g. "my-archive-node", "archive-*")
+	Upstream string `yaml:"upstream" json:"upstream"`
+}
+
+type RoutingParamConfig struct {
+	// Location of the value within params, e.g. "params[0].to" (or "0.to")
+	Path string `yaml:"path" json:"path"`
+	// Value must be equal to this, compared case-insensitively
+	Equals string `yaml:"equals" json:"equals"`
+}
+
+// StripResultFieldsConfig removes non-deterministic fields (e.g. provider metadata) from results of matching
+// methods before they are cached or compared with other upstreams, served responses are left untouched.
+type StripResultFieldsConfig struct {
+	// Method name or pattern (e.g. "eth_getBlockByNumber", "eth_*")
+	Method string `yaml:"method" json:"method"`
+	// Dot separated paths of fields within the result, arrays are traversed (e.g. "transactions.timestamp")
+	Fields []string `yaml:"fields" json:"fields"`
+}
+
+// RetryBudgetConfig limits network-level retries to a fraction of requests, so a partial outage
+// does not multiply the load on remaining healthy upstreams. Each request earns "ratio" tokens
+// (up to "maxTokens") and each retry spends one, when no token is left requests fail without retrying.
+type RetryBudgetConfig struct {
+	// Fraction of requests that may be retried, e.g. 0.1 allows retries for up to 10% of requests
+	Ratio float64 `yaml:"ratio" json:"ratio"`
+	// Max tokens that can be accumulated, which is also the initial amount (defaults to 10)
+	MaxTokens float64 `yaml:"maxTokens" json:"maxTokens"`
+}
+
+// ConsensusConfig sends each request to several upstreams and only responds with a result they agree on,
+// where each upstream's vote counts as much as its "trustWeight" so a trusted node can outweigh cheaper ones.
+type ConsensusConfig struct {
+	// Max upstreams to send each request to, picked in order of their score (defaults to all upstreams of the network)
+	MaxParticipants int `yaml:"maxParticipants" json:"maxParticipants"`
+	// Share of participants' total weight the agreed result must exceed, between 0 and 1 (defaults to 0.5)
+	Threshold float64 `yaml:"threshold" json:"threshold"`
+	// How differing results are resolved per method, the first matching entry wins and other methods require equal results
+	TieBreaks []*ConsensusTieBreakConfig `yaml:"tieBreaks" json:"tieBreaks"`
+	// Min number of upstreams that must return the agreed result on top of the threshold (defaults to no minimum)
+	RequiredAgreements int `yaml:"requiredAgreements" json:"requiredAgreements"`
+	// Per-method maxParticipants and requiredAgreements, the first matching entry wins
+	Methods []*ConsensusMethodConfig `yaml:"methods" json:"methods"`
+	// Upper bounds for X-ERPC-Consensus-Participants and X-ERPC-Consensus-Agreements headers, which
+	// are only honored for admin-authenticated requests (defaults to 0 meaning overrides are rejected)
+	MaxRequestParticipants int `yaml:"maxRequestParticipants" json:"maxRequestParticipants"`
+	MaxRequestAgreements   int `yaml:"maxRequestAgreements" json:"maxRequestAgreements"`
+}
+
+type ConsensusMethodConfig struct {
+	// Method name or pattern (e.g. "eth_sendRawTransaction", "debug_*")
+	Method             string `yaml:"method" json:"method"`
+	MaxParticipants    int    `yaml:"maxParticipants" json:"maxParticipants"`
+	RequiredAgreements int    `yaml:"requiredAgreements" json:"requiredAgreements"`
+}
+
+type ConsensusPreference string
+
+const (
+	// ConsensusPreferEqual requires the agreed result to be identical among its supporters
+	ConsensusPreferEqual ConsensusPreference = "equal"
+	// ConsensusPreferHighest is for monotonic quantities (e.g. eth_blockNumber) where upstreams slightly
+	// behind are not wrong: a result supports every value up to its own, and the highest value supported
+	// by more than the threshold is returned
+	ConsensusPreferHighest ConsensusPreference = "highest"
+)
+
+type ConsensusTieBreakConfig struct {
+	// Method name or pattern (e.g. "eth_blockNumber", "eth_*")
+	Method string `yaml:"method" json:"method"`
+	// Either "equal" or "highest"
+	Prefer ConsensusPreference `yaml:"prefer" json:"prefer"`
+}
+
+// PreferenceFor returns the tie-break preference of the first entry matching the method, defaults to equal.
+func (c *ConsensusConfig) PreferenceFor(method string) ConsensusPreference {
+	if c == nil {
+		return ConsensusPreferEqual
+	}
+	for _, tb := range c.TieBreaks {
+		if WildcardMatch(tb.Method, method) {
+			return tb.Prefer
+		}
+	}
+	return ConsensusPreferEqual
+}
+
+// RequirementFor returns max participants and required agreements for a method, per-method entries
+// take precedence and unset values (0) fall back to the network-wide ones.
+func (c *ConsensusConfig) RequirementFor(method string) (maxParticipants int, requiredAgreements int) {
+	if c == nil {
+		return 0, 0
+	}
+	maxParticipants, requiredAgreements = c.MaxParticipants, c.RequiredAgreements
+	for _, m := range c.Methods {
+		if WildcardMatch(m.Method, method) {
+			if m.MaxParticipants > 0 {
+				maxParticipants = m.MaxParticipants
+			}
+			if m.RequiredAgreements > 0 {
+				requiredAgreements = m.RequiredAgreements
+			}
+			break
+		}
+	}
+	return maxParticipants, requiredAgreements
 }
 
 type EvmNetworkConfig struct {
 	ChainId              int64  `yaml:"chainId" json:"chainId"`
 	FinalityDepth        int64  `yaml:"finalityDepth" json:"finalityDepth"`
 	BlockTrackerInterval string `yaml:"blockTrackerInterval" json:"blockTrackerInterval"`
+	// Average time between blocks (e.g. 12s for Ethereum, 250ms for Arbitrum), realtime methods such as
+	// eth_gasPrice are cached for one block time. Defaults to 1s.
+	BlockTime string `yaml:"blockTime" json:"blockTime"`
+
+	PollSubscriptions  *PollSubscriptionsConfig     `yaml:"pollSubscriptions" json:"pollSubscriptions"`
+	SyntheticResponses *EvmSyntheticResponsesConfig `yaml:"syntheticResponses" json:"syntheticResponses"`
+	LogsBloom          *EvmLogsBloomConfig          `yaml:"logsBloom" json:"logsBloom"`
+	FinalizedPrewarm   *EvmFinalizedPrewarmConfig   `yaml:"finalizedPrewarm" json:"finalizedPrewarm"`
+	VerifyEmptyLogs    *EvmVerifyEmptyLogsConfig    `yaml:"verifyEmptyLogs" json:"verifyEmptyLogs"`
+
+	// When enabled, params of well-known methods (addresses, hashes, hex quantities, block tags) are checked
+	// and malformed requests are rejected with -32602 without calling any upstream.
+	ValidateRequestParams bool `yaml:"validateRequestParams" json:"validateRequestParams"`
+
+	// Block tag (latest, pending, safe or finalized) sent upstream when clients omit the optional block param
+	// (e.g. of eth_call), since upstreams do not all default to the same one. Left as-is when empty.
+	DefaultBlockTag string `yaml:"defaultBlockTag" json:"defaultBlockTag"`
+}
+
+// EvmLogsBloomConfig keeps logsBloom of recently observed blocks, so that eth_getLogs over blocks
+// whose blooms provably exclude the requested address/topics is answered with [] without any upstream call.
+type EvmLogsBloomConfig struct {
+	// Max number of most recent blocks whose blooms are kept in memory (default 1024)
+	MaxBlocks int64 `yaml:"maxBlocks" json:"maxBlocks"`
+}
+
+// EvmFinalizedPrewarmConfig fetches and caches eth_getBlockByNumber of blocks as soon as they become finalized,
+// since they are frequently requested right after. Requires a cache to be configured.
+type EvmFinalizedPrewarmConfig struct {
+	// Max number of newly finalized blocks warmed at once, older ones are skipped when finality jumps further (default 16)
+	Window int64 `yaml:"window" json:"window"`
+	// Also warm blocks with full transaction objects, by default only [blockNumber, false] is warmed
+	FullTransactions bool `yaml:"fullTransactions" json:"fullTransactions"`
+}
+
+// EvmVerifyEmptyLogsConfig re-sends eth_getLogs to a second upstream when the first one returns [] for a range
+// that is old enough to be known by all upstreams, and serves the non-empty result if there is one. Since every
+// empty result of such ranges costs an extra upstream call it is only enabled when configured.
+type EvmVerifyEmptyLogsConfig struct {
+	// Ranges whose toBlock is less than this many blocks behind the head are fresh, upstreams might legitimately
+	// not have their logs yet so empty results are served without verification (default 10)
+	MinBlockDepth int64 `yaml:"minBlockDepth" json:"minBlockDepth"`
+}
+
+// EvmSyntheticResponsesConfig answers frequently polled node-status methods at eRPC itself without
+// calling any upstream, each method is only short-circuited when explicitly enabled.
+type EvmSyntheticResponsesConfig struct {
+	// Respond to eth_syncing with false
+	EthSyncing bool `yaml:"ethSyncing" json:"ethSyncing"`
+	// Respond to net_listening with true
+	NetListening bool `yaml:"netListening" json:"netListening"`
+	// Respond to web3_clientVersion with this string when not empty
+	Web3ClientVersion string `yaml:"web3ClientVersion" json:"web3ClientVersion"`
+}
+
+// PollSubscriptionsConfig enables eth_subscribe emulation over http, where clients long-poll for notifications.
+// It is disabled unless configured for the network.
+type PollSubscriptionsConfig struct {
+	// Max subscriptions open at once on the network, new ones are rejected when exceeded (default 1000)
+	MaxSubscriptions int `yaml:"maxSubscriptions" json:"maxSubscriptions"`
+	// Max subscriptions open at once by a single client ip on the network (default 10)
+	MaxSubscriptionsPerClient int `yaml:"maxSubscriptionsPerClient" json:"maxSubscriptionsPerClient"`
+	// Max notifications buffered per subscription, oldest ones are dropped when exceeded (default 100)
+	MaxBufferSize int `yaml:"maxBufferSize" json:"maxBufferSize"`
+	// Max time a poll waits for new notifications before returning empty (default 10s)
+	MaxWait string `yaml:"maxWait" json:"maxWait"`
+	// Subscriptions not polled for this long are removed (default 5m)
+	IdleTimeout string `yaml:"idleTimeout" json:"idleTimeout"`
 }
 
 type AuthType string
@@ -326,6 +790,20 @@ type MetricsConfig struct {
 	Port     int    `yaml:"port" json:"port"`
 }
 
+// TracingConfig exports OpenTelemetry spans of each request to an OTLP/HTTP collector, when disabled tracing is a no-op.
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Collector address as host:port, defaults to OTEL_EXPORTER_OTLP_ENDPOINT env or "localhost:4318"
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	Insecure bool   `yaml:"insecure" json:"insecure"`
+	// Defaults to "erpc"
+	ServiceName string `yaml:"serviceName" json:"serviceName"`
+	// Fraction of new traces to sample between 0 and 1 (defaults to 1), sampling decision of incoming traceparent is always respected
+	SampleRate float64 `yaml:"sampleRate" json:"sampleRate"`
+	// Send "traceparent" header to upstreams so their spans join the same trace
+	PropagateToUpstreams bool `yaml:"propagateToUpstreams" json:"propagateToUpstreams"`
+}
+
 var cfgInstance *Config
 
 // LoadConfig loads the configuration from the specified file.
@@ -372,6 +850,20 @@ func (c *RateLimitRuleConfig) MarshalZerologObject(e *zerolog.Event) {
 		Str("waitTime", c.WaitTime)
 }
 
+// ResultFieldsToStrip returns fields of all strip configs matching the method.
+func (c *NetworkConfig) ResultFieldsToStrip(method string) []string {
+	if c == nil {
+		return nil
+	}
+	var fields []string
+	for _, sf := range c.StripResultFields {
+		if WildcardMatch(sf.Method, method) {
+			fields = append(fields, sf.Fields...)
+		}
+	}
+	return fields
+}
+
 func (c *NetworkConfig) NetworkId() string {
 	switch c.Architecture {
 	case "evm":
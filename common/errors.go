@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -253,16 +254,86 @@ func (e *BaseError) Base() *BaseError {
 
 type ErrInvalidRequest struct{ BaseError }
 
+const ErrCodeInvalidRequest = "ErrInvalidRequest"
+
 var NewErrInvalidRequest = func(cause error) error {
 	return &ErrInvalidRequest{
 		BaseError{
-			Code:    "ErrInvalidRequest",
+			Code:    ErrCodeInvalidRequest,
 			Message: "invalid request body or headers",
 			Cause:   cause,
 		},
 	}
 }
 
+type ErrInvalidRequestParams struct{ BaseError }
+
+const ErrCodeInvalidRequestParams = "ErrInvalidRequestParams"
+
+var NewErrInvalidRequestParams = func(method string, cause error) error {
+	return &ErrInvalidRequestParams{
+		BaseError{
+			Code:    ErrCodeInvalidRequestParams,
+			Message: "request params do not match expected types",
+			Cause: NewErrJsonRpcExceptionInternal(
+				0,
+				JsonRpcErrorInvalidArgument,
+				fmt.Sprintf("invalid params: %s", cause),
+				nil,
+				nil,
+			),
+			Details: map[string]interface{}{
+				"method": method,
+			},
+		},
+	}
+}
+
+func (e *ErrInvalidRequestParams) ErrorStatusCode() int {
+	return http.StatusBadRequest
+}
+
+type ErrSubscriptionNotFound struct{ BaseError }
+
+const ErrCodeSubscriptionNotFound = "ErrSubscriptionNotFound"
+
+var NewErrSubscriptionNotFound = func(subscriptionId string) error {
+	return &ErrSubscriptionNotFound{
+		BaseError{
+			Code:    ErrCodeSubscriptionNotFound,
+			Message: "subscription not found or expired",
+			Details: map[string]interface{}{
+				"subscriptionId": subscriptionId,
+			},
+		},
+	}
+}
+
+func (e *ErrSubscriptionNotFound) ErrorStatusCode() int {
+	return http.StatusNotFound
+}
+
+type ErrSubscriptionLimitExceeded struct{ BaseError }
+
+const ErrCodeSubscriptionLimitExceeded = "ErrSubscriptionLimitExceeded"
+
+var NewErrSubscriptionLimitExceeded = func(scope string, limit int) error {
+	return &ErrSubscriptionLimitExceeded{
+		BaseError{
+			Code:    ErrCodeSubscriptionLimitExceeded,
+			Message: "too many open subscriptions, unsubscribe or let idle ones expire before subscribing again",
+			Details: map[string]interface{}{
+				"scope": scope,
+				"limit": limit,
+			},
+		},
+	}
+}
+
+func (e *ErrSubscriptionLimitExceeded) ErrorStatusCode() int {
+	return http.StatusTooManyRequests
+}
+
 type ErrInvalidUrlPath struct{ BaseError }
 
 var NewErrInvalidUrlPath = func(path string) error {
@@ -277,12 +348,51 @@ var NewErrInvalidUrlPath = func(path string) error {
 	}
 }
 
+type ErrInvalidContentType struct{ BaseError }
+
+const ErrCodeInvalidContentType = "ErrInvalidContentType"
+
+var NewErrInvalidContentType = func(contentType string) error {
+	return &ErrInvalidContentType{
+		BaseError{
+			Code:    ErrCodeInvalidContentType,
+			Message: "request body must be sent with Content-Type: application/json",
+			Details: map[string]interface{}{
+				"providedContentType": contentType,
+			},
+		},
+	}
+}
+
+func (e *ErrInvalidContentType) ErrorStatusCode() int {
+	return http.StatusUnsupportedMediaType
+}
+
+type ErrServerShuttingDown struct{ BaseError }
+
+const ErrCodeServerShuttingDown = "ErrServerShuttingDown"
+
+var NewErrServerShuttingDown = func() error {
+	return &ErrServerShuttingDown{
+		BaseError{
+			Code:    ErrCodeServerShuttingDown,
+			Message: "server is shutting down and does not accept new requests, retry on another instance",
+		},
+	}
+}
+
+func (e *ErrServerShuttingDown) ErrorStatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
 type ErrInvalidConfig struct{ BaseError }
 
+const ErrCodeInvalidConfig = "ErrInvalidConfig"
+
 var NewErrInvalidConfig = func(message string) error {
 	return &ErrInvalidConfig{
 		BaseError{
-			Code:    "ErrInvalidConfig",
+			Code:    ErrCodeInvalidConfig,
 			Message: message,
 		},
 	}
@@ -346,7 +456,7 @@ type ErrAuthRateLimitRuleExceeded struct{ BaseError }
 
 const ErrCodeAuthRateLimitRuleExceeded ErrorCode = "ErrAuthRateLimitRuleExceeded"
 
-var NewErrAuthRateLimitRuleExceeded = func(projectId, strategy, budget, rule string) error {
+var NewErrAuthRateLimitRuleExceeded = func(projectId, strategy, budget, rule string, limit uint, resetIn time.Duration) error {
 	return &ErrAuthRateLimitRuleExceeded{
 		BaseError{
 			Code:    ErrCodeAuthRateLimitRuleExceeded,
@@ -356,6 +466,8 @@ var NewErrAuthRateLimitRuleExceeded = func(projectId, strategy, budget, rule str
 				"strategy":  strategy,
 				"budget":    budget,
 				"rule":      rule,
+				"limit":     limit,
+				"resetInMs": resetIn.Milliseconds(),
 			},
 		},
 	}
@@ -365,6 +477,10 @@ func (e *ErrAuthRateLimitRuleExceeded) ErrorStatusCode() int {
 	return http.StatusTooManyRequests
 }
 
+func (e *ErrAuthRateLimitRuleExceeded) RateLimit() (uint, time.Duration) {
+	return rateLimitFromDetails(e.Details)
+}
+
 //
 // Projects
 //
@@ -441,6 +557,26 @@ func (e *ErrUnknownNetworkID) ErrorStatusCode() int {
 	return http.StatusBadRequest
 }
 
+type ErrInvalidNetworkId struct{ BaseError }
+
+const ErrCodeInvalidNetworkId ErrorCode = "ErrInvalidNetworkId"
+
+var NewErrInvalidNetworkId = func(networkId string) error {
+	return &ErrInvalidNetworkId{
+		BaseError{
+			Code:    ErrCodeInvalidNetworkId,
+			Message: "network id must be in <architecture>:<reference> format (e.g. evm:1)",
+			Details: map[string]interface{}{
+				"networkId": networkId,
+			},
+		},
+	}
+}
+
+func (e *ErrInvalidNetworkId) ErrorStatusCode() int {
+	return http.StatusBadRequest
+}
+
 type ErrUnknownNetworkArchitecture struct{ BaseError }
 
 var NewErrUnknownNetworkArchitecture = func(arch NetworkArchitecture) error {
@@ -508,6 +644,22 @@ var NewErrFinalizedBlockUnavailable = func(blockNumber int64) error {
 	}
 }
 
+type ErrLatestBlockUnavailable struct{ BaseError }
+
+const ErrCodeLatestBlockUnavailable ErrorCode = "ErrLatestBlockUnavailable"
+
+var NewErrLatestBlockUnavailable = func(networkId string) error {
+	return &ErrLatestBlockUnavailable{
+		BaseError{
+			Code:    ErrCodeLatestBlockUnavailable,
+			Message: "latest block is not known yet for network",
+			Details: map[string]interface{}{
+				"networkId": networkId,
+			},
+		},
+	}
+}
+
 //
 // Upstreams
 //
@@ -611,10 +763,63 @@ func (e *ErrUpstreamMalformedResponse) ErrorStatusCode() int {
 	return http.StatusBadRequest
 }
 
+type ErrUpstreamInvalidResponse struct{ BaseError }
+
+const ErrCodeUpstreamInvalidResponse ErrorCode = "ErrUpstreamInvalidResponse"
+
+var NewErrUpstreamInvalidResponse = func(upstreamId string, reason string) error {
+	return &ErrUpstreamInvalidResponse{
+		BaseError{
+			Code:    ErrCodeUpstreamInvalidResponse,
+			Message: "upstream returned an invalid json-rpc response: " + reason,
+			Details: map[string]interface{}{
+				"upstreamId": upstreamId,
+			},
+		},
+	}
+}
+
+func (e *ErrUpstreamInvalidResponse) ErrorStatusCode() int {
+	return http.StatusBadGateway
+}
+
 type ErrUpstreamsExhausted struct{ BaseError }
 
 const ErrCodeUpstreamsExhausted ErrorCode = "ErrUpstreamsExhausted"
 
+// UpstreamFailure describes why a single upstream failed to serve a request,
+// it is included in the json-rpc error data when all upstreams fail.
+type UpstreamFailure struct {
+	UpstreamId  string    `json:"upstreamId"`
+	Code        ErrorCode `json:"code,omitempty"`
+	JsonRpcCode int       `json:"jsonRpcCode,omitempty"`
+	Message     string    `json:"message"`
+}
+
+func NewUpstreamFailure(upstreamId string, err error) UpstreamFailure {
+	f := UpstreamFailure{
+		UpstreamId: upstreamId,
+		Message:    err.Error(),
+	}
+
+	// ErrUpstreamRequest only adds request metadata, so report the underlying error instead
+	cause := err
+	if ure, ok := cause.(*ErrUpstreamRequest); ok && ure.Cause != nil {
+		cause = ure.Cause
+	}
+	if se, ok := cause.(StandardError); ok {
+		f.Code = se.Base().Code
+		f.Message = se.DeepestMessage()
+	}
+
+	jre := &ErrJsonRpcExceptionInternal{}
+	if errors.As(err, &jre) {
+		f.JsonRpcCode = int(jre.NormalizedCode())
+	}
+
+	return f
+}
+
 var NewErrUpstreamsExhausted = func(
 	req *NormalizedRequest,
 	ersObj map[string]error,
@@ -624,23 +829,29 @@ var NewErrUpstreamsExhausted = func(
 ) error {
 	// TODO create a new error type that holds a map to avoid creating a new array
 	ers := []error{}
+	failures := []UpstreamFailure{}
 	req.RLock()
-	for _, err := range ersObj {
+	for upsId, err := range ersObj {
 		ers = append(ers, err)
+		failures = append(failures, NewUpstreamFailure(upsId, err))
 	}
 	req.RUnlock()
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].UpstreamId < failures[j].UpstreamId
+	})
 	e := &ErrUpstreamsExhausted{
 		BaseError{
 			Code:    ErrCodeUpstreamsExhausted,
 			Message: "all upstream attempts failed",
 			Cause:   errors.Join(ers...),
 			Details: map[string]interface{}{
-				"durationMs": duration.Milliseconds(),
-				"projectId":  prjId,
-				"networkId":  netId,
-				"attempts":   attempts,
-				"retries":    retries,
-				"hedges":     hedges,
+				"durationMs":       duration.Milliseconds(),
+				"projectId":        prjId,
+				"networkId":        netId,
+				"attempts":         attempts,
+				"retries":          retries,
+				"hedges":           hedges,
+				"upstreamFailures": failures,
 			},
 		},
 	}
@@ -710,7 +921,8 @@ func (e *ErrUpstreamsExhausted) SummarizeCauses() string {
 				missing++
 				continue
 			} else if HasErrorCode(e, ErrCodeEndpointCapacityExceeded) ||
-				HasErrorCode(e, ErrCodeUpstreamRateLimitRuleExceeded) {
+				HasErrorCode(e, ErrCodeUpstreamRateLimitRuleExceeded) ||
+				HasErrorCode(e, ErrCodeUpstreamConcurrencyQueueFull) {
 				rateLimit++
 				continue
 			} else if HasErrorCode(e, ErrCodeEndpointBillingIssue) {
@@ -722,7 +934,10 @@ func (e *ErrUpstreamsExhausted) SummarizeCauses() string {
 			} else if errors.Is(e, context.DeadlineExceeded) || HasErrorCode(e, ErrCodeEndpointRequestTimeout) {
 				timeout++
 				continue
-			} else if HasErrorCode(e, ErrCodeEndpointServerSideException) {
+			} else if HasErrorCode(e, ErrCodeEndpointServerSideException) ||
+				HasErrorCode(e, ErrCodeEndpointMalformedResponse) ||
+				HasErrorCode(e, ErrCodeUpstreamInvalidResponse) ||
+				HasErrorCode(e, ErrCodeUpstreamEmptyResponse) {
 				serverError++
 				continue
 			} else if HasErrorCode(e, ErrCodeUpstreamHedgeCancelled) {
@@ -794,6 +1009,16 @@ func (e *ErrUpstreamsExhausted) DeepestMessage() string {
 	return e.Message
 }
 
+func (e *ErrUpstreamsExhausted) UpstreamFailures() []UpstreamFailure {
+	if e.Details == nil {
+		return nil
+	}
+	if failures, ok := e.Details["upstreamFailures"].([]UpstreamFailure); ok {
+		return failures
+	}
+	return nil
+}
+
 func (e *ErrUpstreamsExhausted) UpstreamId() string {
 	return ""
 }
@@ -848,10 +1073,12 @@ var NewErrNoUpstreamsDefined = func(project string) error {
 
 type ErrNoUpstreamsFound struct{ BaseError }
 
+const ErrCodeNoUpstreamsFound ErrorCode = "ErrNoUpstreamsFound"
+
 var NewErrNoUpstreamsFound = func(project string, network string) error {
 	return &ErrNoUpstreamsFound{
 		BaseError{
-			Code:    "ErrNoUpstreamsFound",
+			Code:    ErrCodeNoUpstreamsFound,
 			Message: "no upstreams found for network",
 			Details: map[string]interface{}{
 				"project": project,
@@ -863,6 +1090,45 @@ var NewErrNoUpstreamsFound = func(project string, network string) error {
 
 func (e *ErrNoUpstreamsDefined) ErrorStatusCode() int { return 404 }
 
+type ErrUpstreamNotFound struct{ BaseError }
+
+const ErrCodeUpstreamNotFound ErrorCode = "ErrUpstreamNotFound"
+
+var NewErrUpstreamNotFound = func(project string, upstreamId string) error {
+	return &ErrUpstreamNotFound{
+		BaseError{
+			Code:    ErrCodeUpstreamNotFound,
+			Message: "upstream not found in project",
+			Details: map[string]interface{}{
+				"project":    project,
+				"upstreamId": upstreamId,
+			},
+		},
+	}
+}
+
+func (e *ErrUpstreamNotFound) ErrorStatusCode() int { return 404 }
+
+type ErrNoArchiveUpstream struct{ BaseError }
+
+const ErrCodeNoArchiveUpstream = "ErrNoArchiveUpstream"
+
+var NewErrNoArchiveUpstream = func(network string, blockNumber int64, headBlock int64) error {
+	return &ErrNoArchiveUpstream{
+		BaseError{
+			Code:    ErrCodeNoArchiveUpstream,
+			Message: "request needs historical state but no archive upstream is available for network",
+			Details: map[string]interface{}{
+				"network":     network,
+				"blockNumber": blockNumber,
+				"headBlock":   headBlock,
+			},
+		},
+	}
+}
+
+func (e *ErrNoArchiveUpstream) ErrorStatusCode() int { return 503 }
+
 type ErrUpstreamNetworkNotDetected struct{ BaseError }
 
 var NewErrUpstreamNetworkNotDetected = func(projectId string, upstreamId string) error {
@@ -1009,6 +1275,25 @@ var NewErrJsonRpcRequestUnmarshal = func(cause error) error {
 
 func (e *ErrJsonRpcRequestUnmarshal) ErrorStatusCode() int { return 400 }
 
+type ErrJsonRpcRequestInvalid struct {
+	BaseError
+}
+
+const ErrCodeJsonRpcRequestInvalid ErrorCode = "ErrJsonRpcRequestInvalid"
+
+// NewErrJsonRpcRequestInvalid is for requests that are valid json but not a valid json-rpc envelope
+// (e.g. missing method or an id that is an object), as opposed to bodies that cannot be parsed at all.
+var NewErrJsonRpcRequestInvalid = func(reason string) error {
+	return &ErrJsonRpcRequestInvalid{
+		BaseError{
+			Code:    ErrCodeJsonRpcRequestInvalid,
+			Message: fmt.Sprintf("invalid json-rpc request: %s", reason),
+		},
+	}
+}
+
+func (e *ErrJsonRpcRequestInvalid) ErrorStatusCode() int { return 400 }
+
 type ErrJsonRpcRequestUnresolvableMethod struct {
 	BaseError
 }
@@ -1059,10 +1344,12 @@ var NewErrFailsafeConfiguration = func(cause error, details map[string]interface
 
 type ErrFailsafeTimeoutExceeded struct{ BaseError }
 
+const ErrCodeFailsafeTimeoutExceeded ErrorCode = "ErrFailsafeTimeoutExceeded"
+
 var NewErrFailsafeTimeoutExceeded = func(cause error) error {
 	return &ErrFailsafeTimeoutExceeded{
 		BaseError{
-			Code:    "ErrFailsafeTimeoutExceeded",
+			Code:    ErrCodeFailsafeTimeoutExceeded,
 			Message: "failsafe timeout policy exceeded",
 			Cause:   cause,
 		},
@@ -1167,15 +1454,17 @@ type ErrProjectRateLimitRuleExceeded struct{ BaseError }
 
 const ErrCodeProjectRateLimitRuleExceeded ErrorCode = "ErrProjectRateLimitRuleExceeded"
 
-var NewErrProjectRateLimitRuleExceeded = func(project string, budget string, rule string) error {
+var NewErrProjectRateLimitRuleExceeded = func(project string, budget string, rule string, limit uint, resetIn time.Duration) error {
 	return &ErrProjectRateLimitRuleExceeded{
 		BaseError{
 			Code:    ErrCodeProjectRateLimitRuleExceeded,
 			Message: "project-level rate limit rule exceeded",
 			Details: map[string]interface{}{
-				"project": project,
-				"budget":  budget,
-				"rule":    rule,
+				"project":   project,
+				"budget":    budget,
+				"rule":      rule,
+				"limit":     limit,
+				"resetInMs": resetIn.Milliseconds(),
 			},
 		},
 	}
@@ -1185,20 +1474,26 @@ func (e *ErrProjectRateLimitRuleExceeded) ErrorStatusCode() int {
 	return http.StatusTooManyRequests
 }
 
+func (e *ErrProjectRateLimitRuleExceeded) RateLimit() (uint, time.Duration) {
+	return rateLimitFromDetails(e.Details)
+}
+
 type ErrNetworkRateLimitRuleExceeded struct{ BaseError }
 
 const ErrCodeNetworkRateLimitRuleExceeded ErrorCode = "ErrNetworkRateLimitRuleExceeded"
 
-var NewErrNetworkRateLimitRuleExceeded = func(project string, network string, budget string, rule string) error {
+var NewErrNetworkRateLimitRuleExceeded = func(project string, network string, budget string, rule string, limit uint, resetIn time.Duration) error {
 	return &ErrNetworkRateLimitRuleExceeded{
 		BaseError{
 			Code:    ErrCodeNetworkRateLimitRuleExceeded,
 			Message: "network-level rate limit rule exceeded",
 			Details: map[string]interface{}{
-				"project": project,
-				"network": network,
-				"budget":  budget,
-				"rule":    rule,
+				"project":   project,
+				"network":   network,
+				"budget":    budget,
+				"rule":      rule,
+				"limit":     limit,
+				"resetInMs": resetIn.Milliseconds(),
 			},
 		},
 	}
@@ -1208,6 +1503,23 @@ func (e *ErrNetworkRateLimitRuleExceeded) ErrorStatusCode() int {
 	return http.StatusTooManyRequests
 }
 
+func (e *ErrNetworkRateLimitRuleExceeded) RateLimit() (uint, time.Duration) {
+	return rateLimitFromDetails(e.Details)
+}
+
+// RateLimitedError is implemented by errors of eRPC's own (auth, project and network) rate limiters,
+// it reports the max count of the tripped rule and how long until its permits are replenished.
+type RateLimitedError interface {
+	error
+	RateLimit() (limit uint, resetIn time.Duration)
+}
+
+func rateLimitFromDetails(details map[string]interface{}) (uint, time.Duration) {
+	limit, _ := details["limit"].(uint)
+	resetInMs, _ := details["resetInMs"].(int64)
+	return limit, time.Duration(resetInMs) * time.Millisecond
+}
+
 type ErrNetworkRequestTimeout struct{ BaseError }
 
 const ErrCodeNetworkRequestTimeout ErrorCode = "ErrNetworkRequestTimeout"
@@ -1250,6 +1562,95 @@ func (e *ErrUpstreamRateLimitRuleExceeded) ErrorStatusCode() int {
 	return http.StatusTooManyRequests
 }
 
+type ErrUpstreamConcurrencyQueueFull struct{ BaseError }
+
+const ErrCodeUpstreamConcurrencyQueueFull ErrorCode = "ErrUpstreamConcurrencyQueueFull"
+
+var NewErrUpstreamConcurrencyQueueFull = func(upstreamId string, maxConcurrent, maxQueueSize int) error {
+	return &ErrUpstreamConcurrencyQueueFull{
+		BaseError{
+			Code:    ErrCodeUpstreamConcurrencyQueueFull,
+			Message: "upstream concurrency limit reached and its waiting queue is full",
+			Details: map[string]interface{}{
+				"upstreamId":    upstreamId,
+				"maxConcurrent": maxConcurrent,
+				"maxQueueSize":  maxQueueSize,
+			},
+		},
+	}
+}
+
+func (e *ErrUpstreamConcurrencyQueueFull) ErrorStatusCode() int {
+	return http.StatusTooManyRequests
+}
+
+type ErrRetryBudgetExhausted struct{ BaseError }
+
+const ErrCodeRetryBudgetExhausted ErrorCode = "ErrRetryBudgetExhausted"
+
+var NewErrRetryBudgetExhausted = func(networkId string, cause error) error {
+	return &ErrRetryBudgetExhausted{
+		BaseError{
+			Code:    ErrCodeRetryBudgetExhausted,
+			Message: "request failed and was not retried because network retry budget is exhausted",
+			Cause:   cause,
+			Details: map[string]interface{}{
+				"networkId": networkId,
+			},
+		},
+	}
+}
+
+func (e *ErrRetryBudgetExhausted) ErrorStatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+type ErrConsensusLowConfidence struct{ BaseError }
+
+const ErrCodeConsensusLowConfidence ErrorCode = "ErrConsensusLowConfidence"
+
+var NewErrConsensusLowConfidence = func(networkId string, threshold, bestShare float64, participants int, cause error) error {
+	return &ErrConsensusLowConfidence{
+		BaseError{
+			Code:    ErrCodeConsensusLowConfidence,
+			Message: "no result was supported by enough upstreams' weight to reach consensus threshold",
+			Cause:   cause,
+			Details: map[string]interface{}{
+				"networkId":    networkId,
+				"threshold":    threshold,
+				"bestShare":    bestShare,
+				"participants": participants,
+			},
+		},
+	}
+}
+
+func (e *ErrConsensusLowConfidence) ErrorStatusCode() int {
+	return http.StatusBadGateway
+}
+
+type ErrConsensusInsufficientUpstreams struct{ BaseError }
+
+const ErrCodeConsensusInsufficientUpstreams ErrorCode = "ErrConsensusInsufficientUpstreams"
+
+var NewErrConsensusInsufficientUpstreams = func(networkId string, requiredAgreements, healthyUpstreams int) error {
+	return &ErrConsensusInsufficientUpstreams{
+		BaseError{
+			Code:    ErrCodeConsensusInsufficientUpstreams,
+			Message: "not enough healthy upstreams to reach the required number of agreements",
+			Details: map[string]interface{}{
+				"networkId":          networkId,
+				"requiredAgreements": requiredAgreements,
+				"healthyUpstreams":   healthyUpstreams,
+			},
+		},
+	}
+}
+
+func (e *ErrConsensusInsufficientUpstreams) ErrorStatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
 //
 // Endpoint (3rd party providers, RPC nodes)
 // Main purpose of these error types is internal eRPC error handling (retries, etc)
@@ -1337,6 +1738,131 @@ func (e *ErrEndpointServerSideException) ErrorStatusCode() int {
 	return 500
 }
 
+type ErrUpstreamEmptyResponse struct{ BaseError }
+
+const ErrCodeUpstreamEmptyResponse ErrorCode = "ErrUpstreamEmptyResponse"
+
+var NewErrUpstreamEmptyResponse = func(upstreamId string, statusCode int) error {
+	return &ErrUpstreamEmptyResponse{
+		BaseError{
+			Code:    ErrCodeUpstreamEmptyResponse,
+			Message: "upstream returned a successful status with an empty body",
+			Details: map[string]interface{}{
+				"upstreamId": upstreamId,
+				"statusCode": statusCode,
+			},
+		},
+	}
+}
+
+func (e *ErrUpstreamEmptyResponse) ErrorStatusCode() int {
+	return http.StatusBadGateway
+}
+
+type ErrFaultInjected struct{ BaseError }
+
+const ErrCodeFaultInjected ErrorCode = "ErrFaultInjected"
+
+var NewErrFaultInjected = func(upstreamId string, fault string) error {
+	return &ErrFaultInjected{
+		BaseError{
+			Code:    ErrCodeFaultInjected,
+			Message: fmt.Sprintf("injected %s fault for chaos testing", fault),
+			Details: map[string]interface{}{
+				"upstreamId": upstreamId,
+				"fault":      fault,
+			},
+		},
+	}
+}
+
+func (e *ErrFaultInjected) ErrorStatusCode() int {
+	return http.StatusBadGateway
+}
+
+type ErrEndpointMalformedResponse struct{ BaseError }
+
+const ErrCodeEndpointMalformedResponse = "ErrEndpointMalformedResponse"
+
+var NewErrEndpointMalformedResponse = func(method string, cause error) error {
+	return &ErrEndpointMalformedResponse{
+		BaseError{
+			Code:    ErrCodeEndpointMalformedResponse,
+			Message: "remote endpoint returned a response that does not match expected schema",
+			Cause:   cause,
+			Details: map[string]interface{}{
+				"method": method,
+			},
+		},
+	}
+}
+
+func (e *ErrEndpointMalformedResponse) ErrorStatusCode() int {
+	return 502
+}
+
+type ErrEndpointResponseTooLarge struct{ BaseError }
+
+const ErrCodeEndpointResponseTooLarge = "ErrEndpointResponseTooLarge"
+
+var NewErrEndpointResponseTooLarge = func(maxSize int64) error {
+	return &ErrEndpointResponseTooLarge{
+		BaseError{
+			Code:    ErrCodeEndpointResponseTooLarge,
+			Message: "remote endpoint response body exceeded the max allowed size",
+			Details: map[string]interface{}{
+				"maxSize": maxSize,
+			},
+		},
+	}
+}
+
+func (e *ErrEndpointResponseTooLarge) ErrorStatusCode() int {
+	return 502
+}
+
+type ErrResultSetTooLarge struct{ BaseError }
+
+const ErrCodeResultSetTooLarge = "ErrResultSetTooLarge"
+
+var NewErrResultSetTooLarge = func(method string, maxResults int) error {
+	return &ErrResultSetTooLarge{
+		BaseError{
+			Code:    ErrCodeResultSetTooLarge,
+			Message: "result has more entries than allowed, use a narrower filter (e.g. smaller block range, specific addresses or topics)",
+			Details: map[string]interface{}{
+				"method":     method,
+				"maxResults": maxResults,
+			},
+		},
+	}
+}
+
+func (e *ErrResultSetTooLarge) ErrorStatusCode() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+type ErrGetLogsTooManySubRanges struct{ BaseError }
+
+const ErrCodeGetLogsTooManySubRanges = "ErrGetLogsTooManySubRanges"
+
+var NewErrGetLogsTooManySubRanges = func(subRanges, maxSubRanges int) error {
+	return &ErrGetLogsTooManySubRanges{
+		BaseError{
+			Code:    ErrCodeGetLogsTooManySubRanges,
+			Message: "eth_getLogs block range needs more sub-range queries than allowed, use a narrower block range",
+			Details: map[string]interface{}{
+				"subRanges":    subRanges,
+				"maxSubRanges": maxSubRanges,
+			},
+		},
+	}
+}
+
+func (e *ErrGetLogsTooManySubRanges) ErrorStatusCode() int {
+	return http.StatusRequestEntityTooLarge
+}
+
 type ErrEndpointRequestTimeout struct{ BaseError }
 
 const ErrCodeEndpointRequestTimeout = "ErrEndpointRequestTimeout"
@@ -1620,6 +2146,10 @@ func IsRetryableTowardsUpstream(err error) bool {
 		// RPC-RPC client-side error (invalid params) -> No Retry
 		!HasErrorCode(err, ErrCodeEndpointClientSideException) &&
 		!HasErrorCode(err, ErrCodeJsonRpcRequestUnmarshal) &&
+		!HasErrorCode(err, ErrCodeJsonRpcRequestInvalid) &&
+
+		// Too broad filters return too many results from any upstream -> No Retry
+		!HasErrorCode(err, ErrCodeResultSetTooLarge) &&
 
 		// Upstream-level + 401 / 403 -> No Retry
 		// RPC-RPC vendor billing/capacity/auth -> No Retry
@@ -8,3 +8,33 @@ const (
 	EvmNodeTypeSequencer EvmNodeType = "sequencer"
 	EvmNodeTypeExecution EvmNodeType = "execution"
 )
+
+// Number of recent blocks whose state a (non-archive) full node is expected to keep, older state is pruned.
+const EvmFullNodeStateRetention int64 = 128
+
+// Methods that read account/contract state at a specific block, which only archive nodes can serve for old blocks.
+var evmStateMethods = map[string]bool{
+	"eth_getBalance":          true,
+	"eth_getCode":             true,
+	"eth_getTransactionCount": true,
+	"eth_getStorageAt":        true,
+	"eth_getProof":            true,
+	"eth_call":                true,
+	"eth_createAccessList":    true,
+	"eth_getAccount":          true,
+}
+
+// IsArchiveCapable reports whether a node of this type may serve historical state. Unknown (empty) type is
+// optimistically considered capable since it cannot be detected automatically yet.
+func (t EvmNodeType) IsArchiveCapable() bool {
+	return t == "" || t == EvmNodeTypeArchive
+}
+
+// EvmRequiresArchiveNode reports whether reading state of blockNumber via method needs an archive node,
+// given the current head block of the network.
+func EvmRequiresArchiveNode(method string, blockNumber, headBlock int64) bool {
+	if !evmStateMethods[method] || blockNumber <= 0 || headBlock <= 0 {
+		return false
+	}
+	return headBlock-blockNumber > EvmFullNodeStateRetention
+}
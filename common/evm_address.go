@@ -0,0 +1,51 @@
+package common
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// FormatEvmAddress returns a 0x-prefixed 20 bytes hex address in the requested format,
+// ok is false when the value is not an address so that it can be sent as-is.
+func FormatEvmAddress(address string, format EvmAddressFormat) (string, bool) {
+	if len(address) != 42 || !(strings.HasPrefix(address, "0x") || strings.HasPrefix(address, "0X")) {
+		return "", false
+	}
+	lower := strings.ToLower(address[2:])
+	if _, err := hex.DecodeString(lower); err != nil {
+		return "", false
+	}
+
+	switch format {
+	case EvmAddressFormatLowercase:
+		return "0x" + lower, true
+	case EvmAddressFormatChecksum:
+		return "0x" + checksumEvmAddress(lower), true
+	}
+	return "", false
+}
+
+// checksumEvmAddress applies EIP-55 mixed-case encoding to a lowercase hex address without 0x prefix:
+// a letter is uppercased when the matching nibble of keccak256 of the lowercase address is 8 or higher.
+func checksumEvmAddress(lower string) string {
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(lower))
+	hash := h.Sum(nil)
+
+	out := []byte(lower)
+	for i, c := range out {
+		if c < 'a' || c > 'f' {
+			continue
+		}
+		nibble := hash[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		if nibble&0x0f >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return string(out)
+}
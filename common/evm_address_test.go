@@ -0,0 +1,43 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatEvmAddress(t *testing.T) {
+	// Test vectors from EIP-55
+	checksummed := []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	}
+
+	t.Run("Checksum", func(t *testing.T) {
+		for _, expected := range checksummed {
+			for _, input := range []string{strings.ToLower(expected), "0x" + strings.ToUpper(expected[2:]), expected} {
+				got, ok := FormatEvmAddress(input, EvmAddressFormatChecksum)
+				assert.True(t, ok, input)
+				assert.Equal(t, expected, got, input)
+			}
+		}
+	})
+
+	t.Run("Lowercase", func(t *testing.T) {
+		for _, input := range checksummed {
+			got, ok := FormatEvmAddress(input, EvmAddressFormatLowercase)
+			assert.True(t, ok, input)
+			assert.Equal(t, strings.ToLower(input), got)
+		}
+	})
+
+	t.Run("NonAddressesAreNotFormatted", func(t *testing.T) {
+		for _, input := range []string{"", "0x", "latest", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeA", "0xZaAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"} {
+			_, ok := FormatEvmAddress(input, EvmAddressFormatChecksum)
+			assert.False(t, ok, input)
+		}
+	})
+}
@@ -7,6 +7,138 @@ import (
 	"strings"
 )
 
+// BlockRefKind tells which part of a block reference is set.
+type BlockRefKind string
+
+const (
+	// BlockRefKindNone is for requests that do not target a block (e.g. eth_chainId, eth_getTransactionByHash)
+	BlockRefKindNone BlockRefKind = ""
+	// BlockRefKindTag is for tags moving with the chain (latest, pending, safe, finalized) and earliest
+	BlockRefKindTag BlockRefKind = "tag"
+	// BlockRefKindNumber is for a specific block number
+	BlockRefKindNumber BlockRefKind = "number"
+	// BlockRefKindHash is for a block hash, including EIP-1898 {"blockHash": ...} objects
+	BlockRefKindHash BlockRefKind = "hash"
+	// BlockRefKindRange is for eth_getLogs over a range, tag or number is its upper bound (toBlock)
+	BlockRefKindRange BlockRefKind = "range"
+)
+
+type evmBlockParam struct {
+	index int
+	// Param is always a block hash (e.g. eth_getBlockByHash)
+	hash bool
+	// Param is a block number or tag, EIP-1898 objects are not accepted (e.g. eth_getBlockByNumber)
+	numberOrTag bool
+	// Tag nodes default to when the param is omitted (or null), empty when the param is mandatory
+	defaultTag string
+}
+
+// Methods targeting a single block, by position of their block param. This is the only place block params
+// are described, for block references, normalization, cache keys and params validation.
+var evmBlockParams = map[string]evmBlockParam{
+	"eth_getBlockByNumber":                    {index: 0, numberOrTag: true},
+	"eth_getUncleByBlockNumberAndIndex":       {index: 0, numberOrTag: true},
+	"eth_getTransactionByBlockNumberAndIndex": {index: 0, numberOrTag: true},
+	"eth_getUncleCountByBlockNumber":          {index: 0, numberOrTag: true},
+	"eth_getBlockTransactionCountByNumber":    {index: 0, numberOrTag: true},
+	"eth_getBlockReceipts":                    {index: 0},
+	"eth_getBlockByHash":                      {index: 0, hash: true},
+	"eth_getTransactionByBlockHashAndIndex":   {index: 0, hash: true},
+	"eth_getBlockTransactionCountByHash":      {index: 0, hash: true},
+	"eth_getUncleCountByBlockHash":            {index: 0, hash: true},
+	"eth_getBalance":                          {index: 1},
+	"eth_getCode":                             {index: 1},
+	"eth_getTransactionCount":                 {index: 1},
+	"eth_call":                                {index: 1, defaultTag: "latest"},
+	"eth_createAccessList":                    {index: 1, defaultTag: "latest"},
+	"eth_estimateGas":                         {index: 1, defaultTag: "latest"},
+	"eth_feeHistory":                          {index: 1},
+	"eth_getAccount":                          {index: 1},
+	"eth_getProof":                            {index: 2},
+	"eth_getStorageAt":                        {index: 2},
+}
+
+// BlockRef returns the block targeted by the request params, only one of tag, number or hash is set as told by kind.
+// An omitted optional block param (e.g. of eth_call) is reported as the tag nodes default to, and eth_getLogs
+// reports its toBlock (defaulting to "latest") unless it filters by blockHash.
+func (r *JsonRpcRequest) BlockRef() (tag string, number int64, hash string, kind BlockRefKind, err error) {
+	if r == nil {
+		return "", 0, "", BlockRefKindNone, errors.New("cannot extract block reference when json-rpc request is nil")
+	}
+	r.RLock()
+	defer r.RUnlock()
+	return r.blockRef()
+}
+
+func (r *JsonRpcRequest) blockRef() (tag string, number int64, hash string, kind BlockRefKind, err error) {
+	if r.Method == "eth_getLogs" {
+		if len(r.Params) == 0 {
+			return "", 0, "", BlockRefKindNone, fmt.Errorf("unexpected no parameters for method %s", r.Method)
+		}
+		filter, ok := r.Params[0].(map[string]interface{})
+		if !ok {
+			return "", 0, "", BlockRefKindNone, fmt.Errorf("unexpected filter for method %s: %+v", r.Method, r.Params[0])
+		}
+		if bh, ok := filter["blockHash"].(string); ok && bh != "" {
+			return "", 0, bh, BlockRefKindHash, nil
+		}
+		to, ok := filter["toBlock"]
+		if !ok || to == nil {
+			return "latest", 0, "", BlockRefKindRange, nil
+		}
+		tag, number, hash, _, err = parseEvmBlockRefParam(to)
+		return tag, number, hash, BlockRefKindRange, err
+	}
+
+	bp, ok := evmBlockParams[r.Method]
+	if !ok {
+		return "", 0, "", BlockRefKindNone, nil
+	}
+	if len(r.Params) <= bp.index || r.Params[bp.index] == nil {
+		if bp.defaultTag != "" {
+			return bp.defaultTag, 0, "", BlockRefKindTag, nil
+		}
+		return "", 0, "", BlockRefKindNone, fmt.Errorf("unexpected missing block parameter #%d for method %s: %+v", bp.index+1, r.Method, r.Params)
+	}
+	if bp.hash {
+		bh, ok := r.Params[bp.index].(string)
+		if !ok {
+			return "", 0, "", BlockRefKindNone, fmt.Errorf("block hash parameter is not a string for method %s it is %+v", r.Method, r.Params[bp.index])
+		}
+		return "", 0, bh, BlockRefKindHash, nil
+	}
+	return parseEvmBlockRefParam(r.Params[bp.index])
+}
+
+// parseEvmBlockRefParam resolves a hex number, a tag, a block hash or an EIP-1898 object.
+func parseEvmBlockRefParam(param interface{}) (tag string, number int64, hash string, kind BlockRefKind, err error) {
+	switch bp := param.(type) {
+	case string:
+		if !strings.HasPrefix(bp, "0x") {
+			return bp, 0, "", BlockRefKindTag, nil
+		}
+		// A 32 bytes value can only be a hash, block numbers never get near that
+		if len(bp) == 66 {
+			return "", 0, bp, BlockRefKindHash, nil
+		}
+		bn, err := HexToInt64(bp)
+		if err != nil {
+			return "", 0, "", BlockRefKindNone, err
+		}
+		return "", bn, "", BlockRefKindNumber, nil
+	case float64:
+		return "", int64(bp), "", BlockRefKindNumber, nil
+	case map[string]interface{}:
+		if bh, ok := bp["blockHash"].(string); ok && bh != "" {
+			return "", 0, bh, BlockRefKindHash, nil
+		}
+		if bn, ok := bp["blockNumber"]; ok {
+			return parseEvmBlockRefParam(bn)
+		}
+	}
+	return "", 0, "", BlockRefKindNone, fmt.Errorf("invalid block parameter: %+v", param)
+}
+
 func ExtractEvmBlockReference(rpcReq *JsonRpcRequest, rpcResp *JsonRpcResponse) (string, int64, error) {
 	blockRef, blockNumber, err := ExtractEvmBlockReferenceFromRequest(rpcReq)
 	if err != nil {
@@ -38,28 +170,6 @@ func ExtractEvmBlockReferenceFromRequest(r *JsonRpcRequest) (string, int64, erro
 	defer r.RUnlock()
 
 	switch r.Method {
-	case "eth_getBlockByNumber",
-		"eth_getUncleByBlockNumberAndIndex",
-		"eth_getTransactionByBlockNumberAndIndex",
-		"eth_getUncleCountByBlockNumber",
-		"eth_getBlockTransactionCountByNumber",
-		"eth_getBlockReceipts":
-		if len(r.Params) > 0 {
-			if bns, ok := r.Params[0].(string); ok {
-				if strings.HasPrefix(bns, "0x") {
-					bni, err := HexToInt64(bns)
-					if err != nil {
-						return "", 0, err
-					}
-					return strconv.FormatInt(bni, 10), bni, nil
-				} else {
-					return "", 0, nil
-				}
-			}
-		} else {
-			return "", 0, fmt.Errorf("unexpected no parameters for method %s", r.Method)
-		}
-
 	case "eth_getLogs":
 		if len(r.Params) > 0 {
 			if logsFilter, ok := r.Params[0].(map[string]interface{}); ok {
@@ -79,28 +189,6 @@ func ExtractEvmBlockReferenceFromRequest(r *JsonRpcRequest) (string, int64, erro
 
 		return "", 0, nil
 
-	case "eth_getBalance",
-		"eth_getCode",
-		"eth_getTransactionCount",
-		"eth_call",
-		"eth_feeHistory",
-		"eth_getAccount":
-		if len(r.Params) > 1 {
-			if bns, ok := r.Params[1].(string); ok {
-				if strings.HasPrefix(bns, "0x") {
-					bni, err := HexToInt64(bns)
-					if err != nil {
-						return bns, 0, err
-					}
-					return strconv.FormatInt(bni, 10), bni, nil
-				} else {
-					return "", 0, nil
-				}
-			}
-		} else {
-			return "", 0, fmt.Errorf("unexpected missing 2nd parameter for method %s: %+v", r.Method, r.Params)
-		}
-
 	case "eth_chainId",
 		"eth_getTransactionReceipt",
 		"eth_getTransactionByHash",
@@ -115,40 +203,28 @@ func ExtractEvmBlockReferenceFromRequest(r *JsonRpcRequest) (string, int64, erro
 		// require this method always give them current accurate data (even if it's reorged).
 		// Returning "*" as blockRef means that these data can be cached irrevelant of their block.
 		return "*", 0, nil
+	}
 
-	case "eth_getBlockByHash",
-		"eth_getTransactionByBlockHashAndIndex",
-		"eth_getBlockTransactionCountByHash",
-		"eth_getUncleCountByBlockHash":
-		if len(r.Params) > 0 {
-			if blockHash, ok := r.Params[0].(string); ok {
-				return blockHash, 0, nil
-			}
-			return "", 0, fmt.Errorf("first parameter is not a string for method %s it is %+v", r.Method, r.Params)
-		}
-
-	case "eth_getProof",
-		"eth_getStorageAt":
-		if len(r.Params) > 2 {
-			if bns, ok := r.Params[2].(string); ok {
-				if strings.HasPrefix(bns, "0x") {
-					bni, err := HexToInt64(bns)
-					if err != nil {
-						return bns, 0, err
-					}
-					return strconv.FormatInt(bni, 10), bni, nil
-				} else {
-					return "", 0, nil
-				}
-			}
-		} else {
-			return "", 0, fmt.Errorf("unexpected missing 3rd parameter for method %s: %+v", r.Method, r.Params)
-		}
-
-	default:
+	if _, ok := evmBlockParams[r.Method]; !ok {
 		return "", 0, nil
 	}
-
+	tag, number, hash, kind, err := r.blockRef()
+	if err != nil {
+		return "", 0, err
+	}
+	switch kind {
+	case BlockRefKindNumber:
+		return strconv.FormatInt(number, 10), number, nil
+	case BlockRefKindHash:
+		// A block hash always points to the same block, which makes the data cacheable the same way as
+		// eth_getBlockByHash even without knowing the block number
+		return hash, 0, nil
+	case BlockRefKindTag:
+		if tag == "earliest" {
+			// Genesis never changes, unlike other tags (latest, pending, safe, finalized) that move with the chain
+			return "0", 0, nil
+		}
+	}
 	return "", 0, nil
 }
 
@@ -188,6 +264,34 @@ func ExtractEvmBlockReferenceFromResponse(rpcReq *JsonRpcRequest, rpcResp *JsonR
 				return blockRef, blockNumber, nil
 			}
 		}
+	case "eth_getBlockReceipts":
+		if rpcResp.Result != nil {
+			result, err := rpcResp.ParsedResult()
+			if err != nil {
+				return "", 0, err
+			}
+			rpcResp.RLock()
+			defer rpcResp.RUnlock()
+			// All receipts belong to the same block, the first one is enough and a block without transactions has none
+			if receipts, ok := result.([]interface{}); ok && len(receipts) > 0 {
+				if rcp, ok := receipts[0].(map[string]interface{}); ok {
+					var blockRef string
+					var blockNumber int64
+					blockRef, _ = rcp["blockHash"].(string)
+					if bns, ok := rcp["blockNumber"].(string); ok && bns != "" {
+						bn, err := HexToInt64(bns)
+						if err != nil {
+							return "", 0, err
+						}
+						blockNumber = bn
+					}
+					if blockRef == "" && blockNumber > 0 {
+						blockRef = strconv.FormatInt(blockNumber, 10)
+					}
+					return blockRef, blockNumber, nil
+				}
+			}
+		}
 	case "eth_getBlockByNumber":
 		if rpcResp.Result != nil {
 			result, err := rpcResp.ParsedResult()
@@ -280,6 +280,69 @@ func TestExtractBlockReference(t *testing.T) {
 			expectedNum: 436,
 			expectedErr: false,
 		},
+		{
+			name: "eth_getBalance with EIP-1898 blockNumber object",
+			request: &JsonRpcRequest{
+				Method: "eth_getBalance",
+				Params: []interface{}{"0xabc", map[string]interface{}{"blockNumber": "0x1b4"}},
+			},
+			expectedRef: "436",
+			expectedNum: 436,
+			expectedErr: false,
+		},
+		{
+			name: "eth_call with EIP-1898 blockHash object",
+			request: &JsonRpcRequest{
+				Method: "eth_call",
+				Params: []interface{}{
+					map[string]interface{}{"to": "0xabc"},
+					map[string]interface{}{"blockHash": "0x9a1f7e9b3c", "requireCanonical": true},
+				},
+			},
+			expectedRef: "0x9a1f7e9b3c",
+			expectedNum: 0,
+			expectedErr: false,
+		},
+		{
+			name: "eth_getStorageAt with EIP-1898 blockNumber object",
+			request: &JsonRpcRequest{
+				Method: "eth_getStorageAt",
+				Params: []interface{}{"0xabc", "0x0", map[string]interface{}{"blockNumber": "0x1b4"}},
+			},
+			expectedRef: "436",
+			expectedNum: 436,
+			expectedErr: false,
+		},
+		{
+			name: "eth_call with earliest tag",
+			request: &JsonRpcRequest{
+				Method: "eth_call",
+				Params: []interface{}{map[string]interface{}{"to": "0xabc", "data": "0x1234"}, "earliest"},
+			},
+			expectedRef: "0",
+			expectedNum: 0,
+			expectedErr: false,
+		},
+		{
+			name: "eth_createAccessList with block number",
+			request: &JsonRpcRequest{
+				Method: "eth_createAccessList",
+				Params: []interface{}{map[string]interface{}{"to": "0xabc", "data": "0x1234"}, "0x1b4"},
+			},
+			expectedRef: "436",
+			expectedNum: 436,
+			expectedErr: false,
+		},
+		{
+			name: "eth_call with finalized tag",
+			request: &JsonRpcRequest{
+				Method: "eth_call",
+				Params: []interface{}{map[string]interface{}{"to": "0xabc", "data": "0x1234"}, "finalized"},
+			},
+			expectedRef: "",
+			expectedNum: 0,
+			expectedErr: false,
+		},
 		{
 			name: "eth_chainId",
 			request: &JsonRpcRequest{
@@ -314,3 +377,61 @@ func TestExtractBlockReference(t *testing.T) {
 		})
 	}
 }
+
+func TestJsonRpcRequest_BlockRef(t *testing.T) {
+	hash := "0x9b83c12c69edb74f6c8dd5d052765c1adf940e320bd1291696e6fa07829eee71"
+	tests := []struct {
+		name        string
+		method      string
+		params      []interface{}
+		tag         string
+		number      int64
+		hash        string
+		kind        BlockRefKind
+		expectedErr bool
+	}{
+		{name: "eth_getBlockByNumber with number", method: "eth_getBlockByNumber", params: []interface{}{"0xc5043f", false}, number: 12911679, kind: BlockRefKindNumber},
+		{name: "eth_getBlockByNumber with tag", method: "eth_getBlockByNumber", params: []interface{}{"finalized", false}, tag: "finalized", kind: BlockRefKindTag},
+		{name: "eth_getBlockByHash", method: "eth_getBlockByHash", params: []interface{}{hash, false}, hash: hash, kind: BlockRefKindHash},
+		{name: "eth_getBalance with number", method: "eth_getBalance", params: []interface{}{"0xabc", "0x1b4"}, number: 436, kind: BlockRefKindNumber},
+		{name: "eth_getBalance with EIP-1898 blockNumber object", method: "eth_getBalance", params: []interface{}{"0xabc", map[string]interface{}{"blockNumber": "0x1b4"}}, number: 436, kind: BlockRefKindNumber},
+		{name: "eth_getBalance with EIP-1898 blockHash object", method: "eth_getBalance", params: []interface{}{"0xabc", map[string]interface{}{"blockHash": hash, "requireCanonical": true}}, hash: hash, kind: BlockRefKindHash},
+		{name: "eth_getBalance with plain block hash", method: "eth_getBalance", params: []interface{}{"0xabc", hash}, hash: hash, kind: BlockRefKindHash},
+		{name: "eth_getBalance without block param", method: "eth_getBalance", params: []interface{}{"0xabc"}, expectedErr: true},
+		{name: "eth_call with tag", method: "eth_call", params: []interface{}{map[string]interface{}{"to": "0xabc"}, "pending"}, tag: "pending", kind: BlockRefKindTag},
+		{name: "eth_call without block param defaults to latest", method: "eth_call", params: []interface{}{map[string]interface{}{"to": "0xabc"}}, tag: "latest", kind: BlockRefKindTag},
+		{name: "eth_call with null block param defaults to latest", method: "eth_call", params: []interface{}{map[string]interface{}{"to": "0xabc"}, nil}, tag: "latest", kind: BlockRefKindTag},
+		{name: "eth_getStorageAt with number", method: "eth_getStorageAt", params: []interface{}{"0xabc", "0x0", "0x10"}, number: 16, kind: BlockRefKindNumber},
+		{name: "eth_feeHistory with tag", method: "eth_feeHistory", params: []interface{}{"0x4", "latest", []interface{}{}}, tag: "latest", kind: BlockRefKindTag},
+		{name: "eth_getLogs with range", method: "eth_getLogs", params: []interface{}{map[string]interface{}{"fromBlock": "0x1", "toBlock": "0x10"}}, number: 16, kind: BlockRefKindRange},
+		{name: "eth_getLogs with tag upper bound", method: "eth_getLogs", params: []interface{}{map[string]interface{}{"fromBlock": "0x1", "toBlock": "safe"}}, tag: "safe", kind: BlockRefKindRange},
+		{name: "eth_getLogs without toBlock", method: "eth_getLogs", params: []interface{}{map[string]interface{}{"fromBlock": "0x1"}}, tag: "latest", kind: BlockRefKindRange},
+		{name: "eth_getLogs with blockHash", method: "eth_getLogs", params: []interface{}{map[string]interface{}{"blockHash": hash}}, hash: hash, kind: BlockRefKindHash},
+		{name: "eth_getLogs without filter", method: "eth_getLogs", params: []interface{}{}, expectedErr: true},
+		{name: "invalid hex number", method: "eth_getCode", params: []interface{}{"0xabc", "0xzz"}, expectedErr: true},
+		{name: "invalid block param type", method: "eth_getCode", params: []interface{}{"0xabc", true}, expectedErr: true},
+		{name: "eth_getTransactionByHash does not target a block", method: "eth_getTransactionByHash", params: []interface{}{hash}, kind: BlockRefKindNone},
+		{name: "eth_chainId does not target a block", method: "eth_chainId", params: []interface{}{}, kind: BlockRefKindNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, number, hash, kind, err := (&JsonRpcRequest{Method: tt.method, Params: tt.params}).BlockRef()
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.tag, tag)
+			assert.Equal(t, tt.number, number)
+			assert.Equal(t, tt.hash, hash)
+			assert.Equal(t, tt.kind, kind)
+		})
+	}
+
+	t.Run("NilRequest", func(t *testing.T) {
+		var r *JsonRpcRequest
+		_, _, _, _, err := r.BlockRef()
+		assert.Error(t, err)
+	})
+}
@@ -5,57 +5,174 @@ import (
 	"strings"
 )
 
+// EvmBlockParamIndex returns position of the block parameter for methods that accept
+// a block number, tag, hash or EIP-1898 object, or -1 if method has no such parameter.
+func EvmBlockParamIndex(method string) int {
+	if bp, ok := evmBlockParams[method]; ok {
+		return bp.index
+	}
+	return -1
+}
+
+// EvmDefaultBlockParam returns the block tag a node assumes when the block param of the method
+// is omitted, or empty string if the block param is mandatory (or the method has none).
+func EvmDefaultBlockParam(method string) string {
+	return evmBlockParams[method].defaultTag
+}
+
+// ApplyEvmDefaultBlockTag sets an omitted (or null) block param of methods in which it is optional to the given tag,
+// so that the request means the same block regardless of what the serving upstream defaults to. It tells if params changed.
+func ApplyEvmDefaultBlockTag(r *JsonRpcRequest, tag string) bool {
+	if tag == "" || EvmDefaultBlockParam(r.Method) == "" {
+		return false
+	}
+	r.Lock()
+	defer r.Unlock()
+
+	idx := EvmBlockParamIndex(r.Method)
+	switch {
+	case idx < 0 || len(r.Params) < idx:
+		return false
+	case len(r.Params) == idx:
+		r.Params = append(r.Params, tag)
+	case r.Params[idx] == nil:
+		r.Params[idx] = tag
+	default:
+		return false
+	}
+	return true
+}
+
+// TTLClass describes for how long a json-rpc response can be kept in cache.
+type TTLClass string
+
+const (
+	// TTLClassImmutable is for data that never changes for a network (e.g. chain id),
+	// so it can be cached forever regardless of block finality.
+	TTLClassImmutable TTLClass = "immutable"
+
+	// TTLClassFinalized is for data that can only be cached once the referenced block is finalized.
+	TTLClassFinalized TTLClass = "finalized"
+
+	// TTLClassRealtime is for data that describes the current head (e.g. gas price), so it can only
+	// be cached for about one block time of the network.
+	TTLClassRealtime TTLClass = "realtime"
+
+	// TTLClassUncacheable is for responses that must not be cached at all, e.g. a transaction that is still pending.
+	TTLClassUncacheable TTLClass = "uncacheable"
+)
+
+var evmImmutableMethods = map[string]bool{
+	"eth_chainId": true,
+	"net_version": true,
+}
+
+var evmRealtimeMethods = map[string]bool{
+	"eth_gasPrice":             true,
+	"eth_maxPriorityFeePerGas": true,
+	"eth_blobBaseFee":          true,
+}
+
+// CacheTTLClass returns the caching class of a request based on its method.
+func (r *JsonRpcRequest) CacheTTLClass() TTLClass {
+	if r == nil {
+		return TTLClassFinalized
+	}
+	if evmImmutableMethods[r.Method] {
+		return TTLClassImmutable
+	}
+	if evmRealtimeMethods[r.Method] {
+		return TTLClassRealtime
+	}
+	return TTLClassFinalized
+}
+
+// EvmTxResultTTLClass refines the caching class of transaction lookups once their result is known, ok is false for
+// other methods. A pending transaction (no blockHash yet) is uncacheable, while a mined one never changes after
+// its block is finalized so it becomes immutable, until then it is finalized-class like any other block data.
+func EvmTxResultTTLClass(rpcReq *JsonRpcRequest, rpcResp *JsonRpcResponse, isFinalized func(blockNumber int64) (bool, error)) (TTLClass, bool, error) {
+	if rpcReq == nil || (rpcReq.Method != "eth_getTransactionByHash" && rpcReq.Method != "eth_getTransactionReceipt") {
+		return "", false, nil
+	}
+	blockRef, blockNumber, err := ExtractEvmBlockReferenceFromResponse(rpcReq, rpcResp)
+	if err != nil {
+		return "", true, err
+	}
+	if blockRef == "" && blockNumber == 0 {
+		return TTLClassUncacheable, true, nil
+	}
+	if blockNumber > 0 {
+		fin, err := isFinalized(blockNumber)
+		if err != nil {
+			return "", true, err
+		}
+		if fin {
+			return TTLClassImmutable, true, nil
+		}
+	}
+	return TTLClassFinalized, true, nil
+}
+
+// NormalizeEvmBlockParam canonicalizes a block parameter so that equivalent forms produce the same value.
+// EIP-1898 {"blockNumber": ...} objects are reduced to the plain hex number, and {"blockHash": ...} objects
+// always carry an explicit "requireCanonical" flag (defaults to false as per the EIP).
+func NormalizeEvmBlockParam(param interface{}) (interface{}, error) {
+	if bp, ok := param.(map[string]interface{}); ok {
+		if bh, ok := bp["blockHash"].(string); ok {
+			rc, _ := bp["requireCanonical"].(bool)
+			return map[string]interface{}{
+				"blockHash":        strings.ToLower(bh),
+				"requireCanonical": rc,
+			}, nil
+		}
+		if bn, ok := bp["blockNumber"]; ok {
+			return NormalizeHex(bn)
+		}
+		return nil, fmt.Errorf("invalid block parameter object, must have either blockNumber or blockHash: %+v", bp)
+	}
+	if bh, ok := param.(string); ok && len(bh) == 66 && strings.HasPrefix(bh, "0x") {
+		// Plain block hashes (e.g. of eth_getBlockReceipts) are too large to be a number
+		return strings.ToLower(bh), nil
+	}
+
+	return NormalizeHex(param)
+}
+
 func NormalizeEvmHttpJsonRpc(nrq *NormalizedRequest, r *JsonRpcRequest) error {
 	r.Lock()
 	defer r.Unlock()
 
-	switch r.Method {
-	case "eth_getBlockByNumber",
-		"eth_getUncleByBlockNumberAndIndex",
-		"eth_getTransactionByBlockNumberAndIndex",
-		"eth_getUncleCountByBlockNumber",
-		"eth_getBlockTransactionCountByNumber":
-		if len(r.Params) > 0 {
-			bks, ok := r.Params[0].(string)
+	if bp, ok := evmBlockParams[r.Method]; ok && !bp.hash && len(r.Params) > bp.index {
+		if bp.numberOrTag {
+			bks, ok := r.Params[bp.index].(string)
 			if !ok {
-				bkf, ok := r.Params[0].(float64)
+				bkf, ok := r.Params[bp.index].(float64)
 				if !ok {
 					return fmt.Errorf("invalid block number, must be 0x hex string, or number or latest/finalized")
 				}
 				bks = fmt.Sprintf("%f", bkf)
 				b, err := NormalizeHex(bks)
 				if err == nil {
-					r.Params[0] = b
+					r.Params[bp.index] = b
 				}
 			}
 			if strings.HasPrefix(bks, "0x") {
 				b, err := NormalizeHex(bks)
 				if err == nil {
-					r.Params[0] = b
+					r.Params[bp.index] = b
 				}
 			}
-		}
-
-	case "eth_getBalance",
-		"eth_getCode",
-		"eth_getTransactionCount",
-		"eth_call",
-		"eth_estimateGas":
-		if len(r.Params) > 1 {
-			b, err := NormalizeHex(r.Params[1])
+		} else {
+			b, err := NormalizeEvmBlockParam(r.Params[bp.index])
 			if err != nil {
 				return err
 			}
-			r.Params[1] = b
-		}
-	case "eth_getStorageAt":
-		if len(r.Params) > 2 {
-			b, err := NormalizeHex(r.Params[2])
-			if err != nil {
-				return err
-			}
-			r.Params[2] = b
+			r.Params[bp.index] = b
 		}
+		return nil
+	}
+
+	switch r.Method {
 	case "eth_getLogs":
 		if len(r.Params) > 0 {
 			if paramsMap, ok := r.Params[0].(map[string]interface{}); ok {
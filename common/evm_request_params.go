@@ -0,0 +1,162 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EvmParamSchema is a lightweight check applied to a single param of a json-rpc request.
+// It must return an error describing the problem, or nil if the param is acceptable.
+type EvmParamSchema func(value interface{}) error
+
+var evmBlockTags = map[string]bool{
+	"latest":    true,
+	"earliest":  true,
+	"pending":   true,
+	"safe":      true,
+	"finalized": true,
+}
+
+// Expected shapes of leading params, trailing params that are not listed (or not sent) are not checked.
+// Block params are not listed here, they are checked as described in evmBlockParams.
+var evmRequestParamSchemas = withEvmBlockParamSchemas(map[string][]EvmParamSchema{
+	"eth_getBalance":            {EvmParamAddress},
+	"eth_getCode":               {EvmParamAddress},
+	"eth_getTransactionCount":   {EvmParamAddress},
+	"eth_getStorageAt":          {EvmParamAddress, EvmParamQuantity},
+	"eth_getProof":              {EvmParamAddress},
+	"eth_getAccount":            {EvmParamAddress},
+	"eth_getTransactionByHash":  {EvmParamHash},
+	"eth_getTransactionReceipt": {EvmParamHash},
+	"eth_sendRawTransaction":    {EvmParamData},
+})
+
+// withEvmBlockParamSchemas adds the schema of the block param of every method in evmBlockParams to schemas.
+func withEvmBlockParamSchemas(schemas map[string][]EvmParamSchema) map[string][]EvmParamSchema {
+	for method, bp := range evmBlockParams {
+		s := make([]EvmParamSchema, max(len(schemas[method]), bp.index+1))
+		copy(s, schemas[method])
+		switch {
+		case bp.hash:
+			s[bp.index] = EvmParamHash
+		case bp.numberOrTag:
+			s[bp.index] = EvmParamBlockNumberOrTag
+		default:
+			s[bp.index] = EvmParamBlock
+		}
+		schemas[method] = s
+	}
+	return schemas
+}
+
+// ValidateEvmJsonRpcRequestParams checks params of well-known methods against their expected shapes
+// (addresses, hashes, hex quantities and block tags), methods without a schema are always accepted.
+func ValidateEvmJsonRpcRequestParams(jrq *JsonRpcRequest) error {
+	if jrq == nil {
+		return nil
+	}
+	jrq.RLock()
+	defer jrq.RUnlock()
+
+	schemas, ok := evmRequestParamSchemas[jrq.Method]
+	if !ok {
+		return nil
+	}
+	for i, schema := range schemas {
+		if schema == nil || i >= len(jrq.Params) {
+			continue
+		}
+		if err := schema(jrq.Params[i]); err != nil {
+			return NewErrInvalidRequestParams(jrq.Method, fmt.Errorf("param #%d %w", i, err))
+		}
+	}
+	return nil
+}
+
+// EvmParamAddress requires a 0x-prefixed 20-byte hex string.
+func EvmParamAddress(value interface{}) error {
+	s, ok := value.(string)
+	if !ok || !isFixedHex(s, 40) {
+		return fmt.Errorf("must be a 20-byte hex address, got %s", describeParam(value))
+	}
+	return nil
+}
+
+// EvmParamHash requires a 0x-prefixed 32-byte hex string.
+func EvmParamHash(value interface{}) error {
+	s, ok := value.(string)
+	if !ok || !isFixedHex(s, 64) {
+		return fmt.Errorf("must be a 32-byte hex hash, got %s", describeParam(value))
+	}
+	return nil
+}
+
+// EvmParamQuantity requires a 0x-prefixed hex number.
+func EvmParamQuantity(value interface{}) error {
+	s, ok := value.(string)
+	if !ok || !isHexQuantity(s) {
+		return fmt.Errorf("must be a hex quantity, got %s", describeParam(value))
+	}
+	return nil
+}
+
+// EvmParamData requires 0x-prefixed hex with an even number of digits.
+func EvmParamData(value interface{}) error {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, "0x") || len(s)%2 != 0 || !isHexDigits(s[2:]) {
+		return fmt.Errorf("must be 0x-prefixed hex data, got %s", describeParam(value))
+	}
+	return nil
+}
+
+// EvmParamBlockNumberOrTag requires a hex block number or one of the known block tags.
+func EvmParamBlockNumberOrTag(value interface{}) error {
+	s, ok := value.(string)
+	if !ok || !(evmBlockTags[s] || isHexQuantity(s)) {
+		return fmt.Errorf("must be a hex block number or one of latest, earliest, pending, safe, finalized, got %s", describeParam(value))
+	}
+	return nil
+}
+
+// EvmParamBlock additionally accepts EIP-1898 objects with either blockNumber or blockHash,
+// and null since nodes treat it as the default block of the method.
+func EvmParamBlock(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		if bh, ok := v["blockHash"]; ok {
+			return EvmParamHash(bh)
+		}
+		if bn, ok := v["blockNumber"]; ok {
+			return EvmParamBlockNumberOrTag(bn)
+		}
+		return fmt.Errorf("must have either blockHash or blockNumber when given as an object")
+	default:
+		return EvmParamBlockNumberOrTag(value)
+	}
+}
+
+func describeParam(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func isFixedHex(s string, digits int) bool {
+	return len(s) == digits+2 && strings.HasPrefix(s, "0x") && isHexDigits(s[2:])
+}
+
+func isHexQuantity(s string) bool {
+	return len(s) > 2 && strings.HasPrefix(s, "0x") && isHexDigits(s[2:])
+}
+
+func isHexDigits(s string) bool {
+	for _, c := range s {
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
+			return false
+		}
+	}
+	return true
+}
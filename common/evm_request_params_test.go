@@ -0,0 +1,60 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEvmJsonRpcRequestParams(t *testing.T) {
+	newJrq := func(method string, params ...interface{}) *JsonRpcRequest {
+		return &JsonRpcRequest{JSONRPC: "2.0", ID: 1, Method: method, Params: params}
+	}
+
+	t.Run("ValidParamsAreAccepted", func(t *testing.T) {
+		assert.NoError(t, ValidateEvmJsonRpcRequestParams(newJrq("eth_getBalance", "0x95222290DD7278Aa3Ddd389Cc1E1d165CC4BAfe5", "latest")))
+		assert.NoError(t, ValidateEvmJsonRpcRequestParams(newJrq("eth_getBalance", "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5", "0x10")))
+		assert.NoError(t, ValidateEvmJsonRpcRequestParams(newJrq("eth_getCode", "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5", map[string]interface{}{
+			"blockHash": "0xdc0818cf78f21a8e70579cb46a43643f78291264dda342ae31049421c82d21ae",
+		})))
+		assert.NoError(t, ValidateEvmJsonRpcRequestParams(newJrq("eth_getBlockByNumber", "finalized", true)))
+		assert.NoError(t, ValidateEvmJsonRpcRequestParams(newJrq("eth_call", map[string]interface{}{"to": "0x1"})))
+	})
+
+	t.Run("MalformedAddressIsRejected", func(t *testing.T) {
+		err := ValidateEvmJsonRpcRequestParams(newJrq("eth_getBalance", "0x1234", "latest"))
+		assert.True(t, HasErrorCode(err, ErrCodeInvalidRequestParams), "unexpected error: %v", err)
+
+		jre := &ErrJsonRpcExceptionInternal{}
+		if assert.True(t, errors.As(err, &jre)) {
+			assert.Equal(t, JsonRpcErrorInvalidArgument, jre.NormalizedCode())
+			assert.Contains(t, jre.Message, `param #0 must be a 20-byte hex address, got "0x1234"`)
+		}
+	})
+
+	t.Run("InvalidBlockTagIsRejected", func(t *testing.T) {
+		err := ValidateEvmJsonRpcRequestParams(newJrq("eth_getBalance", "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5", "newest"))
+		assert.True(t, HasErrorCode(err, ErrCodeInvalidRequestParams), "unexpected error: %v", err)
+
+		err = ValidateEvmJsonRpcRequestParams(newJrq("eth_getBlockByNumber", "123", false))
+		assert.True(t, HasErrorCode(err, ErrCodeInvalidRequestParams), "unexpected error: %v", err)
+	})
+
+	t.Run("BlockParamIsCheckedForEveryBlockMethod", func(t *testing.T) {
+		err := ValidateEvmJsonRpcRequestParams(newJrq("eth_estimateGas", map[string]interface{}{"to": "0x1"}, "newest"))
+		assert.True(t, HasErrorCode(err, ErrCodeInvalidRequestParams), "unexpected error: %v", err)
+
+		err = ValidateEvmJsonRpcRequestParams(newJrq("eth_getUncleCountByBlockHash", "0x1234"))
+		assert.True(t, HasErrorCode(err, ErrCodeInvalidRequestParams), "unexpected error: %v", err)
+
+		// EIP-1898 objects are only accepted where nodes accept them
+		err = ValidateEvmJsonRpcRequestParams(newJrq("eth_getBlockByNumber", map[string]interface{}{"blockNumber": "0x1"}, false))
+		assert.True(t, HasErrorCode(err, ErrCodeInvalidRequestParams), "unexpected error: %v", err)
+		assert.NoError(t, ValidateEvmJsonRpcRequestParams(newJrq("eth_getStorageAt", "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5", "0x0", map[string]interface{}{"blockNumber": "0x1"})))
+	})
+
+	t.Run("MethodsWithoutSchemaAreNotChecked", func(t *testing.T) {
+		assert.NoError(t, ValidateEvmJsonRpcRequestParams(newJrq("eth_customMethod", 1, "x")))
+	})
+}
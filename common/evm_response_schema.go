@@ -0,0 +1,96 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// EvmResponseSchema is a lightweight check applied to the parsed "result" of a json-rpc response.
+// It must return an error describing the first problem found, or nil if the result is acceptable.
+type EvmResponseSchema func(result interface{}) error
+
+var (
+	evmResponseSchemasMu sync.RWMutex
+	evmResponseSchemas   = map[string]EvmResponseSchema{
+		"eth_blockNumber":      EvmSchemaHexQuantity,
+		"eth_chainId":          EvmSchemaHexQuantity,
+		"eth_getBlockByNumber": EvmSchemaNullableObject("number", "hash", "parentHash"),
+		"eth_getBlockByHash":   EvmSchemaNullableObject("number", "hash", "parentHash"),
+	}
+)
+
+// RegisterEvmResponseSchema adds or replaces the schema used to validate responses of a method.
+func RegisterEvmResponseSchema(method string, schema EvmResponseSchema) {
+	evmResponseSchemasMu.Lock()
+	defer evmResponseSchemasMu.Unlock()
+	evmResponseSchemas[method] = schema
+}
+
+// ValidateEvmJsonRpcResponse runs the schema registered for the method (if any) against the response result.
+// Responses carrying a json-rpc error are not validated since they are handled by error normalization.
+func ValidateEvmJsonRpcResponse(method string, jrr *JsonRpcResponse) error {
+	if jrr == nil || jrr.Error != nil {
+		return nil
+	}
+
+	evmResponseSchemasMu.RLock()
+	schema, ok := evmResponseSchemas[method]
+	evmResponseSchemasMu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	result, err := jrr.ParsedResult()
+	if err != nil {
+		return NewErrEndpointMalformedResponse(method, err)
+	}
+	if err := schema(result); err != nil {
+		return NewErrEndpointMalformedResponse(method, err)
+	}
+
+	return nil
+}
+
+// EvmSchemaHexQuantity requires the result to be a 0x-prefixed hex quantity.
+func EvmSchemaHexQuantity(result interface{}) error {
+	return requireHexString("result", result)
+}
+
+// EvmSchemaNullableObject requires the result to be either null or an object having all the given fields.
+func EvmSchemaNullableObject(fields ...string) EvmResponseSchema {
+	return func(result interface{}) error {
+		if result == nil {
+			return nil
+		}
+		obj, ok := result.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("result must be an object, got %T", result)
+		}
+		for _, f := range fields {
+			if err := requireHexString(f, obj[f]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func requireHexString(field string, value interface{}) error {
+	if value == nil {
+		return fmt.Errorf("%s is missing", field)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%s must be a hex string, got %T", field, value)
+	}
+	if len(s) < 3 || !strings.HasPrefix(s, "0x") {
+		return fmt.Errorf("%s must be a 0x-prefixed hex string, got %q", field, s)
+	}
+	for _, c := range s[2:] {
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
+			return fmt.Errorf("%s must be a 0x-prefixed hex string, got %q", field, s)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEvmJsonRpcResponse(t *testing.T) {
+	t.Run("ValidBlock", func(t *testing.T) {
+		jrr := &JsonRpcResponse{
+			Result: []byte(`{"number":"0x1b4","hash":"0xdc0818cf78f21a8e70579cb46a43643f78291264dda342ae31049421c82d21ae","parentHash":"0xe99e022112df268087ea7eafaf4790497fd21dbeeb6bd7a1721df161a6657a54"}`),
+		}
+		assert.NoError(t, ValidateEvmJsonRpcResponse("eth_getBlockByNumber", jrr))
+	})
+
+	t.Run("BlockMissingHash", func(t *testing.T) {
+		jrr := &JsonRpcResponse{
+			Result: []byte(`{"number":"0x1b4","parentHash":"0xe99e022112df268087ea7eafaf4790497fd21dbeeb6bd7a1721df161a6657a54"}`),
+		}
+		err := ValidateEvmJsonRpcResponse("eth_getBlockByNumber", jrr)
+		assert.Error(t, err)
+		assert.True(t, HasErrorCode(err, ErrCodeEndpointMalformedResponse))
+		assert.True(t, IsRetryableTowardsUpstream(err))
+	})
+
+	t.Run("NullBlockIsAllowed", func(t *testing.T) {
+		jrr := &JsonRpcResponse{Result: []byte(`null`)}
+		assert.NoError(t, ValidateEvmJsonRpcResponse("eth_getBlockByNumber", jrr))
+	})
+
+	t.Run("BlockNumberMustBeHexQuantity", func(t *testing.T) {
+		assert.NoError(t, ValidateEvmJsonRpcResponse("eth_blockNumber", &JsonRpcResponse{Result: []byte(`"0x1b4"`)}))
+		assert.Error(t, ValidateEvmJsonRpcResponse("eth_blockNumber", &JsonRpcResponse{Result: []byte(`436`)}))
+		assert.Error(t, ValidateEvmJsonRpcResponse("eth_blockNumber", &JsonRpcResponse{Result: []byte(`"0xzz"`)}))
+	})
+
+	t.Run("MethodWithoutSchemaIsNotValidated", func(t *testing.T) {
+		assert.NoError(t, ValidateEvmJsonRpcResponse("eth_getBalance", &JsonRpcResponse{Result: []byte(`{}`)}))
+	})
+}
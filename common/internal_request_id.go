@@ -0,0 +1,76 @@
+package common
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/bytedance/sonic"
+)
+
+type InternalRequestIdStrategy string
+
+const (
+	// InternalRequestIdCounter generates ids from a process-wide monotonic counter (e.g. "erpc-42")
+	InternalRequestIdCounter InternalRequestIdStrategy = "counter"
+	// InternalRequestIdUuid generates random (v4) uuids (e.g. "erpc-0f8fad5b-d9cb-469f-a165-70867728950e")
+	InternalRequestIdUuid InternalRequestIdStrategy = "uuid"
+
+	// Client ids are usually numbers, a string prefix keeps ids of eRPC-originated requests apart from them
+	internalRequestIdPrefix = "erpc-"
+)
+
+var (
+	internalRequestIdStrategy atomic.Value
+	internalRequestIdCounter  atomic.Uint64
+)
+
+// SetInternalRequestIdStrategy changes how ids of eRPC-originated requests (e.g. state polling,
+// health checks, chain id detection) are generated, an empty strategy means counter.
+func SetInternalRequestIdStrategy(strategy InternalRequestIdStrategy) error {
+	switch strategy {
+	case "":
+		strategy = InternalRequestIdCounter
+	case InternalRequestIdCounter, InternalRequestIdUuid:
+	default:
+		return NewErrInvalidConfig(fmt.Sprintf("unknown internalRequestIds strategy: %s (must be counter or uuid)", strategy))
+	}
+	internalRequestIdStrategy.Store(strategy)
+	return nil
+}
+
+// NewInternalRequestId returns a new unique id for a request originated by eRPC itself.
+func NewInternalRequestId() string {
+	if s, _ := internalRequestIdStrategy.Load().(InternalRequestIdStrategy); s == InternalRequestIdUuid {
+		var b [16]byte
+		if _, err := rand.Read(b[:]); err == nil {
+			b[6] = (b[6] & 0x0f) | 0x40
+			b[8] = (b[8] & 0x3f) | 0x80
+			return fmt.Sprintf("%s%x-%x-%x-%x-%x", internalRequestIdPrefix, b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+		}
+	}
+	return internalRequestIdPrefix + strconv.FormatUint(internalRequestIdCounter.Add(1), 10)
+}
+
+// IsInternalRequestId tells whether an id was generated by NewInternalRequestId.
+func IsInternalRequestId(id interface{}) bool {
+	s, ok := id.(string)
+	return ok && strings.HasPrefix(s, internalRequestIdPrefix)
+}
+
+// NewInternalRequest builds a json-rpc request originated by eRPC itself with a unique internal id.
+func NewInternalRequest(method string, params []interface{}) (*NormalizedRequest, error) {
+	jrq, err := NewJsonRpcRequest(NewInternalRequestId(), method, params)
+	if err != nil {
+		return nil, err
+	}
+	body, err := sonic.Marshal(jrq)
+	if err != nil {
+		return nil, err
+	}
+	req := NewNormalizedRequest(body)
+	req.jsonRpcRequest = jrq
+	return req, nil
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInternalRequestId(t *testing.T) {
+	defer func() { _ = SetInternalRequestIdStrategy("") }()
+
+	generate := func(n int) []string {
+		ids := make([]string, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				ids[i] = NewInternalRequestId()
+			}(i)
+		}
+		wg.Wait()
+		return ids
+	}
+	assertUnique := func(t *testing.T, ids []string) {
+		seen := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			assert.False(t, seen[id], "duplicate internal id %s", id)
+			seen[id] = true
+		}
+	}
+
+	for _, strategy := range []InternalRequestIdStrategy{InternalRequestIdCounter, InternalRequestIdUuid} {
+		t.Run("UniqueIdsWith_"+string(strategy), func(t *testing.T) {
+			assert.NoError(t, SetInternalRequestIdStrategy(strategy))
+			ids := generate(1000)
+			assertUnique(t, ids)
+			for _, id := range ids {
+				assert.True(t, IsInternalRequestId(id), id)
+			}
+		})
+	}
+
+	t.Run("UuidFormat", func(t *testing.T) {
+		assert.NoError(t, SetInternalRequestIdStrategy(InternalRequestIdUuid))
+		assert.Regexp(t, regexp.MustCompile(`^erpc-[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), NewInternalRequestId())
+	})
+
+	t.Run("ClientIdsAreNotInternal", func(t *testing.T) {
+		for _, id := range []interface{}{1, float64(75412), "1", "abc", nil} {
+			assert.False(t, IsInternalRequestId(id), "%v", id)
+		}
+	})
+
+	t.Run("InternalRequestsGetDistinctIds", func(t *testing.T) {
+		assert.NoError(t, SetInternalRequestIdStrategy(InternalRequestIdCounter))
+		a, err := NewInternalRequest("eth_chainId", nil)
+		assert.NoError(t, err)
+		b, err := NewInternalRequest("eth_chainId", nil)
+		assert.NoError(t, err)
+
+		ja, err := a.JsonRpcRequest()
+		assert.NoError(t, err)
+		jb, err := b.JsonRpcRequest()
+		assert.NoError(t, err)
+		assert.NotEqual(t, ja.ID, jb.ID)
+		assert.True(t, IsInternalRequestId(ja.ID))
+		assert.Contains(t, string(a.Body()), `"id":"`+ja.ID.(string)+`"`)
+
+		// Identical internal requests still share a cache key, ids are not part of it
+		ha, err := ja.CacheHash()
+		assert.NoError(t, err)
+		hb, err := jb.CacheHash()
+		assert.NoError(t, err)
+		assert.Equal(t, ha, hb)
+	})
+
+	t.Run("InternalRequestWithoutMethodIsRejected", func(t *testing.T) {
+		req, err := NewInternalRequest("", nil)
+		assert.Nil(t, req)
+		assert.True(t, HasErrorCode(err, "ErrJsonRpcRequestUnresolvableMethod"), err)
+	})
+
+	t.Run("UnknownStrategyIsRejected", func(t *testing.T) {
+		assert.Error(t, SetInternalRequestIdStrategy("random"))
+	})
+}
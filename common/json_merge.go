@@ -0,0 +1,167 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// MergeJSONArrays concatenates top-level json arrays into one without decoding their elements, only the
+// bracket structure of each part is checked so the result is a valid array as long as the elements are.
+// Empty parts (no bytes or null) are treated as empty arrays.
+func MergeJSONArrays(parts ...json.RawMessage) (json.RawMessage, error) {
+	size := 2
+	inners := make([][]byte, 0, len(parts))
+	for i, p := range parts {
+		inner, err := jsonArrayInner(p)
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", i, err)
+		}
+		if len(inner) == 0 {
+			continue
+		}
+		if err := scanJsonArrayElements(inner, nil); err != nil {
+			return nil, fmt.Errorf("part %d: %w", i, err)
+		}
+		inners = append(inners, inner)
+		size += len(inner) + 1
+	}
+
+	out := make([]byte, 0, size)
+	out = append(out, '[')
+	for i, inner := range inners {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, inner...)
+	}
+	out = append(out, ']')
+	return out, nil
+}
+
+// MergeSortedJSONArrays merges arrays that are each already sorted by less into one sorted array, e.g. logs
+// of several sub-queries ordered by block number and log index. Elements that compare equal keep the order
+// of their parts, like a stable sort would.
+func MergeSortedJSONArrays(less func(a, b json.RawMessage) bool, parts ...json.RawMessage) (json.RawMessage, error) {
+	lists := make([][]json.RawMessage, 0, len(parts))
+	size, total := 2, 0
+	for i, p := range parts {
+		elems, err := SplitJSONArray(p)
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", i, err)
+		}
+		if len(elems) == 0 {
+			continue
+		}
+		lists = append(lists, elems)
+		total += len(elems)
+		size += len(p)
+	}
+
+	out := make([]byte, 0, size)
+	out = append(out, '[')
+	for n := 0; n < total; n++ {
+		best := -1
+		for i, l := range lists {
+			if len(l) == 0 {
+				continue
+			}
+			if best == -1 || less(l[0], lists[best][0]) {
+				best = i
+			}
+		}
+		if n > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, lists[best][0]...)
+		lists[best] = lists[best][1:]
+	}
+	out = append(out, ']')
+	return out, nil
+}
+
+// SplitJSONArray returns the top-level elements of a json array as sub-slices of raw, nothing is copied or decoded.
+func SplitJSONArray(raw json.RawMessage) ([]json.RawMessage, error) {
+	inner, err := jsonArrayInner(raw)
+	if err != nil || len(inner) == 0 {
+		return nil, err
+	}
+	var elems []json.RawMessage
+	err = scanJsonArrayElements(inner, func(elem []byte) {
+		elems = append(elems, elem)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return elems, nil
+}
+
+// jsonArrayInner returns what is between the outer brackets of an array, trimmed of whitespace.
+func jsonArrayInner(raw []byte) ([]byte, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	if raw[0] != '[' || raw[len(raw)-1] != ']' {
+		return nil, fmt.Errorf("not a json array")
+	}
+	return bytes.TrimSpace(raw[1 : len(raw)-1]), nil
+}
+
+// scanJsonArrayElements walks the inner bytes of an array and calls onElement for each top-level element.
+// It checks that brackets are balanced outside of strings and that elements are neither empty nor dangling.
+func scanJsonArrayElements(inner []byte, onElement func(elem []byte)) error {
+	var open []byte
+	inString, escaped := false, false
+	start := 0
+	emit := func(end int) error {
+		elem := bytes.TrimSpace(inner[start:end])
+		if len(elem) == 0 {
+			return fmt.Errorf("empty element at offset %d", start)
+		}
+		if onElement != nil {
+			onElement(elem)
+		}
+		return nil
+	}
+
+	for i, c := range inner {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '[', '{':
+			open = append(open, c)
+		case ']', '}':
+			// Closing brackets are two code points after their opening ones in ascii
+			if len(open) == 0 || open[len(open)-1] != c-2 {
+				return fmt.Errorf("unbalanced %q at offset %d", c, i)
+			}
+			open = open[:len(open)-1]
+		case ',':
+			if len(open) == 0 {
+				if err := emit(i); err != nil {
+					return err
+				}
+				start = i + 1
+			}
+		}
+	}
+	if inString {
+		return fmt.Errorf("unterminated string")
+	}
+	if len(open) != 0 {
+		return fmt.Errorf("unbalanced brackets")
+	}
+	return emit(len(inner))
+}
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeJSONArrays(t *testing.T) {
+	t.Run("MergesThreeArraysIncludingAnEmptyOne", func(t *testing.T) {
+		out, err := MergeJSONArrays(
+			json.RawMessage(`[{"logIndex":"0x0","data":"a,b"},{"logIndex":"0x1"}]`),
+			json.RawMessage(` [ ] `),
+			json.RawMessage(`[{"logIndex":"0x2","topics":["0x1","0x2"]}]`),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, `[{"logIndex":"0x0","data":"a,b"},{"logIndex":"0x1"},{"logIndex":"0x2","topics":["0x1","0x2"]}]`, string(out))
+		assert.True(t, json.Valid(out))
+	})
+
+	t.Run("TreatsNullAndMissingPartsAsEmpty", func(t *testing.T) {
+		out, err := MergeJSONArrays(nil, json.RawMessage(`null`), json.RawMessage(`[]`))
+		require.NoError(t, err)
+		assert.Equal(t, `[]`, string(out))
+
+		out, err = MergeJSONArrays()
+		require.NoError(t, err)
+		assert.Equal(t, `[]`, string(out))
+	})
+
+	t.Run("IgnoresBracketsInsideStrings", func(t *testing.T) {
+		out, err := MergeJSONArrays(json.RawMessage(`["]\"[", "{"]`), json.RawMessage(`[1]`))
+		require.NoError(t, err)
+		assert.Equal(t, `["]\"[", "{",1]`, string(out))
+		assert.True(t, json.Valid(out))
+	})
+
+	t.Run("RejectsInvalidParts", func(t *testing.T) {
+		for _, raw := range []string{
+			`{"logIndex":"0x0"}`,
+			`[{"a":1]`,
+			`[[1},2]`,
+			`[1,,2]`,
+			`[1,]`,
+			`["unterminated]`,
+		} {
+			_, err := MergeJSONArrays(json.RawMessage(`[1]`), json.RawMessage(raw))
+			assert.Error(t, err, raw)
+		}
+	})
+
+	t.Run("MergesSortedArrays", func(t *testing.T) {
+		byIndex := func(a, b json.RawMessage) bool {
+			var la, lb struct {
+				LogIndex int `json:"i"`
+			}
+			_ = json.Unmarshal(a, &la)
+			_ = json.Unmarshal(b, &lb)
+			return la.LogIndex < lb.LogIndex
+		}
+		out, err := MergeSortedJSONArrays(byIndex,
+			json.RawMessage(`[{"i":0,"p":"a"},{"i":3,"p":"a"}]`),
+			json.RawMessage(`[]`),
+			json.RawMessage(`[{"i":1,"p":"c"},{"i":3,"p":"c"},{"i":4,"p":"c"}]`),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, `[{"i":0,"p":"a"},{"i":1,"p":"c"},{"i":3,"p":"a"},{"i":3,"p":"c"},{"i":4,"p":"c"}]`, string(out))
+	})
+
+	t.Run("SplitsTopLevelElements", func(t *testing.T) {
+		elems, err := SplitJSONArray(json.RawMessage(`[ {"a":[1,2]} , "x,y", 3 ]`))
+		require.NoError(t, err)
+		require.Len(t, elems, 3)
+		assert.Equal(t, `{"a":[1,2]}`, string(elems[0]))
+		assert.Equal(t, `"x,y"`, string(elems[1]))
+		assert.Equal(t, `3`, string(elems[2]))
+	})
+}
@@ -0,0 +1,132 @@
+package common
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const MsgpackContentType = "application/msgpack"
+
+// AcceptsMsgpack tells whether an Accept header asks for msgpack, json stays the default for
+// anything else including "*/*" and empty headers.
+func AcceptsMsgpack(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		// Ignore parameters such as ";q=0.9"
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if strings.EqualFold(mediaType, MsgpackContentType) || strings.EqualFold(mediaType, "application/x-msgpack") {
+			return true
+		}
+	}
+	return false
+}
+
+// JsonToMsgpack re-encodes a json value as msgpack. Object keys are sorted so the output is deterministic,
+// and integers that fit in 64 bits are encoded as msgpack integers instead of floats.
+func JsonToMsgpack(raw []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(raw)))
+	if err := writeMsgpackValue(buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeMsgpackValue(buf *bytes.Buffer, v interface{}) error {
+	switch t := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if t {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		writeMsgpackNumber(buf, t)
+	case string:
+		writeMsgpackString(buf, t)
+	case []interface{}:
+		writeMsgpackHeader(buf, len(t), 0x90, 0xdc, 0xdd)
+		for _, item := range t {
+			if err := writeMsgpackValue(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeMsgpackHeader(buf, len(t), 0x80, 0xde, 0xdf)
+		for _, k := range keys {
+			writeMsgpackString(buf, k)
+			if err := writeMsgpackValue(buf, t[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot encode %T as msgpack", v)
+	}
+	return nil
+}
+
+func writeMsgpackNumber(buf *bytes.Buffer, n json.Number) {
+	if i, err := n.Int64(); err == nil {
+		switch {
+		case i >= 0 && i < 128:
+			buf.WriteByte(byte(i))
+		case i < 0 && i >= -32:
+			buf.WriteByte(byte(i))
+		default:
+			buf.WriteByte(0xd3)
+			buf.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+		}
+		return
+	}
+	f, _ := n.Float64()
+	buf.WriteByte(0xcb)
+	buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+func writeMsgpackString(buf *bytes.Buffer, s string) {
+	switch l := len(s); {
+	case l < 32:
+		buf.WriteByte(0xa0 | byte(l))
+	case l <= math.MaxUint8:
+		buf.WriteByte(0xd9)
+		buf.WriteByte(byte(l))
+	case l <= math.MaxUint16:
+		buf.WriteByte(0xda)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(l)))
+	default:
+		buf.WriteByte(0xdb)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(l)))
+	}
+	buf.WriteString(s)
+}
+
+// writeMsgpackHeader writes the length prefix of an array or map using its fix, 16-bit or 32-bit format.
+func writeMsgpackHeader(buf *bytes.Buffer, l int, fix, b16, b32 byte) {
+	switch {
+	case l < 16:
+		buf.WriteByte(fix | byte(l))
+	case l <= math.MaxUint16:
+		buf.WriteByte(b16)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(l)))
+	default:
+		buf.WriteByte(b32)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(l)))
+	}
+}
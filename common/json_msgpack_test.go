@@ -0,0 +1,44 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonToMsgpack(t *testing.T) {
+	t.Run("EncodesJsonRpcResponseWithSortedKeys", func(t *testing.T) {
+		packed, err := JsonToMsgpack([]byte(`{"jsonrpc":"2.0","result":"0x1","id":1}`))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{
+			0x83,
+			0xa2, 'i', 'd', 0x01,
+			0xa7, 'j', 's', 'o', 'n', 'r', 'p', 'c', 0xa3, '2', '.', '0',
+			0xa6, 'r', 'e', 's', 'u', 'l', 't', 0xa3, '0', 'x', '1',
+		}, packed)
+	})
+
+	t.Run("EncodesScalarsAndArrays", func(t *testing.T) {
+		packed, err := JsonToMsgpack([]byte(`[null,true,false,-1,300,1.5]`))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{
+			0x96,
+			0xc0, 0xc3, 0xc2, 0xff,
+			0xd3, 0, 0, 0, 0, 0, 0, 0x01, 0x2c,
+			0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0,
+		}, packed)
+	})
+
+	t.Run("RejectsMalformedJson", func(t *testing.T) {
+		_, err := JsonToMsgpack([]byte(`{"id":`))
+		assert.Error(t, err)
+	})
+}
+
+func TestAcceptsMsgpack(t *testing.T) {
+	assert.True(t, AcceptsMsgpack("application/msgpack"))
+	assert.True(t, AcceptsMsgpack("application/json;q=0.5, application/x-msgpack"))
+	assert.False(t, AcceptsMsgpack(""))
+	assert.False(t, AcceptsMsgpack("*/*"))
+	assert.False(t, AcceptsMsgpack("application/json"))
+}
@@ -106,6 +106,10 @@ func (r *JsonRpcResponse) UnmarshalJSON(data []byte) error {
 	if err := sonic.Unmarshal(data, &aux); err != nil {
 		return err
 	}
+	// Some nodes send "error":null along with the result, which is the same as no error
+	if isJsonNull(aux.Error) {
+		aux.Error = nil
+	}
 
 	// Special case upstream does not return proper json-rpc response
 	if aux.Error == nil && aux.Result == nil && aux.ID == nil {
@@ -202,6 +206,17 @@ func (r *JsonRpcResponse) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// HasResultAndError tells if both a non-null result and an error are set, which json-rpc forbids,
+// so neither of them can be trusted to be the actual outcome of the request.
+func (r *JsonRpcResponse) HasResultAndError() bool {
+	if r == nil {
+		return false
+	}
+	r.RLock()
+	defer r.RUnlock()
+	return r.Error != nil && !isJsonNull(r.Result)
+}
+
 type JsonRpcRequest struct {
 	sync.RWMutex
 
@@ -211,6 +226,73 @@ type JsonRpcRequest struct {
 	Params  []interface{} `json:"params"`
 }
 
+// NewJsonRpcRequest builds a json-rpc 2.0 request, params are never nil so that
+// marshaled bodies always carry "params":[] and cache hashes do not depend on how the request was built.
+func NewJsonRpcRequest(id interface{}, method string, params []interface{}) (*JsonRpcRequest, error) {
+	if method == "" {
+		return nil, NewErrJsonRpcRequestUnresolvableMethod(map[string]interface{}{
+			"id":     id,
+			"params": params,
+		})
+	}
+	if params == nil {
+		params = []interface{}{}
+	}
+	return &JsonRpcRequest{
+		JSONRPC: "2.0",
+		ID:      id,
+		Method:  method,
+		Params:  params,
+	}, nil
+}
+
+// validateJsonRpcEnvelope explains why a json body that could not be unmarshaled into a JsonRpcRequest
+// is not a valid json-rpc request, it returns nil when the body looks structurally valid.
+func validateJsonRpcEnvelope(body []byte) error {
+	var fields map[string]json.RawMessage
+	if err := sonic.Unmarshal(body, &fields); err != nil {
+		return NewErrJsonRpcRequestInvalid("request must be a json object")
+	}
+	if v, ok := fields["jsonrpc"]; ok && !isJsonNull(v) && v[0] != '"' {
+		return NewErrJsonRpcRequestInvalid(`jsonrpc must be "2.0"`)
+	}
+	if v, ok := fields["method"]; !ok || isJsonNull(v) {
+		return NewErrJsonRpcRequestInvalid("missing method")
+	} else if v[0] != '"' {
+		return NewErrJsonRpcRequestInvalid("method must be a string")
+	}
+	if v, ok := fields["id"]; ok && !isJsonNull(v) && v[0] != '"' && v[0] != '-' && (v[0] < '0' || v[0] > '9') {
+		return NewErrJsonRpcRequestInvalid("id must be a string, number or null")
+	}
+	if v, ok := fields["params"]; ok && !isJsonNull(v) {
+		switch v[0] {
+		case '[':
+		case '{':
+			return NewErrJsonRpcRequestInvalid("params must be an array, by-name (object) params are not supported")
+		default:
+			return NewErrJsonRpcRequestInvalid("params must be an array")
+		}
+	}
+	return nil
+}
+
+// validateJsonRpcRequest checks what unmarshaling into a JsonRpcRequest does not enforce.
+func validateJsonRpcRequest(r *JsonRpcRequest) error {
+	if r.Method == "" {
+		return NewErrJsonRpcRequestInvalid("missing method")
+	}
+	switch r.ID.(type) {
+	case nil, string, float64, int, int64, json.Number:
+	default:
+		return NewErrJsonRpcRequestInvalid("id must be a string, number or null")
+	}
+	return nil
+}
+
+func isJsonNull(v json.RawMessage) bool {
+	return len(v) == 0 || string(v) == "null"
+}
+
 func (r *JsonRpcRequest) MarshalZerologObject(e *zerolog.Event) {
 	if r == nil {
 		return
@@ -230,7 +312,7 @@ func (r *JsonRpcRequest) CacheHash() (string, error) {
 
 	hasher := sha256.New()
 
-	for _, p := range r.Params {
+	for _, p := range r.cacheHashParams() {
 		err := hashValue(hasher, p)
 		if err != nil {
 			return "", err
@@ -241,36 +323,79 @@ func (r *JsonRpcRequest) CacheHash() (string, error) {
 	return fmt.Sprintf("%s:%x", r.Method, b), nil
 }
 
+// cacheHashParams returns the params as they are hashed, with the block param normalized.
+// The caller must hold the read lock.
+func (r *JsonRpcRequest) cacheHashParams() []interface{} {
+	params := r.Params
+	bp, ok := evmBlockParams[r.Method]
+	// Hashes are kept as-is, and so are plain block numbers whose type (e.g. 1 vs "1") upstreams treat differently
+	if !ok || bp.hash || bp.numberOrTag {
+		return params
+	}
+	bpi := bp.index
+	if bp.defaultTag != "" && len(params) == bpi {
+		// An omitted block param means the default tag, so [callObj] is the same as [callObj, "latest"]
+		params = append(params[:len(params):len(params)], bp.defaultTag)
+	}
+	if bpi >= len(params) {
+		return params
+	}
+
+	p := params[bpi]
+	if p == nil && bp.defaultTag != "" {
+		p = bp.defaultTag
+	}
+	// Equivalent block params (e.g. "0x01", "0x1" and {"blockNumber":"0x1"}) must result in the same hash
+	if np, err := NormalizeEvmBlockParam(p); err == nil {
+		p = np
+	}
+	normalized := make([]interface{}, len(params))
+	copy(normalized, params)
+	normalized[bpi] = p
+	return normalized
+}
+
+// hashValue writes each value prefixed with a type tag, so values of different types
+// (e.g. the number 1 and the string "1") never result in the same hash. Strings, map keys and containers
+// are length-prefixed and scalars are terminated, so that no sequence of values can be read as another one
+// (e.g. ["as:b"] and ["a", "b"]).
 func hashValue(h io.Writer, v interface{}) error {
 	switch t := v.(type) {
 	case bool:
-		_, err := h.Write([]byte(fmt.Sprintf("%t", t)))
+		_, err := fmt.Fprintf(h, "b:%t;", t)
 		return err
 	case int:
-		_, err := h.Write([]byte(fmt.Sprintf("%d", t)))
+		_, err := fmt.Fprintf(h, "i:%d;", t)
 		return err
 	case float64:
-		_, err := h.Write([]byte(fmt.Sprintf("%f", t)))
+		_, err := fmt.Fprintf(h, "f:%f;", t)
 		return err
 	case string:
-		_, err := h.Write([]byte(strings.ToLower(t)))
+		_, err := fmt.Fprintf(h, "s%d:%s", len(t), strings.ToLower(t))
 		return err
 	case []interface{}:
+		if _, err := fmt.Fprintf(h, "a%d[", len(t)); err != nil {
+			return err
+		}
 		for _, i := range t {
 			err := hashValue(h, i)
 			if err != nil {
 				return err
 			}
 		}
-		return nil
+		_, err := h.Write([]byte("]"))
+		return err
 	case map[string]interface{}:
 		keys := make([]string, 0, len(t))
 		for k := range t {
 			keys = append(keys, k)
 		}
 		sort.Strings(keys)
+		if _, err := fmt.Fprintf(h, "m%d{", len(t)); err != nil {
+			return err
+		}
 		for _, k := range keys {
-			if _, err := h.Write([]byte(k)); err != nil {
+			if _, err := fmt.Fprintf(h, "k%d:%s=", len(k), k); err != nil {
 				return err
 			}
 			err := hashValue(h, t[k])
@@ -278,7 +403,8 @@ func hashValue(h io.Writer, v interface{}) error {
 				return err
 			}
 		}
-		return nil
+		_, err := h.Write([]byte("}"))
+		return err
 	default:
 		return fmt.Errorf("unsupported type for value during hash: %+v", v)
 	}
@@ -307,6 +433,44 @@ func TranslateToJsonRpcException(err error) error {
 		)
 	}
 
+	if HasErrorCode(err, ErrCodeResultSetTooLarge) {
+		var msg = "result set too large, use a narrower filter"
+		if se, ok := err.(StandardError); ok {
+			msg = se.DeepestMessage()
+		}
+		return NewErrJsonRpcExceptionInternal(
+			0,
+			JsonRpcErrorEvmLogsLargeRange,
+			msg,
+			err,
+			nil,
+		)
+	}
+
+	if HasErrorCode(err, ErrCodeJsonRpcRequestUnmarshal) {
+		return NewErrJsonRpcExceptionInternal(
+			0,
+			JsonRpcErrorParseException,
+			"failed to parse json-rpc request",
+			err,
+			nil,
+		)
+	}
+
+	if HasErrorCode(err, ErrCodeJsonRpcRequestInvalid) {
+		var msg = "invalid json-rpc request"
+		if se, ok := err.(StandardError); ok {
+			msg = se.DeepestMessage()
+		}
+		return NewErrJsonRpcExceptionInternal(
+			0,
+			JsonRpcErrorClientSideException,
+			msg,
+			err,
+			nil,
+		)
+	}
+
 	if HasErrorCode(
 		err,
 		ErrCodeAuthUnauthorized,
@@ -0,0 +1,102 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Hex strings up to this many digits are treated as quantities (they fit in 64 bits), longer ones such as
+// addresses and hashes are data whose leading zeros are significant.
+const maxHexQuantityDigits = 16
+
+// ResponsesEqual tells whether two json-rpc responses carry the same result and error, ignoring
+// whitespace, object key order, the case of hex strings and how a quantity is represented
+// (e.g. "0x0a", "0xa" and 10 are the same). Ids are not compared.
+func ResponsesEqual(a, b *JsonRpcResponse) (bool, error) {
+	if a == nil || b == nil {
+		return a == b, nil
+	}
+
+	a.RLock()
+	resultA, errA := a.Result, a.Error
+	a.RUnlock()
+	b.RLock()
+	resultB, errB := b.Result, b.Error
+	b.RUnlock()
+
+	if (errA == nil) != (errB == nil) {
+		return false, nil
+	}
+	if errA != nil {
+		if errA.Code != errB.Code || errA.Message != errB.Message ||
+			canonicalJsonValue(errA.Data) != canonicalJsonValue(errB.Data) {
+			return false, nil
+		}
+	}
+
+	return canonicalJsonEqual(resultA, resultB)
+}
+
+func canonicalJsonEqual(a, b []byte) (bool, error) {
+	ca, err := CanonicalJson(a)
+	if err != nil {
+		return false, err
+	}
+	cb, err := CanonicalJson(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ca, cb), nil
+}
+
+// CanonicalJson re-encodes a json value so that semantically equal values produce the same bytes:
+// object keys are sorted, hex strings are lowercased, and quantities (integers and short hex strings)
+// are written as hex without leading zeros. An empty input is the same as null.
+func CanonicalJson(raw []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return []byte("null"), nil
+	}
+	// Numbers are kept as-is to avoid losing precision of big integers
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	// encoding/json marshals map keys in sorted order
+	return json.Marshal(canonicalJsonValue(v))
+}
+
+func canonicalJsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		if strings.HasPrefix(t, "0x") || strings.HasPrefix(t, "0X") {
+			digits := t[2:]
+			if len(digits) > 0 && len(digits) <= maxHexQuantityDigits {
+				if n, err := strconv.ParseUint(digits, 16, 64); err == nil {
+					return "0x" + strconv.FormatUint(n, 16)
+				}
+			}
+			return strings.ToLower(t)
+		}
+		return t
+	case json.Number:
+		if n, err := strconv.ParseUint(t.String(), 10, 64); err == nil {
+			return "0x" + strconv.FormatUint(n, 16)
+		}
+		return t
+	case []interface{}:
+		for i := range t {
+			t[i] = canonicalJsonValue(t[i])
+		}
+		return t
+	case map[string]interface{}:
+		for k := range t {
+			t[k] = canonicalJsonValue(t[k])
+		}
+		return t
+	}
+	return v
+}
@@ -0,0 +1,71 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponsesEqual(t *testing.T) {
+	result := func(raw string) *JsonRpcResponse {
+		return &JsonRpcResponse{JSONRPC: "2.0", ID: 1, Result: []byte(raw)}
+	}
+	rpcError := func(code int, message, data string) *JsonRpcResponse {
+		return &JsonRpcResponse{JSONRPC: "2.0", ID: 1, Error: NewErrJsonRpcExceptionExternal(code, message, data)}
+	}
+	equal := func(t *testing.T, a, b *JsonRpcResponse) bool {
+		same, err := ResponsesEqual(a, b)
+		assert.NoError(t, err)
+		return same
+	}
+
+	t.Run("IgnoresKeyOrderAndWhitespace", func(t *testing.T) {
+		assert.True(t, equal(t,
+			result(`{"number":"0x1","hash":"0xabc","txs":[{"from":"0x1","to":"0x2"}]}`),
+			result(`{ "txs": [ {"to":"0x2", "from":"0x1"} ], "hash":"0xabc", "number":"0x1" }`),
+		))
+	})
+
+	t.Run("ArrayOrderIsSignificant", func(t *testing.T) {
+		assert.False(t, equal(t, result(`["0x1","0x2"]`), result(`["0x2","0x1"]`)))
+	})
+
+	t.Run("HexQuantitiesWithDifferentRepresentationAreEqual", func(t *testing.T) {
+		assert.True(t, equal(t, result(`"0x0a"`), result(`"0xa"`)))
+		assert.True(t, equal(t, result(`"0xA"`), result(`10`)))
+		assert.True(t, equal(t, result(`{"gasUsed":"0x00"}`), result(`{"gasUsed":0}`)))
+		assert.False(t, equal(t, result(`"0xa"`), result(`"0xb"`)))
+	})
+
+	t.Run("LeadingZerosOfLongHexDataAreSignificant", func(t *testing.T) {
+		assert.True(t, equal(t,
+			result(`"0x000000000000000000000000000000000000ABCD"`),
+			result(`"0x000000000000000000000000000000000000abcd"`),
+		))
+		assert.False(t, equal(t,
+			result(`"0x000000000000000000000000000000000000abcd"`),
+			result(`"0xabcd"`),
+		))
+	})
+
+	t.Run("BigNumbersKeepPrecision", func(t *testing.T) {
+		assert.False(t, equal(t, result(`123456789012345678901234567890`), result(`123456789012345678901234567891`)))
+	})
+
+	t.Run("ComparesErrorObjects", func(t *testing.T) {
+		assert.True(t, equal(t, rpcError(3, "execution reverted", "0x08c379a0"), rpcError(3, "execution reverted", "0x08C379A0")))
+		assert.False(t, equal(t, rpcError(3, "execution reverted", "0x08c379a0"), rpcError(-32000, "execution reverted", "0x08c379a0")))
+		assert.False(t, equal(t, rpcError(-32000, "missing trie node", ""), result(`null`)))
+	})
+
+	t.Run("EmptyResultEqualsNull", func(t *testing.T) {
+		assert.True(t, equal(t, result(``), result(`null`)))
+		assert.True(t, equal(t, nil, nil))
+		assert.False(t, equal(t, nil, result(`null`)))
+	})
+
+	t.Run("InvalidJsonIsAnError", func(t *testing.T) {
+		_, err := ResponsesEqual(result(`{"a":`), result(`{}`))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,82 @@
+package common
+
+import (
+	"reflect"
+	"strings"
+)
+
+// HashCollision is a group of requests that share a CacheHash although their params are not the same,
+// which means one of them could be served a cached response of the other.
+type HashCollision struct {
+	Hash     string
+	Requests []*JsonRpcRequest
+}
+
+// DetectHashCollisions groups requests by CacheHash and reports the groups whose normalized params differ.
+// Params are compared the way they are meant to be equivalent for caching (same block param normalization
+// and case-insensitive strings), so only accidental collisions of the hashing itself are reported.
+func DetectHashCollisions(reqs []*JsonRpcRequest) ([]HashCollision, error) {
+	var order []string
+	groups := make(map[string][]*JsonRpcRequest)
+	for _, req := range reqs {
+		if req == nil {
+			continue
+		}
+		hash, err := req.CacheHash()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := groups[hash]; !ok {
+			order = append(order, hash)
+		}
+		groups[hash] = append(groups[hash], req)
+	}
+
+	var collisions []HashCollision
+	for _, hash := range order {
+		group := groups[hash]
+		if len(group) < 2 {
+			continue
+		}
+		first := normalizedHashParams(group[0])
+		for _, req := range group[1:] {
+			if !reflect.DeepEqual(first, normalizedHashParams(req)) {
+				collisions = append(collisions, HashCollision{Hash: hash, Requests: group})
+				break
+			}
+		}
+	}
+
+	return collisions, nil
+}
+
+func normalizedHashParams(r *JsonRpcRequest) []interface{} {
+	r.RLock()
+	defer r.RUnlock()
+	params := r.cacheHashParams()
+	normalized := make([]interface{}, len(params))
+	for i, p := range params {
+		normalized[i] = lowercaseStrings(p)
+	}
+	return normalized
+}
+
+func lowercaseStrings(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		return strings.ToLower(t)
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i := range t {
+			out[i] = lowercaseStrings(t[i])
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			out[k] = lowercaseStrings(e)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectHashCollisions(t *testing.T) {
+	request := func(t *testing.T, method string, params ...interface{}) *JsonRpcRequest {
+		req, err := NewJsonRpcRequest(1, method, params)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return req
+	}
+
+	t.Run("EquivalentRequestsAreNotCollisions", func(t *testing.T) {
+		collisions, err := DetectHashCollisions([]*JsonRpcRequest{
+			request(t, "eth_getBalance", "0xABC", "0x01"),
+			request(t, "eth_getBalance", "0xabc", "0x1"),
+			request(t, "eth_getBalance", "0xabc", map[string]interface{}{"blockNumber": "0x1"}),
+			request(t, "eth_getBalance", "0xdef", "0x1"),
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, collisions)
+	})
+
+	t.Run("ConcatenatedStringsDoNotCollide", func(t *testing.T) {
+		// Values are length-prefixed, so a string containing a type tag cannot be read as two values
+		collisions, err := DetectHashCollisions([]*JsonRpcRequest{
+			request(t, "eth_getLogs", []interface{}{"as:b"}),
+			request(t, "eth_getLogs", []interface{}{"a", "b"}),
+			request(t, "eth_getLogs", []interface{}{"ab"}),
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, collisions)
+	})
+
+	t.Run("MapKeysAndNestingDoNotCollide", func(t *testing.T) {
+		collisions, err := DetectHashCollisions([]*JsonRpcRequest{
+			request(t, "eth_call", map[string]interface{}{"ab": "c"}),
+			request(t, "eth_call", map[string]interface{}{"a": "bc"}),
+			request(t, "eth_call", map[string]interface{}{"a": "b", "c": "d"}),
+			request(t, "eth_call", map[string]interface{}{"a": map[string]interface{}{"b": "c"}, "d": "e"}),
+			request(t, "eth_call", []interface{}{"a"}, []interface{}{"b"}),
+			request(t, "eth_call", []interface{}{"a", []interface{}{"b"}}),
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, collisions)
+	})
+
+	t.Run("NumericAndStringOneDoNotCollide", func(t *testing.T) {
+		// Upstreams treat the number 1 and the string "1" differently, values are hashed with a type tag
+		// so they never share a key.
+		numeric := request(t, "eth_getBlockByNumber", 1, false)
+		str := request(t, "eth_getBlockByNumber", "1", false)
+
+		collisions, err := DetectHashCollisions([]*JsonRpcRequest{numeric, str})
+		assert.NoError(t, err)
+		assert.Empty(t, collisions)
+
+		h1, err := numeric.CacheHash()
+		assert.NoError(t, err)
+		h2, err := str.CacheHash()
+		assert.NoError(t, err)
+		assert.NotEqual(t, h1, h2)
+	})
+
+	t.Run("UnsupportedParamIsAnError", func(t *testing.T) {
+		_, err := DetectHashCollisions([]*JsonRpcRequest{request(t, "eth_call", struct{}{})})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,233 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonRpcRequest_CacheHash(t *testing.T) {
+	t.Run("Eip1898BlockNumberObjectMatchesPlainNumber", func(t *testing.T) {
+		plain := &JsonRpcRequest{
+			Method: "eth_getBalance",
+			Params: []interface{}{"0xabc", "0x1b4"},
+		}
+		padded := &JsonRpcRequest{
+			Method: "eth_getBalance",
+			Params: []interface{}{"0xabc", "0x01b4"},
+		}
+		object := &JsonRpcRequest{
+			Method: "eth_getBalance",
+			Params: []interface{}{"0xabc", map[string]interface{}{"blockNumber": "0x01b4"}},
+		}
+
+		h1, err := plain.CacheHash()
+		assert.NoError(t, err)
+		h2, err := padded.CacheHash()
+		assert.NoError(t, err)
+		h3, err := object.CacheHash()
+		assert.NoError(t, err)
+
+		assert.Equal(t, h1, h2)
+		assert.Equal(t, h1, h3)
+	})
+
+	t.Run("Eip1898BlockHashObjectIsStable", func(t *testing.T) {
+		implicit := &JsonRpcRequest{
+			Method: "eth_call",
+			Params: []interface{}{
+				map[string]interface{}{"to": "0xabc"},
+				map[string]interface{}{"blockHash": "0x9A1F7E"},
+			},
+		}
+		explicit := &JsonRpcRequest{
+			Method: "eth_call",
+			Params: []interface{}{
+				map[string]interface{}{"to": "0xabc"},
+				map[string]interface{}{"blockHash": "0x9a1f7e", "requireCanonical": false},
+			},
+		}
+		canonical := &JsonRpcRequest{
+			Method: "eth_call",
+			Params: []interface{}{
+				map[string]interface{}{"to": "0xabc"},
+				map[string]interface{}{"blockHash": "0x9a1f7e", "requireCanonical": true},
+			},
+		}
+
+		h1, err := implicit.CacheHash()
+		assert.NoError(t, err)
+		h2, err := explicit.CacheHash()
+		assert.NoError(t, err)
+		h3, err := canonical.CacheHash()
+		assert.NoError(t, err)
+
+		assert.Equal(t, h1, h2)
+		assert.NotEqual(t, h1, h3)
+	})
+
+	t.Run("EthCallOmittedBlockDefaultsToLatest", func(t *testing.T) {
+		callObj := map[string]interface{}{"to": "0xabc", "data": "0x01"}
+		omitted := &JsonRpcRequest{
+			Method: "eth_call",
+			Params: []interface{}{callObj},
+		}
+		null := &JsonRpcRequest{
+			Method: "eth_call",
+			Params: []interface{}{callObj, nil},
+		}
+		latest := &JsonRpcRequest{
+			Method: "eth_call",
+			Params: []interface{}{callObj, "latest"},
+		}
+		withOverride := &JsonRpcRequest{
+			Method: "eth_call",
+			Params: []interface{}{callObj, "latest", map[string]interface{}{"0xabc": map[string]interface{}{"balance": "0x1"}}},
+		}
+
+		h1, err := omitted.CacheHash()
+		assert.NoError(t, err)
+		h2, err := null.CacheHash()
+		assert.NoError(t, err)
+		h3, err := latest.CacheHash()
+		assert.NoError(t, err)
+		h4, err := withOverride.CacheHash()
+		assert.NoError(t, err)
+
+		assert.Equal(t, h1, h3)
+		assert.Equal(t, h2, h3)
+		assert.NotEqual(t, h3, h4)
+		assert.Len(t, omitted.Params, 1, "hashing must not mutate request params")
+
+		for _, r := range []*JsonRpcRequest{omitted, latest} {
+			ref, num, err := ExtractEvmBlockReferenceFromRequest(r)
+			assert.NoError(t, err)
+			assert.Empty(t, ref, "latest block must not be cacheable")
+			assert.Zero(t, num)
+		}
+	})
+
+	t.Run("MandatoryBlockParamIsNotDefaulted", func(t *testing.T) {
+		omitted := &JsonRpcRequest{
+			Method: "eth_getBalance",
+			Params: []interface{}{"0xabc"},
+		}
+		latest := &JsonRpcRequest{
+			Method: "eth_getBalance",
+			Params: []interface{}{"0xabc", "latest"},
+		}
+
+		h1, err := omitted.CacheHash()
+		assert.NoError(t, err)
+		h2, err := latest.CacheHash()
+		assert.NoError(t, err)
+
+		assert.NotEqual(t, h1, h2)
+	})
+
+	t.Run("NumberAndStringOfSameTextDiffer", func(t *testing.T) {
+		numeric := &JsonRpcRequest{
+			Method: "eth_getBlockByNumber",
+			Params: []interface{}{1, false},
+		}
+		str := &JsonRpcRequest{
+			Method: "eth_getBlockByNumber",
+			Params: []interface{}{"1", false},
+		}
+		boolean := &JsonRpcRequest{
+			Method: "eth_getLogs",
+			Params: []interface{}{true},
+		}
+		boolString := &JsonRpcRequest{
+			Method: "eth_getLogs",
+			Params: []interface{}{"true"},
+		}
+
+		h1, err := numeric.CacheHash()
+		assert.NoError(t, err)
+		h2, err := str.CacheHash()
+		assert.NoError(t, err)
+		h3, err := boolean.CacheHash()
+		assert.NoError(t, err)
+		h4, err := boolString.CacheHash()
+		assert.NoError(t, err)
+
+		assert.NotEqual(t, h1, h2)
+		assert.NotEqual(t, h3, h4)
+	})
+}
+
+func TestJsonRpcRequest_CacheTTLClass(t *testing.T) {
+	assert.Equal(t, TTLClassImmutable, (&JsonRpcRequest{Method: "eth_chainId"}).CacheTTLClass())
+	assert.Equal(t, TTLClassImmutable, (&JsonRpcRequest{Method: "net_version"}).CacheTTLClass())
+	assert.Equal(t, TTLClassFinalized, (&JsonRpcRequest{Method: "eth_getBlockByNumber"}).CacheTTLClass())
+}
+
+func TestEvmTxResultTTLClass(t *testing.T) {
+	finalizedUpTo := func(n int64) func(int64) (bool, error) {
+		return func(blockNumber int64) (bool, error) { return blockNumber <= n, nil }
+	}
+	classify := func(method, result string) (TTLClass, bool) {
+		class, ok, err := EvmTxResultTTLClass(
+			&JsonRpcRequest{Method: method, Params: []interface{}{"0xabc"}},
+			&JsonRpcResponse{Result: []byte(result)},
+			finalizedUpTo(10),
+		)
+		assert.NoError(t, err)
+		return class, ok
+	}
+
+	for _, method := range []string{"eth_getTransactionByHash", "eth_getTransactionReceipt"} {
+		t.Run(method, func(t *testing.T) {
+			class, ok := classify(method, `{"hash":"0xabc","blockHash":"0xdef","blockNumber":"0x5"}`)
+			assert.True(t, ok)
+			assert.Equal(t, TTLClassImmutable, class)
+
+			class, _ = classify(method, `{"hash":"0xabc","blockHash":"0xdef","blockNumber":"0x14"}`)
+			assert.Equal(t, TTLClassFinalized, class)
+
+			class, _ = classify(method, `{"hash":"0xabc","blockHash":null,"blockNumber":null}`)
+			assert.Equal(t, TTLClassUncacheable, class)
+		})
+	}
+
+	t.Run("OtherMethodsAreNotClassified", func(t *testing.T) {
+		_, ok := classify("eth_getBlockByNumber", `{"hash":"0xdef","number":"0x5"}`)
+		assert.False(t, ok)
+	})
+}
+
+func TestNewJsonRpcRequest(t *testing.T) {
+	t.Run("RejectsEmptyMethod", func(t *testing.T) {
+		jrq, err := NewJsonRpcRequest(1, "", []interface{}{"0x1"})
+		assert.Nil(t, jrq)
+		assert.True(t, HasErrorCode(err, "ErrJsonRpcRequestUnresolvableMethod"), err)
+	})
+
+	t.Run("NormalizesNilParamsToEmptySlice", func(t *testing.T) {
+		jrq, err := NewJsonRpcRequest(7, "eth_blockNumber", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "2.0", jrq.JSONRPC)
+		assert.Equal(t, 7, jrq.ID)
+		assert.NotNil(t, jrq.Params)
+		assert.Empty(t, jrq.Params)
+
+		body, err := sonic.Marshal(jrq)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":7,"method":"eth_blockNumber","params":[]}`, string(body))
+	})
+
+	t.Run("HashIsSameAsParsedRequest", func(t *testing.T) {
+		built, err := NewJsonRpcRequest(1, "eth_getBalance", []interface{}{"0xABC", "latest"})
+		assert.NoError(t, err)
+		parsed, err := NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":2,"method":"eth_getBalance","params":["0xabc","latest"]}`)).JsonRpcRequest()
+		assert.NoError(t, err)
+
+		builtHash, err := built.CacheHash()
+		assert.NoError(t, err)
+		parsedHash, err := parsed.CacheHash()
+		assert.NoError(t, err)
+		assert.Equal(t, parsedHash, builtHash)
+	})
+}
@@ -0,0 +1,73 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// ValidateNoDuplicateJsonKeys returns an error naming the first object key that appears more than once
+// at the same level, e.g. `duplicate key "to" at params.0`. Decoders silently keep one of the values
+// which makes the meaning of such payloads ambiguous.
+func ValidateNoDuplicateJsonKeys(raw []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := checkJsonValueKeys(dec, ""); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err == nil {
+		return fmt.Errorf("unexpected data after top-level json value")
+	}
+	return nil
+}
+
+func checkJsonValueKeys(dec *json.Decoder, path string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		seen := map[string]bool{}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := keyTok.(string)
+			if seen[key] {
+				if path == "" {
+					return fmt.Errorf("duplicate key %q", key)
+				}
+				return fmt.Errorf("duplicate key %q at %s", key, path)
+			}
+			seen[key] = true
+			if err := checkJsonValueKeys(dec, joinJsonPath(path, key)); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for i := 0; dec.More(); i++ {
+			if err := checkJsonValueKeys(dec, joinJsonPath(path, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Consume the closing delimiter
+	_, err = dec.Token()
+	return err
+}
+
+func joinJsonPath(path, segment string) string {
+	if path == "" {
+		return segment
+	}
+	return path + "." + segment
+}
@@ -0,0 +1,29 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateNoDuplicateJsonKeys(t *testing.T) {
+	t.Run("RejectsDuplicateKeyInParamsObject", func(t *testing.T) {
+		err := ValidateNoDuplicateJsonKeys([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[{"to":"a","to":"b"},"latest"]}`))
+		assert.EqualError(t, err, `duplicate key "to" at params.0`)
+	})
+
+	t.Run("RejectsDuplicateTopLevelKey", func(t *testing.T) {
+		err := ValidateNoDuplicateJsonKeys([]byte(`{"method":"eth_call","method":"eth_sendRawTransaction"}`))
+		assert.EqualError(t, err, `duplicate key "method"`)
+	})
+
+	t.Run("AllowsSameKeyInSiblingObjects", func(t *testing.T) {
+		err := ValidateNoDuplicateJsonKeys([]byte(`{"params":[{"to":"a","data":{"to":1}},{"to":"b"}]}`))
+		assert.NoError(t, err)
+	})
+
+	t.Run("RejectsMalformedJson", func(t *testing.T) {
+		assert.Error(t, ValidateNoDuplicateJsonKeys([]byte(`{"params":[`)))
+		assert.Error(t, ValidateNoDuplicateJsonKeys([]byte(`{"a":1} {"b":2}`)))
+	})
+}
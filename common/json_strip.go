@@ -0,0 +1,63 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// StripJsonFields removes fields from a json value, each field is a dot separated path of object keys.
+// Arrays met along the path are traversed, so "logs.timestamp" removes "timestamp" from every log.
+// The input is returned as-is when none of the fields exist.
+func StripJsonFields(raw []byte, fields []string) ([]byte, error) {
+	if len(fields) == 0 || len(raw) == 0 {
+		return raw, nil
+	}
+
+	// Numbers are kept as-is to avoid losing precision of big integers
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	stripped := false
+	for _, f := range fields {
+		if f == "" {
+			continue
+		}
+		if stripJsonPath(v, strings.Split(f, ".")) {
+			stripped = true
+		}
+	}
+	if !stripped {
+		return raw, nil
+	}
+
+	return json.Marshal(v)
+}
+
+func stripJsonPath(v interface{}, path []string) bool {
+	switch t := v.(type) {
+	case []interface{}:
+		stripped := false
+		for _, item := range t {
+			if stripJsonPath(item, path) {
+				stripped = true
+			}
+		}
+		return stripped
+	case map[string]interface{}:
+		child, ok := t[path[0]]
+		if !ok {
+			return false
+		}
+		if len(path) == 1 {
+			delete(t, path[0])
+			return true
+		}
+		return stripJsonPath(child, path[1:])
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripJsonFields(t *testing.T) {
+	t.Run("StripsTopLevelAndNestedFields", func(t *testing.T) {
+		out, err := StripJsonFields(
+			[]byte(`{"number":"0x1","timestamp":"0x5","_meta":{"servedBy":"node-7"},"txs":[{"hash":"0xa","seenAt":1},{"hash":"0xb","seenAt":2}]}`),
+			[]string{"_meta", "txs.seenAt"},
+		)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"number":"0x1","timestamp":"0x5","txs":[{"hash":"0xa"},{"hash":"0xb"}]}`, string(out))
+	})
+
+	t.Run("TraversesArrayResults", func(t *testing.T) {
+		out, err := StripJsonFields([]byte(`[{"logIndex":"0x0","meta":1},{"logIndex":"0x1"}]`), []string{"meta"})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `[{"logIndex":"0x0"},{"logIndex":"0x1"}]`, string(out))
+	})
+
+	t.Run("KeepsInputWhenNothingIsStripped", func(t *testing.T) {
+		raw := []byte(`{"b":1, "a":"0x1"}`)
+		out, err := StripJsonFields(raw, []string{"missing", "a.b"})
+		assert.NoError(t, err)
+		assert.Equal(t, string(raw), string(out))
+
+		out, err = StripJsonFields([]byte(`"0x1"`), []string{"meta"})
+		assert.NoError(t, err)
+		assert.Equal(t, `"0x1"`, string(out))
+	})
+
+	t.Run("PreservesLargeNumbers", func(t *testing.T) {
+		out, err := StripJsonFields([]byte(`{"value":123456789012345678901234567890,"meta":1}`), []string{"meta"})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"value":123456789012345678901234567890}`, string(out))
+	})
+
+	t.Run("InvalidJson", func(t *testing.T) {
+		_, err := StripJsonFields([]byte(`{"a":`), []string{"a"})
+		assert.Error(t, err)
+	})
+}
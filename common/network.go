@@ -1,11 +1,33 @@
 package common
 
+import (
+	"context"
+	"strings"
+)
+
 type NetworkArchitecture string
 
 const (
-	ArchitectureEvm NetworkArchitecture = "evm"
+	ArchitectureEvm    NetworkArchitecture = "evm"
+	ArchitectureSolana NetworkArchitecture = "solana"
 )
 
+// ParseNetworkId splits a network id (e.g. "evm:1" or "solana:<genesis-hash>") into its architecture
+// and the architecture-specific reference (chain id for evm).
+func ParseNetworkId(id string) (NetworkArchitecture, string, error) {
+	arch, ref, found := strings.Cut(id, ":")
+	if !found || arch == "" || ref == "" {
+		return "", "", NewErrInvalidNetworkId(id)
+	}
+
+	switch NetworkArchitecture(arch) {
+	case ArchitectureEvm, ArchitectureSolana:
+		return NetworkArchitecture(arch), ref, nil
+	}
+
+	return "", "", NewErrUnknownNetworkArchitecture(NetworkArchitecture(arch))
+}
+
 type Network interface {
 	Id() string
 	Architecture() NetworkArchitecture
@@ -13,3 +35,27 @@ type Network interface {
 	EvmChainId() (int64, error)
 	EvmIsBlockFinalized(blockNumber int64) (bool, error)
 }
+
+// BlockResolver centralizes head/finalized tracking of networks, so that features like
+// finality-aware caching do not need to know where block heights come from.
+type BlockResolver interface {
+	// ResolveTag converts a block tag (e.g. "latest", "finalized") or hex number to a concrete block number.
+	ResolveTag(ctx context.Context, networkId, tag string) (int64, error)
+	// FinalizedHeight returns the highest block number known to be finalized.
+	FinalizedHeight(networkId string) (int64, error)
+	// HeadHeight returns the highest known block number.
+	HeadHeight(networkId string) (int64, error)
+}
+
+// EvmIsBlockFinalizedByResolver reports whether a block is finalized according to the resolver,
+// unknown finality (e.g. before the first probe) is treated as not finalized.
+func EvmIsBlockFinalizedByResolver(r BlockResolver, networkId string, blockNumber int64) (bool, error) {
+	fin, err := r.FinalizedHeight(networkId)
+	if err != nil {
+		if HasErrorCode(err, ErrCodeFinalizedBlockUnavailable) {
+			return false, nil
+		}
+		return false, err
+	}
+	return blockNumber <= fin, nil
+}
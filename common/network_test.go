@@ -0,0 +1,44 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNetworkId(t *testing.T) {
+	t.Run("ValidIds", func(t *testing.T) {
+		cases := map[string]struct {
+			arch NetworkArchitecture
+			ref  string
+		}{
+			"evm:1":     {ArchitectureEvm, "1"},
+			"evm:42161": {ArchitectureEvm, "42161"},
+			"solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdpKuc147dw2N9d": {ArchitectureSolana, "5eykt4UsFv8P8NJdTREpY1vzqKqZKvdpKuc147dw2N9d"},
+			// Only the first colon separates the architecture
+			"solana:mainnet:beta": {ArchitectureSolana, "mainnet:beta"},
+		}
+
+		for id, expected := range cases {
+			arch, ref, err := ParseNetworkId(id)
+			assert.NoError(t, err, id)
+			assert.Equal(t, expected.arch, arch, id)
+			assert.Equal(t, expected.ref, ref, id)
+		}
+	})
+
+	t.Run("MalformedIds", func(t *testing.T) {
+		for _, id := range []string{"", "evm", "evm:", ":1", "1"} {
+			_, _, err := ParseNetworkId(id)
+			assert.True(t, HasErrorCode(err, ErrCodeInvalidNetworkId), "%q: %v", id, err)
+		}
+	})
+
+	t.Run("UnknownArchitecture", func(t *testing.T) {
+		_, _, err := ParseNetworkId("cosmos:hub-4")
+		assert.True(t, HasErrorCode(err, "ErrUnknownNetworkArchitecture"), err)
+
+		_, _, err = ParseNetworkId("EVM:1")
+		assert.Error(t, err)
+	})
+}
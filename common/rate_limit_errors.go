@@ -0,0 +1,92 @@
+package common
+
+import (
+	"strings"
+	"sync"
+)
+
+// RateLimitErrorMatcher recognizes a json-rpc error body that means the upstream rate limited the request,
+// for providers that respond with HTTP 200 (or other non-429 status) instead of 429.
+type RateLimitErrorMatcher struct {
+	// Json-rpc error code to match, 0 matches any code
+	Code int `yaml:"code" json:"code"`
+	// Case-insensitive substring of the error message to match, empty matches any message
+	Message string `yaml:"message" json:"message"`
+}
+
+func (m *RateLimitErrorMatcher) Matches(code int, message string) bool {
+	if m.Code != 0 && m.Code != code {
+		return false
+	}
+	if m.Message != "" && !strings.Contains(strings.ToLower(message), strings.ToLower(m.Message)) {
+		return false
+	}
+	return m.Code != 0 || m.Message != ""
+}
+
+// Matchers that apply to any upstream regardless of its vendor
+var genericRateLimitErrorMatchers = []*RateLimitErrorMatcher{
+	{Message: "has exceeded"},
+	{Message: "exceeded the quota"},
+	{Message: "under too much load"},
+	{Message: "rate limit exceeded"},
+	{Message: "too many requests"},
+	{Code: -32005, Message: "rate limit"},
+}
+
+var (
+	vendorRateLimitErrorMatchersMu sync.RWMutex
+	vendorRateLimitErrorMatchers   = map[string][]*RateLimitErrorMatcher{
+		"alchemy": {
+			// https://docs.alchemy.com/reference/error-reference
+			{Code: -32005},
+			{Message: "compute units per second"},
+		},
+		"infura": {
+			{Message: "project ID request rate exceeded"},
+			{Message: "daily request count exceeded"},
+		},
+		"quicknode": {
+			{Message: "request limit reached"},
+		},
+		"drpc": {
+			{Message: "ratelimited"},
+		},
+		"blastapi": {
+			{Message: "rate limit reached"},
+		},
+	}
+)
+
+// RegisterRateLimitErrorMatchers adds matchers that are only checked for upstreams of the given vendor.
+func RegisterRateLimitErrorMatchers(vendorName string, matchers ...*RateLimitErrorMatcher) {
+	vendorRateLimitErrorMatchersMu.Lock()
+	defer vendorRateLimitErrorMatchersMu.Unlock()
+	vendorRateLimitErrorMatchers[vendorName] = append(vendorRateLimitErrorMatchers[vendorName], matchers...)
+}
+
+// IsVendorRateLimitError checks the error against matchers registered for the vendor only.
+func IsVendorRateLimitError(vendorName string, code int, message string) bool {
+	if vendorName == "" {
+		return false
+	}
+
+	vendorRateLimitErrorMatchersMu.RLock()
+	defer vendorRateLimitErrorMatchersMu.RUnlock()
+	for _, m := range vendorRateLimitErrorMatchers[vendorName] {
+		if m.Matches(code, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRateLimitError checks the error against the generic matchers and the ones registered for the vendor.
+func IsRateLimitError(vendorName string, code int, message string) bool {
+	for _, m := range genericRateLimitErrorMatchers {
+		if m.Matches(code, message) {
+			return true
+		}
+	}
+	return IsVendorRateLimitError(vendorName, code, message)
+}
@@ -0,0 +1,36 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitErrorMatchers(t *testing.T) {
+	t.Run("AlchemyCodeIsRecognizedOnlyForAlchemy", func(t *testing.T) {
+		assert.True(t, IsRateLimitError("alchemy", -32005, "Your app has reached its limit"))
+		assert.False(t, IsRateLimitError("", -32005, "Your app has reached its limit"))
+	})
+
+	t.Run("VendorMessageIsCaseInsensitive", func(t *testing.T) {
+		assert.True(t, IsVendorRateLimitError("infura", -32603, "Project ID Request Rate Exceeded"))
+		assert.False(t, IsVendorRateLimitError("quicknode", -32603, "project ID request rate exceeded"))
+	})
+
+	t.Run("GenericMatchersApplyToAnyVendor", func(t *testing.T) {
+		assert.True(t, IsRateLimitError("", -32000, "Too Many Requests, slow down"))
+		assert.True(t, IsRateLimitError("unknown", -32005, "request rate limit reached"))
+		assert.False(t, IsRateLimitError("", -32005, "execution reverted"))
+	})
+
+	t.Run("RegisteredMatchersExtendVendor", func(t *testing.T) {
+		assert.False(t, IsVendorRateLimitError("acme", -32099, "slow down please"))
+		RegisterRateLimitErrorMatchers("acme", &RateLimitErrorMatcher{Code: -32099, Message: "slow down"})
+		assert.True(t, IsVendorRateLimitError("acme", -32099, "slow down please"))
+		assert.False(t, IsVendorRateLimitError("acme", -32000, "slow down please"))
+	})
+
+	t.Run("EmptyMatcherNeverMatches", func(t *testing.T) {
+		assert.False(t, (&RateLimitErrorMatcher{}).Matches(-32005, "rate limit"))
+	})
+}
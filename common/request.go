@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/rs/zerolog"
@@ -33,6 +35,57 @@ type RequestDirectives struct {
 	// Value can use "*" star char as a wildcard to target multiple upstreams.
 	// For example "alchemy" or "my-own-*", etc.
 	UseUpstream string
+
+	// Instruct the proxy to skip specific upstream(s) for this request, e.g. to compare results without a
+	// suspicious upstream. Value can use "*" star char as a wildcard, it is only honored for admin-authenticated requests.
+	ExcludeUpstream string
+
+	// Instruct the proxy about the importance of the request ("high", "normal" or "low"),
+	// when an upstream is saturated higher priority requests are sent first.
+	// When not provided it is derived from the method (e.g. eth_getLogs and traces are "low").
+	Priority string
+
+	// Instruct the proxy to keep polling eth_getTransactionReceipt for up to this duration (e.g. "30s")
+	// until the receipt is available, instead of returning null for a not yet mined tx.
+	WaitForReceipt string
+
+	// Instruct the proxy to return the logs it could fetch when some sub-ranges of a split eth_getLogs
+	// time out, instead of failing the whole request. Missing ranges are listed in response headers.
+	AllowPartialLogs bool
+
+	// Instruct the proxy to send the request to this many upstreams and require this many of them to agree
+	// when the network uses consensus, e.g. "5" and "3" for a 3-of-5 read. Both are only honored for
+	// admin-authenticated requests and within the network's consensus maxRequest* bounds.
+	ConsensusParticipants string
+	ConsensusAgreements   string
+}
+
+type RequestPriority int
+
+const (
+	RequestPriorityLow    RequestPriority = -1
+	RequestPriorityNormal RequestPriority = 0
+	RequestPriorityHigh   RequestPriority = 1
+)
+
+// Heavy methods mostly used by background jobs (indexers, backfills) which can wait behind interactive calls.
+var lowPriorityMethods = []string{
+	"eth_getLogs",
+	"trace_*",
+	"debug_*",
+	"arbtrace_*",
+}
+
+func ParseRequestPriority(value string) (RequestPriority, bool) {
+	switch strings.ToLower(value) {
+	case "high":
+		return RequestPriorityHigh, true
+	case "normal":
+		return RequestPriorityNormal, true
+	case "low":
+		return RequestPriorityLow, true
+	}
+	return RequestPriorityNormal, false
 }
 
 type NormalizedRequest struct {
@@ -50,6 +103,22 @@ type NormalizedRequest struct {
 
 	lastValidResponse *NormalizedResponse
 	lastUpstream      Upstream
+
+	// Method-specific or client-requested timeout resolved by the server, zero when the default applies
+	timeout time.Duration
+
+	attemptsMu sync.Mutex
+	attempts   []UpstreamAttempt
+}
+
+// UpstreamAttempt records one upstream tried while serving a request, outcome is "success", "error" or "skipped".
+type UpstreamAttempt struct {
+	Upstream   string `json:"upstream"`
+	Outcome    string `json:"outcome"`
+	Error      string `json:"error,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	Empty      bool   `json:"empty,omitempty"`
+	DurationMs int64  `json:"durationMs"`
 }
 
 type UniqueRequestKey struct {
@@ -85,6 +154,25 @@ func (r *NormalizedRequest) LastUpstream() Upstream {
 	return r.lastUpstream
 }
 
+func (r *NormalizedRequest) AddUpstreamAttempt(attempt UpstreamAttempt) {
+	if r == nil {
+		return
+	}
+	r.attemptsMu.Lock()
+	defer r.attemptsMu.Unlock()
+	r.attempts = append(r.attempts, attempt)
+}
+
+// UpstreamAttempts returns the upstreams tried so far in the order they were tried.
+func (r *NormalizedRequest) UpstreamAttempts() []UpstreamAttempt {
+	if r == nil {
+		return nil
+	}
+	r.attemptsMu.Lock()
+	defer r.attemptsMu.Unlock()
+	return append([]UpstreamAttempt(nil), r.attempts...)
+}
+
 func (r *NormalizedRequest) SetLastValidResponse(response *NormalizedResponse) {
 	if r == nil {
 		return
@@ -185,16 +273,27 @@ func (r *NormalizedRequest) ApplyDirectivesFromHttp(
 	queryArgs *fasthttp.Args,
 ) {
 	drc := &RequestDirectives{
-		RetryEmpty:    string(headers.Peek("X-ERPC-Retry-Empty")) != "false",
-		RetryPending:  string(headers.Peek("X-ERPC-Retry-Pending")) != "false",
-		SkipCacheRead: string(headers.Peek("X-ERPC-Skip-Cache-Read")) == "true",
-		UseUpstream:   string(headers.Peek("X-ERPC-Use-Upstream")),
+		RetryEmpty:       string(headers.Peek("X-ERPC-Retry-Empty")) != "false",
+		RetryPending:     string(headers.Peek("X-ERPC-Retry-Pending")) != "false",
+		SkipCacheRead:    string(headers.Peek("X-ERPC-Skip-Cache-Read")) == "true",
+		UseUpstream:      string(headers.Peek("X-ERPC-Use-Upstream")),
+		ExcludeUpstream:  string(headers.Peek("X-ERPC-Exclude-Upstream")),
+		Priority:         string(headers.Peek("X-ERPC-Priority")),
+		WaitForReceipt:   string(headers.Peek("X-ERPC-Wait-For-Receipt")),
+		AllowPartialLogs: string(headers.Peek("X-ERPC-Allow-Partial-Logs")) == "true",
+
+		ConsensusParticipants: string(headers.Peek("X-ERPC-Consensus-Participants")),
+		ConsensusAgreements:   string(headers.Peek("X-ERPC-Consensus-Agreements")),
 	}
 
 	if useUpstream := string(queryArgs.Peek("use-upstream")); useUpstream != "" {
 		drc.UseUpstream = useUpstream
 	}
 
+	if excludeUpstream := string(queryArgs.Peek("exclude-upstream")); excludeUpstream != "" {
+		drc.ExcludeUpstream = excludeUpstream
+	}
+
 	if retryEmpty := string(queryArgs.Peek("retry-empty")); retryEmpty != "" {
 		drc.RetryEmpty = retryEmpty != "false"
 	}
@@ -207,9 +306,45 @@ func (r *NormalizedRequest) ApplyDirectivesFromHttp(
 		drc.SkipCacheRead = skipCacheRead != "false"
 	}
 
+	if priority := string(queryArgs.Peek("priority")); priority != "" {
+		drc.Priority = priority
+	}
+
+	if waitForReceipt := string(queryArgs.Peek("wait-for-receipt")); waitForReceipt != "" {
+		drc.WaitForReceipt = waitForReceipt
+	}
+
+	if allowPartialLogs := string(queryArgs.Peek("allow-partial-logs")); allowPartialLogs != "" {
+		drc.AllowPartialLogs = allowPartialLogs != "false"
+	}
+
 	r.directives = drc
 }
 
+// Priority returns the explicitly requested priority, or a default one based on the method.
+func (r *NormalizedRequest) Priority() RequestPriority {
+	if r == nil {
+		return RequestPriorityNormal
+	}
+	if r.directives != nil && r.directives.Priority != "" {
+		if p, ok := ParseRequestPriority(r.directives.Priority); ok {
+			return p
+		}
+	}
+
+	method, err := r.Method()
+	if err != nil {
+		return RequestPriorityNormal
+	}
+	for _, m := range lowPriorityMethods {
+		if WildcardMatch(m, method) {
+			return RequestPriorityLow
+		}
+	}
+
+	return RequestPriorityNormal
+}
+
 func (r *NormalizedRequest) SkipCacheRead() bool {
 	if r == nil {
 		return false
@@ -220,6 +355,66 @@ func (r *NormalizedRequest) SkipCacheRead() bool {
 	return r.directives.SkipCacheRead
 }
 
+// ExcludesUpstreams tells whether some upstreams must not serve this request, in which case a cached
+// response or the response of a similar in-flight request might come from one of them and is not used.
+func (r *NormalizedRequest) ExcludesUpstreams() bool {
+	if r == nil || r.directives == nil {
+		return false
+	}
+	return r.directives.ExcludeUpstream != ""
+}
+
+// OverridesConsensus tells whether the request asks for its own consensus participants or agreements, in which
+// case a cached response or the response of a similar in-flight request was not agreed on the way it asks for.
+func (r *NormalizedRequest) OverridesConsensus() bool {
+	if r == nil || r.directives == nil {
+		return false
+	}
+	return r.directives.ConsensusParticipants != "" || r.directives.ConsensusAgreements != ""
+}
+
+// WaitForReceipt returns how long to wait for a tx receipt to become available, zero when not requested.
+func (r *NormalizedRequest) WaitForReceipt() time.Duration {
+	if r == nil || r.directives == nil || r.directives.WaitForReceipt == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(r.directives.WaitForReceipt)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// WithTimeout records a timeout overriding the default for this request, so that failsafe timeouts of
+// network and upstreams shorter than it are extended accordingly.
+func (r *NormalizedRequest) WithTimeout(timeout time.Duration) *NormalizedRequest {
+	r.timeout = timeout
+	return r
+}
+
+// Timeout returns the timeout overriding the default for this request, zero when not overridden.
+func (r *NormalizedRequest) Timeout() time.Duration {
+	if r == nil {
+		return 0
+	}
+	return r.timeout
+}
+
+func (r *NormalizedRequest) WithDirectives(directives *RequestDirectives) *NormalizedRequest {
+	r.directives = directives
+	return r
+}
+
+// Clone returns a copy of the directives, so that requests derived from another one (e.g. items of an
+// aggregate) can be adjusted independently, for example pinned by routing rules.
+func (d *RequestDirectives) Clone() *RequestDirectives {
+	if d == nil {
+		return nil
+	}
+	c := *d
+	return &c
+}
+
 func (r *NormalizedRequest) Directives() *RequestDirectives {
 	if r == nil {
 		return nil
@@ -250,12 +445,16 @@ func (r *NormalizedRequest) JsonRpcRequest() (*JsonRpcRequest, error) {
 
 	rpcReq := new(JsonRpcRequest)
 	if err := sonic.Unmarshal(r.body, rpcReq); err != nil {
+		// Bodies that are json but of the wrong shape are invalid requests rather than parse errors
+		if sonic.Valid(r.body) {
+			if verr := validateJsonRpcEnvelope(r.body); verr != nil {
+				return nil, verr
+			}
+		}
 		return nil, NewErrJsonRpcRequestUnmarshal(err)
 	}
-
-	method := rpcReq.Method
-	if method == "" {
-		return nil, NewErrJsonRpcRequestUnresolvableMethod(rpcReq)
+	if err := validateJsonRpcRequest(rpcReq); err != nil {
+		return nil, err
 	}
 
 	if rpcReq.JSONRPC == "" {
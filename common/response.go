@@ -2,6 +2,7 @@ package common
 
 import (
 	"sync"
+	"time"
 
 	"github.com/bytedance/sonic"
 )
@@ -14,11 +15,16 @@ type NormalizedResponse struct {
 	err     error
 
 	fromCache bool
+	cachedAt  time.Time
+	stale     bool
 	attempts  int
 	retries   int
 	hedges    int
 	upstream  Upstream
 
+	// Block ranges whose data is missing from a partial result, e.g. "0x1-0x3e8"
+	missingRanges []string
+
 	jsonRpcResponse *JsonRpcResponse
 	evmBlockNumber  int64
 }
@@ -102,6 +108,46 @@ func (r *NormalizedResponse) WithFromCache(fromCache bool) *NormalizedResponse {
 	return r
 }
 
+func (r *NormalizedResponse) WithCachedAt(cachedAt time.Time) *NormalizedResponse {
+	r.cachedAt = cachedAt
+	return r
+}
+
+// WithStale flags a response served from an expired cache entry because upstreams failed.
+func (r *NormalizedResponse) WithStale(stale bool) *NormalizedResponse {
+	r.stale = stale
+	return r
+}
+
+func (r *NormalizedResponse) IsStale() bool {
+	return r != nil && r.stale
+}
+
+// WithMissingRanges flags a partial result, listing block ranges that could not be fetched.
+func (r *NormalizedResponse) WithMissingRanges(ranges []string) *NormalizedResponse {
+	r.missingRanges = ranges
+	return r
+}
+
+func (r *NormalizedResponse) IsPartial() bool {
+	return r != nil && len(r.missingRanges) > 0
+}
+
+func (r *NormalizedResponse) MissingRanges() []string {
+	if r == nil {
+		return nil
+	}
+	return r.missingRanges
+}
+
+// CacheAge returns how long ago the response was stored in cache, or zero if unknown.
+func (r *NormalizedResponse) CacheAge() time.Duration {
+	if r == nil || r.cachedAt.IsZero() {
+		return 0
+	}
+	return time.Since(r.cachedAt)
+}
+
 func (r *NormalizedResponse) WithBody(body []byte) *NormalizedResponse {
 	r.body = body
 	return r
@@ -0,0 +1,94 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const tlsEnvValuePrefix = "env:"
+
+// NewTLSClientConfig builds a client-side tls config, loading the client certificate (for mutual TLS)
+// and the CA either from files or from PEM values.
+func NewTLSClientConfig(cfg *TLSConfig) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify, // #nosec G402
+	}
+
+	certFile, err := resolveTLSValue("certFile", cfg.CertFile)
+	if err != nil {
+		return nil, err
+	}
+	keyFile, err := resolveTLSValue("keyFile", cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	certPem, err := resolveTLSValue("certPem", cfg.CertPem)
+	if err != nil {
+		return nil, err
+	}
+	keyPem, err := resolveTLSValue("keyPem", cfg.KeyPem)
+	if err != nil {
+		return nil, err
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("both certFile and keyFile must be provided for client certificate")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client cert/key pair: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	} else if certPem != "" || keyPem != "" {
+		if certPem == "" || keyPem == "" {
+			return nil, fmt.Errorf("both certPem and keyPem must be provided for client certificate")
+		}
+		cert, err := tls.X509KeyPair([]byte(certPem), []byte(keyPem))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client cert/key pair: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	caFile, err := resolveTLSValue("caFile", cfg.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	caPem, err := resolveTLSValue("caPem", cfg.CAPem)
+	if err != nil {
+		return nil, err
+	}
+
+	if caFile != "" {
+		caCert, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		caPem = string(caCert)
+	}
+	if caPem != "" {
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM([]byte(caPem)) {
+			return nil, fmt.Errorf("failed to parse CA certificate, no valid PEM blocks found")
+		}
+		config.RootCAs = caCertPool
+	}
+
+	return config, nil
+}
+
+func resolveTLSValue(field, value string) (string, error) {
+	if !strings.HasPrefix(value, tlsEnvValuePrefix) {
+		return value, nil
+	}
+	name := strings.TrimPrefix(value, tlsEnvValuePrefix)
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return "", fmt.Errorf("environment variable %s referenced by tls %s is not set", name, field)
+	}
+	return v, nil
+}
@@ -7,7 +7,6 @@ import (
 	"strings"
 
 	"github.com/IGLOU-EU/go-wildcard/v2"
-	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
 // HexToUint64 converts a hexadecimal string to its decimal representation as a string.
@@ -42,7 +41,7 @@ func NormalizeHex(value interface{}) (string, error) {
 		// Check if blockNumber is already in hex format
 		if strings.HasPrefix(bn, "0x") {
 			// Convert hex to integer to remove 0 padding
-			value, err := hexutil.DecodeUint64(bn)
+			value, err := strconv.ParseUint(bn[2:], 16, 64)
 			if err != nil {
 				return "", err
 			}
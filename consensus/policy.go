@@ -1 +1,100 @@
 package consensus
+
+import (
+	"sort"
+)
+
+// Vote is the answer of a single participant, participants whose results are equivalent must share the same Key.
+// Votes with empty Key (e.g. participant failed) do not support any result but still count towards total weight.
+type Vote struct {
+	Participant string
+	Weight      float64
+	Key         string
+}
+
+// Outcome is a result along with the participants that agreed on it.
+type Outcome struct {
+	Key          string
+	Weight       float64
+	Share        float64
+	Participants []string
+}
+
+// Evaluate groups votes by their Key and returns the result with highest supporting weight,
+// ok is false when that result's share of total weight does not exceed the threshold (between 0 and 1)
+// or is tied with another result.
+func Evaluate(votes []*Vote, threshold float64) (best *Outcome, ok bool) {
+	var total float64
+	groups := map[string]*Outcome{}
+	for _, v := range votes {
+		total += v.Weight
+		if v.Key == "" {
+			continue
+		}
+		g, exists := groups[v.Key]
+		if !exists {
+			g = &Outcome{Key: v.Key}
+			groups[v.Key] = g
+		}
+		g.Weight += v.Weight
+		g.Participants = append(g.Participants, v.Participant)
+	}
+	if total <= 0 || len(groups) == 0 {
+		return nil, false
+	}
+
+	outcomes := make([]*Outcome, 0, len(groups))
+	for _, g := range groups {
+		g.Share = g.Weight / total
+		outcomes = append(outcomes, g)
+	}
+	sort.Slice(outcomes, func(i, j int) bool {
+		return outcomes[i].Weight > outcomes[j].Weight
+	})
+
+	best = outcomes[0]
+	if len(outcomes) > 1 && outcomes[1].Weight == best.Weight {
+		return best, false
+	}
+
+	return best, best.Share > threshold
+}
+
+// EvaluateHighest is for monotonic results whose participants may legitimately differ, e.g. some being a block ahead.
+// A vote supports every value up to its own Key, and the highest value whose supporting share of total weight exceeds
+// the threshold is returned, so a single outlier far ahead cannot win on its own. When no value reaches the threshold
+// the lowest one (supported by every non-empty vote) is returned with ok false.
+func EvaluateHighest(votes []*Vote, threshold float64, less func(a, b string) bool) (best *Outcome, ok bool) {
+	var total float64
+	var keys []string
+	seen := map[string]bool{}
+	for _, v := range votes {
+		total += v.Weight
+		if v.Key != "" && !seen[v.Key] {
+			seen[v.Key] = true
+			keys = append(keys, v.Key)
+		}
+	}
+	if total <= 0 || len(keys) == 0 {
+		return nil, false
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return less(keys[j], keys[i])
+	})
+
+	for _, key := range keys {
+		best = &Outcome{Key: key}
+		for _, v := range votes {
+			if v.Key != "" && !less(v.Key, key) {
+				best.Weight += v.Weight
+				best.Participants = append(best.Participants, v.Participant)
+			}
+		}
+		best.Share = best.Weight / total
+		if best.Share > threshold {
+			return best, true
+		}
+	}
+
+	return best, false
+}
@@ -0,0 +1,127 @@
+package consensus
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+	t.Run("SimpleMajorityWithEqualWeights", func(t *testing.T) {
+		best, ok := Evaluate([]*Vote{
+			{Participant: "a", Weight: 1, Key: "0x1"},
+			{Participant: "b", Weight: 1, Key: "0x1"},
+			{Participant: "c", Weight: 1, Key: "0x2"},
+		}, 0.5)
+		assert.True(t, ok)
+		assert.Equal(t, "0x1", best.Key)
+		assert.ElementsMatch(t, []string{"a", "b"}, best.Participants)
+	})
+
+	t.Run("HighWeightParticipantOutweighsTwoLowWeightOnes", func(t *testing.T) {
+		best, ok := Evaluate([]*Vote{
+			{Participant: "cheap-1", Weight: 1, Key: "0x2"},
+			{Participant: "trusted", Weight: 3, Key: "0x1"},
+			{Participant: "cheap-2", Weight: 1, Key: "0x2"},
+		}, 0.5)
+		assert.True(t, ok)
+		assert.Equal(t, "0x1", best.Key)
+		assert.Equal(t, []string{"trusted"}, best.Participants)
+		assert.InDelta(t, 0.6, best.Share, 0.0001)
+	})
+
+	t.Run("BelowThresholdIsLowConfidence", func(t *testing.T) {
+		best, ok := Evaluate([]*Vote{
+			{Participant: "trusted", Weight: 3, Key: "0x1"},
+			{Participant: "cheap-1", Weight: 1, Key: "0x2"},
+			{Participant: "cheap-2", Weight: 1, Key: "0x2"},
+		}, 0.7)
+		assert.False(t, ok)
+		assert.Equal(t, "0x1", best.Key)
+	})
+
+	t.Run("FailedParticipantsCountTowardsTotalWeight", func(t *testing.T) {
+		_, ok := Evaluate([]*Vote{
+			{Participant: "a", Weight: 1, Key: "0x1"},
+			{Participant: "b", Weight: 1},
+			{Participant: "c", Weight: 1},
+		}, 0.5)
+		assert.False(t, ok)
+	})
+
+	t.Run("TieIsLowConfidence", func(t *testing.T) {
+		_, ok := Evaluate([]*Vote{
+			{Participant: "a", Weight: 2, Key: "0x1"},
+			{Participant: "b", Weight: 2, Key: "0x2"},
+		}, 0.3)
+		assert.False(t, ok)
+	})
+
+	t.Run("NoSuccessfulVotes", func(t *testing.T) {
+		best, ok := Evaluate([]*Vote{
+			{Participant: "a", Weight: 1},
+		}, 0.5)
+		assert.False(t, ok)
+		assert.Nil(t, best)
+	})
+}
+
+func TestEvaluateHighest(t *testing.T) {
+	less := func(a, b string) bool {
+		na, _ := strconv.Atoi(a)
+		nb, _ := strconv.Atoi(b)
+		return na < nb
+	}
+
+	t.Run("DifferingResultsReturnTheHighestSupportedByThreshold", func(t *testing.T) {
+		best, ok := EvaluateHighest([]*Vote{
+			{Participant: "a", Weight: 1, Key: "100"},
+			{Participant: "b", Weight: 1, Key: "102"},
+			{Participant: "c", Weight: 1, Key: "101"},
+		}, 0.5, less)
+		assert.True(t, ok)
+		assert.Equal(t, "101", best.Key)
+		assert.ElementsMatch(t, []string{"b", "c"}, best.Participants)
+		assert.InDelta(t, 2.0/3, best.Share, 0.0001)
+	})
+
+	t.Run("SingleOutlierDoesNotWin", func(t *testing.T) {
+		best, ok := EvaluateHighest([]*Vote{
+			{Participant: "a", Weight: 1, Key: "100"},
+			{Participant: "b", Weight: 1, Key: "100"},
+			{Participant: "c", Weight: 1, Key: "999999"},
+		}, 0.5, less)
+		assert.True(t, ok)
+		assert.Equal(t, "100", best.Key)
+		assert.InDelta(t, 1, best.Share, 0.0001)
+	})
+
+	t.Run("TrustedMajorityAheadWins", func(t *testing.T) {
+		best, ok := EvaluateHighest([]*Vote{
+			{Participant: "a", Weight: 1, Key: "100"},
+			{Participant: "b", Weight: 3, Key: "102"},
+		}, 0.5, less)
+		assert.True(t, ok)
+		assert.Equal(t, "102", best.Key)
+		assert.Equal(t, []string{"b"}, best.Participants)
+	})
+
+	t.Run("FailedParticipantsCountTowardsTotalWeight", func(t *testing.T) {
+		best, ok := EvaluateHighest([]*Vote{
+			{Participant: "a", Weight: 1, Key: "100"},
+			{Participant: "b", Weight: 1},
+			{Participant: "c", Weight: 1},
+		}, 0.5, less)
+		assert.False(t, ok)
+		assert.Equal(t, "100", best.Key)
+	})
+
+	t.Run("NoSuccessfulVote", func(t *testing.T) {
+		best, ok := EvaluateHighest([]*Vote{
+			{Participant: "a", Weight: 1},
+		}, 0.5, less)
+		assert.False(t, ok)
+		assert.Nil(t, best)
+	})
+}
@@ -2,6 +2,7 @@ package data
 
 import (
 	"context"
+	"time"
 
 	"github.com/erpc/erpc/common"
 )
@@ -9,5 +10,7 @@ import (
 type CacheDAL interface {
 	Set(ctx context.Context, req *common.NormalizedRequest, res *common.NormalizedResponse) error
 	Get(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error)
+	// GetStale also returns entries that expired less than maxAge ago (zero means any age), flagged as stale
+	GetStale(ctx context.Context, req *common.NormalizedRequest, maxAge time.Duration) (*common.NormalizedResponse, error)
 	DeleteByGroupKey(ctx context.Context, groupKeys ...string) error
 }
@@ -2,6 +2,8 @@ package data
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/erpc/erpc/common"
 	"github.com/rs/zerolog"
@@ -14,12 +16,23 @@ const (
 
 type Connector interface {
 	Get(ctx context.Context, index, partitionKey, rangeKey string) (string, error)
-	Set(ctx context.Context, partitionKey, rangeKey, value string) error
+	// Set stores the value, a non-nil ttl takes precedence over the ttl configured for the method
+	Set(ctx context.Context, partitionKey, rangeKey, value string, ttl *time.Duration) error
 	SetTTL(method string, ttlStr string) error
 	HasTTL(method string) bool
 	Delete(ctx context.Context, index, partitionKey, rangeKey string) error
 }
 
+// rangeKeyMethod returns the method of a range key, which is "<method>:<hash>" possibly prefixed
+// with the version of the hashing (e.g. "v2:eth_call:<hash>"), so the method is always next to the hash.
+func rangeKeyMethod(rangeKey string) string {
+	parts := strings.Split(rangeKey, ":")
+	if len(parts) < 2 {
+		return strings.ToLower(rangeKey)
+	}
+	return strings.ToLower(parts[len(parts)-2])
+}
+
 func NewConnector(
 	ctx context.Context,
 	logger *zerolog.Logger,
@@ -270,7 +270,8 @@ func (d *DynamoDBConnector) HasTTL(_ string) bool {
 	return false
 }
 
-func (d *DynamoDBConnector) Set(ctx context.Context, partitionKey, rangeKey, value string) error {
+// Set does not expire entries, callers relying on a ttl must check the age of entries they read
+func (d *DynamoDBConnector) Set(ctx context.Context, partitionKey, rangeKey, value string, _ *time.Duration) error {
 	if d.client == nil {
 		return fmt.Errorf("DynamoDB client not initialized yet")
 	}
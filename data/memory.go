@@ -3,8 +3,13 @@ package data
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/bytedance/sonic"
 	"github.com/erpc/erpc/common"
 	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/rs/zerolog"
@@ -12,13 +17,41 @@ import (
 
 const (
 	MemoryDriverName = "memory"
+
+	memorySnapshotVersion = 1
 )
 
 var _ Connector = (*MemoryConnector)(nil)
 
 type MemoryConnector struct {
-	logger *zerolog.Logger
-	cache  *lru.Cache[string, string]
+	logger      *zerolog.Logger
+	cache       *lru.Cache[string, memoryEntry]
+	ttls        map[string]time.Duration
+	persistPath string
+	persistOnce sync.Once
+}
+
+type memoryEntry struct {
+	value string
+	// Zero means the entry never expires
+	expiresAt time.Time
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+type memorySnapshot struct {
+	Version int                   `json:"version"`
+	SavedAt int64                 `json:"savedAt"`
+	Entries []memorySnapshotEntry `json:"entries"`
+}
+
+type memorySnapshotEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Unix milliseconds, zero means the entry never expires
+	ExpiresAt int64 `json:"expiresAt,omitempty"`
 }
 
 func NewMemoryConnector(ctx context.Context, logger *zerolog.Logger, cfg *common.MemoryConnectorConfig) (*MemoryConnector, error) {
@@ -31,29 +64,60 @@ func NewMemoryConnector(ctx context.Context, logger *zerolog.Logger, cfg *common
 		maxItems = cfg.MaxItems
 	}
 
-	cache, err := lru.New[string, string](maxItems)
+	cache, err := lru.New[string, memoryEntry](maxItems)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create LRU cache: %w", err)
 	}
 
-	return &MemoryConnector{
+	m := &MemoryConnector{
 		logger: logger,
 		cache:  cache,
-	}, nil
+		ttls:   make(map[string]time.Duration),
+	}
+
+	if cfg != nil && cfg.PersistPath != "" {
+		m.persistPath = cfg.PersistPath
+		if err := m.load(); err != nil {
+			// A missing, corrupt or stale snapshot must never prevent startup, we just begin with an empty cache
+			logger.Warn().Err(err).Str("path", m.persistPath).Msg("ignoring memory cache snapshot")
+		}
+		go func() {
+			<-ctx.Done()
+			if err := m.Close(context.Background()); err != nil {
+				logger.Error().Err(err).Str("path", m.persistPath).Msg("failed to persist memory cache")
+			}
+		}()
+	}
+
+	return m, nil
 }
 
-func (m *MemoryConnector) SetTTL(_ string, _ string) error {
-	m.logger.Debug().Msgf("Method TTLs not implemented for MemoryConnector")
+func (m *MemoryConnector) SetTTL(method string, ttlStr string) error {
+	ttl, err := time.ParseDuration(ttlStr)
+	if err != nil {
+		return err
+	}
+	m.ttls[strings.ToLower(method)] = ttl
 	return nil
 }
 
-func (d *MemoryConnector) HasTTL(_ string) bool {
-	return false
+func (m *MemoryConnector) HasTTL(method string) bool {
+	_, found := m.ttls[strings.ToLower(method)]
+	return found
 }
 
-func (m *MemoryConnector) Set(ctx context.Context, partitionKey, rangeKey, value string) error {
+func (m *MemoryConnector) Set(ctx context.Context, partitionKey, rangeKey, value string, ttl *time.Duration) error {
 	key := fmt.Sprintf("%s:%s", partitionKey, rangeKey)
-	m.cache.Add(key, value)
+	entry := memoryEntry{value: value}
+	if ttl == nil {
+		if mttl, found := m.ttls[rangeKeyMethod(rangeKey)]; found {
+			ttl = &mttl
+		}
+	}
+	if ttl != nil && *ttl > 0 {
+		entry.expiresAt = time.Now().Add(*ttl)
+	}
+	m.cache.Add(key, entry)
 	return nil
 }
 
@@ -63,19 +127,33 @@ func (m *MemoryConnector) Get(ctx context.Context, index, partitionKey, rangeKey
 	}
 
 	key := fmt.Sprintf("%s:%s", partitionKey, rangeKey)
-	value, ok := m.cache.Get(key)
+	value, ok := m.get(key, time.Now())
 	if !ok {
 		return "", common.NewErrRecordNotFound(fmt.Sprintf("PK: %s RK: %s", partitionKey, rangeKey), MemoryDriverName)
 	}
 	return value, nil
 }
 
+func (m *MemoryConnector) get(key string, now time.Time) (string, bool) {
+	entry, ok := m.cache.Get(key)
+	if !ok {
+		return "", false
+	}
+	if entry.expired(now) {
+		m.cache.Remove(key)
+		return "", false
+	}
+	return entry.value, true
+}
+
 func (m *MemoryConnector) getWithWildcard(_ context.Context, _, partitionKey, rangeKey string) (string, error) {
 	key := fmt.Sprintf("%s:%s", partitionKey, rangeKey)
+	now := time.Now()
 	for _, k := range m.cache.Keys() {
 		if common.WildcardMatch(key, k) {
-			value, _ := m.cache.Get(k)
-			return value, nil
+			if value, ok := m.get(k, now); ok {
+				return value, nil
+			}
 		}
 	}
 	return "", common.NewErrRecordNotFound(fmt.Sprintf("PK: %s RK: %s", partitionKey, rangeKey), MemoryDriverName)
@@ -85,12 +163,14 @@ func (m *MemoryConnector) Query(ctx context.Context, index, partitionKey, rangeK
 	prefix := strings.TrimSuffix(partitionKey, "*")
 	var results []*DataRow
 
+	now := time.Now()
 	for _, key := range m.cache.Keys() {
 		parts := strings.Split(key, ":")
 		if len(parts) == 2 && strings.HasPrefix(parts[0], prefix) {
 			if rangeKey == "" || (strings.HasSuffix(rangeKey, "*") && strings.HasPrefix(parts[1], strings.TrimSuffix(rangeKey, "*"))) || parts[1] == rangeKey {
-				value, _ := m.cache.Get(key)
-				results = append(results, &DataRow{Value: value})
+				if value, ok := m.get(key, now); ok {
+					results = append(results, &DataRow{Value: value})
+				}
 			}
 		}
 	}
@@ -109,14 +189,9 @@ func (m *MemoryConnector) Delete(ctx context.Context, index, partitionKey, range
 }
 
 func (m *MemoryConnector) deleteWithWildcard(_ context.Context, _, partitionKey, rangeKey string) error {
-	prefixPK := strings.TrimSuffix(partitionKey, "*")
-	prefixRK := strings.TrimSuffix(rangeKey, "*")
-
+	pattern := fmt.Sprintf("%s:%s", partitionKey, rangeKey)
 	for _, key := range m.cache.Keys() {
-		parts := strings.Split(key, ":")
-		if len(parts) == 2 &&
-			(partitionKey == "*" || strings.HasPrefix(parts[0], prefixPK)) &&
-			(rangeKey == "*" || strings.HasPrefix(parts[1], prefixRK)) {
+		if common.WildcardMatch(pattern, key) {
 			m.cache.Remove(key)
 		}
 	}
@@ -124,6 +199,99 @@ func (m *MemoryConnector) deleteWithWildcard(_ context.Context, _, partitionKey,
 	return nil
 }
 
+// Close persists the cache to disk when persistPath is configured. It is safe to call more than once,
+// only the first call writes the snapshot.
 func (m *MemoryConnector) Close(ctx context.Context) error {
+	if m.persistPath == "" {
+		return nil
+	}
+
+	var err error
+	m.persistOnce.Do(func() {
+		err = m.persist()
+	})
+	return err
+}
+
+func (m *MemoryConnector) persist() error {
+	now := time.Now()
+	snapshot := memorySnapshot{
+		Version: memorySnapshotVersion,
+		SavedAt: now.UnixMilli(),
+	}
+	// Keys are ordered from oldest to newest, so that reloading them preserves recency in the LRU
+	for _, key := range m.cache.Keys() {
+		entry, ok := m.cache.Peek(key)
+		if !ok || entry.expired(now) {
+			continue
+		}
+		se := memorySnapshotEntry{Key: key, Value: entry.value}
+		if !entry.expiresAt.IsZero() {
+			se.ExpiresAt = entry.expiresAt.UnixMilli()
+		}
+		snapshot.Entries = append(snapshot.Entries, se)
+	}
+
+	body, err := sonic.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash mid-write never leaves a truncated snapshot behind
+	tmp, err := os.CreateTemp(filepath.Dir(m.persistPath), filepath.Base(m.persistPath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), m.persistPath); err != nil {
+		return err
+	}
+
+	m.logger.Info().Int("entries", len(snapshot.Entries)).Str("path", m.persistPath).Msg("persisted memory cache to disk")
+	return nil
+}
+
+func (m *MemoryConnector) load() error {
+	body, err := os.ReadFile(m.persistPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var snapshot memorySnapshot
+	if err := sonic.Unmarshal(body, &snapshot); err != nil {
+		return fmt.Errorf("corrupt snapshot: %w", err)
+	}
+	if snapshot.Version != memorySnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version: %d", snapshot.Version)
+	}
+
+	now := time.Now()
+	loaded := 0
+	for _, se := range snapshot.Entries {
+		if se.Key == "" {
+			continue
+		}
+		entry := memoryEntry{value: se.Value}
+		if se.ExpiresAt > 0 {
+			entry.expiresAt = time.UnixMilli(se.ExpiresAt)
+			if entry.expired(now) {
+				continue
+			}
+		}
+		m.cache.Add(se.Key, entry)
+		loaded++
+	}
+
+	m.logger.Info().Int("entries", loaded).Str("path", m.persistPath).Msg("loaded memory cache from disk")
 	return nil
 }
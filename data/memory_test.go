@@ -0,0 +1,160 @@
+package data
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryConnector_TTL(t *testing.T) {
+	logger := zerolog.Nop()
+
+	t.Run("EntryOfMethodWithTTLExpires", func(t *testing.T) {
+		ctx := context.Background()
+		m, err := NewMemoryConnector(ctx, &logger, &common.MemoryConnectorConfig{MaxItems: 100})
+		assert.NoError(t, err)
+		assert.NoError(t, m.SetTTL("eth_getBlockByNumber", "50ms"))
+		assert.True(t, m.HasTTL("eth_getBlockByNumber"))
+		assert.False(t, m.HasTTL("eth_chainId"))
+
+		assert.NoError(t, m.Set(ctx, "evm:1:200", "eth_getBlockByNumber:def", `{}`, nil))
+		assert.NoError(t, m.Set(ctx, "evm:1:100", "eth_chainId:abc", `"0x1"`, nil))
+		value, err := m.Get(ctx, ConnectorMainIndex, "evm:1:200", "eth_getBlockByNumber:def")
+		assert.NoError(t, err)
+		assert.Equal(t, `{}`, value)
+
+		time.Sleep(100 * time.Millisecond)
+
+		_, err = m.Get(ctx, ConnectorMainIndex, "evm:1:200", "eth_getBlockByNumber:def")
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeRecordNotFound))
+		value, err = m.Get(ctx, ConnectorMainIndex, "evm:1:100", "eth_chainId:abc")
+		assert.NoError(t, err)
+		assert.Equal(t, `"0x1"`, value)
+	})
+
+	t.Run("MethodTTLAppliesToVersionedRangeKeys", func(t *testing.T) {
+		ctx := context.Background()
+		m, err := NewMemoryConnector(ctx, &logger, &common.MemoryConnectorConfig{MaxItems: 100})
+		assert.NoError(t, err)
+		assert.NoError(t, m.SetTTL("eth_getBlockByNumber", "50ms"))
+
+		assert.NoError(t, m.Set(ctx, "evm:1:nil", "v2:eth_getBlockByNumber:def", `{}`, nil))
+		_, err = m.Get(ctx, ConnectorMainIndex, "evm:1:nil", "v2:eth_getBlockByNumber:def")
+		assert.NoError(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+
+		_, err = m.Get(ctx, ConnectorMainIndex, "evm:1:nil", "v2:eth_getBlockByNumber:def")
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeRecordNotFound))
+	})
+
+	t.Run("InvalidTTLIsRejected", func(t *testing.T) {
+		m, err := NewMemoryConnector(context.Background(), &logger, &common.MemoryConnectorConfig{MaxItems: 100})
+		assert.NoError(t, err)
+		assert.Error(t, m.SetTTL("eth_getBlockByNumber", "soon"))
+	})
+}
+
+func TestMemoryConnector_Persistence(t *testing.T) {
+	logger := zerolog.Nop()
+
+	t.Run("EntrySurvivesRestartWithRemainingTTL", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.json")
+		cfg := &common.MemoryConnectorConfig{MaxItems: 100, PersistPath: path}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		m1, err := NewMemoryConnector(ctx, &logger, cfg)
+		assert.NoError(t, err)
+		assert.NoError(t, m1.SetTTL("eth_getBlockByNumber", "10s"))
+
+		assert.NoError(t, m1.Set(ctx, "evm:1:100", "eth_chainId:abc", `"0x1"`, nil))
+		assert.NoError(t, m1.Set(ctx, "evm:1:200", "eth_getBlockByNumber:def", `{"number":"0xc8"}`, nil))
+		before, _ := m1.cache.Peek("evm:1:200:eth_getBlockByNumber:def")
+		assert.NoError(t, m1.Close(ctx))
+
+		m2, err := NewMemoryConnector(ctx, &logger, cfg)
+		assert.NoError(t, err)
+
+		value, err := m2.Get(ctx, ConnectorMainIndex, "evm:1:100", "eth_chainId:abc")
+		assert.NoError(t, err)
+		assert.Equal(t, `"0x1"`, value)
+
+		value, err = m2.Get(ctx, ConnectorMainIndex, "evm:1:200", "eth_getBlockByNumber:def")
+		assert.NoError(t, err)
+		assert.Equal(t, `{"number":"0xc8"}`, value)
+
+		// Expiry is restored from the snapshot rather than restarting the full TTL
+		after, _ := m2.cache.Peek("evm:1:200:eth_getBlockByNumber:def")
+		assert.WithinDuration(t, before.expiresAt, after.expiresAt, time.Millisecond)
+		remaining := time.Until(after.expiresAt)
+		assert.True(t, remaining > 0 && remaining <= 10*time.Second, "unexpected remaining ttl %s", remaining)
+
+		never, _ := m2.cache.Peek("evm:1:100:eth_chainId:abc")
+		assert.True(t, never.expiresAt.IsZero())
+	})
+
+	t.Run("ExpiredEntriesAreNotReloaded", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.json")
+		cfg := &common.MemoryConnectorConfig{MaxItems: 100, PersistPath: path}
+
+		ctx := context.Background()
+		m1, err := NewMemoryConnector(ctx, &logger, cfg)
+		assert.NoError(t, err)
+		assert.NoError(t, m1.SetTTL("eth_getBlockByNumber", "50ms"))
+		assert.NoError(t, m1.Set(ctx, "evm:1:200", "eth_getBlockByNumber:def", `{}`, nil))
+		assert.NoError(t, m1.Close(ctx))
+
+		time.Sleep(100 * time.Millisecond)
+
+		m2, err := NewMemoryConnector(ctx, &logger, cfg)
+		assert.NoError(t, err)
+		_, err = m2.Get(ctx, ConnectorMainIndex, "evm:1:200", "eth_getBlockByNumber:def")
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeRecordNotFound))
+		assert.Equal(t, 0, m2.cache.Len())
+	})
+
+	t.Run("CorruptSnapshotIsIgnored", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`{"version":1,"entries":[{"key":`), 0600))
+
+		m, err := NewMemoryConnector(context.Background(), &logger, &common.MemoryConnectorConfig{MaxItems: 100, PersistPath: path})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, m.cache.Len())
+	})
+
+	t.Run("UnknownSnapshotVersionIsIgnored", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`{"version":99,"entries":[{"key":"a:b","value":"c"}]}`), 0600))
+
+		m, err := NewMemoryConnector(context.Background(), &logger, &common.MemoryConnectorConfig{MaxItems: 100, PersistPath: path})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, m.cache.Len())
+	})
+
+	t.Run("PersistsWhenContextIsCancelled", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.json")
+		ctx, cancel := context.WithCancel(context.Background())
+		m, err := NewMemoryConnector(ctx, &logger, &common.MemoryConnectorConfig{MaxItems: 100, PersistPath: path})
+		assert.NoError(t, err)
+		assert.NoError(t, m.Set(ctx, "evm:1:100", "eth_chainId:abc", `"0x1"`, nil))
+
+		cancel()
+		assert.Eventually(t, func() bool {
+			_, err := os.Stat(path)
+			return err == nil
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("NoPersistPathKeepsNothingOnDisk", func(t *testing.T) {
+		m, err := NewMemoryConnector(context.Background(), &logger, &common.MemoryConnectorConfig{MaxItems: 100})
+		assert.NoError(t, err)
+		assert.NoError(t, m.Close(context.Background()))
+	})
+}
@@ -2,6 +2,7 @@ package data
 
 import (
 	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -18,8 +19,8 @@ func (m *MockConnector) Get(ctx context.Context, index, partitionKey, rangeKey s
 }
 
 // Set mocks the Set method of the Connector interface
-func (m *MockConnector) Set(ctx context.Context, partitionKey, rangeKey, value string) error {
-	args := m.Called(ctx, partitionKey, rangeKey, value)
+func (m *MockConnector) Set(ctx context.Context, partitionKey, rangeKey, value string, ttl *time.Duration) error {
+	args := m.Called(ctx, partitionKey, rangeKey, value, ttl)
 	return args.Error(0)
 }
 
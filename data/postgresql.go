@@ -98,7 +98,8 @@ func (p *PostgreSQLConnector) HasTTL(_ string) bool {
 	return false
 }
 
-func (p *PostgreSQLConnector) Set(ctx context.Context, partitionKey, rangeKey, value string) error {
+// Set does not expire entries, callers relying on a ttl must check the age of entries they read
+func (p *PostgreSQLConnector) Set(ctx context.Context, partitionKey, rangeKey, value string, _ *time.Duration) error {
 	if p.conn == nil {
 		return fmt.Errorf("PostgreSQLConnector not connected yet")
 	}
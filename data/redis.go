@@ -2,10 +2,7 @@ package data
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
@@ -70,7 +67,7 @@ func (r *RedisConnector) connect(ctx context.Context, cfg *common.RedisConnector
 	}
 
 	if cfg.TLS != nil && cfg.TLS.Enabled {
-		tlsConfig, err := createTLSConfig(cfg.TLS)
+		tlsConfig, err := common.NewTLSClientConfig(cfg.TLS)
 		if err != nil {
 			return fmt.Errorf("failed to create TLS config: %w", err)
 		}
@@ -88,32 +85,6 @@ func (r *RedisConnector) connect(ctx context.Context, cfg *common.RedisConnector
 	return nil
 }
 
-func createTLSConfig(tlsCfg *common.TLSConfig) (*tls.Config, error) {
-	config := &tls.Config{
-		InsecureSkipVerify: tlsCfg.InsecureSkipVerify, // #nosec G402
-	}
-
-	if tlsCfg.CertFile != "" && tlsCfg.KeyFile != "" {
-		cert, err := tls.LoadX509KeyPair(tlsCfg.CertFile, tlsCfg.KeyFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load client cert/key pair: %w", err)
-		}
-		config.Certificates = []tls.Certificate{cert}
-	}
-
-	if tlsCfg.CAFile != "" {
-		caCert, err := os.ReadFile(tlsCfg.CAFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read CA file: %w", err)
-		}
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		config.RootCAs = caCertPool
-	}
-
-	return config, nil
-}
-
 func (r *RedisConnector) SetTTL(method string, ttlStr string) error {
 	ttl, err := time.ParseDuration(ttlStr)
 	if err != nil {
@@ -128,19 +99,20 @@ func (r *RedisConnector) HasTTL(method string) bool {
 	return found
 }
 
-func (r *RedisConnector) Set(ctx context.Context, partitionKey, rangeKey, value string) error {
+func (r *RedisConnector) Set(ctx context.Context, partitionKey, rangeKey, value string, ttl *time.Duration) error {
 	if r.client == nil {
 		return fmt.Errorf("redis client not initialized yet")
 	}
 
 	r.logger.Debug().Msgf("writing to Redis with partition key: %s and range key: %s", partitionKey, rangeKey)
-	method := strings.ToLower(strings.Split(rangeKey, ":")[0])
-	ttl, found := r.ttls[method]
-	if !found {
-		ttl = time.Duration(0)
+	expiration := time.Duration(0)
+	if ttl != nil {
+		expiration = *ttl
+	} else if mttl, found := r.ttls[rangeKeyMethod(rangeKey)]; found {
+		expiration = mttl
 	}
 	key := fmt.Sprintf("%s:%s", partitionKey, rangeKey)
-	rs := r.client.Set(ctx, key, value, ttl)
+	rs := r.client.Set(ctx, key, value, expiration)
 	return rs.Err()
 }
 
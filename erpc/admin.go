@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/bytedance/sonic"
 	"github.com/erpc/erpc/common"
 	"github.com/erpc/erpc/upstream"
 )
@@ -56,6 +57,70 @@ func (p *PreparedProject) HandleAdminRequest(ctx context.Context, nq *common.Nor
 			return nil, err
 		}
 		return common.NewNormalizedResponse().WithJsonRpcResponse(jrrs), nil
+	case "erpc_quarantineUpstream", "erpc_unquarantineUpstream":
+		jrr, err := nq.JsonRpcRequest()
+		if err != nil {
+			return nil, err
+		}
+		var upsId string
+		if len(jrr.Params) > 0 {
+			upsId, _ = jrr.Params[0].(string)
+		}
+		if upsId == "" {
+			return nil, common.NewErrInvalidRequest(fmt.Errorf("%s requires upstream id as first param", method))
+		}
+		quarantine := method == "erpc_quarantineUpstream"
+		if quarantine {
+			err = p.upstreamsRegistry.QuarantineUpstream(upsId)
+		} else {
+			err = p.upstreamsRegistry.UnquarantineUpstream(upsId)
+		}
+		if err != nil {
+			return nil, err
+		}
+		jrrs, err := common.NewJsonRpcResponse(
+			jrr.ID,
+			map[string]interface{}{
+				"upstreamId":  upsId,
+				"quarantined": quarantine,
+			},
+			nil,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return common.NewNormalizedResponse().WithJsonRpcResponse(jrrs), nil
+	case "erpc_explainCacheKey":
+		jrr, err := nq.JsonRpcRequest()
+		if err != nil {
+			return nil, err
+		}
+		var networkId string
+		var rawReq []byte
+		if len(jrr.Params) > 1 {
+			networkId, _ = jrr.Params[0].(string)
+			rawReq, _ = sonic.Marshal(jrr.Params[1])
+		}
+		if networkId == "" || rawReq == nil {
+			return nil, common.NewErrInvalidRequest(fmt.Errorf("%s requires network id and json-rpc request as params", method))
+		}
+		nw, err := p.GetNetwork(networkId)
+		if err != nil {
+			return nil, err
+		}
+		explanation, err := nw.explainCacheKey(common.NewNormalizedRequest(rawReq))
+		if err != nil {
+			return nil, err
+		}
+		jrrs, err := common.NewJsonRpcResponse(
+			jrr.ID,
+			explanation,
+			nil,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return common.NewNormalizedResponse().WithJsonRpcResponse(jrrs), nil
 	default:
 		return nil, common.NewErrEndpointUnsupported(
 			fmt.Errorf("admin method %s is not supported", method),
@@ -0,0 +1,252 @@
+package erpc
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/erpc/erpc/consensus"
+	"github.com/erpc/erpc/upstream"
+	"github.com/failsafe-go/failsafe-go"
+	"github.com/rs/zerolog"
+)
+
+const defaultConsensusThreshold = 0.5
+
+type consensusAnswer struct {
+	resp *common.NormalizedResponse
+	err  error
+}
+
+// forwardWithConsensus sends the request to all participating upstreams at once and responds with the result
+// whose supporters' trust weights sum to more than the threshold share of all participants' weight.
+func (n *Network) forwardWithConsensus(
+	exec failsafe.Execution[*common.NormalizedResponse],
+	req *common.NormalizedRequest,
+	method string,
+	participants []*upstream.Upstream,
+	requiredAgreements int,
+	forward func(u *upstream.Upstream, ctx context.Context, lg *zerolog.Logger) (*common.NormalizedResponse, error),
+	lg *zerolog.Logger,
+	startTime time.Time,
+) (*common.NormalizedResponse, error) {
+	cfg := n.cfg.Consensus
+	threshold := cfg.Threshold
+	if threshold <= 0 || threshold >= 1 {
+		threshold = defaultConsensusThreshold
+	}
+
+	answers := make([]consensusAnswer, len(participants))
+	var wg sync.WaitGroup
+	for i, u := range participants {
+		wg.Add(1)
+		go func(i int, u *upstream.Upstream) {
+			defer wg.Done()
+			ulg := lg.With().Str("upstreamId", u.Config().Id).Logger()
+			rp, er := forward(u, exec.Context(), &ulg)
+			answers[i].resp, answers[i].err = n.normalizeResponse(req, rp, er)
+		}(i, u)
+	}
+	wg.Wait()
+
+	stripFields := n.cfg.ResultFieldsToStrip(method)
+	preferHighest := cfg.PreferenceFor(method) == common.ConsensusPreferHighest
+	votes := make([]*consensus.Vote, len(participants))
+	errorsByUpstream := map[string]error{}
+	for i, u := range participants {
+		upsId := u.Config().Id
+		votes[i] = &consensus.Vote{Participant: upsId, Weight: trustWeight(u)}
+		if answers[i].err != nil {
+			errorsByUpstream[upsId] = answers[i].err
+			continue
+		}
+		key, err := consensusKey(answers[i].resp, stripFields)
+		if err != nil {
+			errorsByUpstream[upsId] = err
+			continue
+		}
+		if preferHighest {
+			if _, isQuantity := consensusQuantity(key); !isQuantity {
+				errorsByUpstream[upsId] = fmt.Errorf("result %s is not a quantity as expected by highest tie-break", key)
+				continue
+			}
+		}
+		votes[i].Key = key
+	}
+
+	var best *consensus.Outcome
+	var ok bool
+	if preferHighest {
+		best, ok = consensus.EvaluateHighest(votes, threshold, func(a, b string) bool {
+			qa, _ := consensusQuantity(a)
+			qb, _ := consensusQuantity(b)
+			return qa.Cmp(qb) < 0
+		})
+	} else {
+		best, ok = consensus.Evaluate(votes, threshold)
+	}
+	if best == nil {
+		return nil, common.NewErrUpstreamsExhausted(
+			req,
+			errorsByUpstream,
+			n.ProjectId,
+			n.NetworkId,
+			time.Since(startTime),
+			exec.Attempts(),
+			exec.Retries(),
+			exec.Hedges(),
+		)
+	}
+	if !ok {
+		lg.Debug().Interface("votes", votes).Float64("bestShare", best.Share).Msgf("upstreams did not reach consensus")
+		return nil, common.NewErrConsensusLowConfidence(n.NetworkId, threshold, best.Share, len(participants), nil)
+	}
+	if requiredAgreements > 0 {
+		// With highest tie-break participants are all upstreams that returned at least the outcome
+		agreed := len(best.Participants)
+		if agreed < requiredAgreements {
+			lg.Debug().Interface("votes", votes).Int("agreed", agreed).Int("requiredAgreements", requiredAgreements).Msgf("not enough upstreams agreed on the result")
+			return nil, common.NewErrConsensusLowConfidence(
+				n.NetworkId,
+				threshold,
+				best.Share,
+				len(participants),
+				fmt.Errorf("%d upstreams agreed on the result while %d agreements are required", agreed, requiredAgreements),
+			)
+		}
+	}
+
+	// Serve the answer of the most trusted upstream among those that agreed
+	winner := -1
+	for i, u := range participants {
+		if votes[i].Key != best.Key {
+			continue
+		}
+		if winner == -1 || trustWeight(u) > trustWeight(participants[winner]) {
+			winner = i
+		}
+	}
+	resp := answers[winner].resp
+	resp.SetUpstream(participants[winner])
+
+	lg.Debug().Strs("agreed", best.Participants).Float64("share", best.Share).Msgf("upstreams reached consensus")
+
+	return resp, nil
+}
+
+// consensusParticipants picks the upstreams a request is sent to and how many of them must agree, from the
+// request's override headers if any, then the method's entry and then network-wide consensus config.
+func (n *Network) consensusParticipants(req *common.NormalizedRequest, method string, upsList []*upstream.Upstream) ([]*upstream.Upstream, int, error) {
+	cfg := n.cfg.Consensus
+	maxParticipants, requiredAgreements := cfg.RequirementFor(method)
+	if d := req.Directives(); d != nil {
+		if d.ConsensusParticipants != "" {
+			v, err := parseConsensusOverride("X-ERPC-Consensus-Participants", d.ConsensusParticipants, cfg.MaxRequestParticipants, "maxRequestParticipants")
+			if err != nil {
+				return nil, 0, err
+			}
+			maxParticipants = v
+		}
+		if d.ConsensusAgreements != "" {
+			v, err := parseConsensusOverride("X-ERPC-Consensus-Agreements", d.ConsensusAgreements, cfg.MaxRequestAgreements, "maxRequestAgreements")
+			if err != nil {
+				return nil, 0, err
+			}
+			requiredAgreements = v
+		}
+	}
+
+	if requiredAgreements > 0 {
+		healthy := 0
+		for _, u := range upsList {
+			if !u.IsQuarantined() && u.CircuitBreakerState() != "open" && !u.IsHealthCheckFailing() {
+				healthy++
+			}
+		}
+		if healthy < requiredAgreements {
+			return nil, 0, common.NewErrConsensusInsufficientUpstreams(n.NetworkId, requiredAgreements, healthy)
+		}
+		if maxParticipants > 0 && maxParticipants < requiredAgreements {
+			maxParticipants = requiredAgreements
+		}
+	}
+
+	participants := upsList
+	if maxParticipants > 0 && maxParticipants < len(participants) {
+		participants = participants[:maxParticipants]
+	}
+	return participants, requiredAgreements, nil
+}
+
+func parseConsensusOverride(header, value string, bound int, boundName string) (int, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil || v < 1 {
+		return 0, common.NewErrInvalidRequest(fmt.Errorf("%s must be a positive integer, got %q", header, value))
+	}
+	if bound <= 0 {
+		return 0, common.NewErrInvalidRequest(fmt.Errorf("%s is not allowed because consensus %s is not set for this network", header, boundName))
+	}
+	if v > bound {
+		return 0, common.NewErrInvalidRequest(fmt.Errorf("%s of %d exceeds consensus %s of %d", header, v, boundName, bound))
+	}
+	return v, nil
+}
+
+func validateConsensusConfig(cfg *common.ConsensusConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	for i, m := range cfg.Methods {
+		if m.Method == "" {
+			return common.NewErrInvalidConfig(fmt.Sprintf("consensus method #%d must have a method name or pattern", i))
+		}
+		if m.MaxParticipants < 0 || m.RequiredAgreements < 0 {
+			return common.NewErrInvalidConfig(fmt.Sprintf("consensus method #%d (%s) cannot have negative maxParticipants or requiredAgreements", i, m.Method))
+		}
+	}
+	for i, tb := range cfg.TieBreaks {
+		switch tb.Prefer {
+		case common.ConsensusPreferEqual, common.ConsensusPreferHighest:
+		default:
+			return common.NewErrInvalidConfig(fmt.Sprintf("consensus tie-break #%d has unknown prefer: %s (must be equal or highest)", i, tb.Prefer))
+		}
+	}
+	return nil
+}
+
+func trustWeight(u *upstream.Upstream) float64 {
+	if w := u.Config().TrustWeight; w > 0 {
+		return w
+	}
+	return 1
+}
+
+func consensusKey(resp *common.NormalizedResponse, stripFields []string) (string, error) {
+	jrr, err := resp.JsonRpcResponse()
+	if err != nil {
+		return "", err
+	}
+	result, err := common.StripJsonFields(jrr.Result, stripFields)
+	if err != nil {
+		return "", err
+	}
+	key, err := common.CanonicalJson(result)
+	if err != nil {
+		return "", err
+	}
+	return string(key), nil
+}
+
+// consensusQuantity parses a canonical json key holding a hex quantity (e.g. "0x1273c18").
+func consensusQuantity(key string) (*big.Int, bool) {
+	hex, found := strings.CutPrefix(strings.Trim(key, `"`), "0x")
+	if !found || len(key) < 2 || key[0] != '"' {
+		return nil, false
+	}
+	return new(big.Int).SetString(hex, 16)
+}
@@ -0,0 +1,101 @@
+package erpc
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/erpc/erpc/health"
+)
+
+// Warnings are logged at most once per interval for each entry, the metric counts every call.
+const deprecatedMethodWarnInterval = time.Minute
+
+type deprecatedMethod struct {
+	method  string
+	message string
+	reject  bool
+}
+
+// deprecatedMethods is the registry of methods configured as deprecated for a network, the first matching entry wins.
+type deprecatedMethods struct {
+	entries []*deprecatedMethod
+
+	mu sync.Mutex
+	// Keyed by the entry (not the called method), so that patterns bound the number of keys
+	lastWarned map[*deprecatedMethod]time.Time
+}
+
+func newDeprecatedMethods(cfgs []*common.DeprecatedMethodConfig) (*deprecatedMethods, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+	d := &deprecatedMethods{
+		entries:    make([]*deprecatedMethod, 0, len(cfgs)),
+		lastWarned: make(map[*deprecatedMethod]time.Time),
+	}
+	for i, cfg := range cfgs {
+		if cfg.Method == "" {
+			return nil, common.NewErrInvalidConfig(fmt.Sprintf("deprecated method #%d must define a method", i))
+		}
+		d.entries = append(d.entries, &deprecatedMethod{
+			method:  cfg.Method,
+			message: cfg.Message,
+			reject:  cfg.Reject,
+		})
+	}
+	return d, nil
+}
+
+func (d *deprecatedMethods) match(method string) *deprecatedMethod {
+	for _, e := range d.entries {
+		if common.WildcardMatch(e.method, method) {
+			return e
+		}
+	}
+	return nil
+}
+
+// shouldWarn tells if a warning for the entry was not logged within the last interval, and records it if so.
+func (d *deprecatedMethods) shouldWarn(entry *deprecatedMethod, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if last, ok := d.lastWarned[entry]; ok && now.Sub(last) < deprecatedMethodWarnInterval {
+		return false
+	}
+	d.lastWarned[entry] = now
+	return true
+}
+
+// checkDeprecatedMethod counts and logs calls of deprecated methods, it only returns an error when the matching
+// entry rejects calls.
+func (n *Network) checkDeprecatedMethod(req *common.NormalizedRequest, method string) error {
+	if n.deprecatedMethods == nil {
+		return nil
+	}
+	entry := n.deprecatedMethods.match(method)
+	if entry == nil {
+		return nil
+	}
+
+	// Labeled by the configured method (or pattern) since clients can send any method name
+	health.MetricNetworkDeprecatedMethodCalls.WithLabelValues(n.ProjectId, n.NetworkId, entry.method).Inc()
+	if n.deprecatedMethods.shouldWarn(entry, time.Now()) {
+		n.Logger.Warn().
+			Str("method", method).
+			Str("deprecatedMethod", entry.method).
+			Str("id", req.Id()).
+			Str("deprecationMessage", entry.message).
+			Bool("rejected", entry.reject).
+			Msgf("client called a deprecated method, further calls are only counted in metrics for %s", deprecatedMethodWarnInterval)
+	}
+
+	if entry.reject {
+		if entry.message == "" {
+			return common.NewErrInvalidRequest(fmt.Errorf("method %s is deprecated on this network", method))
+		}
+		return common.NewErrInvalidRequest(fmt.Errorf("method %s is deprecated on this network: %s", method, entry.message))
+	}
+	return nil
+}
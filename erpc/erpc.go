@@ -2,6 +2,8 @@ package erpc
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/erpc/erpc/common"
 	"github.com/erpc/erpc/upstream"
@@ -20,11 +22,26 @@ func NewERPC(
 	evmJsonRpcCache *EvmJsonRpcCache,
 	cfg *common.Config,
 ) (*ERPC, error) {
+	if err := common.SetInternalRequestIdStrategy(cfg.InternalRequestIds); err != nil {
+		return nil, err
+	}
+
 	rateLimitersRegistry, err := upstream.NewRateLimitersRegistry(cfg.RateLimiters, logger)
 	if err != nil {
 		return nil, err
 	}
 
+	if !cfg.AllowFaultInjection {
+		for _, prj := range cfg.Projects {
+			for _, ups := range prj.Upstreams {
+				if ups.FaultInjection != nil {
+					logger.Warn().Str("projectId", prj.Id).Str("upstreamId", ups.Id).Msgf("ignoring faultInjection of upstream because allowFaultInjection is not enabled")
+					ups.FaultInjection = nil
+				}
+			}
+		}
+	}
+
 	vendorsRegistry := vendors.NewVendorsRegistry()
 	projectRegistry, err := NewProjectsRegistry(
 		ctx,
@@ -56,3 +73,14 @@ func (e *ERPC) GetNetwork(projectId string, networkId string) (*Network, error)
 func (e *ERPC) GetProject(projectId string) (*PreparedProject, error) {
 	return e.projectsRegistry.GetProject(projectId)
 }
+
+// CloseUpstreamClients closes clients of the upstreams of all projects, see upstream.HttpJsonRpcClient.Close.
+func (e *ERPC) CloseUpstreamClients() error {
+	var errs []error
+	for _, prj := range e.projectsRegistry.GetAll() {
+		if err := prj.upstreamsRegistry.CloseClients(); err != nil {
+			errs = append(errs, fmt.Errorf("project %s: %w", prj.Config.Id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
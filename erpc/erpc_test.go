@@ -142,3 +142,42 @@ func TestErpc_UpstreamsRegistryCorrectPriorityChange(t *testing.T) {
 		assert.Equal(t, expectedOrder[i], ups.Config().Id)
 	}
 }
+
+func TestErpc_FaultInjectionGate(t *testing.T) {
+	newConfig := func(allow bool) *common.Config {
+		return &common.Config{
+			AllowFaultInjection: allow,
+			Projects: []*common.ProjectConfig{
+				{
+					Id: "test",
+					Upstreams: []*common.UpstreamConfig{
+						{
+							Id:             "rpc1",
+							Type:           "evm",
+							Endpoint:       "http://rpc1.localhost",
+							Evm:            &common.EvmUpstreamConfig{ChainId: 123},
+							FaultInjection: &common.FaultInjectionConfig{ErrorRate: 1},
+						},
+					},
+				},
+			},
+			RateLimiters: &common.RateLimiterConfig{},
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Run("IgnoredUnlessAllowed", func(t *testing.T) {
+		cfg := newConfig(false)
+		_, err := NewERPC(ctx, &log.Logger, nil, cfg)
+		assert.NoError(t, err)
+		assert.Nil(t, cfg.Projects[0].Upstreams[0].FaultInjection)
+	})
+
+	t.Run("KeptWhenAllowed", func(t *testing.T) {
+		cfg := newConfig(true)
+		_, err := NewERPC(ctx, &log.Logger, nil, cfg)
+		assert.NoError(t, err)
+		assert.NotNil(t, cfg.Projects[0].Upstreams[0].FaultInjection)
+	})
+}
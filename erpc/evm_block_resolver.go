@@ -0,0 +1,93 @@
+package erpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/erpc/erpc/common"
+)
+
+var _ common.BlockResolver = (*evmStatePollerBlockResolver)(nil)
+
+// evmStatePollerBlockResolver resolves block heights of a network from the latest/finalized blocks
+// that state pollers periodically fetch from each of its upstreams.
+type evmStatePollerBlockResolver struct {
+	network *Network
+}
+
+func newEvmStatePollerBlockResolver(network *Network) *evmStatePollerBlockResolver {
+	return &evmStatePollerBlockResolver{network: network}
+}
+
+func (r *evmStatePollerBlockResolver) ResolveTag(ctx context.Context, networkId, tag string) (int64, error) {
+	switch tag {
+	case "latest":
+		return r.HeadHeight(networkId)
+	case "finalized", "safe":
+		// Pollers do not track "safe" block, finalized block is always behind it so it is a safe lower bound.
+		return r.FinalizedHeight(networkId)
+	case "earliest":
+		return 0, nil
+	}
+
+	if strings.HasPrefix(tag, "0x") {
+		return common.HexToInt64(tag)
+	}
+
+	return 0, fmt.Errorf("cannot resolve block tag %q for network %s", tag, networkId)
+}
+
+func (r *evmStatePollerBlockResolver) FinalizedHeight(networkId string) (int64, error) {
+	if err := r.checkNetwork(networkId); err != nil {
+		return 0, err
+	}
+
+	var highest int64
+	var found bool
+	for _, poller := range r.network.evmStatePollers {
+		fb, err := poller.EffectiveFinalizedBlock()
+		if err != nil {
+			if common.HasErrorCode(err, common.ErrCodeFinalizedBlockUnavailable) {
+				continue
+			}
+			return 0, err
+		}
+		found = true
+		if fb > highest {
+			highest = fb
+		}
+	}
+
+	if !found {
+		return 0, common.NewErrFinalizedBlockUnavailable(0)
+	}
+
+	return highest, nil
+}
+
+func (r *evmStatePollerBlockResolver) HeadHeight(networkId string) (int64, error) {
+	if err := r.checkNetwork(networkId); err != nil {
+		return 0, err
+	}
+
+	var highest int64
+	for _, poller := range r.network.evmStatePollers {
+		if lb := poller.LatestBlock(); lb > highest {
+			highest = lb
+		}
+	}
+
+	if highest == 0 {
+		return 0, common.NewErrLatestBlockUnavailable(networkId)
+	}
+
+	return highest, nil
+}
+
+func (r *evmStatePollerBlockResolver) checkNetwork(networkId string) error {
+	if networkId != r.network.NetworkId {
+		return fmt.Errorf("block resolver of network %s cannot resolve blocks of network %s", r.network.NetworkId, networkId)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package erpc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/erpc/erpc/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type fakeBlockResolver struct {
+	finalized int64
+	head      int64
+}
+
+func (f *fakeBlockResolver) ResolveTag(ctx context.Context, networkId, tag string) (int64, error) {
+	switch tag {
+	case "latest":
+		return f.HeadHeight(networkId)
+	case "finalized":
+		return f.FinalizedHeight(networkId)
+	}
+	return 0, fmt.Errorf("unsupported tag %s", tag)
+}
+
+func (f *fakeBlockResolver) FinalizedHeight(networkId string) (int64, error) {
+	if f.finalized == 0 {
+		return 0, common.NewErrFinalizedBlockUnavailable(0)
+	}
+	return f.finalized, nil
+}
+
+func (f *fakeBlockResolver) HeadHeight(networkId string) (int64, error) {
+	return f.head, nil
+}
+
+func TestEvmStatePollerBlockResolver(t *testing.T) {
+	t.Run("ResolvesHeightsFromStatePollers", func(t *testing.T) {
+		_, mockNetwork, _ := createCacheTestFixtures(10, 15, nil)
+		resolver := newEvmStatePollerBlockResolver(mockNetwork)
+
+		fin, err := resolver.FinalizedHeight("evm:123")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), fin)
+
+		head, err := resolver.HeadHeight("evm:123")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(15), head)
+
+		for tag, expected := range map[string]int64{"latest": 15, "finalized": 10, "safe": 10, "earliest": 0, "0x5": 5} {
+			bn, err := resolver.ResolveTag(context.Background(), "evm:123", tag)
+			assert.NoError(t, err, tag)
+			assert.Equal(t, expected, bn, tag)
+		}
+
+		_, err = resolver.ResolveTag(context.Background(), "evm:123", "pending")
+		assert.Error(t, err)
+		_, err = resolver.FinalizedHeight("evm:1")
+		assert.Error(t, err)
+	})
+
+	t.Run("InfersFinalizedHeightFromLatestBlock", func(t *testing.T) {
+		_, mockNetwork, _ := createCacheTestFixtures(0, 2000, nil)
+		resolver := newEvmStatePollerBlockResolver(mockNetwork)
+
+		fin, err := resolver.FinalizedHeight("evm:123")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2000-1024), fin)
+	})
+
+	t.Run("ReportsUnavailableBeforeFirstPoll", func(t *testing.T) {
+		_, mockNetwork, _ := createCacheTestFixtures(0, 0, nil)
+		resolver := newEvmStatePollerBlockResolver(mockNetwork)
+
+		_, err := resolver.FinalizedHeight("evm:123")
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeFinalizedBlockUnavailable))
+		_, err = resolver.HeadHeight("evm:123")
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeLatestBlockUnavailable))
+
+		fin, err := mockNetwork.EvmIsBlockFinalized(1)
+		assert.NoError(t, err)
+		assert.False(t, fin)
+	})
+}
+
+func TestBlockResolver_Consumers(t *testing.T) {
+	t.Run("NetworkFinalityUsesResolver", func(t *testing.T) {
+		_, mockNetwork, _ := createCacheTestFixtures(0, 0, nil)
+		mockNetwork.blockResolver = &fakeBlockResolver{finalized: 100, head: 110}
+
+		fin, err := mockNetwork.EvmIsBlockFinalized(100)
+		assert.NoError(t, err)
+		assert.True(t, fin)
+
+		fin, err = mockNetwork.EvmIsBlockFinalized(101)
+		assert.NoError(t, err)
+		assert.False(t, fin)
+	})
+
+	t.Run("CacheFinalityUsesResolver", func(t *testing.T) {
+		mockConnector, mockNetwork, cache := createCacheTestFixtures(0, 0, nil)
+		cache.resolver = &fakeBlockResolver{finalized: 100, head: 110}
+		mockConnector.On("HasTTL", mock.AnythingOfType("string")).Return(false)
+		mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		for _, bn := range []int64{100, 101} {
+			req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["0x%x",false],"id":1}`, bn)))
+			req.SetNetwork(mockNetwork)
+			resp := common.NewNormalizedResponse().WithBody([]byte(fmt.Sprintf(`{"result":{"number":"0x%x","hash":"0xabc"}}`, bn)))
+			assert.NoError(t, cache.Set(context.Background(), req, resp))
+		}
+
+		mockConnector.AssertCalled(t, "Set", mock.Anything, "evm:123:100", mock.Anything, mock.Anything, mock.Anything)
+		mockConnector.AssertNotCalled(t, "Set", mock.Anything, "evm:123:101", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,104 @@
+package erpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultFinalizedPrewarmWindow   int64 = 16
+	defaultFinalizedPrewarmInterval       = 1 * time.Second
+	finalizedPrewarmTimeout               = 30 * time.Second
+)
+
+// evmFinalizedPrewarmer watches the finalized height of the network and warms the cache with blocks
+// that just became finalized, so that the first client reads of those blocks are served from cache.
+type evmFinalizedPrewarmer struct {
+	network  *Network
+	logger   *zerolog.Logger
+	interval time.Duration
+	window   int64
+	fullTxs  bool
+
+	// Highest finalized block seen so far, 0 until the first check
+	lastFinalized int64
+}
+
+func newEvmFinalizedPrewarmer(network *Network, cfg *common.EvmFinalizedPrewarmConfig) *evmFinalizedPrewarmer {
+	if cfg == nil {
+		return nil
+	}
+	pw := &evmFinalizedPrewarmer{
+		network:  network,
+		logger:   network.Logger,
+		interval: defaultFinalizedPrewarmInterval,
+		window:   defaultFinalizedPrewarmWindow,
+		fullTxs:  cfg.FullTransactions,
+	}
+	if cfg.Window > 0 {
+		pw.window = cfg.Window
+	}
+	if network.cfg != nil && network.cfg.Evm != nil {
+		pw.interval = parseDurationOr(network.Logger, "evm.blockTrackerInterval", network.cfg.Evm.BlockTrackerInterval, pw.interval)
+	}
+	return pw
+}
+
+func (pw *evmFinalizedPrewarmer) run(ctx context.Context) {
+	ticker := time.NewTicker(pw.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pw.check(ctx)
+		}
+	}
+}
+
+// check warms blocks finalized since the previous check, at most the most recent window of them.
+// Blocks that were already finalized when eRPC started are not warmed.
+func (pw *evmFinalizedPrewarmer) check(ctx context.Context) {
+	finalized, err := pw.network.BlockResolver().FinalizedHeight(pw.network.NetworkId)
+	if err != nil || finalized <= pw.lastFinalized {
+		return
+	}
+	from := pw.lastFinalized + 1
+	pw.lastFinalized = finalized
+	if from == 1 {
+		return
+	}
+	if finalized-from+1 > pw.window {
+		from = finalized - pw.window + 1
+	}
+
+	variants := []bool{false}
+	if pw.fullTxs {
+		variants = append(variants, true)
+	}
+	var reqs []*common.NormalizedRequest
+	for bn := from; bn <= finalized; bn++ {
+		ref := fmt.Sprintf("0x%x", bn)
+		for _, fullTxs := range variants {
+			req, err := common.NewInternalRequest("eth_getBlockByNumber", []interface{}{ref, fullTxs})
+			if err != nil {
+				pw.logger.Warn().Err(err).Int64("blockNumber", bn).Msg("failed to build prewarm request for finalized block")
+				return
+			}
+			reqs = append(reqs, req)
+		}
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, finalizedPrewarmTimeout)
+	defer cancel()
+	if err := pw.network.WarmCache(wctx, reqs); err != nil {
+		pw.logger.Warn().Err(err).Int64("fromBlock", from).Int64("toBlock", finalized).Msg("failed to prewarm cache with newly finalized blocks")
+		return
+	}
+	pw.logger.Debug().Int64("fromBlock", from).Int64("toBlock", finalized).Msg("prewarmed cache with newly finalized blocks")
+}
@@ -1,10 +1,16 @@
 package erpc
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bytedance/sonic"
@@ -13,10 +19,43 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// Immutable responses (e.g. eth_chainId) do not belong to any block, so they are stored
+// under a dedicated partition that is never invalidated by finality or reorgs.
+const immutableBlockRef = "immutable"
+
+// Block time assumed for networks that do not configure one, kept short so that realtime
+// responses on fast chains are not served for longer than a few blocks.
+const defaultEvmBlockTime = 1 * time.Second
+
+// evmRealtimeTTL is how long realtime-class responses (e.g. eth_gasPrice) are cached: one block time.
+func evmRealtimeTTL(cfg *common.EvmNetworkConfig) (time.Duration, error) {
+	if cfg == nil || cfg.BlockTime == "" {
+		return defaultEvmBlockTime, nil
+	}
+	d, err := time.ParseDuration(cfg.BlockTime)
+	if err != nil || d <= 0 {
+		return 0, common.NewErrInvalidConfig(fmt.Sprintf("invalid evm.blockTime: %s", cfg.BlockTime))
+	}
+	return d, nil
+}
+
 type EvmJsonRpcCache struct {
-	conn    data.Connector
-	network *Network
-	logger  *zerolog.Logger
+	conn        data.Connector
+	network     *Network
+	resolver    common.BlockResolver
+	logger      *zerolog.Logger
+	compression *common.CompressionConfig
+	codec       common.CacheEntryCodec
+	reorgs      *evmReorgTracker
+	policies    []*cachePolicy
+}
+
+// cachePolicy is a parsed project-level override of how a method is cached.
+type cachePolicy struct {
+	method           string
+	enabled          bool
+	ttl              time.Duration
+	allowUnfinalized bool
 }
 
 const (
@@ -42,40 +81,168 @@ func NewEvmJsonRpcCache(ctx context.Context, logger *zerolog.Logger, cfg *common
 		}
 	}
 
+	var codec common.CacheEntryCodec
+	if cfg.EntryCodec != "" {
+		if codec, err = common.GetCacheEntryCodec(cfg.EntryCodec); err != nil {
+			return nil, err
+		}
+	}
+
 	return &EvmJsonRpcCache{
-		conn:   c,
-		logger: logger,
+		conn:        c,
+		logger:      logger,
+		compression: cfg.Compression,
+		codec:       codec,
 	}, nil
 }
 
+// Close releases the connector, e.g. the memory connector writes its snapshot to disk when persistPath is set.
+func (c *EvmJsonRpcCache) Close(ctx context.Context) error {
+	if closer, ok := c.conn.(interface{ Close(context.Context) error }); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
+
 func (c *EvmJsonRpcCache) WithNetwork(network *Network) *EvmJsonRpcCache {
 	network.Logger.Debug().Msgf("creating EvmJsonRpcCache")
 	return &EvmJsonRpcCache{
-		logger:  c.logger,
-		conn:    c.conn,
-		network: network,
+		logger:      c.logger,
+		conn:        c.conn,
+		network:     network,
+		resolver:    network.BlockResolver(),
+		compression: c.compression,
+		codec:       c.codec,
+		reorgs:      newEvmReorgTracker(),
+		policies:    c.policies,
+	}
+}
+
+// WithProjectPolicies applies per-method cache policies of a project, which take precedence over the defaults.
+func (c *EvmJsonRpcCache) WithProjectPolicies(policies []*common.CachePolicyConfig) (*EvmJsonRpcCache, error) {
+	parsed := make([]*cachePolicy, 0, len(policies))
+	for _, p := range policies {
+		cp := &cachePolicy{
+			method:           p.Method,
+			enabled:          p.Enabled == nil || *p.Enabled,
+			allowUnfinalized: p.AllowUnfinalized,
+		}
+		if p.TTL != "" {
+			ttl, err := time.ParseDuration(p.TTL)
+			if err != nil {
+				return nil, common.NewErrInvalidConfig(fmt.Sprintf("invalid cache policy ttl for method %s: %v", p.Method, err))
+			}
+			cp.ttl = ttl
+		}
+		if cp.allowUnfinalized && cp.ttl <= 0 {
+			return nil, common.NewErrInvalidConfig(fmt.Sprintf("cache policy for method %s allows unfinalized data without a ttl", p.Method))
+		}
+		parsed = append(parsed, cp)
+	}
+
+	cc := *c
+	cc.policies = parsed
+	return &cc, nil
+}
+
+// ttlFor returns how long a cached response of the request stays valid, 0 means until evicted.
+// Project policies win over the ttl derived from the network block time for realtime methods.
+func (c *EvmJsonRpcCache) ttlFor(rpcReq *common.JsonRpcRequest, policy *cachePolicy) time.Duration {
+	if policy != nil && policy.ttl > 0 {
+		return policy.ttl
+	}
+	if rpcReq.CacheTTLClass() == common.TTLClassRealtime {
+		if c.network != nil && c.network.realtimeTTL > 0 {
+			return c.network.realtimeTTL
+		}
+		return defaultEvmBlockTime
 	}
+	return 0
+}
+
+// skipsFinalityCheck tells whether responses of not yet finalized (or unknown) blocks may be cached, which is
+// only the case when the connector expires the method or the project policy explicitly allows it.
+func (c *EvmJsonRpcCache) skipsFinalityCheck(method string, policy *cachePolicy) bool {
+	return c.conn.HasTTL(method) || (policy != nil && policy.allowUnfinalized)
+}
+
+// storageTTL is how long the connector keeps an entry, entries are kept longer than their ttl when
+// the network serves stale responses on errors so that they are still there when upstreams fail.
+func (c *EvmJsonRpcCache) storageTTL(ttl time.Duration) *time.Duration {
+	if ttl <= 0 {
+		return nil
+	}
+	if c.network != nil && c.network.serveStale != nil {
+		if c.network.serveStale.maxAge <= 0 {
+			return nil
+		}
+		if c.network.serveStale.maxAge > ttl {
+			ttl = c.network.serveStale.maxAge
+		}
+	}
+	return &ttl
+}
+
+func (c *EvmJsonRpcCache) policyFor(method string) *cachePolicy {
+	for _, p := range c.policies {
+		if common.WildcardMatch(p.method, method) {
+			return p
+		}
+	}
+	return nil
 }
 
 func (c *EvmJsonRpcCache) Get(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error) {
+	return c.get(ctx, req, false, 0)
+}
+
+// GetStale is used when upstreams failed, so it ignores ttl of cache policies as long as the entry
+// was stored less than maxAge ago, responses are flagged as stale regardless of their age.
+func (c *EvmJsonRpcCache) GetStale(ctx context.Context, req *common.NormalizedRequest, maxAge time.Duration) (*common.NormalizedResponse, error) {
+	resp, err := c.get(ctx, req, true, maxAge)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	return resp.WithStale(true), nil
+}
+
+// cacheLookup tells where a request is looked up in cache, or why it cannot be served from cache.
+type cacheLookup struct {
+	ttl         time.Duration
+	blockRef    string
+	groupKey    string
+	requestKey  string
+	uncacheable string
+}
+
+func (c *EvmJsonRpcCache) lookupFor(req *common.NormalizedRequest) (*cacheLookup, error) {
 	rpcReq, err := req.JsonRpcRequest()
 	if err != nil {
 		return nil, err
 	}
 
-	hasTTL := c.conn.HasTTL(rpcReq.Method)
+	policy := c.policyFor(rpcReq.Method)
+	if policy != nil && !policy.enabled {
+		return &cacheLookup{uncacheable: "caching is disabled for this method by project policy"}, nil
+	}
+	ttl := c.ttlFor(rpcReq, policy)
+	hasTTL := c.conn.HasTTL(rpcReq.Method) || ttl > 0
+	skipFinality := c.skipsFinalityCheck(rpcReq.Method, policy)
 
 	blockRef, blockNumber, err := common.ExtractEvmBlockReferenceFromRequest(rpcReq)
 	if err != nil {
 		return nil, err
 	}
+	if rpcReq.CacheTTLClass() == common.TTLClassImmutable {
+		blockRef, blockNumber = immutableBlockRef, 0
+	}
 	if blockRef == "" && blockNumber == 0 && !hasTTL {
-		return nil, nil
+		return &cacheLookup{uncacheable: "request has no block reference or block number (e.g. latest block) and method has no ttl"}, nil
 	}
-	if blockNumber != 0 {
+	if blockNumber != 0 && !skipFinality {
 		s, err := c.shouldCacheForBlock(blockNumber)
 		if err == nil && !s {
-			return nil, nil
+			return &cacheLookup{blockRef: blockRef, uncacheable: fmt.Sprintf("block %d is not finalized yet", blockNumber)}, nil
 		}
 	}
 
@@ -84,15 +251,91 @@ func (c *EvmJsonRpcCache) Get(ctx context.Context, req *common.NormalizedRequest
 		return nil, err
 	}
 
+	return &cacheLookup{
+		ttl:        ttl,
+		blockRef:   blockRef,
+		groupKey:   groupKey,
+		requestKey: requestKey,
+	}, nil
+}
+
+// cacheKeyExplanation is returned by erpc_explainCacheKey admin method to troubleshoot cache misses.
+type cacheKeyExplanation struct {
+	CacheHash  string          `json:"cacheHash"`
+	TTLClass   common.TTLClass `json:"ttlClass"`
+	Cacheable  bool            `json:"cacheable"`
+	Reason     string          `json:"reason,omitempty"`
+	GroupKey   string          `json:"groupKey,omitempty"`
+	RequestKey string          `json:"requestKey,omitempty"`
+}
+
+// explainCacheKey computes the cache key of a request the same way cache reads do, without touching the cache.
+func (n *Network) explainCacheKey(req *common.NormalizedRequest) (*cacheKeyExplanation, error) {
+	req.SetNetwork(n)
+	rpcReq, err := req.JsonRpcRequest()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := req.CacheHash()
+	if err != nil {
+		return nil, err
+	}
+	explanation := &cacheKeyExplanation{
+		CacheHash: hash,
+		TTLClass:  rpcReq.CacheTTLClass(),
+	}
+
+	cache, ok := n.cacheDal.(*EvmJsonRpcCache)
+	if !ok || cache == nil {
+		explanation.Reason = "cache is not configured for this network"
+		return explanation, nil
+	}
+	lookup, err := cache.lookupFor(req)
+	if err != nil {
+		return nil, err
+	}
+	explanation.Cacheable = lookup.uncacheable == ""
+	explanation.Reason = lookup.uncacheable
+	explanation.GroupKey = lookup.groupKey
+	explanation.RequestKey = lookup.requestKey
+
+	return explanation, nil
+}
+
+func (c *EvmJsonRpcCache) get(ctx context.Context, req *common.NormalizedRequest, allowExpired bool, maxAge time.Duration) (*common.NormalizedResponse, error) {
+	rpcReq, err := req.JsonRpcRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	lookup, err := c.lookupFor(req)
+	if err != nil || lookup.uncacheable != "" {
+		return nil, err
+	}
+
 	var resultString string
-	if blockRef != "*" {
-		resultString, err = c.conn.Get(ctx, data.ConnectorMainIndex, groupKey, requestKey)
+	if lookup.blockRef != "*" {
+		resultString, err = c.conn.Get(ctx, data.ConnectorMainIndex, lookup.groupKey, lookup.requestKey)
 	} else {
-		resultString, err = c.conn.Get(ctx, data.ConnectorReverseIndex, groupKey, requestKey)
+		resultString, err = c.conn.Get(ctx, data.ConnectorReverseIndex, lookup.groupKey, lookup.requestKey)
+	}
+	if err != nil {
+		return nil, err
 	}
+
+	resultString, cachedAt, err := decodeCacheEntry(resultString)
 	if err != nil {
 		return nil, err
 	}
+	if !allowExpired && lookup.ttl > 0 && !cachedAt.IsZero() && time.Since(cachedAt) > lookup.ttl {
+		// Transactions of finalized blocks never change, so a ttl meant for their not yet finalized state does not apply
+		if class, _ := c.resultTTLClass(rpcReq, &common.JsonRpcResponse{Result: json.RawMessage(resultString)}); class != common.TTLClassImmutable {
+			return nil, nil
+		}
+	}
+	if allowExpired && maxAge > 0 && (cachedAt.IsZero() || time.Since(cachedAt) > maxAge) {
+		return nil, nil
+	}
 
 	if resultString == `""` || resultString == "null" || resultString == "[]" || resultString == "{}" {
 		return nil, nil
@@ -108,6 +351,7 @@ func (c *EvmJsonRpcCache) Get(ctx context.Context, req *common.NormalizedRequest
 	return common.NewNormalizedResponse().
 		WithRequest(req).
 		WithFromCache(true).
+		WithCachedAt(cachedAt).
 		WithJsonRpcResponse(jrr), nil
 }
 
@@ -124,17 +368,41 @@ func (c *EvmJsonRpcCache) Set(ctx context.Context, req *common.NormalizedRequest
 
 	lg := c.logger.With().Str("networkId", req.NetworkId()).Str("method", rpcReq.Method).Logger()
 
+	policy := c.policyFor(rpcReq.Method)
+	if policy != nil && !policy.enabled {
+		lg.Debug().Msg("will not cache the response because caching is disabled for this method by project policy")
+		return nil
+	}
+
 	shouldCache, err := shouldCache(lg, req, resp, rpcReq, rpcResp)
 	if !shouldCache || err != nil {
 		return err
 	}
+	if class, ok := c.resultTTLClass(rpcReq, rpcResp); ok && class == common.TTLClassUncacheable {
+		lg.Debug().Msg("will not cache the response because its result is not final yet (e.g. pending transaction)")
+		return nil
+	}
 
 	blockRef, blockNumber, err := common.ExtractEvmBlockReference(rpcReq, rpcResp)
 	if err != nil {
 		return err
 	}
+	if rpcReq.CacheTTLClass() == common.TTLClassImmutable {
+		blockRef, blockNumber = immutableBlockRef, 0
+	}
 
-	hasTTL := c.conn.HasTTL(rpcReq.Method)
+	ttl := c.ttlFor(rpcReq, policy)
+	hasTTL := c.conn.HasTTL(rpcReq.Method) || ttl > 0
+	skipFinality := c.skipsFinalityCheck(rpcReq.Method, policy)
+
+	if reason := c.replicaUncacheableReason(rpcReq, resp, blockRef, blockNumber); reason != "" {
+		lg.Debug().
+			Str("upstreamId", resp.UpstreamId()).
+			Str("blockRef", blockRef).
+			Int64("blockNumber", blockNumber).
+			Msgf("will not cache the response of replica upstream because %s", reason)
+		return nil
+	}
 
 	if blockRef == "" && blockNumber == 0 && !hasTTL {
 		// Do not cache if we can't resolve a block reference (e.g. latest block requests)
@@ -145,27 +413,16 @@ func (c *EvmJsonRpcCache) Set(ctx context.Context, req *common.NormalizedRequest
 		return nil
 	}
 
-	if !hasTTL {
-		if blockRef == "" && blockNumber == 0 {
-			// Do not cache if we can't resolve a block reference (e.g. latest block requests)
+	if !skipFinality && blockNumber > 0 {
+		s, e := c.shouldCacheForBlock(blockNumber)
+		if !s || e != nil {
 			lg.Debug().
+				Err(e).
 				Str("blockRef", blockRef).
 				Int64("blockNumber", blockNumber).
-				Msg("will not cache the response because it has no block reference or block number")
-			return nil
-		}
-
-		if blockNumber > 0 {
-			s, e := c.shouldCacheForBlock(blockNumber)
-			if !s || e != nil {
-				lg.Debug().
-					Err(e).
-					Str("blockRef", blockRef).
-					Int64("blockNumber", blockNumber).
-					Interface("result", rpcResp.Result).
-					Msg("will not cache the response because block is not finalized")
-				return e
-			}
+				Interface("result", rpcResp.Result).
+				Msg("will not cache the response because block is not finalized")
+			return e
 		}
 	}
 
@@ -186,10 +443,81 @@ func (c *EvmJsonRpcCache) Set(ctx context.Context, req *common.NormalizedRequest
 	if err != nil {
 		return err
 	}
+	if c.network != nil {
+		if fields := c.network.cfg.ResultFieldsToStrip(rpcReq.Method); len(fields) > 0 {
+			resultBytes, err = common.StripJsonFields(resultBytes, fields)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	entry, err := c.encodeEntry(&common.CacheEntry{
+		Result:   resultBytes,
+		BlockRef: blockRef,
+		CachedAt: time.Now(),
+		Upstream: resp.UpstreamId(),
+	})
+	if err != nil {
+		return err
+	}
 
 	ctx, cancel := context.WithTimeoutCause(ctx, 5*time.Second, errors.New("evm json-rpc cache driver timeout during set"))
 	defer cancel()
-	return c.conn.Set(ctx, pk, rk, string(resultBytes))
+
+	if skipFinality && blockNumber > 0 && c.reorgs != nil {
+		if fin, e := c.shouldCacheForBlock(blockNumber); e == nil && !fin {
+			if err := c.trackNonFinalBlock(ctx, &lg, rpcReq, rpcResp, blockRef, blockNumber); err != nil {
+				return err
+			}
+		}
+	}
+
+	return c.conn.Set(ctx, pk, rk, entry, c.storageTTL(ttl))
+}
+
+// trackNonFinalBlock records hashes of non-final blocks seen in block responses, and when a block
+// is observed with a different hash (i.e. a reorg) it removes all cached entries of the replaced blocks.
+func (c *EvmJsonRpcCache) trackNonFinalBlock(
+	ctx context.Context,
+	lg *zerolog.Logger,
+	rpcReq *common.JsonRpcRequest,
+	rpcResp *common.JsonRpcResponse,
+	blockRef string,
+	blockNumber int64,
+) error {
+	switch rpcReq.Method {
+	case "eth_getBlockByNumber", "eth_getBlockByHash":
+		result, err := rpcResp.ParsedResult()
+		if err != nil {
+			return err
+		}
+		if blk, ok := result.(map[string]interface{}); ok {
+			hash, _ := blk["hash"].(string)
+			parentHash, _ := blk["parentHash"].(string)
+			invalidated := c.reorgs.Observe(blockNumber, hash, parentHash)
+			if len(invalidated) > 0 {
+				groupKeys := make([]string, len(invalidated))
+				for i, n := range invalidated {
+					groupKeys[i] = fmt.Sprintf("%s:%d", c.network.NetworkId, n)
+				}
+				lg.Warn().
+					Int64("blockNumber", blockNumber).
+					Str("hash", hash).
+					Strs("invalidatedGroupKeys", groupKeys).
+					Msg("detected a reorg of non-final blocks, invalidating their cached entries")
+				if err := c.DeleteByGroupKey(ctx, groupKeys...); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if blockRef == strconv.FormatInt(blockNumber, 10) {
+		c.reorgs.MarkCached(blockNumber)
+	}
+
+	return nil
 }
 
 func shouldCache(
@@ -255,7 +583,7 @@ func shouldCache(
 
 func (c *EvmJsonRpcCache) DeleteByGroupKey(ctx context.Context, groupKeys ...string) error {
 	for _, groupKey := range groupKeys {
-		err := c.conn.Delete(ctx, data.ConnectorMainIndex, groupKey, "")
+		err := c.conn.Delete(ctx, data.ConnectorMainIndex, groupKey, "*")
 		if err != nil {
 			return err
 		}
@@ -264,16 +592,68 @@ func (c *EvmJsonRpcCache) DeleteByGroupKey(ctx context.Context, groupKeys ...str
 	return nil
 }
 
+// replicaUncacheableReason tells why a response served by a read replica must not be cached, or empty when it can be.
+// A replica may lag the head, so data resolved against its head (latest tag, non-finalized blocks) could be stale
+// even when a ttl is configured, while finalized blocks, block hashes and immutable data are safe to cache.
+func (c *EvmJsonRpcCache) replicaUncacheableReason(rpcReq *common.JsonRpcRequest, resp *common.NormalizedResponse, blockRef string, blockNumber int64) string {
+	ups := resp.Upstream()
+	if ups == nil {
+		return ""
+	}
+	cfg := ups.Config()
+	if cfg.Evm == nil || !cfg.Evm.Replica {
+		return ""
+	}
+
+	if cfg.Evm.ReplicaMaxLag > 0 && c.network != nil {
+		if poller, ok := c.network.evmStatePollers[cfg.Id]; ok && poller.LatestBlock() > 0 {
+			if head, err := c.resolver.HeadHeight(c.network.NetworkId); err == nil && head-poller.LatestBlock() > cfg.Evm.ReplicaMaxLag {
+				return fmt.Sprintf("it lags %d blocks behind the head (more than replicaMaxLag %d)", head-poller.LatestBlock(), cfg.Evm.ReplicaMaxLag)
+			}
+		}
+	}
+
+	if rpcReq.CacheTTLClass() == common.TTLClassImmutable || blockRef == "*" {
+		return ""
+	}
+	if blockNumber == 0 {
+		if blockRef == "" {
+			return "the response is not bound to a block (e.g. latest)"
+		}
+		// Block hash reference always points to the same block
+		return ""
+	}
+	if fin, err := c.shouldCacheForBlock(blockNumber); err != nil || !fin {
+		return fmt.Sprintf("block %d is not finalized yet", blockNumber)
+	}
+
+	return ""
+}
+
+// resultTTLClass classifies responses whose cacheability depends on the result (e.g. pending vs mined transactions).
+func (c *EvmJsonRpcCache) resultTTLClass(rpcReq *common.JsonRpcRequest, rpcResp *common.JsonRpcResponse) (common.TTLClass, bool) {
+	class, ok, err := common.EvmTxResultTTLClass(rpcReq, rpcResp, c.shouldCacheForBlock)
+	if err != nil {
+		c.logger.Debug().Err(err).Str("method", rpcReq.Method).Msg("could not classify response for caching")
+		return "", false
+	}
+	return class, ok
+}
+
 func (c *EvmJsonRpcCache) shouldCacheForBlock(blockNumber int64) (bool, error) {
-	b, e := c.network.EvmIsBlockFinalized(blockNumber)
-	return b, e
+	return common.EvmIsBlockFinalizedByResolver(c.resolver, c.network.NetworkId, blockNumber)
 }
 
+// Request keys are prefixed with the version of the request hashing, so that entries written by an older
+// hashing are never served when the way keys are derived changes. Bump it along with common.hashValue.
+const cacheKeyVersion = "v2"
+
 func generateKeysForJsonRpcRequest(req *common.NormalizedRequest, blockRef string) (string, string, error) {
-	cacheKey, err := req.CacheHash()
+	hash, err := req.CacheHash()
 	if err != nil {
 		return "", "", err
 	}
+	cacheKey := cacheKeyVersion + ":" + hash
 
 	if blockRef != "" {
 		return fmt.Sprintf("%s:%s", req.NetworkId(), blockRef), cacheKey, nil
@@ -282,7 +662,191 @@ func generateKeysForJsonRpcRequest(req *common.NormalizedRequest, blockRef strin
 	}
 }
 
+// Cache entries are stored as "v2|<unix-timestamp-millis>|<json-result>" so that the age of the entry can be
+// reported to clients. Compressed entries use "v2z|<unix-timestamp-millis>|<base64-gzip-result>" instead.
+// Raw json results written before entries had a format are still accepted.
+//
+// When an entry codec is configured, entries are stored as "v2e|<codec>|<payload>" where the payload is the
+// encoded common.CacheEntry, base64 encoded for binary codecs so that it is safe in text columns. Compressed
+// entries use "v2ez|<codec>|<base64-gzip-payload>" instead. The codec is named in the entry, so changing it
+// does not make the existing entries unreadable.
+const (
+	cacheEntryPrefix           = "v2|"
+	compressedCacheEntryPrefix = "v2z|"
+
+	codecCacheEntryPrefix           = "v2e|"
+	compressedCodecCacheEntryPrefix = "v2ez|"
+)
+
+func encodeCacheEntry(result string, cachedAt time.Time) string {
+	return fmt.Sprintf("%s%s|%s", cacheEntryPrefix, formatCacheTimestamp(cachedAt), result)
+}
+
+func encodeCompressedCacheEntry(result string, cachedAt time.Time) (string, error) {
+	compressed, err := gzipCacheEntry([]byte(result))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%s|%s", compressedCacheEntryPrefix, formatCacheTimestamp(cachedAt), compressed), nil
+}
+
+func encodeCodecCacheEntry(codec common.CacheEntryCodec, entry *common.CacheEntry, compress bool) (string, error) {
+	payload, err := codec.Encode(entry)
+	if err != nil {
+		return "", err
+	}
+	if compress {
+		compressed, err := gzipCacheEntry(payload)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s%s|%s", compressedCodecCacheEntryPrefix, codec.Name(), compressed), nil
+	}
+	if codec.Name() == "json" {
+		return fmt.Sprintf("%s%s|%s", codecCacheEntryPrefix, codec.Name(), payload), nil
+	}
+	return fmt.Sprintf("%s%s|%s", codecCacheEntryPrefix, codec.Name(), base64.StdEncoding.EncodeToString(payload)), nil
+}
+
+func gzipCacheEntry(data []byte) (string, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func gunzipCacheEntry(data string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// Timestamps carry milliseconds (e.g. "1700000000.250") so that sub-second ttls of realtime methods
+// on fast chains can be honored.
+func formatCacheTimestamp(t time.Time) string {
+	return fmt.Sprintf("%d.%03d", t.Unix(), t.Nanosecond()/int(time.Millisecond))
+}
+
+func parseCacheTimestamp(ts string) (time.Time, error) {
+	secs, millis, hasMillis := strings.Cut(ts, ".")
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var ms int64
+	if hasMillis {
+		ms, err = strconv.ParseInt(millis, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(sec, ms*int64(time.Millisecond)), nil
+}
+
+func decodeCodecCacheEntry(rest string, compressed bool) (string, time.Time, error) {
+	sep := strings.IndexByte(rest, '|')
+	if sep == -1 {
+		return "", time.Time{}, fmt.Errorf("malformed cache entry, missing codec separator")
+	}
+	codec, err := common.GetCacheEntryCodec(rest[:sep])
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	var payload []byte
+	switch {
+	case compressed:
+		payload, err = gunzipCacheEntry(rest[sep+1:])
+	case codec.Name() == "json":
+		payload = []byte(rest[sep+1:])
+	default:
+		payload, err = base64.StdEncoding.DecodeString(rest[sep+1:])
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	entry, err := codec.Decode(payload)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("malformed %s cache entry: %w", codec.Name(), err)
+	}
+	return string(entry.Result), entry.CachedAt, nil
+}
+
+func decodeCacheEntry(entry string) (string, time.Time, error) {
+	switch {
+	case strings.HasPrefix(entry, compressedCodecCacheEntryPrefix):
+		return decodeCodecCacheEntry(entry[len(compressedCodecCacheEntryPrefix):], true)
+	case strings.HasPrefix(entry, codecCacheEntryPrefix):
+		return decodeCodecCacheEntry(entry[len(codecCacheEntryPrefix):], false)
+	}
+
+	var rest string
+	var compressed bool
+	switch {
+	case strings.HasPrefix(entry, compressedCacheEntryPrefix):
+		rest, compressed = entry[len(compressedCacheEntryPrefix):], true
+	case strings.HasPrefix(entry, cacheEntryPrefix):
+		rest = entry[len(cacheEntryPrefix):]
+	default:
+		return entry, time.Time{}, nil
+	}
+
+	sep := strings.IndexByte(rest, '|')
+	if sep == -1 {
+		return "", time.Time{}, fmt.Errorf("malformed cache entry, missing timestamp separator")
+	}
+	cachedAt, err := parseCacheTimestamp(rest[:sep])
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("malformed cache entry timestamp: %w", err)
+	}
+	result := rest[sep+1:]
+
+	if compressed {
+		decompressed, err := gunzipCacheEntry(result)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		result = string(decompressed)
+	}
+
+	return result, cachedAt, nil
+}
+
+func (c *EvmJsonRpcCache) encodeEntry(entry *common.CacheEntry) (string, error) {
+	compress := c.compression != nil && c.compression.Enabled && len(entry.Result) >= c.compression.Threshold
+	if c.codec != nil {
+		return encodeCodecCacheEntry(c.codec, entry, compress)
+	}
+	if compress {
+		return encodeCompressedCacheEntry(string(entry.Result), entry.CachedAt)
+	}
+	return encodeCacheEntry(string(entry.Result), entry.CachedAt), nil
+}
+
 func populateDefaults(cfg *common.ConnectorConfig) error {
+	if cfg.Compression != nil && cfg.Compression.Enabled {
+		if cfg.Compression.Algorithm == "" {
+			cfg.Compression.Algorithm = "gzip"
+		}
+		if cfg.Compression.Algorithm != "gzip" {
+			return common.NewErrInvalidConfig(fmt.Sprintf("unsupported cache compression algorithm: %s", cfg.Compression.Algorithm))
+		}
+		if cfg.Compression.Threshold <= 0 {
+			cfg.Compression.Threshold = 1024
+		}
+	}
+
 	switch cfg.Driver {
 	case data.DynamoDBDriverName:
 		if cfg.DynamoDB.Table == "" {
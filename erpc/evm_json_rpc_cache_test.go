@@ -2,6 +2,9 @@ package erpc
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -52,9 +55,10 @@ func createCacheTestFixtures(finBlockNumber int64, latestBlockNumber int64, sync
 		"upsA": poller,
 	}
 	cache := &EvmJsonRpcCache{
-		conn:    mockConnector,
-		logger:  &logger,
-		network: mockNetwork,
+		conn:     mockConnector,
+		logger:   &logger,
+		network:  mockNetwork,
+		resolver: newEvmStatePollerBlockResolver(mockNetwork),
 	}
 	return mockConnector, mockNetwork, cache
 }
@@ -79,13 +83,13 @@ func TestEvmJsonRpcCache_Set(t *testing.T) {
 		req.SetNetwork(mockNetwork)
 		resp := common.NewNormalizedResponse().WithBody([]byte(`{"result":{"hash":"0xabc","blockNumber":"0x2"}}`))
 
-		mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 		mockConnector.On("HasTTL", mock.AnythingOfType("string")).Return(false)
 
 		err := cache.Set(context.Background(), req, resp)
 
 		assert.NoError(t, err)
-		mockConnector.AssertCalled(t, "Set", mock.Anything, "evm:123:*", mock.Anything, mock.Anything)
+		mockConnector.AssertCalled(t, "Set", mock.Anything, "evm:123:*", mock.Anything, mock.Anything, mock.Anything)
 	})
 
 	t.Run("CacheIfBlockNumberIsFinalizedWhenBlockIsUsedForPrimaryKey", func(t *testing.T) {
@@ -95,13 +99,13 @@ func TestEvmJsonRpcCache_Set(t *testing.T) {
 		req.SetNetwork(mockNetwork)
 		resp := common.NewNormalizedResponse().WithBody([]byte(`{"result":{"hash":"0xabc","number":"0x2"}}`))
 
-		mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 		mockConnector.On("HasTTL", mock.AnythingOfType("string")).Return(false)
 
 		err := cache.Set(context.Background(), req, resp)
 
 		assert.NoError(t, err)
-		mockConnector.AssertCalled(t, "Set", mock.Anything, "evm:123:2", mock.Anything, mock.Anything)
+		mockConnector.AssertCalled(t, "Set", mock.Anything, "evm:123:2", mock.Anything, mock.Anything, mock.Anything)
 	})
 
 	t.Run("SkipWhenNoRefAndNoBlockNumberFound", func(t *testing.T) {
@@ -148,7 +152,7 @@ func TestEvmJsonRpcCache_Set(t *testing.T) {
 				req.SetNetwork(mockNetwork)
 				resp := common.NewNormalizedResponse().WithBody([]byte(tc.result))
 
-				mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+				mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 				mockConnector.On("HasTTL", mock.AnythingOfType("string")).Return(false)
 
 				err := cache.Set(context.Background(), req, resp)
@@ -156,7 +160,7 @@ func TestEvmJsonRpcCache_Set(t *testing.T) {
 				assert.NoError(t, err)
 				mockConnector.AssertCalled(t, "Set", mock.Anything, mock.MatchedBy(func(key string) bool {
 					return key == "evm:123:"+tc.expectedRef
-				}), mock.Anything, mock.Anything)
+				}), mock.Anything, mock.Anything, mock.Anything)
 			})
 		}
 	})
@@ -168,13 +172,13 @@ func TestEvmJsonRpcCache_Set(t *testing.T) {
 		req.SetNetwork(mockNetwork)
 		resp := common.NewNormalizedResponse().WithBody([]byte(`{"result":{"number":"0x1","hash":"0xabc"}}`))
 
-		mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 		mockConnector.On("HasTTL", mock.AnythingOfType("string")).Return(false)
 
 		err := cache.Set(context.Background(), req, resp)
 
 		assert.NoError(t, err)
-		mockConnector.AssertCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockConnector.AssertCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 	})
 
 	t.Run("SkipCachingForUnfinalizedBlock", func(t *testing.T) {
@@ -253,13 +257,31 @@ func TestEvmJsonRpcCache_Set(t *testing.T) {
 		req.SetNetwork(mockNetwork)
 		resp := common.NewNormalizedResponse().WithBody([]byte(`{"result":"0x0"}`))
 
-		mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 		mockConnector.On("HasTTL", mock.AnythingOfType("string")).Return(false)
 
 		err := cache.Set(context.Background(), req, resp)
 
 		assert.NoError(t, err)
-		mockConnector.AssertCalled(t, "Set", mock.Anything, "evm:123:5", mock.Anything, mock.Anything)
+		mockConnector.AssertCalled(t, "Set", mock.Anything, "evm:123:5", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("CacheImmutableMethodRegardlessOfFinality", func(t *testing.T) {
+		mockConnector, mockNetwork, cache := createCacheTestFixtures(0, 0, nil)
+
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_chainId","params":[],"id":1}`))
+		req.SetNetwork(mockNetwork)
+		resp := common.NewNormalizedResponse().WithBody([]byte(`{"result":"0x7b"}`))
+
+		mockConnector.On("HasTTL", mock.AnythingOfType("string")).Return(false)
+		mockConnector.On("Set", mock.Anything, "evm:123:immutable", mock.Anything, mock.MatchedBy(func(v string) bool {
+			return strings.HasSuffix(v, `|"0x7b"`)
+		}), mock.Anything).Return(nil)
+
+		err := cache.Set(context.Background(), req, resp)
+
+		assert.NoError(t, err)
+		mockConnector.AssertExpectations(t)
 	})
 }
 
@@ -284,6 +306,27 @@ func TestEvmJsonRpcCache_Get(t *testing.T) {
 		assert.Equal(t, cachedResponse, string(jrr.Result))
 	})
 
+	t.Run("ReturnCacheAgeForTimestampedEntries", func(t *testing.T) {
+		mockConnector, mockNetwork, cache := createCacheTestFixtures(10, 15, nil)
+
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["0x1",false],"id":1}`))
+		req.SetNetwork(mockNetwork)
+
+		cachedAt := time.Now().Add(-1 * time.Hour)
+		cachedResponse := `{"number":"0x1","hash":"0xabc"}`
+		mockConnector.On("Get", mock.Anything, mock.Anything, "evm:123:1", mock.Anything).Return(encodeCacheEntry(cachedResponse, cachedAt), nil)
+		mockConnector.On("HasTTL", mock.AnythingOfType("string")).Return(false)
+
+		resp, err := cache.Get(context.Background(), req)
+
+		assert.NoError(t, err)
+		assert.True(t, resp.FromCache())
+		assert.InDelta(t, time.Hour.Seconds(), resp.CacheAge().Seconds(), 5)
+		jrr, err := resp.JsonRpcResponse()
+		assert.NoError(t, err)
+		assert.Equal(t, cachedResponse, string(jrr.Result))
+	})
+
 	t.Run("SkipCacheForUnfinalizedBlock", func(t *testing.T) {
 		mockConnector, mockNetwork, cache := createCacheTestFixtures(10, 15, nil)
 
@@ -297,3 +340,867 @@ func TestEvmJsonRpcCache_Get(t *testing.T) {
 		mockConnector.AssertNotCalled(t, "Get")
 	})
 }
+
+func TestEvmJsonRpcCache_Reorg(t *testing.T) {
+	setBlock := func(t *testing.T, cache *EvmJsonRpcCache, network *Network, number int64, hash, parentHash string) {
+		req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["0x%x",false],"id":1}`, number)))
+		req.SetNetwork(network)
+		resp := common.NewNormalizedResponse().WithBody([]byte(fmt.Sprintf(`{"result":{"number":"0x%x","hash":"%s","parentHash":"%s"}}`, number, hash, parentHash)))
+		assert.NoError(t, cache.Set(context.Background(), req, resp))
+	}
+
+	t.Run("InvalidateNonFinalBlocksOnReorgAtDepthTwo", func(t *testing.T) {
+		mockConnector, mockNetwork, cache := createCacheTestFixtures(5, 15, nil)
+		cache.reorgs = newEvmReorgTracker()
+		mockConnector.On("HasTTL", mock.AnythingOfType("string")).Return(true)
+		mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockConnector.On("Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		setBlock(t, cache, mockNetwork, 8, "0xa8", "0xa7")
+		setBlock(t, cache, mockNetwork, 9, "0xa9", "0xa8")
+		setBlock(t, cache, mockNetwork, 10, "0xa10", "0xa9")
+		mockConnector.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+		// Block 10 is replaced by a block whose parent is a different block 9
+		setBlock(t, cache, mockNetwork, 10, "0xb10", "0xb9")
+
+		mockConnector.AssertCalled(t, "Delete", mock.Anything, data.ConnectorMainIndex, "evm:123:9", "*")
+		mockConnector.AssertCalled(t, "Delete", mock.Anything, data.ConnectorMainIndex, "evm:123:10", "*")
+		mockConnector.AssertNotCalled(t, "Delete", mock.Anything, data.ConnectorMainIndex, "evm:123:8", "*")
+		mockConnector.AssertCalled(t, "Set", mock.Anything, "evm:123:10", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("KeepEntriesWhenSameHashIsObservedAgain", func(t *testing.T) {
+		mockConnector, mockNetwork, cache := createCacheTestFixtures(5, 15, nil)
+		cache.reorgs = newEvmReorgTracker()
+		mockConnector.On("HasTTL", mock.AnythingOfType("string")).Return(true)
+		mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		setBlock(t, cache, mockNetwork, 9, "0xa9", "0xa8")
+		setBlock(t, cache, mockNetwork, 10, "0xa10", "0xa9")
+		setBlock(t, cache, mockNetwork, 10, "0xa10", "0xa9")
+
+		mockConnector.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("IgnoreFinalizedBlocks", func(t *testing.T) {
+		mockConnector, mockNetwork, cache := createCacheTestFixtures(10, 15, nil)
+		cache.reorgs = newEvmReorgTracker()
+		mockConnector.On("HasTTL", mock.AnythingOfType("string")).Return(true)
+		mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		setBlock(t, cache, mockNetwork, 9, "0xa9", "0xa8")
+		setBlock(t, cache, mockNetwork, 9, "0xb9", "0xb8")
+
+		mockConnector.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestEvmJsonRpcCache_Compression(t *testing.T) {
+	newCompressedCache := func(t *testing.T) (*EvmJsonRpcCache, *Network) {
+		_, mockNetwork, _ := createCacheTestFixtures(10, 15, nil)
+		logger := zerolog.New(zerolog.NewConsoleWriter())
+		cache, err := NewEvmJsonRpcCache(context.Background(), &logger, &common.ConnectorConfig{
+			Driver: "memory",
+			Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+			Compression: &common.CompressionConfig{
+				Enabled:   true,
+				Threshold: 512,
+			},
+		})
+		assert.NoError(t, err)
+		return cache.WithNetwork(mockNetwork), mockNetwork
+	}
+
+	t.Run("RoundTripLargeCompressibleResult", func(t *testing.T) {
+		cache, mockNetwork := newCompressedCache(t)
+
+		logs := make([]string, 0, 200)
+		for i := 0; i < 200; i++ {
+			logs = append(logs, fmt.Sprintf(`{"address":"0x1111111111111111111111111111111111111111","blockNumber":"0x1","logIndex":"0x%x","data":"0x0000000000000000000000000000000000000000000000000000000000000001"}`, i))
+		}
+		result := "[" + strings.Join(logs, ",") + "]"
+
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_getBlockReceipts","params":["0x1"],"id":1}`))
+		req.SetNetwork(mockNetwork)
+		resp := common.NewNormalizedResponse().WithBody([]byte(`{"result":` + result + `}`))
+
+		err := cache.Set(context.Background(), req, resp)
+		assert.NoError(t, err)
+
+		stored, err := cache.conn.Get(context.Background(), data.ConnectorMainIndex, "evm:123:1", mustRequestKey(t, req))
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(stored, compressedCacheEntryPrefix))
+		assert.Less(t, len(stored), len(result))
+
+		cached, err := cache.Get(context.Background(), req)
+		assert.NoError(t, err)
+		jrr, err := cached.JsonRpcResponse()
+		assert.NoError(t, err)
+		assert.Equal(t, result, string(jrr.Result))
+	})
+
+	t.Run("SkipCompressionForTinyResult", func(t *testing.T) {
+		cache, mockNetwork := newCompressedCache(t)
+
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","0x1"],"id":1}`))
+		req.SetNetwork(mockNetwork)
+		resp := common.NewNormalizedResponse().WithBody([]byte(`{"result":"0x100"}`))
+
+		err := cache.Set(context.Background(), req, resp)
+		assert.NoError(t, err)
+
+		stored, err := cache.conn.Get(context.Background(), data.ConnectorMainIndex, "evm:123:1", mustRequestKey(t, req))
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(stored, cacheEntryPrefix))
+
+		cached, err := cache.Get(context.Background(), req)
+		assert.NoError(t, err)
+		jrr, err := cached.JsonRpcResponse()
+		assert.NoError(t, err)
+		assert.Equal(t, `"0x100"`, string(jrr.Result))
+	})
+}
+
+func TestEvmJsonRpcCache_EntryCodec(t *testing.T) {
+	newCodecCache := func(t *testing.T, codec string, compression *common.CompressionConfig) (*EvmJsonRpcCache, *Network) {
+		_, mockNetwork, _ := createCacheTestFixtures(10, 15, nil)
+		logger := zerolog.New(zerolog.NewConsoleWriter())
+		cache, err := NewEvmJsonRpcCache(context.Background(), &logger, &common.ConnectorConfig{
+			Driver:      "memory",
+			Memory:      &common.MemoryConnectorConfig{MaxItems: 100},
+			Compression: compression,
+			EntryCodec:  codec,
+		})
+		assert.NoError(t, err)
+		return cache.WithNetwork(mockNetwork), mockNetwork
+	}
+
+	for _, codec := range []string{"json", "msgpack", "gob"} {
+		for _, compressed := range []bool{false, true} {
+			t.Run(fmt.Sprintf("RoundTrip%sCompressed%v", codec, compressed), func(t *testing.T) {
+				var compression *common.CompressionConfig
+				prefix := codecCacheEntryPrefix + codec + "|"
+				if compressed {
+					compression = &common.CompressionConfig{Enabled: true, Threshold: 1}
+					prefix = compressedCodecCacheEntryPrefix + codec + "|"
+				}
+				cache, mockNetwork := newCodecCache(t, codec, compression)
+
+				req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","0x1"],"id":1}`))
+				req.SetNetwork(mockNetwork)
+				resp := common.NewNormalizedResponse().WithBody([]byte(`{"result":"0x100"}`))
+				assert.NoError(t, cache.Set(context.Background(), req, resp))
+
+				stored, err := cache.conn.Get(context.Background(), data.ConnectorMainIndex, "evm:123:1", mustRequestKey(t, req))
+				assert.NoError(t, err)
+				assert.True(t, strings.HasPrefix(stored, prefix))
+				result, cachedAt, err := decodeCacheEntry(stored)
+				assert.NoError(t, err)
+				assert.Equal(t, `"0x100"`, result)
+				assert.WithinDuration(t, time.Now(), cachedAt, time.Minute)
+
+				cached, err := cache.Get(context.Background(), req)
+				assert.NoError(t, err)
+				jrr, err := cached.JsonRpcResponse()
+				assert.NoError(t, err)
+				assert.Equal(t, `"0x100"`, string(jrr.Result))
+			})
+		}
+	}
+
+	t.Run("EntriesOfAnotherCodecAreStillRead", func(t *testing.T) {
+		cachedAt := time.UnixMilli(1700000000250)
+		msgpack, err := common.GetCacheEntryCodec("msgpack")
+		assert.NoError(t, err)
+		entry, err := encodeCodecCacheEntry(msgpack, &common.CacheEntry{Result: []byte(`"0x1"`), BlockRef: "1", CachedAt: cachedAt}, false)
+		assert.NoError(t, err)
+
+		cache, mockNetwork := newCodecCache(t, "gob", nil)
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","0x1"],"id":1}`))
+		req.SetNetwork(mockNetwork)
+		assert.NoError(t, cache.conn.Set(context.Background(), "evm:123:1", mustRequestKey(t, req), entry, nil))
+
+		cached, err := cache.Get(context.Background(), req)
+		assert.NoError(t, err)
+		jrr, err := cached.JsonRpcResponse()
+		assert.NoError(t, err)
+		assert.Equal(t, `"0x1"`, string(jrr.Result))
+	})
+
+	t.Run("UnknownCodecIsRejected", func(t *testing.T) {
+		logger := zerolog.New(zerolog.NewConsoleWriter())
+		_, err := NewEvmJsonRpcCache(context.Background(), &logger, &common.ConnectorConfig{
+			Driver:     "memory",
+			Memory:     &common.MemoryConnectorConfig{MaxItems: 100},
+			EntryCodec: "protobuf",
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestEvmJsonRpcCache_Close(t *testing.T) {
+	t.Run("PersistsMemoryConnectorWithoutWaitingForContext", func(t *testing.T) {
+		_, mockNetwork, _ := createCacheTestFixtures(10, 15, nil)
+		logger := zerolog.New(zerolog.NewConsoleWriter())
+		cfg := &common.ConnectorConfig{
+			Driver: "memory",
+			Memory: &common.MemoryConnectorConfig{MaxItems: 100, PersistPath: filepath.Join(t.TempDir(), "cache.json")},
+		}
+		cache, err := NewEvmJsonRpcCache(context.Background(), &logger, cfg)
+		assert.NoError(t, err)
+		cache = cache.WithNetwork(mockNetwork)
+
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","0x1"],"id":1}`))
+		req.SetNetwork(mockNetwork)
+		assert.NoError(t, cache.Set(context.Background(), req, common.NewNormalizedResponse().WithBody([]byte(`{"result":"0x100"}`))))
+		assert.NoError(t, cache.Close(context.Background()))
+
+		reloaded, err := NewEvmJsonRpcCache(context.Background(), &logger, cfg)
+		assert.NoError(t, err)
+		cached, err := reloaded.WithNetwork(mockNetwork).Get(context.Background(), req)
+		assert.NoError(t, err)
+		if assert.NotNil(t, cached) {
+			jrr, err := cached.JsonRpcResponse()
+			assert.NoError(t, err)
+			assert.Equal(t, `"0x100"`, string(jrr.Result))
+		}
+	})
+}
+
+func mustRequestKey(t *testing.T, req *common.NormalizedRequest) string {
+	t.Helper()
+	hash, err := req.CacheHash()
+	assert.NoError(t, err)
+	return cacheKeyVersion + ":" + hash
+}
+
+func TestEvmJsonRpcCache_ProjectPolicies(t *testing.T) {
+	disabled := false
+
+	newProjectCaches := func(t *testing.T, policiesA, policiesB []*common.CachePolicyConfig) (*EvmJsonRpcCache, *EvmJsonRpcCache, *Network) {
+		t.Helper()
+		_, mockNetwork, _ := createCacheTestFixtures(10, 15, nil)
+		logger := zerolog.New(zerolog.NewConsoleWriter())
+		base, err := NewEvmJsonRpcCache(context.Background(), &logger, &common.ConnectorConfig{
+			Driver: "memory",
+			Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+		})
+		assert.NoError(t, err)
+
+		// Both projects share the same underlying cache storage
+		cacheA, err := base.WithNetwork(mockNetwork).WithProjectPolicies(policiesA)
+		assert.NoError(t, err)
+		cacheB, err := base.WithNetwork(mockNetwork).WithProjectPolicies(policiesB)
+		assert.NoError(t, err)
+		return cacheA, cacheB, mockNetwork
+	}
+
+	newGetLogs := func(network *Network, toBlock string) (*common.NormalizedRequest, *common.NormalizedResponse) {
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_getLogs","params":[{"fromBlock":"0x1","toBlock":"` + toBlock + `"}],"id":1}`))
+		req.SetNetwork(network)
+		resp := common.NewNormalizedResponse().WithBody([]byte(`{"result":[{"logIndex":"0x0","blockNumber":"0x2"}]}`))
+		return req, resp
+	}
+
+	t.Run("ProjectAEnablesAndProjectBDisablesSameRequest", func(t *testing.T) {
+		cacheA, cacheB, network := newProjectCaches(t,
+			[]*common.CachePolicyConfig{{Method: "eth_getLogs", TTL: "1h", AllowUnfinalized: true}},
+			[]*common.CachePolicyConfig{{Method: "eth_getLogs", Enabled: &disabled}},
+		)
+
+		// Block 0x14 is not finalized yet, only project A's policy allows caching it
+		req, resp := newGetLogs(network, "0x14")
+		assert.NoError(t, cacheA.Set(context.Background(), req, resp))
+
+		cached, err := cacheA.Get(context.Background(), req)
+		assert.NoError(t, err)
+		if assert.NotNil(t, cached) {
+			assert.True(t, cached.FromCache())
+		}
+
+		cached, _ = cacheB.Get(context.Background(), req)
+		assert.Nil(t, cached)
+
+		// Project B never writes even for finalized blocks
+		req, resp = newGetLogs(network, "0x5")
+		assert.NoError(t, cacheB.Set(context.Background(), req, resp))
+		cached, _ = cacheA.Get(context.Background(), req)
+		assert.Nil(t, cached)
+	})
+
+	t.Run("GlobPatternsMatchMethods", func(t *testing.T) {
+		cacheA, cacheB, network := newProjectCaches(t,
+			nil,
+			[]*common.CachePolicyConfig{{Method: "eth_get*", Enabled: &disabled}},
+		)
+
+		req, resp := newGetLogs(network, "0x5")
+		assert.NoError(t, cacheA.Set(context.Background(), req, resp))
+
+		cached, err := cacheA.Get(context.Background(), req)
+		assert.NoError(t, err)
+		assert.NotNil(t, cached)
+
+		cached, _ = cacheB.Get(context.Background(), req)
+		assert.Nil(t, cached)
+	})
+
+	t.Run("FirstMatchingPolicyWins", func(t *testing.T) {
+		_, cacheB, network := newProjectCaches(t,
+			nil,
+			[]*common.CachePolicyConfig{
+				{Method: "eth_getLogs"},
+				{Method: "*", Enabled: &disabled},
+			},
+		)
+
+		req, resp := newGetLogs(network, "0x5")
+		assert.NoError(t, cacheB.Set(context.Background(), req, resp))
+		cached, err := cacheB.Get(context.Background(), req)
+		assert.NoError(t, err)
+		assert.NotNil(t, cached)
+	})
+
+	t.Run("EntriesOlderThanTtlOverrideAreMissed", func(t *testing.T) {
+		cacheA, cacheB, network := newProjectCaches(t,
+			[]*common.CachePolicyConfig{{Method: "eth_getLogs", TTL: "1m"}},
+			nil,
+		)
+
+		req, _ := newGetLogs(network, "0x5")
+		rpcReq, err := req.JsonRpcRequest()
+		assert.NoError(t, err)
+		blockRef, _, err := common.ExtractEvmBlockReferenceFromRequest(rpcReq)
+		assert.NoError(t, err)
+		pk, rk, err := generateKeysForJsonRpcRequest(req, blockRef)
+		assert.NoError(t, err)
+		entry, err := cacheA.encodeEntry(&common.CacheEntry{Result: []byte(`[{"logIndex":"0x0"}]`), CachedAt: time.Now().Add(-2 * time.Minute)})
+		assert.NoError(t, err)
+		assert.NoError(t, cacheA.conn.Set(context.Background(), pk, rk, entry, nil))
+
+		cached, err := cacheA.Get(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Nil(t, cached)
+
+		// Without an override the finalized entry is still served
+		cached, err = cacheB.Get(context.Background(), req)
+		assert.NoError(t, err)
+		assert.NotNil(t, cached)
+	})
+
+	t.Run("TtlAloneKeepsFinalityCheck", func(t *testing.T) {
+		cacheA, _, network := newProjectCaches(t,
+			[]*common.CachePolicyConfig{{Method: "eth_getLogs", TTL: "1h"}},
+			nil,
+		)
+
+		req, resp := newGetLogs(network, "0x14")
+		assert.NoError(t, cacheA.Set(context.Background(), req, resp))
+		cached, _ := cacheA.Get(context.Background(), req)
+		assert.Nil(t, cached)
+
+		req, resp = newGetLogs(network, "0x5")
+		assert.NoError(t, cacheA.Set(context.Background(), req, resp))
+		cached, err := cacheA.Get(context.Background(), req)
+		assert.NoError(t, err)
+		assert.NotNil(t, cached)
+	})
+
+	t.Run("TtlIsPassedToConnector", func(t *testing.T) {
+		mockConnector, mockNetwork, cache := createCacheTestFixtures(10, 15, nil)
+		cache, err := cache.WithProjectPolicies([]*common.CachePolicyConfig{{Method: "eth_getLogs", TTL: "1h"}})
+		assert.NoError(t, err)
+		mockConnector.On("HasTTL", mock.AnythingOfType("string")).Return(false)
+		mockConnector.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(func(ttl *time.Duration) bool {
+			return ttl != nil && *ttl == time.Hour
+		})).Return(nil)
+
+		req, resp := newGetLogs(mockNetwork, "0x5")
+		assert.NoError(t, cache.Set(context.Background(), req, resp))
+		mockConnector.AssertExpectations(t)
+	})
+
+	t.Run("InvalidTtlIsRejected", func(t *testing.T) {
+		_, _, cache := createCacheTestFixtures(10, 15, nil)
+		_, err := cache.WithProjectPolicies([]*common.CachePolicyConfig{{Method: "eth_getLogs", TTL: "soon"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("AllowUnfinalizedWithoutTtlIsRejected", func(t *testing.T) {
+		_, _, cache := createCacheTestFixtures(10, 15, nil)
+		_, err := cache.WithProjectPolicies([]*common.CachePolicyConfig{{Method: "eth_getLogs", AllowUnfinalized: true}})
+		assert.Error(t, err)
+	})
+}
+
+func TestEvmJsonRpcCache_TransactionFinality(t *testing.T) {
+	newCache := func(t *testing.T) (*EvmJsonRpcCache, *Network) {
+		t.Helper()
+		_, mockNetwork, _ := createCacheTestFixtures(10, 15, nil)
+		logger := zerolog.New(zerolog.NewConsoleWriter())
+		base, err := NewEvmJsonRpcCache(context.Background(), &logger, &common.ConnectorConfig{
+			Driver: "memory",
+			Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+		})
+		assert.NoError(t, err)
+		// A short ttl allows caching transactions of not yet finalized blocks
+		cache, err := base.WithNetwork(mockNetwork).WithProjectPolicies([]*common.CachePolicyConfig{
+			{Method: "eth_getTransaction*", TTL: "1m", AllowUnfinalized: true},
+		})
+		assert.NoError(t, err)
+		return cache, mockNetwork
+	}
+	newTx := func(network *Network, hash string) *common.NormalizedRequest {
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_getTransactionByHash","params":["` + hash + `"],"id":1}`))
+		req.SetNetwork(network)
+		return req
+	}
+	// storeAged writes the result as the cache would, but as if it was cached 2 minutes ago
+	storeAged := func(t *testing.T, cache *EvmJsonRpcCache, req *common.NormalizedRequest, result string) {
+		t.Helper()
+		rpcReq, err := req.JsonRpcRequest()
+		assert.NoError(t, err)
+		blockRef, _, err := common.ExtractEvmBlockReference(rpcReq, &common.JsonRpcResponse{Result: []byte(result)})
+		assert.NoError(t, err)
+		pk, rk, err := generateKeysForJsonRpcRequest(req, blockRef)
+		assert.NoError(t, err)
+		entry, err := cache.encodeEntry(&common.CacheEntry{Result: []byte(result), CachedAt: time.Now().Add(-2 * time.Minute)})
+		assert.NoError(t, err)
+		assert.NoError(t, cache.conn.Set(context.Background(), pk, rk, entry, nil))
+	}
+
+	t.Run("FinalizedTransactionIsCachedPermanently", func(t *testing.T) {
+		cache, network := newCache(t)
+		req := newTx(network, "0xaaa")
+		storeAged(t, cache, req, `{"hash":"0xaaa","blockHash":"0xb5","blockNumber":"0x5"}`)
+
+		cached, err := cache.Get(context.Background(), req)
+		assert.NoError(t, err)
+		if assert.NotNil(t, cached) {
+			jrr, err := cached.JsonRpcResponse()
+			assert.NoError(t, err)
+			assert.Contains(t, string(jrr.Result), `"blockNumber":"0x5"`)
+		}
+	})
+
+	t.Run("UnfinalizedTransactionExpiresWithTtl", func(t *testing.T) {
+		cache, network := newCache(t)
+		req := newTx(network, "0xbbb")
+		storeAged(t, cache, req, `{"hash":"0xbbb","blockHash":"0xb14","blockNumber":"0x14"}`)
+
+		cached, err := cache.Get(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Nil(t, cached)
+	})
+
+	t.Run("PendingTransactionIsNotCachedEvenWithConnectorTtl", func(t *testing.T) {
+		mockConnector, mockNetwork, cache := createCacheTestFixtures(10, 15, nil)
+		mockConnector.On("HasTTL", mock.AnythingOfType("string")).Return(true)
+		req := newTx(mockNetwork, "0xddd")
+		resp := common.NewNormalizedResponse().WithBody([]byte(`{"result":{"hash":"0xddd","blockHash":null,"blockNumber":null}}`))
+
+		assert.NoError(t, cache.Set(context.Background(), req, resp))
+		mockConnector.AssertNotCalled(t, "Set")
+	})
+
+	t.Run("PendingTransactionIsNotCached", func(t *testing.T) {
+		cache, network := newCache(t)
+		req := newTx(network, "0xccc")
+		resp := common.NewNormalizedResponse().WithBody([]byte(`{"result":{"hash":"0xccc","blockHash":null,"blockNumber":null}}`))
+		assert.NoError(t, cache.Set(context.Background(), req, resp))
+
+		cached, _ := cache.Get(context.Background(), req)
+		assert.Nil(t, cached)
+	})
+}
+
+func TestEvmJsonRpcCache_StripResultFields(t *testing.T) {
+	_, mockNetwork, _ := createCacheTestFixtures(10, 15, nil)
+	mockNetwork.cfg.StripResultFields = []*common.StripResultFieldsConfig{
+		{Method: "eth_getBlockByNumber", Fields: []string{"_meta"}},
+	}
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	base, err := NewEvmJsonRpcCache(context.Background(), &logger, &common.ConnectorConfig{
+		Driver: "memory",
+		Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+	})
+	assert.NoError(t, err)
+	cache := base.WithNetwork(mockNetwork)
+
+	req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["0x5",false],"id":1}`))
+	req.SetNetwork(mockNetwork)
+	resp := common.NewNormalizedResponse().WithBody([]byte(`{"result":{"number":"0x5","hash":"0xabc","_meta":{"servedAt":1712345678}}}`))
+	assert.NoError(t, cache.Set(context.Background(), req, resp))
+
+	cached, err := cache.Get(context.Background(), req)
+	assert.NoError(t, err)
+	if assert.NotNil(t, cached) {
+		jrr, err := cached.JsonRpcResponse()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"number":"0x5","hash":"0xabc"}`, string(jrr.Result))
+	}
+}
+
+func TestEvmJsonRpcCache_EthCall(t *testing.T) {
+	newCache := func(t *testing.T) (*Network, *EvmJsonRpcCache) {
+		_, mockNetwork, _ := createCacheTestFixtures(10, 15, nil)
+		logger := zerolog.New(zerolog.NewConsoleWriter())
+		base, err := NewEvmJsonRpcCache(context.Background(), &logger, &common.ConnectorConfig{
+			Driver: "memory",
+			Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return mockNetwork, base.WithNetwork(mockNetwork)
+	}
+	newCall := func(network *Network, params string) *common.NormalizedRequest {
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_call","params":` + params + `,"id":1}`))
+		req.SetNetwork(network)
+		return req
+	}
+	storeResult := func(t *testing.T, cache *EvmJsonRpcCache, req *common.NormalizedRequest) {
+		t.Helper()
+		resp := common.NewNormalizedResponse().WithRequest(req).WithBody([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x0000000000000000000000000000000000000000000000000000000000000001"}`))
+		assert.NoError(t, cache.Set(context.Background(), req, resp))
+	}
+	callObj := `{"to":"0x1f9840a85d5af5bf1d1762f925bdaddc4201f984","data":"0x70a08231"}`
+
+	t.Run("SameCallAtFinalizedBlockIsHitSecondTime", func(t *testing.T) {
+		network, cache := newCache(t)
+
+		req := newCall(network, `[`+callObj+`,"0x5"]`)
+		cached, err := cache.Get(context.Background(), req)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeRecordNotFound), "first call must be a miss: %v", err)
+		assert.Nil(t, cached)
+		storeResult(t, cache, req)
+
+		// Same call with different key order and block number formatting
+		again := newCall(network, `[{"data":"0x70a08231","to":"0x1f9840a85d5af5bf1d1762f925bdaddc4201f984"},"0x05"]`)
+		cached, err = cache.Get(context.Background(), again)
+		assert.NoError(t, err)
+		if assert.NotNil(t, cached) {
+			assert.True(t, cached.FromCache())
+		}
+	})
+
+	t.Run("CallAtBlockHashOrEarliestIsCached", func(t *testing.T) {
+		network, cache := newCache(t)
+
+		for _, block := range []string{`{"blockHash":"0xabc"}`, `"earliest"`} {
+			req := newCall(network, `[`+callObj+`,`+block+`]`)
+			storeResult(t, cache, req)
+			cached, err := cache.Get(context.Background(), newCall(network, `[`+callObj+`,`+block+`]`))
+			assert.NoError(t, err)
+			assert.NotNil(t, cached, "call at %s must be cached", block)
+		}
+	})
+
+	t.Run("DifferentCallObjectIsMiss", func(t *testing.T) {
+		network, cache := newCache(t)
+
+		storeResult(t, cache, newCall(network, `[`+callObj+`,"0x5"]`))
+		cached, err := cache.Get(context.Background(), newCall(network, `[{"to":"0x1f9840a85d5af5bf1d1762f925bdaddc4201f984","data":"0x18160ddd"},"0x5"]`))
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeRecordNotFound), "unexpected error: %v", err)
+		assert.Nil(t, cached)
+	})
+
+	t.Run("MovingTagsAndUnfinalizedBlocksAreNotCached", func(t *testing.T) {
+		network, cache := newCache(t)
+
+		for _, params := range []string{
+			`[` + callObj + `,"latest"]`,
+			`[` + callObj + `,"pending"]`,
+			`[` + callObj + `]`,
+			`[` + callObj + `,"0xc"]`,
+		} {
+			storeResult(t, cache, newCall(network, params))
+			cached, err := cache.Get(context.Background(), newCall(network, params))
+			assert.NoError(t, err)
+			assert.Nil(t, cached, "call with params %s must not be cached", params)
+		}
+	})
+}
+
+func TestEvmJsonRpcCache_EthCreateAccessList(t *testing.T) {
+	_, network, _ := createCacheTestFixtures(10, 15, nil)
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	base, err := NewEvmJsonRpcCache(context.Background(), &logger, &common.ConnectorConfig{
+		Driver: "memory",
+		Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cache := base.WithNetwork(network)
+
+	newRequest := func(block string) *common.NormalizedRequest {
+		params := `[{"from":"0x1f9840a85d5af5bf1d1762f925bdaddc4201f984","to":"0x6b175474e89094c44da98b954eedeac495271d0f","data":"0x70a08231"}`
+		if block != "" {
+			params += `,` + block
+		}
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_createAccessList","params":` + params + `],"id":1}`))
+		req.SetNetwork(network)
+		return req
+	}
+	storeAndGet := func(t *testing.T, block string) *common.NormalizedResponse {
+		t.Helper()
+		req := newRequest(block)
+		resp := common.NewNormalizedResponse().WithRequest(req).WithBody([]byte(`{"jsonrpc":"2.0","id":1,"result":{"accessList":[],"gasUsed":"0x5208"}}`))
+		assert.NoError(t, cache.Set(context.Background(), req, resp))
+		cached, err := cache.Get(context.Background(), newRequest(block))
+		assert.NoError(t, err)
+		return cached
+	}
+
+	t.Run("IsCachedAtFinalizedBlock", func(t *testing.T) {
+		assert.Equal(t, common.TTLClassFinalized, (&common.JsonRpcRequest{Method: "eth_createAccessList"}).CacheTTLClass())
+
+		cached := storeAndGet(t, `"0x5"`)
+		if assert.NotNil(t, cached) {
+			assert.True(t, cached.FromCache())
+		}
+		// Block param is normalized like eth_call
+		again, err := cache.Get(context.Background(), newRequest(`{"blockNumber":"0x05"}`))
+		assert.NoError(t, err)
+		assert.NotNil(t, again)
+	})
+
+	t.Run("IsNotCachedAtLatestOrUnfinalizedBlock", func(t *testing.T) {
+		for _, block := range []string{`"latest"`, ``, `"0xc"`} {
+			assert.Nil(t, storeAndGet(t, block), "access list at block %q must not be cached", block)
+		}
+	})
+}
+
+func TestEvmJsonRpcCache_EthGetBlockReceipts(t *testing.T) {
+	_, network, _ := createCacheTestFixtures(10, 15, nil)
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	base, err := NewEvmJsonRpcCache(context.Background(), &logger, &common.ConnectorConfig{
+		Driver: "memory",
+		Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cache := base.WithNetwork(network)
+
+	newRequest := func(block string) *common.NormalizedRequest {
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_getBlockReceipts","params":[` + block + `],"id":1}`))
+		req.SetNetwork(network)
+		return req
+	}
+	storeAndGet := func(t *testing.T, block string, receiptsBlockNumber string) *common.NormalizedResponse {
+		t.Helper()
+		req := newRequest(block)
+		result := `[{"blockHash":"0x` + strings.Repeat("ab", 32) + `","blockNumber":"` + receiptsBlockNumber + `","transactionIndex":"0x0","status":"0x1"}]`
+		resp := common.NewNormalizedResponse().WithRequest(req).WithBody([]byte(`{"jsonrpc":"2.0","id":1,"result":` + result + `}`))
+		assert.NoError(t, cache.Set(context.Background(), req, resp))
+		cached, err := cache.Get(context.Background(), newRequest(block))
+		if common.HasErrorCode(err, common.ErrCodeRecordNotFound) {
+			return nil
+		}
+		assert.NoError(t, err)
+		return cached
+	}
+	hash := `"0x` + strings.Repeat("ab", 32) + `"`
+
+	t.Run("IsCachedPermanentlyAtFinalizedBlock", func(t *testing.T) {
+		assert.Equal(t, common.TTLClassFinalized, (&common.JsonRpcRequest{Method: "eth_getBlockReceipts"}).CacheTTLClass())
+
+		cached := storeAndGet(t, `"0x5"`, "0x5")
+		if assert.NotNil(t, cached) {
+			assert.True(t, cached.FromCache())
+		}
+		// Equivalent block params share the same entry
+		again, err := cache.Get(context.Background(), newRequest(`{"blockNumber":"0x05"}`))
+		assert.NoError(t, err)
+		assert.NotNil(t, again)
+	})
+
+	t.Run("IsCachedByHashOnceReceiptsBlockIsFinalized", func(t *testing.T) {
+		assert.NotNil(t, storeAndGet(t, hash, "0x7"))
+		assert.NotNil(t, storeAndGet(t, `{"blockHash":`+hash+`}`, "0x7"))
+	})
+
+	t.Run("IsNotCachedAtMovingTagsOrUnfinalizedBlocks", func(t *testing.T) {
+		assert.Nil(t, storeAndGet(t, `"latest"`, "0xf"))
+		assert.Nil(t, storeAndGet(t, `"0xc"`, "0xc"))
+		assert.Nil(t, storeAndGet(t, `"0x`+strings.Repeat("cd", 32)+`"`, "0xc"))
+	})
+}
+
+func TestEvmJsonRpcCache_Replica(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	newCache := func(t *testing.T) (*Network, *EvmJsonRpcCache) {
+		_, network, _ := createCacheTestFixtures(10, 15, nil)
+		base, err := NewEvmJsonRpcCache(context.Background(), &logger, &common.ConnectorConfig{
+			Driver: "memory",
+			Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		// A ttl makes head data cacheable for regular upstreams
+		cache, err := base.WithNetwork(network).WithProjectPolicies([]*common.CachePolicyConfig{
+			{Method: "eth_getBlockByNumber", TTL: "1m", AllowUnfinalized: true},
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return network, cache
+	}
+	newUpstream := func(t *testing.T, network *Network, id string, replica bool, latestBlock int64) *upstream.Upstream {
+		ups, err := upstream.NewUpstream("test", &common.UpstreamConfig{
+			Id:       id,
+			Endpoint: "http://" + id + ".localhost",
+			Evm:      &common.EvmUpstreamConfig{ChainId: 123, Replica: replica, ReplicaMaxLag: 3},
+		}, upstream.NewClientRegistry(&logger), nil, vendors.NewVendorsRegistry(), &logger, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		poller, err := upstream.NewEvmStatePoller(context.Background(), &logger, network, ups, health.NewTracker("prjA", 100*time.Second))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		poller.SuggestLatestBlock(latestBlock)
+		network.evmStatePollers[id] = poller
+		return ups
+	}
+	storeAndGet := func(t *testing.T, network *Network, cache *EvmJsonRpcCache, ups *upstream.Upstream, block, number string) *common.NormalizedResponse {
+		t.Helper()
+		newRequest := func() *common.NormalizedRequest {
+			req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["` + block + `",false],"id":1}`))
+			req.SetNetwork(network)
+			return req
+		}
+		req := newRequest()
+		resp := common.NewNormalizedResponse().WithRequest(req).
+			WithBody([]byte(`{"jsonrpc":"2.0","id":1,"result":{"number":"` + number + `","hash":"0xabc` + number[2:] + `"}}`)).
+			SetUpstream(ups)
+		assert.NoError(t, cache.Set(context.Background(), req, resp))
+		cached, err := cache.Get(context.Background(), newRequest())
+		if common.HasErrorCode(err, common.ErrCodeRecordNotFound) {
+			return nil
+		}
+		assert.NoError(t, err)
+		return cached
+	}
+
+	t.Run("HeadBlockOfReplicaIsNotCachedButFinalizedIs", func(t *testing.T) {
+		network, cache := newCache(t)
+		replica := newUpstream(t, network, "replica1", true, 14)
+
+		assert.Nil(t, storeAndGet(t, network, cache, replica, "0xe", "0xe"), "head block of a replica must not be cached")
+		assert.NotNil(t, storeAndGet(t, network, cache, replica, "0x5", "0x5"), "finalized block of a replica must be cached")
+	})
+
+	t.Run("HeadBlockOfRegularUpstreamIsCachedWithTTL", func(t *testing.T) {
+		network, cache := newCache(t)
+		regular := newUpstream(t, network, "regular1", false, 15)
+
+		assert.NotNil(t, storeAndGet(t, network, cache, regular, "0xe", "0xe"))
+	})
+
+	t.Run("ReplicaLaggingBeyondToleranceIsNotCached", func(t *testing.T) {
+		network, cache := newCache(t)
+		replica := newUpstream(t, network, "replica1", true, 8)
+
+		assert.Nil(t, storeAndGet(t, network, cache, replica, "0x5", "0x5"))
+	})
+}
+
+func TestEvmJsonRpcCache_RealtimeTTL(t *testing.T) {
+	t.Run("DerivedTtlScalesWithBlockTime", func(t *testing.T) {
+		ttl, err := evmRealtimeTTL(&common.EvmNetworkConfig{BlockTime: "12s"})
+		assert.NoError(t, err)
+		assert.Equal(t, 12*time.Second, ttl)
+
+		ttl, err = evmRealtimeTTL(&common.EvmNetworkConfig{BlockTime: "250ms"})
+		assert.NoError(t, err)
+		assert.Equal(t, 250*time.Millisecond, ttl)
+
+		ttl, err = evmRealtimeTTL(&common.EvmNetworkConfig{})
+		assert.NoError(t, err)
+		assert.Equal(t, defaultEvmBlockTime, ttl)
+
+		_, err = evmRealtimeTTL(&common.EvmNetworkConfig{BlockTime: "fast"})
+		assert.Error(t, err)
+	})
+
+	t.Run("TimestampsKeepMilliseconds", func(t *testing.T) {
+		cachedAt := time.UnixMilli(1700000000250)
+		_, decodedAt, err := decodeCacheEntry(encodeCacheEntry(`"0x1"`, cachedAt))
+		assert.NoError(t, err)
+		assert.True(t, cachedAt.Equal(decodedAt))
+	})
+
+	t.Run("EntriesAreWrittenWithV2PrefixAndRawResultsAreStillRead", func(t *testing.T) {
+		cachedAt := time.UnixMilli(1700000000250)
+		assert.Equal(t, `v2|1700000000.250|"0x1"`, encodeCacheEntry(`"0x1"`, cachedAt))
+
+		compressed, err := encodeCompressedCacheEntry(`"0x1"`, cachedAt)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(compressed, compressedCacheEntryPrefix))
+		result, decodedAt, err := decodeCacheEntry(compressed)
+		assert.NoError(t, err)
+		assert.Equal(t, `"0x1"`, result)
+		assert.True(t, cachedAt.Equal(decodedAt))
+
+		result, decodedAt, err = decodeCacheEntry(`"0x1"`)
+		assert.NoError(t, err)
+		assert.Equal(t, `"0x1"`, result)
+		assert.True(t, decodedAt.IsZero())
+	})
+
+	newGasPrice := func(network *Network) *common.NormalizedRequest {
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","method":"eth_gasPrice","params":[],"id":1}`))
+		req.SetNetwork(network)
+		return req
+	}
+	storeAged := func(t *testing.T, cache *EvmJsonRpcCache, req *common.NormalizedRequest, age time.Duration) {
+		t.Helper()
+		pk, rk, err := generateKeysForJsonRpcRequest(req, "")
+		assert.NoError(t, err)
+		entry, err := cache.encodeEntry(&common.CacheEntry{Result: []byte(`"0x3b9aca00"`), CachedAt: time.Now().Add(-age)})
+		assert.NoError(t, err)
+		assert.NoError(t, cache.conn.Set(context.Background(), pk, rk, entry, nil))
+	}
+
+	for _, tc := range []struct {
+		blockTime time.Duration
+		fresh     time.Duration
+		expired   time.Duration
+	}{
+		{blockTime: 12 * time.Second, fresh: 5 * time.Second, expired: 13 * time.Second},
+		{blockTime: 250 * time.Millisecond, fresh: 100 * time.Millisecond, expired: 1 * time.Second},
+	} {
+		t.Run(fmt.Sprintf("GasPriceIsCachedForOneBlockTimeOf%s", tc.blockTime), func(t *testing.T) {
+			_, mockNetwork, _ := createCacheTestFixtures(10, 15, nil)
+			mockNetwork.realtimeTTL = tc.blockTime
+			logger := zerolog.New(zerolog.NewConsoleWriter())
+			base, err := NewEvmJsonRpcCache(context.Background(), &logger, &common.ConnectorConfig{
+				Driver: "memory",
+				Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+			})
+			assert.NoError(t, err)
+			cache := base.WithNetwork(mockNetwork)
+
+			req := newGasPrice(mockNetwork)
+			rpcReq, err := req.JsonRpcRequest()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.blockTime, cache.ttlFor(rpcReq, nil))
+
+			storeAged(t, cache, req, tc.fresh)
+			cached, err := cache.Get(context.Background(), req)
+			assert.NoError(t, err)
+			assert.NotNil(t, cached)
+
+			storeAged(t, cache, req, tc.expired)
+			cached, err = cache.Get(context.Background(), req)
+			assert.NoError(t, err)
+			assert.Nil(t, cached)
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package erpc
+
+import (
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/erpc/erpc/common"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	defaultLogsBloomMaxBlocks int64 = 1024
+	logsBloomByteLength             = 256
+)
+
+// evmLogsBloomIndex remembers logsBloom of recently observed blocks. Blooms never have false negatives,
+// so when every block of a range excludes the filter, the range provably contains no matching logs.
+type evmLogsBloomIndex struct {
+	mu        sync.RWMutex
+	blooms    map[int64][]byte
+	highest   int64
+	maxBlocks int64
+}
+
+func newEvmLogsBloomIndex(cfg *common.EvmLogsBloomConfig) *evmLogsBloomIndex {
+	if cfg == nil {
+		return nil
+	}
+	maxBlocks := cfg.MaxBlocks
+	if maxBlocks <= 0 {
+		maxBlocks = defaultLogsBloomMaxBlocks
+	}
+	return &evmLogsBloomIndex{
+		blooms:    make(map[int64][]byte),
+		maxBlocks: maxBlocks,
+	}
+}
+
+// Observe stores the bloom of a finalized block given as 0x-prefixed hex.
+func (x *evmLogsBloomIndex) Observe(blockNumber int64, bloomHex string) {
+	bloom, err := hex.DecodeString(strings.TrimPrefix(bloomHex, "0x"))
+	if blockNumber <= 0 || err != nil || len(bloom) != logsBloomByteLength {
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.blooms[blockNumber] = bloom
+	if blockNumber > x.highest {
+		x.highest = blockNumber
+	}
+	oldest := x.highest - x.maxBlocks
+	for n := range x.blooms {
+		if n <= oldest {
+			delete(x.blooms, n)
+		}
+	}
+}
+
+// MayContainLogs returns false only when blooms of all blocks in the range are known and none of them
+// may contain a log emitted by one of the addresses (if any) matching all topic positions (if any).
+func (x *evmLogsBloomIndex) MayContainLogs(fromBlock, toBlock int64, addresses [][]byte, topics [][][]byte) bool {
+	if fromBlock <= 0 || toBlock < fromBlock || toBlock-fromBlock >= x.maxBlocks {
+		return true
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	for n := fromBlock; n <= toBlock; n++ {
+		bloom, ok := x.blooms[n]
+		if !ok || bloomMatchesFilter(bloom, addresses, topics) {
+			return true
+		}
+	}
+	return false
+}
+
+func bloomMatchesFilter(bloom []byte, addresses [][]byte, topics [][][]byte) bool {
+	if len(addresses) > 0 && !bloomContainsAny(bloom, addresses) {
+		return false
+	}
+	for _, alternatives := range topics {
+		if len(alternatives) > 0 && !bloomContainsAny(bloom, alternatives) {
+			return false
+		}
+	}
+	return true
+}
+
+func bloomContainsAny(bloom []byte, values [][]byte) bool {
+	for _, v := range values {
+		if bloomContains(bloom, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func bloomContains(bloom []byte, value []byte) bool {
+	for _, bit := range bloomBits(value) {
+		if bloom[logsBloomByteLength-1-bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomBits returns the 3 bits (out of 2048) that Ethereum sets in a block's logsBloom for a value,
+// taken from the first 6 bytes of its keccak256 hash.
+func bloomBits(value []byte) [3]uint {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(value)
+	sum := h.Sum(nil)
+	var bits [3]uint
+	for i := range bits {
+		bits[i] = (uint(sum[2*i])<<8 | uint(sum[2*i+1])) & 2047
+	}
+	return bits
+}
+
+// evmLogsBloomShortCircuit answers eth_getLogs with an empty array when stored blooms of the requested
+// range exclude the filter, the second return value is false when the request must be forwarded as usual.
+func (n *Network) evmLogsBloomShortCircuit(req *common.NormalizedRequest, method string) (*common.NormalizedResponse, bool) {
+	if n.logsBloom == nil || method != "eth_getLogs" {
+		return nil, false
+	}
+	jrq, err := req.JsonRpcRequest()
+	if err != nil {
+		return nil, false
+	}
+
+	jrq.RLock()
+	id := jrq.ID
+	var filter map[string]interface{}
+	if len(jrq.Params) > 0 {
+		filter, _ = jrq.Params[0].(map[string]interface{})
+	}
+	fromBlock, toBlock, addresses, topics, ok := parseLogsBloomFilter(filter)
+	jrq.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+	// Blooms of unfinalized blocks might belong to orphaned blocks after a reorg, so only finalized ranges are answered
+	if finalized, err := n.EvmIsBlockFinalized(toBlock); err != nil || !finalized {
+		return nil, false
+	}
+	if n.logsBloom.MayContainLogs(fromBlock, toBlock, addresses, topics) {
+		return nil, false
+	}
+
+	jrr, err := common.NewJsonRpcResponse(id, []interface{}{}, nil)
+	if err != nil {
+		return nil, false
+	}
+	n.Logger.Debug().Int64("fromBlock", fromBlock).Int64("toBlock", toBlock).Msgf("logs blooms exclude the filter, responding with empty logs")
+
+	return common.NewNormalizedResponse().WithRequest(req).WithJsonRpcResponse(jrr), true
+}
+
+// parseLogsBloomFilter only accepts filters with explicit numeric block range, since tags such as
+// "latest" (or a blockHash) do not tell which stored blooms to consult.
+func parseLogsBloomFilter(filter map[string]interface{}) (int64, int64, [][]byte, [][][]byte, bool) {
+	if filter == nil || filter["blockHash"] != nil {
+		return 0, 0, nil, nil, false
+	}
+	fromHex, _ := filter["fromBlock"].(string)
+	toHex, _ := filter["toBlock"].(string)
+	if !strings.HasPrefix(fromHex, "0x") || !strings.HasPrefix(toHex, "0x") {
+		return 0, 0, nil, nil, false
+	}
+	fromBlock, err := common.HexToInt64(fromHex)
+	if err != nil {
+		return 0, 0, nil, nil, false
+	}
+	toBlock, err := common.HexToInt64(toHex)
+	if err != nil {
+		return 0, 0, nil, nil, false
+	}
+
+	addresses, ok := parseBloomValues(filter["address"])
+	if !ok {
+		return 0, 0, nil, nil, false
+	}
+	var topics [][][]byte
+	if filter["topics"] != nil {
+		positions, ok := filter["topics"].([]interface{})
+		if !ok {
+			return 0, 0, nil, nil, false
+		}
+		for _, p := range positions {
+			alternatives, ok := parseBloomValues(p)
+			if !ok {
+				return 0, 0, nil, nil, false
+			}
+			topics = append(topics, alternatives)
+		}
+	}
+
+	return fromBlock, toBlock, addresses, topics, true
+}
+
+// parseBloomValues decodes a single hex value or an array of alternatives, nil means any value.
+func parseBloomValues(v interface{}) ([][]byte, bool) {
+	switch t := v.(type) {
+	case nil:
+		return nil, true
+	case string:
+		b, err := hex.DecodeString(strings.TrimPrefix(t, "0x"))
+		if err != nil {
+			return nil, false
+		}
+		return [][]byte{b}, true
+	case []interface{}:
+		var values [][]byte
+		for _, item := range t {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+			if err != nil {
+				return nil, false
+			}
+			values = append(values, b)
+		}
+		return values, true
+	default:
+		return nil, false
+	}
+}
+
+// observeLogsBloom stores logsBloom of finalized blocks returned by successful eth_getBlockBy* responses,
+// blooms of newer blocks are not kept since a reorg could replace those blocks.
+func (n *Network) observeLogsBloom(method string, resp *common.NormalizedResponse) {
+	if n.logsBloom == nil || (method != "eth_getBlockByNumber" && method != "eth_getBlockByHash") {
+		return
+	}
+	jrr, err := resp.JsonRpcResponse()
+	if err != nil || jrr == nil {
+		return
+	}
+	res, err := jrr.ParsedResult()
+	if err != nil {
+		return
+	}
+	blk, ok := res.(map[string]interface{})
+	if !ok {
+		return
+	}
+	bnh, _ := blk["number"].(string)
+	bloomHex, _ := blk["logsBloom"].(string)
+	blockNumber, err := common.HexToInt64(bnh)
+	if err != nil || bloomHex == "" {
+		return
+	}
+	if finalized, err := n.EvmIsBlockFinalized(blockNumber); err != nil || !finalized {
+		return
+	}
+	n.logsBloom.Observe(blockNumber, bloomHex)
+}
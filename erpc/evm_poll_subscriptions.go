@@ -0,0 +1,545 @@
+package erpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/erpc/erpc/common"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Polls buffered notifications of a subscription created via eth_subscribe over http
+	pollSubscriptionMethod = "erpc_pollSubscription"
+
+	pollSubscriptionKindNewHeads = "newHeads"
+	pollSubscriptionKindLogs     = "logs"
+
+	defaultPollSubscriptionMaxBufferSize = 100
+	defaultPollSubscriptionMaxSubs       = 1000
+	defaultPollSubscriptionMaxSubsClient = 10
+	defaultPollSubscriptionMaxWait       = 10 * time.Second
+	defaultPollSubscriptionIdleTimeout   = 5 * time.Minute
+	defaultPollSubscriptionHeadInterval  = 1 * time.Second
+
+	// Max number of skipped blocks fetched when the head jumped more than one block between two checks
+	pollSubscriptionMaxHeadBackfill = 16
+
+	// Holds the client ip subscriptions are counted against for maxSubscriptionsPerClient
+	pollSubscriptionClientContext common.ContextKey = "pollSubscriptionClient"
+)
+
+// evmPollSubscriptions emulates eth_subscribe for clients that cannot use websockets. Clients get a subscription
+// id and then long-poll for notifications, which are accumulated in a bounded buffer per subscription whenever
+// the head known by the network's state pollers advances. Identical subscriptions are coalesced: each new block
+// is fetched once for all newHeads subscribers, and logs once per distinct filter, then fanned out to every
+// subscriber's buffer. The head tracker stops when the last subscription goes away.
+type evmPollSubscriptions struct {
+	network *Network
+	logger  *zerolog.Logger
+
+	maxBufferSize    int
+	maxSubs          int
+	maxSubsPerClient int
+	maxWait          time.Duration
+	idleTimeout      time.Duration
+	headInterval     time.Duration
+
+	mu          sync.Mutex
+	subs        map[string]*pollSubscription
+	lastHead    int64
+	stopTracker context.CancelFunc
+
+	fetchBlock func(ctx context.Context, blockRef string) (json.RawMessage, int64, error)
+	fetchLogs  func(ctx context.Context, filter map[string]interface{}) ([]json.RawMessage, error)
+}
+
+type pollSubscription struct {
+	id     string
+	kind   string
+	client string
+	filter map[string]interface{}
+	// Canonical json of the filter, logs subscriptions with the same key share one eth_getLogs per block range
+	filterKey string
+
+	mu           sync.Mutex
+	events       []json.RawMessage
+	dropped      int
+	notify       chan struct{}
+	lastPolledAt time.Time
+}
+
+type pollSubscriptionResult struct {
+	Subscription string            `json:"subscription"`
+	Events       []json.RawMessage `json:"events"`
+	// Number of notifications dropped since the previous poll because the buffer was full
+	Dropped int `json:"dropped,omitempty"`
+}
+
+func newEvmPollSubscriptions(network *Network, cfg *common.PollSubscriptionsConfig) *evmPollSubscriptions {
+	ps := &evmPollSubscriptions{
+		network:          network,
+		logger:           network.Logger,
+		maxBufferSize:    defaultPollSubscriptionMaxBufferSize,
+		maxSubs:          defaultPollSubscriptionMaxSubs,
+		maxSubsPerClient: defaultPollSubscriptionMaxSubsClient,
+		maxWait:          defaultPollSubscriptionMaxWait,
+		idleTimeout:      defaultPollSubscriptionIdleTimeout,
+		headInterval:     defaultPollSubscriptionHeadInterval,
+		subs:             make(map[string]*pollSubscription),
+	}
+
+	if cfg != nil {
+		if cfg.MaxBufferSize > 0 {
+			ps.maxBufferSize = cfg.MaxBufferSize
+		}
+		if cfg.MaxSubscriptions > 0 {
+			ps.maxSubs = cfg.MaxSubscriptions
+		}
+		if cfg.MaxSubscriptionsPerClient > 0 {
+			ps.maxSubsPerClient = cfg.MaxSubscriptionsPerClient
+		}
+		ps.maxWait = parseDurationOr(network.Logger, "pollSubscriptions.maxWait", cfg.MaxWait, ps.maxWait)
+		ps.idleTimeout = parseDurationOr(network.Logger, "pollSubscriptions.idleTimeout", cfg.IdleTimeout, ps.idleTimeout)
+	}
+	if network.cfg != nil && network.cfg.Evm != nil {
+		ps.headInterval = parseDurationOr(network.Logger, "evm.blockTrackerInterval", network.cfg.Evm.BlockTrackerInterval, ps.headInterval)
+	}
+
+	ps.fetchBlock = ps.fetchBlockFromNetwork
+	ps.fetchLogs = ps.fetchLogsFromNetwork
+
+	return ps
+}
+
+func parseDurationOr(logger *zerolog.Logger, name, value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		logger.Warn().Err(err).Str(name, value).Msgf("invalid duration, using default of %s", fallback)
+		return fallback
+	}
+	return d
+}
+
+// Handle serves the subscription methods locally, the second return value is false for any other method.
+func (ps *evmPollSubscriptions) Handle(ctx context.Context, req *common.NormalizedRequest, method string) (*common.NormalizedResponse, bool, error) {
+	switch method {
+	case "eth_subscribe", "eth_unsubscribe", pollSubscriptionMethod:
+	default:
+		return nil, false, nil
+	}
+
+	jrq, err := req.JsonRpcRequest()
+	if err != nil {
+		return nil, true, err
+	}
+	jrq.RLock()
+	params := jrq.Params
+	id := jrq.ID
+	jrq.RUnlock()
+
+	var result interface{}
+	switch method {
+	case "eth_subscribe":
+		client, _ := ctx.Value(pollSubscriptionClientContext).(string)
+		result, err = ps.subscribe(client, params)
+	case "eth_unsubscribe":
+		result, err = ps.unsubscribe(params)
+	case pollSubscriptionMethod:
+		result, err = ps.poll(ctx, params)
+	}
+	if err != nil {
+		return nil, true, err
+	}
+
+	jrr, err := common.NewJsonRpcResponse(id, result, nil)
+	if err != nil {
+		return nil, true, err
+	}
+	return common.NewNormalizedResponse().WithRequest(req).WithJsonRpcResponse(jrr), true, nil
+}
+
+// subscribe creates a subscription counted against the client (an ip), an empty client is only counted
+// against the network-wide limit.
+func (ps *evmPollSubscriptions) subscribe(client string, params []interface{}) (string, error) {
+	if len(params) == 0 {
+		return "", common.NewErrInvalidRequest(fmt.Errorf("eth_subscribe requires subscription type as first param"))
+	}
+	kind, _ := params[0].(string)
+
+	sub := &pollSubscription{
+		kind:         kind,
+		client:       client,
+		notify:       make(chan struct{}, 1),
+		lastPolledAt: time.Now(),
+	}
+	switch kind {
+	case pollSubscriptionKindNewHeads:
+	case pollSubscriptionKindLogs:
+		sub.filter = map[string]interface{}{}
+		if len(params) > 1 {
+			filter, ok := params[1].(map[string]interface{})
+			if !ok {
+				return "", common.NewErrInvalidRequest(fmt.Errorf("logs subscription filter must be an object"))
+			}
+			for _, k := range []string{"address", "topics"} {
+				if v, ok := filter[k]; ok {
+					sub.filter[k] = v
+				}
+			}
+		}
+	default:
+		return "", common.NewErrInvalidRequest(fmt.Errorf("subscription type %q is not supported over http, use newHeads or logs", kind))
+	}
+	if sub.filter != nil {
+		// encoding/json sorts map keys, so the same filter always has the same key
+		key, err := json.Marshal(sub.filter)
+		if err != nil {
+			return "", common.NewErrInvalidRequest(fmt.Errorf("logs subscription filter must be json: %w", err))
+		}
+		sub.filterKey = string(key)
+	}
+
+	sid, err := newPollSubscriptionId()
+	if err != nil {
+		return "", err
+	}
+	sub.id = sid
+
+	ps.mu.Lock()
+	if len(ps.subs) >= ps.maxSubs {
+		ps.mu.Unlock()
+		return "", common.NewErrSubscriptionLimitExceeded("network", ps.maxSubs)
+	}
+	if client != "" {
+		count := 0
+		for _, s := range ps.subs {
+			if s.client == client {
+				count++
+			}
+		}
+		if count >= ps.maxSubsPerClient {
+			ps.mu.Unlock()
+			return "", common.NewErrSubscriptionLimitExceeded("client", ps.maxSubsPerClient)
+		}
+	}
+	ps.subs[sid] = sub
+	ps.ensureTrackerLocked()
+	ps.mu.Unlock()
+
+	ps.logger.Debug().Str("subscriptionId", sid).Str("kind", kind).Msg("created http poll subscription")
+	return sid, nil
+}
+
+func (ps *evmPollSubscriptions) unsubscribe(params []interface{}) (bool, error) {
+	sid, err := subscriptionIdParam(params)
+	if err != nil {
+		return false, err
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if _, ok := ps.subs[sid]; !ok {
+		return false, nil
+	}
+	delete(ps.subs, sid)
+	ps.stopTrackerIfIdleLocked()
+	return true, nil
+}
+
+func (ps *evmPollSubscriptions) poll(ctx context.Context, params []interface{}) (*pollSubscriptionResult, error) {
+	sid, err := subscriptionIdParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	ps.mu.Lock()
+	sub, ok := ps.subs[sid]
+	ps.mu.Unlock()
+	if !ok {
+		return nil, common.NewErrSubscriptionNotFound(sid)
+	}
+
+	wait := ps.maxWait
+	if len(params) > 1 {
+		if ms, ok := params[1].(float64); ok && ms >= 0 && time.Duration(ms)*time.Millisecond < wait {
+			wait = time.Duration(ms) * time.Millisecond
+		}
+	}
+
+	sub.mu.Lock()
+	sub.lastPolledAt = time.Now()
+	empty := len(sub.events) == 0
+	sub.mu.Unlock()
+
+	if empty && wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-sub.notify:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		timer.Stop()
+	}
+
+	return sub.drain(), nil
+}
+
+func (s *pollSubscription) push(event json.RawMessage, maxBufferSize int) {
+	s.mu.Lock()
+	if len(s.events) >= maxBufferSize {
+		// Keep the most recent notifications, the client learns how many it missed via "dropped"
+		s.events = s.events[1:]
+		s.dropped++
+	}
+	s.events = append(s.events, event)
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *pollSubscription) drain() *pollSubscriptionResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := &pollSubscriptionResult{
+		Subscription: s.id,
+		Events:       s.events,
+		Dropped:      s.dropped,
+	}
+	if res.Events == nil {
+		res.Events = []json.RawMessage{}
+	}
+	s.events = nil
+	s.dropped = 0
+	s.lastPolledAt = time.Now()
+
+	// A notification might be pending for events we are returning now
+	select {
+	case <-s.notify:
+	default:
+	}
+
+	return res
+}
+
+func (ps *evmPollSubscriptions) publish(kind string, events ...json.RawMessage) {
+	ps.mu.Lock()
+	subs := make([]*pollSubscription, 0, len(ps.subs))
+	for _, sub := range ps.subs {
+		if sub.kind == kind {
+			subs = append(subs, sub)
+		}
+	}
+	ps.mu.Unlock()
+
+	for _, sub := range subs {
+		for _, ev := range events {
+			sub.push(ev, ps.maxBufferSize)
+		}
+	}
+}
+
+func (ps *evmPollSubscriptions) ensureTrackerLocked() {
+	if ps.stopTracker != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ps.stopTracker = cancel
+	go ps.trackHeads(ctx)
+}
+
+func (ps *evmPollSubscriptions) stopTrackerIfIdleLocked() {
+	if len(ps.subs) > 0 || ps.stopTracker == nil {
+		return
+	}
+	ps.stopTracker()
+	ps.stopTracker = nil
+	ps.lastHead = 0
+}
+
+func (ps *evmPollSubscriptions) trackHeads(ctx context.Context) {
+	ticker := time.NewTicker(ps.headInterval)
+	defer ticker.Stop()
+
+	ps.checkHead(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ps.expireIdle()
+			ps.checkHead(ctx)
+		}
+	}
+}
+
+func (ps *evmPollSubscriptions) expireIdle() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	now := time.Now()
+	for sid, sub := range ps.subs {
+		sub.mu.Lock()
+		idle := now.Sub(sub.lastPolledAt) > ps.idleTimeout
+		sub.mu.Unlock()
+		if idle {
+			ps.logger.Debug().Str("subscriptionId", sid).Msg("removing idle http poll subscription")
+			delete(ps.subs, sid)
+		}
+	}
+	ps.stopTrackerIfIdleLocked()
+}
+
+// checkHead publishes blocks (and their logs) up to the head known by state pollers since the previous check.
+func (ps *evmPollSubscriptions) checkHead(ctx context.Context) {
+	head, err := ps.network.BlockResolver().HeadHeight(ps.network.NetworkId)
+	if err != nil {
+		ps.logger.Debug().Err(err).Msg("head block is not known yet for http poll subscriptions")
+		return
+	}
+
+	ps.mu.Lock()
+	prev := ps.lastHead
+	if head > prev {
+		ps.lastHead = head
+	}
+	ps.mu.Unlock()
+
+	// Nothing is published for the very first head, subscribers only get blocks that arrive after subscribing
+	if prev == 0 || head <= prev {
+		return
+	}
+
+	from := prev + 1
+	if head-from > pollSubscriptionMaxHeadBackfill {
+		from = head - pollSubscriptionMaxHeadBackfill
+	}
+
+	heads := make([]json.RawMessage, 0, head-from+1)
+	for bn := from; bn <= head; bn++ {
+		blk, _, err := ps.fetchBlock(ctx, fmt.Sprintf("0x%x", bn))
+		if err != nil {
+			ps.logger.Debug().Err(err).Int64("blockNumber", bn).Msg("failed to fetch block for http poll subscriptions")
+			continue
+		}
+		heads = append(heads, blk)
+	}
+	ps.publish(pollSubscriptionKindNewHeads, heads...)
+
+	ps.publishLogs(ctx, from, head)
+}
+
+func (ps *evmPollSubscriptions) publishLogs(ctx context.Context, from, to int64) {
+	ps.mu.Lock()
+	groups := make(map[string][]*pollSubscription)
+	for _, sub := range ps.subs {
+		if sub.kind == pollSubscriptionKindLogs {
+			groups[sub.filterKey] = append(groups[sub.filterKey], sub)
+		}
+	}
+	ps.mu.Unlock()
+
+	for key, subs := range groups {
+		filter := map[string]interface{}{
+			"fromBlock": fmt.Sprintf("0x%x", from),
+			"toBlock":   fmt.Sprintf("0x%x", to),
+		}
+		for k, v := range subs[0].filter {
+			filter[k] = v
+		}
+		logs, err := ps.fetchLogs(ctx, filter)
+		if err != nil {
+			ps.logger.Debug().Err(err).Str("filter", key).Int("subscribers", len(subs)).Msg("failed to fetch logs for http poll subscriptions")
+			continue
+		}
+		for _, sub := range subs {
+			for _, lg := range logs {
+				sub.push(lg, ps.maxBufferSize)
+			}
+		}
+	}
+}
+
+func (ps *evmPollSubscriptions) fetchBlockFromNetwork(ctx context.Context, blockRef string) (json.RawMessage, int64, error) {
+	result, err := ps.forward(ctx, "eth_getBlockByNumber", []interface{}{blockRef, false})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var blk struct {
+		Number string `json:"number"`
+	}
+	if err := sonic.Unmarshal(result, &blk); err != nil {
+		return nil, 0, err
+	}
+	bn, err := common.HexToInt64(blk.Number)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, bn, nil
+}
+
+func (ps *evmPollSubscriptions) fetchLogsFromNetwork(ctx context.Context, filter map[string]interface{}) ([]json.RawMessage, error) {
+	result, err := ps.forward(ctx, "eth_getLogs", []interface{}{filter})
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []json.RawMessage
+	if err := sonic.Unmarshal(result, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+func (ps *evmPollSubscriptions) forward(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+	req, err := common.NewInternalRequest(method, params)
+	if err != nil {
+		return nil, err
+	}
+	rctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	resp, err := ps.network.Forward(rctx, req)
+	if err != nil {
+		return nil, err
+	}
+	jrr, err := resp.JsonRpcResponse()
+	if err != nil {
+		return nil, err
+	}
+	if jrr.Error != nil {
+		return nil, jrr.Error
+	}
+	return jrr.Result, nil
+}
+
+func subscriptionIdParam(params []interface{}) (string, error) {
+	if len(params) > 0 {
+		if sid, ok := params[0].(string); ok && sid != "" {
+			return sid, nil
+		}
+	}
+	return "", common.NewErrInvalidRequest(fmt.Errorf("subscription id is required as first param"))
+}
+
+func newPollSubscriptionId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "0x" + hex.EncodeToString(b), nil
+}
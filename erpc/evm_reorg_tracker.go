@@ -0,0 +1,105 @@
+package erpc
+
+import (
+	"sort"
+	"sync"
+)
+
+// How many recent blocks (behind the highest observed one) are tracked for reorg detection.
+// Blocks older than this are expected to be finalized long before a reorg could reach them.
+const reorgTrackerWindow int64 = 128
+
+// evmReorgTracker remembers the canonical hash of recently observed non-final blocks,
+// and which of those blocks have entries in the cache. When a different hash is observed
+// for a tracked block it reports the cached blocks that must be invalidated.
+type evmReorgTracker struct {
+	mu      sync.Mutex
+	hashes  map[int64]string
+	cached  map[int64]bool
+	highest int64
+}
+
+func newEvmReorgTracker() *evmReorgTracker {
+	return &evmReorgTracker{
+		hashes: make(map[int64]string),
+		cached: make(map[int64]bool),
+	}
+}
+
+// Observe records the hash (and optionally the parent hash) of a block, and returns the
+// cached block numbers that belonged to a replaced chain, in ascending order.
+func (t *evmReorgTracker) Observe(blockNumber int64, hash, parentHash string) []int64 {
+	if blockNumber <= 0 || hash == "" {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var reorgFrom int64
+	if prev, ok := t.hashes[blockNumber]; ok && prev != hash {
+		reorgFrom = blockNumber
+	}
+	if parentHash != "" {
+		if prev, ok := t.hashes[blockNumber-1]; ok && prev != parentHash {
+			reorgFrom = blockNumber - 1
+		}
+	}
+
+	var invalidated []int64
+	if reorgFrom > 0 {
+		for n := range t.cached {
+			if n >= reorgFrom {
+				invalidated = append(invalidated, n)
+				delete(t.cached, n)
+			}
+		}
+		for n := range t.hashes {
+			if n >= reorgFrom {
+				delete(t.hashes, n)
+			}
+		}
+		sort.Slice(invalidated, func(i, j int) bool { return invalidated[i] < invalidated[j] })
+	}
+
+	t.hashes[blockNumber] = hash
+	if parentHash != "" {
+		t.hashes[blockNumber-1] = parentHash
+	}
+	if blockNumber > t.highest {
+		t.highest = blockNumber
+	}
+	t.prune()
+
+	return invalidated
+}
+
+// MarkCached records that the cache holds entries grouped under this block number.
+func (t *evmReorgTracker) MarkCached(blockNumber int64) {
+	if blockNumber <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cached[blockNumber] = true
+	if blockNumber > t.highest {
+		t.highest = blockNumber
+	}
+	t.prune()
+}
+
+func (t *evmReorgTracker) prune() {
+	oldest := t.highest - reorgTrackerWindow
+	for n := range t.hashes {
+		if n < oldest {
+			delete(t.hashes, n)
+		}
+	}
+	for n := range t.cached {
+		if n < oldest {
+			delete(t.cached, n)
+		}
+	}
+}
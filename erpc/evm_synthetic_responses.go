@@ -0,0 +1,40 @@
+package erpc
+
+import (
+	"github.com/erpc/erpc/common"
+)
+
+// evmSyntheticResponse answers node-status methods locally when enabled for the network,
+// the second return value is false when the request must be forwarded as usual.
+func (n *Network) evmSyntheticResponse(req *common.NormalizedRequest, method string) (*common.NormalizedResponse, bool, error) {
+	if n.cfg == nil || n.cfg.Evm == nil || n.cfg.Evm.SyntheticResponses == nil {
+		return nil, false, nil
+	}
+	cfg := n.cfg.Evm.SyntheticResponses
+
+	var result interface{}
+	switch {
+	case method == "eth_syncing" && cfg.EthSyncing:
+		result = false
+	case method == "net_listening" && cfg.NetListening:
+		result = true
+	case method == "web3_clientVersion" && cfg.Web3ClientVersion != "":
+		result = cfg.Web3ClientVersion
+	default:
+		return nil, false, nil
+	}
+
+	jrq, err := req.JsonRpcRequest()
+	if err != nil {
+		return nil, true, err
+	}
+	jrq.RLock()
+	id := jrq.ID
+	jrq.RUnlock()
+
+	jrr, err := common.NewJsonRpcResponse(id, result, nil)
+	if err != nil {
+		return nil, true, err
+	}
+	return common.NewNormalizedResponse().WithRequest(req).WithJsonRpcResponse(jrr), true, nil
+}
@@ -0,0 +1,113 @@
+package erpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/erpc/erpc/health"
+	"github.com/erpc/erpc/upstream"
+)
+
+const defaultVerifyEmptyLogsMinBlockDepth = 10
+
+// verifyEmptyLogs re-sends eth_getLogs that returned [] for a range well behind the head to the next upstream
+// that neither failed this request nor already returned [] for it, since a lagging or buggy upstream might
+// miss logs another one has.
+// The verifier response is returned when it has logs, otherwise the original empty response is kept.
+func (n *Network) verifyEmptyLogs(
+	ctx context.Context,
+	req *common.NormalizedRequest,
+	method string,
+	resp *common.NormalizedResponse,
+	upsList []*upstream.Upstream,
+	failedUpstreams map[string]error,
+) *common.NormalizedResponse {
+	if method != "eth_getLogs" || n.cfg.Evm == nil || n.cfg.Evm.VerifyEmptyLogs == nil {
+		return resp
+	}
+	if resp == nil || resp.IsObjectNull() || !resp.IsResultEmptyish() {
+		return resp
+	}
+	if jrr, err := resp.JsonRpcResponse(); err != nil || jrr == nil || jrr.Error != nil {
+		return resp
+	}
+	servedBy := resp.UpstreamId()
+	if servedBy == "" {
+		return resp
+	}
+
+	toBlock, ok := logsFilterToBlock(req)
+	if !ok {
+		return resp
+	}
+	head, err := n.BlockResolver().HeadHeight(n.NetworkId)
+	if err != nil {
+		return resp
+	}
+	minDepth := n.cfg.Evm.VerifyEmptyLogs.MinBlockDepth
+	if minDepth <= 0 {
+		minDepth = defaultVerifyEmptyLogsMinBlockDepth
+	}
+	if head-toBlock < minDepth {
+		return resp
+	}
+
+	servedEmpty := map[string]bool{servedBy: true}
+	for _, attempt := range req.UpstreamAttempts() {
+		if attempt.Empty {
+			servedEmpty[attempt.Upstream] = true
+		}
+	}
+
+	var verifier *upstream.Upstream
+	req.RLock()
+	for _, u := range upsList {
+		id := u.Config().Id
+		if _, failed := failedUpstreams[id]; !servedEmpty[id] && !failed {
+			verifier = u
+			break
+		}
+	}
+	req.RUnlock()
+	if verifier == nil {
+		return resp
+	}
+	verifierId := verifier.Config().Id
+
+	lg := n.Logger.With().Str("upstreamId", servedBy).Str("verifierUpstreamId", verifierId).Int64("toBlock", toBlock).Logger()
+	health.MetricUpstreamEmptyLogsVerificationTotal.WithLabelValues(n.ProjectId, n.NetworkId, servedBy, verifierId).Inc()
+
+	vreq := common.NewNormalizedRequest(append([]byte(nil), req.Body()...)).WithDirectives(req.Directives())
+	vreq.SetNetwork(n)
+	start := time.Now()
+	vresp, err := verifier.Forward(ctx, vreq)
+	req.AddUpstreamAttempt(newUpstreamAttempt(verifierId, err, time.Since(start)))
+	if err != nil {
+		lg.Debug().Err(err).Msgf("could not verify empty logs on another upstream, serving the empty result")
+		return resp
+	}
+	vjrr, err := vresp.JsonRpcResponse()
+	if err != nil || vjrr == nil || vjrr.Error != nil || vresp.IsResultEmptyish() {
+		return resp
+	}
+
+	health.MetricUpstreamEmptyLogsDiscrepancyTotal.WithLabelValues(n.ProjectId, n.NetworkId, servedBy, verifierId).Inc()
+	lg.Warn().Msgf("upstream returned empty logs for a range another upstream has logs for, serving the non-empty result")
+
+	return vresp.WithRequest(req).SetUpstream(verifier)
+}
+
+// logsFilterToBlock returns the explicit numeric toBlock of an eth_getLogs filter, tags and blockHash filters
+// are not verified since they are either near the head or do not tell how old the range is.
+func logsFilterToBlock(req *common.NormalizedRequest) (int64, bool) {
+	jrq, err := req.JsonRpcRequest()
+	if err != nil {
+		return 0, false
+	}
+	_, toBlock, _, kind, err := jrq.BlockRef()
+	if err != nil || kind != common.BlockRefKindRange || toBlock <= 0 {
+		return 0, false
+	}
+	return toBlock, true
+}
@@ -0,0 +1,58 @@
+package erpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/erpc/erpc/common"
+)
+
+// maxWaitForReceipt bounds the wait-for-receipt directive, the request timeout might end polling sooner.
+const maxWaitForReceipt = 5 * time.Minute
+
+// forwardWaitingForReceipt polls eth_getTransactionReceipt every block time until the receipt is available,
+// when the wait (or request deadline) is reached the last null result is returned as-is.
+func (n *Network) forwardWaitingForReceipt(ctx context.Context, req *common.NormalizedRequest, wait time.Duration) (*common.NormalizedResponse, error) {
+	// Parsed upfront so that the final response can carry the id of the client's request
+	if _, err := req.JsonRpcRequest(); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(min(wait, maxWaitForReceipt))
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	// Blocks are not produced faster than block time, so polling more often would only waste upstream quota
+	interval := n.realtimeTTL
+	if interval <= 0 {
+		interval = defaultEvmBlockTime
+	}
+
+	// Each poll is a separate request so that state of a previous attempt (e.g. last valid response) does not
+	// leak into the next one, also an empty receipt is expected here and does not need to be retried per poll.
+	drc := common.RequestDirectives{}
+	if d := req.Directives(); d != nil {
+		drc = *d
+	}
+	drc.WaitForReceipt = ""
+	drc.RetryEmpty = false
+
+	for polls := 1; ; polls++ {
+		resp, err := n.Forward(ctx, common.NewNormalizedRequest(req.Body()).WithDirectives(&drc))
+		if err != nil {
+			return nil, err
+		}
+		if !resp.IsResultEmptyish() || !time.Now().Add(interval).Before(deadline) {
+			n.Logger.Debug().Int("polls", polls).Bool("found", !resp.IsResultEmptyish()).Msgf("finished waiting for tx receipt")
+			return common.CopyResponseForRequest(resp, req)
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return common.CopyResponseForRequest(resp, req)
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package erpc
+
+import (
+	"github.com/erpc/erpc/common"
+	"github.com/valyala/fasthttp"
+)
+
+// Below this size gzip overhead (headers and cpu) is not worth it
+const defaultResponseCompressionMinSize = 1024
+
+// compressResponses gzips responses of the wrapped handler when the client accepts gzip and the body is large enough.
+func compressResponses(cfg *common.ResponseCompressionConfig, next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	minSize := defaultResponseCompressionMinSize
+	if cfg != nil {
+		if cfg.Enabled != nil && !*cfg.Enabled {
+			return next
+		}
+		if cfg.MinSize > 0 {
+			minSize = cfg.MinSize
+		}
+	}
+
+	return func(ctx *fasthttp.RequestCtx) {
+		next(ctx)
+
+		resp := &ctx.Response
+		resp.Header.Add(fasthttp.HeaderVary, fasthttp.HeaderAcceptEncoding)
+
+		if !ctx.Request.Header.HasAcceptEncoding("gzip") ||
+			len(resp.Header.ContentEncoding()) > 0 ||
+			// Streamed bodies (e.g. server-sent events) never end, reading them here would block forever
+			resp.IsBodyStream() ||
+			len(resp.Body()) < minSize {
+			return
+		}
+
+		compressed := fasthttp.AppendGzipBytes(nil, resp.Body())
+		resp.SetBodyRaw(compressed)
+		resp.Header.SetContentEncoding("gzip")
+	}
+}
@@ -0,0 +1,84 @@
+package erpc
+
+import (
+	"github.com/bytedance/sonic"
+	"github.com/erpc/erpc/common"
+	"github.com/erpc/erpc/upstream"
+	"github.com/valyala/fasthttp"
+)
+
+const healthCheckPath = "/healthz"
+
+type healthCheckResult struct {
+	Status   string                                `json:"status"`
+	Networks map[string]*upstream.NetworkReadiness `json:"networks,omitempty"`
+}
+
+// handleHealthCheck responds 200 when the server is up, or when "network" query arg is given only if
+// at least one upstream of that network (across all projects) is healthy, otherwise 503. Once shutdown
+// started it always responds 503.
+// With "verbose=1" the body enumerates healthy/total upstream counts of each network.
+func (s *HttpServer) handleHealthCheck(fastCtx *fasthttp.RequestCtx) {
+	if s.draining.Load() {
+		// Load balancers must stop routing to this instance while in-flight requests complete
+		fastCtx.SetConnectionClose()
+		s.writeHealthCheckResponse(fastCtx, fasthttp.StatusServiceUnavailable, &healthCheckResult{Status: "SHUTTING_DOWN"})
+		return
+	}
+
+	args := fastCtx.QueryArgs()
+	networkId := string(args.Peek("network"))
+	verbose := args.GetBool("verbose")
+
+	if networkId != "" {
+		if _, _, err := common.ParseNetworkId(networkId); err != nil {
+			s.writeHealthCheckResponse(fastCtx, decideErrorStatusCode(err), &healthCheckResult{Status: err.Error()})
+			return
+		}
+		// Networks are initialized lazily, so a load balancer probing before any traffic still gets a real answer
+		for _, prj := range s.erpc.projectsRegistry.GetAll() {
+			if _, err := prj.GetNetwork(networkId); err != nil {
+				s.logger.Debug().Err(err).Str("projectId", prj.Config.Id).Str("networkId", networkId).Msgf("network is not available for health check")
+			}
+		}
+	}
+
+	networks := make(map[string]*upstream.NetworkReadiness)
+	for _, prj := range s.erpc.projectsRegistry.GetAll() {
+		for nwId, nr := range prj.upstreamsRegistry.GetNetworksReadiness() {
+			agg, ok := networks[nwId]
+			if !ok {
+				agg = &upstream.NetworkReadiness{}
+				networks[nwId] = agg
+			}
+			agg.Healthy += nr.Healthy
+			agg.Total += nr.Total
+		}
+	}
+
+	statusCode := fasthttp.StatusOK
+	result := &healthCheckResult{Status: "OK"}
+	if networkId != "" {
+		if nr, ok := networks[networkId]; !ok || nr.Healthy == 0 {
+			statusCode = fasthttp.StatusServiceUnavailable
+			result.Status = "NO_HEALTHY_UPSTREAMS"
+		}
+	}
+	if verbose {
+		result.Networks = networks
+	}
+
+	s.writeHealthCheckResponse(fastCtx, statusCode, result)
+}
+
+func (s *HttpServer) writeHealthCheckResponse(fastCtx *fasthttp.RequestCtx, statusCode int, result *healthCheckResult) {
+	body, err := sonic.Marshal(result)
+	if err != nil {
+		s.logger.Error().Err(err).Msgf("failed to encode health check response")
+		fastCtx.SetStatusCode(fasthttp.StatusInternalServerError)
+		return
+	}
+	fastCtx.SetStatusCode(statusCode)
+	fastCtx.Response.Header.Set("Content-Type", "application/json")
+	fastCtx.SetBody(body)
+}
@@ -0,0 +1,104 @@
+package erpc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pathTemplate maps request paths like "/v1/{project}/{network}" to a project and network, placeholders
+// take a whole path segment: {project}, {network} (e.g. "evm:1") or {architecture} and {chainId}.
+type pathTemplate struct {
+	raw      string
+	segments []string
+}
+
+type pathTarget struct {
+	projectId    string
+	networkId    string
+	architecture string
+	chainId      string
+}
+
+func newPathTemplate(raw string) (*pathTemplate, error) {
+	if !strings.HasPrefix(raw, "/") {
+		return nil, fmt.Errorf("path template must start with /: %s", raw)
+	}
+	segments := strings.Split(strings.TrimSuffix(raw[1:], "/"), "/")
+	seen := map[string]bool{}
+	for _, seg := range segments {
+		if !strings.HasPrefix(seg, "{") {
+			if strings.ContainsAny(seg, "{}") {
+				return nil, fmt.Errorf("placeholder must take a whole path segment in template %s: %s", raw, seg)
+			}
+			continue
+		}
+		switch seg {
+		case "{project}", "{network}", "{architecture}", "{chainId}":
+			if seen[seg] {
+				return nil, fmt.Errorf("placeholder %s appears more than once in template %s", seg, raw)
+			}
+			seen[seg] = true
+		default:
+			return nil, fmt.Errorf("unknown placeholder %s in template %s (must be {project}, {network}, {architecture} or {chainId})", seg, raw)
+		}
+	}
+	if !seen["{project}"] {
+		return nil, fmt.Errorf("path template must contain {project}: %s", raw)
+	}
+	if seen["{network}"] == (seen["{architecture}"] || seen["{chainId}"]) || seen["{architecture}"] != seen["{chainId}"] {
+		return nil, fmt.Errorf("path template must contain either {network} or both {architecture} and {chainId}: %s", raw)
+	}
+	return &pathTemplate{raw: raw, segments: segments}, nil
+}
+
+// match returns the project and network addressed by path, ok is false when the path does not follow the template.
+// A {network} segment that is not "<architecture>:<chainId>" still matches, resolving it reports it as not found.
+func (t *pathTemplate) match(path string) (*pathTarget, bool) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+	parts := strings.Split(strings.TrimSuffix(path[1:], "/"), "/")
+	if len(parts) != len(t.segments) {
+		return nil, false
+	}
+
+	target := &pathTarget{}
+	for i, seg := range t.segments {
+		part := parts[i]
+		switch seg {
+		case "{project}":
+			target.projectId = part
+		case "{network}":
+			target.networkId = part
+			if arch, chainId, ok := strings.Cut(part, ":"); ok {
+				target.architecture = arch
+				target.chainId = chainId
+			}
+		case "{architecture}":
+			target.architecture = part
+		case "{chainId}":
+			target.chainId = part
+		default:
+			if part != seg {
+				return nil, false
+			}
+			continue
+		}
+		if part == "" {
+			return nil, false
+		}
+	}
+	if target.networkId == "" {
+		target.networkId = target.architecture + ":" + target.chainId
+	}
+	return target, true
+}
+
+func matchPathTemplates(templates []*pathTemplate, path string) (*pathTarget, bool) {
+	for _, t := range templates {
+		if target, ok := t.match(path); ok {
+			return target, true
+		}
+	}
+	return nil, false
+}
@@ -8,8 +8,11 @@ import (
 	"fmt"
 	"net"
 	"runtime/debug"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bytedance/sonic"
@@ -18,13 +21,27 @@ import (
 	"github.com/erpc/erpc/health"
 	"github.com/rs/zerolog"
 	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const defaultShutdownGracePeriod = 30 * time.Second
+
 type HttpServer struct {
-	config *common.ServerConfig
-	server *fasthttp.Server
-	erpc   *ERPC
-	logger *zerolog.Logger
+	config        *common.ServerConfig
+	server        *fasthttp.Server
+	erpc          *ERPC
+	logger        *zerolog.Logger
+	pathTemplates []*pathTemplate
+
+	shutdownGracePeriod time.Duration
+	// Set once shutdown starts, from then on the server reports itself unready and rejects new requests
+	draining atomic.Bool
+	// Closed once shutdown starts so that long-lived streams end instead of holding up the shutdown
+	drain        chan struct{}
+	shutdownOnce sync.Once
+	shutdownErr  error
 }
 
 var bufPool = sync.Pool{
@@ -33,7 +50,7 @@ var bufPool = sync.Pool{
 	},
 }
 
-func NewHttpServer(ctx context.Context, logger *zerolog.Logger, cfg *common.ServerConfig, erpc *ERPC) *HttpServer {
+func NewHttpServer(ctx context.Context, logger *zerolog.Logger, cfg *common.ServerConfig, erpc *ERPC) (*HttpServer, error) {
 	reqMaxTimeout, err := time.ParseDuration(cfg.MaxTimeout)
 	if err != nil {
 		if cfg.MaxTimeout != "" {
@@ -42,17 +59,38 @@ func NewHttpServer(ctx context.Context, logger *zerolog.Logger, cfg *common.Serv
 		reqMaxTimeout = 30 * time.Second
 	}
 
+	timeouts := newRequestTimeouts(logger, cfg, reqMaxTimeout)
+
 	srv := &HttpServer{
-		config: cfg,
-		erpc:   erpc,
-		logger: logger,
+		config:              cfg,
+		erpc:                erpc,
+		logger:              logger,
+		shutdownGracePeriod: defaultShutdownGracePeriod,
+		drain:               make(chan struct{}),
+	}
+	if cfg.ShutdownGracePeriod != "" {
+		d, err := time.ParseDuration(cfg.ShutdownGracePeriod)
+		if err != nil {
+			return nil, common.NewErrInvalidConfig(fmt.Sprintf("invalid server.shutdownGracePeriod %q: %v", cfg.ShutdownGracePeriod, err))
+		}
+		if d < 0 {
+			return nil, common.NewErrInvalidConfig(fmt.Sprintf("server.shutdownGracePeriod must not be negative, got %q", cfg.ShutdownGracePeriod))
+		}
+		srv.shutdownGracePeriod = d
+	}
+	for _, raw := range cfg.PathTemplates {
+		t, err := newPathTemplate(raw)
+		if err != nil {
+			return nil, common.NewErrInvalidConfig(fmt.Sprintf("invalid server.pathTemplates entry: %v", err))
+		}
+		srv.pathTemplates = append(srv.pathTemplates, t)
 	}
 
 	srv.server = &fasthttp.Server{
 		Handler: fasthttp.TimeoutHandler(
-			srv.createRequestHandler(ctx, reqMaxTimeout),
+			compressResponses(cfg.ResponseCompression, srv.createRequestHandler(ctx, timeouts)),
 			// This is the last resort timeout if nothing could be done in time
-			reqMaxTimeout+1*time.Second,
+			timeouts.Max()+1*time.Second,
 			`{"jsonrpc":"2.0","error":{"code":-32603,"message":"request timeout before any upstream responded"}}`,
 		),
 		ReadTimeout:  5 * time.Second,
@@ -68,10 +106,109 @@ func NewHttpServer(ctx context.Context, logger *zerolog.Logger, cfg *common.Serv
 		}
 	}()
 
-	return srv
+	return srv, nil
+}
+
+// requestTimeouts resolves the deadline of each request based on its method,
+// falling back to server's maxTimeout when there is no method-specific override.
+type requestTimeouts struct {
+	defaultTimeout time.Duration
+	// Exact method names
+	methods map[string]time.Duration
+	// Wildcard patterns, in the order they are matched
+	patterns    []methodTimeout
+	maxOverride time.Duration
+}
+
+type methodTimeout struct {
+	pattern string
+	timeout time.Duration
+}
+
+// newRequestTimeouts parses method-specific timeouts, invalid entries are logged and ignored.
+func newRequestTimeouts(logger *zerolog.Logger, cfg *common.ServerConfig, defaultTimeout time.Duration) *requestTimeouts {
+	timeouts := &requestTimeouts{
+		defaultTimeout: defaultTimeout,
+		methods:        make(map[string]time.Duration, len(cfg.MethodTimeouts)),
+	}
+	for method, tm := range cfg.MethodTimeouts {
+		d, err := time.ParseDuration(tm)
+		if err != nil {
+			logger.Error().Err(err).Str("method", method).Msgf("failed to parse method timeout duration, ignoring it")
+			continue
+		}
+		if strings.Contains(method, "*") {
+			timeouts.patterns = append(timeouts.patterns, methodTimeout{pattern: method, timeout: d})
+		} else {
+			timeouts.methods[method] = d
+		}
+	}
+	// Longest (most specific) patterns are matched first, ties are broken alphabetically so that overlapping
+	// patterns always resolve the same way
+	sort.Slice(timeouts.patterns, func(i, j int) bool {
+		pi, pj := timeouts.patterns[i].pattern, timeouts.patterns[j].pattern
+		if len(pi) != len(pj) {
+			return len(pi) > len(pj)
+		}
+		return pi < pj
+	})
+	if cfg.MaxTimeoutOverride != "" {
+		d, err := time.ParseDuration(cfg.MaxTimeoutOverride)
+		if err != nil {
+			logger.Error().Err(err).Msgf("failed to parse max timeout override duration, ignoring X-ERPC-Timeout headers")
+		} else {
+			timeouts.maxOverride = d
+		}
+	}
+
+	return timeouts
+}
+
+// ForRequest honors the timeout requested by the client (e.g. "X-ERPC-Timeout: 30s") clamped to maxOverride,
+// invalid values or a server without maxTimeoutOverride fall back to the method's timeout.
+func (t *requestTimeouts) ForRequest(method string, requested string) time.Duration {
+	if t.maxOverride > 0 && requested != "" {
+		if d, err := time.ParseDuration(requested); err == nil && d > 0 {
+			if d > t.maxOverride {
+				return t.maxOverride
+			}
+			return d
+		}
+	}
+	return t.ForMethod(method)
+}
+
+func (t *requestTimeouts) ForMethod(method string) time.Duration {
+	if d, ok := t.methods[method]; ok {
+		return d
+	}
+	for _, p := range t.patterns {
+		if common.WildcardMatch(p.pattern, method) {
+			return p.timeout
+		}
+	}
+	return t.defaultTimeout
+}
+
+func (t *requestTimeouts) Max() time.Duration {
+	max := t.defaultTimeout
+	if t.maxOverride > max {
+		max = t.maxOverride
+	}
+	for _, d := range t.methods {
+		if d > max {
+			max = d
+		}
+	}
+	for _, p := range t.patterns {
+		if p.timeout > max {
+			max = p.timeout
+		}
+	}
+	return max
 }
 
-func (s *HttpServer) createRequestHandler(mainCtx context.Context, reqMaxTimeout time.Duration) fasthttp.RequestHandler {
+func (s *HttpServer) createRequestHandler(mainCtx context.Context, timeouts *requestTimeouts) fasthttp.RequestHandler {
 	return func(fastCtx *fasthttp.RequestCtx) {
 		defer func() {
 			defer func() { recover() }()
@@ -89,26 +226,47 @@ func (s *HttpServer) createRequestHandler(mainCtx context.Context, reqMaxTimeout
 		buf.Reset()
 		encoder := json.NewEncoder(buf)
 
-		segments := strings.Split(string(fastCtx.Path()), "/")
-		if len(segments) != 2 && len(segments) != 3 && len(segments) != 4 {
-			handleErrorResponse(s.logger, nil, common.NewErrInvalidUrlPath(string(fastCtx.Path())), fastCtx, encoder, buf)
+		if string(fastCtx.Path()) == healthCheckPath {
+			s.handleHealthCheck(fastCtx)
 			return
 		}
 
-		projectId := segments[1]
-		architecture, chainId := "", ""
+		if s.draining.Load() {
+			// Requests arriving on kept-alive connections after shutdown started, the client should go elsewhere
+			fastCtx.SetConnectionClose()
+			handleErrorResponse(s.logger, nil, common.NewErrServerShuttingDown(), fastCtx, encoder, buf)
+			return
+		}
+
+		var projectId, architecture, chainId string
 		isAdmin := false
 
-		if len(segments) == 4 {
-			architecture = segments[2]
-			chainId = segments[3]
-		} else if len(segments) == 3 {
-			if segments[2] == "admin" {
-				isAdmin = true
-			} else {
+		if target, ok := matchPathTemplates(s.pathTemplates, string(fastCtx.Path())); ok {
+			if target.architecture == "" || target.chainId == "" {
+				handleErrorResponse(s.logger, nil, common.NewErrNetworkNotFound(target.networkId), fastCtx, encoder, buf)
+				return
+			}
+			projectId, architecture, chainId = target.projectId, target.architecture, target.chainId
+		} else {
+			segments := strings.Split(string(fastCtx.Path()), "/")
+			if len(segments) != 2 && len(segments) != 3 && len(segments) != 4 {
 				handleErrorResponse(s.logger, nil, common.NewErrInvalidUrlPath(string(fastCtx.Path())), fastCtx, encoder, buf)
 				return
 			}
+
+			projectId = segments[1]
+
+			if len(segments) == 4 {
+				architecture = segments[2]
+				chainId = segments[3]
+			} else if len(segments) == 3 {
+				if segments[2] == "admin" {
+					isAdmin = true
+				} else {
+					handleErrorResponse(s.logger, nil, common.NewErrInvalidUrlPath(string(fastCtx.Path())), fastCtx, encoder, buf)
+					return
+				}
+			}
 		}
 
 		lg := s.logger.With().Str("projectId", projectId).Str("architecture", architecture).Str("chainId", chainId).Logger()
@@ -129,6 +287,19 @@ func (s *HttpServer) createRequestHandler(mainCtx context.Context, reqMaxTimeout
 			}
 		}
 
+		if isEventStreamRequest(fastCtx) && architecture != "" && chainId != "" {
+			s.handleEventStream(mainCtx, fastCtx, &lg, project, fmt.Sprintf("%s:%s", architecture, chainId), encoder, buf)
+			return
+		}
+
+		if s.config.RequireJsonContentType && fastCtx.IsPost() {
+			contentType := string(fastCtx.Request.Header.ContentType())
+			if !isJsonContentType(contentType) {
+				handleErrorResponse(&lg, nil, common.NewErrInvalidContentType(contentType), fastCtx, encoder, buf)
+				return
+			}
+		}
+
 		body := fastCtx.PostBody()
 
 		lg.Debug().Msgf("received request with body: %s", body)
@@ -142,12 +313,14 @@ func (s *HttpServer) createRequestHandler(mainCtx context.Context, reqMaxTimeout
 		}
 
 		responses := make([]interface{}, len(requests))
+		normalizedRequests := make([]*common.NormalizedRequest, len(requests))
 		var wg sync.WaitGroup
 
 		var headersCopy fasthttp.RequestHeader
 		var queryArgsCopy fasthttp.Args
 		fastCtx.Request.Header.CopyTo(&headersCopy)
 		fastCtx.QueryArgs().CopyTo(&queryArgsCopy)
+		clientIp := fastCtx.RemoteIP().String()
 
 		for i, reqBody := range requests {
 			wg.Add(1)
@@ -165,29 +338,83 @@ func (s *HttpServer) createRequestHandler(mainCtx context.Context, reqMaxTimeout
 
 				defer wg.Done()
 
-				requestCtx, cancel := context.WithTimeoutCause(mainCtx, reqMaxTimeout, common.NewErrRequestTimeout(reqMaxTimeout))
-				defer cancel()
-
 				nq := common.NewNormalizedRequest(rawReq)
 				nq.ApplyDirectivesFromHttp(headersCopy, queryArgsCopy)
+				normalizedRequests[index] = nq
 
 				m, _ := nq.Method()
 				rlg := lg.With().Str("method", m).Logger()
 
+				spanCtx := incomingPropagator.Extract(mainCtx, fasthttpHeaderCarrier{headersCopy})
+				spanCtx, span := otel.Tracer(tracerName).Start(
+					spanCtx,
+					"erpc.request",
+					trace.WithSpanKind(trace.SpanKindServer),
+					trace.WithAttributes(
+						attribute.String("erpc.project_id", projectId),
+						attribute.String("rpc.method", m),
+					),
+				)
+				var reqErr error
+				defer func() { endSpan(span, reqErr) }()
+				fail := func(err error) {
+					reqErr = err
+					responses[index] = processErrorBody(&rlg, nq, err)
+				}
+
+				// Ambiguous payloads would make the cache key differ from what upstreams actually receive
+				if s.config.StrictJsonParsing {
+					if err := common.ValidateNoDuplicateJsonKeys(rawReq); err != nil {
+						fail(common.NewErrInvalidRequest(err))
+						return
+					}
+				}
+
+				// Malformed envelopes are rejected upfront instead of being attempted on every upstream
+				if _, err := nq.JsonRpcRequest(); err != nil {
+					fail(err)
+					return
+				}
+
+				reqTimeout := timeouts.ForRequest(m, string(headersCopy.Peek("X-ERPC-Timeout")))
+				requestCtx, cancel := context.WithTimeoutCause(spanCtx, reqTimeout, common.NewErrRequestTimeout(reqTimeout))
+				defer cancel()
+				if reqTimeout != timeouts.defaultTimeout {
+					// Shorter failsafe timeouts of network and upstreams would otherwise cut the request first
+					nq.WithTimeout(reqTimeout)
+				}
+				if m == "eth_subscribe" {
+					requestCtx = context.WithValue(requestCtx, pollSubscriptionClientContext, clientIp)
+				}
+
 				ap, err := auth.NewPayloadFromHttp(project.Config.Id, nq, headersCopy, queryArgsCopy)
 				if err != nil {
-					responses[index] = processErrorBody(&rlg, nq, err)
+					fail(err)
 					return
 				}
 
 				if isAdmin {
 					if err := project.AuthenticateAdmin(requestCtx, nq, ap); err != nil {
-						responses[index] = processErrorBody(&rlg, nq, err)
+						fail(err)
+						return
+					}
+				} else if d := nq.Directives(); d.ExcludeUpstream != "" || d.ConsensusParticipants != "" || d.ConsensusAgreements != "" {
+					// Excluding upstreams is meant for debugging by operators and overriding consensus changes how much
+					// load a request puts on upstreams, so both require admin credentials
+					if project.Config.Admin == nil {
+						fail(common.NewErrAuthUnauthorized(
+							"",
+							"excluding upstreams or overriding consensus requires admin to be enabled for this project",
+						))
+						return
+					}
+					if err := project.AuthenticateAdmin(requestCtx, nq, ap); err != nil {
+						fail(err)
 						return
 					}
 				} else {
 					if err := project.AuthenticateConsumer(requestCtx, nq, ap); err != nil {
-						responses[index] = processErrorBody(&rlg, nq, err)
+						fail(err)
 						return
 					}
 				}
@@ -196,20 +423,16 @@ func (s *HttpServer) createRequestHandler(mainCtx context.Context, reqMaxTimeout
 					if project.Config.Admin != nil {
 						resp, err := project.HandleAdminRequest(requestCtx, nq)
 						if err != nil {
-							responses[index] = processErrorBody(&rlg, nq, err)
+							fail(err)
 							return
 						}
 						responses[index] = resp
 						return
 					} else {
-						responses[index] = processErrorBody(
-							&rlg,
-							nq,
-							common.NewErrAuthUnauthorized(
-								"",
-								"admin is not enabled for this project",
-							),
-						)
+						fail(common.NewErrAuthUnauthorized(
+							"",
+							"admin is not enabled for this project",
+						))
 						return
 					}
 				}
@@ -219,14 +442,14 @@ func (s *HttpServer) createRequestHandler(mainCtx context.Context, reqMaxTimeout
 				if architecture == "" || chainId == "" {
 					var req map[string]interface{}
 					if err := sonic.Unmarshal(rawReq, &req); err != nil {
-						responses[index] = processErrorBody(&rlg, nq, common.NewErrInvalidRequest(err))
+						fail(common.NewErrJsonRpcRequestUnmarshal(err))
 						return
 					}
 					if networkIdFromBody, ok := req["networkId"].(string); ok {
 						networkId = networkIdFromBody
 						parts := strings.Split(networkId, ":")
 						if len(parts) != 2 {
-							responses[index] = processErrorBody(&rlg, nq, common.NewErrInvalidRequest(fmt.Errorf(
+							fail(common.NewErrInvalidRequest(fmt.Errorf(
 								"networkId must follow this format: 'architecture:chainId' for example 'evm:42161'",
 							)))
 							return
@@ -234,7 +457,7 @@ func (s *HttpServer) createRequestHandler(mainCtx context.Context, reqMaxTimeout
 						architecture = parts[0]
 						chainId = parts[1]
 					} else {
-						responses[index] = processErrorBody(&rlg, nq, common.NewErrInvalidRequest(fmt.Errorf(
+						fail(common.NewErrInvalidRequest(fmt.Errorf(
 							"networkId must follow this format: 'architecture:chainId' for example 'evm:42161'",
 						)))
 						return
@@ -245,14 +468,19 @@ func (s *HttpServer) createRequestHandler(mainCtx context.Context, reqMaxTimeout
 
 				nw, err := project.GetNetwork(networkId)
 				if err != nil {
-					responses[index] = processErrorBody(&rlg, nq, err)
+					if common.HasErrorCode(err, common.ErrCodeNoUpstreamsFound) {
+						// No upstream serves this network at all, which is the same as a network that does not exist
+						err = common.NewErrNetworkNotFound(networkId)
+					}
+					fail(err)
 					return
 				}
 				nq.SetNetwork(nw)
+				span.SetAttributes(attribute.String("erpc.network_id", networkId))
 
 				resp, err := project.Forward(requestCtx, networkId, nq)
 				if err != nil {
-					responses[index] = processErrorBody(&rlg, nq, err)
+					fail(err)
 					return
 				}
 
@@ -276,6 +504,9 @@ func (s *HttpServer) createRequestHandler(mainCtx context.Context, reqMaxTimeout
 		} else {
 			res := responses[0]
 			setResponseHeaders(res, fastCtx)
+			if s.config.TraceHeader && string(fastCtx.Request.Header.Peek("X-ERPC-Trace")) == "true" {
+				setTraceHeader(normalizedRequests[0], fastCtx)
+			}
 			setResponseStatusCode(res, fastCtx)
 			err = encoder.Encode(res)
 			if err != nil {
@@ -285,6 +516,19 @@ func (s *HttpServer) createRequestHandler(mainCtx context.Context, reqMaxTimeout
 			}
 		}
 
+		// Responses (and cache entries) are always json, internal consumers may ask for msgpack to save bandwidth
+		if common.AcceptsMsgpack(string(fastCtx.Request.Header.Peek("Accept"))) {
+			packed, err := common.JsonToMsgpack(buf.Bytes())
+			if err != nil {
+				fastCtx.SetStatusCode(fasthttp.StatusInternalServerError)
+				fastCtx.SetBodyString(fmt.Sprintf(`{"jsonrpc":"2.0","error":{"code":-32603,"message":"%s"}}`, err.Error()))
+				return
+			}
+			fastCtx.Response.Header.SetContentType(common.MsgpackContentType)
+			fastCtx.SetBody(packed)
+			return
+		}
+
 		fastCtx.SetBody(buf.Bytes())
 	}
 }
@@ -339,6 +583,12 @@ func (s *HttpServer) handleCORS(ctx *fasthttp.RequestCtx, corsConfig *common.COR
 	return true
 }
 
+func isJsonContentType(contentType string) bool {
+	// Ignore parameters such as "; charset=utf-8"
+	mediaType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	return strings.EqualFold(mediaType, "application/json")
+}
+
 func setResponseHeaders(res interface{}, fastCtx *fasthttp.RequestCtx) {
 	var rm common.ResponseMetadata
 	var ok bool
@@ -364,8 +614,20 @@ func setResponseHeaders(res interface{}, fastCtx *fasthttp.RequestCtx) {
 	if ok && rm != nil {
 		if rm.FromCache() {
 			fastCtx.Response.Header.Set("X-ERPC-Cache", "HIT")
+			fastCtx.Response.Header.Set("X-Cache", "HIT")
+			if nr, ok := rm.(*common.NormalizedResponse); ok {
+				fastCtx.Response.Header.Set("X-ERPC-Cache-Age", fmt.Sprintf("%d", int64(nr.CacheAge().Seconds())))
+				if nr.IsStale() {
+					fastCtx.Response.Header.Set("X-ERPC-Stale", "true")
+				}
+			}
 		} else {
 			fastCtx.Response.Header.Set("X-ERPC-Cache", "MISS")
+			fastCtx.Response.Header.Set("X-Cache", "MISS")
+		}
+		if nr, ok := rm.(*common.NormalizedResponse); ok && nr.IsPartial() {
+			fastCtx.Response.Header.Set("X-ERPC-Partial", "true")
+			fastCtx.Response.Header.Set("X-ERPC-Missing-Ranges", strings.Join(nr.MissingRanges(), ","))
 		}
 		if rm.UpstreamId() != "" {
 			fastCtx.Response.Header.Set("X-ERPC-Upstream", rm.UpstreamId())
@@ -376,13 +638,28 @@ func setResponseHeaders(res interface{}, fastCtx *fasthttp.RequestCtx) {
 	}
 }
 
+// setTraceHeader lists the upstreams tried for the request, e.g. X-ERPC-Trace: [{"upstream":"rpc1","outcome":"error",...},...]
+func setTraceHeader(nq *common.NormalizedRequest, fastCtx *fasthttp.RequestCtx) {
+	attempts := nq.UpstreamAttempts()
+	if len(attempts) == 0 {
+		return
+	}
+	trace, err := sonic.Marshal(attempts)
+	if err != nil {
+		return
+	}
+	fastCtx.Response.Header.Set("X-ERPC-Trace", string(trace))
+}
+
 func setResponseStatusCode(respOrErr interface{}, fastCtx *fasthttp.RequestCtx) {
 	if err, ok := respOrErr.(error); ok {
 		fastCtx.SetStatusCode(decideErrorStatusCode(err))
+		setRateLimitHeaders(err, fastCtx)
 	} else if resp, ok := respOrErr.(map[string]interface{}); ok {
 		if errObj, ok := resp["error"].(map[string]interface{}); ok {
 			if cause, ok := errObj["cause"].(error); ok {
 				fastCtx.SetStatusCode(decideErrorStatusCode(cause))
+				setRateLimitHeaders(cause, fastCtx)
 			} else {
 				fastCtx.SetStatusCode(fasthttp.StatusOK)
 			}
@@ -394,6 +671,26 @@ func setResponseStatusCode(respOrErr interface{}, fastCtx *fasthttp.RequestCtx)
 	}
 }
 
+// setRateLimitHeaders tells standard clients when to retry if one of eRPC's own rate limiters tripped,
+// e.g. Retry-After: 1, X-RateLimit-Limit: 100, X-RateLimit-Remaining: 0, X-RateLimit-Reset: 1
+func setRateLimitHeaders(err error, fastCtx *fasthttp.RequestCtx) {
+	var rle common.RateLimitedError
+	if !errors.As(err, &rle) {
+		return
+	}
+	limit, resetIn := rle.RateLimit()
+	// Round up so that clients never retry before the permits are replenished
+	resetSec := int64((resetIn + time.Second - 1) / time.Second)
+	if resetSec < 1 {
+		resetSec = 1
+	}
+	fastCtx.SetStatusCode(fasthttp.StatusTooManyRequests)
+	fastCtx.Response.Header.Set("Retry-After", strconv.FormatInt(resetSec, 10))
+	fastCtx.Response.Header.Set("X-RateLimit-Limit", strconv.FormatUint(uint64(limit), 10))
+	fastCtx.Response.Header.Set("X-RateLimit-Remaining", "0")
+	fastCtx.Response.Header.Set("X-RateLimit-Reset", strconv.FormatInt(resetSec, 10))
+}
+
 func processErrorBody(logger *zerolog.Logger, nq *common.NormalizedRequest, err error) interface{} {
 	if !common.IsNull(err) {
 		if nq != nil {
@@ -426,13 +723,25 @@ func processErrorBody(logger *zerolog.Logger, nq *common.NormalizedRequest, err
 	}
 	jre := &common.ErrJsonRpcExceptionInternal{}
 	if errors.As(err, &jre) {
+		data := jre.Details["data"]
+		if data == nil {
+			// When every upstream failed, list each failure so clients can see what went wrong where
+			uer := &common.ErrUpstreamsExhausted{}
+			if errors.As(err, &uer) {
+				if failures := uer.UpstreamFailures(); len(failures) > 0 {
+					data = map[string]interface{}{
+						"upstreamFailures": failures,
+					}
+				}
+			}
+		}
 		return map[string]interface{}{
 			"jsonrpc": jsonrpcVersion,
 			"id":      reqId,
 			"error": map[string]interface{}{
 				"code":    jre.NormalizedCode(),
 				"message": jre.Message,
-				"data":    jre.Details["data"],
+				"data":    data,
 				"cause":   err,
 			},
 		}
@@ -518,7 +827,16 @@ func (s *HttpServer) Start(logger *zerolog.Logger) error {
 	return s.server.Serve(ln)
 }
 
+// Shutdown marks the server as unready, stops accepting connections and waits for in-flight requests to
+// complete up to the shutdown grace period. It is safe to call more than once, later calls wait for the first.
 func (s *HttpServer) Shutdown(logger *zerolog.Logger) error {
-	logger.Info().Msg("stopping http server...")
-	return s.server.Shutdown()
+	s.shutdownOnce.Do(func() {
+		logger.Info().Dur("gracePeriod", s.shutdownGracePeriod).Msg("stopping http server...")
+		s.draining.Store(true)
+		close(s.drain)
+		ctx, cancel := context.WithTimeout(context.Background(), s.shutdownGracePeriod)
+		defer cancel()
+		s.shutdownErr = s.server.ShutdownWithContext(ctx)
+	})
+	return s.shutdownErr
 }
@@ -7,6 +7,8 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -18,6 +20,7 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
 )
 
 func TestHttpServer_RaceTimeouts(t *testing.T) {
@@ -65,7 +68,8 @@ func TestHttpServer_RaceTimeouts(t *testing.T) {
 	erpcInstance, err := NewERPC(ctx, &logger, nil, cfg)
 	require.NoError(t, err)
 
-	httpServer := NewHttpServer(ctx, &logger, cfg.Server, erpcInstance)
+	httpServer, err := NewHttpServer(ctx, &logger, cfg.Server, erpcInstance)
+	require.NoError(t, err)
 
 	// Start the server on a random port
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
@@ -318,6 +322,36 @@ func TestHttpServer_SingleUpstream(t *testing.T) {
 		assert.Contains(t, string(errStr), "ErrJsonRpcRequestUnmarshal")
 	})
 
+	t.Run("NonJsonBodyIsParseError", func(t *testing.T) {
+		statusCode, body := sendRequest(`not json at all`, nil, nil)
+
+		assert.Equal(t, http.StatusBadRequest, statusCode, body)
+		assert.Contains(t, body, `"code":-32700`)
+	})
+
+	t.Run("MissingMethodIsInvalidRequest", func(t *testing.T) {
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","params":[],"id":1}`, nil, nil)
+
+		assert.Equal(t, http.StatusBadRequest, statusCode, body)
+		assert.Contains(t, body, `"code":-32600`)
+		assert.Contains(t, body, "missing method")
+	})
+
+	t.Run("InvalidEnvelopeFieldsAreInvalidRequests", func(t *testing.T) {
+		cases := map[string]string{
+			`{"jsonrpc":"2.0","method":"eth_chainId","params":[],"id":{"a":1}}`: "id must be a string, number or null",
+			`{"jsonrpc":"2.0","method":"eth_chainId","params":"0x1","id":1}`:    "params must be an array",
+			`{"jsonrpc":"2.0","method":123,"params":[],"id":1}`:                 "method must be a string",
+		}
+		for reqBody, msg := range cases {
+			statusCode, body := sendRequest(reqBody, nil, nil)
+
+			assert.Equal(t, http.StatusBadRequest, statusCode, body)
+			assert.Contains(t, body, `"code":-32600`, reqBody)
+			assert.Contains(t, body, msg, reqBody)
+		}
+	})
+
 	t.Run("UnsupportedMethod", func(t *testing.T) {
 		defer gock.Off()
 
@@ -454,7 +488,8 @@ func createServerTestFixtures(cfg *common.Config, t *testing.T) (
 	erpcInstance, err := NewERPC(ctx, &logger, nil, cfg)
 	require.NoError(t, err)
 
-	httpServer := NewHttpServer(ctx, &logger, cfg.Server, erpcInstance)
+	httpServer, err := NewHttpServer(ctx, &logger, cfg.Server, erpcInstance)
+	require.NoError(t, err)
 
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	require.NoError(t, err)
@@ -612,4 +647,2135 @@ func TestHttpServer_MultipleUpstreams(t *testing.T) {
 
 		assert.True(t, gock.IsDone(), "All mocks should have been called")
 	})
+
+	t.Run("AllUpstreamsFailedListsEachFailureInErrorData", func(t *testing.T) {
+		defer gock.Clean()
+
+		gock.New("http://rpc1.localhost").
+			Post("/").
+			Persist().
+			Reply(500).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"error": map[string]interface{}{
+					"code":    -32603,
+					"message": "rpc1 internal failure",
+				},
+			})
+		gock.New("http://rpc2.localhost").
+			Post("/").
+			Persist().
+			Reply(429).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"error": map[string]interface{}{
+					"code":    -32005,
+					"message": "rpc2 capacity exceeded",
+				},
+			})
+
+		_, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x1234","0x1"],"id":1}`, nil, nil)
+
+		var resp struct {
+			Error struct {
+				Data struct {
+					UpstreamFailures []common.UpstreamFailure `json:"upstreamFailures"`
+				} `json:"data"`
+			} `json:"error"`
+		}
+		require.NoError(t, sonic.UnmarshalString(body, &resp), body)
+
+		failures := resp.Error.Data.UpstreamFailures
+		require.Len(t, failures, 2, body)
+		assert.Equal(t, "rpc1", failures[0].UpstreamId)
+		assert.Equal(t, common.ErrorCode(common.ErrCodeEndpointServerSideException), failures[0].Code)
+		assert.Contains(t, failures[0].Message, "rpc1 internal failure")
+		assert.Equal(t, "rpc2", failures[1].UpstreamId)
+		assert.Equal(t, common.ErrorCode(common.ErrCodeEndpointCapacityExceeded), failures[1].Code)
+		assert.Contains(t, failures[1].Message, "rpc2 capacity exceeded")
+	})
+}
+
+func TestHttpServer_CacheHeaders(t *testing.T) {
+	t.Run("HitIncludesCacheAge", func(t *testing.T) {
+		resp := common.NewNormalizedResponse().
+			WithFromCache(true).
+			WithCachedAt(time.Now().Add(-5 * time.Minute))
+
+		fastCtx := &fasthttp.RequestCtx{}
+		setResponseHeaders(resp, fastCtx)
+
+		assert.Equal(t, "HIT", string(fastCtx.Response.Header.Peek("X-Cache")))
+		assert.Equal(t, "HIT", string(fastCtx.Response.Header.Peek("X-ERPC-Cache")))
+		assert.Equal(t, "300", string(fastCtx.Response.Header.Peek("X-ERPC-Cache-Age")))
+	})
+
+	t.Run("MissHasNoCacheAge", func(t *testing.T) {
+		resp := common.NewNormalizedResponse()
+
+		fastCtx := &fasthttp.RequestCtx{}
+		setResponseHeaders(resp, fastCtx)
+
+		assert.Equal(t, "MISS", string(fastCtx.Response.Header.Peek("X-Cache")))
+		assert.Equal(t, "MISS", string(fastCtx.Response.Header.Peek("X-ERPC-Cache")))
+		assert.Empty(t, fastCtx.Response.Header.Peek("X-ERPC-Cache-Age"))
+	})
+}
+
+func TestHttpServer_MethodTimeouts(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "500ms",
+			MethodTimeouts: map[string]string{
+				"trace_*": "5s",
+			},
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId: 1,
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc1.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 1,
+						},
+						VendorName: "llama",
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	sendRequest, _ := createServerTestFixtures(cfg, t)
+
+	t.Run("SlowTraceMethodGetsExtendedTimeout", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://rpc1.localhost").
+			Post("/").
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "trace_replayBlockTransactions")
+			}).
+			Reply(200).
+			Delay(1 * time.Second).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  []interface{}{},
+			})
+
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"trace_replayBlockTransactions","params":["0x1",["trace"]],"id":1}`, nil, nil)
+		assert.Equal(t, http.StatusOK, statusCode, body)
+		assert.NotContains(t, body, "timeout")
+	})
+
+	t.Run("QuickMethodKeepsShortTimeout", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://rpc1.localhost").
+			Post("/").
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getBalance")
+			}).
+			Reply(200).
+			Delay(1 * time.Second).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  "0x1",
+			})
+
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","latest"],"id":1}`, nil, nil)
+		assert.NotEqual(t, http.StatusOK, statusCode, body)
+		assert.Contains(t, body, "timeout")
+	})
+}
+
+func TestHttpServer_MethodTimeoutsExtendFailsafeTimeouts(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "500ms",
+			MethodTimeouts: map[string]string{
+				"trace_*": "3s",
+			},
+			MaxTimeoutOverride: "3s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId: 1,
+						},
+						Failsafe: &common.FailsafeConfig{
+							Timeout: &common.TimeoutPolicyConfig{Duration: "500ms"},
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc1.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 1,
+						},
+						VendorName: "llama",
+						Failsafe: &common.FailsafeConfig{
+							Timeout: &common.TimeoutPolicyConfig{Duration: "500ms"},
+						},
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	sendRequest, _ := createServerTestFixtures(cfg, t)
+
+	mockSlow := func(method string) {
+		gock.New("http://rpc1.localhost").
+			Post("/").
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), method)
+			}).
+			Reply(200).
+			Delay(1 * time.Second).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  []interface{}{},
+			})
+	}
+
+	t.Run("MethodTimeoutLongerThanFailsafeTimeoutsApplies", func(t *testing.T) {
+		defer gock.Off()
+		mockSlow("trace_replayBlockTransactions")
+
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"trace_replayBlockTransactions","params":["0x1",["trace"]],"id":1}`, nil, nil)
+		assert.Equal(t, http.StatusOK, statusCode, body)
+		assert.NotContains(t, body, "timeout")
+	})
+
+	t.Run("HeaderTimeoutLongerThanFailsafeTimeoutsApplies", func(t *testing.T) {
+		defer gock.Off()
+		mockSlow("eth_getLogs")
+
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_getLogs","params":[{"fromBlock":"0x1","toBlock":"0x2"}],"id":1}`, map[string]string{"X-ERPC-Timeout": "2s"}, nil)
+		assert.Equal(t, http.StatusOK, statusCode, body)
+		assert.NotContains(t, body, "timeout")
+	})
+
+	t.Run("OtherMethodsKeepFailsafeTimeouts", func(t *testing.T) {
+		defer gock.Off()
+		mockSlow("eth_getLogs")
+
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_getLogs","params":[{"fromBlock":"0x1","toBlock":"0x2"}],"id":1}`, nil, nil)
+		assert.NotEqual(t, http.StatusOK, statusCode, body)
+		assert.Contains(t, body, "timeout")
+	})
+}
+
+func TestRequestTimeouts_ForMethod(t *testing.T) {
+	logger := zerolog.Nop()
+	newTimeouts := func() *requestTimeouts {
+		return newRequestTimeouts(&logger, &common.ServerConfig{
+			MethodTimeouts: map[string]string{
+				"eth_*":           "2s",
+				"eth_get*":        "3s",
+				"eth_getLogs":     "4s",
+				"trace_*":         "5s",
+				"*_replay*":       "6s",
+				"debug_traceCall": "7s",
+				"debug_trace*":    "8s",
+			},
+		}, 1*time.Second)
+	}
+
+	t.Run("ExactMatchWinsOverPatterns", func(t *testing.T) {
+		assert.Equal(t, 4*time.Second, newTimeouts().ForMethod("eth_getLogs"))
+		assert.Equal(t, 7*time.Second, newTimeouts().ForMethod("debug_traceCall"))
+	})
+
+	t.Run("LongestPatternWins", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			timeouts := newTimeouts()
+			assert.Equal(t, 3*time.Second, timeouts.ForMethod("eth_getBalance"))
+			assert.Equal(t, 2*time.Second, timeouts.ForMethod("eth_call"))
+			assert.Equal(t, 8*time.Second, timeouts.ForMethod("debug_traceTransaction"))
+		}
+	})
+
+	t.Run("SameLengthPatternsAreMatchedAlphabetically", func(t *testing.T) {
+		// "*_replay*" and "trace_*" have the same length, "*_replay*" sorts first
+		for i := 0; i < 20; i++ {
+			assert.Equal(t, 6*time.Second, newTimeouts().ForMethod("trace_replayBlockTransactions"))
+		}
+	})
+
+	t.Run("UnmatchedMethodUsesDefault", func(t *testing.T) {
+		assert.Equal(t, 1*time.Second, newTimeouts().ForMethod("net_version"))
+	})
+
+	t.Run("InvalidHeaderFallsBackToSortedPatterns", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			timeouts := newTimeouts()
+			timeouts.maxOverride = 10 * time.Second
+			assert.Equal(t, 3*time.Second, timeouts.ForRequest("eth_getBalance", "soon"))
+			assert.Equal(t, 6*time.Second, timeouts.ForRequest("trace_replayBlockTransactions", ""))
+			assert.Equal(t, 9*time.Second, timeouts.ForRequest("trace_replayBlockTransactions", "9s"))
+		}
+	})
+}
+
+func TestHttpServer_TimeoutOverrideHeader(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout:         "500ms",
+			MaxTimeoutOverride: "2s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId: 1,
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc1.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 1,
+						},
+						VendorName: "llama",
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	sendRequest, _ := createServerTestFixtures(cfg, t)
+
+	mockSlowTrace := func(delay time.Duration) {
+		gock.New("http://rpc1.localhost").
+			Post("/").
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "trace_replayBlockTransactions")
+			}).
+			Reply(200).
+			Delay(delay).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  []interface{}{},
+			})
+	}
+	traceReq := `{"jsonrpc":"2.0","method":"trace_replayBlockTransactions","params":["0x1",["trace"]],"id":1}`
+
+	t.Run("HeaderExtendsDeadlineUpToMax", func(t *testing.T) {
+		defer gock.Off()
+		mockSlowTrace(1 * time.Second)
+
+		statusCode, body := sendRequest(traceReq, map[string]string{"X-ERPC-Timeout": "1500ms"}, nil)
+		assert.Equal(t, http.StatusOK, statusCode, body)
+		assert.NotContains(t, body, "timeout")
+	})
+
+	t.Run("HeaderBeyondMaxIsClamped", func(t *testing.T) {
+		defer gock.Off()
+		mockSlowTrace(3 * time.Second)
+
+		start := time.Now()
+		statusCode, body := sendRequest(traceReq, map[string]string{"X-ERPC-Timeout": "30s"}, nil)
+		assert.NotEqual(t, http.StatusOK, statusCode, body)
+		assert.Contains(t, body, "timeout")
+		assert.Less(t, time.Since(start), 3*time.Second)
+	})
+
+	t.Run("WithoutHeaderDefaultTimeoutApplies", func(t *testing.T) {
+		defer gock.Off()
+		mockSlowTrace(1 * time.Second)
+
+		statusCode, body := sendRequest(traceReq, nil, nil)
+		assert.NotEqual(t, http.StatusOK, statusCode, body)
+		assert.Contains(t, body, "timeout")
+	})
+}
+
+func TestHttpServer_ServeStaleOnError(t *testing.T) {
+	gock.EnableNetworking()
+	gock.NetworkingFilter(func(req *http.Request) bool {
+		return strings.Split(req.URL.Host, ":")[0] == "localhost"
+	})
+	defer gock.Off()
+
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "5s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				CachePolicies: []*common.CachePolicyConfig{
+					{Method: "eth_getBlockByNumber", TTL: "100ms", AllowUnfinalized: true},
+				},
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId: 1,
+						},
+						ServeStaleOnError: &common.ServeStaleOnErrorConfig{
+							Methods: []string{"eth_getBlock*"},
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Id:         "rpc1",
+						Type:       common.UpstreamTypeEvm,
+						Endpoint:   "http://rpc1.localhost",
+						Evm:        &common.EvmUpstreamConfig{ChainId: 1},
+						VendorName: "llama",
+						JsonRpc:    &common.JsonRpcUpstreamConfig{SupportsBatch: &common.FALSE},
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache, err := NewEvmJsonRpcCache(ctx, &logger, &common.ConnectorConfig{
+		Driver: "memory",
+		Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+	})
+	require.NoError(t, err)
+	erpcInstance, err := NewERPC(ctx, &logger, cache, cfg)
+	require.NoError(t, err)
+	httpServer, err := NewHttpServer(ctx, &logger, cfg.Server, erpcInstance)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go httpServer.server.Serve(listener) // nolint:errcheck
+	baseURL := fmt.Sprintf("http://localhost:%d/test_project/evm/1", listener.Addr().(*net.TCPAddr).Port)
+
+	send := func(t *testing.T, body string) (*http.Response, string) {
+		t.Helper()
+		resp, err := (&http.Client{Timeout: 10 * time.Second}).Post(baseURL, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(respBody)
+	}
+	mockBlock := func(status int, body string) {
+		gock.New("http://rpc1.localhost").
+			Post("").
+			Persist().
+			Filter(func(request *http.Request) bool {
+				b := safeReadBody(request)
+				return strings.Contains(b, "eth_getBlockByNumber") && strings.Contains(b, `"0x10"`)
+			}).
+			Reply(status).
+			BodyString(body)
+	}
+
+	blockReq := `{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["0x10",false]}`
+
+	mockBlock(200, `{"jsonrpc":"2.0","id":1,"result":{"number":"0x10","hash":"0xabc"}}`)
+	resp, body := send(t, blockReq)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Empty(t, resp.Header.Get("X-ERPC-Stale"))
+
+	// Let the cache entry be stored and then expire
+	time.Sleep(300 * time.Millisecond)
+	gock.Flush()
+	mockBlock(500, `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"internal error"}}`)
+
+	t.Run("ServesExpiredEntryWhenUpstreamsFail", func(t *testing.T) {
+		resp, body := send(t, blockReq)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, body)
+		assert.Equal(t, "true", resp.Header.Get("X-ERPC-Stale"))
+		assert.Equal(t, "HIT", resp.Header.Get("X-ERPC-Cache"))
+		assert.Contains(t, body, `"hash":"0xabc"`)
+	})
+
+	t.Run("IneligibleMethodReturnsError", func(t *testing.T) {
+		gock.New("http://rpc1.localhost").
+			Post("").
+			Persist().
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getBalance")
+			}).
+			Reply(500).
+			BodyString(`{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"internal error"}}`)
+
+		resp, _ := send(t, `{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x1234","0x10"]}`)
+		assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("X-ERPC-Stale"))
+	})
+}
+
+func TestHttpServer_ExplainCacheKey(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "5s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id:    "test_project",
+				Admin: &common.AdminConfig{},
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId: 1,
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Id:         "rpc1",
+						Type:       common.UpstreamTypeEvm,
+						Endpoint:   "http://rpc1.localhost",
+						Evm:        &common.EvmUpstreamConfig{ChainId: 1},
+						VendorName: "llama",
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache, err := NewEvmJsonRpcCache(ctx, &logger, &common.ConnectorConfig{
+		Driver: "memory",
+		Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+	})
+	require.NoError(t, err)
+	erpcInstance, err := NewERPC(ctx, &logger, cache, cfg)
+	require.NoError(t, err)
+	httpServer, err := NewHttpServer(ctx, &logger, cfg.Server, erpcInstance)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go httpServer.server.Serve(listener) // nolint:errcheck
+	adminURL := fmt.Sprintf("http://localhost:%d/test_project/admin", listener.Addr().(*net.TCPAddr).Port)
+
+	explain := func(t *testing.T, rpcReq string) map[string]interface{} {
+		t.Helper()
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"erpc_explainCacheKey","params":["evm:1",%s]}`, rpcReq)
+		resp, err := (&http.Client{Timeout: 10 * time.Second}).Post(adminURL, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(respBody))
+
+		var parsed map[string]interface{}
+		require.NoError(t, sonic.Unmarshal(respBody, &parsed))
+		result, ok := parsed["result"].(map[string]interface{})
+		require.True(t, ok, "unexpected response: %s", respBody)
+		return result
+	}
+
+	t.Run("GetLogsWithLatestRangeIsNotCacheable", func(t *testing.T) {
+		rpcReq := `{"jsonrpc":"2.0","id":7,"method":"eth_getLogs","params":[{"fromBlock":"0x10","toBlock":"latest","address":"0x1234"}]}`
+		hash, err := common.NewNormalizedRequest([]byte(rpcReq)).CacheHash()
+		require.NoError(t, err)
+
+		result := explain(t, rpcReq)
+		assert.Equal(t, hash, result["cacheHash"])
+		assert.Equal(t, string(common.TTLClassFinalized), result["ttlClass"])
+		assert.Equal(t, false, result["cacheable"])
+		assert.Contains(t, result["reason"], "no block reference")
+	})
+
+	t.Run("ImmutableMethodIsCacheable", func(t *testing.T) {
+		result := explain(t, `{"jsonrpc":"2.0","id":7,"method":"eth_chainId","params":[]}`)
+		assert.Equal(t, string(common.TTLClassImmutable), result["ttlClass"])
+		assert.Equal(t, true, result["cacheable"])
+		assert.Nil(t, result["reason"])
+		assert.Equal(t, "evm:1:immutable", result["groupKey"])
+	})
+}
+
+func TestHttpServer_CORS(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "5s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				CORS: &common.CORSConfig{
+					AllowedOrigins:   []string{"https://*.example.com"},
+					AllowedMethods:   []string{"POST", "OPTIONS"},
+					AllowedHeaders:   []string{"Content-Type"},
+					AllowCredentials: true,
+					MaxAge:           600,
+				},
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId: 1,
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc1.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 1,
+						},
+						VendorName: "llama",
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	sendRequest, baseURL := createServerTestFixtures(cfg, t)
+
+	t.Run("PreflightFromAllowedOrigin", func(t *testing.T) {
+		req, err := http.NewRequest("OPTIONS", baseURL+"/test_project/evm/1", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "POST, OPTIONS", resp.Header.Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "Content-Type", resp.Header.Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("PreflightFromDisallowedOriginGetsNoCorsHeaders", func(t *testing.T) {
+		req, err := http.NewRequest("OPTIONS", baseURL+"/test_project/evm/1", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", "https://evil.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("RequestFromDisallowedOriginIsRejected", func(t *testing.T) {
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_chainId","params":[],"id":1}`, map[string]string{
+			"Origin": "https://evil.com",
+		}, nil)
+
+		assert.Equal(t, http.StatusForbidden, statusCode)
+		assert.Contains(t, body, "disallowed origin")
+	})
+
+	t.Run("RequestFromAllowedOriginIsServed", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://rpc1.localhost").
+			Post("/").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  "0x1",
+			})
+
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","latest"],"id":1}`, map[string]string{
+			"Origin": "https://app.example.com",
+		}, nil)
+
+		assert.Equal(t, http.StatusOK, statusCode, body)
+		assert.Contains(t, body, `"result":"0x1"`)
+	})
+}
+
+func TestHttpServer_RequireJsonContentType(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout:             "5s",
+			RequireJsonContentType: true,
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId: 1,
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc1.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 1,
+						},
+						VendorName: "llama",
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	sendRequest, _ := createServerTestFixtures(cfg, t)
+
+	t.Run("RejectsNonJsonContentType", func(t *testing.T) {
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","latest"],"id":1}`, map[string]string{
+			"Content-Type": "text/plain",
+		}, nil)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, statusCode)
+		assert.Contains(t, body, common.ErrCodeInvalidContentType)
+	})
+
+	t.Run("AcceptsJsonContentTypeWithCharset", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://rpc1.localhost").
+			Post("/").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  "0x1",
+			})
+
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","latest"],"id":1}`, map[string]string{
+			"Content-Type": "application/json; charset=utf-8",
+		}, nil)
+
+		assert.Equal(t, http.StatusOK, statusCode, body)
+	})
+}
+
+func TestHttpServer_StrictJsonParsing(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout:        "5s",
+			StrictJsonParsing: true,
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId: 1,
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc1.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 1,
+						},
+						VendorName: "llama",
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	sendRequest, _ := createServerTestFixtures(cfg, t)
+
+	t.Run("RejectsDuplicateKeysInParams", func(t *testing.T) {
+		defer gock.Off()
+
+		upstreamMock := gock.New("http://rpc1.localhost").
+			Post("/").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  "0x1",
+			})
+
+		_, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_call","params":[{"to":"0xa","to":"0xb","data":"0x01"},"latest"],"id":1}`, nil, nil)
+
+		assert.Contains(t, body, common.ErrCodeInvalidRequest)
+		assert.Contains(t, body, `duplicate key \"to\" at params.0`)
+		assert.False(t, upstreamMock.Mock.Done(), "ambiguous request must not reach the upstream")
+	})
+
+	t.Run("AcceptsRequestsWithoutDuplicateKeys", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://rpc1.localhost").
+			Post("/").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  "0x1",
+			})
+
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_call","params":[{"to":"0xa","data":"0x01"},"latest"],"id":1}`, nil, nil)
+
+		assert.Equal(t, http.StatusOK, statusCode, body)
+		assert.Contains(t, body, `"result":"0x1"`)
+	})
+}
+
+func TestHttpServer_ValidateRequestParams(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "5s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId:               1,
+							ValidateRequestParams: true,
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc1.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 1,
+						},
+						VendorName: "llama",
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	sendRequest, _ := createServerTestFixtures(cfg, t)
+
+	// No upstream mocks are registered, so only rejected requests can get an invalid params error
+	t.Run("MalformedAddressIsRejected", func(t *testing.T) {
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","latest"],"id":1}`, nil, nil)
+
+		assert.Equal(t, http.StatusBadRequest, statusCode, body)
+		assert.Contains(t, body, `"code":-32602`)
+		assert.Contains(t, body, "20-byte hex address")
+	})
+
+	t.Run("InvalidBlockTagIsRejected", func(t *testing.T) {
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5","newest"],"id":1}`, nil, nil)
+
+		assert.Equal(t, http.StatusBadRequest, statusCode, body)
+		assert.Contains(t, body, `"code":-32602`)
+		assert.Contains(t, body, "hex block number or one of latest")
+	})
+
+	t.Run("ValidParamsAreForwarded", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://rpc1.localhost").
+			Post("/").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  "0x1",
+			})
+
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5","latest"],"id":1}`, nil, nil)
+
+		assert.Equal(t, http.StatusOK, statusCode, body)
+		assert.Contains(t, body, `"result":"0x1"`)
+	})
+}
+
+func TestHttpServer_DefaultBlockTag(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "5s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId:         1,
+							DefaultBlockTag: "pending",
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc1.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 1,
+						},
+						VendorName: "llama",
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	sendRequest, _ := createServerTestFixtures(cfg, t)
+
+	// Object keys are not sent in a stable order, so the call is told apart by its data and the block param by what follows it
+	expectParams := func(data, blockParam string) {
+		gock.New("http://rpc1.localhost").
+			Post("/").
+			Filter(func(request *http.Request) bool {
+				body := safeReadBody(request)
+				return strings.Contains(body, "eth_call") && strings.Contains(body, `"data":"`+data+`"`) && strings.Contains(body, `},`+blockParam+`]`)
+			}).
+			Reply(200).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  "0x1",
+			})
+	}
+
+	t.Run("OmittedBlockParamIsSentAsConfiguredTag", func(t *testing.T) {
+		defer gock.Off()
+		expectParams("0x01", `"pending"`)
+
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_call","params":[{"to":"0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5","data":"0x01"}],"id":1}`, nil, nil)
+
+		assert.Equal(t, http.StatusOK, statusCode, body)
+		assert.Contains(t, body, `"result":"0x1"`)
+		assert.True(t, gock.IsDone(), "upstream must receive the default block tag")
+	})
+
+	t.Run("NullBlockParamIsSentAsConfiguredTag", func(t *testing.T) {
+		defer gock.Off()
+		expectParams("0x02", `"pending"`)
+
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_call","params":[{"to":"0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5","data":"0x02"},null],"id":1}`, nil, nil)
+
+		assert.Equal(t, http.StatusOK, statusCode, body)
+		assert.True(t, gock.IsDone(), "upstream must receive the default block tag")
+	})
+
+	t.Run("ExplicitBlockParamIsKept", func(t *testing.T) {
+		defer gock.Off()
+		expectParams("0x03", `"latest"`)
+
+		statusCode, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_call","params":[{"to":"0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5","data":"0x03"},"latest"],"id":1}`, nil, nil)
+
+		assert.Equal(t, http.StatusOK, statusCode, body)
+		assert.True(t, gock.IsDone(), "upstream must receive the explicit block tag")
+	})
+}
+
+func TestHttpServer_ResponseCompression(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "5s",
+			ResponseCompression: &common.ResponseCompressionConfig{
+				MinSize: 512,
+			},
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId: 1,
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc1.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 1,
+						},
+						VendorName: "llama",
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	_, baseURL := createServerTestFixtures(cfg, t)
+
+	logs := make([]map[string]interface{}, 50)
+	for i := range logs {
+		logs[i] = map[string]interface{}{
+			"address":         "0x1f9840a85d5af5bf1d1762f925bdaddc4201f984",
+			"blockNumber":     fmt.Sprintf("0x%x", 1000+i),
+			"transactionHash": fmt.Sprintf("0x%064x", i),
+			"data":            "0x",
+			"topics":          []string{"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"},
+		}
+	}
+
+	// Transport compression is disabled so the client neither advertises gzip on its own nor decodes responses
+	send := func(t *testing.T, body string, acceptEncoding string) (*http.Response, []byte) {
+		req, err := http.NewRequest("POST", baseURL+"/test_project/evm/1", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		client := &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{DisableCompression: true},
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, respBody
+	}
+
+	mockGetLogs := func() {
+		gock.New("http://rpc1.localhost").
+			Post("/").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  logs,
+			})
+	}
+	getLogsBody := `{"jsonrpc":"2.0","method":"eth_getLogs","params":[{"fromBlock":"0x3e8","toBlock":"0x419"}],"id":1}`
+
+	t.Run("LargeResponseIsGzippedWhenAccepted", func(t *testing.T) {
+		defer gock.Off()
+		mockGetLogs()
+
+		resp, body := send(t, getLogsBody, "gzip, deflate")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+		assert.Contains(t, resp.Header.Get("Vary"), "Accept-Encoding")
+
+		plain, err := fasthttp.AppendGunzipBytes(nil, body)
+		require.NoError(t, err)
+		assert.Contains(t, string(plain), "0x1f9840a85d5af5bf1d1762f925bdaddc4201f984")
+		assert.Less(t, len(body), len(plain))
+	})
+
+	t.Run("LargeResponseIsPlainWithoutAcceptEncoding", func(t *testing.T) {
+		defer gock.Off()
+		mockGetLogs()
+
+		resp, body := send(t, getLogsBody, "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+		assert.Contains(t, string(body), "0x1f9840a85d5af5bf1d1762f925bdaddc4201f984")
+	})
+
+	t.Run("SmallResponseIsNotCompressed", func(t *testing.T) {
+		defer gock.Off()
+		gock.New("http://rpc1.localhost").
+			Post("/").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  "0x1",
+			})
+
+		resp, body := send(t, `{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","latest"],"id":1}`, "gzip")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+		assert.Contains(t, string(body), `"result":"0x1"`)
+	})
+}
+
+func TestHttpServer_WaitForReceipt(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "5s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId:   1,
+							BlockTime: "100ms",
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc1.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 1,
+						},
+						VendorName: "llama",
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	sendRequest, _ := createServerTestFixtures(cfg, t)
+
+	receiptRequest := `{"jsonrpc":"2.0","method":"eth_getTransactionReceipt","params":["0xe014f359cb3988f9944cd8003aac58812730383041993fdf762efcee21172d15"],"id":1}`
+	mockReceipt := func(result interface{}) *gock.Request {
+		r := gock.New("http://rpc1.localhost").
+			Post("/").
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getTransactionReceipt")
+			})
+		r.Reply(200).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  result,
+			})
+		return r
+	}
+
+	t.Run("ReceiptAppearingOnThirdPollIsReturned", func(t *testing.T) {
+		defer gock.Off()
+		mockReceipt(nil)
+		mockReceipt(nil)
+		mockReceipt(map[string]interface{}{
+			"transactionHash": "0xe014f359cb3988f9944cd8003aac58812730383041993fdf762efcee21172d15",
+			"blockNumber":     "0x10",
+			"status":          "0x1",
+		})
+
+		start := time.Now()
+		statusCode, body := sendRequest(receiptRequest, map[string]string{"X-ERPC-Wait-For-Receipt": "3s"}, nil)
+
+		assert.Equal(t, http.StatusOK, statusCode, body)
+		assert.Contains(t, body, `"blockNumber":"0x10"`)
+		assert.True(t, gock.IsDone(), "receipt must be polled exactly three times")
+		assert.Less(t, time.Since(start), 2*time.Second, "must return as soon as receipt is available")
+	})
+
+	t.Run("NullIsReturnedWhenWaitIsOver", func(t *testing.T) {
+		defer gock.Off()
+		mockReceipt(nil).Persist()
+
+		start := time.Now()
+		statusCode, body := sendRequest(receiptRequest, map[string]string{"X-ERPC-Wait-For-Receipt": "350ms"}, nil)
+
+		assert.Equal(t, http.StatusOK, statusCode, body)
+		assert.Contains(t, body, `"result":null`)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+
+	t.Run("WithoutDirectiveReceiptIsNotPolled", func(t *testing.T) {
+		defer gock.Off()
+		mockReceipt(nil)
+		mockReceipt(map[string]interface{}{"blockNumber": "0x10"})
+
+		statusCode, body := sendRequest(receiptRequest, map[string]string{"X-ERPC-Retry-Empty": "false"}, nil)
+
+		assert.Equal(t, http.StatusOK, statusCode, body)
+		assert.Contains(t, body, `"result":null`)
+	})
+}
+
+func TestHttpServer_MsgpackResponse(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "5s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId: 1,
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc1.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 1,
+						},
+						VendorName: "llama",
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	_, baseURL := createServerTestFixtures(cfg, t)
+
+	send := func(t *testing.T, accept string) (*http.Response, []byte) {
+		gock.New("http://rpc1.localhost").
+			Post("/").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  "0x1",
+			})
+
+		req, err := http.NewRequest("POST", baseURL+"/test_project/evm/1", strings.NewReader(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","latest"],"id":1}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, respBody
+	}
+
+	t.Run("MsgpackIsReturnedWhenAccepted", func(t *testing.T) {
+		defer gock.Off()
+
+		resp, body := send(t, "application/msgpack")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/msgpack", resp.Header.Get("Content-Type"))
+
+		expected, err := common.JsonToMsgpack([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+		require.NoError(t, err)
+		assert.Equal(t, expected, body)
+	})
+
+	t.Run("JsonIsReturnedByDefault", func(t *testing.T) {
+		defer gock.Off()
+
+		resp, body := send(t, "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.Contains(t, string(body), `"result":"0x1"`)
+	})
+
+	t.Run("JsonIsReturnedForOtherAcceptHeaders", func(t *testing.T) {
+		defer gock.Off()
+
+		resp, body := send(t, "application/json, */*;q=0.8")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.Contains(t, string(body), `"result":"0x1"`)
+	})
+}
+
+func TestHttpServer_HealthCheck(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "5s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Id:         "rpc1",
+						Type:       common.UpstreamTypeEvm,
+						Endpoint:   "http://rpc1.localhost",
+						Evm:        &common.EvmUpstreamConfig{ChainId: 1},
+						VendorName: "llama",
+					},
+					{
+						Id:         "rpc2",
+						Type:       common.UpstreamTypeEvm,
+						Endpoint:   "http://rpc2.localhost",
+						Evm:        &common.EvmUpstreamConfig{ChainId: 1},
+						VendorName: "llama",
+					},
+					{
+						Id:         "rpc3",
+						Type:       common.UpstreamTypeEvm,
+						Endpoint:   "http://rpc3.localhost",
+						Evm:        &common.EvmUpstreamConfig{ChainId: 137},
+						VendorName: "llama",
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	erpcInstance, err := NewERPC(ctx, &logger, nil, cfg)
+	require.NoError(t, err)
+	httpServer, err := NewHttpServer(ctx, &logger, cfg.Server, erpcInstance)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go httpServer.server.Serve(listener) // nolint:errcheck
+	baseURL := fmt.Sprintf("http://localhost:%d", listener.Addr().(*net.TCPAddr).Port)
+
+	get := func(t *testing.T, query string) (int, string) {
+		resp, err := http.Get(baseURL + "/healthz" + query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("ServerIsUp", func(t *testing.T) {
+		status, body := get(t, "")
+		assert.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"status":"OK"}`, body)
+	})
+
+	t.Run("NetworkWithHealthyUpstreams", func(t *testing.T) {
+		status, _ := get(t, "?network=evm:1")
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("NetworkWithAllUpstreamsDown", func(t *testing.T) {
+		prj, err := erpcInstance.GetProject("test_project")
+		require.NoError(t, err)
+		require.NoError(t, prj.upstreamsRegistry.QuarantineUpstream("rpc3"))
+		defer prj.upstreamsRegistry.UnquarantineUpstream("rpc3") // nolint:errcheck
+
+		status, body := get(t, "?network=evm:137")
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Contains(t, body, "NO_HEALTHY_UPSTREAMS")
+
+		// Other networks are not affected
+		status, _ = get(t, "?network=evm:1")
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("NetworkWithFailingHealthChecks", func(t *testing.T) {
+		gock.EnableNetworking()
+		gock.NetworkingFilter(func(req *http.Request) bool {
+			return strings.Split(req.URL.Host, ":")[0] == "localhost"
+		})
+		defer gock.Off()
+
+		prj, err := erpcInstance.GetProject("test_project")
+		require.NoError(t, err)
+		ups, ok := prj.upstreamsRegistry.GetUpstream("rpc3")
+		require.True(t, ok)
+
+		gock.New("http://rpc3.localhost").
+			Post("").
+			Reply(503).
+			BodyString(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"service unavailable"}}`)
+		assert.Error(t, ups.HealthCheck(context.Background()))
+
+		status, body := get(t, "?network=evm:137")
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Contains(t, body, "NO_HEALTHY_UPSTREAMS")
+
+		// A later successful check makes the upstream healthy again
+		gock.New("http://rpc3.localhost").
+			Post("").
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":"0x89"}`)
+		assert.NoError(t, ups.HealthCheck(context.Background()))
+
+		status, _ = get(t, "?network=evm:137")
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("NetworkWithoutUpstreams", func(t *testing.T) {
+		status, _ := get(t, "?network=evm:10")
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+	})
+
+	t.Run("InvalidNetworkId", func(t *testing.T) {
+		status, _ := get(t, "?network=evm")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("VerboseEnumeratesNetworks", func(t *testing.T) {
+		prj, err := erpcInstance.GetProject("test_project")
+		require.NoError(t, err)
+		require.NoError(t, prj.upstreamsRegistry.QuarantineUpstream("rpc2"))
+		defer prj.upstreamsRegistry.UnquarantineUpstream("rpc2") // nolint:errcheck
+
+		status, body := get(t, "?verbose=1")
+		assert.Equal(t, http.StatusOK, status)
+
+		var result struct {
+			Status   string `json:"status"`
+			Networks map[string]struct {
+				Healthy int `json:"healthy"`
+				Total   int `json:"total"`
+			} `json:"networks"`
+		}
+		require.NoError(t, sonic.UnmarshalString(body, &result))
+		assert.Equal(t, "OK", result.Status)
+		assert.Equal(t, 1, result.Networks["evm:1"].Healthy)
+		assert.Equal(t, 2, result.Networks["evm:1"].Total)
+		assert.Equal(t, 1, result.Networks["evm:137"].Healthy)
+		assert.Equal(t, 1, result.Networks["evm:137"].Total)
+	})
+}
+
+func TestHttpServer_PartialGetLogs(t *testing.T) {
+	gock.EnableNetworking()
+	gock.NetworkingFilter(func(req *http.Request) bool {
+		return strings.Split(req.URL.Host, ":")[0] == "localhost"
+	})
+	defer gock.Off()
+
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "5s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm:          &common.EvmNetworkConfig{ChainId: 1},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Id:         "rpc1",
+						Type:       common.UpstreamTypeEvm,
+						Endpoint:   "http://rpc1.localhost",
+						VendorName: "llama",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId:              1,
+							GetLogsSplitEnabled:  true,
+							GetLogsMaxBlockRange: 10,
+						},
+						JsonRpc: &common.JsonRpcUpstreamConfig{SupportsBatch: &common.FALSE},
+						Failsafe: &common.FailsafeConfig{
+							Timeout: &common.TimeoutPolicyConfig{Duration: "200ms"},
+						},
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	erpcInstance, err := NewERPC(ctx, &logger, nil, cfg)
+	require.NoError(t, err)
+	httpServer, err := NewHttpServer(ctx, &logger, cfg.Server, erpcInstance)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go httpServer.server.Serve(listener) // nolint:errcheck
+	baseURL := fmt.Sprintf("http://localhost:%d/test_project/evm/1", listener.Addr().(*net.TCPAddr).Port)
+
+	mockLogs := func(fromBlock string, delay time.Duration) {
+		gock.New("http://rpc1.localhost").
+			Post("").
+			Persist().
+			Filter(func(request *http.Request) bool {
+				b := safeReadBody(request)
+				return strings.Contains(b, "eth_getLogs") && strings.Contains(b, `"fromBlock":"`+fromBlock+`"`)
+			}).
+			Reply(200).
+			Delay(delay).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":[{"logIndex":"0x0","blockNumber":"` + fromBlock + `"}]}`)
+	}
+	mockLogs("0x1", 0)
+	mockLogs("0xb", time.Second)
+	mockLogs("0x15", 0)
+
+	send := func(t *testing.T, headers map[string]string) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequest("POST", baseURL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"fromBlock":"0x1","toBlock":"0x1e"}]}`))
+		require.NoError(t, err)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(respBody)
+	}
+
+	t.Run("TimedOutSubRangeIsFlaggedAsMissing", func(t *testing.T) {
+		resp, body := send(t, map[string]string{"X-ERPC-Allow-Partial-Logs": "true"})
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode, body)
+		assert.Equal(t, "true", resp.Header.Get("X-ERPC-Partial"))
+		assert.Equal(t, "0xb-0x14", resp.Header.Get("X-ERPC-Missing-Ranges"))
+		assert.Contains(t, body, `"blockNumber":"0x1"`)
+		assert.Contains(t, body, `"blockNumber":"0x15"`)
+		assert.NotContains(t, body, `"blockNumber":"0xb"`)
+	})
+
+	t.Run("WholeRequestFailsWithoutDirective", func(t *testing.T) {
+		resp, body := send(t, nil)
+
+		assert.NotEqual(t, http.StatusOK, resp.StatusCode, body)
+		assert.Empty(t, resp.Header.Get("X-ERPC-Partial"))
+	})
+}
+
+func TestHttpServer_TraceHeader(t *testing.T) {
+	newConfig := func(traceHeader bool) *common.Config {
+		return &common.Config{
+			Server: &common.ServerConfig{
+				MaxTimeout:  "5s",
+				TraceHeader: traceHeader,
+			},
+			Projects: []*common.ProjectConfig{
+				{
+					Id: "test_project",
+					Networks: []*common.NetworkConfig{
+						{
+							Architecture: common.ArchitectureEvm,
+							Evm: &common.EvmNetworkConfig{
+								ChainId: 1,
+							},
+						},
+					},
+					Upstreams: []*common.UpstreamConfig{
+						{
+							Id:       "rpc1",
+							Type:     common.UpstreamTypeEvm,
+							Endpoint: "http://rpc1.localhost",
+							Evm: &common.EvmUpstreamConfig{
+								ChainId: 1,
+							},
+							Failsafe: &common.FailsafeConfig{},
+						},
+						{
+							Id:       "rpc2",
+							Type:     common.UpstreamTypeEvm,
+							Endpoint: "http://rpc2.localhost",
+							Evm: &common.EvmUpstreamConfig{
+								ChainId: 1,
+							},
+							Failsafe: &common.FailsafeConfig{},
+						},
+					},
+				},
+			},
+			RateLimiters: &common.RateLimiterConfig{},
+		}
+	}
+
+	send := func(t *testing.T, baseURL string, traceHeader string) *http.Response {
+		gock.New("http://rpc1.localhost").
+			Post("/").
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getBalance")
+			}).
+			Reply(500).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"error":   map[string]interface{}{"code": -32603, "message": "internal error"},
+			})
+		gock.New("http://rpc2.localhost").
+			Post("/").
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getBalance")
+			}).
+			Reply(200).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  "0x2222",
+			})
+
+		req, err := http.NewRequest("POST", baseURL+"/test_project/evm/1", strings.NewReader(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","latest"],"id":1}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if traceHeader != "" {
+			req.Header.Set("X-ERPC-Trace", traceHeader)
+		}
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), "0x2222")
+		return resp
+	}
+
+	t.Run("ListsFailedOverAttempts", func(t *testing.T) {
+		defer gock.Off()
+		_, baseURL := createServerTestFixtures(newConfig(true), t)
+
+		resp := send(t, baseURL, "true")
+
+		var attempts []common.UpstreamAttempt
+		require.NoError(t, sonic.Unmarshal([]byte(resp.Header.Get("X-ERPC-Trace")), &attempts), resp.Header.Get("X-ERPC-Trace"))
+		if assert.Len(t, attempts, 2) {
+			assert.Equal(t, "rpc1", attempts[0].Upstream)
+			assert.Equal(t, "error", attempts[0].Outcome)
+			assert.Contains(t, attempts[0].Error, "ErrEndpointServerSideException")
+			assert.Equal(t, "rpc2", attempts[1].Upstream)
+			assert.Equal(t, "success", attempts[1].Outcome)
+			assert.Empty(t, attempts[1].Error)
+		}
+	})
+
+	t.Run("OmittedWhenNotRequested", func(t *testing.T) {
+		defer gock.Off()
+		_, baseURL := createServerTestFixtures(newConfig(true), t)
+
+		resp := send(t, baseURL, "")
+		assert.Empty(t, resp.Header.Get("X-ERPC-Trace"))
+	})
+
+	t.Run("OmittedWhenDisabled", func(t *testing.T) {
+		defer gock.Off()
+		_, baseURL := createServerTestFixtures(newConfig(false), t)
+
+		resp := send(t, baseURL, "true")
+		assert.Empty(t, resp.Header.Get("X-ERPC-Trace"))
+	})
+}
+
+func TestHttpServer_PathTemplates(t *testing.T) {
+	newProject := func(id string, endpoint string) *common.ProjectConfig {
+		return &common.ProjectConfig{
+			Id: id,
+			Networks: []*common.NetworkConfig{
+				{
+					Architecture: common.ArchitectureEvm,
+					Evm: &common.EvmNetworkConfig{
+						ChainId: 1,
+					},
+				},
+			},
+			Upstreams: []*common.UpstreamConfig{
+				{
+					Id:       id + "-rpc",
+					Type:     common.UpstreamTypeEvm,
+					Endpoint: endpoint,
+					Evm: &common.EvmUpstreamConfig{
+						ChainId: 1,
+					},
+				},
+			},
+		}
+	}
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout:    "5s",
+			PathTemplates: []string{"/v1/{project}/{network}", "/rpc/{architecture}/{chainId}/{project}"},
+		},
+		Projects: []*common.ProjectConfig{
+			newProject("proj", "http://rpc1.localhost"),
+			newProject("test_project", "http://rpc2.localhost"),
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	sendRequest, baseURL := createServerTestFixtures(cfg, t)
+
+	post := func(t *testing.T, path string) (int, string) {
+		req, err := http.NewRequest("POST", baseURL+path, strings.NewReader(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","latest"],"id":1}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+	mockBalance := func(host string, result string) {
+		gock.New(host).
+			Post("/").
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getBalance")
+			}).
+			Reply(200).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  result,
+			})
+	}
+
+	t.Run("RoutesToProjectAndNetworkOfTheTemplate", func(t *testing.T) {
+		defer gock.Off()
+		mockBalance("http://rpc1.localhost", "0x1111")
+		mockBalance("http://rpc2.localhost", "0x2222")
+
+		status, body := post(t, "/v1/proj/evm:1")
+		assert.Equal(t, http.StatusOK, status, body)
+		assert.Contains(t, body, `"result":"0x1111"`)
+
+		status, body = post(t, "/rpc/evm/1/test_project")
+		assert.Equal(t, http.StatusOK, status, body)
+		assert.Contains(t, body, `"result":"0x2222"`)
+	})
+
+	t.Run("UnknownProjectIsNotFound", func(t *testing.T) {
+		status, body := post(t, "/v1/unknown/evm:1")
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Contains(t, body, `"jsonrpc":"2.0"`)
+		assert.Contains(t, body, "ErrProjectNotFound")
+	})
+
+	t.Run("UnknownNetworkIsNotFound", func(t *testing.T) {
+		status, body := post(t, "/v1/proj/evm:999")
+		assert.Equal(t, http.StatusNotFound, status, body)
+		assert.Contains(t, body, `"jsonrpc":"2.0"`)
+		assert.Contains(t, body, "ErrNetworkNotFound")
+	})
+
+	t.Run("MalformedNetworkIsNotFound", func(t *testing.T) {
+		status, body := post(t, "/v1/proj/mainnet")
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Contains(t, body, `"jsonrpc":"2.0"`)
+		assert.Contains(t, body, "ErrNetworkNotFound")
+	})
+
+	t.Run("DefaultPathsStillWork", func(t *testing.T) {
+		defer gock.Off()
+		mockBalance("http://rpc2.localhost", "0x2222")
+
+		status, body := sendRequest(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","latest"],"id":1}`, nil, nil)
+		assert.Equal(t, http.StatusOK, status, body)
+		assert.Contains(t, body, `"result":"0x2222"`)
+	})
+}
+
+func TestPathTemplate(t *testing.T) {
+	t.Run("InvalidTemplatesAreRejected", func(t *testing.T) {
+		for _, raw := range []string{
+			"v1/{project}/{network}",
+			"/v1/{network}",
+			"/v1/{project}",
+			"/v1/{project}/{network}/{chainId}",
+			"/v1/{project}/{architecture}",
+			"/v1/{project}/{net}",
+			"/v1/p-{project}/{network}",
+			"/v1/{project}/{project}/{network}",
+		} {
+			_, err := newPathTemplate(raw)
+			assert.Error(t, err, raw)
+		}
+	})
+
+	t.Run("InvalidTemplateFailsServerCreation", func(t *testing.T) {
+		logger := zerolog.New(zerolog.NewConsoleWriter())
+		_, err := NewHttpServer(context.Background(), &logger, &common.ServerConfig{
+			MaxTimeout:    "5s",
+			PathTemplates: []string{"/v1/{project}/{network}", "/v1/{network}"},
+		}, nil)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeInvalidConfig), "unexpected error: %v", err)
+	})
+
+	t.Run("MatchesOnlyPathsOfTheSameShape", func(t *testing.T) {
+		tp, err := newPathTemplate("/v1/{project}/{network}")
+		require.NoError(t, err)
+
+		target, ok := tp.match("/v1/main/evm:42161")
+		if assert.True(t, ok) {
+			assert.Equal(t, "main", target.projectId)
+			assert.Equal(t, "evm", target.architecture)
+			assert.Equal(t, "42161", target.chainId)
+		}
+		_, ok = tp.match("/v1/main/evm:42161/")
+		assert.True(t, ok)
+
+		for _, path := range []string{"/v2/main/evm:1", "/v1/main", "/v1/main/evm:1/extra", "/v1//evm:1", "/main/evm/1"} {
+			_, ok := tp.match(path)
+			assert.False(t, ok, path)
+		}
+	})
+}
+
+func TestHttpServer_ExcludeUpstream(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "5s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Auth: &common.AuthConfig{
+					Strategies: []*common.AuthStrategyConfig{
+						{Type: common.AuthTypeSecret, Secret: &common.SecretStrategyConfig{Value: "consumer-secret"}},
+					},
+				},
+				Admin: &common.AdminConfig{
+					Auth: &common.AuthConfig{
+						Strategies: []*common.AuthStrategyConfig{
+							{Type: common.AuthTypeSecret, Secret: &common.SecretStrategyConfig{Value: "admin-secret"}},
+						},
+					},
+				},
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId: 1,
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Id:       "rpc1",
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc1.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 1,
+						},
+					},
+					{
+						Id:       "rpc2",
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc2.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 1,
+						},
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	sendRequest, _ := createServerTestFixtures(cfg, t)
+
+	mockBalance := func(host string, result string) {
+		gock.New(host).
+			Post("/").
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getBalance")
+			}).
+			Reply(200).
+			JSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"result":  result,
+			})
+	}
+	body := `{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","latest"],"id":1}`
+
+	t.Run("ExcludedUpstreamIsSkippedAndAnotherIsUsed", func(t *testing.T) {
+		defer gock.Off()
+		mockBalance("http://rpc1.localhost", "0x1111")
+		mockBalance("http://rpc2.localhost", "0x2222")
+
+		statusCode, respBody := sendRequest(body, map[string]string{
+			"X-ERPC-Secret-Token":     "admin-secret",
+			"X-ERPC-Exclude-Upstream": "rpc1",
+		}, nil)
+
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Contains(t, respBody, "0x2222")
+		assert.Equal(t, 1, len(gock.Pending()), "rpc1 must not be called")
+	})
+
+	t.Run("ConsumerCredentialsCannotExcludeUpstreams", func(t *testing.T) {
+		defer gock.Off()
+		mockBalance("http://rpc2.localhost", "0x2222")
+
+		statusCode, respBody := sendRequest(body, map[string]string{
+			"X-ERPC-Secret-Token":     "consumer-secret",
+			"X-ERPC-Exclude-Upstream": "rpc1",
+		}, nil)
+
+		assert.Equal(t, http.StatusUnauthorized, statusCode)
+		assert.Contains(t, respBody, "ErrAuthUnauthorized")
+		assert.Equal(t, 1, len(gock.Pending()), "no upstream must be called")
+	})
+
+	t.Run("ExcludingAllUpstreamsFails", func(t *testing.T) {
+		defer gock.Off()
+
+		statusCode, respBody := sendRequest(body, map[string]string{
+			"X-ERPC-Secret-Token":     "admin-secret",
+			"X-ERPC-Exclude-Upstream": "rpc*",
+		}, nil)
+
+		assert.NotEqual(t, http.StatusOK, statusCode)
+		assert.Contains(t, respBody, "ErrUpstreamNotAllowed")
+	})
+}
+
+func TestHttpServer_RateLimitHeaders(t *testing.T) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "5s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id:              "test_project",
+				RateLimitBudget: "project_budget",
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId: 1,
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Id:       "rpc1",
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc1.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 1,
+						},
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{
+			Budgets: []*common.RateLimitBudgetConfig{
+				{
+					Id: "project_budget",
+					Rules: []*common.RateLimitRuleConfig{
+						{Method: "eth_getBalance", MaxCount: 1, Period: "60s"},
+					},
+				},
+			},
+		},
+	}
+
+	_, baseURL := createServerTestFixtures(cfg, t)
+	defer gock.Off()
+
+	gock.New("http://rpc1.localhost").
+		Post("/").
+		Filter(func(request *http.Request) bool {
+			return strings.Contains(safeReadBody(request), "eth_getBalance")
+		}).
+		Reply(200).
+		JSON(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"result":  "0x1111",
+		})
+
+	send := func() (*http.Response, string) {
+		req, err := http.NewRequest("POST", baseURL+"/test_project/evm/1", strings.NewReader(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x111","latest"],"id":1}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+
+	resp, body := send()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "0x1111")
+	assert.Empty(t, resp.Header.Get("Retry-After"))
+
+	resp, body = send()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Contains(t, body, "ErrProjectRateLimitRuleExceeded")
+	retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	require.NoError(t, err)
+	assert.True(t, retryAfter >= 1 && retryAfter <= 60, "unexpected Retry-After: %d", retryAfter)
+	assert.Equal(t, "1", resp.Header.Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", resp.Header.Get("X-RateLimit-Remaining"))
+	assert.Equal(t, resp.Header.Get("Retry-After"), resp.Header.Get("X-RateLimit-Reset"))
+}
+
+func TestHttpServer_GracefulShutdown(t *testing.T) {
+	gock.Off()
+
+	// Upstream holds eth_getBalance responses until released, so a request can be kept in flight during shutdown
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	upstreamServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		result := `"0x1"`
+		switch {
+		case strings.Contains(string(body), "eth_getBalance"):
+			received <- struct{}{}
+			<-release
+			result = `"0x2a"`
+		case strings.Contains(string(body), "eth_getBlockByNumber"):
+			result = `{"number":"0x10","hash":"0x01"}`
+		case strings.Contains(string(body), "eth_syncing"):
+			result = `false`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":%s}`, result)
+	}))
+	defer upstreamServer.Close()
+
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout:          "10s",
+			ShutdownGracePeriod: "5s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Id:       "rpc1",
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: upstreamServer.URL,
+						Evm:      &common.EvmUpstreamConfig{ChainId: 1},
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	erpcInstance, err := NewERPC(ctx, &logger, nil, cfg)
+	require.NoError(t, err)
+	httpServer, err := NewHttpServer(ctx, &logger, cfg.Server, erpcInstance)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go httpServer.server.Serve(listener) // nolint:errcheck
+	baseURL := fmt.Sprintf("http://%s", listener.Addr().String())
+
+	send := func(body string) (int, string, error) {
+		resp, err := http.Post(baseURL+"/test_project/evm/1", "application/json", strings.NewReader(body))
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(respBody), err
+	}
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		status, body, err := send(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000000001","0x1"]}`)
+		inFlight <- result{status, body, err}
+	}()
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("in-flight request never reached the upstream")
+	}
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- httpServer.Shutdown(&logger)
+	}()
+
+	// New requests are either refused at connection level or rejected by the draining server
+	assert.Eventually(t, func() bool {
+		status, _, err := send(`{"jsonrpc":"2.0","id":2,"method":"eth_chainId","params":[]}`)
+		return err != nil || status == http.StatusServiceUnavailable
+	}, 2*time.Second, 20*time.Millisecond)
+	resp, err := http.Get(baseURL + "/healthz")
+	if err == nil {
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	select {
+	case <-shutdownErr:
+		t.Fatal("shutdown must wait for the in-flight request")
+	default:
+	}
+
+	close(release)
+	res := <-inFlight
+	require.NoError(t, res.err)
+	assert.Equal(t, http.StatusOK, res.status)
+	assert.Contains(t, res.body, `"0x2a"`)
+
+	select {
+	case err := <-shutdownErr:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not complete after the in-flight request")
+	}
+	assert.NoError(t, erpcInstance.CloseUpstreamClients())
+}
+
+func TestHttpServer_InvalidShutdownGracePeriod(t *testing.T) {
+	logger := zerolog.Nop()
+	for _, period := range []string{"soon", "-1s"} {
+		t.Run(period, func(t *testing.T) {
+			_, err := NewHttpServer(context.Background(), &logger, &common.ServerConfig{ShutdownGracePeriod: period}, nil)
+			assert.True(t, common.HasErrorCode(err, common.ErrCodeInvalidConfig), err)
+		})
+	}
 }
@@ -0,0 +1,215 @@
+package erpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/erpc/erpc/auth"
+	"github.com/erpc/erpc/common"
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	sseContentType = "text/event-stream"
+
+	// Comments are sent this often so that disconnected clients are noticed and proxies keep the stream open
+	defaultSseHeartbeatInterval = 15 * time.Second
+	sseWriteTimeout             = 10 * time.Second
+)
+
+// isEventStreamRequest tells if the client asks to stream subscription notifications as server-sent events,
+// e.g. GET /main/evm/1?subscription=newHeads with "Accept: text/event-stream".
+func isEventStreamRequest(fastCtx *fasthttp.RequestCtx) bool {
+	return fastCtx.IsGet() && strings.Contains(string(fastCtx.Request.Header.Peek(fasthttp.HeaderAccept)), sseContentType)
+}
+
+// handleEventStream subscribes the client to newHeads (default) or logs of the network, a logs filter can be passed as
+// json in the "filter" query param. Notifications come from the same poll subscriptions that serve eth_subscribe over
+// http, each client gets its own bounded buffer which drops the oldest notifications when the client falls behind.
+func (s *HttpServer) handleEventStream(
+	mainCtx context.Context,
+	fastCtx *fasthttp.RequestCtx,
+	lg *zerolog.Logger,
+	project *PreparedProject,
+	networkId string,
+	encoder sonic.Encoder,
+	buf *bytes.Buffer,
+) {
+	params, err := eventStreamSubscribeParams(fastCtx.QueryArgs())
+	if err != nil {
+		handleErrorResponse(lg, nil, common.NewErrInvalidRequest(err), fastCtx, encoder, buf)
+		return
+	}
+	jrq, err := common.NewJsonRpcRequest(1, "eth_subscribe", params)
+	if err != nil {
+		handleErrorResponse(lg, nil, err, fastCtx, encoder, buf)
+		return
+	}
+	body, err := sonic.Marshal(jrq)
+	if err != nil {
+		handleErrorResponse(lg, nil, err, fastCtx, encoder, buf)
+		return
+	}
+	nq := common.NewNormalizedRequest(body)
+	nq.ApplyDirectivesFromHttp(&fastCtx.Request.Header, fastCtx.QueryArgs())
+
+	ap, err := auth.NewPayloadFromHttp(project.Config.Id, nq, &fastCtx.Request.Header, fastCtx.QueryArgs())
+	if err != nil {
+		handleErrorResponse(lg, nq, err, fastCtx, encoder, buf)
+		return
+	}
+	if err := project.AuthenticateConsumer(mainCtx, nq, ap); err != nil {
+		handleErrorResponse(lg, nq, err, fastCtx, encoder, buf)
+		return
+	}
+
+	nw, err := project.GetNetwork(networkId)
+	if err != nil {
+		handleErrorResponse(lg, nq, err, fastCtx, encoder, buf)
+		return
+	}
+	ps := nw.pollSubscriptions
+	if ps == nil {
+		handleErrorResponse(lg, nq, common.NewErrInvalidRequest(fmt.Errorf("network %s does not support subscriptions", networkId)), fastCtx, encoder, buf)
+		return
+	}
+
+	sid, err := ps.subscribe(fastCtx.RemoteIP().String(), params)
+	if err != nil {
+		handleErrorResponse(lg, nq, err, fastCtx, encoder, buf)
+		return
+	}
+	ps.mu.Lock()
+	sub := ps.subs[sid]
+	ps.mu.Unlock()
+	if sub == nil {
+		handleErrorResponse(lg, nq, common.NewErrSubscriptionNotFound(sid), fastCtx, encoder, buf)
+		return
+	}
+
+	heartbeat := defaultSseHeartbeatInterval
+	if ps.idleTimeout/2 < heartbeat {
+		// Draining on each heartbeat is what keeps the subscription from being expired as idle
+		heartbeat = ps.idleTimeout / 2
+	}
+
+	fastCtx.SetStatusCode(fasthttp.StatusOK)
+	fastCtx.SetContentType(sseContentType)
+	fastCtx.Response.Header.Set(fasthttp.HeaderCacheControl, "no-cache")
+	fastCtx.Response.Header.Set("X-Accel-Buffering", "no")
+
+	slg := lg.With().Str("subscriptionId", sid).Str("kind", sub.kind).Logger()
+	es := &eventStream{
+		conn: fastCtx.Conn(),
+		kind: sub.kind,
+	}
+	fastCtx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		es.w = w
+		defer func() {
+			_, _ = ps.unsubscribe([]interface{}{sid})
+			slg.Debug().Msg("event stream closed")
+		}()
+		slg.Debug().Msg("event stream opened")
+
+		if err := es.comment("subscription " + sid); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-mainCtx.Done():
+				return
+			case <-s.drain:
+				slg.Debug().Msg("closing event stream because server is shutting down")
+				return
+			case <-sub.notify:
+			case <-ticker.C:
+				ps.mu.Lock()
+				_, ok := ps.subs[sid]
+				ps.mu.Unlock()
+				if !ok {
+					return
+				}
+				if err := es.comment("ping"); err != nil {
+					slg.Debug().Err(err).Msg("event stream client disconnected")
+					return
+				}
+			}
+
+			res := sub.drain()
+			if err := es.send(res); err != nil {
+				slg.Debug().Err(err).Msg("event stream client disconnected")
+				return
+			}
+		}
+	})
+}
+
+func eventStreamSubscribeParams(args *fasthttp.Args) ([]interface{}, error) {
+	kind := string(args.Peek("subscription"))
+	if kind == "" {
+		kind = pollSubscriptionKindNewHeads
+	}
+	params := []interface{}{kind}
+	if raw := args.Peek("filter"); len(raw) > 0 {
+		var filter map[string]interface{}
+		if err := sonic.Unmarshal(raw, &filter); err != nil {
+			return nil, fmt.Errorf("filter query param must be a json object: %w", err)
+		}
+		params = append(params, filter)
+	}
+	return params, nil
+}
+
+type eventStream struct {
+	conn   net.Conn
+	w      *bufio.Writer
+	kind   string
+	lastId int64
+}
+
+// send writes one event per notification, e.g. "id: 1\nevent: newHeads\ndata: {...}\n\n", preceded by
+// a "dropped" event with the number of notifications lost since the previous send (if any).
+func (es *eventStream) send(res *pollSubscriptionResult) error {
+	if len(res.Events) == 0 && res.Dropped == 0 {
+		return nil
+	}
+	if res.Dropped > 0 {
+		fmt.Fprintf(es.w, "event: dropped\ndata: %d\n\n", res.Dropped)
+	}
+	var data bytes.Buffer
+	for _, ev := range res.Events {
+		data.Reset()
+		if err := json.Compact(&data, ev); err != nil {
+			// Payloads always come from upstreams as valid json, raw bytes are a fallback that must stay on one line
+			data.Reset()
+			data.Write(bytes.ReplaceAll(ev, []byte("\n"), nil))
+		}
+		es.lastId++
+		fmt.Fprintf(es.w, "id: %d\nevent: %s\ndata: %s\n\n", es.lastId, es.kind, data.Bytes())
+	}
+	return es.flush()
+}
+
+func (es *eventStream) comment(text string) error {
+	fmt.Fprintf(es.w, ": %s\n\n", text)
+	return es.flush()
+}
+
+// flush extends the write deadline on every write, the server's write timeout would otherwise end long-lived streams.
+func (es *eventStream) flush() error {
+	if es.conn != nil {
+		_ = es.conn.SetWriteDeadline(time.Now().Add(sseWriteTimeout))
+	}
+	return es.w.Flush()
+}
@@ -0,0 +1,202 @@
+package erpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/h2non/gock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpServer_EventStream(t *testing.T) {
+	setupMocksForEvmStatePoller()
+	gock.EnableNetworking()
+	gock.NetworkingFilter(func(req *http.Request) bool {
+		return strings.Split(req.URL.Host, ":")[0] == "localhost"
+	})
+	defer gock.Off()
+
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "5s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Networks: []*common.NetworkConfig{
+					{
+						Architecture: common.ArchitectureEvm,
+						Evm: &common.EvmNetworkConfig{
+							ChainId:              123,
+							BlockTrackerInterval: "10ms",
+							// Also shortens heartbeats, which is how disconnected clients are noticed
+							PollSubscriptions: &common.PollSubscriptionsConfig{IdleTimeout: "1s"},
+						},
+					},
+				},
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Id:       "rpc1",
+						Type:     common.UpstreamTypeEvm,
+						Endpoint: "http://rpc1.localhost",
+						Evm: &common.EvmUpstreamConfig{
+							ChainId: 123,
+						},
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	erpcInstance, err := NewERPC(ctx, &logger, nil, cfg)
+	require.NoError(t, err)
+	project, err := erpcInstance.GetProject("test_project")
+	require.NoError(t, err)
+	network, err := project.GetNetwork("evm:123")
+	require.NoError(t, err)
+
+	head := &atomic.Int64{}
+	head.Store(100)
+	network.blockResolver = &atomicHeadResolver{head: head}
+	network.pollSubscriptions.fetchBlock = func(ctx context.Context, blockRef string) (json.RawMessage, int64, error) {
+		bn, _ := common.HexToInt64(blockRef)
+		// Pretty-printed on purpose, each event must still be sent on a single data line
+		return json.RawMessage(fmt.Sprintf("{\n  \"number\": \"0x%x\"\n}", bn)), bn, nil
+	}
+
+	httpServer, err := NewHttpServer(ctx, &logger, cfg.Server, erpcInstance)
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = httpServer.server.Serve(listener) }()
+	baseURL := fmt.Sprintf("http://localhost:%d", listener.Addr().(*net.TCPAddr).Port)
+
+	subscriptions := func() int {
+		network.pollSubscriptions.mu.Lock()
+		defer network.pollSubscriptions.mu.Unlock()
+		return len(network.pollSubscriptions.subs)
+	}
+
+	t.Run("StreamsNewHeadsAndUnsubscribesOnDisconnect", func(t *testing.T) {
+		reqCtx, reqCancel := context.WithCancel(ctx)
+		defer reqCancel()
+		req, err := http.NewRequestWithContext(reqCtx, "GET", baseURL+"/test_project/evm/123?subscription=newHeads", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "text/event-stream")
+
+		client := &http.Client{Transport: &http.Transport{}}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+		assert.Eventually(t, func() bool {
+			network.pollSubscriptions.mu.Lock()
+			defer network.pollSubscriptions.mu.Unlock()
+			return network.pollSubscriptions.lastHead == 100
+		}, 2*time.Second, 5*time.Millisecond)
+		head.Store(102)
+
+		type event struct{ id, name, data string }
+		events := make(chan event, 10)
+		go func() {
+			scanner := bufio.NewScanner(resp.Body)
+			var ev event
+			for scanner.Scan() {
+				line := scanner.Text()
+				switch {
+				case strings.HasPrefix(line, "id: "):
+					ev.id = strings.TrimPrefix(line, "id: ")
+				case strings.HasPrefix(line, "event: "):
+					ev.name = strings.TrimPrefix(line, "event: ")
+				case strings.HasPrefix(line, "data: "):
+					ev.data = strings.TrimPrefix(line, "data: ")
+				case line == "" && ev.name != "":
+					events <- ev
+					ev = event{}
+				}
+			}
+			close(events)
+		}()
+
+		var received []event
+		timeout := time.After(3 * time.Second)
+		for len(received) < 2 {
+			select {
+			case ev, ok := <-events:
+				require.True(t, ok, "stream ended before receiving two events")
+				received = append(received, ev)
+			case <-timeout:
+				t.Fatalf("expected two events, got %d", len(received))
+			}
+		}
+		assert.Equal(t, event{id: "1", name: "newHeads", data: `{"number":"0x65"}`}, received[0])
+		assert.Equal(t, event{id: "2", name: "newHeads", data: `{"number":"0x66"}`}, received[1])
+		assert.Equal(t, 1, subscriptions())
+
+		reqCancel()
+		assert.Eventually(t, func() bool { return subscriptions() == 0 }, 5*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("UnsupportedSubscriptionIsRejected", func(t *testing.T) {
+		req, err := http.NewRequest("GET", baseURL+"/test_project/evm/123?subscription=newPendingTransactions", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "text/event-stream")
+
+		resp, err := (&http.Client{Transport: &http.Transport{}, Timeout: 5 * time.Second}).Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "application/json")
+		assert.Contains(t, string(body), "ErrInvalidRequest")
+		assert.Equal(t, 0, subscriptions())
+	})
+
+	// Must run last since it shuts the server down
+	t.Run("OpenStreamsEndOnShutdown", func(t *testing.T) {
+		req, err := http.NewRequest("GET", baseURL+"/test_project/evm/123?subscription=newHeads", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "text/event-stream")
+
+		resp, err := (&http.Client{Transport: &http.Transport{}}).Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Eventually(t, func() bool { return subscriptions() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+		ended := make(chan struct{})
+		go func() {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			close(ended)
+		}()
+
+		start := time.Now()
+		assert.NoError(t, httpServer.Shutdown(&logger))
+		assert.Less(t, time.Since(start), 5*time.Second, "shutdown must not wait for the grace period")
+		select {
+		case <-ended:
+		case <-time.After(5 * time.Second):
+			t.Fatal("event stream did not end on shutdown")
+		}
+		assert.Eventually(t, func() bool { return subscriptions() == 0 }, 2*time.Second, 10*time.Millisecond)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/erpc/erpc/common"
@@ -15,12 +16,16 @@ import (
 	"github.com/spf13/afero"
 )
 
+// Init loads the configuration and starts eRPC, the returned shutdown function stops serving in order (see
+// HttpServer.Shutdown), closes upstream clients and the cache, and stops the metrics server. It returns once
+// all of them are done, and is meant to be called before ctx is cancelled so that in-flight requests can
+// still use the rest of components (e.g. cache connectors) while they complete.
 func Init(
 	ctx context.Context,
 	logger zerolog.Logger,
 	fs afero.Fs,
 	args []string,
-) error {
+) (shutdown func(), err error) {
 	//
 	// 1) Load configuration
 	//
@@ -30,13 +35,13 @@ func Init(
 		configPath = args[1]
 	}
 	if _, err := fs.Stat(configPath); errors.Is(err, os.ErrNotExist) {
-		return fmt.Errorf("config file '%s' does not exist", configPath)
+		return nil, fmt.Errorf("config file '%s' does not exist", configPath)
 	}
 	logger.Info().Msgf("resolved configuration file to: %s", configPath)
 	cfg, err := common.LoadConfig(fs, configPath)
 
 	if err != nil {
-		return fmt.Errorf("failed to load configuration from %s: %v", configPath, err)
+		return nil, fmt.Errorf("failed to load configuration from %s: %v", configPath, err)
 	}
 	level, err := zerolog.ParseLevel(cfg.LogLevel)
 	if err != nil {
@@ -50,6 +55,9 @@ func Init(
 	// 2) Initialize eRPC
 	//
 	logger.Info().Msg("initializing eRPC")
+	if err := InitTracing(ctx, &logger, cfg.Tracing); err != nil {
+		return nil, fmt.Errorf("failed to initialize tracing: %v", err)
+	}
 	var evmJsonRpcCache *EvmJsonRpcCache
 	if cfg.Database != nil {
 		if cfg.Database.EvmJsonRpcCache != nil {
@@ -61,7 +69,7 @@ func Init(
 	}
 	erpcInstance, err := NewERPC(ctx, &logger, evmJsonRpcCache, cfg)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	//
@@ -70,7 +78,10 @@ func Init(
 	logger.Info().Msg("initializing transports")
 	var httpServer *HttpServer
 	if cfg.Server != nil {
-		httpServer = NewHttpServer(ctx, &logger, cfg.Server, erpcInstance)
+		httpServer, err = NewHttpServer(ctx, &logger, cfg.Server, erpcInstance)
+		if err != nil {
+			return nil, err
+		}
 		go func() {
 			if err := httpServer.Start(&logger); err != nil {
 				if err != http.ErrServerClosed {
@@ -81,6 +92,7 @@ func Init(
 		}()
 	}
 
+	var stopMetricsServer func()
 	if cfg.Metrics != nil && cfg.Metrics.Enabled {
 		addrV4 := fmt.Sprintf("%s:%d", cfg.Metrics.HostV4, cfg.Metrics.Port)
 		addrV6 := fmt.Sprintf("%s:%d", cfg.Metrics.HostV6, cfg.Metrics.Port)
@@ -96,8 +108,7 @@ func Init(
 				util.OsExit(util.ExitCodeHttpServerFailed)
 			}
 		}()
-		go func() {
-			<-ctx.Done()
+		stopMetricsServer = sync.OnceFunc(func() {
 			logger.Info().Msg("shutting down metrics server...")
 			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
@@ -106,8 +117,35 @@ func Init(
 			} else {
 				logger.Info().Msg("metrics server stopped")
 			}
+		})
+		go func() {
+			<-ctx.Done()
+			stopMetricsServer()
 		}()
 	}
 
-	return nil
+	//
+	// 4) Orderly shutdown
+	//
+	shutdown = func() {
+		if httpServer != nil {
+			if err := httpServer.Shutdown(&logger); err != nil {
+				logger.Warn().Err(err).Msg("not all in-flight requests completed within shutdown grace period")
+			}
+		}
+		if err := erpcInstance.CloseUpstreamClients(); err != nil {
+			logger.Warn().Err(err).Msg("failed to close some upstream clients")
+		}
+		if evmJsonRpcCache != nil {
+			// Nothing writes to cache anymore, so connectors persisting to disk can do so completely
+			if err := evmJsonRpcCache.Close(context.Background()); err != nil {
+				logger.Error().Err(err).Msg("failed to close evm json rpc cache")
+			}
+		}
+		if stopMetricsServer != nil {
+			stopMetricsServer()
+		}
+	}
+
+	return shutdown, nil
 }
@@ -0,0 +1,123 @@
+package erpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/erpc/erpc/common"
+	"github.com/erpc/erpc/upstream"
+)
+
+const (
+	// Aggregates independent read requests in a single call, e.g. {"method":"erpc_aggregate","params":[[{"method":"eth_call","params":[...]}, ...]]}
+	aggregateMethod = "erpc_aggregate"
+
+	maxAggregateItems = 100
+)
+
+// forwardAggregate splits an aggregate request into its items and forwards each of them through the network
+// on its own, so every item is served from cache when possible, identical items share one in-flight upstream
+// call and successful items are cached individually. Results are returned in the same order as the items,
+// a failed item carries its json-rpc error without failing the others.
+func (n *Network) forwardAggregate(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error) {
+	jrq, err := req.JsonRpcRequest()
+	if err != nil {
+		return nil, err
+	}
+	jrq.RLock()
+	params := jrq.Params
+	id := jrq.ID
+	jrq.RUnlock()
+
+	items, err := n.parseAggregateItems(params)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]json.RawMessage, len(items))
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func(i int, item *common.NormalizedRequest) {
+			defer wg.Done()
+			results[i] = n.forwardAggregateItem(ctx, item)
+		}(i, item.WithDirectives(req.Directives().Clone()))
+	}
+	wg.Wait()
+
+	jrr, err := common.NewJsonRpcResponse(id, results, nil)
+	if err != nil {
+		return nil, err
+	}
+	return common.NewNormalizedResponse().WithRequest(req).WithJsonRpcResponse(jrr), nil
+}
+
+func (n *Network) parseAggregateItems(params []interface{}) ([]*common.NormalizedRequest, error) {
+	if len(params) != 1 {
+		return nil, common.NewErrInvalidRequest(fmt.Errorf("%s requires an array of requests as the only param", aggregateMethod))
+	}
+	rawItems, ok := params[0].([]interface{})
+	if !ok {
+		return nil, common.NewErrInvalidRequest(fmt.Errorf("%s requires an array of requests as the only param", aggregateMethod))
+	}
+	if len(rawItems) > maxAggregateItems {
+		return nil, common.NewErrInvalidRequest(fmt.Errorf("%s accepts at most %d requests, got %d", aggregateMethod, maxAggregateItems, len(rawItems)))
+	}
+
+	items := make([]*common.NormalizedRequest, len(rawItems))
+	for i, raw := range rawItems {
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, common.NewErrInvalidRequest(fmt.Errorf("%s item %d must be an object with method and params", aggregateMethod, i))
+		}
+		method, _ := obj["method"].(string)
+		if !upstream.IsIdempotentMethod(method) {
+			return nil, common.NewErrInvalidRequest(fmt.Errorf("%s item %d: method %q is not a read request", aggregateMethod, i, method))
+		}
+		var itemParams []interface{}
+		if p, ok := obj["params"]; ok && p != nil {
+			if itemParams, ok = p.([]interface{}); !ok {
+				return nil, common.NewErrInvalidRequest(fmt.Errorf("%s item %d: params must be an array", aggregateMethod, i))
+			}
+		}
+		itemId := obj["id"]
+		if itemId == nil {
+			itemId = i
+		}
+
+		jrq, err := common.NewJsonRpcRequest(itemId, method, itemParams)
+		if err != nil {
+			return nil, err
+		}
+		body, err := sonic.Marshal(jrq)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = common.NewNormalizedRequest(body)
+	}
+
+	return items, nil
+}
+
+func (n *Network) forwardAggregateItem(ctx context.Context, item *common.NormalizedRequest) json.RawMessage {
+	var resp *common.NormalizedResponse
+	err := n.acquireProjectPermit(item)
+	if err == nil {
+		resp, err = n.Forward(ctx, item)
+	}
+	if err == nil {
+		var jrr *common.JsonRpcResponse
+		if jrr, err = resp.JsonRpcResponse(); err == nil {
+			var out []byte
+			if out, err = sonic.Marshal(jrr); err == nil {
+				return out
+			}
+		}
+	}
+
+	out, _ := sonic.Marshal(processErrorBody(n.Logger, item, err))
+	return out
+}
@@ -0,0 +1,127 @@
+package erpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/erpc/erpc/common"
+)
+
+const (
+	// Fetches receipts of a small block range in a single call, e.g. {"method":"erpc_getBlockReceiptsRange","params":["0x10","0x14"]}
+	blockReceiptsRangeMethod = "erpc_getBlockReceiptsRange"
+
+	maxBlockReceiptsRange = 20
+)
+
+// forwardBlockReceiptsRange fans out one eth_getBlockReceipts per block of the (inclusive) range through the network,
+// so every block is served from cache when possible and cached on its own once finalized. Receipts of all blocks are
+// returned as one array in block order, and the request fails as a whole if any block cannot be fetched.
+func (n *Network) forwardBlockReceiptsRange(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error) {
+	jrq, err := req.JsonRpcRequest()
+	if err != nil {
+		return nil, err
+	}
+	jrq.RLock()
+	params := jrq.Params
+	id := jrq.ID
+	jrq.RUnlock()
+
+	from, to, err := parseBlockReceiptsRange(params)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	count := int(to - from + 1)
+	results := make([]json.RawMessage, count)
+	var wg sync.WaitGroup
+	var failOnce sync.Once
+	var firstErr error
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := n.forwardBlockReceipts(ctx, req, from+int64(i))
+			if err != nil {
+				// Remaining blocks are useless once one of them failed
+				failOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	merged, err := common.MergeJSONArrays(results...)
+	if err != nil {
+		return nil, err
+	}
+	jrr, err := common.NewJsonRpcResponse(id, merged, nil)
+	if err != nil {
+		return nil, err
+	}
+	return common.NewNormalizedResponse().WithRequest(req).WithJsonRpcResponse(jrr), nil
+}
+
+func parseBlockReceiptsRange(params []interface{}) (from int64, to int64, err error) {
+	if len(params) != 2 {
+		return 0, 0, common.NewErrInvalidRequest(fmt.Errorf("%s requires fromBlock and toBlock as hex numbers", blockReceiptsRangeMethod))
+	}
+	bounds := make([]int64, 2)
+	for i, p := range params {
+		s, ok := p.(string)
+		if !ok {
+			return 0, 0, common.NewErrInvalidRequest(fmt.Errorf("%s requires fromBlock and toBlock as hex numbers", blockReceiptsRangeMethod))
+		}
+		bounds[i], err = common.HexToInt64(s)
+		if err != nil {
+			return 0, 0, common.NewErrInvalidRequest(fmt.Errorf("%s param #%d is not a hex number: %w", blockReceiptsRangeMethod, i, err))
+		}
+	}
+	from, to = bounds[0], bounds[1]
+	if from > to {
+		return 0, 0, common.NewErrInvalidRequest(fmt.Errorf("%s fromBlock %d is after toBlock %d", blockReceiptsRangeMethod, from, to))
+	}
+	if to-from+1 > maxBlockReceiptsRange {
+		return 0, 0, common.NewErrInvalidRequest(fmt.Errorf("%s accepts at most %d blocks, got %d", blockReceiptsRangeMethod, maxBlockReceiptsRange, to-from+1))
+	}
+	return from, to, nil
+}
+
+// forwardBlockReceipts charges the project budget once per block, since the project does not charge the range itself.
+func (n *Network) forwardBlockReceipts(ctx context.Context, req *common.NormalizedRequest, blockNumber int64) (json.RawMessage, error) {
+	breq, err := common.NewInternalRequest("eth_getBlockReceipts", []interface{}{fmt.Sprintf("0x%x", blockNumber)})
+	if err != nil {
+		return nil, err
+	}
+	breq.WithDirectives(req.Directives().Clone())
+	if err := n.acquireProjectPermit(breq); err != nil {
+		return nil, err
+	}
+	resp, err := n.Forward(ctx, breq)
+	if err != nil {
+		return nil, err
+	}
+	jrr, err := resp.JsonRpcResponse()
+	if err != nil {
+		return nil, err
+	}
+	if jrr.Error != nil {
+		return nil, jrr.Error
+	}
+	if len(jrr.Result) == 0 || string(jrr.Result) == "null" {
+		return nil, common.NewErrEndpointMissingData(fmt.Errorf("no receipts returned for block %d", blockNumber))
+	}
+	return jrr.Result, nil
+}
@@ -0,0 +1,31 @@
+package erpc
+
+import (
+	"errors"
+	"time"
+
+	"github.com/erpc/erpc/common"
+)
+
+// newUpstreamAttempt describes the outcome of forwarding a request to an upstream, errors are summarized
+// the same way as in metrics so that no request data or upstream urls end up in the trace.
+func newUpstreamAttempt(upstreamId string, err error, duration time.Duration) common.UpstreamAttempt {
+	attempt := common.UpstreamAttempt{
+		Upstream:   upstreamId,
+		Outcome:    "success",
+		DurationMs: duration.Milliseconds(),
+	}
+	if err == nil {
+		return attempt
+	}
+
+	var skipped *common.ErrUpstreamRequestSkipped
+	if errors.As(err, &skipped) {
+		attempt.Outcome = "skipped"
+		attempt.Reason = common.ErrorSummary(skipped.Cause)
+		return attempt
+	}
+	attempt.Outcome = "error"
+	attempt.Error = common.ErrorSummary(err)
+	return attempt
+}
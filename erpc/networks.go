@@ -13,8 +13,11 @@ import (
 	"github.com/erpc/erpc/upstream"
 	"github.com/failsafe-go/failsafe-go"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+const warmCacheConcurrency = 10
+
 type Network struct {
 	cfg *common.NetworkConfig
 
@@ -27,12 +30,24 @@ type Network struct {
 
 	failsafePolicies     []failsafe.Policy[*common.NormalizedResponse]
 	failsafeExecutor     failsafe.Executor[*common.NormalizedResponse]
+	retryBudget          *retryBudget
+	routingRules         []*routingRule
+	deprecatedMethods    *deprecatedMethods
+	serveStale           *serveStalePolicy
 	rateLimitersRegistry *upstream.RateLimitersRegistry
-	cacheDal             data.CacheDAL
-	metricsTracker       *health.Tracker
-	upstreamsRegistry    *upstream.UpstreamsRegistry
-
-	evmStatePollers map[string]*upstream.EvmStatePoller
+	// Charges the project rate limit budget, set by the project so that requests fanned out by the network
+	// (e.g. aggregate items) each pay for their own upstream calls
+	projectRateLimit  func(req *common.NormalizedRequest) error
+	cacheDal          data.CacheDAL
+	metricsTracker    *health.Tracker
+	upstreamsRegistry *upstream.UpstreamsRegistry
+
+	evmStatePollers   map[string]*upstream.EvmStatePoller
+	blockResolver     common.BlockResolver
+	pollSubscriptions *evmPollSubscriptions
+	logsBloom         *evmLogsBloomIndex
+	finalizedPrewarm  *evmFinalizedPrewarmer
+	realtimeTTL       time.Duration
 }
 
 func (n *Network) Bootstrap(ctx context.Context) error {
@@ -50,6 +65,13 @@ func (n *Network) Bootstrap(ctx context.Context) error {
 			n.evmStatePollers[u.Config().Id] = poller
 			n.Logger.Info().Str("upstreamId", u.Config().Id).Msgf("bootstraped evm state poller to track upstream latest, finalized blocks and syncing states")
 		}
+		if n.finalizedPrewarm != nil {
+			if n.cacheDal == nil {
+				n.Logger.Warn().Msg("evm.finalizedPrewarm is ignored because no cache is configured")
+			} else {
+				go n.finalizedPrewarm.run(ctx)
+			}
+		}
 	} else {
 		return fmt.Errorf("network architecture not supported: %s", n.Architecture())
 	}
@@ -80,60 +102,99 @@ func (n *Network) Forward(ctx context.Context, req *common.NormalizedRequest) (*
 	method, _ := req.Method()
 	lg := n.Logger.With().Str("method", method).Str("id", req.Id()).Str("ptr", fmt.Sprintf("%p", req)).Logger()
 
+	// 0) Malformed params would fail on every upstream anyway
+	if n.cfg != nil && n.cfg.Evm != nil && n.cfg.Evm.ValidateRequestParams {
+		jrq, err := req.JsonRpcRequest()
+		if err != nil {
+			return nil, err
+		}
+		if err := common.ValidateEvmJsonRpcRequestParams(jrq); err != nil {
+			return nil, err
+		}
+	}
+
+	// 0) Deprecated methods are flagged to help migrating clients, and only fail when configured to
+	if err := n.checkDeprecatedMethod(req, method); err != nil {
+		return nil, err
+	}
+
+	// 0) Omitted block params are made explicit so that every upstream serves the same block
+	if n.cfg != nil && n.cfg.Evm != nil && n.cfg.Evm.DefaultBlockTag != "" {
+		if jrq, err := req.JsonRpcRequest(); err == nil && common.ApplyEvmDefaultBlockTag(jrq, n.cfg.Evm.DefaultBlockTag) {
+			lg.Debug().Str("blockTag", n.cfg.Evm.DefaultBlockTag).Msgf("filled omitted block param with default block tag")
+		}
+	}
+
+	// 0) Clients waiting for a tx to be mined are served once the receipt is available, instead of busy-polling
+	if wait := req.WaitForReceipt(); wait > 0 && method == "eth_getTransactionReceipt" {
+		return n.forwardWaitingForReceipt(ctx, req, wait)
+	}
+
+	// 0) Subscriptions over http are served locally from buffered notifications
+	if n.pollSubscriptions != nil {
+		if resp, handled, err := n.pollSubscriptions.Handle(ctx, req, method); handled {
+			return resp, err
+		}
+	}
+
+	// 0) Aggregated reads are split so that each item is cached and multiplexed on its own
+	if method == aggregateMethod {
+		return n.forwardAggregate(ctx, req)
+	}
+
+	// 0) Receipts of a block range are fetched block by block so that each of them is cached on its own
+	if method == blockReceiptsRangeMethod {
+		return n.forwardBlockReceiptsRange(ctx, req)
+	}
+
+	// 0) Node-status methods can be answered by eRPC itself when configured
+	if resp, handled, err := n.evmSyntheticResponse(req, method); handled {
+		return resp, err
+	}
+	if resp, handled := n.evmLogsBloomShortCircuit(req, method); handled {
+		return resp, nil
+	}
+
+	// Responses of other requests might come from upstreams or a consensus this request does not accept
+	ownResponseOnly := req.ExcludesUpstreams() || req.OverridesConsensus()
+
 	// 1) In-flight multiplexing
 	var inf *Multiplexer
 	mlxHash, err := req.CacheHash()
-	if err == nil && mlxHash != "" {
+	if err == nil && mlxHash != "" && !ownResponseOnly {
 		n.inFlightMutex.Lock()
-		var exists bool
-		if inf, exists = n.inFlightRequests[mlxHash]; exists {
+		if leader, exists := n.inFlightRequests[mlxHash]; exists {
 			n.inFlightMutex.Unlock()
 			lg.Debug().Msgf("found similar in-flight request, waiting for result")
 			health.MetricNetworkMultiplexedRequests.WithLabelValues(n.ProjectId, n.NetworkId, method).Inc()
 
-			inf.mu.RLock()
-			if inf.resp != nil || inf.err != nil {
-				inf.mu.RUnlock()
-				resp, err := common.CopyResponseForRequest(inf.resp, req)
-				if err != nil {
-					return nil, err
-				}
-				return resp, inf.err
-			}
-			inf.mu.RUnlock()
-
-			select {
-			case <-inf.done:
-				resp, err := common.CopyResponseForRequest(inf.resp, req)
-				if err != nil {
-					return nil, err
-				}
-				return resp, inf.err
-			case <-ctx.Done():
-				err := ctx.Err()
-				if errors.Is(err, context.DeadlineExceeded) {
-					return nil, common.NewErrNetworkRequestTimeout(time.Since(startTime))
-				}
-
-				return nil, err
+			resp, shared, err := n.waitForInFlight(ctx, leader, req, startTime)
+			if shared {
+				return resp, err
 			}
+			// A partial response might miss data this request does not accept to lose
+			lg.Debug().Msgf("similar in-flight request returned a partial response, forwarding on its own")
+		} else {
+			inf = NewMultiplexer()
+			n.inFlightRequests[mlxHash] = inf
+			n.inFlightMutex.Unlock()
+			defer func() {
+				n.inFlightMutex.Lock()
+				defer n.inFlightMutex.Unlock()
+				delete(n.inFlightRequests, mlxHash)
+			}()
 		}
-		inf = NewMultiplexer()
-		n.inFlightRequests[mlxHash] = inf
-		n.inFlightMutex.Unlock()
-		defer func() {
-			n.inFlightMutex.Lock()
-			defer n.inFlightMutex.Unlock()
-			delete(n.inFlightRequests, mlxHash)
-		}()
 	}
 
 	// 2) Get from cache if exists
-	if n.cacheDal != nil && !req.SkipCacheRead() {
+	if n.cacheDal != nil && !req.SkipCacheRead() && !ownResponseOnly {
 		lg.Debug().Msgf("checking cache for request")
 		cctx, cancel := context.WithTimeoutCause(ctx, 2*time.Second, errors.New("cache driver timeout during get"))
 		defer cancel()
+		cctx, span := startSpan(cctx, "erpc.cache.get", attribute.String("rpc.method", method))
 		resp, err := n.cacheDal.Get(cctx, req)
+		span.SetAttributes(attribute.Bool("erpc.cache.hit", err == nil && resp != nil && !resp.IsObjectNull() && !resp.IsResultEmptyish()))
+		endSpan(span, nil)
 		if err != nil {
 			lg.Debug().Err(err).Msgf("could not find response in cache")
 			health.MetricNetworkCacheMisses.WithLabelValues(n.ProjectId, n.NetworkId, method).Inc()
@@ -147,8 +208,12 @@ func (n *Network) Forward(ctx context.Context, req *common.NormalizedRequest) (*
 		}
 	}
 
+	n.applyRoutingRules(req, method)
+
+	_, selectSpan := startSpan(ctx, "erpc.upstream.select", attribute.String("rpc.method", method))
 	upsList, err := n.upstreamsRegistry.GetSortedUpstreams(n.NetworkId, method)
 	if err != nil {
+		endSpan(selectSpan, err)
 		if inf != nil {
 			inf.Close(nil, err)
 		}
@@ -157,11 +222,23 @@ func (n *Network) Forward(ctx context.Context, req *common.NormalizedRequest) (*
 
 	// 3) Check if we should handle this method on this network
 	if err := n.shouldHandleMethod(method, upsList); err != nil {
+		endSpan(selectSpan, err)
+		if inf != nil {
+			inf.Close(nil, err)
+		}
+		return nil, err
+	}
+
+	upsList, err = n.filterArchiveUpstreams(req, method, upsList)
+	if err != nil {
+		endSpan(selectSpan, err)
 		if inf != nil {
 			inf.Close(nil, err)
 		}
 		return nil, err
 	}
+	selectSpan.SetAttributes(attribute.Int("erpc.upstreams_count", len(upsList)))
+	endSpan(selectSpan, nil)
 
 	// 3) Apply rate limits
 	if err := n.acquireRateLimitPermit(req); err != nil {
@@ -171,6 +248,19 @@ func (n *Network) Forward(ctx context.Context, req *common.NormalizedRequest) (*
 		return nil, err
 	}
 
+	// Consensus requirements are resolved once, a request that cannot be satisfied is not worth retrying
+	var consensusUps []*upstream.Upstream
+	var requiredAgreements int
+	if n.cfg.Consensus != nil {
+		consensusUps, requiredAgreements, err = n.consensusParticipants(req, method, upsList)
+		if err != nil {
+			if inf != nil {
+				inf.Close(nil, err)
+			}
+			return nil, err
+		}
+	}
+
 	// 4) Iterate over upstreams and forward the request until success or fatal failure
 	tryForward := func(
 		u *upstream.Upstream,
@@ -179,7 +269,17 @@ func (n *Network) Forward(ctx context.Context, req *common.NormalizedRequest) (*
 	) (resp *common.NormalizedResponse, err error) {
 		lg.Debug().Msgf("trying to forward request to upstream")
 
+		ctx, span := startSpan(ctx, "erpc.upstream.forward",
+			attribute.String("erpc.upstream_id", u.Config().Id),
+			attribute.String("rpc.method", method),
+		)
+		defer func() { endSpan(span, err) }()
+
+		forwardStart := time.Now()
 		resp, err = u.Forward(ctx, req)
+		attempt := newUpstreamAttempt(u.Config().Id, err, time.Since(forwardStart))
+		attempt.Empty = err == nil && resp != nil && resp.IsResultEmptyish()
+		req.AddUpstreamAttempt(attempt)
 
 		if !common.IsNull(err) {
 			// If upstream complains that the method is not supported let's dynamically add it ignoreMethods config
@@ -203,15 +303,38 @@ func (n *Network) Forward(ctx context.Context, req *common.NormalizedRequest) (*
 	// 5) Actual forwarding logic
 	var execution failsafe.Execution[*common.NormalizedResponse]
 	var errorsByUpstream = map[string]error{}
+	var retriesCharged int
+
+	if n.retryBudget != nil {
+		n.retryBudget.Deposit()
+	}
 
 	i := 0
-	resp, execErr := n.failsafeExecutor.
+	executor := n.failsafeExecutor
+	if ex := upstream.ExecutorForRequestTimeout(n.failsafePolicies, n.cfg.Failsafe, req.Timeout()); ex != nil {
+		executor = ex
+	}
+	resp, execErr := executor.
 		WithContext(ctx).
 		GetWithExecution(func(exec failsafe.Execution[*common.NormalizedResponse]) (*common.NormalizedResponse, error) {
 			req.Lock()
 			execution = exec
+			// Hedges do not count as retries, so only charge the budget when retries count goes up
+			isNewRetry := exec.Retries() > retriesCharged
+			if isNewRetry {
+				retriesCharged = exec.Retries()
+			}
 			req.Unlock()
 
+			if isNewRetry && n.retryBudget != nil && !n.retryBudget.TryWithdraw() {
+				lg.Debug().Err(exec.LastError()).Msgf("not retrying request because network retry budget is exhausted")
+				return nil, common.NewErrRetryBudgetExhausted(n.NetworkId, exec.LastError())
+			}
+
+			if n.cfg.Consensus != nil {
+				return n.forwardWithConsensus(exec, req, method, consensusUps, requiredAgreements, tryForward, &lg, startTime)
+			}
+
 			// We should try all upstreams at least once, but using "i" we make sure
 			// across different executions of the failsafe we pick up next upstream vs retrying the same upstream.
 			// This mimicks a round-robin behavior, for example when doing hedge or retries.
@@ -232,6 +355,11 @@ func (n *Network) Forward(ctx context.Context, req *common.NormalizedRequest) (*
 						// the previous error was not retryable. e.g. Billing issues
 						// Or there was a rate-limit error.
 						req.Unlock()
+						req.AddUpstreamAttempt(common.UpstreamAttempt{
+							Upstream: upsId,
+							Outcome:  "skipped",
+							Reason:   "previous attempt failed with a non-retryable error",
+						})
 						continue
 					}
 				}
@@ -313,19 +441,16 @@ func (n *Network) Forward(ctx context.Context, req *common.NormalizedRequest) (*
 						execution.Hedges(),
 					)
 				}
-				if inf != nil {
-					inf.Close(nil, err)
-				}
-				return nil, err
+				return n.failOrServeStale(ctx, req, method, inf, err)
 			}
 		} else {
-			if inf != nil {
-				inf.Close(nil, err)
-			}
-			return nil, err
+			return n.failOrServeStale(ctx, req, method, inf, err)
 		}
 	}
 
+	// Verified before caching so that a wrongly empty result is not stored
+	resp = n.verifyEmptyLogs(ctx, req, method, resp, upsList, errorsByUpstream)
+
 	if resp != nil {
 		if execution != nil {
 			resp.SetAttempts(execution.Attempts())
@@ -333,7 +458,8 @@ func (n *Network) Forward(ctx context.Context, req *common.NormalizedRequest) (*
 			resp.SetHedges(execution.Hedges())
 		}
 
-		if n.cacheDal != nil {
+		// Partial results must not be served later as if they were complete
+		if n.cacheDal != nil && !resp.IsPartial() {
 			go (func(resp *common.NormalizedResponse) {
 				c, cancel := context.WithTimeoutCause(context.Background(), 10*time.Second, errors.New("cache driver timeout during set"))
 				defer cancel()
@@ -347,6 +473,8 @@ func (n *Network) Forward(ctx context.Context, req *common.NormalizedRequest) (*
 
 	if execErr == nil && resp != nil && !resp.IsObjectNull() {
 		n.enrichStatePoller(method, req, resp)
+		n.observeLogsBloom(method, resp)
+		n.sampleShadowComparison(method, req, resp)
 	}
 	if inf != nil {
 		inf.Close(resp, nil)
@@ -354,18 +482,74 @@ func (n *Network) Forward(ctx context.Context, req *common.NormalizedRequest) (*
 	return resp, nil
 }
 
-func (n *Network) EvmIsBlockFinalized(blockNumber int64) (bool, error) {
-	for _, poller := range n.evmStatePollers {
-		if fin, err := poller.IsBlockFinalized(blockNumber); err != nil {
-			if common.HasErrorCode(err, common.ErrCodeFinalizedBlockUnavailable) {
-				continue
+// WarmCache proactively fetches the given requests and stores their responses in the cache.
+// Requests that are already cached are skipped, and a failure of one request does not abort the others,
+// instead all errors are returned joined together.
+func (n *Network) WarmCache(ctx context.Context, reqs []*common.NormalizedRequest) error {
+	if n.cacheDal == nil {
+		return common.NewErrInvalidConfig("cache is not configured for network " + n.NetworkId)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	sem := make(chan struct{}, warmCacheConcurrency)
+
+	for _, req := range reqs {
+		wg.Add(1)
+		go func(req *common.NormalizedRequest) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				mu.Lock()
+				errs = append(errs, ctx.Err())
+				mu.Unlock()
+				return
 			}
-			return false, err
-		} else if fin {
-			return true, nil
-		}
+
+			if err := n.warmCacheForRequest(ctx, req); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(req)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (n *Network) warmCacheForRequest(ctx context.Context, req *common.NormalizedRequest) error {
+	req.SetNetwork(n)
+
+	cached, err := n.cacheDal.Get(ctx, req)
+	if err == nil && cached != nil && !cached.IsObjectNull() && !cached.IsResultEmptyish() {
+		return nil
+	}
+
+	resp, err := n.Forward(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	// Forward() stores the response asynchronously, so we store it here as well to make sure
+	// the entry is available as soon as warming is finished.
+	return n.cacheDal.Set(ctx, req, resp)
+}
+
+func (n *Network) EvmIsBlockFinalized(blockNumber int64) (bool, error) {
+	return common.EvmIsBlockFinalizedByResolver(n.BlockResolver(), n.NetworkId, blockNumber)
+}
+
+// BlockResolver returns the resolver used for head/finalized block heights of this network,
+// which by default is backed by the evm state pollers of its upstreams.
+func (n *Network) BlockResolver() common.BlockResolver {
+	if n.blockResolver == nil {
+		return newEvmStatePollerBlockResolver(n)
 	}
-	return false, nil
+	return n.blockResolver
 }
 
 func (n *Network) Config() *common.NetworkConfig {
@@ -391,6 +575,83 @@ func (n *Network) shouldHandleMethod(method string, upsList []*upstream.Upstream
 	return nil
 }
 
+// filterArchiveUpstreams restricts candidates to archive-capable upstreams when the request reads state
+// older than what full nodes retain, so that it never lands on a full node that would fail with pruned state.
+func (n *Network) filterArchiveUpstreams(req *common.NormalizedRequest, method string, upsList []*upstream.Upstream) ([]*upstream.Upstream, error) {
+	if n.Architecture() != common.ArchitectureEvm {
+		return upsList, nil
+	}
+
+	jrq, err := req.JsonRpcRequest()
+	if err != nil {
+		return upsList, nil
+	}
+	_, blockNumber, _, kind, err := jrq.BlockRef()
+	if err != nil || kind != common.BlockRefKindNumber || blockNumber <= 0 {
+		return upsList, nil
+	}
+	headBlock, err := n.BlockResolver().HeadHeight(n.NetworkId)
+	if err != nil || !common.EvmRequiresArchiveNode(method, blockNumber, headBlock) {
+		return upsList, nil
+	}
+
+	archiveList := make([]*upstream.Upstream, 0, len(upsList))
+	for _, u := range upsList {
+		cfg := u.Config()
+		if u.Capability(upstream.CapabilityArchive) == upstream.CapabilityUnsupported {
+			continue
+		}
+		if cfg.Evm == nil || cfg.Evm.NodeType.IsArchiveCapable() {
+			archiveList = append(archiveList, u)
+		}
+	}
+	if len(archiveList) == 0 {
+		return nil, common.NewErrNoArchiveUpstream(n.NetworkId, blockNumber, headBlock)
+	}
+
+	return archiveList, nil
+}
+
+// failOrServeStale responds with a stale cache entry when enabled and available, otherwise with the error.
+// Stale responses are neither stored in cache again nor used to update state pollers.
+// waitForInFlight waits for the response of a similar in-flight request, reporting whether it can be shared
+// with this request. Partial responses are not shared, so that the request is forwarded on its own instead.
+func (n *Network) waitForInFlight(ctx context.Context, inf *Multiplexer, req *common.NormalizedRequest, startTime time.Time) (*common.NormalizedResponse, bool, error) {
+	select {
+	case <-inf.done:
+	case <-ctx.Done():
+		err := ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, true, common.NewErrNetworkRequestTimeout(time.Since(startTime))
+		}
+		return nil, true, err
+	}
+
+	inf.mu.RLock()
+	defer inf.mu.RUnlock()
+	if inf.resp != nil && inf.resp.IsPartial() {
+		return nil, false, nil
+	}
+	resp, err := common.CopyResponseForRequest(inf.resp, req)
+	if err != nil {
+		return nil, true, err
+	}
+	return resp, true, inf.err
+}
+
+func (n *Network) failOrServeStale(ctx context.Context, req *common.NormalizedRequest, method string, inf *Multiplexer, err error) (*common.NormalizedResponse, error) {
+	if stale := n.staleResponseOnError(ctx, req, method, err); stale != nil {
+		if inf != nil {
+			inf.Close(stale, nil)
+		}
+		return stale, nil
+	}
+	if inf != nil {
+		inf.Close(nil, err)
+	}
+	return nil, err
+}
+
 func (n *Network) enrichStatePoller(method string, req *common.NormalizedRequest, resp *common.NormalizedResponse) {
 	switch n.Architecture() {
 	case common.ArchitectureEvm:
@@ -454,6 +715,8 @@ func (n *Network) normalizeResponse(req *common.NormalizedRequest, resp *common.
 				err,
 				nil,
 			)
+		} else if common.HasErrorCode(err, common.ErrCodeJsonRpcRequestInvalid) {
+			return resp, common.TranslateToJsonRpcException(err)
 		}
 
 		return resp, common.NewErrJsonRpcExceptionInternal(
@@ -468,6 +731,15 @@ func (n *Network) normalizeResponse(req *common.NormalizedRequest, resp *common.
 	}
 }
 
+// acquireProjectPermit charges the project budget for a request fanned out by the network (aggregate items or
+// blocks of a receipts range), since the project does not charge the fan-out request itself.
+func (n *Network) acquireProjectPermit(req *common.NormalizedRequest) error {
+	if n.projectRateLimit == nil {
+		return nil
+	}
+	return n.projectRateLimit(req)
+}
+
 func (n *Network) acquireRateLimitPermit(req *common.NormalizedRequest) error {
 	if n.cfg.RateLimitBudget == "" {
 		return nil
@@ -504,6 +776,8 @@ func (n *Network) acquireRateLimitPermit(req *common.NormalizedRequest) error {
 					n.NetworkId,
 					n.cfg.RateLimitBudget,
 					fmt.Sprintf("%+v", rule.Config),
+					rule.Config.MaxCount,
+					rule.ResetIn(),
 				)
 			} else {
 				lg.Debug().Object("rateLimitRule", rule.Config).Msgf("network-level rate limit passed")
@@ -46,10 +46,26 @@ func NewNetwork(
 ) (*Network, error) {
 	lg := logger.With().Str("networkId", nwCfg.NetworkId()).Logger()
 
+	routingRules, err := newRoutingRules(nwCfg.RoutingRules)
+	if err != nil {
+		return nil, err
+	}
+	serveStale, err := newServeStalePolicy(nwCfg.ServeStaleOnError)
+	if err != nil {
+		return nil, err
+	}
+	deprecated, err := newDeprecatedMethods(nwCfg.DeprecatedMethods)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateConsensusConfig(nwCfg.Consensus); err != nil {
+		return nil, err
+	}
+
 	var policies []failsafe.Policy[*common.NormalizedResponse]
 	if nwCfg.Failsafe != nil {
 		key := fmt.Sprintf("%s-%s", prjId, nwCfg.NetworkId())
-		pls, err := upstream.CreateFailSafePolicies(&lg, upstream.ScopeNetwork, key, nwCfg.Failsafe)
+		pls, err := upstream.CreateFailSafePolicies(&lg, upstream.ScopeNetwork, key, nwCfg.Failsafe, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -67,16 +83,43 @@ func NewNetwork(
 		metricsTracker:       metricsTracker,
 		rateLimitersRegistry: rateLimitersRegistry,
 
-		inFlightMutex:    &sync.Mutex{},
-		inFlightRequests: make(map[string]*Multiplexer),
-		failsafePolicies: policies,
-		failsafeExecutor: failsafe.NewExecutor(policies...),
+		inFlightMutex:     &sync.Mutex{},
+		inFlightRequests:  make(map[string]*Multiplexer),
+		failsafePolicies:  policies,
+		failsafeExecutor:  failsafe.NewExecutor(policies...),
+		retryBudget:       newRetryBudget(nwCfg.RetryBudget),
+		routingRules:      routingRules,
+		deprecatedMethods: deprecated,
+		serveStale:        serveStale,
 	}
 
+	network.blockResolver = newEvmStatePollerBlockResolver(network)
+
 	if nwCfg.Architecture == "" {
 		nwCfg.Architecture = common.ArchitectureEvm
 	}
 
+	if nwCfg.Architecture == common.ArchitectureEvm {
+		var psCfg *common.PollSubscriptionsConfig
+		if nwCfg.Evm != nil {
+			psCfg = nwCfg.Evm.PollSubscriptions
+			network.logsBloom = newEvmLogsBloomIndex(nwCfg.Evm.LogsBloom)
+			network.finalizedPrewarm = newEvmFinalizedPrewarmer(network, nwCfg.Evm.FinalizedPrewarm)
+			switch nwCfg.Evm.DefaultBlockTag {
+			case "", "latest", "pending", "safe", "finalized":
+			default:
+				return nil, common.NewErrInvalidConfig(fmt.Sprintf("invalid evm.defaultBlockTag for network %s: %s (must be latest, pending, safe or finalized)", nwCfg.NetworkId(), nwCfg.Evm.DefaultBlockTag))
+			}
+		}
+		network.realtimeTTL, err = evmRealtimeTTL(nwCfg.Evm)
+		if err != nil {
+			return nil, err
+		}
+		if psCfg != nil {
+			network.pollSubscriptions = newEvmPollSubscriptions(network, psCfg)
+		}
+	}
+
 	return network, nil
 }
 
@@ -99,7 +142,11 @@ func (r *NetworksRegistry) RegisterNetwork(
 	switch nwCfg.Architecture {
 	case "evm":
 		if r.evmJsonRpcCache != nil {
-			network.cacheDal = r.evmJsonRpcCache.WithNetwork(network)
+			cache, err := r.evmJsonRpcCache.WithNetwork(network).WithProjectPolicies(prjCfg.CachePolicies)
+			if err != nil {
+				return nil, err
+			}
+			network.cacheDal = cache
 		}
 	default:
 		return nil, errors.New("unknown network architecture")
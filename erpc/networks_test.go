@@ -3,14 +3,17 @@ package erpc
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -21,6 +24,7 @@ import (
 	"github.com/erpc/erpc/util"
 	"github.com/erpc/erpc/vendors"
 	"github.com/h2non/gock"
+	promUtil "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
@@ -5124,6 +5128,29 @@ func TestNetwork_Forward(t *testing.T) {
 }
 
 func TestNetwork_InFlightRequests(t *testing.T) {
+	t.Run("PartialResponsesAreNotShared", func(t *testing.T) {
+		network := &Network{}
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":2,"method":"eth_getLogs","params":[]}`))
+		_, err := req.JsonRpcRequest()
+		assert.NoError(t, err)
+
+		partial := NewMultiplexer()
+		partial.Close(common.NewNormalizedResponse().
+			WithBody([]byte(`{"jsonrpc":"2.0","id":1,"result":[]}`)).
+			WithMissingRanges([]string{"0x1-0x3e8"}), nil)
+		resp, shared, err := network.waitForInFlight(context.Background(), partial, req, time.Now())
+		assert.NoError(t, err)
+		assert.False(t, shared)
+		assert.Nil(t, resp)
+
+		complete := NewMultiplexer()
+		complete.Close(common.NewNormalizedResponse().WithBody([]byte(`{"jsonrpc":"2.0","id":1,"result":[]}`)), nil)
+		resp, shared, err = network.waitForInFlight(context.Background(), complete, req, time.Now())
+		assert.NoError(t, err)
+		assert.True(t, shared)
+		assert.NotNil(t, resp)
+	})
+
 	t.Run("MultipleSuccessfulConcurrentRequests", func(t *testing.T) {
 		resetGock()
 		defer resetGock()
@@ -5394,6 +5421,256 @@ func TestNetwork_InFlightRequests(t *testing.T) {
 	})
 }
 
+func TestNetwork_WarmCache(t *testing.T) {
+	t.Run("WarmsMissingEntriesAndServesFromCache", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupTestNetwork(t)
+		err := network.Bootstrap(context.Background())
+		assert.NoError(t, err)
+		for _, poller := range network.evmStatePollers {
+			poller.SuggestFinalizedBlock(100)
+			poller.SuggestLatestBlock(110)
+		}
+
+		cache, err := NewEvmJsonRpcCache(context.Background(), &log.Logger, &common.ConnectorConfig{
+			Driver: "memory",
+			Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+		})
+		assert.NoError(t, err)
+		network.cacheDal = cache.WithNetwork(network)
+
+		addresses := []string{"0x111", "0x222", "0x333"}
+		for _, addr := range addresses {
+			addr := addr
+			gock.New("http://rpc1.localhost").
+				Post("").
+				Times(1).
+				Filter(func(request *http.Request) bool {
+					body := safeReadBody(request)
+					return strings.Contains(body, "eth_getBalance") && strings.Contains(body, addr)
+				}).
+				Reply(200).
+				BodyString(`{"jsonrpc":"2.0","id":1,"result":"0x100"}`)
+		}
+
+		newReqs := func() []*common.NormalizedRequest {
+			reqs := []*common.NormalizedRequest{}
+			for _, addr := range addresses {
+				reqs = append(reqs, common.NewNormalizedRequest([]byte(
+					fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["%s","0x1"]}`, addr),
+				)))
+			}
+			return reqs
+		}
+
+		err = network.WarmCache(context.Background(), newReqs())
+		assert.NoError(t, err)
+
+		if left := anyTestMocksLeft(); left > 0 {
+			t.Errorf("Expected all test mocks to be consumed, got %v left", left)
+		}
+
+		// Warming again must not hit upstreams since all entries are cached already
+		err = network.WarmCache(context.Background(), newReqs())
+		assert.NoError(t, err)
+
+		for _, req := range newReqs() {
+			resp, err := network.Forward(context.Background(), req)
+			assert.NoError(t, err)
+			assert.True(t, resp.FromCache())
+		}
+	})
+
+	t.Run("RequestsExcludingUpstreamsAreNotServedFromCache", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupTestNetwork(t)
+		err := network.Bootstrap(context.Background())
+		assert.NoError(t, err)
+		for _, poller := range network.evmStatePollers {
+			poller.SuggestFinalizedBlock(100)
+			poller.SuggestLatestBlock(110)
+		}
+		cache, err := NewEvmJsonRpcCache(context.Background(), &log.Logger, &common.ConnectorConfig{
+			Driver: "memory",
+			Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+		})
+		assert.NoError(t, err)
+		network.cacheDal = cache.WithNetwork(network)
+
+		gock.New("http://rpc1.localhost").
+			Post("").
+			Times(2).
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getBalance")
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":"0x100"}`)
+
+		body := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x111","0x1"]}`)
+		err = network.WarmCache(context.Background(), []*common.NormalizedRequest{common.NewNormalizedRequest(body)})
+		assert.NoError(t, err)
+		resp, err := network.Forward(context.Background(), common.NewNormalizedRequest(body))
+		assert.NoError(t, err)
+		assert.True(t, resp.FromCache())
+
+		req := common.NewNormalizedRequest(body).WithDirectives(&common.RequestDirectives{ExcludeUpstream: "other-*"})
+		resp, err = network.Forward(context.Background(), req)
+		assert.NoError(t, err)
+		assert.False(t, resp.FromCache())
+
+		if left := anyTestMocksLeft(); left > 0 {
+			t.Errorf("Expected all test mocks to be consumed, got %v left", left)
+		}
+	})
+
+	t.Run("AggregatesErrorsWithoutAborting", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupTestNetwork(t)
+		cache, err := NewEvmJsonRpcCache(context.Background(), &log.Logger, &common.ConnectorConfig{
+			Driver: "memory",
+			Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+		})
+		assert.NoError(t, err)
+		network.cacheDal = cache.WithNetwork(network)
+
+		gock.New("http://rpc1.localhost").
+			Post("").
+			Times(2).
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getBalance")
+			}).
+			Reply(400).
+			BodyString(`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"invalid params"}}`)
+
+		reqs := []*common.NormalizedRequest{
+			common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x111","0x1"]}`)),
+			common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":2,"method":"eth_getBalance","params":["0x222","0x1"]}`)),
+		}
+		err = network.WarmCache(context.Background(), reqs)
+		assert.Error(t, err)
+		assert.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 2)
+	})
+}
+
+func TestNetwork_FinalizedPrewarm(t *testing.T) {
+	setup := func(t *testing.T, cfg *common.EvmFinalizedPrewarmConfig) (*Network, *evmFinalizedPrewarmer) {
+		network := setupTestNetwork(t)
+		assert.NoError(t, network.Bootstrap(context.Background()))
+		cache, err := NewEvmJsonRpcCache(context.Background(), &log.Logger, &common.ConnectorConfig{
+			Driver: "memory",
+			Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+		})
+		assert.NoError(t, err)
+		network.cacheDal = cache.WithNetwork(network)
+		// Block tracker mocks would answer every eth_getBlockByNumber, only the mocks of each test must be served
+		resetGock()
+		return network, newEvmFinalizedPrewarmer(network, cfg)
+	}
+	suggestFinalized := func(network *Network, blockNumber int64) {
+		for _, poller := range network.evmStatePollers {
+			poller.SuggestFinalizedBlock(blockNumber)
+			poller.SuggestLatestBlock(blockNumber + 10)
+		}
+	}
+	mockBlock := func(blockRef string) {
+		gock.New("http://rpc1.localhost").
+			Post("").
+			Times(1).
+			Filter(func(request *http.Request) bool {
+				body := safeReadBody(request)
+				return strings.Contains(body, "eth_getBlockByNumber") && strings.Contains(body, `"`+blockRef+`"`)
+			}).
+			Reply(200).
+			BodyString(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"result":{"number":"%s","hash":"0xabc"}}`, blockRef))
+	}
+
+	t.Run("AdvancingFinalizedHeightWarmsNewlyFinalizedBlocks", func(t *testing.T) {
+		defer resetGock()
+		network, pw := setup(t, &common.EvmFinalizedPrewarmConfig{})
+
+		// Blocks already finalized on startup are not warmed
+		suggestFinalized(network, 100)
+		pw.check(context.Background())
+		assert.Equal(t, 0, len(gock.Pending()))
+
+		mockBlock("0x65")
+		mockBlock("0x66")
+		suggestFinalized(network, 102)
+		pw.check(context.Background())
+		assert.True(t, gock.IsDone(), "expected newly finalized blocks to be fetched")
+
+		resp, err := network.Forward(context.Background(), common.NewNormalizedRequest([]byte(
+			`{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["0x65",false]}`,
+		)))
+		if assert.NoError(t, err) {
+			assert.True(t, resp.FromCache())
+		}
+	})
+
+	t.Run("OnlyRecentWindowIsWarmed", func(t *testing.T) {
+		defer resetGock()
+		network, pw := setup(t, &common.EvmFinalizedPrewarmConfig{Window: 2})
+
+		suggestFinalized(network, 100)
+		pw.check(context.Background())
+
+		mockBlock("0x3e7")
+		mockBlock("0x3e8")
+		suggestFinalized(network, 1000)
+		pw.check(context.Background())
+		assert.True(t, gock.IsDone(), "expected the two most recent finalized blocks to be fetched")
+		assert.Equal(t, int64(1000), pw.lastFinalized)
+	})
+}
+
+func TestNetwork_ImmutableCache(t *testing.T) {
+	t.Run("ChainIdServedFromCacheAfterFirstFetch", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupTestNetwork(t)
+		cache, err := NewEvmJsonRpcCache(context.Background(), &log.Logger, &common.ConnectorConfig{
+			Driver: "memory",
+			Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+		})
+		assert.NoError(t, err)
+		network.cacheDal = cache.WithNetwork(network)
+
+		gock.New("http://rpc1.localhost").
+			Post("").
+			Times(1).
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_chainId")
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":"0x7b"}`)
+
+		resp, err := network.Forward(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`)))
+		assert.NoError(t, err)
+		assert.False(t, resp.FromCache())
+
+		// Cache is populated asynchronously after forwarding
+		time.Sleep(100 * time.Millisecond)
+
+		resp, err = network.Forward(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":2,"method":"eth_chainId","params":[]}`)))
+		assert.NoError(t, err)
+		assert.True(t, resp.FromCache())
+		jrr, err := resp.JsonRpcResponse()
+		assert.NoError(t, err)
+		assert.Equal(t, `"0x7b"`, string(jrr.Result))
+
+		if left := anyTestMocksLeft(); left > 0 {
+			t.Errorf("Expected all test mocks to be consumed, got %v left", left)
+		}
+	})
+}
+
 func setupTestNetwork(t *testing.T) *Network {
 	t.Helper()
 
@@ -5484,3 +5761,2238 @@ func safeReadBody(request *http.Request) string {
 	request.Body = io.NopCloser(bytes.NewBuffer(body))
 	return string(body)
 }
+
+func TestNetwork_ArchiveAffinity(t *testing.T) {
+	const head = 0x1273c18
+
+	setupNetwork := func(t *testing.T, archiveNodeType common.EvmNodeType) *Network {
+		t.Helper()
+		setupMocksForEvmStatePoller()
+
+		rateLimitersRegistry, _ := upstream.NewRateLimitersRegistry(&common.RateLimiterConfig{}, &log.Logger)
+		metricsTracker := health.NewTracker("test", time.Minute)
+		upstreamsRegistry := upstream.NewUpstreamsRegistry(
+			&log.Logger,
+			"test",
+			[]*common.UpstreamConfig{
+				{
+					Type:     common.UpstreamTypeEvm,
+					Id:       "full",
+					Endpoint: "http://rpc1.localhost",
+					Evm: &common.EvmUpstreamConfig{
+						ChainId:  123,
+						NodeType: common.EvmNodeTypeFull,
+					},
+				},
+				{
+					Type:     common.UpstreamTypeEvm,
+					Id:       "archive",
+					Endpoint: "http://rpc2.localhost",
+					Evm: &common.EvmUpstreamConfig{
+						ChainId:  123,
+						NodeType: archiveNodeType,
+					},
+				},
+			},
+			rateLimitersRegistry,
+			vendors.NewVendorsRegistry(),
+			metricsTracker,
+			1*time.Second,
+		)
+		network, err := NewNetwork(
+			&log.Logger,
+			"test",
+			&common.NetworkConfig{
+				Architecture: common.ArchitectureEvm,
+				Evm: &common.EvmNetworkConfig{
+					ChainId: 123,
+				},
+			},
+			rateLimitersRegistry,
+			upstreamsRegistry,
+			metricsTracker,
+		)
+		assert.NoError(t, err)
+		network.blockResolver = &fakeBlockResolver{head: head}
+
+		assert.NoError(t, upstreamsRegistry.Bootstrap(context.Background()))
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, upstreamsRegistry.PrepareUpstreamsForNetwork(util.EvmNetworkId(123)))
+		time.Sleep(100 * time.Millisecond)
+
+		return network
+	}
+
+	mockGetBalance := func(host string, result string) {
+		gock.New(host).
+			Post("").
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getBalance")
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":"` + result + `"}`)
+	}
+
+	t.Run("HistoricalGetBalanceOnlyRoutesToArchiveUpstream", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, common.EvmNodeTypeArchive)
+		mockGetBalance("http://rpc1.localhost", "0xfull")
+		mockGetBalance("http://rpc2.localhost", "0xarchive")
+
+		req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x111","0x%x"]}`, head-1000)))
+		resp, err := network.Forward(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, "archive", resp.Upstream().Config().Id)
+
+		// The full node mock must never be hit
+		assert.Equal(t, 1, anyTestMocksLeft())
+	})
+
+	t.Run("HistoricalGetStorageAtOnlyRoutesToArchiveUpstream", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, common.EvmNodeTypeArchive)
+		for _, host := range []string{"http://rpc1.localhost", "http://rpc2.localhost"} {
+			gock.New(host).
+				Post("").
+				Filter(func(request *http.Request) bool {
+					return strings.Contains(safeReadBody(request), "eth_getStorageAt")
+				}).
+				Reply(200).
+				BodyString(`{"jsonrpc":"2.0","id":1,"result":"0x0"}`)
+		}
+
+		req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"eth_getStorageAt","params":["0x111","0x0","0x%x"]}`, head-1000)))
+		resp, err := network.Forward(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, "archive", resp.Upstream().Config().Id)
+		assert.Equal(t, 1, anyTestMocksLeft())
+	})
+
+	t.Run("RecentGetBalanceCanUseFullUpstream", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, common.EvmNodeTypeArchive)
+		mockGetBalance("http://rpc1.localhost", "0xfull")
+		mockGetBalance("http://rpc2.localhost", "0xarchive")
+
+		req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x111","0x%x"]}`, head-10)))
+		_, err := network.Forward(context.Background(), req)
+		assert.NoError(t, err)
+	})
+
+	t.Run("NoArchiveUpstreamAvailable", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, common.EvmNodeTypeFull)
+		mockGetBalance("http://rpc1.localhost", "0xfull")
+		mockGetBalance("http://rpc2.localhost", "0xfull")
+
+		req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x111","0x%x"]}`, head-1000)))
+		_, err := network.Forward(context.Background(), req)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeNoArchiveUpstream), "unexpected error: %v", err)
+		assert.Equal(t, 2, anyTestMocksLeft())
+	})
+}
+
+func TestNetwork_HedgeCancelsLosingUpstreamCall(t *testing.T) {
+	resetGock()
+	defer resetGock()
+
+	var traceCalls atomic.Int32
+	loserCancelled := make(chan struct{}, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "eth_traceTransaction") {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"number":"0x1"}}`))
+			return
+		}
+		if traceCalls.Add(1) == 1 {
+			// First attempt is slow, so a hedge is sent and wins, after which this one must be cancelled
+			select {
+			case <-r.Context().Done():
+				loserCancelled <- struct{}{}
+			case <-time.After(5 * time.Second):
+				_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"from":"slow"}}`))
+			}
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"from":"hedge"}}`))
+	})
+	srv1 := httptest.NewServer(handler)
+	defer srv1.Close()
+	srv2 := httptest.NewServer(handler)
+	defer srv2.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rlr, err := upstream.NewRateLimitersRegistry(&common.RateLimiterConfig{}, &log.Logger)
+	assert.NoError(t, err)
+	mt := health.NewTracker("prjA", 2*time.Second)
+	upr := upstream.NewUpstreamsRegistry(
+		&log.Logger,
+		"prjA",
+		[]*common.UpstreamConfig{
+			{
+				Type:     common.UpstreamTypeEvm,
+				Id:       "rpc1",
+				Endpoint: srv1.URL,
+				Evm:      &common.EvmUpstreamConfig{ChainId: 123},
+			},
+			{
+				Type:     common.UpstreamTypeEvm,
+				Id:       "rpc2",
+				Endpoint: srv2.URL,
+				Evm:      &common.EvmUpstreamConfig{ChainId: 123},
+			},
+		},
+		rlr,
+		vendors.NewVendorsRegistry(), mt, 1*time.Second,
+	)
+	assert.NoError(t, upr.Bootstrap(ctx))
+	assert.NoError(t, upr.PrepareUpstreamsForNetwork(util.EvmNetworkId(123)))
+
+	ntw, err := NewNetwork(
+		&log.Logger,
+		"prjA",
+		&common.NetworkConfig{
+			Architecture: common.ArchitectureEvm,
+			Evm: &common.EvmNetworkConfig{
+				ChainId: 123,
+			},
+			Failsafe: &common.FailsafeConfig{
+				Hedge: &common.HedgePolicyConfig{
+					Delay:    "100ms",
+					MaxCount: 1,
+				},
+			},
+		},
+		rlr,
+		upr,
+		mt,
+	)
+	assert.NoError(t, err)
+
+	req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_traceTransaction","params":["0x1273c18"]}`))
+	resp, err := ntw.Forward(ctx, req)
+	assert.NoError(t, err)
+	jrr, err := resp.JsonRpcResponse()
+	assert.NoError(t, err)
+	assert.Contains(t, string(jrr.Result), "hedge")
+
+	select {
+	case <-loserCancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the losing upstream call to be cancelled")
+	}
+}
+
+func TestNetwork_PollSubscriptions(t *testing.T) {
+	forward := func(t *testing.T, network *Network, method string, params ...interface{}) *common.JsonRpcResponse {
+		t.Helper()
+		body, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+		assert.NoError(t, err)
+		resp, err := network.Forward(context.Background(), common.NewNormalizedRequest(body))
+		assert.NoError(t, err)
+		jrr, err := resp.JsonRpcResponse()
+		assert.NoError(t, err)
+		return jrr
+	}
+
+	setupNetwork := func(t *testing.T, cfg *common.PollSubscriptionsConfig) (*Network, *atomic.Int64) {
+		t.Helper()
+		network, err := NewNetwork(
+			&log.Logger,
+			"test",
+			&common.NetworkConfig{
+				Architecture: common.ArchitectureEvm,
+				Evm: &common.EvmNetworkConfig{
+					ChainId:              123,
+					BlockTrackerInterval: "10ms",
+					PollSubscriptions:    cfg,
+				},
+			},
+			nil, nil, nil,
+		)
+		assert.NoError(t, err)
+
+		head := &atomic.Int64{}
+		network.blockResolver = &atomicHeadResolver{head: head}
+		network.pollSubscriptions.fetchBlock = func(ctx context.Context, blockRef string) (json.RawMessage, int64, error) {
+			bn, _ := common.HexToInt64(blockRef)
+			return json.RawMessage(fmt.Sprintf(`{"number":"0x%x"}`, bn)), bn, nil
+		}
+		return network, head
+	}
+
+	waitForTracker := func(t *testing.T, network *Network, head int64) {
+		t.Helper()
+		assert.Eventually(t, func() bool {
+			network.pollSubscriptions.mu.Lock()
+			defer network.pollSubscriptions.mu.Unlock()
+			return network.pollSubscriptions.lastHead == head
+		}, time.Second, 5*time.Millisecond)
+	}
+
+	t.Run("DrainsBufferedNewHeads", func(t *testing.T) {
+		network, head := setupNetwork(t, &common.PollSubscriptionsConfig{})
+		head.Store(100)
+
+		jrr := forward(t, network, "eth_subscribe", "newHeads")
+		assert.Nil(t, jrr.Error)
+		var sid string
+		assert.NoError(t, json.Unmarshal(jrr.Result, &sid))
+		assert.NotEmpty(t, sid)
+		waitForTracker(t, network, 100)
+
+		head.Store(102)
+		waitForTracker(t, network, 102)
+
+		jrr = forward(t, network, pollSubscriptionMethod, sid, 1000)
+		assert.Nil(t, jrr.Error)
+		var res pollSubscriptionResult
+		assert.NoError(t, json.Unmarshal(jrr.Result, &res))
+		assert.Equal(t, sid, res.Subscription)
+		assert.Equal(t, 0, res.Dropped)
+		if assert.Len(t, res.Events, 2) {
+			assert.JSONEq(t, `{"number":"0x65"}`, string(res.Events[0]))
+			assert.JSONEq(t, `{"number":"0x66"}`, string(res.Events[1]))
+		}
+
+		// Buffer is empty after draining, so the next poll returns nothing once the wait elapses
+		jrr = forward(t, network, pollSubscriptionMethod, sid, 20)
+		assert.NoError(t, json.Unmarshal(jrr.Result, &res))
+		assert.Empty(t, res.Events)
+
+		jrr = forward(t, network, "eth_unsubscribe", sid)
+		assert.Equal(t, "true", string(jrr.Result))
+	})
+
+	t.Run("IdenticalSubscriptionsShareOneUpstreamFetch", func(t *testing.T) {
+		network, head := setupNetwork(t, &common.PollSubscriptionsConfig{})
+		ps := network.pollSubscriptions
+		var blockFetches atomic.Int32
+		fetchBlock := ps.fetchBlock
+		ps.fetchBlock = func(ctx context.Context, blockRef string) (json.RawMessage, int64, error) {
+			blockFetches.Add(1)
+			return fetchBlock(ctx, blockRef)
+		}
+		var logsFetchesMu sync.Mutex
+		logsFetches := map[string]int{}
+		ps.fetchLogs = func(ctx context.Context, filter map[string]interface{}) ([]json.RawMessage, error) {
+			logsFetchesMu.Lock()
+			defer logsFetchesMu.Unlock()
+			logsFetches[fmt.Sprint(filter["address"])]++
+			return []json.RawMessage{json.RawMessage(fmt.Sprintf(`{"address":"%v","blockNumber":"%v"}`, filter["address"], filter["toBlock"]))}, nil
+		}
+		head.Store(100)
+
+		subscribe := func(params ...interface{}) string {
+			jrr := forward(t, network, "eth_subscribe", params...)
+			var sid string
+			assert.NoError(t, json.Unmarshal(jrr.Result, &sid))
+			return sid
+		}
+		heads1 := subscribe("newHeads")
+		heads2 := subscribe("newHeads")
+		logsA1 := subscribe("logs", map[string]interface{}{"address": "0xaaaa", "topics": []interface{}{"0x01"}})
+		logsA2 := subscribe("logs", map[string]interface{}{"topics": []interface{}{"0x01"}, "address": "0xaaaa"})
+		logsB := subscribe("logs", map[string]interface{}{"address": "0xbbbb"})
+		waitForTracker(t, network, 100)
+
+		head.Store(102)
+		for _, sid := range []string{heads1, heads2} {
+			jrr := forward(t, network, pollSubscriptionMethod, sid, 1000)
+			var res pollSubscriptionResult
+			assert.NoError(t, json.Unmarshal(jrr.Result, &res))
+			assert.Len(t, res.Events, 2, sid)
+		}
+		for _, sid := range []string{logsA1, logsA2, logsB} {
+			jrr := forward(t, network, pollSubscriptionMethod, sid, 1000)
+			var res pollSubscriptionResult
+			assert.NoError(t, json.Unmarshal(jrr.Result, &res))
+			assert.Len(t, res.Events, 1, sid)
+		}
+
+		assert.Equal(t, int32(2), blockFetches.Load(), "each new block is fetched once for all newHeads subscribers")
+		logsFetchesMu.Lock()
+		assert.Equal(t, map[string]int{"0xaaaa": 1, "0xbbbb": 1}, logsFetches, "logs are fetched once per distinct filter")
+		logsFetchesMu.Unlock()
+
+		// The shared tracker stops once the last subscriber is gone
+		for _, sid := range []string{heads1, heads2, logsA1, logsA2, logsB} {
+			forward(t, network, "eth_unsubscribe", sid)
+		}
+		ps.mu.Lock()
+		assert.Nil(t, ps.stopTracker)
+		ps.mu.Unlock()
+	})
+
+	t.Run("DropsOldestEventsWhenBufferIsFull", func(t *testing.T) {
+		network, head := setupNetwork(t, &common.PollSubscriptionsConfig{MaxBufferSize: 2})
+		head.Store(100)
+
+		jrr := forward(t, network, "eth_subscribe", "newHeads")
+		var sid string
+		assert.NoError(t, json.Unmarshal(jrr.Result, &sid))
+		waitForTracker(t, network, 100)
+
+		head.Store(105)
+		waitForTracker(t, network, 105)
+
+		jrr = forward(t, network, pollSubscriptionMethod, sid)
+		var res pollSubscriptionResult
+		assert.NoError(t, json.Unmarshal(jrr.Result, &res))
+		assert.Equal(t, 3, res.Dropped)
+		if assert.Len(t, res.Events, 2) {
+			assert.JSONEq(t, `{"number":"0x68"}`, string(res.Events[0]))
+			assert.JSONEq(t, `{"number":"0x69"}`, string(res.Events[1]))
+		}
+	})
+
+	t.Run("UnknownSubscriptionReturnsNotFound", func(t *testing.T) {
+		network, _ := setupNetwork(t, &common.PollSubscriptionsConfig{})
+
+		body := []byte(`{"jsonrpc":"2.0","id":1,"method":"erpc_pollSubscription","params":["0x1234"]}`)
+		_, err := network.Forward(context.Background(), common.NewNormalizedRequest(body))
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeSubscriptionNotFound))
+
+		jrr := forward(t, network, "eth_unsubscribe", "0x1234")
+		assert.Equal(t, "false", string(jrr.Result))
+	})
+
+	t.Run("DisabledUnlessConfigured", func(t *testing.T) {
+		network, err := NewNetwork(
+			&log.Logger,
+			"test",
+			&common.NetworkConfig{
+				Architecture: common.ArchitectureEvm,
+				Evm:          &common.EvmNetworkConfig{ChainId: 123},
+			},
+			nil, nil, nil,
+		)
+		assert.NoError(t, err)
+		assert.Nil(t, network.pollSubscriptions)
+	})
+
+	t.Run("SubscriptionsAreCappedPerClientAndNetwork", func(t *testing.T) {
+		network, _ := setupNetwork(t, &common.PollSubscriptionsConfig{MaxSubscriptions: 3, MaxSubscriptionsPerClient: 2})
+		subscribe := func(client string) error {
+			ctx := context.WithValue(context.Background(), pollSubscriptionClientContext, client)
+			body := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_subscribe","params":["newHeads"]}`)
+			_, err := network.Forward(ctx, common.NewNormalizedRequest(body))
+			return err
+		}
+
+		assert.NoError(t, subscribe("10.0.0.1"))
+		assert.NoError(t, subscribe("10.0.0.1"))
+		err := subscribe("10.0.0.1")
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeSubscriptionLimitExceeded), err)
+
+		assert.NoError(t, subscribe("10.0.0.2"))
+		err = subscribe("10.0.0.3")
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeSubscriptionLimitExceeded), err)
+	})
+}
+
+// atomicHeadResolver reports a head that tests can move while state pollers are not running.
+type atomicHeadResolver struct {
+	fakeBlockResolver
+	head *atomic.Int64
+}
+
+func (r *atomicHeadResolver) HeadHeight(networkId string) (int64, error) {
+	if h := r.head.Load(); h > 0 {
+		return h, nil
+	}
+	return 0, common.NewErrFinalizedBlockUnavailable(0)
+}
+
+func TestNetwork_ShadowComparison(t *testing.T) {
+	setupNetwork := func(t *testing.T, sampleRate float64, stripFields []*common.StripResultFieldsConfig) *Network {
+		t.Helper()
+		setupMocksForEvmStatePoller()
+
+		rateLimitersRegistry, _ := upstream.NewRateLimitersRegistry(&common.RateLimiterConfig{}, &log.Logger)
+		metricsTracker := health.NewTracker("test", time.Minute)
+		upstreamsRegistry := upstream.NewUpstreamsRegistry(
+			&log.Logger,
+			"test",
+			[]*common.UpstreamConfig{
+				{
+					Type:     common.UpstreamTypeEvm,
+					Id:       "shadowed",
+					Endpoint: "http://rpc1.localhost",
+					Evm: &common.EvmUpstreamConfig{
+						ChainId: 123,
+					},
+					Shadow: &common.ShadowConfig{
+						SampleRate:        sampleRate,
+						ReferenceUpstream: "reference",
+					},
+				},
+				{
+					Type:     common.UpstreamTypeEvm,
+					Id:       "reference",
+					Endpoint: "http://rpc2.localhost",
+					Evm: &common.EvmUpstreamConfig{
+						ChainId: 123,
+					},
+				},
+			},
+			rateLimitersRegistry,
+			vendors.NewVendorsRegistry(),
+			metricsTracker,
+			1*time.Second,
+		)
+		network, err := NewNetwork(
+			&log.Logger,
+			"test",
+			&common.NetworkConfig{
+				Architecture: common.ArchitectureEvm,
+				Evm: &common.EvmNetworkConfig{
+					ChainId: 123,
+				},
+				StripResultFields: stripFields,
+			},
+			rateLimitersRegistry,
+			upstreamsRegistry,
+			metricsTracker,
+		)
+		assert.NoError(t, err)
+
+		assert.NoError(t, upstreamsRegistry.Bootstrap(context.Background()))
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, upstreamsRegistry.PrepareUpstreamsForNetwork(util.EvmNetworkId(123)))
+		time.Sleep(100 * time.Millisecond)
+
+		return network
+	}
+
+	mockResult := func(host string, result string) {
+		gock.New(host).
+			Post("").
+			Persist().
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getTransactionReceipt")
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":` + result + `}`)
+	}
+
+	// Upstreams are picked in random order when they have no score yet, so send until the shadowed one serves
+	forwardUntilShadowedServes := func(t *testing.T, network *Network) *common.NormalizedResponse {
+		t.Helper()
+		for i := 0; i < 30; i++ {
+			req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getTransactionReceipt","params":["0xabc"]}`))
+			resp, err := network.Forward(context.Background(), req)
+			assert.NoError(t, err)
+			if resp != nil && resp.Upstream().Config().Id == "shadowed" {
+				return resp
+			}
+		}
+		t.Fatal("shadowed upstream never served the request")
+		return nil
+	}
+
+	labels := []string{"test", util.EvmNetworkId(123), "shadowed", "eth_getTransactionReceipt", "reference"}
+
+	t.Run("LyingUpstreamIncrementsMismatchCounter", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 1, nil)
+		mockResult("http://rpc1.localhost", `{"status":"0x0","blockNumber":"0x10"}`)
+		mockResult("http://rpc2.localhost", `{"status":"0x1","blockNumber":"0x10"}`)
+
+		comparisons := promUtil.ToFloat64(health.MetricUpstreamShadowComparisonTotal.WithLabelValues(labels...))
+		mismatches := promUtil.ToFloat64(health.MetricUpstreamShadowMismatchTotal.WithLabelValues(labels...))
+
+		resp := forwardUntilShadowedServes(t, network)
+
+		// The served response is the one from the shadowed upstream, comparison never changes it
+		jrr, err := resp.JsonRpcResponse()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"status":"0x0","blockNumber":"0x10"}`, string(jrr.Result))
+
+		assert.Eventually(t, func() bool {
+			return promUtil.ToFloat64(health.MetricUpstreamShadowMismatchTotal.WithLabelValues(labels...)) > mismatches
+		}, 2*time.Second, 10*time.Millisecond)
+		assert.Greater(t, promUtil.ToFloat64(health.MetricUpstreamShadowComparisonTotal.WithLabelValues(labels...)), comparisons)
+	})
+
+	t.Run("EquivalentResponsesAreNotMismatches", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 1, nil)
+		mockResult("http://rpc1.localhost", `{"status":"0x1","blockHash":"0xABCD"}`)
+		mockResult("http://rpc2.localhost", `{ "blockHash": "0xabcd", "status": "0x1" }`)
+
+		comparisons := promUtil.ToFloat64(health.MetricUpstreamShadowComparisonTotal.WithLabelValues(labels...))
+		mismatches := promUtil.ToFloat64(health.MetricUpstreamShadowMismatchTotal.WithLabelValues(labels...))
+
+		forwardUntilShadowedServes(t, network)
+
+		assert.Eventually(t, func() bool {
+			return promUtil.ToFloat64(health.MetricUpstreamShadowComparisonTotal.WithLabelValues(labels...)) > comparisons
+		}, 2*time.Second, 10*time.Millisecond)
+		assert.Equal(t, mismatches, promUtil.ToFloat64(health.MetricUpstreamShadowMismatchTotal.WithLabelValues(labels...)))
+	})
+
+	t.Run("ZeroSampleRateNeverCompares", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0, nil)
+		mockResult("http://rpc1.localhost", `{"status":"0x0"}`)
+		mockResult("http://rpc2.localhost", `{"status":"0x1"}`)
+
+		comparisons := promUtil.ToFloat64(health.MetricUpstreamShadowComparisonTotal.WithLabelValues(labels...))
+
+		forwardUntilShadowedServes(t, network)
+		time.Sleep(100 * time.Millisecond)
+
+		assert.Equal(t, comparisons, promUtil.ToFloat64(health.MetricUpstreamShadowComparisonTotal.WithLabelValues(labels...)))
+	})
+
+	t.Run("StrippedProviderMetadataIsNotAMismatch", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 1, []*common.StripResultFieldsConfig{
+			{Method: "eth_getTransaction*", Fields: []string{"_meta", "logs.servedAt"}},
+		})
+		mockResult("http://rpc1.localhost", `{"status":"0x1","_meta":{"node":"a-7"},"logs":[{"logIndex":"0x0","servedAt":1}]}`)
+		mockResult("http://rpc2.localhost", `{"status":"0x1","_meta":{"node":"b-2"},"logs":[{"logIndex":"0x0","servedAt":2}]}`)
+
+		comparisons := promUtil.ToFloat64(health.MetricUpstreamShadowComparisonTotal.WithLabelValues(labels...))
+		mismatches := promUtil.ToFloat64(health.MetricUpstreamShadowMismatchTotal.WithLabelValues(labels...))
+
+		resp := forwardUntilShadowedServes(t, network)
+
+		// Stripping only applies to comparison, the client still gets the full result
+		jrr, err := resp.JsonRpcResponse()
+		assert.NoError(t, err)
+		assert.Contains(t, string(jrr.Result), "a-7")
+
+		assert.Eventually(t, func() bool {
+			return promUtil.ToFloat64(health.MetricUpstreamShadowComparisonTotal.WithLabelValues(labels...)) > comparisons
+		}, 2*time.Second, 10*time.Millisecond)
+		assert.Equal(t, mismatches, promUtil.ToFloat64(health.MetricUpstreamShadowMismatchTotal.WithLabelValues(labels...)))
+	})
+
+	t.Run("WriteMethodsAreNeverShadowed", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 1, nil)
+		var referenceHits atomic.Int32
+		for host, hits := range map[string]*atomic.Int32{"http://rpc1.localhost": nil, "http://rpc2.localhost": &referenceHits} {
+			hits := hits
+			gock.New(host).
+				Post("").
+				Persist().
+				Filter(func(request *http.Request) bool {
+					if !strings.Contains(safeReadBody(request), "eth_sendRawTransaction") {
+						return false
+					}
+					if hits != nil {
+						hits.Add(1)
+					}
+					return true
+				}).
+				Reply(200).
+				BodyString(`{"jsonrpc":"2.0","id":1,"result":"0x1111111111111111111111111111111111111111111111111111111111111111"}`)
+		}
+
+		// The reference upstream may serve some requests itself, it must not receive any other copy
+		servedByReference := int32(0)
+		for i := 0; i < 30; i++ {
+			req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["0xabc"]}`))
+			resp, err := network.Forward(context.Background(), req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			if resp.Upstream().Config().Id == "reference" {
+				servedByReference++
+				continue
+			}
+			break
+		}
+		time.Sleep(200 * time.Millisecond)
+
+		assert.Equal(t, servedByReference, referenceHits.Load())
+	})
+}
+
+func TestNetwork_VerifyEmptyLogs(t *testing.T) {
+	setupNetwork := func(t *testing.T) *Network {
+		t.Helper()
+		setupMocksForEvmStatePoller()
+
+		rateLimitersRegistry, _ := upstream.NewRateLimitersRegistry(&common.RateLimiterConfig{}, &log.Logger)
+		metricsTracker := health.NewTracker("test", time.Minute)
+		upstreamsRegistry := upstream.NewUpstreamsRegistry(
+			&log.Logger,
+			"test",
+			[]*common.UpstreamConfig{
+				{
+					Type:     common.UpstreamTypeEvm,
+					Id:       "empty",
+					Endpoint: "http://rpc1.localhost",
+					Evm: &common.EvmUpstreamConfig{
+						ChainId: 123,
+					},
+				},
+				{
+					Type:     common.UpstreamTypeEvm,
+					Id:       "populated",
+					Endpoint: "http://rpc2.localhost",
+					Evm: &common.EvmUpstreamConfig{
+						ChainId: 123,
+					},
+				},
+			},
+			rateLimitersRegistry,
+			vendors.NewVendorsRegistry(),
+			metricsTracker,
+			1*time.Second,
+		)
+		network, err := NewNetwork(
+			&log.Logger,
+			"test",
+			&common.NetworkConfig{
+				Architecture: common.ArchitectureEvm,
+				Evm: &common.EvmNetworkConfig{
+					ChainId:         123,
+					VerifyEmptyLogs: &common.EvmVerifyEmptyLogsConfig{},
+				},
+			},
+			rateLimitersRegistry,
+			upstreamsRegistry,
+			metricsTracker,
+		)
+		assert.NoError(t, err)
+
+		assert.NoError(t, upstreamsRegistry.Bootstrap(context.Background()))
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, upstreamsRegistry.PrepareUpstreamsForNetwork(util.EvmNetworkId(123)))
+		// State pollers tell how far behind the head the requested range is
+		assert.NoError(t, network.Bootstrap(context.Background()))
+		time.Sleep(100 * time.Millisecond)
+		network.evmStatePollers["empty"].SuggestLatestBlock(0x1273c18)
+		network.evmStatePollers["populated"].SuggestLatestBlock(0x1273c18)
+
+		return network
+	}
+
+	mockLogs := func(host string, result string) {
+		gock.New(host).
+			Post("").
+			Persist().
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getLogs")
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":` + result + `}`)
+	}
+
+	twoLogs := `[{"address":"0x1111111111111111111111111111111111111111","blockNumber":"0x100","logIndex":"0x0"},` +
+		`{"address":"0x1111111111111111111111111111111111111111","blockNumber":"0x100","logIndex":"0x1"}]`
+	labels := []string{"test", util.EvmNetworkId(123), "empty", "populated"}
+
+	t.Run("NonEmptyResultWins", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t)
+		mockLogs("http://rpc1.localhost", `[]`)
+		mockLogs("http://rpc2.localhost", twoLogs)
+
+		discrepancies := promUtil.ToFloat64(health.MetricUpstreamEmptyLogsDiscrepancyTotal.WithLabelValues(labels...))
+
+		// Upstreams are picked in random order when they have no score yet, so send until the empty one serves first
+		for i := 0; i < 30; i++ {
+			req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"fromBlock":"0x100","toBlock":"0x100"}]}`))
+			resp, err := network.Forward(context.Background(), req)
+			assert.NoError(t, err)
+
+			jrr, err := resp.JsonRpcResponse()
+			assert.NoError(t, err)
+			assert.JSONEq(t, twoLogs, string(jrr.Result))
+			assert.Equal(t, "populated", resp.UpstreamId())
+
+			if promUtil.ToFloat64(health.MetricUpstreamEmptyLogsDiscrepancyTotal.WithLabelValues(labels...)) > discrepancies {
+				return
+			}
+		}
+		t.Fatal("empty upstream never served the request first")
+	})
+
+	t.Run("FreshRangeIsNotVerified", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t)
+		mockLogs("http://rpc1.localhost", `[]`)
+		mockLogs("http://rpc2.localhost", twoLogs)
+
+		verifications := promUtil.ToFloat64(health.MetricUpstreamEmptyLogsVerificationTotal.WithLabelValues(labels...))
+
+		// Head is 0x1273c18, so this range is within the default min depth
+		for i := 0; i < 30; i++ {
+			req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"fromBlock":"0x1273c10","toBlock":"0x1273c18"}]}`))
+			resp, err := network.Forward(context.Background(), req)
+			assert.NoError(t, err)
+
+			if resp.UpstreamId() == "empty" {
+				jrr, err := resp.JsonRpcResponse()
+				assert.NoError(t, err)
+				assert.Equal(t, `[]`, string(jrr.Result))
+				assert.Equal(t, verifications, promUtil.ToFloat64(health.MetricUpstreamEmptyLogsVerificationTotal.WithLabelValues(labels...)))
+				return
+			}
+		}
+		t.Fatal("empty upstream never served the request")
+	})
+
+	t.Run("UpstreamThatAlreadyReturnedEmptyIsNotUsedAsVerifier", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t)
+		mockLogs("http://rpc2.localhost", twoLogs)
+
+		upsList, err := network.upstreamsRegistry.GetSortedUpstreams(util.EvmNetworkId(123), "eth_getLogs")
+		assert.NoError(t, err)
+		var served *upstream.Upstream
+		for _, u := range upsList {
+			if u.Config().Id == "empty" {
+				served = u
+			}
+		}
+
+		// A retry on empty already got [] from "populated" before "empty" served the final response
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"fromBlock":"0x100","toBlock":"0x100"}]}`))
+		req.AddUpstreamAttempt(common.UpstreamAttempt{Upstream: "populated", Outcome: "success", Empty: true})
+		req.AddUpstreamAttempt(common.UpstreamAttempt{Upstream: "empty", Outcome: "success", Empty: true})
+		resp := common.NewNormalizedResponse().WithRequest(req).WithBody([]byte(`{"jsonrpc":"2.0","id":1,"result":[]}`)).SetUpstream(served)
+
+		verifications := promUtil.ToFloat64(health.MetricUpstreamEmptyLogsVerificationTotal.WithLabelValues(labels...))
+		vresp := network.verifyEmptyLogs(context.Background(), req, "eth_getLogs", resp, upsList, map[string]error{})
+
+		assert.Equal(t, resp, vresp)
+		assert.Equal(t, verifications, promUtil.ToFloat64(health.MetricUpstreamEmptyLogsVerificationTotal.WithLabelValues(labels...)))
+		assert.Len(t, req.UpstreamAttempts(), 2)
+	})
+}
+
+func TestNetwork_RetryBudget(t *testing.T) {
+	const requests = 100
+
+	run := func(t *testing.T, budget *common.RetryBudgetConfig) (int32, int) {
+		t.Helper()
+		resetGock()
+		defer resetGock()
+
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"internal error"}}`))
+		}))
+		defer srv.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		rlr, err := upstream.NewRateLimitersRegistry(&common.RateLimiterConfig{}, &log.Logger)
+		assert.NoError(t, err)
+		mt := health.NewTracker("prjA", 2*time.Second)
+		upr := upstream.NewUpstreamsRegistry(
+			&log.Logger,
+			"prjA",
+			[]*common.UpstreamConfig{
+				{
+					Type:     common.UpstreamTypeEvm,
+					Id:       "rpc1",
+					Endpoint: srv.URL,
+					Evm:      &common.EvmUpstreamConfig{ChainId: 123},
+				},
+			},
+			rlr,
+			vendors.NewVendorsRegistry(), mt, 1*time.Second,
+		)
+		assert.NoError(t, upr.Bootstrap(ctx))
+		assert.NoError(t, upr.PrepareUpstreamsForNetwork(util.EvmNetworkId(123)))
+
+		ntw, err := NewNetwork(
+			&log.Logger,
+			"prjA",
+			&common.NetworkConfig{
+				Architecture: common.ArchitectureEvm,
+				Evm: &common.EvmNetworkConfig{
+					ChainId: 123,
+				},
+				Failsafe: &common.FailsafeConfig{
+					Retry: &common.RetryPolicyConfig{
+						MaxAttempts: 3,
+					},
+				},
+				RetryBudget: budget,
+			},
+			rlr,
+			upr,
+			mt,
+		)
+		assert.NoError(t, err)
+
+		budgetErrors := 0
+		for i := 0; i < requests; i++ {
+			req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"eth_getBalance","params":["0x%x","latest"]}`, i, i)))
+			_, err := ntw.Forward(ctx, req)
+			assert.Error(t, err)
+			if common.HasErrorCode(err, common.ErrCodeRetryBudgetExhausted) {
+				budgetErrors++
+			}
+		}
+
+		return calls.Load(), budgetErrors
+	}
+
+	t.Run("WithoutBudgetEveryRequestIsRetried", func(t *testing.T) {
+		calls, budgetErrors := run(t, nil)
+		assert.Equal(t, int32(requests*3), calls)
+		assert.Equal(t, 0, budgetErrors)
+	})
+
+	t.Run("BudgetCapsRetriesUnderSustainedFailures", func(t *testing.T) {
+		calls, budgetErrors := run(t, &common.RetryBudgetConfig{Ratio: 0.1, MaxTokens: 5})
+
+		// Initial tokens plus 10% of requests is the most retries that can happen
+		assert.LessOrEqual(t, calls, int32(requests+5+requests*0.1))
+		assert.Greater(t, calls, int32(requests))
+		assert.Greater(t, budgetErrors, requests/2)
+	})
+}
+
+func TestNetwork_QuarantinedUpstream(t *testing.T) {
+	resetGock()
+	defer resetGock()
+
+	var calls1, calls2 atomic.Int32
+	newServer := func(calls *atomic.Int32) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+		}))
+	}
+	srv1 := newServer(&calls1)
+	defer srv1.Close()
+	srv2 := newServer(&calls2)
+	defer srv2.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rlr, err := upstream.NewRateLimitersRegistry(&common.RateLimiterConfig{}, &log.Logger)
+	assert.NoError(t, err)
+	mt := health.NewTracker("prjA", 2*time.Second)
+	upr := upstream.NewUpstreamsRegistry(
+		&log.Logger,
+		"prjA",
+		[]*common.UpstreamConfig{
+			{
+				Type:     common.UpstreamTypeEvm,
+				Id:       "rpc1",
+				Endpoint: srv1.URL,
+				Evm:      &common.EvmUpstreamConfig{ChainId: 123},
+			},
+			{
+				Type:     common.UpstreamTypeEvm,
+				Id:       "rpc2",
+				Endpoint: srv2.URL,
+				Evm:      &common.EvmUpstreamConfig{ChainId: 123},
+			},
+		},
+		rlr,
+		vendors.NewVendorsRegistry(), mt, 1*time.Second,
+	)
+	assert.NoError(t, upr.Bootstrap(ctx))
+	assert.NoError(t, upr.PrepareUpstreamsForNetwork(util.EvmNetworkId(123)))
+
+	ntw, err := NewNetwork(
+		&log.Logger,
+		"prjA",
+		&common.NetworkConfig{
+			Architecture: common.ArchitectureEvm,
+			Evm: &common.EvmNetworkConfig{
+				ChainId: 123,
+			},
+		},
+		rlr,
+		upr,
+		mt,
+	)
+	assert.NoError(t, err)
+
+	forward := func(i int) *common.NormalizedResponse {
+		req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"eth_getBalance","params":["0x%x","latest"]}`, i, i)))
+		resp, err := ntw.Forward(ctx, req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	assert.NoError(t, upr.QuarantineUpstream("rpc1"))
+	for i := 0; i < 20; i++ {
+		resp := forward(i)
+		assert.Equal(t, "rpc2", resp.Upstream().Config().Id)
+	}
+	assert.Equal(t, int32(0), calls1.Load())
+	assert.Equal(t, int32(20), calls2.Load())
+
+	// Upstreams are re-ordered periodically by score, so rpc1 gets traffic again once it is back in rotation
+	assert.NoError(t, upr.UnquarantineUpstream("rpc1"))
+	i := 20
+	assert.Eventually(t, func() bool {
+		i++
+		forward(i)
+		return calls1.Load() > 0
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestNetwork_Consensus(t *testing.T) {
+	setupNetwork := func(t *testing.T, threshold float64, weights ...float64) *Network {
+		t.Helper()
+		setupMocksForEvmStatePoller()
+
+		upsCfgs := []*common.UpstreamConfig{}
+		for i, w := range weights {
+			upsCfgs = append(upsCfgs, &common.UpstreamConfig{
+				Type:        common.UpstreamTypeEvm,
+				Id:          fmt.Sprintf("rpc%d", i+1),
+				Endpoint:    fmt.Sprintf("http://rpc%d.localhost", i+1),
+				TrustWeight: w,
+				Evm: &common.EvmUpstreamConfig{
+					ChainId: 123,
+				},
+			})
+		}
+
+		rateLimitersRegistry, _ := upstream.NewRateLimitersRegistry(&common.RateLimiterConfig{}, &log.Logger)
+		metricsTracker := health.NewTracker("test", time.Minute)
+		upstreamsRegistry := upstream.NewUpstreamsRegistry(
+			&log.Logger,
+			"test",
+			upsCfgs,
+			rateLimitersRegistry,
+			vendors.NewVendorsRegistry(),
+			metricsTracker,
+			1*time.Second,
+		)
+		network, err := NewNetwork(
+			&log.Logger,
+			"test",
+			&common.NetworkConfig{
+				Architecture: common.ArchitectureEvm,
+				Evm: &common.EvmNetworkConfig{
+					ChainId: 123,
+				},
+				Consensus: &common.ConsensusConfig{
+					Threshold: threshold,
+				},
+			},
+			rateLimitersRegistry,
+			upstreamsRegistry,
+			metricsTracker,
+		)
+		assert.NoError(t, err)
+
+		assert.NoError(t, upstreamsRegistry.Bootstrap(context.Background()))
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, upstreamsRegistry.PrepareUpstreamsForNetwork(util.EvmNetworkId(123)))
+		time.Sleep(100 * time.Millisecond)
+
+		return network
+	}
+
+	mockBalance := func(host string, result string) {
+		gock.New(host).
+			Post("").
+			Persist().
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getBalance")
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":"` + result + `"}`)
+	}
+
+	forward := func(network *Network) (*common.NormalizedResponse, error) {
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x1234","0x10"]}`))
+		return network.Forward(context.Background(), req)
+	}
+
+	t.Run("HighWeightUpstreamWinsOverTwoLowWeightOnes", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0.5, 3, 1, 1)
+		mockBalance("http://rpc1.localhost", "0x1")
+		mockBalance("http://rpc2.localhost", "0x2")
+		mockBalance("http://rpc3.localhost", "0x2")
+
+		resp, err := forward(network)
+		assert.NoError(t, err)
+		if assert.NotNil(t, resp) {
+			jrr, err := resp.JsonRpcResponse()
+			assert.NoError(t, err)
+			assert.Equal(t, `"0x1"`, string(jrr.Result))
+			assert.Equal(t, "rpc1", resp.Upstream().Config().Id)
+		}
+	})
+
+	t.Run("EqualWeightsUseMajority", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0.5, 1, 1, 1)
+		mockBalance("http://rpc1.localhost", "0x1")
+		mockBalance("http://rpc2.localhost", "0x2")
+		mockBalance("http://rpc3.localhost", "0x2")
+
+		resp, err := forward(network)
+		assert.NoError(t, err)
+		if assert.NotNil(t, resp) {
+			jrr, err := resp.JsonRpcResponse()
+			assert.NoError(t, err)
+			assert.Equal(t, `"0x2"`, string(jrr.Result))
+		}
+	})
+
+	t.Run("NoResultClearsThreshold", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0.7, 3, 1, 1)
+		mockBalance("http://rpc1.localhost", "0x1")
+		mockBalance("http://rpc2.localhost", "0x2")
+		mockBalance("http://rpc3.localhost", "0x2")
+
+		resp, err := forward(network)
+		assert.Nil(t, resp)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeConsensusLowConfidence), "expected low confidence error, got: %v", err)
+	})
+
+	mockBlockNumber := func(host string, result string) {
+		gock.New(host).
+			Post("").
+			Persist().
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_blockNumber")
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":"` + result + `"}`)
+	}
+	forwardBlockNumber := func(network *Network) (*common.NormalizedResponse, error) {
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`))
+		return network.Forward(context.Background(), req)
+	}
+
+	t.Run("DifferingBlockNumbersFailWithoutTieBreak", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0.5, 1, 1, 1)
+		mockBlockNumber("http://rpc1.localhost", "0x64")
+		mockBlockNumber("http://rpc2.localhost", "0x66")
+		mockBlockNumber("http://rpc3.localhost", "0x65")
+
+		resp, err := forwardBlockNumber(network)
+		assert.Nil(t, resp)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeConsensusLowConfidence), "expected low confidence error, got: %v", err)
+	})
+
+	t.Run("HighestTieBreakReturnsHighestBlockNumberReachedByThreshold", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0.5, 1, 1, 1)
+		network.cfg.Consensus.TieBreaks = []*common.ConsensusTieBreakConfig{
+			{Method: "eth_blockNumber", Prefer: common.ConsensusPreferHighest},
+		}
+		mockBlockNumber("http://rpc1.localhost", "0x64")
+		mockBlockNumber("http://rpc2.localhost", "0x66")
+		mockBlockNumber("http://rpc3.localhost", "0x65")
+
+		resp, err := forwardBlockNumber(network)
+		assert.NoError(t, err)
+		if assert.NotNil(t, resp) {
+			jrr, err := resp.JsonRpcResponse()
+			assert.NoError(t, err)
+			// Only rpc2 reached 0x66, while two of three upstreams reached 0x65
+			assert.Equal(t, `"0x65"`, string(jrr.Result))
+			assert.Equal(t, "rpc3", resp.Upstream().Config().Id)
+		}
+	})
+
+	t.Run("HighestTieBreakDoesNotApplyToOtherMethods", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0.5, 1, 1, 1)
+		network.cfg.Consensus.TieBreaks = []*common.ConsensusTieBreakConfig{
+			{Method: "eth_blockNumber", Prefer: common.ConsensusPreferHighest},
+		}
+		mockBalance("http://rpc1.localhost", "0x1")
+		mockBalance("http://rpc2.localhost", "0x2")
+		mockBalance("http://rpc3.localhost", "0x3")
+
+		resp, err := forward(network)
+		assert.Nil(t, resp)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeConsensusLowConfidence), "expected low confidence error, got: %v", err)
+	})
+
+	forwardWithAgreements := func(network *Network, agreements string) (*common.NormalizedResponse, error) {
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x1234","0x10"]}`)).
+			WithDirectives(&common.RequestDirectives{ConsensusAgreements: agreements})
+		return network.Forward(context.Background(), req)
+	}
+
+	t.Run("RequestOverrideRequiresThreeAgreements", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0.5, 1, 1, 1)
+		network.cfg.Consensus.MaxRequestAgreements = 3
+		mockBalance("http://rpc1.localhost", "0x1")
+		mockBalance("http://rpc2.localhost", "0x1")
+		mockBalance("http://rpc3.localhost", "0x2")
+
+		// 2-of-3 is enough by default
+		resp, err := forward(network)
+		assert.NoError(t, err)
+		assert.NotNil(t, resp)
+
+		resp, err = forwardWithAgreements(network, "3")
+		assert.Nil(t, resp)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeConsensusLowConfidence), "expected low confidence error, got: %v", err)
+	})
+
+	t.Run("RequestOverrideFailsWithOnlyTwoHealthyUpstreams", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0.5, 1, 1)
+		network.cfg.Consensus.MaxRequestAgreements = 3
+		mockBalance("http://rpc1.localhost", "0x1")
+		mockBalance("http://rpc2.localhost", "0x1")
+
+		resp, err := forwardWithAgreements(network, "3")
+		assert.Nil(t, resp)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeConsensusInsufficientUpstreams), "expected insufficient upstreams error, got: %v", err)
+	})
+
+	t.Run("RequestOverrideIsNotServedFromCache", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0.5, 1, 1, 1)
+		network.cfg.Consensus.MaxRequestAgreements = 3
+		network.blockResolver = &fakeBlockResolver{finalized: 100, head: 110}
+		cache, err := NewEvmJsonRpcCache(context.Background(), &log.Logger, &common.ConnectorConfig{
+			Driver: "memory",
+			Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+		})
+		assert.NoError(t, err)
+		network.cacheDal = cache.WithNetwork(network)
+		mockBalance("http://rpc1.localhost", "0x1")
+		mockBalance("http://rpc2.localhost", "0x1")
+		mockBalance("http://rpc3.localhost", "0x2")
+
+		resp, err := forward(network)
+		assert.NoError(t, err)
+		assert.NotNil(t, resp)
+		assert.Eventually(t, func() bool {
+			resp, err := forward(network)
+			return err == nil && resp.FromCache()
+		}, time.Second, 10*time.Millisecond, "2-of-3 response must be cached")
+
+		// The cached response was only agreed by two upstreams
+		resp, err = forwardWithAgreements(network, "3")
+		assert.Nil(t, resp)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeConsensusLowConfidence), "expected low confidence error, got: %v", err)
+	})
+
+	t.Run("RequestOverrideBeyondBoundsIsRejected", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0.5, 1, 1, 1)
+		network.cfg.Consensus.MaxRequestAgreements = 2
+
+		resp, err := forwardWithAgreements(network, "3")
+		assert.Nil(t, resp)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeInvalidRequest), "expected invalid request error, got: %v", err)
+	})
+
+	t.Run("MethodRequiresAllParticipantsToAgree", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0.5, 1, 1, 1)
+		network.cfg.Consensus.Methods = []*common.ConsensusMethodConfig{
+			{Method: "eth_getBalance", RequiredAgreements: 3},
+		}
+		mockBalance("http://rpc1.localhost", "0x1")
+		mockBalance("http://rpc2.localhost", "0x1")
+		mockBalance("http://rpc3.localhost", "0x1")
+
+		resp, err := forward(network)
+		assert.NoError(t, err)
+		if assert.NotNil(t, resp) {
+			jrr, err := resp.JsonRpcResponse()
+			assert.NoError(t, err)
+			assert.Equal(t, `"0x1"`, string(jrr.Result))
+		}
+	})
+}
+
+func TestNetwork_LocalRegion(t *testing.T) {
+	setupNetwork := func(t *testing.T) *Network {
+		t.Helper()
+		setupMocksForEvmStatePoller()
+
+		rateLimitersRegistry, _ := upstream.NewRateLimitersRegistry(&common.RateLimiterConfig{}, &log.Logger)
+		metricsTracker := health.NewTracker("test", time.Minute)
+		upstreamsRegistry := upstream.NewUpstreamsRegistry(
+			&log.Logger,
+			"test",
+			[]*common.UpstreamConfig{
+				{
+					Type:     common.UpstreamTypeEvm,
+					Id:       "rpc1",
+					Endpoint: "http://rpc1.localhost",
+					Region:   "eu-west",
+					Evm: &common.EvmUpstreamConfig{
+						ChainId: 123,
+					},
+				},
+				{
+					Type:     common.UpstreamTypeEvm,
+					Id:       "rpc2",
+					Endpoint: "http://rpc2.localhost",
+					Region:   "us-east",
+					Evm: &common.EvmUpstreamConfig{
+						ChainId: 123,
+					},
+				},
+			},
+			rateLimitersRegistry,
+			vendors.NewVendorsRegistry(),
+			metricsTracker,
+			1*time.Second,
+		)
+		upstreamsRegistry.SetLocalRegion("us-east")
+		network, err := NewNetwork(
+			&log.Logger,
+			"test",
+			&common.NetworkConfig{
+				Architecture: common.ArchitectureEvm,
+				Evm: &common.EvmNetworkConfig{
+					ChainId: 123,
+				},
+			},
+			rateLimitersRegistry,
+			upstreamsRegistry,
+			metricsTracker,
+		)
+		assert.NoError(t, err)
+
+		assert.NoError(t, upstreamsRegistry.Bootstrap(context.Background()))
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, upstreamsRegistry.PrepareUpstreamsForNetwork(util.EvmNetworkId(123)))
+		time.Sleep(100 * time.Millisecond)
+
+		return network
+	}
+
+	mockBalance := func(host string, status int, body string) {
+		gock.New(host).
+			Post("").
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getBalance")
+			}).
+			Reply(status).
+			BodyString(body)
+	}
+	forward := func(network *Network) (*common.NormalizedResponse, error) {
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x1234","0x10"]}`))
+		return network.Forward(context.Background(), req)
+	}
+
+	t.Run("SameRegionUpstreamIsPreferred", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t)
+		mockBalance("http://rpc1.localhost", 200, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`)
+		mockBalance("http://rpc2.localhost", 200, `{"jsonrpc":"2.0","id":1,"result":"0x2"}`)
+
+		resp, err := forward(network)
+		assert.NoError(t, err)
+		if assert.NotNil(t, resp) {
+			assert.Equal(t, "rpc2", resp.Upstream().Config().Id)
+		}
+		assert.Equal(t, 1, anyTestMocksLeft(), "cross-region upstream must not be called")
+	})
+
+	t.Run("CrossRegionUpstreamIsUsedWhenLocalOneFails", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t)
+		mockBalance("http://rpc2.localhost", 500, `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"internal error"}}`)
+		mockBalance("http://rpc1.localhost", 200, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`)
+
+		resp, err := forward(network)
+		assert.NoError(t, err)
+		if assert.NotNil(t, resp) {
+			assert.Equal(t, "rpc1", resp.Upstream().Config().Id)
+		}
+	})
+}
+
+func TestNetwork_SyntheticResponses(t *testing.T) {
+	setupNetwork := func(t *testing.T, synthetic *common.EvmSyntheticResponsesConfig) *Network {
+		t.Helper()
+		setupMocksForEvmStatePoller()
+
+		rateLimitersRegistry, _ := upstream.NewRateLimitersRegistry(&common.RateLimiterConfig{}, &log.Logger)
+		metricsTracker := health.NewTracker("test", time.Minute)
+		upstreamsRegistry := upstream.NewUpstreamsRegistry(
+			&log.Logger,
+			"test",
+			[]*common.UpstreamConfig{
+				{
+					Type:     common.UpstreamTypeEvm,
+					Id:       "rpc1",
+					Endpoint: "http://rpc1.localhost",
+					Evm: &common.EvmUpstreamConfig{
+						ChainId: 123,
+					},
+				},
+			},
+			rateLimitersRegistry,
+			vendors.NewVendorsRegistry(),
+			metricsTracker,
+			1*time.Second,
+		)
+		network, err := NewNetwork(
+			&log.Logger,
+			"test",
+			&common.NetworkConfig{
+				Architecture: common.ArchitectureEvm,
+				Evm: &common.EvmNetworkConfig{
+					ChainId:            123,
+					SyntheticResponses: synthetic,
+				},
+			},
+			rateLimitersRegistry,
+			upstreamsRegistry,
+			metricsTracker,
+		)
+		assert.NoError(t, err)
+
+		assert.NoError(t, upstreamsRegistry.Bootstrap(context.Background()))
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, upstreamsRegistry.PrepareUpstreamsForNetwork(util.EvmNetworkId(123)))
+		time.Sleep(100 * time.Millisecond)
+
+		return network
+	}
+
+	mockUpstream := func(method string, result string) *gock.Response {
+		return gock.New("http://rpc1.localhost").
+			Post("").
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), method)
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":` + result + `}`)
+	}
+
+	forward := func(t *testing.T, network *Network, method string) string {
+		t.Helper()
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":7,"method":"` + method + `","params":[]}`))
+		resp, err := network.Forward(context.Background(), req)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		jrr, err := resp.JsonRpcResponse()
+		if err != nil {
+			t.Fatalf("expected json-rpc response, got %v", err)
+		}
+		assert.EqualValues(t, 7, jrr.ID)
+		return string(jrr.Result)
+	}
+
+	t.Run("EnabledMethodsAreAnsweredWithoutUpstream", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, &common.EvmSyntheticResponsesConfig{
+			EthSyncing:        true,
+			NetListening:      true,
+			Web3ClientVersion: "erpc/test",
+		})
+		syncingMock := mockUpstream("eth_syncing", `{"startingBlock":"0x0"}`)
+		listeningMock := mockUpstream("net_listening", `false`)
+		versionMock := mockUpstream("web3_clientVersion", `"Geth/v1.14.7"`)
+
+		assert.Equal(t, `false`, forward(t, network, "eth_syncing"))
+		assert.Equal(t, `true`, forward(t, network, "net_listening"))
+		assert.Equal(t, `"erpc/test"`, forward(t, network, "web3_clientVersion"))
+
+		assert.False(t, syncingMock.Mock.Done(), "eth_syncing must not be sent to upstream")
+		assert.False(t, listeningMock.Mock.Done(), "net_listening must not be sent to upstream")
+		assert.False(t, versionMock.Mock.Done(), "web3_clientVersion must not be sent to upstream")
+	})
+
+	t.Run("DisabledByDefault", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, nil)
+		syncingMock := mockUpstream("eth_syncing", `{"startingBlock":"0x0"}`)
+
+		assert.JSONEq(t, `{"startingBlock":"0x0"}`, forward(t, network, "eth_syncing"))
+		assert.True(t, syncingMock.Mock.Done())
+	})
+
+	t.Run("OnlyConfiguredMethodsAreShortCircuited", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, &common.EvmSyntheticResponsesConfig{EthSyncing: true})
+		versionMock := mockUpstream("web3_clientVersion", `"Geth/v1.14.7"`)
+
+		assert.Equal(t, `"Geth/v1.14.7"`, forward(t, network, "web3_clientVersion"))
+		assert.True(t, versionMock.Mock.Done())
+	})
+}
+
+func TestNetwork_RoutingRules(t *testing.T) {
+	const pinnedAddress = "0xAbC0000000000000000000000000000000000001"
+
+	setupNetwork := func(t *testing.T, rules []*common.RoutingRuleConfig) (*Network, error) {
+		t.Helper()
+		setupMocksForEvmStatePoller()
+
+		rateLimitersRegistry, _ := upstream.NewRateLimitersRegistry(&common.RateLimiterConfig{}, &log.Logger)
+		metricsTracker := health.NewTracker("test", time.Minute)
+		upstreamsRegistry := upstream.NewUpstreamsRegistry(
+			&log.Logger,
+			"test",
+			[]*common.UpstreamConfig{
+				{
+					Type:     common.UpstreamTypeEvm,
+					Id:       "rpc1",
+					Endpoint: "http://rpc1.localhost",
+					Evm: &common.EvmUpstreamConfig{
+						ChainId: 123,
+					},
+				},
+				{
+					Type:     common.UpstreamTypeEvm,
+					Id:       "rpc2",
+					Endpoint: "http://rpc2.localhost",
+					Evm: &common.EvmUpstreamConfig{
+						ChainId: 123,
+					},
+				},
+			},
+			rateLimitersRegistry,
+			vendors.NewVendorsRegistry(),
+			metricsTracker,
+			1*time.Second,
+		)
+		network, err := NewNetwork(
+			&log.Logger,
+			"test",
+			&common.NetworkConfig{
+				Architecture: common.ArchitectureEvm,
+				Evm: &common.EvmNetworkConfig{
+					ChainId: 123,
+				},
+				RoutingRules: rules,
+			},
+			rateLimitersRegistry,
+			upstreamsRegistry,
+			metricsTracker,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		assert.NoError(t, upstreamsRegistry.Bootstrap(context.Background()))
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, upstreamsRegistry.PrepareUpstreamsForNetwork(util.EvmNetworkId(123)))
+		time.Sleep(100 * time.Millisecond)
+
+		return network, nil
+	}
+
+	mockUpstream := func(host string, result string) {
+		gock.New(host).
+			Post("").
+			Persist().
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_call")
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":"` + result + `"}`)
+	}
+
+	ethCall := func(t *testing.T, network *Network, to string) *common.NormalizedResponse {
+		t.Helper()
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[{"to":"` + to + `","data":"0x01"},"latest"]}`))
+		resp, err := network.Forward(context.Background(), req)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return resp
+	}
+
+	t.Run("CallToPinnedAddressIsRoutedToDesignatedUpstream", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network, err := setupNetwork(t, []*common.RoutingRuleConfig{
+			{
+				Method: "eth_call",
+				Params: []*common.RoutingParamConfig{
+					{Path: "params[0].to", Equals: pinnedAddress},
+				},
+				Upstream: "rpc2",
+			},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		mockUpstream("http://rpc1.localhost", "0x1111")
+		mockUpstream("http://rpc2.localhost", "0x2222")
+
+		for _, to := range []string{pinnedAddress, strings.ToLower(pinnedAddress), pinnedAddress} {
+			resp := ethCall(t, network, to)
+			assert.Equal(t, "rpc2", resp.Upstream().Config().Id)
+			jrr, err := resp.JsonRpcResponse()
+			assert.NoError(t, err)
+			assert.Equal(t, `"0x2222"`, string(jrr.Result))
+		}
+
+		resp := ethCall(t, network, "0x0000000000000000000000000000000000000002")
+		assert.NotNil(t, resp.Upstream())
+	})
+
+	t.Run("FirstMatchingRuleWins", func(t *testing.T) {
+		rules, err := newRoutingRules([]*common.RoutingRuleConfig{
+			{Method: "eth_*", Params: []*common.RoutingParamConfig{{Path: "0.to", Equals: pinnedAddress}}, Upstream: "first"},
+			{Method: "eth_call", Upstream: "second"},
+		})
+		assert.NoError(t, err)
+
+		params := []interface{}{map[string]interface{}{"to": pinnedAddress}, "latest"}
+		assert.Equal(t, "first", matchRoutingRule(rules, "eth_call", params).upstream)
+		params = []interface{}{map[string]interface{}{"to": "0x02"}, "latest"}
+		assert.Equal(t, "second", matchRoutingRule(rules, "eth_call", params).upstream)
+		assert.Nil(t, matchRoutingRule(rules, "net_version", params))
+	})
+
+	t.Run("RuleWithoutUpstreamIsRejected", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		_, err := setupNetwork(t, []*common.RoutingRuleConfig{
+			{Method: "eth_call"},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestNetwork_DeprecatedMethods(t *testing.T) {
+	newNetwork := func(t *testing.T, cfgs []*common.DeprecatedMethodConfig) *Network {
+		t.Helper()
+		network := setupTestNetwork(t)
+		deprecated, err := newDeprecatedMethods(cfgs)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		network.deprecatedMethods = deprecated
+		return network
+	}
+	calls := func(method string) float64 {
+		return promUtil.ToFloat64(health.MetricNetworkDeprecatedMethodCalls.WithLabelValues("test", "evm:123", method))
+	}
+
+	t.Run("DeprecatedMethodIsServedAndCounted", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+		network := newNetwork(t, []*common.DeprecatedMethodConfig{
+			{Method: "eth_getStorageAt", Message: "read the storage via eth_call instead"},
+		})
+		gock.New("http://rpc1.localhost").
+			Post("").
+			Times(2).
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getStorageAt")
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":"0x01"}`)
+
+		before := calls("eth_getStorageAt")
+		for i := 0; i < 2; i++ {
+			req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"eth_getStorageAt","params":["0x0000000000000000000000000000000000000001","0x%x","latest"]}`, i)))
+			resp, err := network.Forward(context.Background(), req)
+			if assert.NoError(t, err) {
+				jrr, err := resp.JsonRpcResponse()
+				assert.NoError(t, err)
+				assert.Equal(t, `"0x01"`, string(jrr.Result))
+			}
+		}
+		assert.Equal(t, before+2, calls("eth_getStorageAt"))
+	})
+
+	t.Run("PatternsMatchAndOtherMethodsAreNotCounted", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+		network := newNetwork(t, []*common.DeprecatedMethodConfig{
+			{Method: "shh_*", Reject: true},
+		})
+		gock.New("http://rpc1.localhost").
+			Post("").
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_chainId")
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":"0x7b"}`)
+
+		before := calls("shh_*")
+		for _, method := range []string{"shh_post", "shh_version", "shh_anything"} {
+			_, err := network.Forward(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"`+method+`","params":[{}]}`)))
+			assert.True(t, common.HasErrorCode(err, common.ErrCodeInvalidRequest), "unexpected error: %v", err)
+		}
+		// Calls are counted under the pattern, not under each method name clients send
+		assert.Equal(t, before+3, calls("shh_*"))
+		assert.Equal(t, float64(0), calls("shh_post"))
+		assert.Len(t, network.deprecatedMethods.lastWarned, 1)
+
+		beforeChainId := calls("eth_chainId")
+		_, err := network.Forward(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`)))
+		assert.NoError(t, err)
+		assert.Equal(t, beforeChainId, calls("eth_chainId"))
+	})
+
+	t.Run("EntryWithoutMethodIsRejected", func(t *testing.T) {
+		_, err := newDeprecatedMethods([]*common.DeprecatedMethodConfig{{Message: "no method"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestNetwork_LogsBloom(t *testing.T) {
+	const (
+		emitterAddress = "0x1111111111111111111111111111111111111111"
+		otherAddress   = "0x2222222222222222222222222222222222222222"
+		transferTopic  = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	)
+
+	bloomOf := func(values ...string) string {
+		bloom := make([]byte, logsBloomByteLength)
+		for _, v := range values {
+			b, _ := hex.DecodeString(strings.TrimPrefix(v, "0x"))
+			for _, bit := range bloomBits(b) {
+				bloom[logsBloomByteLength-1-bit/8] |= 1 << (bit % 8)
+			}
+		}
+		return "0x" + hex.EncodeToString(bloom)
+	}
+
+	setupNetwork := func(t *testing.T, finalizedBlock int64) *Network {
+		t.Helper()
+		setupMocksForEvmStatePoller()
+
+		rateLimitersRegistry, _ := upstream.NewRateLimitersRegistry(&common.RateLimiterConfig{}, &log.Logger)
+		metricsTracker := health.NewTracker("test", time.Minute)
+		upstreamsRegistry := upstream.NewUpstreamsRegistry(
+			&log.Logger,
+			"test",
+			[]*common.UpstreamConfig{
+				{
+					Type:     common.UpstreamTypeEvm,
+					Id:       "rpc1",
+					Endpoint: "http://rpc1.localhost",
+					Evm: &common.EvmUpstreamConfig{
+						ChainId: 123,
+					},
+				},
+			},
+			rateLimitersRegistry,
+			vendors.NewVendorsRegistry(),
+			metricsTracker,
+			1*time.Second,
+		)
+		network, err := NewNetwork(
+			&log.Logger,
+			"test",
+			&common.NetworkConfig{
+				Architecture: common.ArchitectureEvm,
+				Evm: &common.EvmNetworkConfig{
+					ChainId:   123,
+					LogsBloom: &common.EvmLogsBloomConfig{},
+				},
+			},
+			rateLimitersRegistry,
+			upstreamsRegistry,
+			metricsTracker,
+		)
+		assert.NoError(t, err)
+
+		assert.NoError(t, upstreamsRegistry.Bootstrap(context.Background()))
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, upstreamsRegistry.PrepareUpstreamsForNetwork(util.EvmNetworkId(123)))
+		assert.NoError(t, network.Bootstrap(context.Background()))
+		time.Sleep(100 * time.Millisecond)
+		network.evmStatePollers["rpc1"].SuggestLatestBlock(0x20)
+		network.evmStatePollers["rpc1"].SuggestFinalizedBlock(finalizedBlock)
+
+		// Blocks 0x10 and 0x11 only contain transfer logs of the emitter address
+		for _, blk := range []string{"0x10", "0x11"} {
+			hash := "0xb10c" + strings.TrimPrefix(blk, "0x")
+			gock.New("http://rpc1.localhost").
+				Post("").
+				Filter(func(request *http.Request) bool {
+					body := safeReadBody(request)
+					return strings.Contains(body, "eth_getBlockByHash") && strings.Contains(body, hash)
+				}).
+				Reply(200).
+				BodyString(`{"jsonrpc":"2.0","id":1,"result":{"number":"` + blk + `","hash":"` + hash + `","logsBloom":"` + bloomOf(emitterAddress, transferTopic) + `"}}`)
+
+			req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByHash","params":["` + hash + `",false]}`))
+			if _, err := network.Forward(context.Background(), req); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+
+		return network
+	}
+
+	mockGetLogs := func() *gock.Response {
+		return gock.New("http://rpc1.localhost").
+			Post("").
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getLogs")
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":[{"address":"` + emitterAddress + `","blockNumber":"0x10"}]}`)
+	}
+
+	getLogs := func(t *testing.T, network *Network, filter string) string {
+		t.Helper()
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":9,"method":"eth_getLogs","params":[` + filter + `]}`))
+		resp, err := network.Forward(context.Background(), req)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		jrr, err := resp.JsonRpcResponse()
+		if err != nil {
+			t.Fatalf("expected json-rpc response, got %v", err)
+		}
+		assert.EqualValues(t, 9, jrr.ID)
+		return string(jrr.Result)
+	}
+
+	t.Run("ExcludedAddressReturnsEmptyWithoutUpstreamCall", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0x18)
+		logsMock := mockGetLogs()
+
+		assert.Equal(t, `[]`, getLogs(t, network, `{"fromBlock":"0x10","toBlock":"0x11","address":"`+otherAddress+`"}`))
+		assert.Equal(t, `[]`, getLogs(t, network, `{"fromBlock":"0x10","toBlock":"0x11","address":"`+emitterAddress+`","topics":[null,"0x01"]}`))
+		assert.False(t, logsMock.Mock.Done(), "upstream must not be called for ranges excluded by blooms")
+	})
+
+	t.Run("PositiveBloomFallsBackToUpstream", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0x18)
+		logsMock := mockGetLogs()
+
+		result := getLogs(t, network, `{"fromBlock":"0x10","toBlock":"0x11","address":["`+otherAddress+`","`+emitterAddress+`"],"topics":["`+transferTopic+`"]}`)
+		assert.Contains(t, result, emitterAddress)
+		assert.True(t, logsMock.Mock.Done())
+	})
+
+	t.Run("UnknownBlocksFallBackToUpstream", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		network := setupNetwork(t, 0x18)
+		logsMock := mockGetLogs()
+
+		getLogs(t, network, `{"fromBlock":"0x10","toBlock":"0x12","address":"`+otherAddress+`"}`)
+		assert.True(t, logsMock.Mock.Done())
+	})
+
+	t.Run("UnfinalizedBlocksFallBackToUpstream", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+
+		// Block 0x11 is not finalized so its bloom is not kept, it could still be reorged
+		network := setupNetwork(t, 0x10)
+		logsMock := mockGetLogs()
+
+		getLogs(t, network, `{"fromBlock":"0x10","toBlock":"0x11","address":"`+otherAddress+`"}`)
+		assert.True(t, logsMock.Mock.Done())
+	})
+}
+
+func TestNetwork_EmptyResponseBody(t *testing.T) {
+	resetGock()
+	defer resetGock()
+
+	var calls1, calls2 atomic.Int32
+	srv1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls1.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv1.Close()
+	srv2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls2.Add(1)
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer srv2.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rlr, err := upstream.NewRateLimitersRegistry(&common.RateLimiterConfig{}, &log.Logger)
+	assert.NoError(t, err)
+	mt := health.NewTracker("prjA", 2*time.Second)
+	upr := upstream.NewUpstreamsRegistry(
+		&log.Logger,
+		"prjA",
+		[]*common.UpstreamConfig{
+			{
+				Type:     common.UpstreamTypeEvm,
+				Id:       "rpc1",
+				Endpoint: srv1.URL,
+				Evm:      &common.EvmUpstreamConfig{ChainId: 123},
+			},
+			{
+				Type:     common.UpstreamTypeEvm,
+				Id:       "rpc2",
+				Endpoint: srv2.URL,
+				Evm:      &common.EvmUpstreamConfig{ChainId: 123},
+			},
+		},
+		rlr,
+		vendors.NewVendorsRegistry(), mt, 1*time.Second,
+	)
+	assert.NoError(t, upr.Bootstrap(ctx))
+	assert.NoError(t, upr.PrepareUpstreamsForNetwork(util.EvmNetworkId(123)))
+
+	ntw, err := NewNetwork(
+		&log.Logger,
+		"prjA",
+		&common.NetworkConfig{
+			Architecture: common.ArchitectureEvm,
+			Evm: &common.EvmNetworkConfig{
+				ChainId: 123,
+			},
+		},
+		rlr,
+		upr,
+		mt,
+	)
+	assert.NoError(t, err)
+
+	newRequest := func(i int) *common.NormalizedRequest {
+		req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"eth_getBalance","params":["0x%x","latest"]}`, i, i)))
+		req.SetNetwork(ntw)
+		return req
+	}
+
+	t.Run("EmptyBodyIsRetryableUpstreamError", func(t *testing.T) {
+		rpc1, ok := upr.GetUpstream("rpc1")
+		if !assert.True(t, ok) {
+			return
+		}
+		before := promUtil.ToFloat64(health.MetricUpstreamEmptyBodyTotal.WithLabelValues("prjA", util.EvmNetworkId(123), "rpc1", "eth_getBalance"))
+
+		_, err := rpc1.Forward(ctx, newRequest(1))
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeUpstreamEmptyResponse), "unexpected error: %v", err)
+		assert.True(t, common.IsRetryableTowardsUpstream(err))
+		assert.Equal(t, before+1, promUtil.ToFloat64(health.MetricUpstreamEmptyBodyTotal.WithLabelValues("prjA", util.EvmNetworkId(123), "rpc1", "eth_getBalance")))
+	})
+
+	t.Run("NetworkFailsOverToNextUpstream", func(t *testing.T) {
+		calls1.Store(0)
+		i := 100
+		assert.Eventually(t, func() bool {
+			i++
+			resp, err := ntw.Forward(ctx, newRequest(i))
+			if !assert.NoError(t, err) {
+				return true
+			}
+			assert.Equal(t, "rpc2", resp.Upstream().Config().Id)
+			return calls1.Load() > 0
+		}, 5*time.Second, 10*time.Millisecond, "rpc1 must be tried at least once")
+	})
+}
+
+func TestNetwork_ResultAndErrorResponse(t *testing.T) {
+	resetGock()
+	defer resetGock()
+
+	var calls1 atomic.Int32
+	srv1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls1.Add(1)
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1","error":{"code":-32000,"message":"missing trie node"}}`))
+	}))
+	defer srv1.Close()
+	srv2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x2"}`))
+	}))
+	defer srv2.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rlr, err := upstream.NewRateLimitersRegistry(&common.RateLimiterConfig{}, &log.Logger)
+	assert.NoError(t, err)
+	mt := health.NewTracker("prjA", 2*time.Second)
+	upr := upstream.NewUpstreamsRegistry(
+		&log.Logger,
+		"prjA",
+		[]*common.UpstreamConfig{
+			{
+				Type:     common.UpstreamTypeEvm,
+				Id:       "rpc1",
+				Endpoint: srv1.URL,
+				Evm:      &common.EvmUpstreamConfig{ChainId: 123},
+			},
+			{
+				Type:     common.UpstreamTypeEvm,
+				Id:       "rpc2",
+				Endpoint: srv2.URL,
+				Evm:      &common.EvmUpstreamConfig{ChainId: 123},
+			},
+		},
+		rlr,
+		vendors.NewVendorsRegistry(), mt, 1*time.Second,
+	)
+	assert.NoError(t, upr.Bootstrap(ctx))
+	assert.NoError(t, upr.PrepareUpstreamsForNetwork(util.EvmNetworkId(123)))
+
+	ntw, err := NewNetwork(
+		&log.Logger,
+		"prjA",
+		&common.NetworkConfig{
+			Architecture: common.ArchitectureEvm,
+			Evm: &common.EvmNetworkConfig{
+				ChainId: 123,
+			},
+		},
+		rlr,
+		upr,
+		mt,
+	)
+	assert.NoError(t, err)
+
+	i := 0
+	assert.Eventually(t, func() bool {
+		i++
+		req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"eth_getBalance","params":["0x%x","latest"]}`, i, i)))
+		req.SetNetwork(ntw)
+		resp, err := ntw.Forward(ctx, req)
+		if !assert.NoError(t, err) {
+			return true
+		}
+		jrr, err := resp.JsonRpcResponse()
+		assert.NoError(t, err)
+		assert.Equal(t, `"0x2"`, string(jrr.Result))
+		assert.Equal(t, "rpc2", resp.Upstream().Config().Id)
+		return calls1.Load() > 0
+	}, 5*time.Second, 10*time.Millisecond, "rpc1 must be tried at least once")
+}
+
+func TestNetwork_Aggregate(t *testing.T) {
+	newNetwork := func(t *testing.T) *Network {
+		network := setupTestNetwork(t)
+		assert.NoError(t, network.Bootstrap(context.Background()))
+		network.evmStatePollers["test"].SuggestLatestBlock(9)
+		network.evmStatePollers["test"].SuggestFinalizedBlock(8)
+		cache, err := NewEvmJsonRpcCache(context.Background(), &log.Logger, &common.ConnectorConfig{
+			Driver: "memory",
+			Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+		})
+		assert.NoError(t, err)
+		network.cacheDal = cache.WithNetwork(network)
+		return network
+	}
+	mockBalance := func(address, result string) {
+		gock.New("http://rpc1.localhost").
+			Post("").
+			Times(1).
+			Filter(func(request *http.Request) bool {
+				body := safeReadBody(request)
+				return strings.Contains(body, "eth_getBalance") && strings.Contains(body, address)
+			}).
+			Reply(200).
+			Delay(100 * time.Millisecond).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":"` + result + `"}`)
+	}
+
+	t.Run("CacheHitsAndForwardedItemsAreReturnedInOrder", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+		network := newNetwork(t)
+
+		mockBalance("0x00000000000000000000000000000000000000aa", "0x10")
+		mockBalance("0x00000000000000000000000000000000000000bb", "0x20")
+
+		// Warm up the cache for the first item
+		_, err := network.Forward(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x00000000000000000000000000000000000000aa","0x1"]}`)))
+		assert.NoError(t, err)
+		time.Sleep(100 * time.Millisecond)
+
+		// The same uncached item twice must result in a single upstream call
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":7,"method":"erpc_aggregate","params":[[
+			{"method":"eth_getBalance","params":["0x00000000000000000000000000000000000000aa","0x1"]},
+			{"method":"eth_getBalance","params":["0x00000000000000000000000000000000000000bb","0x1"]},
+			{"id":"x","method":"eth_getBalance","params":["0x00000000000000000000000000000000000000bb","0x1"]}
+		]]}`))
+		resp, err := network.Forward(context.Background(), req)
+		if !assert.NoError(t, err) {
+			return
+		}
+		jrr, err := resp.JsonRpcResponse()
+		assert.NoError(t, err)
+
+		var results []struct {
+			ID     interface{} `json:"id"`
+			Result string      `json:"result"`
+		}
+		assert.NoError(t, sonic.Unmarshal(jrr.Result, &results))
+		if assert.Len(t, results, 3) {
+			assert.Equal(t, "0x10", results[0].Result)
+			assert.Equal(t, float64(0), results[0].ID)
+			assert.Equal(t, "0x20", results[1].Result)
+			assert.Equal(t, float64(1), results[1].ID)
+			assert.Equal(t, "0x20", results[2].Result)
+			assert.Equal(t, "x", results[2].ID)
+		}
+		assert.Equal(t, 7, int(jrr.ID.(float64)))
+
+		if left := anyTestMocksLeft(); left > 0 {
+			t.Errorf("Expected all test mocks to be consumed, got %v left", left)
+		}
+	})
+
+	t.Run("WriteMethodsAreRejected", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+		network := newNetwork(t)
+
+		_, err := network.Forward(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"erpc_aggregate","params":[[
+			{"method":"eth_chainId","params":[]},
+			{"method":"eth_sendRawTransaction","params":["0x01"]}
+		]]}`)))
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeInvalidRequest), "unexpected error: %v", err)
+	})
+}
+
+func TestNetwork_BlockReceiptsRange(t *testing.T) {
+	newNetwork := func(t *testing.T) *Network {
+		network := setupTestNetwork(t)
+		assert.NoError(t, network.Bootstrap(context.Background()))
+		network.evmStatePollers["test"].SuggestLatestBlock(9)
+		network.evmStatePollers["test"].SuggestFinalizedBlock(8)
+		cache, err := NewEvmJsonRpcCache(context.Background(), &log.Logger, &common.ConnectorConfig{
+			Driver: "memory",
+			Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+		})
+		assert.NoError(t, err)
+		network.cacheDal = cache.WithNetwork(network)
+		return network
+	}
+	mockReceipts := func(blockNumber string) {
+		gock.New("http://rpc1.localhost").
+			Post("").
+			Times(1).
+			Filter(func(request *http.Request) bool {
+				body := safeReadBody(request)
+				return strings.Contains(body, "eth_getBlockReceipts") && strings.Contains(body, `"`+blockNumber+`"`)
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":[{"blockNumber":"` + blockNumber + `","transactionIndex":"0x0"},{"blockNumber":"` + blockNumber + `","transactionIndex":"0x1"}]}`)
+	}
+	forwardRange := func(t *testing.T, network *Network, from, to string) []map[string]string {
+		t.Helper()
+		resp, err := network.Forward(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":3,"method":"erpc_getBlockReceiptsRange","params":["`+from+`","`+to+`"]}`)))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		jrr, err := resp.JsonRpcResponse()
+		assert.NoError(t, err)
+		var receipts []map[string]string
+		assert.NoError(t, sonic.Unmarshal(jrr.Result, &receipts))
+		return receipts
+	}
+
+	t.Run("ReceiptsAreMergedInBlockOrderAndCachedPerBlock", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+		network := newNetwork(t)
+
+		mockReceipts("0x5")
+		mockReceipts("0x6")
+		mockReceipts("0x7")
+
+		receipts := forwardRange(t, network, "0x5", "0x7")
+		if assert.Len(t, receipts, 6) {
+			for i, r := range receipts {
+				assert.Equal(t, fmt.Sprintf("0x%x", 5+i/2), r["blockNumber"])
+				assert.Equal(t, fmt.Sprintf("0x%x", i%2), r["transactionIndex"])
+			}
+		}
+		if left := anyTestMocksLeft(); left > 0 {
+			t.Errorf("Expected all test mocks to be consumed, got %v left", left)
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		// Finalized blocks are now served from cache without any upstream call
+		receipts = forwardRange(t, network, "0x6", "0x7")
+		assert.Len(t, receipts, 4)
+	})
+
+	t.Run("InvalidRangesAreRejected", func(t *testing.T) {
+		resetGock()
+		defer resetGock()
+		network := newNetwork(t)
+
+		for _, params := range []string{`["0x7","0x5"]`, `["0x1","0x100"]`, `["0x1"]`, `["latest","0x5"]`} {
+			_, err := network.Forward(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"erpc_getBlockReceiptsRange","params":`+params+`}`)))
+			assert.True(t, common.HasErrorCode(err, common.ErrCodeInvalidRequest), "unexpected error for %s: %v", params, err)
+		}
+	})
+}
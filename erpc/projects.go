@@ -76,8 +76,11 @@ func (p *PreparedProject) Forward(ctx context.Context, networkId string, nq *com
 	}
 	method, _ := nq.Method()
 
-	if err := p.acquireRateLimitPermit(nq); err != nil {
-		return nil, err
+	// Fan-out methods are charged per item by the network instead
+	if method != aggregateMethod && method != blockReceiptsRangeMethod {
+		if err := p.acquireRateLimitPermit(nq); err != nil {
+			return nil, err
+		}
 	}
 
 	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
@@ -93,6 +96,7 @@ func (p *PreparedProject) Forward(ctx context.Context, networkId string, nq *com
 	lg := p.Logger.With().Str("method", method).Str("id", nq.Id()).Str("ptr", fmt.Sprintf("%p", nq)).Logger()
 	lg.Debug().Msgf("forwarding request to network")
 	resp, err := network.Forward(ctx, nq)
+	network.metricsTracker.RecordNetworkRequestOutcome(network.NetworkId, method, requestOutcome(err))
 
 	if err == nil || common.HasErrorCode(err, common.ErrCodeEndpointClientSideException) {
 		if err != nil {
@@ -110,6 +114,25 @@ func (p *PreparedProject) Forward(ctx context.Context, networkId string, nq *com
 	return nil, err
 }
 
+func requestOutcome(err error) string {
+	switch {
+	case err == nil:
+		return health.OutcomeSuccess
+	case common.HasErrorCode(err, common.ErrCodeEndpointClientSideException, common.ErrCodeInvalidRequest):
+		return health.OutcomeClientError
+	default:
+		return health.OutcomeUpstreamError
+	}
+}
+
+func (p *PreparedProject) WarmCache(ctx context.Context, networkId string, reqs []*common.NormalizedRequest) error {
+	network, err := p.GetNetwork(networkId)
+	if err != nil {
+		return err
+	}
+	return network.WarmCache(ctx, reqs)
+}
+
 func (p *PreparedProject) initializeNetwork(networkId string) (*Network, error) {
 	// 1) Find all upstreams that support this network
 	err := p.upstreamsRegistry.PrepareUpstreamsForNetwork(networkId)
@@ -177,6 +200,7 @@ func (p *PreparedProject) initializeNetwork(networkId string) (*Network, error)
 	if err != nil {
 		return nil, err
 	}
+	nw.projectRateLimit = p.acquireRateLimitPermit
 
 	err = nw.Bootstrap(p.appCtx)
 	if err != nil {
@@ -220,6 +244,8 @@ func (p *PreparedProject) acquireRateLimitPermit(req *common.NormalizedRequest)
 					p.Config.Id,
 					p.Config.RateLimitBudget,
 					fmt.Sprintf("%+v", rule.Config),
+					rule.Config.MaxCount,
+					rule.ResetIn(),
 				)
 			} else {
 				lg.Debug().Object("rateLimitRule", rule.Config).Msgf("project-level rate limit passed")
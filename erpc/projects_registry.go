@@ -61,6 +61,14 @@ func (r *ProjectsRegistry) GetProject(projectId string) (project *PreparedProjec
 	return
 }
 
+func (r *ProjectsRegistry) GetAll() []*PreparedProject {
+	projects := make([]*PreparedProject, 0, len(r.preparedProjects))
+	for _, project := range r.preparedProjects {
+		projects = append(projects, project)
+	}
+	return projects
+}
+
 func (r *ProjectsRegistry) RegisterProject(prjCfg *common.ProjectConfig) (*PreparedProject, error) {
 	if _, ok := r.preparedProjects[prjCfg.Id]; ok {
 		return nil, common.NewErrProjectAlreadyExists(prjCfg.Id)
@@ -86,6 +94,11 @@ func (r *ProjectsRegistry) RegisterProject(prjCfg *common.ProjectConfig) (*Prepa
 		metricsTracker,
 		1*time.Second,
 	)
+	upstreamsRegistry.SetLocalRegion(prjCfg.LocalRegion)
+	upstreamsRegistry.SetUpstreamGroups(prjCfg.UpstreamGroups)
+	if r.evmJsonRpcCache != nil {
+		upstreamsRegistry.SetCapabilitiesStore(r.evmJsonRpcCache.conn)
+	}
 	err = upstreamsRegistry.Bootstrap(r.appCtx)
 	if err != nil {
 		return nil, err
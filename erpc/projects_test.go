@@ -3,13 +3,18 @@ package erpc
 import (
 	"context"
 	"errors"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/erpc/erpc/common"
+	"github.com/erpc/erpc/health"
 	"github.com/erpc/erpc/upstream"
 	"github.com/erpc/erpc/vendors"
 	"github.com/h2non/gock"
+	promUtil "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestProject_Forward(t *testing.T) {
@@ -95,4 +100,253 @@ func TestProject_Forward(t *testing.T) {
 
 		log.Logger.Info().Msgf("Last Resp: %+v", lastResp)
 	})
+
+	t.Run("AggregateItemsAreRateLimitedIndividually", func(t *testing.T) {
+		defer gock.Off()
+		defer gock.Clean()
+		defer gock.CleanUnmatchedRequest()
+		setupMocksForEvmStatePoller()
+
+		gock.New("http://rpc1.localhost").
+			Post("").
+			Persist().
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getBalance")
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`)
+
+		rateLimitersRegistry, err := upstream.NewRateLimitersRegistry(
+			&common.RateLimiterConfig{
+				Budgets: []*common.RateLimitBudgetConfig{
+					{
+						Id: "MyLimiterBudget_Aggregate",
+						Rules: []*common.RateLimitRuleConfig{
+							{
+								Method:   "*",
+								MaxCount: 3,
+								Period:   "60s",
+							},
+						},
+					},
+				},
+			},
+			&log.Logger,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prjReg, err := NewProjectsRegistry(
+			context.Background(),
+			&log.Logger,
+			[]*common.ProjectConfig{
+				{
+					Id:              "prjAggregate",
+					RateLimitBudget: "MyLimiterBudget_Aggregate",
+					Networks: []*common.NetworkConfig{
+						{
+							Architecture: common.ArchitectureEvm,
+							Evm: &common.EvmNetworkConfig{
+								ChainId: 123,
+							},
+						},
+					},
+					Upstreams: []*common.UpstreamConfig{
+						{
+							Endpoint: "http://rpc1.localhost",
+							Evm: &common.EvmUpstreamConfig{
+								ChainId: 123,
+							},
+						},
+					},
+				},
+			},
+			nil,
+			rateLimitersRegistry,
+			vendors.NewVendorsRegistry(),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prj, err := prjReg.GetProject("prjAggregate")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 5 items with a budget of 3 requests, the aggregate call itself is not charged
+		resp, err := prj.Forward(context.Background(), "evm:123", common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"erpc_aggregate","params":[[
+			{"method":"eth_getBalance","params":["0x00000000000000000000000000000000000000a1","0x1"]},
+			{"method":"eth_getBalance","params":["0x00000000000000000000000000000000000000a2","0x1"]},
+			{"method":"eth_getBalance","params":["0x00000000000000000000000000000000000000a3","0x1"]},
+			{"method":"eth_getBalance","params":["0x00000000000000000000000000000000000000a4","0x1"]},
+			{"method":"eth_getBalance","params":["0x00000000000000000000000000000000000000a5","0x1"]}
+		]]}`)))
+		if !assert.NoError(t, err) {
+			return
+		}
+		jrr, err := resp.JsonRpcResponse()
+		assert.NoError(t, err)
+		assert.Equal(t, 3, strings.Count(string(jrr.Result), `"result":"0x1"`))
+		assert.Equal(t, 2, strings.Count(string(jrr.Result), `"error"`))
+	})
+
+	t.Run("BlockReceiptsRangeIsRateLimitedPerBlock", func(t *testing.T) {
+		defer gock.Off()
+		defer gock.Clean()
+		defer gock.CleanUnmatchedRequest()
+		setupMocksForEvmStatePoller()
+
+		gock.New("http://rpc1.localhost").
+			Post("").
+			Persist().
+			Filter(func(request *http.Request) bool {
+				return strings.Contains(safeReadBody(request), "eth_getBlockReceipts")
+			}).
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":[{"transactionHash":"0x1"}]}`)
+
+		rateLimitersRegistry, err := upstream.NewRateLimitersRegistry(
+			&common.RateLimiterConfig{
+				Budgets: []*common.RateLimitBudgetConfig{
+					{
+						Id: "MyLimiterBudget_Receipts",
+						Rules: []*common.RateLimitRuleConfig{
+							{
+								Method:   "*",
+								MaxCount: 3,
+								Period:   "60s",
+							},
+						},
+					},
+				},
+			},
+			&log.Logger,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prjReg, err := NewProjectsRegistry(
+			context.Background(),
+			&log.Logger,
+			[]*common.ProjectConfig{
+				{
+					Id:              "prjReceipts",
+					RateLimitBudget: "MyLimiterBudget_Receipts",
+					Networks: []*common.NetworkConfig{
+						{
+							Architecture: common.ArchitectureEvm,
+							Evm: &common.EvmNetworkConfig{
+								ChainId: 123,
+							},
+						},
+					},
+					Upstreams: []*common.UpstreamConfig{
+						{
+							Endpoint: "http://rpc1.localhost",
+							Evm: &common.EvmUpstreamConfig{
+								ChainId: 123,
+							},
+						},
+					},
+				},
+			},
+			nil,
+			rateLimitersRegistry,
+			vendors.NewVendorsRegistry(),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prj, err := prjReg.GetProject("prjReceipts")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 3 blocks fit the budget of 3 requests since the range call itself is not charged
+		_, err = prj.Forward(context.Background(), "evm:123", common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"erpc_getBlockReceiptsRange","params":["0x1","0x3"]}`)))
+		assert.NoError(t, err)
+
+		_, err = prj.Forward(context.Background(), "evm:123", common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":2,"method":"erpc_getBlockReceiptsRange","params":["0x4","0x5"]}`)))
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeProjectRateLimitRuleExceeded), err)
+	})
+
+	t.Run("RecordsFinalOutcomePerMethod", func(t *testing.T) {
+		defer gock.Off()
+		defer gock.Clean()
+		defer gock.CleanUnmatchedRequest()
+		setupMocksForEvmStatePoller()
+
+		rateLimitersRegistry, err := upstream.NewRateLimitersRegistry(&common.RateLimiterConfig{}, &log.Logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prjReg, err := NewProjectsRegistry(
+			context.Background(),
+			&log.Logger,
+			[]*common.ProjectConfig{
+				{
+					Id: "prjOutcomes",
+					Networks: []*common.NetworkConfig{
+						{
+							Architecture: common.ArchitectureEvm,
+							Evm: &common.EvmNetworkConfig{
+								ChainId: 123,
+							},
+							Failsafe: &common.FailsafeConfig{},
+						},
+					},
+					Upstreams: []*common.UpstreamConfig{
+						{
+							Endpoint: "http://rpc1.localhost",
+							Evm: &common.EvmUpstreamConfig{
+								ChainId: 123,
+							},
+							Failsafe: &common.FailsafeConfig{},
+						},
+					},
+				},
+			},
+			nil,
+			rateLimitersRegistry,
+			vendors.NewVendorsRegistry(),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prj, err := prjReg.GetProject("prjOutcomes")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		outcome := func(method, outcome string) float64 {
+			return promUtil.ToFloat64(health.MetricNetworkRequestOutcomeTotal.WithLabelValues("prjOutcomes", "evm:123", method, outcome))
+		}
+		mockReply := func(method string, status int, body map[string]interface{}) {
+			gock.New("http://rpc1.localhost").
+				Post("").
+				Filter(func(request *http.Request) bool {
+					return strings.Contains(safeReadBody(request), method)
+				}).
+				Reply(status).
+				JSON(body)
+		}
+
+		mockReply("eth_getBalance", 200, map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": "0x1"})
+		mockReply("eth_getLogs", 500, map[string]interface{}{"jsonrpc": "2.0", "id": 1, "error": map[string]interface{}{"code": -32603, "message": "internal error"}})
+		mockReply("eth_call", 200, map[string]interface{}{"jsonrpc": "2.0", "id": 1, "error": map[string]interface{}{"code": -32602, "message": "invalid argument"}})
+
+		_, err = prj.Forward(context.Background(), "evm:123", common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x111","latest"]}`)))
+		assert.NoError(t, err)
+		_, err = prj.Forward(context.Background(), "evm:123", common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"fromBlock":"0x1","toBlock":"0x2"}]}`)))
+		assert.Error(t, err)
+		_, err = prj.Forward(context.Background(), "evm:123", common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[{"to":"0x111"},"latest"]}`)))
+		assert.Error(t, err)
+
+		assert.Equal(t, float64(1), outcome("eth_getBalance", health.OutcomeSuccess))
+		assert.Equal(t, float64(0), outcome("eth_getBalance", health.OutcomeUpstreamError))
+		assert.Equal(t, float64(1), outcome("eth_getLogs", health.OutcomeUpstreamError))
+		assert.Equal(t, float64(0), outcome("eth_getLogs", health.OutcomeSuccess))
+		assert.Equal(t, float64(1), outcome("eth_call", health.OutcomeClientError))
+		assert.Equal(t, float64(0), outcome("eth_call", health.OutcomeUpstreamError))
+	})
 }
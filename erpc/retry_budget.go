@@ -0,0 +1,54 @@
+package erpc
+
+import (
+	"sync"
+
+	"github.com/erpc/erpc/common"
+)
+
+const defaultRetryBudgetMaxTokens = 10
+
+// retryBudget is a token bucket refilled by requests rather than time, so retries stay proportional
+// to traffic: every request deposits "ratio" tokens and every retry withdraws a whole token.
+type retryBudget struct {
+	ratio     float64
+	maxTokens float64
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+func newRetryBudget(cfg *common.RetryBudgetConfig) *retryBudget {
+	if cfg == nil || cfg.Ratio <= 0 {
+		return nil
+	}
+	maxTokens := cfg.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultRetryBudgetMaxTokens
+	}
+	return &retryBudget{
+		ratio:     cfg.Ratio,
+		maxTokens: maxTokens,
+		tokens:    maxTokens,
+	}
+}
+
+func (b *retryBudget) Deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
+
+// TryWithdraw spends a token for a retry, returns false when the budget does not allow retrying.
+func (b *retryBudget) TryWithdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,127 @@
+package erpc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/erpc/erpc/common"
+)
+
+type routingRule struct {
+	method   string
+	params   []*routingParamPredicate
+	upstream string
+}
+
+type routingParamPredicate struct {
+	path   []string
+	equals string
+}
+
+func newRoutingRules(cfgs []*common.RoutingRuleConfig) ([]*routingRule, error) {
+	rules := make([]*routingRule, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		if cfg.Upstream == "" {
+			return nil, common.NewErrInvalidConfig(fmt.Sprintf("routing rule #%d must define an upstream", i))
+		}
+		method := cfg.Method
+		if method == "" {
+			method = "*"
+		}
+		rule := &routingRule{method: method, upstream: cfg.Upstream}
+		for _, p := range cfg.Params {
+			path := parseParamPath(p.Path)
+			if len(path) == 0 {
+				return nil, common.NewErrInvalidConfig(fmt.Sprintf("routing rule #%d has a param predicate without path", i))
+			}
+			rule.params = append(rule.params, &routingParamPredicate{path: path, equals: p.Equals})
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// parseParamPath accepts both "params[0].to" and "0.to" forms and returns the segments, e.g. ["0", "to"].
+func parseParamPath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "params")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	var segments []string
+	for _, seg := range strings.Split(path, ".") {
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	return segments
+}
+
+// matchRoutingRule returns the first rule whose method and all param predicates match the request, or nil.
+func matchRoutingRule(rules []*routingRule, method string, params []interface{}) *routingRule {
+	for _, rule := range rules {
+		if !common.WildcardMatch(rule.method, method) {
+			continue
+		}
+		matched := true
+		for _, p := range rule.params {
+			v, ok := lookupParam(params, p.path)
+			if !ok || !strings.EqualFold(fmt.Sprint(v), p.equals) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rule
+		}
+	}
+	return nil
+}
+
+func lookupParam(params []interface{}, path []string) (interface{}, bool) {
+	var current interface{} = params
+	for _, seg := range path {
+		switch c := current.(type) {
+		case []interface{}:
+			idx, err := strconv.Atoi(seg)
+			if err != nil || idx < 0 || idx >= len(c) {
+				return nil, false
+			}
+			current = c[idx]
+		case map[string]interface{}:
+			v, ok := c[seg]
+			if !ok {
+				return nil, false
+			}
+			current = v
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// applyRoutingRules pins the request to the upstream(s) of the first matching rule,
+// unless the client already asked for specific upstream(s) via directives.
+func (n *Network) applyRoutingRules(req *common.NormalizedRequest, method string) {
+	if len(n.routingRules) == 0 {
+		return
+	}
+	dirs := req.Directives()
+	if dirs == nil || dirs.UseUpstream != "" {
+		return
+	}
+	jrq, err := req.JsonRpcRequest()
+	if err != nil {
+		return
+	}
+	jrq.RLock()
+	rule := matchRoutingRule(n.routingRules, method, jrq.Params)
+	jrq.RUnlock()
+	if rule == nil {
+		return
+	}
+
+	n.Logger.Debug().Str("method", method).Str("upstream", rule.upstream).Msgf("request matched a routing rule")
+	req.Lock()
+	dirs.UseUpstream = rule.upstream
+	req.Unlock()
+}
@@ -0,0 +1,100 @@
+package erpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/erpc/erpc/common"
+)
+
+// Methods whose stale response would be misleading or harmful, either because they change state
+// or because their whole point is to return the current value (e.g. nonce or gas price).
+var staleIneligibleMethods = map[string]bool{
+	"eth_sendRawTransaction":    true,
+	"eth_sendTransaction":       true,
+	"eth_sendUserOperation":     true,
+	"eth_sign":                  true,
+	"eth_signTransaction":       true,
+	"eth_submitWork":            true,
+	"eth_submitHashrate":        true,
+	"eth_newFilter":             true,
+	"eth_newBlockFilter":        true,
+	"eth_uninstallFilter":       true,
+	"eth_getFilterChanges":      true,
+	"eth_subscribe":             true,
+	"eth_unsubscribe":           true,
+	"eth_blockNumber":           true,
+	"eth_gasPrice":              true,
+	"eth_maxPriorityFeePerGas":  true,
+	"eth_blobBaseFee":           true,
+	"eth_feeHistory":            true,
+	"eth_estimateGas":           true,
+	"eth_getTransactionCount":   true,
+	"eth_syncing":               true,
+	"net_peerCount":             true,
+	"net_listening":             true,
+	"erpc_pollSubscription":     true,
+	"eth_estimateUserOperation": true,
+}
+
+type serveStalePolicy struct {
+	methods []string
+	maxAge  time.Duration
+}
+
+func newServeStalePolicy(cfg *common.ServeStaleOnErrorConfig) (*serveStalePolicy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	p := &serveStalePolicy{methods: cfg.Methods}
+	if cfg.MaxAge != "" {
+		d, err := time.ParseDuration(cfg.MaxAge)
+		if err != nil {
+			return nil, common.NewErrInvalidConfig(fmt.Sprintf("invalid serveStaleOnError.maxAge: %v", err))
+		}
+		p.maxAge = d
+	}
+	return p, nil
+}
+
+func (p *serveStalePolicy) eligible(method string) bool {
+	if staleIneligibleMethods[method] || strings.HasPrefix(method, "personal_") {
+		return false
+	}
+	if len(p.methods) == 0 {
+		return true
+	}
+	for _, m := range p.methods {
+		if common.WildcardMatch(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// staleResponseOnError looks up the most recent cache entry (even if expired) for a request that failed
+// on all upstreams, returns nil when serving stale data is disabled, not allowed for method or not available.
+func (n *Network) staleResponseOnError(ctx context.Context, req *common.NormalizedRequest, method string, cause error) *common.NormalizedResponse {
+	if n.serveStale == nil || n.cacheDal == nil || !n.serveStale.eligible(method) {
+		return nil
+	}
+	// Errors caused by the request itself would be returned by any upstream, serving stale data would hide them
+	if common.HasErrorCode(cause, common.ErrCodeEndpointClientSideException) {
+		return nil
+	}
+
+	// The request context might be already exceeded (e.g. upstreams timed out), which must not prevent the lookup
+	cctx, cancel := context.WithTimeoutCause(context.WithoutCancel(ctx), 2*time.Second, errors.New("cache driver timeout during get stale"))
+	defer cancel()
+	resp, err := n.cacheDal.GetStale(cctx, req, n.serveStale.maxAge)
+	if err != nil || resp == nil || resp.IsObjectNull() || resp.IsResultEmptyish() {
+		return nil
+	}
+
+	n.Logger.Warn().Err(cause).Str("method", method).Dur("cacheAge", resp.CacheAge()).Msgf("all upstreams failed, serving stale response from cache")
+
+	return resp
+}
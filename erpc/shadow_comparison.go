@@ -0,0 +1,99 @@
+package erpc
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/erpc/erpc/health"
+	"github.com/erpc/erpc/upstream"
+)
+
+const shadowComparisonTimeout = 10 * time.Second
+
+// sampleShadowComparison re-sends a sample of successful requests to the reference upstream configured
+// for the upstream that served them, and compares both results in the background. Only read methods are
+// sampled, a write (e.g. eth_sendRawTransaction) must never reach another provider a second time.
+func (n *Network) sampleShadowComparison(method string, req *common.NormalizedRequest, resp *common.NormalizedResponse) {
+	if !upstream.IsIdempotentMethod(method) {
+		return
+	}
+	ups := resp.Upstream()
+	if ups == nil {
+		return
+	}
+	cfg := ups.Config()
+	if cfg.Shadow == nil || cfg.Shadow.SampleRate <= 0 || cfg.Shadow.ReferenceUpstream == "" || cfg.Shadow.ReferenceUpstream == cfg.Id {
+		return
+	}
+	if rand.Float64() >= cfg.Shadow.SampleRate { // #nosec G404
+		return
+	}
+
+	jrr, err := resp.JsonRpcResponse()
+	if err != nil || jrr == nil || jrr.Error != nil {
+		return
+	}
+	// Copy what is needed now since the served response and request might be released or mutated afterwards
+	result := append([]byte(nil), jrr.Result...)
+	body := append([]byte(nil), req.Body()...)
+
+	go n.compareWithReference(method, cfg.Id, cfg.Shadow.ReferenceUpstream, body, result)
+}
+
+func (n *Network) compareWithReference(method, upsId, refId string, body, result []byte) {
+	lg := n.Logger.With().Str("method", method).Str("upstreamId", upsId).Str("referenceUpstreamId", refId).Logger()
+
+	ref, ok := n.upstreamsRegistry.GetUpstream(refId)
+	if !ok {
+		lg.Warn().Msgf("shadow reference upstream is not defined, skipping comparison")
+		return
+	}
+
+	ctx, cancel := context.WithTimeoutCause(context.Background(), shadowComparisonTimeout, errors.New("shadow comparison timeout"))
+	defer cancel()
+
+	sreq := common.NewNormalizedRequest(body)
+	sreq.SetNetwork(n)
+	sresp, err := ref.Forward(ctx, sreq)
+	if err != nil {
+		lg.Debug().Err(err).Msgf("could not get shadow response from reference upstream")
+		return
+	}
+	sjrr, err := sresp.JsonRpcResponse()
+	if err != nil || sjrr == nil || sjrr.Error != nil {
+		lg.Debug().Err(err).Msgf("reference upstream did not return a valid result for shadow comparison")
+		return
+	}
+
+	health.MetricUpstreamShadowComparisonTotal.WithLabelValues(n.ProjectId, n.NetworkId, upsId, method, refId).Inc()
+
+	same, err := equalJsonResults(result, sjrr.Result, n.cfg.ResultFieldsToStrip(method))
+	if err != nil {
+		lg.Debug().Err(err).Msgf("could not canonicalize results for shadow comparison")
+		return
+	}
+	if !same {
+		health.MetricUpstreamShadowMismatchTotal.WithLabelValues(n.ProjectId, n.NetworkId, upsId, method, refId).Inc()
+		lg.Warn().
+			RawJSON("result", result).
+			RawJSON("referenceResult", sjrr.Result).
+			Msgf("upstream response does not match reference upstream")
+	}
+}
+
+// equalJsonResults compares two json results the same way as common.ResponsesEqual, ignoring the given
+// non-deterministic fields.
+func equalJsonResults(a, b []byte, stripFields []string) (bool, error) {
+	a, err := common.StripJsonFields(a, stripFields)
+	if err != nil {
+		return false, err
+	}
+	b, err = common.StripJsonFields(b, stripFields)
+	if err != nil {
+		return false, err
+	}
+	return common.ResponsesEqual(&common.JsonRpcResponse{Result: a}, &common.JsonRpcResponse{Result: b})
+}
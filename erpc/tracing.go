@@ -0,0 +1,113 @@
+package erpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/erpc/erpc"
+
+// Trace context of clients is always extracted so spans join their traces, whereas injection towards
+// upstreams uses the global propagator which stays a no-op unless "propagateToUpstreams" is enabled.
+var incomingPropagator = propagation.TraceContext{}
+
+// InitTracing installs an OTLP/HTTP exporter as global tracer provider, without it all spans are no-op.
+func InitTracing(ctx context.Context, logger *zerolog.Logger, cfg *common.TracingConfig) error {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	opts := []otlptracehttp.Option{}
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "erpc"
+	}
+	sampleRate := cfg.SampleRate
+	if sampleRate <= 0 || sampleRate > 1 {
+		sampleRate = 1
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRate))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(tp)
+	if cfg.PropagateToUpstreams {
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(shutdownCtx); err != nil {
+			logger.Error().Err(err).Msgf("failed to flush remaining trace spans")
+		}
+	}()
+
+	logger.Info().Str("serviceName", serviceName).Float64("sampleRate", sampleRate).Msgf("tracing enabled")
+
+	return nil
+}
+
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan tags the span with outcome of the operation before ending it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("erpc.outcome", "error"))
+	} else {
+		span.SetStatus(codes.Ok, "")
+		span.SetAttributes(attribute.String("erpc.outcome", "success"))
+	}
+	span.End()
+}
+
+// fasthttpHeaderCarrier adapts fasthttp request headers to otel propagation.TextMapCarrier.
+type fasthttpHeaderCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+func (c fasthttpHeaderCarrier) Get(key string) string {
+	return string(c.header.Peek(key))
+}
+
+func (c fasthttpHeaderCarrier) Set(key, value string) {
+	c.header.Set(key, value)
+}
+
+func (c fasthttpHeaderCarrier) Keys() []string {
+	keys := []string{}
+	c.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
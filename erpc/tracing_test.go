@@ -0,0 +1,153 @@
+package erpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/h2non/gock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing_RequestSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prevTp, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(prevTp)
+		otel.SetTextMapPropagator(prevProp)
+	}()
+
+	gock.EnableNetworking()
+	gock.NetworkingFilter(func(req *http.Request) bool {
+		return strings.Split(req.URL.Host, ":")[0] == "localhost"
+	})
+	defer gock.Off()
+
+	const traceId = "4bf92f3577b34da6a3ce929d0e0e4736"
+	var upstreamTraceparent string
+	gock.New("http://rpc1.localhost").
+		Post("").
+		Persist().
+		Filter(func(request *http.Request) bool {
+			if !strings.Contains(safeReadBody(request), "eth_getBalance") {
+				return false
+			}
+			upstreamTraceparent = request.Header.Get("traceparent")
+			return true
+		}).
+		Reply(200).
+		BodyString(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`)
+
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			MaxTimeout: "5s",
+		},
+		Projects: []*common.ProjectConfig{
+			{
+				Id: "test_project",
+				Upstreams: []*common.UpstreamConfig{
+					{
+						Id:         "rpc1",
+						Type:       common.UpstreamTypeEvm,
+						Endpoint:   "http://rpc1.localhost",
+						Evm:        &common.EvmUpstreamConfig{ChainId: 1},
+						VendorName: "llama",
+						// Batched requests are sent on behalf of many clients so they carry no trace context
+						JsonRpc: &common.JsonRpcUpstreamConfig{SupportsBatch: &common.FALSE},
+					},
+				},
+			},
+		},
+		RateLimiters: &common.RateLimiterConfig{},
+	}
+
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache, err := NewEvmJsonRpcCache(ctx, &logger, &common.ConnectorConfig{
+		Driver: "memory",
+		Memory: &common.MemoryConnectorConfig{MaxItems: 100},
+	})
+	require.NoError(t, err)
+	erpcInstance, err := NewERPC(ctx, &logger, cache, cfg)
+	require.NoError(t, err)
+	httpServer, err := NewHttpServer(ctx, &logger, cfg.Server, erpcInstance)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go httpServer.server.Serve(listener) // nolint:errcheck
+
+	req, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("http://localhost:%d/test_project/evm/1", listener.Addr().(*net.TCPAddr).Port),
+		strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x1234","0x10"]}`),
+	)
+	require.NoError(t, err)
+	req.Header.Set("traceparent", "00-"+traceId+"-00f067aa0ba902b7-01")
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Contains(t, string(body), `"result":"0x1"`)
+
+	spansByName := map[string]tracetest.SpanStub{}
+	for _, s := range exporter.GetSpans() {
+		if s.SpanContext.TraceID().String() == traceId {
+			spansByName[s.Name] = s
+		}
+	}
+	attrsOf := func(s tracetest.SpanStub) map[attribute.Key]attribute.Value {
+		m := map[attribute.Key]attribute.Value{}
+		for _, kv := range s.Attributes {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+
+	root, ok := spansByName["erpc.request"]
+	require.True(t, ok, "request span must join the incoming trace")
+	assert.Equal(t, "00f067aa0ba902b7", root.Parent.SpanID().String())
+	assert.Equal(t, "test_project", attrsOf(root)["erpc.project_id"].AsString())
+	assert.Equal(t, "evm:1", attrsOf(root)["erpc.network_id"].AsString())
+	assert.Equal(t, "eth_getBalance", attrsOf(root)["rpc.method"].AsString())
+	assert.Equal(t, "success", attrsOf(root)["erpc.outcome"].AsString())
+
+	for _, name := range []string{"erpc.cache.get", "erpc.upstream.select", "erpc.upstream.forward"} {
+		s, ok := spansByName[name]
+		if assert.True(t, ok, "expected span %s", name) {
+			assert.Equal(t, root.SpanContext.SpanID(), s.Parent.SpanID(), "span %s must be a child of request span", name)
+		}
+	}
+
+	cacheSpan := spansByName["erpc.cache.get"]
+	assert.False(t, attrsOf(cacheSpan)["erpc.cache.hit"].AsBool())
+
+	attempt := spansByName["erpc.upstream.forward"]
+	assert.Equal(t, "rpc1", attrsOf(attempt)["erpc.upstream_id"].AsString())
+	assert.Equal(t, "eth_getBalance", attrsOf(attempt)["rpc.method"].AsString())
+	assert.Equal(t, "success", attrsOf(attempt)["erpc.outcome"].AsString())
+	assert.Equal(t, codes.Ok, attempt.Status.Code)
+
+	// Upstream receives trace context of its attempt span when propagation is enabled
+	assert.Equal(t, "00-"+traceId+"-"+attempt.SpanContext.SpanID().String()+"-01", upstreamTraceparent)
+}
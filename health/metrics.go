@@ -61,6 +61,54 @@ var (
 		Help:      "Total number of empty responses from upstreams.",
 	}, []string{"project", "network", "upstream", "category"})
 
+	MetricUpstreamEmptyBodyTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "erpc",
+		Name:      "upstream_request_empty_body_total",
+		Help:      "Total number of successful http responses with an empty body from upstreams (e.g. keep-alive races or load balancer hiccups).",
+	}, []string{"project", "network", "upstream", "category"})
+
+	MetricUpstreamShadowComparisonTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "erpc",
+		Name:      "upstream_shadow_comparison_total",
+		Help:      "Total number of sampled upstream responses compared against a reference upstream.",
+	}, []string{"project", "network", "upstream", "category", "reference"})
+
+	MetricUpstreamShadowMismatchTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "erpc",
+		Name:      "upstream_shadow_mismatch_total",
+		Help:      "Total number of sampled upstream responses that did not match the reference upstream.",
+	}, []string{"project", "network", "upstream", "category", "reference"})
+
+	MetricUpstreamEmptyLogsVerificationTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "erpc",
+		Name:      "upstream_empty_logs_verification_total",
+		Help:      "Total number of empty eth_getLogs results of an upstream re-queried on another upstream for verification.",
+	}, []string{"project", "network", "upstream", "verifier"})
+
+	MetricUpstreamEmptyLogsDiscrepancyTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "erpc",
+		Name:      "upstream_empty_logs_discrepancy_total",
+		Help:      "Total number of empty eth_getLogs results of an upstream for which another upstream returned logs.",
+	}, []string{"project", "network", "upstream", "verifier"})
+
+	MetricUpstreamRequestCostTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "erpc",
+		Name:      "upstream_request_cost_total",
+		Help:      "Accumulated billing cost of requests to upstreams, based on their configured cost weights.",
+	}, []string{"project", "network", "upstream", "category"})
+
+	MetricUpstreamInjectedFaultTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "erpc",
+		Name:      "upstream_injected_fault_total",
+		Help:      "Total number of faults injected into requests to upstreams for chaos testing.",
+	}, []string{"project", "network", "upstream", "category", "fault"})
+
+	MetricUpstreamQuarantined = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "erpc",
+		Name:      "upstream_quarantined",
+		Help:      "Whether an upstream is manually quarantined by an operator (1) or serving traffic (0).",
+	}, []string{"project", "upstream"})
+
 	MetricUpstreamBlockHeadLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: "erpc",
 		Name:      "upstream_block_head_lag",
@@ -103,6 +151,18 @@ var (
 		Help:      "Total number of successful requests for a network.",
 	}, []string{"project", "network", "category"})
 
+	MetricNetworkRequestOutcomeTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "erpc",
+		Name:      "network_request_outcome_total",
+		Help:      "Total number of requests for a network by final outcome after failover (success, client_error or upstream_error).",
+	}, []string{"project", "network", "category", "outcome"})
+
+	MetricNetworkDeprecatedMethodCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "erpc",
+		Name:      "network_deprecated_method_calls_total",
+		Help:      "Total number of requests for methods configured as deprecated on a network, by configured method or pattern.",
+	}, []string{"project", "network", "category"})
+
 	MetricNetworkCacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "erpc",
 		Name:      "network_cache_hits_total",
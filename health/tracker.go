@@ -197,6 +197,21 @@ func (t *Tracker) RecordUpstreamRemoteRateLimited(ups, network, method string) {
 	MetricUpstreamRemoteRateLimitedTotal.WithLabelValues(t.projectId, network, ups, method).Inc()
 }
 
+// Final outcomes of network requests, client errors (e.g. invalid params or reverts) are kept apart
+// from upstream failures so that alerts on a method's success rate are not triggered by bad clients.
+const (
+	OutcomeSuccess       = "success"
+	OutcomeClientError   = "client_error"
+	OutcomeUpstreamError = "upstream_error"
+)
+
+func (t *Tracker) RecordNetworkRequestOutcome(network, method, outcome string) {
+	if t == nil {
+		return
+	}
+	MetricNetworkRequestOutcomeTotal.WithLabelValues(t.projectId, network, method, outcome).Inc()
+}
+
 func (t *Tracker) GetUpstreamMethodMetrics(ups, network, method string) *TrackedMetrics {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -286,7 +286,8 @@ func prepareERPCConfig(fs afero.Fs, config StressTestConfig) (string, string, er
 func initializeERPC(fs afero.Fs, configPath string) error {
 	args := []string{"erpc-test", configPath}
 	logger := log.With().Logger()
-	return erpc.Init(context.Background(), logger, fs, args)
+	_, err := erpc.Init(context.Background(), logger, fs, args)
+	return err
 }
 
 func runK6StressTest(fs afero.Fs, baseUrl string, config StressTestConfig) error {
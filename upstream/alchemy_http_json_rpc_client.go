@@ -141,6 +141,18 @@ func (c *AlchemyHttpJsonRpcClient) getOrCreateClient(network common.Network) (Ht
 	return client, nil
 }
 
+func (c *AlchemyHttpJsonRpcClient) HealthCheck(ctx context.Context) error {
+	client, err := representativeClient(c.upstream, &c.mu, c.clients, c.getOrCreateClient)
+	if err != nil {
+		return err
+	}
+	return client.HealthCheck(ctx)
+}
+
+func (c *AlchemyHttpJsonRpcClient) Close() error {
+	return closeClients(&c.mu, c.clients)
+}
+
 func (c *AlchemyHttpJsonRpcClient) SendRequest(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error) {
 	network := req.Network()
 	if network == nil {
@@ -174,6 +174,18 @@ func (c *BlastapiHttpJsonRpcClient) getOrCreateClient(network common.Network) (H
 	return client, nil
 }
 
+func (c *BlastapiHttpJsonRpcClient) HealthCheck(ctx context.Context) error {
+	client, err := representativeClient(c.upstream, &c.mu, c.clients, c.getOrCreateClient)
+	if err != nil {
+		return err
+	}
+	return client.HealthCheck(ctx)
+}
+
+func (c *BlastapiHttpJsonRpcClient) Close() error {
+	return closeClients(&c.mu, c.clients)
+}
+
 func (c *BlastapiHttpJsonRpcClient) SendRequest(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error) {
 	network := req.Network()
 	if network == nil {
@@ -0,0 +1,292 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/erpc/erpc/data"
+	"github.com/rs/zerolog"
+)
+
+type Capability string
+
+const (
+	CapabilityTrace   Capability = "trace"
+	CapabilityDebug   Capability = "debug"
+	CapabilityArchive Capability = "archive"
+	// CapabilityBlockReceipts is for eth_getBlockReceipts, which is still missing on some clients and providers
+	CapabilityBlockReceipts Capability = "blockReceipts"
+)
+
+type CapabilityState int
+
+const (
+	// CapabilityUnknown means the capability was never probed, or the last probe failed for reasons
+	// unrelated to the capability itself (e.g. timeout), so routing must not rely on it either way.
+	CapabilityUnknown CapabilityState = iota
+	CapabilitySupported
+	CapabilityUnsupported
+)
+
+const (
+	defaultCapabilityProbeTtl     = 1 * time.Hour
+	defaultCapabilityProbeTimeout = 10 * time.Second
+)
+
+var defaultProbedCapabilities = []Capability{CapabilityTrace, CapabilityDebug, CapabilityArchive, CapabilityBlockReceipts}
+
+// Cheap requests whose outcome tells whether an evm node supports a capability.
+var capabilityProbeRequests = map[Capability]func() (*common.NormalizedRequest, error){
+	CapabilityTrace: func() (*common.NormalizedRequest, error) {
+		return common.NewInternalRequest("trace_block", []interface{}{"0x1"})
+	},
+	CapabilityDebug: func() (*common.NormalizedRequest, error) {
+		return common.NewInternalRequest("debug_traceBlockByNumber", []interface{}{"0x1", map[string]interface{}{"tracer": "callTracer"}})
+	},
+	CapabilityArchive: func() (*common.NormalizedRequest, error) {
+		return common.NewInternalRequest("eth_getBalance", []interface{}{"0x0000000000000000000000000000000000000000", "0x1"})
+	},
+	CapabilityBlockReceipts: func() (*common.NormalizedRequest, error) {
+		return common.NewInternalRequest("eth_getBlockReceipts", []interface{}{"0x1"})
+	},
+}
+
+// capabilityMethodPrefixes maps method namespaces to the capability they require.
+var capabilityMethodPrefixes = map[string]Capability{
+	"trace_": CapabilityTrace,
+	"debug_": CapabilityDebug,
+}
+
+// capabilityMethods maps single methods to the capability they require.
+var capabilityMethods = map[string]Capability{
+	"eth_getBlockReceipts": CapabilityBlockReceipts,
+}
+
+type capabilityEntry struct {
+	state     CapabilityState
+	expiresAt time.Time
+}
+
+// capabilityCache keeps probed capabilities of an upstream for a ttl, so that routing decisions read
+// the last known results instead of probing per request. Expired (or unknown) entries are re-probed
+// by Refresh, which runs in background on an interval. When a store is set, probe results are also
+// written to it and loaded back on start, unknown states are never persisted.
+type capabilityCache struct {
+	mu           sync.RWMutex
+	entries      map[Capability]*capabilityEntry
+	capabilities []Capability
+	ttl          time.Duration
+	probe        func(ctx context.Context, capability Capability) (bool, error)
+	now          func() time.Time
+	store        data.Connector
+	storeKey     string
+	logger       *zerolog.Logger
+}
+
+func newCapabilityCache(cfg *common.CapabilityProbeConfig, probe func(ctx context.Context, capability Capability) (bool, error)) (*capabilityCache, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	c := &capabilityCache{
+		entries:      make(map[Capability]*capabilityEntry),
+		capabilities: defaultProbedCapabilities,
+		ttl:          defaultCapabilityProbeTtl,
+		probe:        probe,
+		now:          time.Now,
+	}
+	if cfg.Ttl != "" {
+		ttl, err := time.ParseDuration(cfg.Ttl)
+		if err != nil || ttl <= 0 {
+			return nil, common.NewErrInvalidConfig(fmt.Sprintf("invalid capabilityProbe.ttl: %s", cfg.Ttl))
+		}
+		c.ttl = ttl
+	}
+	if len(cfg.Capabilities) > 0 {
+		c.capabilities = nil
+		for _, name := range cfg.Capabilities {
+			capability := Capability(name)
+			if _, ok := capabilityProbeRequests[capability]; !ok {
+				return nil, common.NewErrInvalidConfig(fmt.Sprintf("unknown capability in capabilityProbe.capabilities: %s", name))
+			}
+			c.capabilities = append(c.capabilities, capability)
+		}
+	}
+	return c, nil
+}
+
+// State returns the last probed state of a capability, even if expired and waiting to be refreshed.
+func (c *capabilityCache) State(capability Capability) CapabilityState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if e, ok := c.entries[capability]; ok {
+		return e.state
+	}
+	return CapabilityUnknown
+}
+
+// Refresh probes capabilities that were never probed, are expired, or whose last probe failed.
+func (c *capabilityCache) Refresh(ctx context.Context) {
+	for _, capability := range c.capabilities {
+		c.mu.RLock()
+		e, ok := c.entries[capability]
+		due := !ok || e.state == CapabilityUnknown || !c.now().Before(e.expiresAt)
+		c.mu.RUnlock()
+		if !due {
+			continue
+		}
+
+		state := CapabilityUnknown
+		if supported, err := c.probe(ctx, capability); err == nil {
+			state = CapabilityUnsupported
+			if supported {
+				state = CapabilitySupported
+			}
+		}
+
+		entry := &capabilityEntry{state: state, expiresAt: c.now().Add(c.ttl)}
+		c.mu.Lock()
+		c.entries[capability] = entry
+		c.mu.Unlock()
+		if state != CapabilityUnknown {
+			c.persist(ctx, capability, entry)
+		}
+	}
+}
+
+// Stored values are "<state>|<expires-at-unix-millis>", e.g. "1|1700000000000".
+func (c *capabilityCache) persist(ctx context.Context, capability Capability, entry *capabilityEntry) {
+	if c.store == nil {
+		return
+	}
+	value := fmt.Sprintf("%d|%d", entry.state, entry.expiresAt.UnixMilli())
+	if err := c.store.Set(ctx, c.storeKey, string(capability), value, &c.ttl); err != nil && c.logger != nil {
+		c.logger.Warn().Err(err).Str("capability", string(capability)).Msg("could not persist probed upstream capability")
+	}
+}
+
+// load restores persisted capabilities that are not expired yet, missing or malformed ones are probed as usual.
+func (c *capabilityCache) load(ctx context.Context) {
+	if c.store == nil {
+		return
+	}
+	for _, capability := range c.capabilities {
+		value, err := c.store.Get(ctx, data.ConnectorMainIndex, c.storeKey, string(capability))
+		if err != nil {
+			continue
+		}
+		stateStr, expiresStr, ok := strings.Cut(value, "|")
+		if !ok {
+			continue
+		}
+		state, err := strconv.Atoi(stateStr)
+		if err != nil || (CapabilityState(state) != CapabilitySupported && CapabilityState(state) != CapabilityUnsupported) {
+			continue
+		}
+		expiresMs, err := strconv.ParseInt(expiresStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		expiresAt := time.UnixMilli(expiresMs)
+		if !c.now().Before(expiresAt) {
+			continue
+		}
+		c.mu.Lock()
+		c.entries[capability] = &capabilityEntry{state: CapabilityState(state), expiresAt: expiresAt}
+		c.mu.Unlock()
+	}
+}
+
+// refreshInterval keeps entries fresh before they expire, while retrying failed probes reasonably soon.
+func (c *capabilityCache) refreshInterval() time.Duration {
+	return max(c.ttl/4, time.Second)
+}
+
+func (c *capabilityCache) run(ctx context.Context) {
+	c.load(ctx)
+	c.Refresh(ctx)
+	ticker := time.NewTicker(c.refreshInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Refresh(ctx)
+		}
+	}
+}
+
+// StartCapabilityProbes probes configured capabilities and keeps refreshing them until ctx is done,
+// results are persisted in the store when it is not nil.
+func (u *Upstream) StartCapabilityProbes(ctx context.Context, store data.Connector) {
+	if u.capabilities == nil {
+		return
+	}
+	if store != nil {
+		u.capabilities.store = store
+		u.capabilities.storeKey = fmt.Sprintf("capabilities:%s:%s", u.ProjectId, u.config.Id)
+		u.capabilities.logger = &u.Logger
+	}
+	go u.capabilities.run(ctx)
+}
+
+// Capability returns the last known state of a capability, CapabilityUnknown when probing is disabled.
+func (u *Upstream) Capability(capability Capability) CapabilityState {
+	if u.capabilities == nil {
+		return CapabilityUnknown
+	}
+	return u.capabilities.State(capability)
+}
+
+// probeCapability returns an error only when the outcome says nothing about the capability (e.g. network
+// failure or rate limit), an unsupported method or pruned state means the capability is not available.
+func (u *Upstream) probeCapability(ctx context.Context, capability Capability) (bool, error) {
+	newProbe, ok := capabilityProbeRequests[capability]
+	if !ok {
+		return false, fmt.Errorf("no probe defined for capability: %s", capability)
+	}
+
+	probe, err := newProbe()
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultCapabilityProbeTimeout)
+	defer cancel()
+
+	_, err = u.Forward(ctx, probe)
+	if err == nil {
+		return true, nil
+	}
+	if common.HasErrorCode(err, common.ErrCodeEndpointUnsupported) ||
+		common.HasErrorCode(err, common.ErrCodeUpstreamMethodIgnored) {
+		// Methods ignored by config are not probed at all, which is the same as unsupported for routing
+		return false, nil
+	}
+	if common.HasErrorCode(err, common.ErrCodeEndpointMissingData) {
+		// Trace and debug methods exist even if early blocks are not traceable, but old state is what archive means
+		return capability != CapabilityArchive, nil
+	}
+	if common.HasErrorCode(err, common.ErrCodeEndpointClientSideException) {
+		// Method exists but rejected our params, capability is still available
+		return true, nil
+	}
+	return false, err
+}
+
+func methodCapability(method string) (Capability, bool) {
+	if capability, ok := capabilityMethods[method]; ok {
+		return capability, true
+	}
+	for prefix, capability := range capabilityMethodPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return capability, true
+		}
+	}
+	return "", false
+}
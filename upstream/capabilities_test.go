@@ -0,0 +1,122 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/erpc/erpc/data"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilityCache(t *testing.T) {
+	newCache := func(t *testing.T, probe func(ctx context.Context, capability Capability) (bool, error)) (*capabilityCache, *time.Time) {
+		c, err := newCapabilityCache(&common.CapabilityProbeConfig{
+			Ttl:          "1m",
+			Capabilities: []string{"trace"},
+		}, probe)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		now := time.Now()
+		c.now = func() time.Time { return now }
+		return c, &now
+	}
+
+	t.Run("ProbedCapabilityIsReusedWithinTtlAndReprobedAfterExpiry", func(t *testing.T) {
+		probes := 0
+		c, now := newCache(t, func(ctx context.Context, capability Capability) (bool, error) {
+			probes++
+			return probes == 1, nil
+		})
+
+		assert.Equal(t, CapabilityUnknown, c.State(CapabilityTrace))
+
+		c.Refresh(context.Background())
+		assert.Equal(t, 1, probes)
+		assert.Equal(t, CapabilitySupported, c.State(CapabilityTrace))
+
+		*now = now.Add(59 * time.Second)
+		c.Refresh(context.Background())
+		assert.Equal(t, 1, probes, "must not re-probe within ttl")
+		assert.Equal(t, CapabilitySupported, c.State(CapabilityTrace))
+
+		*now = now.Add(2 * time.Second)
+		c.Refresh(context.Background())
+		assert.Equal(t, 2, probes, "must re-probe after ttl")
+		assert.Equal(t, CapabilityUnsupported, c.State(CapabilityTrace))
+	})
+
+	t.Run("ProbeFailureMarksCapabilityUnknownAndIsRetried", func(t *testing.T) {
+		probes := 0
+		c, _ := newCache(t, func(ctx context.Context, capability Capability) (bool, error) {
+			probes++
+			if probes == 1 {
+				return false, errors.New("connection refused")
+			}
+			return true, nil
+		})
+
+		c.Refresh(context.Background())
+		assert.Equal(t, CapabilityUnknown, c.State(CapabilityTrace))
+
+		c.Refresh(context.Background())
+		assert.Equal(t, 2, probes, "failed probe must be retried without waiting for ttl")
+		assert.Equal(t, CapabilitySupported, c.State(CapabilityTrace))
+	})
+
+	t.Run("PersistedCapabilityIsReusedAfterRestartWithinTtl", func(t *testing.T) {
+		logger := zerolog.Nop()
+		store, err := data.NewMemoryConnector(context.Background(), &logger, &common.MemoryConnectorConfig{MaxItems: 100})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		probes := 0
+		probe := func(ctx context.Context, capability Capability) (bool, error) {
+			probes++
+			return true, nil
+		}
+		first, now := newCache(t, probe)
+		first.store, first.storeKey = store, "capabilities:prj:rpc1"
+		first.load(context.Background())
+		first.Refresh(context.Background())
+		assert.Equal(t, 1, probes)
+
+		restarted, _ := newCache(t, probe)
+		restarted.store, restarted.storeKey = store, "capabilities:prj:rpc1"
+		restarted.now = func() time.Time { return now.Add(30 * time.Second) }
+		restarted.load(context.Background())
+		restarted.Refresh(context.Background())
+		assert.Equal(t, 1, probes, "must reuse the persisted capability within ttl")
+		assert.Equal(t, CapabilitySupported, restarted.State(CapabilityTrace))
+
+		expired, _ := newCache(t, probe)
+		expired.store, expired.storeKey = store, "capabilities:prj:rpc1"
+		expired.now = func() time.Time { return now.Add(2 * time.Minute) }
+		expired.load(context.Background())
+		assert.Equal(t, CapabilityUnknown, expired.State(CapabilityTrace))
+		expired.Refresh(context.Background())
+		assert.Equal(t, 2, probes, "must re-probe once the persisted capability expired")
+	})
+
+	t.Run("BlockReceiptsMethodRequiresItsCapability", func(t *testing.T) {
+		capability, ok := methodCapability("eth_getBlockReceipts")
+		assert.True(t, ok)
+		assert.Equal(t, CapabilityBlockReceipts, capability)
+
+		_, ok = methodCapability("eth_getTransactionReceipt")
+		assert.False(t, ok)
+
+		_, err := newCapabilityCache(&common.CapabilityProbeConfig{Capabilities: []string{"blockReceipts"}}, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("UnknownCapabilityIsRejected", func(t *testing.T) {
+		_, err := newCapabilityCache(&common.CapabilityProbeConfig{Capabilities: []string{"teleport"}}, nil)
+		assert.Error(t, err)
+	})
+}
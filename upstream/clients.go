@@ -1,6 +1,7 @@
 package upstream
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"sync"
@@ -70,7 +71,7 @@ func (manager *ClientRegistry) CreateClient(ups *Upstream) (ClientInterface, err
 				if parsedUrl.Scheme == "http" || parsedUrl.Scheme == "https" {
 					newClient, err = NewGenericHttpJsonRpcClient(manager.logger, ups, parsedUrl)
 					if err != nil {
-						clientErr = fmt.Errorf("failed to create HTTP client for upstream: %v", cfg.Id)
+						clientErr = fmt.Errorf("failed to create HTTP client for upstream: %v: %w", cfg.Id, err)
 					}
 				} else if parsedUrl.Scheme == "ws" || parsedUrl.Scheme == "wss" {
 					clientErr = fmt.Errorf("websocket client not implemented yet")
@@ -132,3 +133,32 @@ func (manager *ClientRegistry) CreateClient(ups *Upstream) (ClientInterface, err
 
 	return newClient, clientErr
 }
+
+// Close closes all clients created so far, see HttpJsonRpcClient.Close.
+func (manager *ClientRegistry) Close() error {
+	var errs []error
+	manager.clients.Range(func(_, client interface{}) bool {
+		if c, ok := client.(HttpJsonRpcClient); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return true
+	})
+	return errors.Join(errs...)
+}
+
+// closeClients closes every underlying client of a vendor client, so that none of the networks it served
+// keeps connections open.
+func closeClients[K comparable](mu *sync.RWMutex, clients map[K]HttpJsonRpcClient) error {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var errs []error
+	for _, c := range clients {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
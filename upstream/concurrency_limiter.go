@@ -0,0 +1,137 @@
+package upstream
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+
+	"github.com/erpc/erpc/common"
+)
+
+const defaultConcurrencyMaxQueueSize = 1000
+
+// ConcurrencyLimiter caps in-flight requests towards an upstream. When saturated, requests wait in a
+// bounded queue and freed slots are handed to the highest priority waiter first (FIFO within a priority).
+type ConcurrencyLimiter struct {
+	upstreamId    string
+	maxConcurrent int
+	maxQueueSize  int
+
+	mu       sync.Mutex
+	inFlight int
+	queue    concurrencyQueue
+	seq      uint64
+}
+
+func NewConcurrencyLimiter(upstreamId string, cfg *common.ConcurrencyConfig) *ConcurrencyLimiter {
+	maxQueueSize := cfg.MaxQueueSize
+	if maxQueueSize <= 0 {
+		maxQueueSize = defaultConcurrencyMaxQueueSize
+	}
+	return &ConcurrencyLimiter{
+		upstreamId:    upstreamId,
+		maxConcurrent: cfg.MaxConcurrent,
+		maxQueueSize:  maxQueueSize,
+	}
+}
+
+// Acquire blocks until a slot is available (or ctx is done), callers must call Release once finished.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context, priority common.RequestPriority) error {
+	l.mu.Lock()
+	if l.inFlight < l.maxConcurrent && l.queue.Len() == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return nil
+	}
+	if l.queue.Len() >= l.maxQueueSize {
+		l.mu.Unlock()
+		return common.NewErrUpstreamConcurrencyQueueFull(l.upstreamId, l.maxConcurrent, l.maxQueueSize)
+	}
+	l.seq++
+	w := &concurrencyWaiter{
+		priority: priority,
+		seq:      l.seq,
+		ready:    make(chan struct{}),
+	}
+	heap.Push(&l.queue, w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		granted := w.index < 0
+		if !granted {
+			heap.Remove(&l.queue, w.index)
+		}
+		l.mu.Unlock()
+		if granted {
+			// The slot was handed over while giving up, pass it on to the next waiter
+			l.Release()
+		}
+		return ctx.Err()
+	}
+}
+
+func (l *ConcurrencyLimiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.queue.Len() > 0 {
+		// Hand the slot directly to the next waiter so in-flight count stays the same
+		w := heap.Pop(&l.queue).(*concurrencyWaiter)
+		close(w.ready)
+		return
+	}
+	if l.inFlight > 0 {
+		l.inFlight--
+	}
+}
+
+// Queued returns how many requests are currently waiting for a slot.
+func (l *ConcurrencyLimiter) Queued() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.queue.Len()
+}
+
+type concurrencyWaiter struct {
+	priority common.RequestPriority
+	seq      uint64
+	ready    chan struct{}
+	index    int
+}
+
+type concurrencyQueue []*concurrencyWaiter
+
+func (q concurrencyQueue) Len() int { return len(q) }
+
+func (q concurrencyQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q concurrencyQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *concurrencyQueue) Push(x interface{}) {
+	w := x.(*concurrencyWaiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *concurrencyQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*q = old[:n-1]
+	return w
+}
@@ -0,0 +1,233 @@
+package upstream
+
+import (
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erpc/erpc/common"
+)
+
+const (
+	defaultDebugBundleFailureThreshold = 3
+	defaultDebugBundleMaxBundles       = 10
+	maxDebugBundleBodySize             = 64 * 1024
+	redactedValue                      = "REDACTED"
+)
+
+// Headers that carry credentials under a generic name, other headers are redacted when their name hints at a secret.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+var sensitiveHeaderHints = []string{"key", "token", "secret", "auth", "password"}
+
+// DebugBundle is a snapshot of one exchange with an upstream, captured after repeated failures of a method.
+type DebugBundle struct {
+	UpstreamId          string            `json:"upstreamId"`
+	Method              string            `json:"method"`
+	ConsecutiveFailures int               `json:"consecutiveFailures"`
+	CapturedAt          time.Time         `json:"capturedAt"`
+	Url                 string            `json:"url"`
+	RequestHeaders      map[string]string `json:"requestHeaders,omitempty"`
+	RequestBody         string            `json:"requestBody"`
+	StatusCode          int               `json:"statusCode,omitempty"`
+	ResponseHeaders     map[string]string `json:"responseHeaders,omitempty"`
+	ResponseBody        string            `json:"responseBody,omitempty"`
+	DurationMs          int64             `json:"durationMs"`
+	Error               string            `json:"error,omitempty"`
+}
+
+// debugBundleRecorder counts consecutive failures per method and keeps the latest captured bundles.
+type debugBundleRecorder struct {
+	mu         sync.Mutex
+	threshold  int
+	maxBundles int
+	failures   map[string]int
+	bundles    []*DebugBundle
+}
+
+func newDebugBundleRecorder(cfg *common.DebugBundleConfig) *debugBundleRecorder {
+	if cfg == nil {
+		return nil
+	}
+	r := &debugBundleRecorder{
+		threshold:  defaultDebugBundleFailureThreshold,
+		maxBundles: defaultDebugBundleMaxBundles,
+		failures:   make(map[string]int),
+	}
+	if cfg.FailureThreshold > 0 {
+		r.threshold = cfg.FailureThreshold
+	}
+	if cfg.MaxBundles > 0 {
+		r.maxBundles = cfg.MaxBundles
+	}
+	return r
+}
+
+// observe resets the failure streak of a method on success, and returns the streak length when a failure
+// completes a streak of threshold failures (i.e. every Nth consecutive failure is captured), 0 otherwise.
+func (r *debugBundleRecorder) observe(method string, failed bool) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !failed {
+		delete(r.failures, method)
+		return 0
+	}
+	r.failures[method]++
+	count := r.failures[method]
+	if count%r.threshold != 0 {
+		return 0
+	}
+	return count
+}
+
+func (r *debugBundleRecorder) add(bundle *DebugBundle) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.bundles = append(r.bundles, bundle)
+	if len(r.bundles) > r.maxBundles {
+		r.bundles = r.bundles[len(r.bundles)-r.maxBundles:]
+	}
+}
+
+func (r *debugBundleRecorder) Bundles() []*DebugBundle {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*DebugBundle(nil), r.bundles...)
+}
+
+// DebugBundles returns the latest captured bundles, oldest first, nil when capture is not enabled.
+func (u *Upstream) DebugBundles() []*DebugBundle {
+	if u.debugBundles == nil {
+		return nil
+	}
+	return u.debugBundles.Bundles()
+}
+
+// exchangeOutcome is the result of one json-rpc request of an exchange, a batch exchange has one per request.
+type exchangeOutcome struct {
+	method string
+	err    error
+}
+
+// recordDebugBundle feeds a single request exchange to the upstream's recorder, see recordExchange.
+func (c *GenericHttpJsonRpcClient) recordDebugBundle(method string, requestBody []byte, resp *http.Response, respBody []byte, startedAt time.Time, err error) {
+	c.recordExchange([]exchangeOutcome{{method: method, err: err}}, requestBody, resp, respBody, startedAt)
+}
+
+// recordExchange feeds the outcome of each request of an exchange to the upstream's recorder, and logs a bundle
+// for every method whose failure streak reached the threshold. Errors caused by the request itself are not
+// counted against the upstream. Independently of the recorder, a sampled share of all exchanges is logged when
+// captureSample is configured. The bundle is only built when it is going to be logged.
+func (c *GenericHttpJsonRpcClient) recordExchange(outcomes []exchangeOutcome, requestBody []byte, resp *http.Response, respBody []byte, startedAt time.Time) {
+	if c.upstream == nil || len(outcomes) == 0 {
+		return
+	}
+	sampled := c.sampleRate > 0 && rand.Float64() < c.sampleRate // #nosec G404
+	var captured []exchangeOutcome
+	var streaks []int
+	if c.upstream.debugBundles != nil {
+		for _, o := range outcomes {
+			if o.err != nil && common.HasErrorCode(o.err, common.ErrCodeEndpointClientSideException) {
+				continue
+			}
+			if count := c.upstream.debugBundles.observe(o.method, o.err != nil); count > 0 {
+				captured = append(captured, o)
+				streaks = append(streaks, count)
+			}
+		}
+	}
+	if !sampled && len(captured) == 0 {
+		return
+	}
+
+	methods := make([]string, 0, len(outcomes))
+	for _, o := range outcomes {
+		methods = append(methods, o.method)
+	}
+	bundle := &DebugBundle{
+		UpstreamId:  c.upstream.Config().Id,
+		Method:      strings.Join(methods, ","),
+		CapturedAt:  time.Now(),
+		Url:         redactUrl(c.Url),
+		RequestBody: string(requestBody),
+		DurationMs:  time.Since(startedAt).Milliseconds(),
+	}
+	if resp != nil {
+		bundle.StatusCode = resp.StatusCode
+		bundle.ResponseHeaders = redactHeaders(resp.Header)
+		if resp.Request != nil {
+			bundle.RequestHeaders = redactHeaders(resp.Request.Header)
+		}
+	}
+	if len(respBody) > maxDebugBundleBodySize {
+		bundle.ResponseBody = string(respBody[:maxDebugBundleBodySize]) + "...(truncated)"
+	} else {
+		bundle.ResponseBody = string(respBody)
+	}
+	if len(outcomes) == 1 && outcomes[0].err != nil {
+		bundle.Error = outcomes[0].err.Error()
+	}
+
+	if sampled {
+		c.logger.Info().Interface("exchange", bundle).Msgf("sampled exchange with upstream for method %s", bundle.Method)
+	}
+	for i, o := range captured {
+		cb := *bundle
+		cb.Method = o.method
+		cb.ConsecutiveFailures = streaks[i]
+		cb.Error = o.err.Error()
+		c.upstream.debugBundles.add(&cb)
+		c.logger.Warn().Interface("debugBundle", &cb).Msgf("upstream failed %d times in a row for method %s, captured debug bundle", cb.ConsecutiveFailures, o.method)
+	}
+}
+
+// redactUrl keeps only scheme and host, because most providers put api keys in path, query or user info.
+func redactUrl(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	s := u.Scheme + "://" + u.Host
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+		s += "/" + redactedValue
+	}
+	return s
+}
+
+func redactHeaders(h http.Header) map[string]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		if isSensitiveHeader(name) {
+			out[name] = redactedValue
+		} else {
+			out[name] = strings.Join(values, ", ")
+		}
+	}
+	return out
+}
+
+func isSensitiveHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	if sensitiveHeaders[name] {
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, hint := range sensitiveHeaderHints {
+		if strings.Contains(lower, hint) {
+			return true
+		}
+	}
+	return false
+}
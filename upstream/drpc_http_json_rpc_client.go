@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/erpc/erpc/common"
 )
@@ -128,26 +130,61 @@ var drpcNetworkNames = map[int64]string{
 	999999999:   "zora-sepolia",
 }
 
+const drpcDefaultBaseUrl = "https://lb.drpc.org/ogrpc"
+
+// How long a key that hit dRPC rate limits is skipped while other keys of the pool are available.
+var drpcKeyCooldown = 30 * time.Second
+
 type DrpcHttpJsonRpcClient struct {
 	upstream *Upstream
-	apiKey   string
+	keys     []*drpcKey
+	next     atomic.Uint64
+	baseUrl  string
 	clients  map[string]HttpJsonRpcClient
 	mu       sync.RWMutex
 }
 
+type drpcKey struct {
+	apiKey string
+	// Unix nanoseconds until which the key is skipped because it was rate-limited
+	cooldownUntil atomic.Int64
+}
+
+// NewDrpcHttpJsonRpcClient accepts drpc://KEY, drpc://KEY/ and drpc://KEY?baseUrl=https://... forms,
+// several comma-separated keys (drpc://KEY1,KEY2) are rotated to spread load across them.
+// Unknown query params are ignored so that the same endpoint can be shared with other tooling.
 func NewDrpcHttpJsonRpcClient(pu *Upstream, parsedUrl *url.URL) (HttpJsonRpcClient, error) {
 	if !strings.HasSuffix(parsedUrl.Scheme, "drpc") {
 		return nil, fmt.Errorf("invalid DRPC URL scheme: %s", parsedUrl.Scheme)
 	}
 
-	apiKey := parsedUrl.Host
-	if apiKey == "" {
+	if parsedUrl.Host == "" {
 		return nil, fmt.Errorf("missing DRPC API key in URL")
 	}
+	var keys []*drpcKey
+	for _, k := range strings.Split(parsedUrl.Host, ",") {
+		if k == "" {
+			return nil, fmt.Errorf("empty DRPC API key in URL")
+		}
+		keys = append(keys, &drpcKey{apiKey: k})
+	}
+	if parsedUrl.Path != "" && parsedUrl.Path != "/" {
+		return nil, fmt.Errorf("unexpected path in DRPC URL, expected format drpc://API_KEY: %s", parsedUrl.Path)
+	}
+
+	baseUrl := drpcDefaultBaseUrl
+	if bu := parsedUrl.Query().Get("baseUrl"); bu != "" {
+		pbu, err := url.Parse(bu)
+		if err != nil || pbu.Host == "" || (pbu.Scheme != "http" && pbu.Scheme != "https") {
+			return nil, fmt.Errorf("invalid baseUrl in DRPC URL: %s", bu)
+		}
+		baseUrl = strings.TrimSuffix(bu, "/")
+	}
 
 	return &DrpcHttpJsonRpcClient{
 		upstream: pu,
-		apiKey:   apiKey,
+		keys:     keys,
+		baseUrl:  baseUrl,
 		clients:  make(map[string]HttpJsonRpcClient),
 	}, nil
 }
@@ -157,11 +194,15 @@ func (c *DrpcHttpJsonRpcClient) GetType() ClientType {
 }
 
 func (c *DrpcHttpJsonRpcClient) SupportsNetwork(networkId string) (bool, error) {
-	if !strings.HasPrefix(networkId, "evm:") {
+	arch, ref, err := common.ParseNetworkId(networkId)
+	if err != nil {
+		return false, err
+	}
+	if arch != common.ArchitectureEvm {
 		return false, nil
 	}
 
-	chainId, err := strconv.ParseInt(networkId[4:], 10, 64)
+	chainId, err := strconv.ParseInt(ref, 10, 64)
 	if err != nil {
 		return false, err
 	}
@@ -170,10 +211,32 @@ func (c *DrpcHttpJsonRpcClient) SupportsNetwork(networkId string) (bool, error)
 	return ok, nil
 }
 
-func (c *DrpcHttpJsonRpcClient) getOrCreateClient(network common.Network) (HttpJsonRpcClient, error) {
-	networkID := network.Id()
+// pickKey rotates round-robin over keys that are not cooling down after a rate limit,
+// when all of them are, the one whose cooldown ends first is used.
+func (c *DrpcHttpJsonRpcClient) pickKey() *drpcKey {
+	if len(c.keys) == 1 {
+		return c.keys[0]
+	}
+	now := time.Now().UnixNano()
+	start := c.next.Add(1) - 1
+	var soonest *drpcKey
+	for i := 0; i < len(c.keys); i++ {
+		k := c.keys[(start+uint64(i))%uint64(len(c.keys))]
+		until := k.cooldownUntil.Load()
+		if until <= now {
+			return k
+		}
+		if soonest == nil || until < soonest.cooldownUntil.Load() {
+			soonest = k
+		}
+	}
+	return soonest
+}
+
+func (c *DrpcHttpJsonRpcClient) getOrCreateClient(network common.Network, key *drpcKey) (HttpJsonRpcClient, error) {
+	clientKey := network.Id() + "/" + key.apiKey
 	c.mu.RLock()
-	client, exists := c.clients[networkID]
+	client, exists := c.clients[clientKey]
 	c.mu.RUnlock()
 
 	if exists {
@@ -184,7 +247,7 @@ func (c *DrpcHttpJsonRpcClient) getOrCreateClient(network common.Network) (HttpJ
 	defer c.mu.Unlock()
 
 	// Double-check to ensure another goroutine hasn't created the client
-	if client, exists := c.clients[networkID]; exists {
+	if client, exists := c.clients[clientKey]; exists {
 		return client, nil
 	}
 
@@ -202,31 +265,54 @@ func (c *DrpcHttpJsonRpcClient) getOrCreateClient(network common.Network) (HttpJ
 		return nil, fmt.Errorf("unsupported network chain ID for DRPC: %d", chainID)
 	}
 
-	drpcURL := fmt.Sprintf("https://lb.drpc.org/ogrpc?network=%s&dkey=%s", netName, c.apiKey)
-	parsedURL, err := url.Parse(drpcURL)
+	parsedURL, err := url.Parse(c.baseUrl)
 	if err != nil {
 		return nil, err
 	}
+	qs := parsedURL.Query()
+	qs.Set("network", netName)
+	qs.Set("dkey", key.apiKey)
+	parsedURL.RawQuery = qs.Encode()
 
 	client, err = NewGenericHttpJsonRpcClient(&c.upstream.Logger, c.upstream, parsedURL)
 	if err != nil {
 		return nil, err
 	}
 
-	c.clients[networkID] = client
+	c.clients[clientKey] = client
 	return client, nil
 }
 
+func (c *DrpcHttpJsonRpcClient) HealthCheck(ctx context.Context) error {
+	client, err := representativeClient(c.upstream, &c.mu, c.clients, func(network common.Network) (HttpJsonRpcClient, error) {
+		return c.getOrCreateClient(network, c.pickKey())
+	})
+	if err != nil {
+		return err
+	}
+	return client.HealthCheck(ctx)
+}
+
+func (c *DrpcHttpJsonRpcClient) Close() error {
+	return closeClients(&c.mu, c.clients)
+}
+
 func (c *DrpcHttpJsonRpcClient) SendRequest(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error) {
 	network := req.Network()
 	if network == nil {
 		return nil, fmt.Errorf("network information is missing in the request")
 	}
 
-	client, err := c.getOrCreateClient(network)
+	key := c.pickKey()
+	client, err := c.getOrCreateClient(network, key)
 	if err != nil {
 		return nil, err
 	}
 
-	return client.SendRequest(ctx, req)
+	resp, err := client.SendRequest(ctx, req)
+	if len(c.keys) > 1 && common.HasErrorCode(err, common.ErrCodeEndpointCapacityExceeded) {
+		key.cooldownUntil.Store(time.Now().Add(drpcKeyCooldown).UnixNano())
+		c.upstream.Logger.Debug().Err(err).Msgf("dRPC key hit rate limits, skipping it for %s", drpcKeyCooldown)
+	}
+	return resp, err
 }
@@ -0,0 +1,137 @@
+package upstream
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/erpc/erpc/common"
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDrpcHttpJsonRpcClient(t *testing.T) {
+	t.Run("UrlVariantsResolveToSameConfig", func(t *testing.T) {
+		variants := []string{
+			"drpc://abc123",
+			"drpc://abc123/",
+			"drpc://abc123?foo=bar",
+			"drpc://abc123/?foo=bar&utm=x",
+			"evm+drpc://abc123",
+		}
+
+		for _, v := range variants {
+			pu, err := url.Parse(v)
+			assert.NoError(t, err, v)
+			c, err := NewDrpcHttpJsonRpcClient(&Upstream{}, pu)
+			assert.NoError(t, err, v)
+			dc := c.(*DrpcHttpJsonRpcClient)
+			if assert.Len(t, dc.keys, 1, v) {
+				assert.Equal(t, "abc123", dc.keys[0].apiKey, v)
+			}
+			assert.Equal(t, drpcDefaultBaseUrl, dc.baseUrl, v)
+		}
+	})
+
+	t.Run("BaseUrlOverride", func(t *testing.T) {
+		pu, _ := url.Parse("drpc://abc123/?baseUrl=https://custom.drpc.example/ogrpc/&foo=bar")
+		c, err := NewDrpcHttpJsonRpcClient(&Upstream{}, pu)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://custom.drpc.example/ogrpc", c.(*DrpcHttpJsonRpcClient).baseUrl)
+	})
+
+	t.Run("MalformedUrls", func(t *testing.T) {
+		malformed := []string{
+			"drpc://",
+			"drpc:///abc123",
+			"drpc://abc123/extra/path",
+			"drpc://abc123?baseUrl=not-a-url",
+			"alchemy://abc123",
+		}
+
+		for _, v := range malformed {
+			pu, err := url.Parse(v)
+			assert.NoError(t, err, v)
+			_, err = NewDrpcHttpJsonRpcClient(&Upstream{}, pu)
+			assert.Error(t, err, v)
+		}
+	})
+}
+
+func TestDrpcHttpJsonRpcClient_SupportsNetwork(t *testing.T) {
+	pu, _ := url.Parse("drpc://abc123")
+	c, err := NewDrpcHttpJsonRpcClient(&Upstream{}, pu)
+	assert.NoError(t, err)
+
+	supported, err := c.SupportsNetwork("evm:1")
+	assert.NoError(t, err)
+	assert.True(t, supported)
+
+	supported, err = c.SupportsNetwork("evm:123456789")
+	assert.NoError(t, err)
+	assert.False(t, supported)
+
+	supported, err = c.SupportsNetwork("solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdpKuc147dw2N9d")
+	assert.NoError(t, err)
+	assert.False(t, supported)
+
+	for _, id := range []string{"evm", "evm:", "evm:abc", "cosmos:hub-4"} {
+		_, err = c.SupportsNetwork(id)
+		assert.Error(t, err, id)
+	}
+}
+
+func TestDrpcHttpJsonRpcClient_KeyRotation(t *testing.T) {
+	defer gock.Off()
+	defer gock.Clean()
+
+	ups := &Upstream{
+		config: &common.UpstreamConfig{
+			Id:       "my-drpc",
+			Endpoint: "drpc://keyA,keyB?baseUrl=http://rpc1.localhost/ogrpc",
+		},
+	}
+	pu, _ := url.Parse(ups.config.Endpoint)
+	c, err := NewDrpcHttpJsonRpcClient(ups, pu)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, c.(*DrpcHttpJsonRpcClient).keys, 2)
+
+	hitsByKey := map[string]int{}
+	mockKey := func(key string, status int, body string) {
+		gock.New("http://rpc1.localhost").
+			Post("/ogrpc").
+			Persist().
+			Filter(func(request *http.Request) bool {
+				if request.URL.Query().Get("dkey") != key {
+					return false
+				}
+				hitsByKey[key]++
+				return true
+			}).
+			Reply(status).
+			BodyString(body)
+	}
+	mockKey("keyA", 429, `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"rate limit exceeded"}}`)
+	mockKey("keyB", 200, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`)
+
+	send := func() error {
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`))
+		req.SetNetwork(&healthCheckNetwork{chainId: 1})
+		_, err := c.SendRequest(context.Background(), req)
+		return err
+	}
+
+	// First request lands on keyA which is rate-limited
+	err = send()
+	assert.True(t, common.HasErrorCode(err, common.ErrCodeEndpointCapacityExceeded), "unexpected error: %v", err)
+
+	// While keyA cools down all traffic shifts to keyB
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, send())
+	}
+	assert.Equal(t, 1, hitsByKey["keyA"])
+	assert.Equal(t, 4, hitsByKey["keyB"])
+}
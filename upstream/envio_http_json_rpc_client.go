@@ -3,7 +3,6 @@ package upstream
 import (
 	"context"
 	"fmt"
-	"math/rand"
 	"net/url"
 	"strconv"
 	"strings"
@@ -117,8 +116,10 @@ func (c *EnvioHttpJsonRpcClient) SupportsNetwork(networkId string) (bool, error)
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	rid := rand.Intn(100_000_000) // #nosec G404
-	pr := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"eth_chainId","params":[]}`, rid)))
+	pr, err := common.NewInternalRequest("eth_chainId", nil)
+	if err != nil {
+		return false, err
+	}
 	resp, err := client.SendRequest(ctx, pr)
 	if err != nil {
 		return false, err
@@ -187,6 +188,18 @@ func (c *EnvioHttpJsonRpcClient) getOrCreateClient(network common.Network) (Http
 	return c.createClient(chainID)
 }
 
+func (c *EnvioHttpJsonRpcClient) HealthCheck(ctx context.Context) error {
+	client, err := representativeClient(c.upstream, &c.mu, c.clients, c.getOrCreateClient)
+	if err != nil {
+		return err
+	}
+	return client.HealthCheck(ctx)
+}
+
+func (c *EnvioHttpJsonRpcClient) Close() error {
+	return closeClients(&c.mu, c.clients)
+}
+
 func (c *EnvioHttpJsonRpcClient) SendRequest(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error) {
 	network := req.Network()
 	if network == nil {
@@ -149,6 +149,18 @@ func (c *EtherspotHttpJsonRpcClient) getOrCreateClient(network common.Network) (
 	return c.createClient(chainID)
 }
 
+func (c *EtherspotHttpJsonRpcClient) HealthCheck(ctx context.Context) error {
+	client, err := representativeClient(c.upstream, &c.mu, c.clients, c.getOrCreateClient)
+	if err != nil {
+		return err
+	}
+	return client.HealthCheck(ctx)
+}
+
+func (c *EtherspotHttpJsonRpcClient) Close() error {
+	return closeClients(&c.mu, c.clients)
+}
+
 func (c *EtherspotHttpJsonRpcClient) SendRequest(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error) {
 	network := req.Network()
 	if network == nil {
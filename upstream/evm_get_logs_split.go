@@ -0,0 +1,362 @@
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/erpc/erpc/common"
+)
+
+const (
+	defaultGetLogsSplitConcurrency  = 2
+	defaultGetLogsSplitMaxSubRanges = 100
+)
+
+type blockRange struct {
+	from int64
+	to   int64
+}
+
+// getLogsSubRanges returns the sub-ranges an eth_getLogs request must be split into to respect the
+// upstream's getLogsMaxBlockRange, nil when splitting is disabled, no split is needed or the range is not
+// explicit numbers. Ranges needing more than getLogsSplitMaxSubRanges sub-queries are rejected.
+func (u *Upstream) getLogsSubRanges(req *common.NormalizedRequest) ([]blockRange, map[string]interface{}, error) {
+	cfg := u.Config()
+	if cfg.Evm == nil || !cfg.Evm.GetLogsSplitEnabled || cfg.Evm.GetLogsMaxBlockRange <= 0 {
+		return nil, nil, nil
+	}
+	jrq, err := req.JsonRpcRequest()
+	if err != nil || jrq.Method != "eth_getLogs" || len(jrq.Params) == 0 {
+		return nil, nil, nil
+	}
+	filter, ok := jrq.Params[0].(map[string]interface{})
+	if !ok || filter["blockHash"] != nil {
+		return nil, nil, nil
+	}
+	fromHex, _ := filter["fromBlock"].(string)
+	toHex, _ := filter["toBlock"].(string)
+	if !strings.HasPrefix(fromHex, "0x") || !strings.HasPrefix(toHex, "0x") {
+		return nil, nil, nil
+	}
+	fromBlock, err := common.HexToInt64(fromHex)
+	if err != nil {
+		return nil, nil, nil
+	}
+	toBlock, err := common.HexToInt64(toHex)
+	if err != nil || toBlock < fromBlock {
+		return nil, nil, nil
+	}
+
+	maxRange := int64(cfg.Evm.GetLogsMaxBlockRange)
+	if toBlock-fromBlock+1 <= maxRange {
+		return nil, nil, nil
+	}
+	count := (toBlock-fromBlock)/maxRange + 1
+	if maxSubRanges := u.getLogsSplitMaxSubRanges(); count > int64(maxSubRanges) {
+		return nil, nil, common.NewErrGetLogsTooManySubRanges(int(count), maxSubRanges)
+	}
+	ranges := make([]blockRange, 0, count)
+	for from := fromBlock; from <= toBlock; from += maxRange {
+		ranges = append(ranges, blockRange{from: from, to: min(from+maxRange-1, toBlock)})
+	}
+	return ranges, filter, nil
+}
+
+// getLogsSplitMaxSubRanges is the max number of sub-range queries a single request is split into on this upstream.
+func (u *Upstream) getLogsSplitMaxSubRanges() int {
+	if cfg := u.Config(); cfg.Evm != nil && cfg.Evm.GetLogsSplitMaxSubRanges > 0 {
+		return cfg.Evm.GetLogsSplitMaxSubRanges
+	}
+	return defaultGetLogsSplitMaxSubRanges
+}
+
+// getLogsMaxResults is the max number of logs of a single (merged) eth_getLogs response, 0 when not limited.
+func (u *Upstream) getLogsMaxResults() int {
+	if cfg := u.Config(); cfg.Evm != nil {
+		return cfg.Evm.GetLogsMaxResults
+	}
+	return 0
+}
+
+// getLogsSplitConcurrency is how many sub-range queries of a single request run at once on this upstream.
+func (u *Upstream) getLogsSplitConcurrency() int {
+	if cfg := u.Config(); cfg.Evm != nil && cfg.Evm.GetLogsSplitConcurrency > 0 {
+		return cfg.Evm.GetLogsSplitConcurrency
+	}
+	return defaultGetLogsSplitConcurrency
+}
+
+// forwardGetLogsSplit sends each sub-range as a separate request through this upstream and merges the logs
+// in block order. Sub-queries are bounded by a semaphore so a huge range does not flood the upstream,
+// and the first failure cancels the remaining ones since a partial result would be wrong. Only when the
+// client allows partial logs, timed out sub-ranges are left out and reported as missing ranges instead.
+func (u *Upstream) forwardGetLogsSplit(ctx context.Context, req *common.NormalizedRequest, ranges []blockRange, filter map[string]interface{}) (*common.NormalizedResponse, error) {
+	jrq, err := req.JsonRpcRequest()
+	if err != nil {
+		return nil, err
+	}
+	req.SetLastUpstream(u)
+
+	u.Logger.Debug().Int("subRanges", len(ranges)).Msgf("splitting eth_getLogs request over getLogsMaxBlockRange")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req.RLock()
+	subFilters := make([]map[string]interface{}, len(ranges))
+	for i, r := range ranges {
+		sf := make(map[string]interface{}, len(filter))
+		for k, v := range filter {
+			sf[k] = v
+		}
+		sf["fromBlock"] = fmt.Sprintf("0x%x", r.from)
+		sf["toBlock"] = fmt.Sprintf("0x%x", r.to)
+		subFilters[i] = sf
+	}
+	req.RUnlock()
+
+	allowPartial := req.Directives() != nil && req.Directives().AllowPartialLogs
+	results := make([]json.RawMessage, len(ranges))
+	timeouts := make([]error, len(ranges))
+	sem := make(chan struct{}, u.getLogsSplitConcurrency())
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+
+	for i := range ranges {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			logs, err := u.forwardGetLogsSubRange(ctx, req, subFilters[i])
+			if err != nil && allowPartial && ctx.Err() == nil && isTimeoutError(err) {
+				timeouts[i] = err
+				return
+			}
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = logs
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for i, r := range ranges {
+		if timeouts[i] != nil {
+			missing = append(missing, fmt.Sprintf("0x%x-0x%x", r.from, r.to))
+		}
+	}
+	if len(missing) == len(ranges) {
+		// Nothing was fetched, a normal timeout error is more useful than an empty partial result
+		return nil, timeouts[0]
+	}
+	if len(missing) > 0 {
+		u.Logger.Warn().Strs("missingRanges", missing).Int("subRanges", len(ranges)).Msgf("some eth_getLogs sub-ranges timed out, returning partial result")
+	}
+
+	if maxResults := u.getLogsMaxResults(); maxResults > 0 {
+		total := 0
+		for _, logs := range results {
+			elems, err := common.SplitJSONArray(logs)
+			if err != nil {
+				return nil, err
+			}
+			total += len(elems)
+		}
+		if total > maxResults {
+			return nil, common.NewErrResultSetTooLarge("eth_getLogs", maxResults)
+		}
+	}
+	// Sub-ranges are in block order already, their logs are concatenated as-is without decoding
+	merged, err := common.MergeJSONArrays(results...)
+	if err != nil {
+		return nil, err
+	}
+
+	jrr, err := common.NewJsonRpcResponse(jrq.ID, merged, nil)
+	if err != nil {
+		return nil, err
+	}
+	return common.NewNormalizedResponse().WithRequest(req).WithJsonRpcResponse(jrr).WithMissingRanges(missing), nil
+}
+
+func isTimeoutError(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) ||
+		common.HasErrorCode(err, common.ErrCodeEndpointRequestTimeout, common.ErrCodeFailsafeTimeoutExceeded)
+}
+
+func (u *Upstream) forwardGetLogsSubRange(ctx context.Context, req *common.NormalizedRequest, filter map[string]interface{}) (json.RawMessage, error) {
+	// Distinct ids keep sub-requests apart when the client batches them together
+	sub, err := common.NewInternalRequest("eth_getLogs", []interface{}{filter})
+	if err != nil {
+		return nil, err
+	}
+	sub.WithDirectives(req.Directives())
+	sub.SetNetwork(req.Network())
+
+	resp, err := u.Forward(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	jrr, err := resp.JsonRpcResponse()
+	if err != nil {
+		return nil, err
+	}
+	if jrr.Error != nil {
+		return nil, jrr.Error
+	}
+
+	return jrr.Result, nil
+}
+
+// getLogsAddressSplitCandidate returns the distinct addresses of an eth_getLogs request that targets a single
+// block (same from/to number or a blockHash), nil when the request does not filter on multiple addresses.
+func getLogsAddressSplitCandidate(req *common.NormalizedRequest) ([]interface{}, map[string]interface{}) {
+	jrq, err := req.JsonRpcRequest()
+	if err != nil || jrq.Method != "eth_getLogs" || len(jrq.Params) == 0 {
+		return nil, nil
+	}
+	filter, ok := jrq.Params[0].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	if filter["blockHash"] == nil {
+		fromHex, _ := filter["fromBlock"].(string)
+		toHex, _ := filter["toBlock"].(string)
+		if !strings.HasPrefix(fromHex, "0x") || !strings.HasPrefix(toHex, "0x") {
+			return nil, nil
+		}
+		fromBlock, err := common.HexToInt64(fromHex)
+		if err != nil {
+			return nil, nil
+		}
+		if toBlock, err := common.HexToInt64(toHex); err != nil || toBlock != fromBlock {
+			return nil, nil
+		}
+	}
+
+	list, ok := filter["address"].([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(list))
+	addresses := make([]interface{}, 0, len(list))
+	for _, a := range list {
+		s, ok := a.(string)
+		if !ok {
+			return nil, nil
+		}
+		if k := strings.ToLower(s); !seen[k] {
+			seen[k] = true
+			addresses = append(addresses, s)
+		}
+	}
+	return addresses, filter
+}
+
+// forwardGetLogsByAddress splits the addresses of a single-block eth_getLogs request in two halves and merges
+// the logs in logIndex order. Each half goes through Forward again, so it is split further as long as it
+// still returns too many logs and has more than one address left.
+func (u *Upstream) forwardGetLogsByAddress(ctx context.Context, req *common.NormalizedRequest, addresses []interface{}, filter map[string]interface{}) (*common.NormalizedResponse, error) {
+	jrq, err := req.JsonRpcRequest()
+	if err != nil {
+		return nil, err
+	}
+	req.SetLastUpstream(u)
+
+	u.Logger.Debug().Int("addresses", len(addresses)).Msgf("splitting single-block eth_getLogs request by address")
+
+	half := len(addresses) / 2
+	var merged []json.RawMessage
+	for _, subset := range [][]interface{}{addresses[:half], addresses[half:]} {
+		req.RLock()
+		sf := make(map[string]interface{}, len(filter))
+		for k, v := range filter {
+			sf[k] = v
+		}
+		req.RUnlock()
+		sf["address"] = subset
+
+		result, err := u.forwardGetLogsSubRange(ctx, req, sf)
+		if err != nil {
+			return nil, err
+		}
+		logs, err := common.SplitJSONArray(result)
+		if err != nil {
+			return nil, err
+		}
+		merged = append(merged, logs...)
+	}
+	if maxResults := u.getLogsMaxResults(); maxResults > 0 && len(merged) > maxResults {
+		return nil, common.NewErrResultSetTooLarge("eth_getLogs", maxResults)
+	}
+
+	if err := sortLogsByIndex(merged); err != nil {
+		return nil, err
+	}
+
+	jrr, err := common.NewJsonRpcResponse(jrq.ID, merged, nil)
+	if err != nil {
+		return nil, err
+	}
+	return common.NewNormalizedResponse().WithRequest(req).WithJsonRpcResponse(jrr), nil
+}
+
+func sortLogsByIndex(logs []json.RawMessage) error {
+	indexes := make([]int64, len(logs))
+	for i, raw := range logs {
+		var l struct {
+			LogIndex string `json:"logIndex"`
+		}
+		if err := sonic.Unmarshal(raw, &l); err != nil {
+			return err
+		}
+		if l.LogIndex == "" {
+			continue
+		}
+		idx, err := common.HexToInt64(l.LogIndex)
+		if err != nil {
+			return err
+		}
+		indexes[i] = idx
+	}
+	sort.Stable(logsByIndex{logs: logs, indexes: indexes})
+	return nil
+}
+
+type logsByIndex struct {
+	logs    []json.RawMessage
+	indexes []int64
+}
+
+func (l logsByIndex) Len() int           { return len(l.logs) }
+func (l logsByIndex) Less(i, j int) bool { return l.indexes[i] < l.indexes[j] }
+func (l logsByIndex) Swap(i, j int) {
+	l.logs[i], l.logs[j] = l.logs[j], l.logs[i]
+	l.indexes[i], l.indexes[j] = l.indexes[j], l.indexes[i]
+}
@@ -3,7 +3,6 @@ package upstream
 import (
 	"context"
 	"fmt"
-	"math/rand"
 	"sync"
 	"time"
 
@@ -146,6 +145,15 @@ func (e *EvmStatePoller) poll(ctx context.Context) {
 		}
 	}()
 
+	// Check the endpoint is healthy, so that readiness does not rely only on failures of served requests
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := e.upstream.HealthCheck(ctx); err != nil {
+			e.logger.Debug().Err(err).Msg("health check failed in evm state poller")
+		}
+	}()
+
 	// Fetch "syncing" state
 	wg.Add(1)
 	go func() {
@@ -219,29 +227,37 @@ func (e *EvmStatePoller) FinalizedBlock() int64 {
 }
 
 func (e *EvmStatePoller) IsBlockFinalized(blockNumber int64) (bool, error) {
+	fb, err := e.EffectiveFinalizedBlock()
+	if err != nil {
+		return false, common.NewErrFinalizedBlockUnavailable(blockNumber)
+	}
+
+	e.logger.Debug().
+		Int64("finalizedBlock", fb).
+		Int64("blockNumber", blockNumber).
+		Msgf("calculating block finality")
+
+	return blockNumber <= fb, nil
+}
+
+// EffectiveFinalizedBlock returns the finalized block reported by the upstream, or when the upstream
+// does not support "finalized" tag, a block inferred from latest block and network finality depth.
+func (e *EvmStatePoller) EffectiveFinalizedBlock() (int64, error) {
+	e.mu.RLock()
 	finalizedBlock := e.finalizedBlockNumber
 	latestBlock := e.latestBlockNumber
+	e.mu.RUnlock()
+
 	if latestBlock == 0 && finalizedBlock == 0 {
 		e.logger.Debug().
 			Int64("finalizedBlock", finalizedBlock).
 			Int64("latestBlock", latestBlock).
-			Int64("blockNumber", blockNumber).
 			Msgf("finalized/latest blocks are not available yet when checking block finality")
-		return false, common.NewErrFinalizedBlockUnavailable(blockNumber)
+		return 0, common.NewErrFinalizedBlockUnavailable(0)
 	}
 
-	e.logger.Debug().
-		Int64("finalizedBlock", finalizedBlock).
-		Int64("latestBlock", latestBlock).
-		Int64("blockNumber", blockNumber).
-		Msgf("calculating block finality")
-
 	if finalizedBlock > 0 {
-		return blockNumber <= finalizedBlock, nil
-	}
-
-	if latestBlock == 0 {
-		return false, nil
+		return finalizedBlock, nil
 	}
 
 	var fb int64
@@ -264,10 +280,9 @@ func (e *EvmStatePoller) IsBlockFinalized(blockNumber int64) (bool, error) {
 	e.logger.Debug().
 		Int64("inferredFinalizedBlock", fb).
 		Int64("latestBlock", latestBlock).
-		Int64("blockNumber", blockNumber).
-		Msgf("calculating block finality using inferred finalized block")
+		Msgf("inferred finalized block from latest block")
 
-	return blockNumber <= fb, nil
+	return fb, nil
 }
 
 func (e *EvmStatePoller) SuggestFinalizedBlock(blockNumber int64) {
@@ -295,10 +310,10 @@ func (e *EvmStatePoller) fetchFinalizedBlockNumber(ctx context.Context) (int64,
 }
 
 func (e *EvmStatePoller) fetchBlock(ctx context.Context, blockTag string) (int64, error) {
-	randId := rand.Intn(100_000_000) // #nosec G404
-	pr := common.NewNormalizedRequest([]byte(
-		fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"eth_getBlockByNumber","params":["%s",false]}`, randId, blockTag),
-	))
+	pr, err := common.NewInternalRequest("eth_getBlockByNumber", []interface{}{blockTag, false})
+	if err != nil {
+		return 0, err
+	}
 	pr.SetNetwork(e.network)
 
 	resp, err := e.upstream.Forward(ctx, pr)
@@ -348,7 +363,10 @@ func (e *EvmStatePoller) fetchBlock(ctx context.Context, blockTag string) (int64
 }
 
 func (e *EvmStatePoller) fetchSyncingState(ctx context.Context) (bool, error) {
-	pr := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_syncing","params":[]}`))
+	pr, err := common.NewInternalRequest("eth_syncing", nil)
+	if err != nil {
+		return false, err
+	}
 	pr.SetNetwork(e.network)
 
 	resp, err := e.upstream.Forward(ctx, pr)
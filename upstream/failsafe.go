@@ -27,7 +27,9 @@ const (
 	ScopeUpstream Scope = "upstream"
 )
 
-func CreateFailSafePolicies(logger *zerolog.Logger, scope Scope, component string, fsCfg *common.FailsafeConfig) ([]failsafe.Policy[*common.NormalizedResponse], error) {
+// CreateFailSafePolicies builds the policies in the order failsafe-go expects them, onCircuitRecovered (optional)
+// is called whenever an upstream-level circuit breaker closes again after being half-open.
+func CreateFailSafePolicies(logger *zerolog.Logger, scope Scope, component string, fsCfg *common.FailsafeConfig, onCircuitRecovered func()) ([]failsafe.Policy[*common.NormalizedResponse], error) {
 	// The order of policies below are important as per docs of failsafe-go
 	var policies = []failsafe.Policy[*common.NormalizedResponse]{}
 
@@ -57,7 +59,7 @@ func CreateFailSafePolicies(logger *zerolog.Logger, scope Scope, component strin
 	// CircuitBreaker does not make sense for network-level requests
 	if scope == ScopeUpstream {
 		if fsCfg.CircuitBreaker != nil {
-			p, err := createCircuitBreakerPolicy(logger, component, fsCfg.CircuitBreaker)
+			p, err := createCircuitBreakerPolicy(logger, component, fsCfg.CircuitBreaker, onCircuitRecovered)
 			if err != nil {
 				return nil, err
 			}
@@ -87,7 +89,7 @@ func CreateFailSafePolicies(logger *zerolog.Logger, scope Scope, component strin
 	return policies, nil
 }
 
-func createCircuitBreakerPolicy(logger *zerolog.Logger, component string, cfg *common.CircuitBreakerPolicyConfig) (failsafe.Policy[*common.NormalizedResponse], error) {
+func createCircuitBreakerPolicy(logger *zerolog.Logger, component string, cfg *common.CircuitBreakerPolicyConfig, onRecovered func()) (failsafe.Policy[*common.NormalizedResponse], error) {
 	builder := circuitbreaker.Builder[*common.NormalizedResponse]()
 
 	if cfg.FailureThresholdCount > 0 {
@@ -127,6 +129,9 @@ func createCircuitBreakerPolicy(logger *zerolog.Logger, component string, cfg *c
 			Uint("failureRate", mt.FailureRate()).
 			Uint("successRate", mt.SuccessRate()).
 			Msgf("circuit breaker state changed from %s to %s", event.OldState, event.NewState)
+		if onRecovered != nil && event.OldState == circuitbreaker.HalfOpenState && event.NewState == circuitbreaker.ClosedState {
+			onRecovered()
+		}
 	})
 	builder.OnFailure(func(event failsafe.ExecutionEvent[*common.NormalizedResponse]) {
 		err := event.LastError()
@@ -249,6 +254,11 @@ func createRetryPolicy(scope Scope, component string, cfg *common.RetryPolicyCon
 	}
 
 	builder.HandleIf(func(result *common.NormalizedResponse, err error) bool {
+		// Retrying is exactly what the budget is protecting against
+		if common.HasErrorCode(err, common.ErrCodeRetryBudgetExhausted) {
+			return false
+		}
+
 		// 400 / 404 / 405 / 413 -> No Retry
 		// RPC-RPC client-side error (invalid params) -> No Retry
 		if common.HasErrorCode(err, common.ErrCodeEndpointClientSideException) {
@@ -364,6 +374,33 @@ func createTimeoutPolicy(component string, cfg *common.TimeoutPolicyConfig) (fai
 	return builder.Build(), nil
 }
 
+// ExecutorForRequestTimeout returns an executor whose timeout policy allows the requested timeout, reusing all
+// other (stateful) policies as they are. It returns nil when no timeout policy is configured or the configured
+// one is already long enough, in which case the regular executor should be used.
+func ExecutorForRequestTimeout(
+	policies []failsafe.Policy[*common.NormalizedResponse],
+	fsCfg *common.FailsafeConfig,
+	requested time.Duration,
+) failsafe.Executor[*common.NormalizedResponse] {
+	if requested <= 0 || fsCfg == nil || fsCfg.Timeout == nil {
+		return nil
+	}
+	configured, err := time.ParseDuration(fsCfg.Timeout.Duration)
+	if err != nil || requested <= configured {
+		return nil
+	}
+
+	extended := make([]failsafe.Policy[*common.NormalizedResponse], len(policies))
+	for i, p := range policies {
+		if _, ok := p.(timeout.Timeout[*common.NormalizedResponse]); ok {
+			extended[i] = timeout.With[*common.NormalizedResponse](requested)
+		} else {
+			extended[i] = p
+		}
+	}
+	return failsafe.NewExecutor(extended...)
+}
+
 func TranslateFailsafeError(upstreamId, method string, execErr error) error {
 	var err error
 	var retryExceededErr retrypolicy.ExceededError
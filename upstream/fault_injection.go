@@ -0,0 +1,79 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/erpc/erpc/health"
+)
+
+const (
+	faultLatency = "latency"
+	faultError   = "error"
+	faultDrop    = "drop"
+)
+
+// faultInjector delays or fails requests before they are sent to an upstream, for chaos testing only.
+type faultInjector struct {
+	latency   time.Duration
+	errorRate float64
+	dropRate  float64
+	random    func() float64
+}
+
+func newFaultInjector(cfg *common.FaultInjectionConfig) (*faultInjector, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.ErrorRate < 0 || cfg.ErrorRate > 1 || cfg.DropRate < 0 || cfg.DropRate > 1 {
+		return nil, fmt.Errorf("faultInjection errorRate and dropRate must be between 0 and 1")
+	}
+	f := &faultInjector{
+		errorRate: cfg.ErrorRate,
+		dropRate:  cfg.DropRate,
+		random:    rand.Float64, // #nosec G404
+	}
+	if cfg.Latency != "" {
+		d, err := time.ParseDuration(cfg.Latency)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid faultInjection latency: %s", cfg.Latency)
+		}
+		f.latency = d
+	}
+	return f, nil
+}
+
+// inject applies configured faults to a request, a non-nil error means the request must not be sent.
+// Injected errors carry ErrFaultInjected in their chain so they can be told apart in logs and metrics.
+func (f *faultInjector) inject(ctx context.Context, ups *Upstream, req *common.NormalizedRequest) error {
+	method, _ := req.Method()
+	record := func(fault string) {
+		health.MetricUpstreamInjectedFaultTotal.WithLabelValues(ups.ProjectId, req.NetworkId(), ups.Config().Id, method, fault).Inc()
+	}
+
+	if f.latency > 0 {
+		record(faultLatency)
+		timer := time.NewTimer(f.latency)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	if f.dropRate > 0 && f.random() < f.dropRate {
+		record(faultDrop)
+		return common.NewErrFaultInjected(ups.Config().Id, faultDrop)
+	}
+
+	if f.errorRate > 0 && f.random() < f.errorRate {
+		record(faultError)
+		return common.NewErrEndpointServerSideException(common.NewErrFaultInjected(ups.Config().Id, faultError), nil)
+	}
+
+	return nil
+}
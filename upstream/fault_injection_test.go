@@ -0,0 +1,96 @@
+package upstream
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultInjection(t *testing.T) {
+	logger := zerolog.Nop()
+
+	newClient := func(t *testing.T, cfg *common.FaultInjectionConfig) (HttpJsonRpcClient, *atomic.Int32) {
+		ups := &Upstream{
+			ProjectId: "prjA",
+			config: &common.UpstreamConfig{
+				Id:             "rpc1",
+				Endpoint:       "http://rpc1.localhost",
+				FaultInjection: cfg,
+			},
+		}
+		client, err := NewGenericHttpJsonRpcClient(&logger, ups, &url.URL{Scheme: "http", Host: "rpc1.localhost"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		sent := &atomic.Int32{}
+		client.(*GenericHttpJsonRpcClient).httpClient = &http.Client{
+			Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				sent.Add(1)
+				return &http.Response{
+					StatusCode: 200,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       io.NopCloser(strings.NewReader(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`)),
+					Request:    req,
+				}, nil
+			}),
+		}
+		return client, sent
+	}
+	newRequest := func() *common.NormalizedRequest {
+		return common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`))
+	}
+
+	t.Run("FullErrorRateAlwaysFailsWithoutReachingUpstream", func(t *testing.T) {
+		client, sent := newClient(t, &common.FaultInjectionConfig{ErrorRate: 1})
+
+		for i := 0; i < 20; i++ {
+			_, err := client.SendRequest(context.Background(), newRequest())
+			assert.True(t, common.HasErrorCode(err, common.ErrCodeEndpointServerSideException), "unexpected error: %v", err)
+			assert.True(t, common.HasErrorCode(err, common.ErrCodeFaultInjected), "injected errors must be distinguishable: %v", err)
+		}
+		assert.Equal(t, int32(0), sent.Load())
+	})
+
+	t.Run("FullDropRateFailsAsInjectedDrop", func(t *testing.T) {
+		client, sent := newClient(t, &common.FaultInjectionConfig{DropRate: 1})
+
+		_, err := client.SendRequest(context.Background(), newRequest())
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeFaultInjected), "unexpected error: %v", err)
+		assert.False(t, common.HasErrorCode(err, common.ErrCodeEndpointServerSideException))
+		assert.Equal(t, int32(0), sent.Load())
+	})
+
+	t.Run("LatencyIsAddedBeforeSending", func(t *testing.T) {
+		client, sent := newClient(t, &common.FaultInjectionConfig{Latency: "50ms"})
+
+		start := time.Now()
+		_, err := client.SendRequest(context.Background(), newRequest())
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		assert.Equal(t, int32(1), sent.Load())
+	})
+
+	t.Run("NoFaultsWithoutConfig", func(t *testing.T) {
+		client, sent := newClient(t, nil)
+
+		for i := 0; i < 5; i++ {
+			_, err := client.SendRequest(context.Background(), newRequest())
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, int32(5), sent.Load())
+	})
+
+	t.Run("InvalidRateIsRejected", func(t *testing.T) {
+		_, err := newFaultInjector(&common.FaultInjectionConfig{ErrorRate: 25})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,121 @@
+package upstream
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/erpc/erpc/common"
+)
+
+// Cheap methods that every node of an architecture supports, used to check the endpoint is reachable and healthy.
+var healthCheckMethods = map[common.NetworkArchitecture]string{
+	common.ArchitectureEvm:    "eth_chainId",
+	common.ArchitectureSolana: "getHealth",
+}
+
+func healthCheckMethod(cfg *common.UpstreamConfig) (string, error) {
+	// Upstream types are "<architecture>" or "<architecture>+<vendor>" (e.g. "evm+alchemy")
+	arch := common.ArchitectureEvm
+	if cfg != nil && cfg.Type != "" {
+		arch = common.NetworkArchitecture(strings.SplitN(string(cfg.Type), "+", 2)[0])
+	}
+
+	method, ok := healthCheckMethods[arch]
+	if !ok {
+		return "", fmt.Errorf("no health check method defined for architecture: %s", arch)
+	}
+	return method, nil
+}
+
+func sendHealthCheck(
+	ctx context.Context,
+	cfg *common.UpstreamConfig,
+	send func(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error),
+) error {
+	method, err := healthCheckMethod(cfg)
+	if err != nil {
+		return err
+	}
+
+	req, err := common.NewInternalRequest(method, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := send(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	jrr, err := resp.JsonRpcResponse()
+	if err != nil {
+		return err
+	}
+	if jrr.Error != nil {
+		return jrr.Error
+	}
+
+	return nil
+}
+
+// representativeClient returns the underlying client that vendor clients (which keep one client per network)
+// delegate health checks to: the one for the upstream's configured chain, otherwise any already created client.
+func representativeClient[K cmp.Ordered](
+	ups *Upstream,
+	mu *sync.RWMutex,
+	clients map[K]HttpJsonRpcClient,
+	getOrCreate func(network common.Network) (HttpJsonRpcClient, error),
+) (HttpJsonRpcClient, error) {
+	cfg := ups.Config()
+	if cfg.Evm != nil && cfg.Evm.ChainId > 0 {
+		return getOrCreate(&healthCheckNetwork{chainId: int64(cfg.Evm.ChainId)})
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var client HttpJsonRpcClient
+	var first K
+	for k, c := range clients {
+		if client == nil || k < first {
+			client, first = c, k
+		}
+	}
+	if client == nil {
+		return nil, fmt.Errorf("cannot health check upstream %s before any network is known, set evm.chainId in its config", cfg.Id)
+	}
+
+	return client, nil
+}
+
+// healthCheckNetwork is a minimal network used to resolve the vendor client of a chain outside of any request.
+type healthCheckNetwork struct {
+	chainId int64
+}
+
+var _ common.Network = (*healthCheckNetwork)(nil)
+
+func (n *healthCheckNetwork) Id() string {
+	return fmt.Sprintf("evm:%d", n.chainId)
+}
+
+func (n *healthCheckNetwork) Architecture() common.NetworkArchitecture {
+	return common.ArchitectureEvm
+}
+
+func (n *healthCheckNetwork) Config() *common.NetworkConfig {
+	return &common.NetworkConfig{
+		Architecture: common.ArchitectureEvm,
+		Evm:          &common.EvmNetworkConfig{ChainId: n.chainId},
+	}
+}
+
+func (n *healthCheckNetwork) EvmChainId() (int64, error) {
+	return n.chainId, nil
+}
+
+func (n *healthCheckNetwork) EvmIsBlockFinalized(blockNumber int64) (bool, error) {
+	return false, nil
+}
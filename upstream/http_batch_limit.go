@@ -0,0 +1,69 @@
+package upstream
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/sonic"
+)
+
+// Phrases providers use when a batch has more items than they accept, e.g. "batch too large" (geth),
+// "Batch size is too large. Max batch size is 100" or "Batch of more than 3 requests are not allowed".
+var batchLimitPhrases = []string{
+	"batch too large",
+	"batch size is too large",
+	"batch size too large",
+	"batch limit",
+	"batch size limit",
+	"max batch size",
+	"maximum batch size",
+	"batch of more than",
+	"batch request limit",
+	"too many requests in batch",
+	"too many requests in a batch",
+}
+
+var batchLimitNumber = regexp.MustCompile(`\d+`)
+
+// detectBatchLimit tells if a non-array batch response rejects the batch for being too large, along with the
+// max batch size the provider mentions in its message (0 when it does not mention one).
+func detectBatchLimit(statusCode int, respBody []byte) (int, bool) {
+	var r struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Message string `json:"message"`
+	}
+	msg := ""
+	if err := sonic.Unmarshal(respBody, &r); err == nil {
+		if r.Error != nil {
+			msg = r.Error.Message
+		} else {
+			msg = r.Message
+		}
+	} else {
+		msg = string(respBody)
+	}
+
+	lmsg := strings.ToLower(msg)
+	matched := false
+	for _, phrase := range batchLimitPhrases {
+		if strings.Contains(lmsg, phrase) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		// A plain 413 for a batch is unambiguous even when the body says nothing useful
+		return 0, statusCode == http.StatusRequestEntityTooLarge
+	}
+
+	if n := batchLimitNumber.FindString(msg); n != "" {
+		if limit, err := strconv.Atoi(n); err == nil && limit > 0 {
+			return limit, true
+		}
+	}
+	return 0, true
+}
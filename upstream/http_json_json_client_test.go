@@ -1,15 +1,33 @@
 package upstream
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/erpc/erpc/common"
+	"github.com/erpc/erpc/vendors"
 	"github.com/h2non/gock"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
@@ -89,6 +107,44 @@ func TestHttpJsonRpcClient_NoResponseErrors(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("ResultAndErrorBothSet", func(t *testing.T) {
+		defer gock.Off()
+
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Id:       "rpc1",
+				Endpoint: "http://rpc1.localhost:8545",
+			},
+		}, &url.URL{Scheme: "http", Host: "rpc1.localhost:8545"})
+		assert.NoError(t, err)
+
+		gock.New("http://rpc1.localhost:8545").
+			Post("/").
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":"0x1","error":{"code":-32000,"message":"header not found"}}`)
+
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`))
+		_, err = client.SendRequest(context.Background(), req)
+
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeUpstreamInvalidResponse), "unexpected error: %v", err)
+		assert.True(t, common.IsRetryableTowardsUpstream(err))
+
+		// A null error along with the result is a valid response
+		gock.New("http://rpc1.localhost:8545").
+			Post("/").
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"result":"0x1","error":null}`)
+
+		resp, err := client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)))
+		assert.NoError(t, err)
+		if assert.NotNil(t, resp) {
+			jrr, err := resp.JsonRpcResponse()
+			assert.NoError(t, err)
+			assert.Nil(t, jrr.Error)
+			assert.Equal(t, `"0x1"`, string(jrr.Result))
+		}
+	})
+
 	t.Run("ConcurrentRequestsRaceCondition", func(t *testing.T) {
 		defer gock.Off()
 
@@ -422,6 +478,44 @@ func TestHttpJsonRpcClient_BatchRequests(t *testing.T) {
 		wg.Wait()
 	})
 
+	t.Run("SingleObjectResponseForBatchRequestGetsEachRequestId", func(t *testing.T) {
+		defer gock.Off()
+
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Endpoint: "http://rpc1.localhost:8545",
+				JsonRpc: &common.JsonRpcUpstreamConfig{
+					SupportsBatch: &common.TRUE,
+					BatchMaxSize:  5,
+					BatchMaxWait:  "50ms",
+				},
+			},
+		}, &url.URL{Scheme: "http", Host: "rpc1.localhost:8545"})
+		assert.NoError(t, err)
+
+		gock.New("http://rpc1.localhost:8545").
+			Post("/").
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":null,"result":"0x1"}`)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"eth_chainId","params":[]}`, id)))
+				resp, err := client.SendRequest(context.Background(), req)
+				if !assert.NoError(t, err) {
+					return
+				}
+				jrr, err := resp.JsonRpcResponse()
+				assert.NoError(t, err)
+				assert.Equal(t, float64(id), jrr.ID)
+			}(i + 1)
+		}
+		wg.Wait()
+	})
+
 	t.Run("SingleRequestUnauthorized", func(t *testing.T) {
 		defer gock.Off()
 
@@ -511,6 +605,109 @@ func TestHttpJsonRpcClient_BatchRequests(t *testing.T) {
 		}
 		wg.Wait()
 	})
+
+	t.Run("SplitsLargeBatchIntoChunksOfMaxSize", func(t *testing.T) {
+		var mu sync.Mutex
+		chunkSizes := []int{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var reqs []map[string]interface{}
+			if err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&reqs); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			mu.Lock()
+			chunkSizes = append(chunkSizes, len(reqs))
+			mu.Unlock()
+
+			resps := make([]map[string]interface{}, 0, len(reqs))
+			for _, rq := range reqs {
+				resps = append(resps, map[string]interface{}{
+					"jsonrpc": "2.0",
+					"id":      rq["id"],
+					"result":  fmt.Sprintf("0x%x", int(rq["id"].(float64))),
+				})
+			}
+			body, _ := sonic.Marshal(resps)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(body)
+		}))
+		defer server.Close()
+
+		serverUrl, _ := url.Parse(server.URL)
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Endpoint: server.URL,
+				JsonRpc: &common.JsonRpcUpstreamConfig{
+					SupportsBatch: &common.TRUE,
+					BatchMaxSize:  100,
+					BatchMaxWait:  "500ms",
+				},
+			},
+		}, serverUrl)
+		assert.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for i := 1; i <= 250; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"eth_getBalance","params":["0x111","latest"]}`, id)))
+				resp, err := client.SendRequest(context.Background(), req)
+				if !assert.NoError(t, err) {
+					return
+				}
+				jrr, err := resp.JsonRpcResponse()
+				assert.NoError(t, err)
+				assert.Equal(t, fmt.Sprintf(`"0x%x"`, id), string(jrr.Result))
+			}(i)
+		}
+		wg.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.ElementsMatch(t, []int{100, 100, 50}, chunkSizes)
+	})
+
+	t.Run("ReorderedBatchItemsAreMatchedById", func(t *testing.T) {
+		defer gock.Off()
+
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Endpoint: "http://rpc1.localhost:8545",
+				JsonRpc: &common.JsonRpcUpstreamConfig{
+					SupportsBatch: &common.TRUE,
+					BatchMaxSize:  3,
+					BatchMaxWait:  "500ms",
+				},
+			},
+		}, &url.URL{Scheme: "http", Host: "rpc1.localhost:8545"})
+		assert.NoError(t, err)
+
+		// Items come back in reverse order, one of them with its id stringified
+		gock.New("http://rpc1.localhost:8545").
+			Post("/").
+			Reply(200).
+			BodyString(`[{"jsonrpc":"2.0","id":"3","result":"0x3"},{"jsonrpc":"2.0","id":2,"result":"0x2"},{"jsonrpc":"2.0","id":1,"result":"0x1"}]`)
+
+		var wg sync.WaitGroup
+		for i := 1; i <= 3; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"eth_getBalance","params":["0x%x","latest"]}`, id, id)))
+				resp, err := client.SendRequest(context.Background(), req)
+				if !assert.NoError(t, err) {
+					return
+				}
+				jrr, err := resp.JsonRpcResponse()
+				assert.NoError(t, err)
+				assert.Equal(t, fmt.Sprintf(`"0x%x"`, id), string(jrr.Result))
+			}(i)
+		}
+		wg.Wait()
+
+		assert.True(t, gock.IsDone())
+	})
 }
 
 func TestHttpJsonRpcClient_BatchRequestErrors(t *testing.T) {
@@ -557,6 +754,7 @@ func TestHttpJsonRpcClient_BatchRequestErrors(t *testing.T) {
 		assert.NotNil(t, results[0])
 		assert.NotNil(t, results[1])
 		assert.Contains(t, results[2].(string), "no response received for request")
+		assert.Contains(t, results[2].(string), common.ErrCodeEndpointServerSideException)
 	})
 
 	t.Run("BatchRequestTimeout", func(t *testing.T) {
@@ -601,3 +799,1282 @@ func TestHttpJsonRpcClient_BatchRequestErrors(t *testing.T) {
 		wg.Wait()
 	})
 }
+
+func TestHttpJsonRpcClient_AdaptiveBatchSize(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+
+	newClient := func(t *testing.T, providerLimit int, rejectBody string) (*GenericHttpJsonRpcClient, *sync.Mutex, *[]int) {
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Id:       "rpc1",
+				Endpoint: "http://rpc1.localhost:8545",
+				JsonRpc: &common.JsonRpcUpstreamConfig{
+					SupportsBatch: &[]bool{true}[0],
+					BatchMaxSize:  200,
+					BatchMaxWait:  "50ms",
+				},
+			},
+		}, &url.URL{Scheme: "http", Host: "rpc1.localhost:8545"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		gc := client.(*GenericHttpJsonRpcClient)
+
+		mu := &sync.Mutex{}
+		sizes := &[]int{}
+		gc.httpClient = &http.Client{
+			Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				var items []map[string]interface{}
+				body, _ := io.ReadAll(req.Body)
+				if err := sonic.Unmarshal(body, &items); err != nil {
+					return nil, err
+				}
+				mu.Lock()
+				*sizes = append(*sizes, len(items))
+				mu.Unlock()
+
+				respBody := rejectBody
+				if len(items) <= providerLimit {
+					results := make([]string, 0, len(items))
+					for _, item := range items {
+						results = append(results, fmt.Sprintf(`{"jsonrpc":"2.0","id":%v,"result":"0x1"}`, item["id"]))
+					}
+					respBody = "[" + strings.Join(results, ",") + "]"
+				}
+				return &http.Response{
+					StatusCode: 200,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       io.NopCloser(strings.NewReader(respBody)),
+					Request:    req,
+				}, nil
+			}),
+		}
+		return gc, mu, sizes
+	}
+	sendConcurrently := func(t *testing.T, client HttpJsonRpcClient, count int, firstId int) {
+		var wg sync.WaitGroup
+		var failed atomic.Int32
+		for i := 0; i < count; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"eth_blockNumber","params":[]}`, id)))
+				if _, err := client.SendRequest(context.Background(), req); err != nil {
+					t.Logf("request %d failed: %v", id, err)
+					failed.Add(1)
+				}
+			}(firstId + i)
+		}
+		wg.Wait()
+		assert.Equal(t, int32(0), failed.Load())
+	}
+
+	t.Run("LearnsLimitFromErrorAndSplitsSubsequentBatches", func(t *testing.T) {
+		client, mu, sizes := newClient(t, 50, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Batch size is too large. Max batch size is 50"}}`)
+
+		sendConcurrently(t, client, 120, 1)
+		mu.Lock()
+		assert.Greater(t, (*sizes)[0], 50)
+		mu.Unlock()
+		assert.Equal(t, 50, client.batchMaxSize)
+
+		mu.Lock()
+		*sizes = nil
+		mu.Unlock()
+		sendConcurrently(t, client, 120, 1000)
+		mu.Lock()
+		defer mu.Unlock()
+		assert.NotEmpty(t, *sizes)
+		for _, size := range *sizes {
+			assert.LessOrEqual(t, size, 50)
+		}
+	})
+
+	t.Run("HalvesBatchWhenLimitIsNotMentioned", func(t *testing.T) {
+		client, mu, sizes := newClient(t, 40, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batch too large"}}`)
+
+		sendConcurrently(t, client, 100, 1)
+		assert.LessOrEqual(t, client.batchMaxSize, 40)
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Greater(t, (*sizes)[0], 40)
+	})
+
+	t.Run("OtherSingleObjectErrorsAreNotTreatedAsLimits", func(t *testing.T) {
+		client, _, _ := newClient(t, 0, `{"jsonrpc":"2.0","id":null,"error":{"code":-32000,"message":"internal error"}}`)
+
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`))
+		req2 := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":2,"method":"eth_blockNumber","params":[]}`))
+		var wg sync.WaitGroup
+		for _, r := range []*common.NormalizedRequest{req, req2} {
+			wg.Add(1)
+			go func(r *common.NormalizedRequest) {
+				defer wg.Done()
+				_, err := client.SendRequest(context.Background(), r)
+				assert.Error(t, err)
+			}(r)
+		}
+		wg.Wait()
+		assert.Equal(t, 200, client.batchMaxSize)
+	})
+}
+
+func TestHttpJsonRpcClient_TransportConfig(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	parsedUrl := &url.URL{Scheme: "http", Host: "rpc1.localhost:8545"}
+
+	t.Run("AppliesConfiguredPoolSettings", func(t *testing.T) {
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Endpoint: "http://rpc1.localhost:8545",
+				JsonRpc: &common.JsonRpcUpstreamConfig{
+					Transport: &common.HttpTransportConfig{
+						MaxIdleConns:        64,
+						MaxIdleConnsPerHost: 32,
+						MaxConnsPerHost:     48,
+						IdleConnTimeout:     "15s",
+						DisableHttp2:        true,
+					},
+				},
+			},
+		}, parsedUrl)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		transport, ok := client.(*GenericHttpJsonRpcClient).httpClient.Transport.(*http.Transport)
+		if !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, 64, transport.MaxIdleConns)
+		assert.Equal(t, 32, transport.MaxIdleConnsPerHost)
+		assert.Equal(t, 48, transport.MaxConnsPerHost)
+		assert.Equal(t, 15*time.Second, transport.IdleConnTimeout)
+		assert.NotNil(t, transport.TLSNextProto)
+		assert.Empty(t, transport.TLSNextProto)
+	})
+
+	t.Run("KeepsDefaultsForUnsetValues", func(t *testing.T) {
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Endpoint: "http://rpc1.localhost:8545",
+				JsonRpc: &common.JsonRpcUpstreamConfig{
+					Transport: &common.HttpTransportConfig{
+						MaxConnsPerHost: 10,
+					},
+				},
+			},
+		}, parsedUrl)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		transport := client.(*GenericHttpJsonRpcClient).httpClient.Transport.(*http.Transport)
+		assert.Equal(t, 1024, transport.MaxIdleConns)
+		assert.Equal(t, 256, transport.MaxIdleConnsPerHost)
+		assert.Equal(t, 10, transport.MaxConnsPerHost)
+		assert.Equal(t, 90*time.Second, transport.IdleConnTimeout)
+		assert.Nil(t, transport.TLSNextProto)
+	})
+
+	t.Run("RejectsInvalidIdleTimeout", func(t *testing.T) {
+		_, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Endpoint: "http://rpc1.localhost:8545",
+				JsonRpc: &common.JsonRpcUpstreamConfig{
+					Transport: &common.HttpTransportConfig{IdleConnTimeout: "soon"},
+				},
+			},
+		}, parsedUrl)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeInvalidConfig), err)
+	})
+}
+
+func TestHttpJsonRpcClient_WarmupConnections(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+
+	t.Run("PreEstablishesConnections", func(t *testing.T) {
+		const n = 4
+
+		// Block handlers until all warmup requests arrived, so each of them must use its own connection
+		var arrived sync.WaitGroup
+		var received atomic.Int32
+		arrived.Add(n)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodHead, r.Method)
+			if received.Add(1) <= n {
+				arrived.Done()
+				arrived.Wait()
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}))
+		defer srv.Close()
+
+		parsedUrl, _ := url.Parse(srv.URL)
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Endpoint: srv.URL,
+			},
+		}, parsedUrl)
+		assert.NoError(t, err)
+
+		var dials atomic.Int32
+		dialer := &net.Dialer{}
+		gc := client.(*GenericHttpJsonRpcClient)
+		gc.httpClient = &http.Client{
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost: n,
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					dials.Add(1)
+					return dialer.DialContext(ctx, network, addr)
+				},
+			},
+		}
+
+		err = gc.WarmupConnections(context.Background(), n)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(n), dials.Load())
+
+		// Subsequent requests must reuse the warmed up connections
+		for i := 0; i < n; i++ {
+			resp, err := gc.httpClient.Head(srv.URL)
+			if assert.NoError(t, err) {
+				resp.Body.Close()
+			}
+		}
+		assert.Equal(t, int32(n), dials.Load())
+	})
+}
+
+func TestHttpJsonRpcClient_ConnectionClosedRetry(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+
+	newServer := func(received *atomic.Int32) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if received.Add(1) == 1 {
+				// Simulate provider closing the connection without writing any response
+				conn, _, err := w.(http.Hijacker).Hijack()
+				if err == nil {
+					conn.Close()
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+		}))
+	}
+
+	newClient := func(srvUrl string) HttpJsonRpcClient {
+		parsedUrl, _ := url.Parse(srvUrl)
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Endpoint: srvUrl,
+			},
+		}, parsedUrl)
+		assert.NoError(t, err)
+		client.(*GenericHttpJsonRpcClient).httpClient = &http.Client{Transport: &http.Transport{}}
+		return client
+	}
+
+	t.Run("RetriesIdempotentMethodOnce", func(t *testing.T) {
+		var received atomic.Int32
+		srv := newServer(&received)
+		defer srv.Close()
+
+		client := newClient(srv.URL)
+		resp, err := client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)))
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), received.Load())
+		jrr, err := resp.JsonRpcResponse()
+		assert.NoError(t, err)
+		assert.Equal(t, `"0x1"`, string(jrr.Result))
+	})
+
+	t.Run("DoesNotRetryNonIdempotentMethod", func(t *testing.T) {
+		var received atomic.Int32
+		srv := newServer(&received)
+		defer srv.Close()
+
+		client := newClient(srv.URL)
+		_, err := client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["0xabc"]}`)))
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), received.Load())
+	})
+
+	t.Run("DoesNotRetryWritesFilterMethodsOrUnknownMethods", func(t *testing.T) {
+		for _, method := range []string{
+			"eth_sendBundle",
+			"eth_sendPrivateTransaction",
+			"eth_sendRawTransactionConditional",
+			"eth_newPendingTransactionFilter",
+			"eth_getFilterChanges",
+			"debug_setHead",
+			"somevendor_submitOrder",
+		} {
+			var received atomic.Int32
+			srv := newServer(&received)
+
+			client := newClient(srv.URL)
+			_, err := client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"%s","params":[]}`, method))))
+			assert.Error(t, err, method)
+			assert.Equal(t, int32(1), received.Load(), method)
+			srv.Close()
+		}
+	})
+
+	t.Run("RetriesTraceMethods", func(t *testing.T) {
+		var received atomic.Int32
+		srv := newServer(&received)
+		defer srv.Close()
+
+		client := newClient(srv.URL)
+		_, err := client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"debug_traceTransaction","params":["0xabc"]}`)))
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), received.Load())
+	})
+}
+
+func TestHttpJsonRpcClient_ClientCertificates(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+
+	allowedCertPem, allowedKeyPem := generateTestClientCert(t, "allowed-client")
+	otherCertPem, otherKeyPem := generateTestClientCert(t, "other-client")
+
+	clientCAs := x509.NewCertPool()
+	assert.True(t, clientCAs.AppendCertsFromPEM([]byte(allowedCertPem)))
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x7b"}`))
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	serverCaPem := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	parsedUrl, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+
+	sendWith := func(t *testing.T, tlsCfg *common.TLSConfig) (*common.NormalizedResponse, error) {
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Id:       "mtls",
+				Endpoint: server.URL,
+				TLS:      tlsCfg,
+			},
+		}, parsedUrl)
+		if err != nil {
+			return nil, err
+		}
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`))
+		return client.SendRequest(context.Background(), req)
+	}
+
+	t.Run("AcceptsConfiguredCertFromPem", func(t *testing.T) {
+		resp, err := sendWith(t, &common.TLSConfig{
+			Enabled: true,
+			CertPem: allowedCertPem,
+			KeyPem:  allowedKeyPem,
+			CAPem:   serverCaPem,
+		})
+		assert.NoError(t, err)
+		jrr, err := resp.JsonRpcResponse()
+		assert.NoError(t, err)
+		assert.Equal(t, `"0x7b"`, string(jrr.Result))
+	})
+
+	t.Run("AcceptsConfiguredCertFromFilesViaEnv", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile := filepath.Join(dir, "client.crt")
+		keyFile := filepath.Join(dir, "client.key")
+		assert.NoError(t, os.WriteFile(certFile, []byte(allowedCertPem), 0600))
+		assert.NoError(t, os.WriteFile(keyFile, []byte(allowedKeyPem), 0600))
+		t.Setenv("ERPC_TEST_CLIENT_CERT_FILE", certFile)
+
+		_, err := sendWith(t, &common.TLSConfig{
+			Enabled:  true,
+			CertFile: "env:ERPC_TEST_CLIENT_CERT_FILE",
+			KeyFile:  keyFile,
+			CAPem:    serverCaPem,
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("RejectedWithOtherCert", func(t *testing.T) {
+		_, err := sendWith(t, &common.TLSConfig{
+			Enabled: true,
+			CertPem: otherCertPem,
+			KeyPem:  otherKeyPem,
+			CAPem:   serverCaPem,
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("RejectedWithoutCert", func(t *testing.T) {
+		_, err := sendWith(t, &common.TLSConfig{
+			Enabled: true,
+			CAPem:   serverCaPem,
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("InvalidCertMaterialFailsAtConstruction", func(t *testing.T) {
+		_, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Id:       "mtls",
+				Endpoint: server.URL,
+				TLS: &common.TLSConfig{
+					Enabled: true,
+					CertPem: allowedCertPem,
+					KeyPem:  "not a key",
+				},
+			},
+		}, parsedUrl)
+		assert.Error(t, err)
+		assert.True(t, common.HasErrorCode(err, "ErrInvalidConfig"))
+		assert.Contains(t, err.Error(), "failed to parse client cert/key pair")
+	})
+
+	t.Run("MissingEnvVariableFailsAtConstruction", func(t *testing.T) {
+		_, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Id:       "mtls",
+				Endpoint: server.URL,
+				TLS: &common.TLSConfig{
+					Enabled: true,
+					CertPem: "env:ERPC_TEST_UNSET_CLIENT_CERT",
+					KeyPem:  allowedKeyPem,
+				},
+			},
+		}, parsedUrl)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "ERPC_TEST_UNSET_CLIENT_CERT")
+	})
+}
+
+func generateTestClientCert(t *testing.T, commonName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return string(certPem), string(keyPem)
+}
+
+func TestHttpJsonRpcClient_HealthCheck(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+
+	newServer := func(t *testing.T, reply string) (*httptest.Server, *[]string) {
+		var mu sync.Mutex
+		methods := []string{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Method string `json:"method"`
+			}
+			_ = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&body)
+			mu.Lock()
+			methods = append(methods, body.Method)
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(reply))
+		}))
+		t.Cleanup(server.Close)
+		return server, &methods
+	}
+
+	newClient := func(t *testing.T, server *httptest.Server, upsType common.UpstreamType) HttpJsonRpcClient {
+		parsedUrl, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Id:       "hc",
+				Type:     upsType,
+				Endpoint: server.URL,
+			},
+		}, parsedUrl)
+		assert.NoError(t, err)
+		return client
+	}
+
+	t.Run("EvmDefaultsToEthChainId", func(t *testing.T) {
+		server, methods := newServer(t, `{"jsonrpc":"2.0","id":75413,"result":"0x1"}`)
+		client := newClient(t, server, common.UpstreamTypeEvm)
+
+		assert.NoError(t, client.HealthCheck(context.Background()))
+		assert.Equal(t, []string{"eth_chainId"}, *methods)
+	})
+
+	t.Run("SolanaDefaultsToGetHealth", func(t *testing.T) {
+		server, methods := newServer(t, `{"jsonrpc":"2.0","id":75413,"result":"ok"}`)
+		client := newClient(t, server, common.UpstreamType("solana"))
+
+		assert.NoError(t, client.HealthCheck(context.Background()))
+		assert.Equal(t, []string{"getHealth"}, *methods)
+	})
+
+	t.Run("UnhealthyWhenEndpointReturnsError", func(t *testing.T) {
+		server, _ := newServer(t, `{"jsonrpc":"2.0","id":75413,"error":{"code":-32603,"message":"node is behind"}}`)
+		client := newClient(t, server, common.UpstreamTypeEvm)
+
+		err := client.HealthCheck(context.Background())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "node is behind")
+	})
+
+	t.Run("DrpcDelegatesToUnderlyingClient", func(t *testing.T) {
+		server, methods := newServer(t, `{"jsonrpc":"2.0","id":75413,"result":"0x1"}`)
+		parsedUrl, err := url.Parse("drpc://my-key?baseUrl=" + url.QueryEscape(server.URL))
+		assert.NoError(t, err)
+		ups := &Upstream{
+			Logger: logger,
+			config: &common.UpstreamConfig{
+				Id:       "drpc",
+				Type:     common.UpstreamTypeEvmDrpc,
+				Endpoint: parsedUrl.String(),
+				Evm:      &common.EvmUpstreamConfig{ChainId: 1},
+			},
+		}
+		client, err := NewDrpcHttpJsonRpcClient(ups, parsedUrl)
+		assert.NoError(t, err)
+
+		assert.NoError(t, client.HealthCheck(context.Background()))
+		assert.Equal(t, []string{"eth_chainId"}, *methods)
+	})
+
+	t.Run("VendorWithoutKnownNetworkFails", func(t *testing.T) {
+		parsedUrl, err := url.Parse("drpc://my-key")
+		assert.NoError(t, err)
+		client, err := NewDrpcHttpJsonRpcClient(&Upstream{
+			Logger: logger,
+			config: &common.UpstreamConfig{Id: "drpc", Type: common.UpstreamTypeEvmDrpc},
+		}, parsedUrl)
+		assert.NoError(t, err)
+
+		assert.Error(t, client.HealthCheck(context.Background()))
+	})
+}
+
+func TestHttpJsonRpcClient_RateLimitErrorBodies(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+
+	sendWithVendor := func(t *testing.T, vendor common.Vendor, body string) error {
+		t.Helper()
+		ups := &Upstream{
+			config: &common.UpstreamConfig{
+				Id:       "rpc1",
+				Endpoint: "http://rpc1.localhost:8545",
+			},
+			vendor: vendor,
+		}
+		client, err := NewGenericHttpJsonRpcClient(&logger, ups, &url.URL{Scheme: "http", Host: "rpc1.localhost:8545"})
+		assert.NoError(t, err)
+
+		gock.New("http://rpc1.localhost:8545").
+			Post("/").
+			Reply(200).
+			BodyString(body)
+
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x111","latest"]}`))
+		req.SetLastUpstream(ups)
+		_, err = client.SendRequest(context.Background(), req)
+		return err
+	}
+
+	t.Run("AlchemyCode32005", func(t *testing.T) {
+		defer gock.Off()
+
+		err := sendWithVendor(t, vendors.CreateAlchemyVendor(), `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"Your app has reached its throughput limit"}}`)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeEndpointCapacityExceeded), "unexpected error: %v", err)
+	})
+
+	t.Run("VendorSpecificMessage", func(t *testing.T) {
+		defer gock.Off()
+
+		err := sendWithVendor(t, vendors.CreateInfuraVendor(), `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"project ID request rate exceeded"}}`)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeEndpointCapacityExceeded), "unexpected error: %v", err)
+	})
+
+	t.Run("GenericMessageWithoutVendor", func(t *testing.T) {
+		defer gock.Off()
+
+		err := sendWithVendor(t, nil, `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"too many requests"}}`)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeEndpointCapacityExceeded), "unexpected error: %v", err)
+	})
+
+	t.Run("UnrelatedServerErrorIsNotRateLimit", func(t *testing.T) {
+		defer gock.Off()
+
+		err := sendWithVendor(t, vendors.CreateAlchemyVendor(), `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"internal error"}}`)
+		assert.Error(t, err)
+		assert.False(t, common.HasErrorCode(err, common.ErrCodeEndpointCapacityExceeded), "unexpected error: %v", err)
+	})
+}
+
+func TestHttpJsonRpcClient_ContextCancellation(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+
+	// blockingServer holds every request until the caller gives up, and reports when it observed the cancellation
+	blockingServer := func() (*httptest.Server, chan struct{}, chan struct{}) {
+		received := make(chan struct{}, 10)
+		cancelled := make(chan struct{}, 10)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Server only notices the client went away once the request body is consumed
+			_, _ = io.ReadAll(r.Body)
+			received <- struct{}{}
+			select {
+			case <-r.Context().Done():
+				cancelled <- struct{}{}
+			case <-time.After(5 * time.Second):
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+			}
+		}))
+		return server, received, cancelled
+	}
+
+	t.Run("CancellingParentContextAbortsUpstreamCall", func(t *testing.T) {
+		server, received, cancelled := blockingServer()
+		defer server.Close()
+
+		serverUrl, _ := url.Parse(server.URL)
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{Endpoint: server.URL},
+		}, serverUrl)
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() {
+			req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[{},"latest"]}`))
+			_, err := client.SendRequest(ctx, req)
+			errCh <- err
+		}()
+
+		<-received
+		cancel()
+
+		select {
+		case err := <-errCh:
+			assert.Error(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("expected request to return promptly after cancellation")
+		}
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("expected upstream to observe the cancellation")
+		}
+	})
+
+	t.Run("BatchIsAbortedWhenAllCallersCancel", func(t *testing.T) {
+		server, received, cancelled := blockingServer()
+		defer server.Close()
+
+		serverUrl, _ := url.Parse(server.URL)
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Endpoint: server.URL,
+				JsonRpc: &common.JsonRpcUpstreamConfig{
+					SupportsBatch: &common.TRUE,
+					BatchMaxSize:  2,
+					BatchMaxWait:  "10ms",
+				},
+			},
+		}, serverUrl)
+		assert.NoError(t, err)
+
+		ctx1, cancel1 := context.WithCancel(context.Background())
+		ctx2, cancel2 := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		for i, ctx := range []context.Context{ctx1, ctx2} {
+			wg.Add(1)
+			go func(id int, ctx context.Context) {
+				defer wg.Done()
+				req := common.NewNormalizedRequest([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"eth_call","params":[{},"latest"]}`, id)))
+				_, err := client.SendRequest(ctx, req)
+				assert.Error(t, err)
+			}(i+1, ctx)
+		}
+
+		<-received
+		cancel1()
+		select {
+		case <-cancelled:
+			t.Fatal("batch must keep going while another caller is still waiting")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		cancel2()
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("expected upstream to observe the cancellation")
+		}
+		wg.Wait()
+	})
+}
+
+func TestHttpJsonRpcClient_ErrorCodeRemap(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+
+	t.Run("RemapsNonstandardCodeAndKeepsOriginalInData", func(t *testing.T) {
+		defer gock.Off()
+
+		ups := &Upstream{
+			config: &common.UpstreamConfig{
+				Id:       "rpc1",
+				Endpoint: "http://rpc1.localhost:8545",
+				JsonRpc: &common.JsonRpcUpstreamConfig{
+					ErrorCodeRemap: map[int]int{1001: -32602},
+				},
+			},
+		}
+		client, err := NewGenericHttpJsonRpcClient(&logger, ups, &url.URL{Scheme: "http", Host: "rpc1.localhost:8545"})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		gock.New("http://rpc1.localhost:8545").
+			Post("/").
+			Reply(200).
+			BodyString(`{"jsonrpc":"2.0","id":1,"error":{"code":1001,"message":"bad address length","data":"0x1234"}}`)
+
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x111","latest"]}`))
+		req.SetLastUpstream(ups)
+		_, err = client.SendRequest(context.Background(), req)
+
+		// Canonical -32602 is classified as an invalid argument instead of a retryable server-side error
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeEndpointClientSideException), "unexpected error: %v", err)
+		jre := &common.ErrJsonRpcExceptionInternal{}
+		if !assert.ErrorAs(t, err, &jre) {
+			return
+		}
+		assert.Equal(t, 1001, jre.OriginalCode())
+		assert.Equal(t, map[string]interface{}{"originalCode": 1001, "data": "0x1234"}, jre.Details["data"])
+	})
+}
+
+// chunkedResultReader lazily generates a json-rpc response with an array result of roughly the given size,
+// counting how many bytes were actually pulled by the client.
+type chunkedResultReader struct {
+	size    int
+	emitted int
+	done    bool
+	read    int64
+	pending []byte
+}
+
+func (r *chunkedResultReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		switch {
+		case r.done:
+			return 0, io.EOF
+		case r.emitted == 0:
+			r.pending = []byte(`{"jsonrpc":"2.0","id":1,"result":[`)
+		case r.emitted < r.size:
+			r.pending = []byte(`"0x1f9840a85d5af5bf1d1762f925bdaddc4201f984",`)
+		default:
+			r.pending = []byte(`"0x0"]}`)
+			r.done = true
+		}
+		r.emitted += len(r.pending)
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	r.read += int64(n)
+	return n, nil
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestHttpJsonRpcClient_StreamedResponseBody(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+
+	newClient := func(t *testing.T, maxResponseSize int64, body *chunkedResultReader) HttpJsonRpcClient {
+		ups := &Upstream{
+			config: &common.UpstreamConfig{
+				Id:       "rpc1",
+				Endpoint: "http://rpc1.localhost:8545",
+				JsonRpc: &common.JsonRpcUpstreamConfig{
+					MaxResponseSize: maxResponseSize,
+				},
+			},
+		}
+		client, err := NewGenericHttpJsonRpcClient(&logger, ups, &url.URL{Scheme: "http", Host: "rpc1.localhost:8545"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		client.(*GenericHttpJsonRpcClient).httpClient = &http.Client{
+			Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode:       200,
+					Header:           http.Header{"Content-Type": []string{"application/json"}},
+					TransferEncoding: []string{"chunked"},
+					ContentLength:    -1,
+					Body:             io.NopCloser(body),
+					Request:          req,
+				}, nil
+			}),
+		}
+		return client
+	}
+	newRequest := func() *common.NormalizedRequest {
+		return common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"fromBlock":"0x1","toBlock":"0x100"}]}`))
+	}
+
+	t.Run("OversizedChunkedResponseIsAbortedWithoutFullBuffering", func(t *testing.T) {
+		body := &chunkedResultReader{size: 64 * 1024 * 1024}
+		client := newClient(t, 1024*1024, body)
+
+		_, err := client.SendRequest(context.Background(), newRequest())
+
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeEndpointResponseTooLarge), "unexpected error: %v", err)
+		assert.LessOrEqual(t, body.read, int64(1024*1024+1), "must stop reading right after the limit")
+	})
+
+	t.Run("ChunkedResponseWithinLimitIsReadFully", func(t *testing.T) {
+		body := &chunkedResultReader{size: 256 * 1024}
+		client := newClient(t, 1024*1024, body)
+
+		resp, err := client.SendRequest(context.Background(), newRequest())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, body.read, int64(len(resp.Body())))
+		jrr, err := resp.JsonRpcResponse()
+		if !assert.NoError(t, err) {
+			return
+		}
+		res, err := jrr.ParsedResult()
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Greater(t, len(res.([]interface{})), 1000)
+	})
+
+	t.Run("UnlimitedByDefault", func(t *testing.T) {
+		body := &chunkedResultReader{size: 2 * 1024 * 1024}
+		client := newClient(t, 0, body)
+
+		resp, err := client.SendRequest(context.Background(), newRequest())
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, body.read, int64(len(resp.Body())))
+	})
+}
+
+// logsStreamReader emits an eth_getLogs response with the given number of logs, one log per read
+type logsStreamReader struct {
+	logs    int
+	emitted int
+	done    bool
+	read    int64
+	pending []byte
+}
+
+func (r *logsStreamReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		switch {
+		case r.done:
+			return 0, io.EOF
+		case r.emitted == 0 && r.read == 0:
+			r.pending = []byte(`{"jsonrpc":"2.0","id":1,"result":[`)
+		case r.emitted < r.logs:
+			if r.emitted > 0 {
+				r.pending = append(r.pending, ',')
+			}
+			// Braces, brackets and the "result" word inside strings must not be counted as entries
+			r.pending = append(r.pending, fmt.Sprintf(`{"address":"0x1f98","topics":["0xddf2","0x{]"],"data":"\"result\":[{","logIndex":"0x%x"}`, r.emitted)...)
+			r.emitted++
+		default:
+			r.pending = []byte(`]}`)
+			r.done = true
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	r.read += int64(n)
+	return n, nil
+}
+
+func TestHttpJsonRpcClient_GetLogsMaxResults(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+
+	newClient := func(t *testing.T, maxResults int, body io.Reader) HttpJsonRpcClient {
+		ups := &Upstream{
+			config: &common.UpstreamConfig{
+				Id:       "rpc1",
+				Endpoint: "http://rpc1.localhost:8545",
+				Evm: &common.EvmUpstreamConfig{
+					GetLogsMaxResults: maxResults,
+				},
+			},
+		}
+		client, err := NewGenericHttpJsonRpcClient(&logger, ups, &url.URL{Scheme: "http", Host: "rpc1.localhost:8545"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		client.(*GenericHttpJsonRpcClient).httpClient = &http.Client{
+			Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode:       200,
+					Header:           http.Header{"Content-Type": []string{"application/json"}},
+					TransferEncoding: []string{"chunked"},
+					ContentLength:    -1,
+					Body:             io.NopCloser(body),
+					Request:          req,
+				}, nil
+			}),
+		}
+		return client
+	}
+	newRequest := func(method string) *common.NormalizedRequest {
+		return common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":[{"fromBlock":"0x1","toBlock":"0x100"}]}`))
+	}
+
+	t.Run("TooManyLogsAreRejectedWhileStreaming", func(t *testing.T) {
+		body := &logsStreamReader{logs: 100000}
+		client := newClient(t, 10, body)
+
+		_, err := client.SendRequest(context.Background(), newRequest("eth_getLogs"))
+
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeResultSetTooLarge), "unexpected error: %v", err)
+		assert.False(t, common.IsRetryableTowardsUpstream(err))
+		assert.LessOrEqual(t, body.emitted, 12, "must stop reading right after the limit")
+	})
+
+	t.Run("LogsWithinLimitAreReturned", func(t *testing.T) {
+		client := newClient(t, 10, &logsStreamReader{logs: 10})
+
+		resp, err := client.SendRequest(context.Background(), newRequest("eth_getLogs"))
+		if !assert.NoError(t, err) {
+			return
+		}
+		jrr, err := resp.JsonRpcResponse()
+		if !assert.NoError(t, err) {
+			return
+		}
+		res, err := jrr.ParsedResult()
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Len(t, res.([]interface{}), 10)
+	})
+
+	t.Run("OtherMethodsAreNotLimited", func(t *testing.T) {
+		client := newClient(t, 10, &logsStreamReader{logs: 20})
+
+		_, err := client.SendRequest(context.Background(), newRequest("eth_getFilterLogs"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("TranslatesToLargeRangeJsonRpcError", func(t *testing.T) {
+		jre := &common.ErrJsonRpcExceptionInternal{}
+		err := common.TranslateToJsonRpcException(common.NewErrResultSetTooLarge("eth_getLogs", 10))
+		if assert.ErrorAs(t, err, &jre) {
+			assert.Equal(t, common.JsonRpcErrorEvmLogsLargeRange, jre.NormalizedCode())
+			assert.Contains(t, jre.Message, "narrower filter")
+		}
+	})
+}
+
+func TestHttpJsonRpcClient_DebugBundle(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+
+	newBatchClient := func(t *testing.T, jsonRpc *common.JsonRpcUpstreamConfig, statusCodes ...int) (*Upstream, HttpJsonRpcClient) {
+		ups := &Upstream{
+			config: &common.UpstreamConfig{
+				Id:       "rpc1",
+				Endpoint: "https://rpc1.localhost/v2/secret-api-key",
+				JsonRpc:  jsonRpc,
+			},
+			debugBundles: newDebugBundleRecorder(&common.DebugBundleConfig{FailureThreshold: 3}),
+		}
+		client, err := NewGenericHttpJsonRpcClient(&logger, ups, &url.URL{Scheme: "https", Host: "rpc1.localhost", Path: "/v2/secret-api-key"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		calls := 0
+		client.(*GenericHttpJsonRpcClient).httpClient = &http.Client{
+			Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				status := statusCodes[calls%len(statusCodes)]
+				calls++
+				body := `{"jsonrpc":"2.0","id":1,"result":"0x1"}`
+				if status != 200 {
+					body = `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"internal error"}}`
+				}
+				return &http.Response{
+					StatusCode: status,
+					Header: http.Header{
+						"Content-Type": []string{"application/json"},
+						"X-Api-Key":    []string{"secret-api-key"},
+						"X-Request-Id": []string{"abc123"},
+					},
+					Body:    io.NopCloser(strings.NewReader(body)),
+					Request: req,
+				}, nil
+			}),
+		}
+		return ups, client
+	}
+	newClient := func(t *testing.T, statusCodes ...int) (*Upstream, HttpJsonRpcClient) {
+		return newBatchClient(t, nil, statusCodes...)
+	}
+	send := func(client HttpJsonRpcClient) error {
+		_, err := client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000000000","latest"]}`)))
+		return err
+	}
+
+	t.Run("BundleIsCapturedOnceFailureThresholdIsReached", func(t *testing.T) {
+		ups, client := newClient(t, 503)
+
+		assert.Error(t, send(client))
+		assert.Error(t, send(client))
+		assert.Empty(t, ups.DebugBundles(), "must not capture before threshold")
+
+		assert.Error(t, send(client))
+		bundles := ups.DebugBundles()
+		if !assert.Len(t, bundles, 1) {
+			return
+		}
+		b := bundles[0]
+		assert.Equal(t, "rpc1", b.UpstreamId)
+		assert.Equal(t, "eth_getBalance", b.Method)
+		assert.Equal(t, 3, b.ConsecutiveFailures)
+		assert.Equal(t, 503, b.StatusCode)
+		assert.Contains(t, b.RequestBody, `"method":"eth_getBalance"`)
+		assert.Contains(t, b.ResponseBody, "internal error")
+		assert.Equal(t, "abc123", b.ResponseHeaders["X-Request-Id"])
+		assert.Equal(t, "application/json", b.RequestHeaders["Content-Type"])
+		assert.NotEmpty(t, b.Error)
+
+		assert.Equal(t, "https://rpc1.localhost/REDACTED", b.Url)
+		assert.Equal(t, "REDACTED", b.ResponseHeaders["X-Api-Key"])
+	})
+
+	t.Run("SuccessResetsFailureStreak", func(t *testing.T) {
+		ups, client := newClient(t, 503, 503, 200)
+
+		for i := 0; i < 6; i++ {
+			_ = send(client)
+		}
+		assert.Empty(t, ups.DebugBundles(), "streak must restart after each success")
+	})
+
+	t.Run("BatchedRequestsAreCaptured", func(t *testing.T) {
+		ups, client := newBatchClient(t, &common.JsonRpcUpstreamConfig{SupportsBatch: &common.TRUE, BatchMaxWait: "10ms"}, 503)
+
+		for i := 0; i < 3; i++ {
+			assert.Error(t, send(client))
+		}
+		bundles := ups.DebugBundles()
+		if assert.Len(t, bundles, 1) {
+			assert.Equal(t, "eth_getBalance", bundles[0].Method)
+			assert.Equal(t, 3, bundles[0].ConsecutiveFailures)
+			assert.True(t, strings.HasPrefix(bundles[0].RequestBody, "["), "batch request body must be captured: %s", bundles[0].RequestBody)
+			assert.Contains(t, bundles[0].ResponseBody, "internal error")
+			assert.NotEmpty(t, bundles[0].Error)
+		}
+	})
+
+	t.Run("NoCaptureWhenNotEnabled", func(t *testing.T) {
+		ups, client := newClient(t, 503)
+		ups.debugBundles = nil
+
+		for i := 0; i < 3; i++ {
+			assert.Error(t, send(client))
+		}
+		assert.Nil(t, ups.DebugBundles())
+	})
+}
+
+func TestHttpJsonRpcClient_CaptureSample(t *testing.T) {
+	newBatchClient := func(t *testing.T, rate float64, jsonRpc *common.JsonRpcUpstreamConfig) (*bytes.Buffer, HttpJsonRpcClient) {
+		logs := &bytes.Buffer{}
+		logger := zerolog.New(logs)
+		ups := &Upstream{
+			config: &common.UpstreamConfig{
+				Id:            "rpc1",
+				Endpoint:      "https://rpc1.localhost/v2/secret-api-key",
+				CaptureSample: &common.CaptureSampleConfig{Rate: rate},
+				JsonRpc:       jsonRpc,
+			},
+		}
+		client, err := NewGenericHttpJsonRpcClient(&logger, ups, &url.URL{Scheme: "https", Host: "rpc1.localhost", Path: "/v2/secret-api-key"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		client.(*GenericHttpJsonRpcClient).httpClient = &http.Client{
+			Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				req.Header.Set("Authorization", "Bearer secret-token")
+				return &http.Response{
+					StatusCode: 200,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       io.NopCloser(strings.NewReader(`{"jsonrpc":"2.0","id":1,"result":"0x1234"}`)),
+					Request:    req,
+				}, nil
+			}),
+		}
+		return logs, client
+	}
+	newClient := func(t *testing.T, rate float64) (*bytes.Buffer, HttpJsonRpcClient) {
+		return newBatchClient(t, rate, nil)
+	}
+	send := func(t *testing.T, client HttpJsonRpcClient) {
+		_, err := client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000000000","latest"]}`)))
+		assert.NoError(t, err)
+	}
+
+	t.Run("FullRateLogsRedactedExchange", func(t *testing.T) {
+		logs, client := newClient(t, 1)
+		send(t, client)
+
+		out := logs.String()
+		assert.Contains(t, out, "sampled exchange with upstream")
+		assert.Contains(t, out, `eth_getBalance`)
+		assert.Contains(t, out, `0x1234`)
+		assert.Contains(t, out, `"url":"https://rpc1.localhost/REDACTED"`)
+		assert.Contains(t, out, `"Authorization":"REDACTED"`)
+		assert.NotContains(t, out, "secret-api-key")
+		assert.NotContains(t, out, "secret-token")
+	})
+
+	t.Run("BatchExchangesAreSampled", func(t *testing.T) {
+		logs, client := newBatchClient(t, 1, &common.JsonRpcUpstreamConfig{SupportsBatch: &common.TRUE, BatchMaxWait: "10ms"})
+		send(t, client)
+
+		out := logs.String()
+		assert.Contains(t, out, "sampled exchange with upstream for method eth_getBalance")
+		assert.Contains(t, out, `"requestBody":"[`)
+		assert.NotContains(t, out, "secret-token")
+	})
+
+	t.Run("ZeroRateLogsNothing", func(t *testing.T) {
+		logs, client := newClient(t, 0)
+		for i := 0; i < 20; i++ {
+			send(t, client)
+		}
+		assert.NotContains(t, logs.String(), "sampled exchange")
+	})
+
+	t.Run("RateOutOfBoundsIsRejected", func(t *testing.T) {
+		logger := zerolog.Nop()
+		ups := &Upstream{config: &common.UpstreamConfig{Id: "rpc1", CaptureSample: &common.CaptureSampleConfig{Rate: 1.5}}}
+		_, err := NewGenericHttpJsonRpcClient(&logger, ups, &url.URL{Scheme: "https", Host: "rpc1.localhost"})
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeInvalidConfig), "unexpected error: %v", err)
+	})
+}
+
+func TestHttpJsonRpcClient_AddressFormat(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	const mixed = "0x5AAEB6053f3e94c9b9a09f33669435e7ef1beaed"
+
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, string(body))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(string(body), "[") {
+			_, _ = w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":"0x1"}]`))
+		} else {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	newClient := func(t *testing.T, format common.EvmAddressFormat, batch bool) HttpJsonRpcClient {
+		cfg := &common.UpstreamConfig{
+			Id:       "test",
+			Endpoint: server.URL,
+			Evm:      &common.EvmUpstreamConfig{AddressFormat: format},
+		}
+		if batch {
+			cfg.JsonRpc = &common.JsonRpcUpstreamConfig{SupportsBatch: &common.TRUE, BatchMaxSize: 1}
+		}
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{config: cfg}, u)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return client
+	}
+	sendAndCapture := func(t *testing.T, client HttpJsonRpcClient, body string) (string, *common.NormalizedRequest) {
+		mu.Lock()
+		received = nil
+		mu.Unlock()
+		req := common.NewNormalizedRequest([]byte(body))
+		_, err := client.SendRequest(context.Background(), req)
+		assert.NoError(t, err)
+		mu.Lock()
+		defer mu.Unlock()
+		if !assert.Len(t, received, 1) {
+			return "", req
+		}
+		return received[0], req
+	}
+
+	cases := []struct {
+		format   common.EvmAddressFormat
+		expected string
+	}{
+		{common.EvmAddressFormatChecksum, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
+		{common.EvmAddressFormatLowercase, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+		{"", mixed},
+	}
+	for _, tc := range cases {
+		for _, batch := range []bool{false, true} {
+			t.Run(fmt.Sprintf("Format_%s_Batch_%v", tc.format, batch), func(t *testing.T) {
+				client := newClient(t, tc.format, batch)
+				for _, method := range []string{"eth_getBalance", "eth_getCode"} {
+					sent, req := sendAndCapture(t, client, `{"jsonrpc":"2.0","id":1,"method":"`+method+`","params":["`+mixed+`","latest"]}`)
+					assert.Contains(t, sent, `"params":["`+tc.expected+`","latest"]`)
+
+					// The shared request (and so its cache key and other upstreams) keeps what the client sent
+					jrq, err := req.JsonRpcRequest()
+					assert.NoError(t, err)
+					assert.Equal(t, mixed, jrq.Params[0])
+				}
+			})
+		}
+	}
+
+	t.Run("OtherMethodsAreNotRewritten", func(t *testing.T) {
+		client := newClient(t, common.EvmAddressFormatChecksum, false)
+		sent, _ := sendAndCapture(t, client, `{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByHash","params":["`+mixed+`",false]}`)
+		assert.Contains(t, sent, mixed)
+	})
+
+	t.Run("InvalidFormatIsRejected", func(t *testing.T) {
+		_, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{config: &common.UpstreamConfig{
+			Id:  "test",
+			Evm: &common.EvmUpstreamConfig{AddressFormat: "uppercase"},
+		}}, u)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeInvalidConfig))
+	})
+}
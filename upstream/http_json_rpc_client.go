@@ -3,26 +3,41 @@ package upstream
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/erpc/erpc/common"
+	"github.com/erpc/erpc/health"
 	"github.com/erpc/erpc/util"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 )
 
+// maxWarmupConnections caps how many connections are pre-established per upstream to avoid being abusive towards providers.
+const maxWarmupConnections = 16
+
 type HttpJsonRpcClient interface {
 	GetType() ClientType
 	SupportsNetwork(networkId string) (bool, error)
 	SendRequest(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error)
+	// HealthCheck sends a cheap request (e.g. eth_chainId for evm) to verify the endpoint is reachable and healthy.
+	HealthCheck(ctx context.Context) error
+	// Close releases idle connections towards the endpoint, requests still in flight are not interrupted.
+	Close() error
 }
 
 type GenericHttpJsonRpcClient struct {
@@ -37,9 +52,15 @@ type GenericHttpJsonRpcClient struct {
 	batchMaxWait  time.Duration
 
 	batchMu       sync.Mutex
-	batchRequests map[interface{}]*batchRequest
+	batchRequests map[string]*batchRequest
 	batchDeadline *time.Time
 	batchTimer    *time.Timer
+
+	faults     *faultInjector
+	signer     *sigV4Signer
+	sampleRate float64
+
+	addressFormat common.EvmAddressFormat
 }
 
 type batchRequest struct {
@@ -76,30 +97,158 @@ func NewGenericHttpJsonRpcClient(logger *zerolog.Logger, pu *Upstream, parsedUrl
 				client.batchMaxWait = 50 * time.Millisecond
 			}
 
-			client.batchRequests = make(map[interface{}]*batchRequest)
+			client.batchRequests = make(map[string]*batchRequest)
+		}
+	}
+
+	faults, err := newFaultInjector(pu.config.FaultInjection)
+	if err != nil {
+		return nil, common.NewErrInvalidConfig(fmt.Sprintf("invalid fault injection config for upstream %s: %v", pu.config.Id, err))
+	}
+	client.faults = faults
+
+	if cs := pu.config.CaptureSample; cs != nil {
+		if cs.Rate < 0 || cs.Rate > 1 {
+			return nil, common.NewErrInvalidConfig(fmt.Sprintf("invalid captureSample.rate for upstream %s: %v (must be between 0 and 1)", pu.config.Id, cs.Rate))
+		}
+		client.sampleRate = cs.Rate
+	}
+
+	if pu.config.Evm != nil {
+		switch pu.config.Evm.AddressFormat {
+		case "", common.EvmAddressFormatLowercase, common.EvmAddressFormatChecksum:
+			client.addressFormat = pu.config.Evm.AddressFormat
+		default:
+			return nil, common.NewErrInvalidConfig(fmt.Sprintf("invalid evm.addressFormat for upstream %s: %s (must be lowercase or checksum)", pu.config.Id, pu.config.Evm.AddressFormat))
+		}
+	}
+
+	if pu.config.JsonRpc != nil {
+		signer, err := newSigV4Signer(pu.config.Id, pu.config.JsonRpc.SigV4)
+		if err != nil {
+			return nil, err
+		}
+		client.signer = signer
+	}
+
+	var tlsConfig *tls.Config
+	if pu.config.TLS != nil && pu.config.TLS.Enabled {
+		tc, err := common.NewTLSClientConfig(pu.config.TLS)
+		if err != nil {
+			return nil, common.NewErrInvalidConfig(fmt.Sprintf("invalid tls config for upstream %s: %v", pu.config.Id, err))
 		}
+		tlsConfig = tc
 	}
 
-	if util.IsTest() {
+	var transportCfg *common.HttpTransportConfig
+	if pu.config.JsonRpc != nil {
+		transportCfg = pu.config.JsonRpc.Transport
+	}
+
+	if util.IsTest() && transportCfg == nil {
 		client.httpClient = &http.Client{}
+		if tlsConfig != nil {
+			client.httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+		}
 	} else {
+		transport, err := newHttpTransport(transportCfg, tlsConfig)
+		if err != nil {
+			return nil, common.NewErrInvalidConfig(fmt.Sprintf("invalid transport config for upstream %s: %v", pu.config.Id, err))
+		}
 		client.httpClient = &http.Client{
-			Timeout: 60 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        1024,
-				MaxIdleConnsPerHost: 256,
-				IdleConnTimeout:     90 * time.Second,
-			},
+			Timeout:   60 * time.Second,
+			Transport: transport,
 		}
 	}
 
 	return client, nil
 }
 
+func newHttpTransport(cfg *common.HttpTransportConfig, tlsConfig *tls.Config) (*http.Transport, error) {
+	transport := &http.Transport{
+		MaxIdleConns:        1024,
+		MaxIdleConnsPerHost: 256,
+		IdleConnTimeout:     90 * time.Second,
+		TLSClientConfig:     tlsConfig,
+	}
+	if cfg == nil {
+		return transport, nil
+	}
+
+	if cfg.MaxIdleConns > 0 {
+		transport.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	}
+	if cfg.IdleConnTimeout != "" {
+		d, err := time.ParseDuration(cfg.IdleConnTimeout)
+		if err != nil {
+			return nil, err
+		}
+		transport.IdleConnTimeout = d
+	}
+	if cfg.DisableHttp2 {
+		// A non-nil empty map prevents the transport from upgrading connections to HTTP/2
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return transport, nil
+}
+
+// WarmupConnections opens up to n concurrent connections towards the endpoint and leaves them idle in the pool,
+// so that TLS handshakes are done before the first real request. HEAD requests are used so that providers
+// do not account them as rpc calls, and their response status is irrelevant.
+func (c *GenericHttpJsonRpcClient) WarmupConnections(ctx context.Context, n int) error {
+	if n > maxWarmupConnections {
+		n = maxWarmupConnections
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.Url.String(), nil)
+			if err == nil {
+				var resp *http.Response
+				resp, err = c.httpClient.Do(req)
+				if err == nil {
+					_, _ = io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
 func (c *GenericHttpJsonRpcClient) GetType() ClientType {
 	return ClientTypeHttpJsonRpc
 }
 
+func (c *GenericHttpJsonRpcClient) HealthCheck(ctx context.Context) error {
+	// Health checks are sent individually, so they are not delayed nor masked by batching
+	return sendHealthCheck(ctx, c.upstream.Config(), c.sendSingleRequest)
+}
+
+func (c *GenericHttpJsonRpcClient) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
 func (c *GenericHttpJsonRpcClient) SupportsNetwork(networkId string) (bool, error) {
 	cfg := c.upstream.Config()
 	if cfg.Evm != nil && cfg.Evm.ChainId > 0 {
@@ -109,6 +258,12 @@ func (c *GenericHttpJsonRpcClient) SupportsNetwork(networkId string) (bool, erro
 }
 
 func (c *GenericHttpJsonRpcClient) SendRequest(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error) {
+	if c.faults != nil {
+		if err := c.faults.inject(ctx, c.upstream, req); err != nil {
+			return nil, err
+		}
+	}
+
 	if !c.supportsBatch {
 		return c.sendSingleRequest(ctx, req)
 	}
@@ -152,62 +307,93 @@ func (c *GenericHttpJsonRpcClient) SendRequest(ctx context.Context, req *common.
 }
 
 func (c *GenericHttpJsonRpcClient) queueRequest(id interface{}, req *batchRequest) {
+	key := batchIdKey(id)
 	c.batchMu.Lock()
 
-	if _, ok := c.batchRequests[id]; ok {
+	if _, ok := c.batchRequests[key]; ok {
 		// We must not include multiple requests with same ID in batch requests
 		// to avoid issues when mapping responses.
 		c.batchTimer.Stop()
+		requests, deadline := c.takeBatchLocked()
 		c.batchMu.Unlock()
-		c.processBatch()
+		c.sendBatch(requests, deadline)
 		c.queueRequest(id, req)
 		return
 	}
 
-	c.batchRequests[id] = req
+	c.batchRequests[key] = req
 	ctxd, ok := req.ctx.Deadline()
 	if ctxd.After(time.Now()) && ok {
 		if c.batchDeadline == nil || ctxd.After(*c.batchDeadline) {
 			c.batchDeadline = &ctxd
 		}
 	}
-	c.logger.Debug().Msgf("queuing request %s for batch (current batch: %d)", id, len(c.batchRequests))
+	c.logger.Debug().Msgf("queuing request %s for batch (current batch: %d)", key, len(c.batchRequests))
 
 	if len(c.batchRequests) == 1 {
 		c.batchTimer = time.AfterFunc(c.batchMaxWait, c.processBatch)
 		c.batchMu.Unlock()
 	} else if len(c.batchRequests) >= c.batchMaxSize {
 		c.batchTimer.Stop()
+		// Take the full batch before unlocking, otherwise concurrent requests could be added
+		// on top of it and exceed the max batch size supported by the upstream.
+		requests, deadline := c.takeBatchLocked()
 		c.batchMu.Unlock()
-		c.processBatch()
+		c.sendBatch(requests, deadline)
 	} else {
 		c.batchMu.Unlock()
 	}
 }
 
 func (c *GenericHttpJsonRpcClient) processBatch() {
-	var batchCtx context.Context
-	var cancelCtx context.CancelFunc
-
 	c.batchMu.Lock()
-	requests := c.batchRequests
-	if c.batchDeadline != nil {
-		batchCtx, cancelCtx = context.WithDeadline(context.Background(), *c.batchDeadline)
-		defer cancelCtx()
-	} else {
-		batchCtx = context.Background()
-	}
-	c.batchRequests = make(map[interface{}]*batchRequest)
-	c.batchDeadline = nil
+	requests, deadline := c.takeBatchLocked()
 	c.batchMu.Unlock()
 
+	c.sendBatch(requests, deadline)
+}
+
+// takeBatchLocked detaches currently queued requests so that a new batch can be started, batchMu must be held.
+func (c *GenericHttpJsonRpcClient) takeBatchLocked() (map[string]*batchRequest, *time.Time) {
+	requests, deadline := c.batchRequests, c.batchDeadline
+	c.batchRequests = make(map[string]*batchRequest)
+	c.batchDeadline = nil
+	return requests, deadline
+}
+
+// cancelWhenAllDone aborts the in-flight batch once every request in it is cancelled (e.g. all clients
+// disconnected or lost a hedge), because no one is waiting for its response anymore.
+func (c *GenericHttpJsonRpcClient) cancelWhenAllDone(batchCtx context.Context, cancel context.CancelFunc, requests map[string]*batchRequest) {
+	var remaining atomic.Int32
+	remaining.Store(int32(len(requests)))
+	for _, req := range requests {
+		stop := context.AfterFunc(req.ctx, func() {
+			if remaining.Add(-1) == 0 {
+				cancel()
+			}
+		})
+		context.AfterFunc(batchCtx, func() { stop() })
+	}
+}
+
+func (c *GenericHttpJsonRpcClient) sendBatch(requests map[string]*batchRequest, deadline *time.Time) {
 	ln := len(requests)
 	if ln == 0 {
 		return
 	}
+
+	var batchCtx context.Context
+	var cancelCtx context.CancelFunc
+	if deadline != nil {
+		batchCtx, cancelCtx = context.WithDeadline(context.Background(), *deadline)
+	} else {
+		batchCtx, cancelCtx = context.WithCancel(context.Background())
+	}
+	defer cancelCtx()
+	c.cancelWhenAllDone(batchCtx, cancelCtx, requests)
 	c.logger.Debug().Msgf("processing batch with %d requests", ln)
 
-	batchReq := make([]common.JsonRpcRequest, 0, ln)
+	batchReq := make([]*common.JsonRpcRequest, 0, ln)
 	for _, req := range requests {
 		jrReq, err := req.request.JsonRpcRequest()
 		if err != nil {
@@ -221,12 +407,18 @@ func (c *GenericHttpJsonRpcClient) processBatch() {
 			continue
 		}
 		req.request.RLock()
-		batchReq = append(batchReq, common.JsonRpcRequest{
-			JSONRPC: jrReq.JSONRPC,
-			Method:  jrReq.Method,
-			Params:  jrReq.Params,
-			ID:      jrReq.ID,
-		})
+		outReq, err := common.NewJsonRpcRequest(jrReq.ID, jrReq.Method, c.outboundParams(jrReq.Method, jrReq.Params))
+		if err != nil {
+			req.err <- common.NewErrUpstreamRequest(
+				err,
+				c.upstream.Config().Id,
+				req.request.NetworkId(),
+				jrReq.Method,
+				0, 0, 0, 0,
+			)
+			continue
+		}
+		batchReq = append(batchReq, outReq)
 	}
 
 	requestBody, err := sonic.Marshal(batchReq)
@@ -245,6 +437,9 @@ func (c *GenericHttpJsonRpcClient) processBatch() {
 	httpReq, errReq := http.NewRequestWithContext(batchCtx, "POST", c.Url.String(), bytes.NewBuffer(requestBody))
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("User-Agent", fmt.Sprintf("erpc (Project/%s; Budget/%s)", c.upstream.ProjectId, c.upstream.config.RateLimitBudget))
+	if errReq == nil && c.signer != nil {
+		errReq = c.signer.sign(httpReq, requestBody, time.Now())
+	}
 	if errReq != nil {
 		for _, req := range requests {
 			req.err <- errReq
@@ -273,34 +468,62 @@ func (c *GenericHttpJsonRpcClient) processBatch() {
 
 	select {
 	case <-batchCtx.Done():
+		outcomes := make([]exchangeOutcome, 0, len(requests))
 		for _, req := range requests {
 			err := batchCtx.Err()
 			if errors.Is(err, context.DeadlineExceeded) {
 				err = common.NewErrEndpointRequestTimeout(time.Since(startedAt))
+				outcomes = append(outcomes, batchExchangeOutcome(req, err))
 			}
 			req.err <- err
 		}
+		c.recordExchange(outcomes, requestBody, nil, nil, startedAt)
 		return
 	case err := <-batchErrChan:
+		outcomes := make([]exchangeOutcome, 0, len(requests))
 		for _, req := range requests {
-			req.err <- common.NewErrEndpointServerSideException(
+			err := common.NewErrEndpointServerSideException(
 				fmt.Errorf(strings.ReplaceAll(err.Error(), c.Url.String(), "")),
 				nil,
 			)
+			outcomes = append(outcomes, batchExchangeOutcome(req, err))
+			req.err <- err
 		}
+		c.recordExchange(outcomes, requestBody, nil, nil, startedAt)
 		return
 	case resp := <-batchRespChan:
-		c.processBatchResponse(requests, resp)
+		c.processBatchResponse(requests, resp, deadline, requestBody, startedAt)
 		return
 	}
 }
 
-func (c *GenericHttpJsonRpcClient) processBatchResponse(requests map[interface{}]*batchRequest, resp *http.Response) {
+func batchExchangeOutcome(req *batchRequest, err error) exchangeOutcome {
+	method, _ := req.request.Method()
+	return exchangeOutcome{method: method, err: err}
+}
+
+func (c *GenericHttpJsonRpcClient) processBatchResponse(requests map[string]*batchRequest, resp *http.Response, deadline *time.Time, requestBody []byte, startedAt time.Time) {
 	defer resp.Body.Close()
-	respBody, err := io.ReadAll(resp.Body)
+
+	// Outcome of each request for debug bundles, left empty when the batch is resent in smaller ones
+	var respBody []byte
+	outcomes := make([]exchangeOutcome, 0, len(requests))
+	defer func() {
+		c.recordExchange(outcomes, requestBody, resp, respBody, startedAt)
+	}()
+	deliver := func(req *batchRequest, nr *common.NormalizedResponse, err error) {
+		outcomes = append(outcomes, batchExchangeOutcome(req, err))
+		if err != nil {
+			req.err <- err
+		} else {
+			req.response <- nr
+		}
+	}
+
+	respBody, err := readResponseBody(resp.Body, resp.ContentLength, c.maxResponseSize(len(requests)))
 	if err != nil {
 		for _, req := range requests {
-			req.err <- err
+			deliver(req, nil, err)
 		}
 		return
 	}
@@ -309,17 +532,31 @@ func (c *GenericHttpJsonRpcClient) processBatchResponse(requests map[interface{}
 		c.logger.Debug().Str("body", string(respBody)).Msgf("received batch response")
 	}
 
+	if isEmptySuccessBody(resp, respBody) {
+		for _, req := range requests {
+			deliver(req, nil, c.emptyBodyError(resp, req.request, "batch"))
+		}
+		return
+	}
+
+	if trimmed := bytes.TrimSpace(respBody); len(trimmed) > 0 && trimmed[0] != '[' && len(requests) > 1 {
+		if limit, ok := detectBatchLimit(resp.StatusCode, trimmed); ok {
+			c.shrinkBatchAndResend(requests, limit, deadline)
+			return
+		}
+	}
+
 	// Usually when upstream is dead and returns a non-JSON response body
 	if respBody[0] == '<' {
 		for _, req := range requests {
-			req.err <- common.NewErrEndpointServerSideException(
+			deliver(req, nil, common.NewErrEndpointServerSideException(
 				fmt.Errorf("upstream returned non-JSON response body"),
 				map[string]interface{}{
 					"statusCode": resp.StatusCode,
 					"headers":    resp.Header,
 					"body":       string(respBody),
 				},
-			)
+			))
 		}
 		return
 	}
@@ -330,23 +567,15 @@ func (c *GenericHttpJsonRpcClient) processBatchResponse(requests map[interface{}
 		// Try parsing as single json-rpc object,
 		// some providers return a single object on some errors even when request is batch.
 		// this is a workaround to handle those cases.
-		nr := common.NewNormalizedResponse().WithBody(respBody)
 		for _, br := range requests {
-			inr, err := common.CopyResponseForRequest(nr, br.request)
-			if err != nil {
-				br.err <- err
-				continue
-			}
-			err = c.normalizeJsonRpcError(resp, inr)
-			if err != nil {
-				br.err <- err
-			} else {
-				br.response <- nr
-			}
+			nr := common.NewNormalizedResponse().WithRequest(br.request).WithBody(withBatchItemId(respBody, br.request))
+			deliver(br, nr, c.normalizeJsonRpcError(resp, nr))
 		}
 		return
 	}
 
+	// Items are matched to requests strictly by ID (never by position) because some upstreams
+	// reorder batch items, IDs are unique within a batch since queueRequest flushes on duplicates.
 	for _, rawResp := range batchResp {
 		var jrResp common.JsonRpcResponse
 		err := sonic.Unmarshal(rawResp, &jrResp)
@@ -354,27 +583,110 @@ func (c *GenericHttpJsonRpcClient) processBatchResponse(requests map[interface{}
 			continue
 		}
 
-		if req, ok := requests[jrResp.ID]; ok {
+		key := batchIdKey(jrResp.ID)
+		if req, ok := requests[key]; ok {
 			nr := common.NewNormalizedResponse().WithRequest(req.request).WithBody(rawResp)
-			err := c.normalizeJsonRpcError(resp, nr)
-			if err != nil {
-				req.err <- err
-			} else {
-				req.response <- nr
-			}
-			delete(requests, jrResp.ID)
+			deliver(req, nr, c.normalizeJsonRpcError(resp, nr))
+			delete(requests, key)
 		}
 	}
 
-	// Handle any remaining requests that didn't receive a response which is very unexpected
-	// it means the upstream response did not include any item with request.ID for one or more the requests
-	for _, req := range requests {
-		jrReq, err := req.request.JsonRpcRequest()
-		if err != nil {
-			req.err <- fmt.Errorf("unexpected no response received for request: %w", err)
-		} else {
-			req.err <- fmt.Errorf("unexpected no response received for request %s", jrReq.ID)
+	// Requests without a corresponding item get a server-side error so they can be retried on other upstreams
+	for key, req := range requests {
+		deliver(req, nil, common.NewErrEndpointServerSideException(
+			fmt.Errorf("unexpected no response received for request %s in upstream batch response", key),
+			map[string]interface{}{
+				"statusCode": resp.StatusCode,
+				"batchItems": len(batchResp),
+			},
+		))
+	}
+}
+
+// shrinkBatchAndResend lowers the batch size of this upstream when it rejects a batch for being too large, either to
+// the limit mentioned in its error or to half of the rejected batch, and sends the rejected requests again in smaller
+// batches. Sizes only ever go down so this always ends, a single request is never batched.
+func (c *GenericHttpJsonRpcClient) shrinkBatchAndResend(requests map[string]*batchRequest, limit int, deadline *time.Time) {
+	size := len(requests) / 2
+	if limit > 0 && limit < len(requests) {
+		size = limit
+	}
+	if size < 1 {
+		size = 1
+	}
+
+	c.batchMu.Lock()
+	if size < c.batchMaxSize {
+		c.logger.Warn().Int("previousBatchMaxSize", c.batchMaxSize).Int("batchMaxSize", size).Int("rejectedBatchSize", len(requests)).
+			Msgf("upstream rejected batch as too large, lowering its max batch size")
+		c.batchMaxSize = size
+	}
+	c.batchMu.Unlock()
+
+	var wg sync.WaitGroup
+	send := func(chunk map[string]*batchRequest) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.sendBatch(chunk, deadline)
+		}()
+	}
+	chunk := make(map[string]*batchRequest, size)
+	for key, req := range requests {
+		chunk[key] = req
+		if len(chunk) >= size {
+			send(chunk)
+			chunk = make(map[string]*batchRequest, size)
+		}
+	}
+	if len(chunk) > 0 {
+		send(chunk)
+	}
+	wg.Wait()
+}
+
+// withBatchItemId sets the id of the request on a single json-rpc object returned for a whole batch, which
+// carries at most one of the ids of the batch. Other fields are kept as-is, and so is a body that is not a json-rpc response.
+func withBatchItemId(body []byte, req *common.NormalizedRequest) []byte {
+	jrq, err := req.JsonRpcRequest()
+	if err != nil {
+		return body
+	}
+	var obj map[string]json.RawMessage
+	if err := sonic.Unmarshal(body, &obj); err != nil {
+		return body
+	}
+	if _, ok := obj["result"]; !ok {
+		if _, ok := obj["error"]; !ok {
+			return body
+		}
+	}
+	id, err := sonic.Marshal(jrq.ID)
+	if err != nil {
+		return body
+	}
+	obj["id"] = id
+	rewritten, err := sonic.Marshal(obj)
+	if err != nil {
+		return body
+	}
+	return rewritten
+}
+
+// batchIdKey normalizes a json-rpc ID so that e.g. 1, 1.0 and "1" sent back by upstreams match the same request.
+func batchIdKey(id interface{}) string {
+	switch v := id.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		if v == math.Trunc(v) {
+			return strconv.FormatInt(int64(v), 10)
 		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
 	}
 }
 
@@ -391,12 +703,12 @@ func (c *GenericHttpJsonRpcClient) sendSingleRequest(ctx context.Context, req *c
 	}
 
 	req.RLock()
-	requestBody, err := sonic.Marshal(common.JsonRpcRequest{
-		JSONRPC: jrReq.JSONRPC,
-		Method:  jrReq.Method,
-		Params:  jrReq.Params,
-		ID:      jrReq.ID,
-	})
+	outReq, err := common.NewJsonRpcRequest(jrReq.ID, jrReq.Method, c.outboundParams(jrReq.Method, jrReq.Params))
+	if err != nil {
+		req.RUnlock()
+		return nil, err
+	}
+	requestBody, err := sonic.Marshal(outReq)
 	req.RUnlock()
 
 	if err != nil {
@@ -406,8 +718,89 @@ func (c *GenericHttpJsonRpcClient) sendSingleRequest(ctx context.Context, req *c
 	c.logger.Debug().Msgf("sending json rpc POST request to %s: %s", c.Url.Host, requestBody)
 
 	reqStartTime := time.Now()
+	resp, err := c.doHttpRequest(ctx, requestBody)
+	if err != nil && isConnectionClosedError(err) && IsIdempotentMethod(jrReq.Method) {
+		// Keep-alive connections might be closed by the remote side between reuses,
+		// in that case it is safe to retry read-only requests once on a fresh connection.
+		c.logger.Debug().Err(err).Str("method", jrReq.Method).Msgf("connection closed by remote endpoint, retrying once on a fresh connection")
+		resp, err = c.doHttpRequest(ctx, requestBody)
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = common.NewErrEndpointRequestTimeout(time.Since(reqStartTime))
+		}
+		c.recordDebugBundle(jrReq.Method, requestBody, nil, nil, reqStartTime, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var body io.Reader = resp.Body
+	if c.upstream != nil && jrReq.Method == "eth_getLogs" {
+		if maxResults := c.upstream.getLogsMaxResults(); maxResults > 0 {
+			body = newResultCountingReader(resp.Body, jrReq.Method, maxResults)
+		}
+	}
+	respBody, err := readResponseBody(body, resp.ContentLength, c.maxResponseSize(1))
+	if err != nil {
+		c.recordDebugBundle(jrReq.Method, requestBody, resp, respBody, reqStartTime, err)
+		return nil, err
+	}
+
+	if isEmptySuccessBody(resp, respBody) {
+		err := c.emptyBodyError(resp, req, jrReq.Method)
+		c.recordDebugBundle(jrReq.Method, requestBody, resp, respBody, reqStartTime, err)
+		return nil, err
+	}
+
+	nr := common.NewNormalizedResponse().WithRequest(req).WithBody(respBody)
+
+	err = c.normalizeJsonRpcError(resp, nr)
+	c.recordDebugBundle(jrReq.Method, requestBody, resp, respBody, reqStartTime, err)
+
+	return nr, err
+}
+
+// isEmptySuccessBody detects successful responses without a body, which usually come from keep-alive races
+// or load balancer hiccups rather than the node itself, so they are worth retrying on another upstream.
+func isEmptySuccessBody(resp *http.Response, respBody []byte) bool {
+	return resp.StatusCode >= 200 && resp.StatusCode <= 299 && len(bytes.TrimSpace(respBody)) == 0
+}
+
+// addressFirstMethods take an account address as their first param.
+var addressFirstMethods = map[string]bool{
+	"eth_getBalance":          true,
+	"eth_getCode":             true,
+	"eth_getTransactionCount": true,
+	"eth_getStorageAt":        true,
+	"eth_getProof":            true,
+}
+
+// outboundParams returns params as this upstream expects them, the request itself is left untouched
+// since it is shared with other upstreams (and its cache key) that might expect another address format.
+func (c *GenericHttpJsonRpcClient) outboundParams(method string, params []interface{}) []interface{} {
+	if c.addressFormat == "" || !addressFirstMethods[method] || len(params) == 0 {
+		return params
+	}
+	address, ok := params[0].(string)
+	if !ok {
+		return params
+	}
+	formatted, ok := common.FormatEvmAddress(address, c.addressFormat)
+	if !ok || formatted == address {
+		return params
+	}
+	out := make([]interface{}, len(params))
+	copy(out, params)
+	out[0] = formatted
+	return out
+}
+
+func (c *GenericHttpJsonRpcClient) emptyBodyError(resp *http.Response, req *common.NormalizedRequest, category string) error {
+	health.MetricUpstreamEmptyBodyTotal.WithLabelValues(c.upstream.ProjectId, req.NetworkId(), c.upstream.Config().Id, category).Inc()
+	return common.NewErrUpstreamEmptyResponse(c.upstream.Config().Id, resp.StatusCode)
+}
+
+func (c *GenericHttpJsonRpcClient) doHttpRequest(ctx context.Context, requestBody []byte) (*http.Response, error) {
 	httpReq, errReq := http.NewRequestWithContext(ctx, "POST", c.Url.String(), bytes.NewBuffer(requestBody))
-	httpReq.Header.Set("Content-Type", "application/json")
 	if errReq != nil {
 		return nil, &common.BaseError{
 			Code:    "ErrHttp",
@@ -419,23 +812,73 @@ func (c *GenericHttpJsonRpcClient) sendSingleRequest(ctx context.Context, req *c
 			},
 		}
 	}
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			return nil, common.NewErrEndpointRequestTimeout(time.Since(reqStartTime))
+	httpReq.Header.Set("Content-Type", "application/json")
+	// No-op unless tracing is configured to propagate trace context to upstreams
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(httpReq.Header))
+	if c.signer != nil {
+		if err := c.signer.sign(httpReq, requestBody, time.Now()); err != nil {
+			return nil, err
 		}
-		return nil, err
-	}
-	defer resp.Body.Close()
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
 	}
 
-	nr := common.NewNormalizedResponse().WithRequest(req).WithBody(respBody)
+	return c.httpClient.Do(httpReq)
+}
 
-	return nr, c.normalizeJsonRpcError(resp, nr)
+// isConnectionClosedError detects errors caused by the remote side closing a (reused) connection
+// before or while we were writing the request.
+func isConnectionClosedError(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// readOnlyMethods only read data from the node, so they are safe to send more than once or to another upstream.
+// Any other method (e.g. eth_send*, filter methods whose state lives on one node, vendor writes) is never
+// sent twice transparently, an allow-list keeps methods we do not know about on the safe side.
+var readOnlyMethods = map[string]bool{
+	"eth_blockNumber":                         true,
+	"eth_chainId":                             true,
+	"eth_syncing":                             true,
+	"eth_gasPrice":                            true,
+	"eth_maxPriorityFeePerGas":                true,
+	"eth_feeHistory":                          true,
+	"eth_blobBaseFee":                         true,
+	"eth_getBalance":                          true,
+	"eth_getCode":                             true,
+	"eth_getStorageAt":                        true,
+	"eth_getTransactionCount":                 true,
+	"eth_getProof":                            true,
+	"eth_call":                                true,
+	"eth_estimateGas":                         true,
+	"eth_createAccessList":                    true,
+	"eth_getBlockByNumber":                    true,
+	"eth_getBlockByHash":                      true,
+	"eth_getBlockReceipts":                    true,
+	"eth_getBlockTransactionCountByNumber":    true,
+	"eth_getBlockTransactionCountByHash":      true,
+	"eth_getTransactionByHash":                true,
+	"eth_getTransactionByBlockNumberAndIndex": true,
+	"eth_getTransactionByBlockHashAndIndex":   true,
+	"eth_getTransactionReceipt":               true,
+	"eth_getRawTransactionByHash":             true,
+	"eth_getUncleByBlockNumberAndIndex":       true,
+	"eth_getUncleByBlockHashAndIndex":         true,
+	"eth_getUncleCountByBlockNumber":          true,
+	"eth_getUncleCountByBlockHash":            true,
+	"eth_getLogs":                             true,
+	"eth_protocolVersion":                     true,
+	"net_version":                             true,
+	"net_listening":                           true,
+	"net_peerCount":                           true,
+	"web3_clientVersion":                      true,
+	"web3_sha3":                               true,
+}
+
+// IsIdempotentMethod tells whether a method only reads data, so it is safe to send it more than once.
+// Trace methods and debug tracing replay transactions without broadcasting them, so they are reads as well.
+func IsIdempotentMethod(method string) bool {
+	return readOnlyMethods[method] || strings.HasPrefix(method, "trace_") || strings.HasPrefix(method, "debug_trace")
 }
 
 func (c *GenericHttpJsonRpcClient) normalizeJsonRpcError(r *http.Response, nr *common.NormalizedResponse) error {
@@ -457,11 +900,22 @@ func (c *GenericHttpJsonRpcClient) normalizeJsonRpcError(r *http.Response, nr *c
 		return e
 	}
 
+	// Failing over is safer than guessing which of the two is authoritative
+	if jr.HasResultAndError() {
+		return common.NewErrUpstreamInvalidResponse(c.upstream.Config().Id, "both result and error are set")
+	}
+
 	if jr.Error == nil {
 		return nil
 	}
 
+	// Captured before the remap so that the code the upstream actually responded with is kept
+	originalCode := jr.Error.Code
+	remapped := c.remapJsonRpcErrorCode(jr.Error)
 	if e := extractJsonRpcError(r, nr, jr); e != nil {
+		if remapped {
+			preserveOriginalErrorCode(e, originalCode)
+		}
 		return e
 	}
 
@@ -481,6 +935,38 @@ func (c *GenericHttpJsonRpcClient) normalizeJsonRpcError(r *http.Response, nr *c
 	return e
 }
 
+// remapJsonRpcErrorCode rewrites the error code according to upstream's errorCodeRemap table,
+// returning whether the code was changed.
+func (c *GenericHttpJsonRpcClient) remapJsonRpcErrorCode(err *common.ErrJsonRpcExceptionExternal) bool {
+	cfg := c.upstream.Config()
+	if cfg.JsonRpc == nil || len(cfg.JsonRpc.ErrorCodeRemap) == 0 {
+		return false
+	}
+	canonical, ok := cfg.JsonRpc.ErrorCodeRemap[err.Code]
+	if !ok || canonical == err.Code {
+		return false
+	}
+	err.Code = canonical
+	return true
+}
+
+// preserveOriginalErrorCode replaces the remapped code the error was built with by the one the upstream
+// responded with, and exposes it to clients under "originalCode" of error data.
+func preserveOriginalErrorCode(err error, originalCode int) {
+	jre := &common.ErrJsonRpcExceptionInternal{}
+	if !errors.As(err, &jre) {
+		return
+	}
+	jre.Details["originalCode"] = originalCode
+	data := map[string]interface{}{
+		"originalCode": originalCode,
+	}
+	if prev, ok := jre.Details["data"]; ok && prev != nil {
+		data["data"] = prev
+	}
+	jre.Details["data"] = data
+}
+
 func extractJsonRpcError(r *http.Response, nr *common.NormalizedResponse, jr *common.JsonRpcResponse) error {
 	if jr != nil && jr.Error != nil {
 		err := jr.Error
@@ -489,6 +975,22 @@ func extractJsonRpcError(r *http.Response, nr *common.NormalizedResponse, jr *co
 		details["statusCode"] = r.StatusCode
 		details["headers"] = util.ExtractUsefulHeaders(r.Header)
 
+		vendorName := upstreamVendorName(nr)
+
+		// Rate limits are checked before vendor-specific handling, as some providers respond with
+		// generic error codes (e.g. -32005) that would otherwise be treated as server-side exceptions.
+		if r.StatusCode != 429 && common.IsVendorRateLimitError(vendorName, err.Code, err.Message) {
+			return common.NewErrEndpointCapacityExceeded(
+				common.NewErrJsonRpcExceptionInternal(
+					err.Code,
+					common.JsonRpcErrorCapacityExceeded,
+					err.Message,
+					nil,
+					details,
+				),
+			)
+		}
+
 		if ver := getVendorSpecificErrorIfAny(r, nr, jr, details); ver != nil {
 			return ver
 		}
@@ -546,10 +1048,7 @@ func extractJsonRpcError(r *http.Response, nr *common.NormalizedResponse, jr *co
 					details,
 				),
 			)
-		} else if r.StatusCode == 429 ||
-			strings.Contains(err.Message, "has exceeded") ||
-			strings.Contains(err.Message, "Exceeded the quota") ||
-			strings.Contains(err.Message, "under too much load") {
+		} else if r.StatusCode == 429 || common.IsRateLimitError(vendorName, err.Code, err.Message) {
 
 			return common.NewErrEndpointCapacityExceeded(
 				common.NewErrJsonRpcExceptionInternal(
@@ -736,6 +1235,21 @@ func extractJsonRpcError(r *http.Response, nr *common.NormalizedResponse, jr *co
 	return nil
 }
 
+func upstreamVendorName(nr *common.NormalizedResponse) string {
+	req := nr.Request()
+	if req == nil {
+		return ""
+	}
+	ups := req.LastUpstream()
+	if ups == nil {
+		return ""
+	}
+	if vn := ups.Vendor(); vn != nil {
+		return vn.Name()
+	}
+	return ups.Config().VendorName
+}
+
 func getVendorSpecificErrorIfAny(
 	rp *http.Response,
 	nr *common.NormalizedResponse,
@@ -0,0 +1,123 @@
+package upstream
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/erpc/erpc/common"
+)
+
+const (
+	// Initial buffer size when the upstream does not announce a content length (e.g. chunked responses)
+	defaultResponseBufferSize = 32 * 1024
+	// Announced content lengths above this are not trusted for pre-allocating the buffer
+	maxPreallocatedResponseSize = 16 * 1024 * 1024
+)
+
+// readResponseBody reads a response body into a buffer sized from the content length when known, and
+// aborts as soon as more than maxSize bytes are received (when maxSize > 0) without reading the rest.
+// Bodies within the limit are fully buffered, json-rpc parsing happens lazily on the normalized response.
+func readResponseBody(body io.Reader, contentLength int64, maxSize int64) ([]byte, error) {
+	if maxSize > 0 && contentLength > maxSize {
+		return nil, common.NewErrEndpointResponseTooLarge(maxSize)
+	}
+
+	size := int64(defaultResponseBufferSize)
+	if contentLength > 0 && contentLength <= maxPreallocatedResponseSize {
+		// One extra byte so that reaching EOF does not trigger a grow
+		size = contentLength + 1
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, size))
+
+	if maxSize <= 0 {
+		_, err := buf.ReadFrom(body)
+		return buf.Bytes(), err
+	}
+
+	// Reading one byte past the limit tells an exactly-max-sized body apart from a larger one
+	if _, err := buf.ReadFrom(io.LimitReader(body, maxSize+1)); err != nil {
+		return nil, err
+	}
+	if int64(buf.Len()) > maxSize {
+		return nil, common.NewErrEndpointResponseTooLarge(maxSize)
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *GenericHttpJsonRpcClient) maxResponseSize(items int) int64 {
+	if c.upstream == nil || c.upstream.config.JsonRpc == nil || c.upstream.config.JsonRpc.MaxResponseSize <= 0 {
+		return 0
+	}
+	return c.upstream.config.JsonRpc.MaxResponseSize * int64(max(items, 1))
+}
+
+// resultCountingReader passes a json-rpc response body through while counting the entries of its top-level
+// "result" array, so that a response with too many entries is aborted before it is fully received.
+type resultCountingReader struct {
+	body       io.Reader
+	method     string
+	maxResults int
+
+	count    int
+	depth    int
+	inResult bool
+	inString bool
+	escaped  bool
+	// Last string seen directly within the top-level object, i.e. the key right before a "result" array
+	lastKey       []byte
+	lastKeyCapped bool
+}
+
+func newResultCountingReader(body io.Reader, method string, maxResults int) *resultCountingReader {
+	return &resultCountingReader{
+		body:       body,
+		method:     method,
+		maxResults: maxResults,
+		lastKey:    make([]byte, 0, 8),
+	}
+}
+
+func (r *resultCountingReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	for _, b := range p[:n] {
+		if r.inString {
+			switch {
+			case r.escaped:
+				r.escaped = false
+			case b == '\\':
+				r.escaped = true
+			case b == '"':
+				r.inString = false
+			case r.depth == 1 && len(r.lastKey) < cap(r.lastKey):
+				r.lastKey = append(r.lastKey, b)
+			case r.depth == 1:
+				r.lastKeyCapped = true
+			}
+			continue
+		}
+		switch b {
+		case '"':
+			r.inString = true
+			if r.depth == 1 {
+				r.lastKey = r.lastKey[:0]
+				r.lastKeyCapped = false
+			}
+		case '{', '[':
+			r.depth++
+			if r.depth == 2 && b == '[' && !r.lastKeyCapped && string(r.lastKey) == "result" {
+				r.inResult = true
+			} else if r.depth == 3 && r.inResult {
+				r.count++
+				if r.count > r.maxResults {
+					return 0, common.NewErrResultSetTooLarge(r.method, r.maxResults)
+				}
+			}
+		case '}', ']':
+			r.depth--
+			if r.depth < 2 {
+				r.inResult = false
+			}
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,143 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/erpc/erpc/common"
+)
+
+var _ HttpJsonRpcClient = (*MockHttpJsonRpcClient)(nil)
+
+// MockHttpJsonRpcResponse is a canned outcome returned by MockHttpJsonRpcClient.
+// When Error is set it is returned instead of Result, and Delay is applied in both cases.
+type MockHttpJsonRpcResponse struct {
+	Result interface{}
+	Error  error
+	Delay  time.Duration
+}
+
+// MockHttpJsonRpcClient is a deterministic HttpJsonRpcClient for tests and dry-runs.
+// Responses are programmed per request cache hash or per method (cache hash takes precedence),
+// and all calls are counted so routing and caching behavior can be asserted.
+type MockHttpJsonRpcClient struct {
+	networkIds map[string]bool
+	responses  map[string]*MockHttpJsonRpcResponse
+	calls      map[string]int
+	mu         sync.Mutex
+}
+
+func NewMockHttpJsonRpcClient(networkIds ...string) *MockHttpJsonRpcClient {
+	c := &MockHttpJsonRpcClient{
+		networkIds: make(map[string]bool),
+		responses:  make(map[string]*MockHttpJsonRpcResponse),
+		calls:      make(map[string]int),
+	}
+	for _, n := range networkIds {
+		c.networkIds[n] = true
+	}
+	return c
+}
+
+// On programs the response for a method name (e.g. "eth_chainId") or a request cache hash.
+func (c *MockHttpJsonRpcClient) On(key string, resp *MockHttpJsonRpcResponse) *MockHttpJsonRpcClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses[key] = resp
+	return c
+}
+
+// OnResult is a shorthand to program a successful result for a method or request cache hash.
+func (c *MockHttpJsonRpcClient) OnResult(key string, result interface{}) *MockHttpJsonRpcClient {
+	return c.On(key, &MockHttpJsonRpcResponse{Result: result})
+}
+
+// OnError is a shorthand to program an error for a method or request cache hash.
+func (c *MockHttpJsonRpcClient) OnError(key string, err error) *MockHttpJsonRpcClient {
+	return c.On(key, &MockHttpJsonRpcResponse{Error: err})
+}
+
+// Calls returns how many requests were received for a method.
+func (c *MockHttpJsonRpcClient) Calls(method string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[method]
+}
+
+// TotalCalls returns how many requests were received across all methods.
+func (c *MockHttpJsonRpcClient) TotalCalls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	total := 0
+	for _, n := range c.calls {
+		total += n
+	}
+	return total
+}
+
+func (c *MockHttpJsonRpcClient) GetType() ClientType {
+	return ClientTypeHttpJsonRpc
+}
+
+func (c *MockHttpJsonRpcClient) SupportsNetwork(networkId string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.networkIds[networkId], nil
+}
+
+// HealthCheck sends an eth_chainId request, so health can be simulated by programming that method.
+func (c *MockHttpJsonRpcClient) HealthCheck(ctx context.Context) error {
+	return sendHealthCheck(ctx, nil, c.SendRequest)
+}
+
+func (c *MockHttpJsonRpcClient) Close() error {
+	return nil
+}
+
+func (c *MockHttpJsonRpcClient) SendRequest(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error) {
+	jrReq, err := req.JsonRpcRequest()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := jrReq.CacheHash()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.calls[jrReq.Method]++
+	resp, ok := c.responses[hash]
+	if !ok {
+		resp, ok = c.responses[jrReq.Method]
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, common.NewErrEndpointUnsupported(
+			fmt.Errorf("no mock response programmed for method %s", jrReq.Method),
+		)
+	}
+
+	if resp.Delay > 0 {
+		select {
+		case <-time.After(resp.Delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	jrr, err := common.NewJsonRpcResponse(jrReq.ID, resp.Result, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return common.NewNormalizedResponse().
+		WithRequest(req).
+		WithJsonRpcResponse(jrr), nil
+}
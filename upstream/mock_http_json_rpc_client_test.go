@@ -0,0 +1,100 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockHttpJsonRpcClient(t *testing.T) {
+	t.Run("ReturnsProgrammedResponsesByMethod", func(t *testing.T) {
+		client := NewMockHttpJsonRpcClient("evm:123").
+			OnResult("eth_chainId", "0x7b")
+
+		resp, err := client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":7,"method":"eth_chainId","params":[]}`)))
+		assert.NoError(t, err)
+		jrr, err := resp.JsonRpcResponse()
+		assert.NoError(t, err)
+		assert.Equal(t, `"0x7b"`, string(jrr.Result))
+		assert.EqualValues(t, 7, jrr.ID)
+
+		supported, err := client.SupportsNetwork("evm:123")
+		assert.NoError(t, err)
+		assert.True(t, supported)
+		supported, err = client.SupportsNetwork("evm:1")
+		assert.NoError(t, err)
+		assert.False(t, supported)
+	})
+
+	t.Run("CacheHashTakesPrecedenceOverMethod", func(t *testing.T) {
+		specific := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x111","0x1"]}`))
+		jrq, err := specific.JsonRpcRequest()
+		assert.NoError(t, err)
+		hash, err := jrq.CacheHash()
+		assert.NoError(t, err)
+
+		client := NewMockHttpJsonRpcClient().
+			OnResult("eth_getBalance", "0x0").
+			OnResult(hash, "0x100")
+
+		resp, err := client.SendRequest(context.Background(), specific)
+		assert.NoError(t, err)
+		jrr, _ := resp.JsonRpcResponse()
+		assert.Equal(t, `"0x100"`, string(jrr.Result))
+
+		resp, err = client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":2,"method":"eth_getBalance","params":["0x222","0x1"]}`)))
+		assert.NoError(t, err)
+		jrr, _ = resp.JsonRpcResponse()
+		assert.Equal(t, `"0x0"`, string(jrr.Result))
+	})
+
+	t.Run("CountsCalls", func(t *testing.T) {
+		client := NewMockHttpJsonRpcClient().
+			OnResult("eth_chainId", "0x7b").
+			OnResult("eth_blockNumber", "0x10")
+
+		for i := 0; i < 3; i++ {
+			_, _ = client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(`{"method":"eth_chainId","params":[]}`)))
+		}
+		_, _ = client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(`{"method":"eth_blockNumber","params":[]}`)))
+		_, _ = client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(`{"method":"eth_unknown","params":[]}`)))
+
+		assert.Equal(t, 3, client.Calls("eth_chainId"))
+		assert.Equal(t, 1, client.Calls("eth_blockNumber"))
+		assert.Equal(t, 1, client.Calls("eth_unknown"))
+		assert.Equal(t, 5, client.TotalCalls())
+	})
+
+	t.Run("ReturnsInducedErrors", func(t *testing.T) {
+		induced := common.NewErrEndpointServerSideException(errors.New("boom"), nil)
+		client := NewMockHttpJsonRpcClient().OnError("eth_call", induced)
+
+		resp, err := client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(`{"method":"eth_call","params":[]}`)))
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, induced)
+
+		_, err = client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(`{"method":"eth_unknown","params":[]}`)))
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeEndpointUnsupported))
+	})
+
+	t.Run("AppliesDelayAndRespectsContext", func(t *testing.T) {
+		client := NewMockHttpJsonRpcClient().On("eth_getLogs", &MockHttpJsonRpcResponse{
+			Result: []interface{}{},
+			Delay:  200 * time.Millisecond,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := client.SendRequest(ctx, common.NewNormalizedRequest([]byte(`{"method":"eth_getLogs","params":[]}`)))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		start := time.Now()
+		_, err = client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(`{"method":"eth_getLogs","params":[]}`)))
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	})
+}
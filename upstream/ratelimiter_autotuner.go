@@ -3,6 +3,7 @@ package upstream
 import (
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -20,6 +21,24 @@ type RateLimitAutoTuner struct {
 	minBudget          int
 	maxBudget          int
 	mu                 sync.RWMutex
+
+	// Budgets of rules before the first adjustment, to tell whether a rule is currently throttled down
+	baselines map[string]uint
+	// Permits granted by each rule (*RateLimitRule -> *permitUsage) in its current period, counted without
+	// taking mu since every request passing the rate limiter records one
+	usage sync.Map
+}
+
+type permitUsage struct {
+	period time.Duration
+	window atomic.Pointer[permitWindow]
+}
+
+// permitWindow is replaced as a whole when a new period starts, so that permits counted in the new period
+// can never be reset by another caller.
+type permitWindow struct {
+	start int64
+	count atomic.Uint64
 }
 
 type ErrorCounter struct {
@@ -48,7 +67,70 @@ func NewRateLimitAutoTuner(
 		decreaseFactor:     decreaseFactor,
 		minBudget:          minBudget,
 		maxBudget:          maxBudget,
+		baselines:          make(map[string]uint),
+	}
+}
+
+// RecordPermit counts a permit granted by a rule, so that Headroom knows how close the rule is to its limit.
+func (arl *RateLimitAutoTuner) RecordPermit(rule *RateLimitRule) {
+	v, ok := arl.usage.Load(rule)
+	if !ok {
+		v, _ = arl.usage.LoadOrStore(rule, &permitUsage{period: rulePeriod(rule)})
+	}
+	u := v.(*permitUsage)
+
+	now := time.Now().UnixNano()
+	for {
+		w := u.window.Load()
+		if w != nil && now-w.start < int64(u.period) {
+			w.count.Add(1)
+			return
+		}
+		next := &permitWindow{start: now}
+		next.count.Store(1)
+		if u.window.CompareAndSwap(w, next) {
+			return
+		}
+	}
+}
+
+// Headroom returns between 0 (no budget left) and 1 (untouched budget) for the most constrained rule of a method.
+// It accounts both for permits used in the current period, and for budgets the tuner decreased below their
+// baseline after remote rate limits, so that traffic moves away before requests start being rejected.
+func (arl *RateLimitAutoTuner) Headroom(method string) float64 {
+	rules := arl.budget.GetRulesByMethod(method)
+
+	arl.mu.RLock()
+	defer arl.mu.RUnlock()
+
+	headroom := 1.0
+	for _, rule := range rules {
+		maxCount := rule.Config.MaxCount
+		if maxCount == 0 {
+			return 0
+		}
+
+		h := 1.0
+		if v, ok := arl.usage.Load(rule); ok {
+			u := v.(*permitUsage)
+			if w := u.window.Load(); w != nil && time.Now().UnixNano()-w.start < int64(u.period) {
+				h = math.Max(0, 1-float64(w.count.Load())/float64(maxCount))
+			}
+		}
+		if baseline, ok := arl.baselines[rule.Config.Method]; ok && baseline > 0 && maxCount < baseline {
+			h *= float64(maxCount) / float64(baseline)
+		}
+		headroom = math.Min(headroom, h)
+	}
+
+	return headroom
+}
+
+func rulePeriod(rule *RateLimitRule) time.Duration {
+	if d, err := time.ParseDuration(rule.Config.Period); err == nil && d > 0 {
+		return d
 	}
+	return time.Second
 }
 
 func (arl *RateLimitAutoTuner) RecordSuccess(method string) {
@@ -83,6 +165,9 @@ func (arl *RateLimitAutoTuner) adjustBudget(method string) {
 		rules := arl.budget.GetRulesByMethod(method)
 		for _, rule := range rules {
 			currentMax := rule.Config.MaxCount
+			if _, ok := arl.baselines[rule.Config.Method]; !ok {
+				arl.baselines[rule.Config.Method] = currentMax
+			}
 			erc := arl.errorCounts[method].errorCount
 			ttc := arl.errorCounts[method].totalCount
 
@@ -2,6 +2,7 @@ package upstream
 
 import (
 	"sync"
+	"time"
 
 	"github.com/erpc/erpc/common"
 	"github.com/failsafe-go/failsafe-go/ratelimiter"
@@ -17,8 +18,19 @@ type RateLimiterBudget struct {
 }
 
 type RateLimitRule struct {
-	Config  *common.RateLimitRuleConfig
-	Limiter ratelimiter.RateLimiter[interface{}]
+	Config    *common.RateLimitRuleConfig
+	Limiter   ratelimiter.RateLimiter[interface{}]
+	startedAt time.Time
+}
+
+// ResetIn tells how long until the rule's permits are replenished, bursty limiters refill
+// all permits at the start of each period counted from when they were created.
+func (r *RateLimitRule) ResetIn() time.Duration {
+	period, err := time.ParseDuration(r.Config.Period)
+	if err != nil || period <= 0 {
+		return 0
+	}
+	return period - time.Since(r.startedAt)%period
 }
 
 func (b *RateLimiterBudget) GetRulesByMethod(method string) []*RateLimitRule {
@@ -54,6 +66,7 @@ func (b *RateLimiterBudget) AdjustBudget(rule *RateLimitRule, newMaxCount uint)
 	}
 	rule.Config = newCfg
 	rule.Limiter = newLimiter
+	rule.startedAt = time.Now()
 
 	return nil
 }
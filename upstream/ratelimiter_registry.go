@@ -52,8 +52,9 @@ func (r *RateLimitersRegistry) bootstrap() error {
 
 			budget.rulesMu.Lock()
 			budget.Rules = append(budget.Rules, &RateLimitRule{
-				Config:  rule,
-				Limiter: limiter,
+				Config:    rule,
+				Limiter:   limiter,
+				startedAt: time.Now(),
 			})
 			budget.rulesMu.Unlock()
 		}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/erpc/erpc/common"
+	"github.com/erpc/erpc/data"
 	"github.com/erpc/erpc/health"
 	"github.com/erpc/erpc/vendors"
 	"github.com/rs/zerolog"
@@ -24,6 +25,10 @@ type UpstreamsRegistry struct {
 	vendorsRegistry      *vendors.VendorsRegistry
 	rateLimitersRegistry *RateLimitersRegistry
 	upsCfg               []*common.UpstreamConfig
+	localRegion          string
+	groupCfgs            []*common.UpstreamGroupConfig
+	groups               map[string]*UpstreamGroup
+	capabilitiesStore    data.Connector
 
 	allUpstreams []*Upstream
 	upstreamsMu  *sync.RWMutex
@@ -31,6 +36,42 @@ type UpstreamsRegistry struct {
 	sortedUpstreams map[string]map[string][]*Upstream
 	// map of upstream -> network (or *) -> method (or *) => score
 	upstreamScores map[string]map[string]map[string]float64
+	// map of "upstream/network/method" => smoothed success rate, kept across metrics windows
+	methodReliability map[string]*methodReliability
+}
+
+type methodReliability struct {
+	// Smoothed success rate between 0 and 1
+	rate float64
+	// Counters of the current metrics window that were already folded into rate
+	foldedRequests float64
+	foldedErrors   float64
+}
+
+const (
+	// Weight of the latest metrics window in the smoothed per-method success rate of an upstream
+	methodReliabilityAlpha = 0.3
+	// Fewer new requests than this are too noisy to learn from, they are folded once more requests arrive
+	methodReliabilityMinRequests = 10
+)
+
+type UpstreamSnapshot struct {
+	Id              string   `json:"id"`
+	Networks        []string `json:"networks"`
+	CircuitBreaker  string   `json:"circuitBreaker"`
+	RequestsTotal   float64  `json:"requestsTotal"`
+	ErrorsTotal     float64  `json:"errorsTotal"`
+	ErrorRate       float64  `json:"errorRate"`
+	P90LatencySecs  float64  `json:"p90LatencySecs"`
+	BlockHeadLag    float64  `json:"blockHeadLag"`
+	FinalizationLag float64  `json:"finalizationLag"`
+	Quarantined     bool     `json:"quarantined"`
+}
+
+// NetworkReadiness counts upstreams of a network, and how many of them can currently serve requests.
+type NetworkReadiness struct {
+	Healthy int `json:"healthy"`
+	Total   int `json:"total"`
 }
 
 type UpstreamsHealth struct {
@@ -59,6 +100,7 @@ func NewUpstreamsRegistry(
 		upsCfg:               upsCfg,
 		sortedUpstreams:      make(map[string]map[string][]*Upstream),
 		upstreamScores:       make(map[string]map[string]map[string]float64),
+		methodReliability:    make(map[string]*methodReliability),
 		upstreamsMu:          &sync.RWMutex{},
 	}
 }
@@ -68,6 +110,9 @@ func (u *UpstreamsRegistry) Bootstrap(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	for _, ups := range u.allUpstreams {
+		ups.StartCapabilityProbes(ctx, u.capabilitiesStore)
+	}
 	return u.scheduleScoreCalculationTimers(ctx)
 }
 
@@ -98,6 +143,7 @@ func (u *UpstreamsRegistry) PrepareUpstreamsForNetwork(networkId string) error {
 	if len(upstreams) == 0 {
 		return common.NewErrNoUpstreamsFound(u.prjId, networkId)
 	}
+	u.preferLocalRegion(upstreams)
 
 	if _, ok := u.sortedUpstreams[networkId]; !ok {
 		u.sortedUpstreams[networkId] = make(map[string][]*Upstream)
@@ -140,7 +186,43 @@ func (u *UpstreamsRegistry) PrepareUpstreamsForNetwork(networkId string) error {
 	return nil
 }
 
+// GetSortedUpstreams returns upstreams of a network ordered by their score for the method, quarantined ones excluded.
 func (u *UpstreamsRegistry) GetSortedUpstreams(networkId, method string) ([]*Upstream, error) {
+	upsList, err := u.getSortedUpstreams(networkId, method)
+	if err != nil {
+		return nil, err
+	}
+	upsList = excludeQuarantined(upsList)
+	if len(upsList) == 0 {
+		return nil, common.NewErrNoUpstreamsFound(u.prjId, networkId)
+	}
+	return applySlowStart(deprioritizeSaturatedGroups(upsList, method)), nil
+}
+
+// applySlowStart moves upstreams that are ramping up after their circuit breaker closed to the end of the list
+// for a share of requests that shrinks as they ramp up, so they get a growing share of traffic instead of all of it.
+func applySlowStart(upsList []*Upstream) []*Upstream {
+	var kept, demoted []*Upstream
+	for i, ups := range upsList {
+		if share := ups.SlowStartShare(); share < 1 && rand.Float64() >= share {
+			if kept == nil {
+				kept = make([]*Upstream, i, len(upsList))
+				copy(kept, upsList[:i])
+			}
+			demoted = append(demoted, ups)
+			continue
+		}
+		if kept != nil {
+			kept = append(kept, ups)
+		}
+	}
+	if kept == nil {
+		return upsList
+	}
+	return append(kept, demoted...)
+}
+
+func (u *UpstreamsRegistry) getSortedUpstreams(networkId, method string) ([]*Upstream, error) {
 	u.upstreamsMu.RLock()
 	upsList := u.sortedUpstreams[networkId][method]
 	u.upstreamsMu.RUnlock()
@@ -189,6 +271,160 @@ func (u *UpstreamsRegistry) GetSortedUpstreams(networkId, method string) ([]*Ups
 	return upsList, nil
 }
 
+// excludeQuarantined returns the same list when nothing is quarantined to avoid allocating on every request.
+func excludeQuarantined(upsList []*Upstream) []*Upstream {
+	var filtered []*Upstream
+	for i, ups := range upsList {
+		if !ups.IsQuarantined() {
+			if filtered != nil {
+				filtered = append(filtered, ups)
+			}
+			continue
+		}
+		if filtered == nil {
+			filtered = make([]*Upstream, i, len(upsList))
+			copy(filtered, upsList[:i])
+		}
+	}
+	if filtered == nil {
+		return upsList
+	}
+	return filtered
+}
+
+// QuarantineUpstream takes an upstream out of rotation until it is unquarantined, calling it again has no effect.
+func (u *UpstreamsRegistry) QuarantineUpstream(id string) error {
+	return u.setQuarantined(id, true)
+}
+
+// UnquarantineUpstream puts a quarantined upstream back into rotation, calling it again has no effect.
+func (u *UpstreamsRegistry) UnquarantineUpstream(id string) error {
+	return u.setQuarantined(id, false)
+}
+
+func (u *UpstreamsRegistry) setQuarantined(id string, quarantined bool) error {
+	ups, ok := u.GetUpstream(id)
+	if !ok {
+		return common.NewErrUpstreamNotFound(u.prjId, id)
+	}
+
+	if ups.quarantined.CompareAndSwap(!quarantined, quarantined) {
+		if quarantined {
+			u.logger.Warn().Str("upstreamId", id).Msg("upstream quarantined, it will not receive any traffic until unquarantined")
+			health.MetricUpstreamQuarantined.WithLabelValues(u.prjId, id).Set(1)
+		} else {
+			u.logger.Info().Str("upstreamId", id).Msg("upstream unquarantined and back in rotation")
+			health.MetricUpstreamQuarantined.WithLabelValues(u.prjId, id).Set(0)
+		}
+	}
+
+	return nil
+}
+
+// CloseClients closes the clients of all upstreams, it is meant for shutdown once no more requests are forwarded.
+func (u *UpstreamsRegistry) CloseClients() error {
+	return u.clientRegistry.Close()
+}
+
+func (u *UpstreamsRegistry) GetMetricsTracker() *health.Tracker {
+	return u.metricsTracker
+}
+
+func (u *UpstreamsRegistry) GetUpstream(id string) (*Upstream, bool) {
+	u.upstreamsMu.RLock()
+	defer u.upstreamsMu.RUnlock()
+
+	for _, ups := range u.allUpstreams {
+		if ups.Config().Id == id {
+			return ups, true
+		}
+	}
+
+	return nil, false
+}
+
+// GetUpstreamsForNetwork returns a copy of upstreams that support the network,
+// using the already prepared list when available so order reflects current scores.
+func (u *UpstreamsRegistry) GetUpstreamsForNetwork(networkId string) []*Upstream {
+	u.upstreamsMu.RLock()
+	defer u.upstreamsMu.RUnlock()
+
+	if upsList, ok := u.sortedUpstreams[networkId]["*"]; ok {
+		cpUps := make([]*Upstream, len(upsList))
+		copy(cpUps, upsList)
+		return cpUps
+	}
+
+	var upstreams []*Upstream
+	for _, ups := range u.allUpstreams {
+		if s, e := ups.SupportsNetwork(networkId); e == nil && s {
+			upstreams = append(upstreams, ups)
+		}
+	}
+
+	return upstreams
+}
+
+// GetUpstreamsSnapshot returns a point-in-time view of health, circuit breaker and latency stats of each upstream.
+func (u *UpstreamsRegistry) GetUpstreamsSnapshot() []*UpstreamSnapshot {
+	u.upstreamsMu.RLock()
+	upsList := make([]*Upstream, len(u.allUpstreams))
+	copy(upsList, u.allUpstreams)
+	u.upstreamsMu.RUnlock()
+
+	snapshots := make([]*UpstreamSnapshot, 0, len(upsList))
+	for _, ups := range upsList {
+		upsId := ups.Config().Id
+		snp := &UpstreamSnapshot{
+			Id:             upsId,
+			Networks:       ups.ActiveNetworks(),
+			CircuitBreaker: ups.CircuitBreakerState(),
+			Quarantined:    ups.IsQuarantined(),
+		}
+
+		metrics := u.metricsTracker.GetUpstreamMethodMetrics(upsId, "*", "*")
+		metrics.Mutex.RLock()
+		snp.RequestsTotal = metrics.RequestsTotal
+		snp.ErrorsTotal = metrics.ErrorsTotal
+		if metrics.RequestsTotal > 0 {
+			snp.ErrorRate = metrics.ErrorsTotal / metrics.RequestsTotal
+		}
+		snp.P90LatencySecs = metrics.LatencySecs.P90()
+		snp.BlockHeadLag = metrics.BlockHeadLag
+		snp.FinalizationLag = metrics.FinalizationLag
+		metrics.Mutex.RUnlock()
+
+		snapshots = append(snapshots, snp)
+	}
+
+	return snapshots
+}
+
+// GetNetworksReadiness returns healthy/total upstream counts of each prepared network,
+// where an upstream is healthy when it is not quarantined, its circuit breaker is not open and its last
+// health check did not fail.
+func (u *UpstreamsRegistry) GetNetworksReadiness() map[string]*NetworkReadiness {
+	u.upstreamsMu.RLock()
+	defer u.upstreamsMu.RUnlock()
+
+	readiness := make(map[string]*NetworkReadiness, len(u.sortedUpstreams))
+	for networkId, methods := range u.sortedUpstreams {
+		if networkId == "*" {
+			continue
+		}
+		upsList := methods["*"]
+		nr := &NetworkReadiness{Total: len(upsList)}
+		for _, ups := range upsList {
+			if !ups.IsQuarantined() && ups.CircuitBreakerState() != "open" && !ups.IsHealthCheckFailing() {
+				nr.Healthy++
+			}
+		}
+		readiness[networkId] = nr
+	}
+
+	return readiness
+}
+
 func (u *UpstreamsRegistry) RLockUpstreams() {
 	u.upstreamsMu.RLock()
 }
@@ -213,6 +449,7 @@ func (u *UpstreamsRegistry) sortUpstreams(networkId, method string, upstreams []
 		rand.Shuffle(len(upstreams), func(i, j int) {
 			upstreams[i], upstreams[j] = upstreams[j], upstreams[i]
 		})
+		u.preferLocalRegion(upstreams)
 		return
 	}
 
@@ -234,6 +471,40 @@ func (u *UpstreamsRegistry) sortUpstreams(networkId, method string, upstreams []
 		// If random values are equal, sort by upstream ID for consistency
 		return upstreams[i].Config().Id < upstreams[j].Config().Id
 	})
+	u.preferLocalRegion(upstreams)
+}
+
+// SetLocalRegion makes upstreams of the given region come first regardless of their score, others are only
+// tried when all of them fail (or are skipped). Upstreams without a region are never considered local.
+func (u *UpstreamsRegistry) SetLocalRegion(region string) {
+	u.upstreamsMu.Lock()
+	defer u.upstreamsMu.Unlock()
+	u.localRegion = region
+}
+
+// SetUpstreamGroups defines the groups upstreams can be a member of, it must be called before Bootstrap.
+func (u *UpstreamsRegistry) SetUpstreamGroups(groups []*common.UpstreamGroupConfig) {
+	u.upstreamsMu.Lock()
+	defer u.upstreamsMu.Unlock()
+	u.groupCfgs = groups
+}
+
+// SetCapabilitiesStore persists probed capabilities of upstreams in the given connector, so that they are
+// reused across restarts within their ttl instead of being probed again. It must be called before Bootstrap.
+func (u *UpstreamsRegistry) SetCapabilitiesStore(store data.Connector) {
+	u.upstreamsMu.Lock()
+	defer u.upstreamsMu.Unlock()
+	u.capabilitiesStore = store
+}
+
+// preferLocalRegion moves upstreams of the local region to the front, keeping the order within each group.
+func (u *UpstreamsRegistry) preferLocalRegion(upstreams []*Upstream) {
+	if u.localRegion == "" {
+		return
+	}
+	sort.SliceStable(upstreams, func(i, j int) bool {
+		return upstreams[i].Config().Region == u.localRegion && upstreams[j].Config().Region != u.localRegion
+	})
 }
 
 func (u *UpstreamsRegistry) RefreshUpstreamNetworkMethodScores() error {
@@ -263,11 +534,30 @@ func (u *UpstreamsRegistry) RefreshUpstreamNetworkMethodScores() error {
 }
 
 func (u *UpstreamsRegistry) registerUpstreams() error {
+	u.groups = make(map[string]*UpstreamGroup, len(u.groupCfgs))
+	for _, gCfg := range u.groupCfgs {
+		if _, exists := u.groups[gCfg.Id]; exists {
+			return common.NewErrInvalidConfig(fmt.Sprintf("upstream group %s is defined more than once", gCfg.Id))
+		}
+		group, err := newUpstreamGroup(u.logger, gCfg, u.rateLimitersRegistry)
+		if err != nil {
+			return err
+		}
+		u.groups[gCfg.Id] = group
+	}
+
 	for _, upsCfg := range u.upsCfg {
 		upstream, err := u.NewUpstream(u.prjId, upsCfg, u.logger, u.metricsTracker)
 		if err != nil {
 			return err
 		}
+		if upsCfg.Group != "" {
+			group, ok := u.groups[upsCfg.Group]
+			if !ok {
+				return common.NewErrInvalidConfig(fmt.Sprintf("upstream %s refers to undefined upstream group %s", upsCfg.Id, upsCfg.Group))
+			}
+			upstream.group = group
+		}
 		u.allUpstreams = append(u.allUpstreams, upstream)
 	}
 
@@ -303,7 +593,7 @@ func (u *UpstreamsRegistry) scheduleScoreCalculationTimers(ctx context.Context)
 }
 
 func (u *UpstreamsRegistry) updateScoresAndSort(networkId, method string, upsList []*Upstream) {
-	var p90Latencies, errorRates, totalRequests, throttledRates, blockHeadLags, finalizationLags []float64
+	var p90Latencies, errorRates, totalErrors, totalRequests, throttledRates, blockHeadLags, finalizationLags, costs []float64
 
 	for _, ups := range upsList {
 		metrics := u.metricsTracker.GetUpstreamMethodMetrics(ups.Config().Id, networkId, method)
@@ -315,6 +605,7 @@ func (u *UpstreamsRegistry) updateScoresAndSort(networkId, method string, upsLis
 			Str("upstreamId", ups.Config().Id).
 			Interface("metrics", metrics).
 			Msg("queried upstream metrics")
+		costs = append(costs, ups.MethodCost(method))
 		p90Latencies = append(p90Latencies, metrics.LatencySecs.P90())
 		blockHeadLags = append(blockHeadLags, metrics.BlockHeadLag)
 		finalizationLags = append(finalizationLags, metrics.FinalizationLag)
@@ -322,21 +613,29 @@ func (u *UpstreamsRegistry) updateScoresAndSort(networkId, method string, upsLis
 		if metrics.RequestsTotal > 0 {
 			errorRates = append(errorRates, metrics.ErrorsTotal/metrics.RequestsTotal)
 			throttledRates = append(throttledRates, rateLimitedTotal/metrics.RequestsTotal)
+			totalErrors = append(totalErrors, metrics.ErrorsTotal)
 			totalRequests = append(totalRequests, metrics.RequestsTotal)
 		} else {
 			errorRates = append(errorRates, 0)
 			throttledRates = append(throttledRates, 0)
+			totalErrors = append(totalErrors, 0)
 			totalRequests = append(totalRequests, 0)
 		}
 		metrics.Mutex.RUnlock()
 	}
 
+	reliabilities := make([]float64, len(upsList))
+	for i, ups := range upsList {
+		reliabilities[i] = u.learnMethodReliability(ups.Config().Id, networkId, method, totalErrors[i], totalRequests[i])
+	}
+
 	normP90Latencies := normalizeValues(p90Latencies)
 	normErrorRates := normalizeValues(errorRates)
 	normThrottledRates := normalizeValues(throttledRates)
 	normTotalRequests := normalizeValues(totalRequests)
 	normBlockHeadLags := normalizeValues(blockHeadLags)
 	normFinalizationLags := normalizeValues(finalizationLags)
+	normCosts := normalizeValues(costs)
 	for i, ups := range upsList {
 		score := u.calculateScore(
 			normTotalRequests[i],
@@ -346,6 +645,13 @@ func (u *UpstreamsRegistry) updateScoresAndSort(networkId, method string, upsLis
 			normBlockHeadLags[i],
 			normFinalizationLags[i],
 		)
+		// Cheaper upstreams are preferred when costs of the method differ, weighted below errors and latency
+		// so that an unhealthy cheap upstream does not win over a healthy expensive one.
+		score += expCurve(1-normCosts[i]) * 2
+		// Upstreams close to their (auto-tuned) rate limit budget are deprioritized before they start rejecting requests
+		score += expCurve(ups.RateLimitHeadroom(method)) * 3
+		// Upstreams that kept failing this specific method are deprioritized for it even after the metrics window resets
+		score += expCurve(reliabilities[i]) * 4
 		u.upstreamScores[ups.Config().Id][networkId][method] = score
 		u.logger.Trace().Str("projectId", u.prjId).
 			Str("upstreamId", ups.Config().Id).
@@ -366,6 +672,40 @@ func (u *UpstreamsRegistry) updateScoresAndSort(networkId, method string, upsLis
 	u.logger.Trace().Str("projectId", u.prjId).Str("networkId", networkId).Str("method", method).Str("newSort", newSortStr).Msgf("sorted upstreams")
 }
 
+// learnMethodReliability folds the success rate of requests seen since the last fold into the smoothed success
+// rate of the upstream for this method (1 until enough requests were seen), so that refreshing scores several
+// times within a metrics window counts its requests only once. errors and requests are the counters of the
+// current window, upstreamsMu must be held.
+func (u *UpstreamsRegistry) learnMethodReliability(upsId, networkId, method string, errors, requests float64) float64 {
+	key := upsId + "/" + networkId + "/" + method
+	mr, ok := u.methodReliability[key]
+	if !ok {
+		mr = &methodReliability{rate: 1}
+		u.methodReliability[key] = mr
+	}
+	if requests < mr.foldedRequests {
+		// Metrics window was reset since the last fold
+		mr.foldedRequests, mr.foldedErrors = 0, 0
+	}
+	newRequests := requests - mr.foldedRequests
+	if newRequests >= methodReliabilityMinRequests {
+		errorRate := math.Min(math.Max((errors-mr.foldedErrors)/newRequests, 0), 1)
+		mr.rate = methodReliabilityAlpha*(1-errorRate) + (1-methodReliabilityAlpha)*mr.rate
+		mr.foldedRequests, mr.foldedErrors = requests, errors
+	}
+	return mr.rate
+}
+
+// MethodReliability returns the smoothed success rate (between 0 and 1) the upstream has shown for the method.
+func (u *UpstreamsRegistry) MethodReliability(upsId, networkId, method string) float64 {
+	u.upstreamsMu.RLock()
+	defer u.upstreamsMu.RUnlock()
+	if mr, ok := u.methodReliability[upsId+"/"+networkId+"/"+method]; ok {
+		return mr.rate
+	}
+	return 1
+}
+
 func (u *UpstreamsRegistry) calculateScore(
 	normTotalRequests,
 	normP90Latency,
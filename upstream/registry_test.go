@@ -10,9 +10,11 @@ import (
 	"github.com/erpc/erpc/common"
 	"github.com/erpc/erpc/health"
 	"github.com/erpc/erpc/vendors"
+	promUtil "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUpstreamsRegistry(t *testing.T) {
@@ -442,6 +444,605 @@ func TestCalculateScoreDynamicScenarios(t *testing.T) {
 	}
 }
 
+func TestUpstreamsRegistry_Getters(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	projectID := "test-project"
+	networkID := "evm:123"
+
+	t.Run("GetUpstreamById", func(t *testing.T) {
+		registry, _ := createTestRegistry(projectID, &logger, 10*time.Hour)
+
+		ups, ok := registry.GetUpstream("upstream-b")
+		assert.True(t, ok)
+		assert.Equal(t, "upstream-b", ups.Config().Id)
+
+		ups, ok = registry.GetUpstream("upstream-x")
+		assert.False(t, ok)
+		assert.Nil(t, ups)
+	})
+
+	t.Run("GetUpstreamsForNetwork", func(t *testing.T) {
+		registry, _ := createTestRegistry(projectID, &logger, 10*time.Hour)
+
+		upsList := registry.GetUpstreamsForNetwork(networkID)
+		ids := []string{}
+		for _, ups := range upsList {
+			ids = append(ids, ups.Config().Id)
+		}
+		assert.ElementsMatch(t, []string{"upstream-a", "upstream-b", "upstream-c"}, ids)
+
+		assert.Empty(t, registry.GetUpstreamsForNetwork("evm:999"))
+	})
+
+	t.Run("GetUpstreamsSnapshot", func(t *testing.T) {
+		registry, metricsTracker := createTestRegistry(projectID, &logger, 10*time.Hour)
+
+		simulateRequests(metricsTracker, networkID, "upstream-a", "eth_call", 10, 5)
+		simulateRequestsWithLatency(metricsTracker, networkID, "upstream-b", "eth_call", 5, 0.2)
+
+		snapshots := registry.GetUpstreamsSnapshot()
+		assert.Len(t, snapshots, 3)
+
+		byId := map[string]*UpstreamSnapshot{}
+		for _, snp := range snapshots {
+			byId[snp.Id] = snp
+		}
+		assert.Equal(t, float64(10), byId["upstream-a"].RequestsTotal)
+		assert.Equal(t, float64(5), byId["upstream-a"].ErrorsTotal)
+		assert.Equal(t, 0.5, byId["upstream-a"].ErrorRate)
+		assert.InDelta(t, 0.2, byId["upstream-b"].P90LatencySecs, 0.01)
+		assert.Equal(t, float64(0), byId["upstream-c"].RequestsTotal)
+		assert.Contains(t, byId["upstream-c"].Networks, networkID)
+	})
+}
+
+func TestUpstreamsRegistry_Quarantine(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	projectID := "test-project"
+	networkID := "evm:123"
+
+	sortedIds := func(t *testing.T, registry *UpstreamsRegistry) []string {
+		t.Helper()
+		upsList, err := registry.GetSortedUpstreams(networkID, "eth_call")
+		assert.NoError(t, err)
+		ids := []string{}
+		for _, ups := range upsList {
+			ids = append(ids, ups.Config().Id)
+		}
+		return ids
+	}
+
+	t.Run("QuarantinedUpstreamIsSkippedUntilUnquarantined", func(t *testing.T) {
+		registry, _ := createTestRegistry(projectID, &logger, 10*time.Hour)
+
+		assert.NoError(t, registry.QuarantineUpstream("upstream-b"))
+		assert.ElementsMatch(t, []string{"upstream-a", "upstream-c"}, sortedIds(t, registry))
+
+		ups, _ := registry.GetUpstream("upstream-b")
+		assert.True(t, ups.IsQuarantined())
+		for _, snp := range registry.GetUpstreamsSnapshot() {
+			assert.Equal(t, snp.Id == "upstream-b", snp.Quarantined, snp.Id)
+		}
+		assert.Equal(t, float64(1), promUtil.ToFloat64(health.MetricUpstreamQuarantined.WithLabelValues(projectID, "upstream-b")))
+
+		assert.NoError(t, registry.UnquarantineUpstream("upstream-b"))
+		assert.ElementsMatch(t, []string{"upstream-a", "upstream-b", "upstream-c"}, sortedIds(t, registry))
+		assert.Equal(t, float64(0), promUtil.ToFloat64(health.MetricUpstreamQuarantined.WithLabelValues(projectID, "upstream-b")))
+	})
+
+	t.Run("QuarantineIsIdempotent", func(t *testing.T) {
+		registry, _ := createTestRegistry(projectID, &logger, 10*time.Hour)
+
+		assert.NoError(t, registry.QuarantineUpstream("upstream-a"))
+		assert.NoError(t, registry.QuarantineUpstream("upstream-a"))
+		assert.ElementsMatch(t, []string{"upstream-b", "upstream-c"}, sortedIds(t, registry))
+
+		assert.NoError(t, registry.UnquarantineUpstream("upstream-a"))
+		assert.NoError(t, registry.UnquarantineUpstream("upstream-a"))
+		assert.NoError(t, registry.UnquarantineUpstream("upstream-c"))
+		assert.ElementsMatch(t, []string{"upstream-a", "upstream-b", "upstream-c"}, sortedIds(t, registry))
+	})
+
+	t.Run("AllQuarantinedMeansNoUpstreams", func(t *testing.T) {
+		registry, _ := createTestRegistry(projectID, &logger, 10*time.Hour)
+
+		for _, id := range []string{"upstream-a", "upstream-b", "upstream-c"} {
+			assert.NoError(t, registry.QuarantineUpstream(id))
+		}
+		_, err := registry.GetSortedUpstreams(networkID, "eth_call")
+		assert.True(t, common.HasErrorCode(err, "ErrNoUpstreamsFound"), err)
+	})
+
+	t.Run("UnknownUpstream", func(t *testing.T) {
+		registry, _ := createTestRegistry(projectID, &logger, 10*time.Hour)
+
+		err := registry.QuarantineUpstream("upstream-x")
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeUpstreamNotFound), err)
+		err = registry.UnquarantineUpstream("upstream-x")
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeUpstreamNotFound), err)
+	})
+}
+
+func TestUpstreamsRegistry_SlowStart(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	projectID := "test-project"
+	networkID := "evm:123"
+	method := "eth_call"
+
+	firstShare := func(t *testing.T, registry *UpstreamsRegistry, id string) float64 {
+		t.Helper()
+		first := 0
+		for i := 0; i < 1000; i++ {
+			upsList, err := registry.GetSortedUpstreams(networkID, method)
+			assert.NoError(t, err)
+			assert.Len(t, upsList, 3)
+			if upsList[0].Config().Id == id {
+				first++
+			}
+		}
+		return float64(first) / 1000
+	}
+	recovering := func(registry *UpstreamsRegistry, id string, window, ago time.Duration) *Upstream {
+		ups, _ := registry.GetUpstream(id)
+		ups.slowStartWindow = window
+		ups.recoveredAt.Store(time.Now().Add(-ago).UnixNano())
+		return ups
+	}
+
+	t.Run("RecoveredUpstreamGetsGrowingShareOfTraffic", func(t *testing.T) {
+		registry, metricsTracker := createTestRegistry(projectID, &logger, 10*time.Hour)
+		_, _ = registry.GetSortedUpstreams(networkID, method)
+		simulateRequests(metricsTracker, networkID, "upstream-a", method, 100, 0)
+		simulateRequests(metricsTracker, networkID, "upstream-b", method, 100, 20)
+		simulateRequests(metricsTracker, networkID, "upstream-c", method, 100, 40)
+		registry.RefreshUpstreamNetworkMethodScores()
+		assert.Equal(t, 1.0, firstShare(t, registry, "upstream-a"))
+
+		recovering(registry, "upstream-a", time.Hour, 6*time.Minute)
+		early := firstShare(t, registry, "upstream-a")
+		assert.Less(t, early, 0.25)
+
+		recovering(registry, "upstream-a", time.Hour, 48*time.Minute)
+		late := firstShare(t, registry, "upstream-a")
+		assert.Greater(t, late, 0.65)
+		assert.Greater(t, late, early)
+	})
+
+	t.Run("RampUpEndsAfterWindow", func(t *testing.T) {
+		registry, metricsTracker := createTestRegistry(projectID, &logger, 10*time.Hour)
+		_, _ = registry.GetSortedUpstreams(networkID, method)
+		simulateRequests(metricsTracker, networkID, "upstream-a", method, 100, 0)
+		simulateRequests(metricsTracker, networkID, "upstream-b", method, 100, 20)
+		simulateRequests(metricsTracker, networkID, "upstream-c", method, 100, 40)
+		registry.RefreshUpstreamNetworkMethodScores()
+
+		ups := recovering(registry, "upstream-a", time.Minute, 2*time.Minute)
+		assert.Equal(t, 1.0, firstShare(t, registry, "upstream-a"))
+		assert.Equal(t, int64(0), ups.recoveredAt.Load())
+	})
+
+	t.Run("DisabledWithoutWindow", func(t *testing.T) {
+		registry, _ := createTestRegistry(projectID, &logger, 10*time.Hour)
+		ups, _ := registry.GetUpstream("upstream-a")
+		ups.markRecovered()
+		assert.Equal(t, int64(0), ups.recoveredAt.Load())
+		assert.Equal(t, 1.0, ups.SlowStartShare())
+	})
+}
+
+func TestUpstreamsRegistry_MethodReliability(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	projectID := "test-project"
+	networkID := "evm:123"
+
+	sortedIds := func(t *testing.T, registry *UpstreamsRegistry, method string) []string {
+		t.Helper()
+		upsList, err := registry.GetSortedUpstreams(networkID, method)
+		assert.NoError(t, err)
+		ids := []string{}
+		for _, ups := range upsList {
+			ids = append(ids, ups.Config().Id)
+		}
+		return ids
+	}
+
+	t.Run("UpstreamFailingOneMethodIsOnlyDeprioritizedForThatMethod", func(t *testing.T) {
+		registry, metricsTracker := createTestRegistry(projectID, &logger, 1*time.Second)
+		for _, method := range []string{"eth_getLogs", "eth_call"} {
+			_, _ = registry.GetSortedUpstreams(networkID, method)
+			for _, id := range []string{"upstream-a", "upstream-b", "upstream-c"} {
+				errors := 0
+				if id == "upstream-a" && method == "eth_getLogs" {
+					errors = 50
+				}
+				simulateRequests(metricsTracker, networkID, id, method, 100, errors)
+			}
+		}
+		registry.RefreshUpstreamNetworkMethodScores()
+
+		assert.Equal(t, "upstream-a", sortedIds(t, registry, "eth_getLogs")[2])
+		assert.Less(t, registry.MethodReliability("upstream-a", networkID, "eth_getLogs"), 1.0)
+		assert.Equal(t, 1.0, registry.MethodReliability("upstream-a", networkID, "eth_call"))
+		assert.Contains(t, sortedIds(t, registry, "eth_call"), "upstream-a")
+		assert.Equal(t, registry.upstreamScores["upstream-b"][networkID]["eth_call"], registry.upstreamScores["upstream-a"][networkID]["eth_call"])
+
+		// Once the metrics window resets the failures are no longer in metrics, but the learned reliability still applies
+		time.Sleep(1200 * time.Millisecond)
+		registry.RefreshUpstreamNetworkMethodScores()
+		assert.Equal(t, "upstream-a", sortedIds(t, registry, "eth_getLogs")[2])
+		assert.Equal(t, registry.upstreamScores["upstream-b"][networkID]["eth_call"], registry.upstreamScores["upstream-a"][networkID]["eth_call"])
+	})
+
+	t.Run("ReliabilityRecoversWithSuccessfulWindows", func(t *testing.T) {
+		registry, _ := createTestRegistry(projectID, &logger, 10*time.Hour)
+
+		registry.upstreamsMu.Lock()
+		low := registry.learnMethodReliability("upstream-a", networkID, "eth_getLogs", 100, 100)
+		// Too few new requests to learn from
+		same := registry.learnMethodReliability("upstream-a", networkID, "eth_getLogs", 100, 105)
+		recovered := low
+		for i := 1; i <= 20; i++ {
+			// Each refresh sees 100 more successful requests in the same window
+			recovered = registry.learnMethodReliability("upstream-a", networkID, "eth_getLogs", 100, float64(105+100*i))
+		}
+		registry.upstreamsMu.Unlock()
+
+		assert.InDelta(t, 0.7, low, 0.0001)
+		assert.Equal(t, low, same)
+		assert.Greater(t, recovered, 0.99)
+	})
+
+	t.Run("RefreshesWithinTheSameWindowCountRequestsOnce", func(t *testing.T) {
+		registry, _ := createTestRegistry(projectID, &logger, 10*time.Hour)
+
+		registry.upstreamsMu.Lock()
+		defer registry.upstreamsMu.Unlock()
+		first := registry.learnMethodReliability("upstream-a", networkID, "eth_getLogs", 50, 100)
+		for i := 0; i < 20; i++ {
+			assert.Equal(t, first, registry.learnMethodReliability("upstream-a", networkID, "eth_getLogs", 50, 100))
+		}
+		assert.InDelta(t, 0.85, first, 0.0001)
+
+		// After the window resets only the requests of the new window are folded
+		afterReset := registry.learnMethodReliability("upstream-a", networkID, "eth_getLogs", 0, 20)
+		assert.InDelta(t, 0.3+0.7*0.85, afterReset, 0.0001)
+	})
+}
+
+func TestUpstreamsRegistry_CostWeights(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	projectID := "test-project"
+	networkID := "evm:123"
+
+	withCosts := func(registry *UpstreamsRegistry, costs map[string]map[string]float64) {
+		for id, weights := range costs {
+			ups, _ := registry.GetUpstream(id)
+			ups.Config().CostWeights = weights
+		}
+	}
+
+	t.Run("ExpensiveMethodPrefersCheaperHealthyUpstream", func(t *testing.T) {
+		registry, metricsTracker := createTestRegistry(projectID, &logger, 10*time.Hour)
+		withCosts(registry, map[string]map[string]float64{
+			"upstream-a": {"trace_*": 10},
+			"upstream-b": {"trace_*": 2},
+			"upstream-c": {"trace_*": 5},
+		})
+		_, _ = registry.GetSortedUpstreams(networkID, "trace_block")
+
+		for _, id := range []string{"upstream-a", "upstream-b", "upstream-c"} {
+			simulateRequests(metricsTracker, networkID, id, "trace_block", 100, 0)
+		}
+
+		checkUpstreamScoreOrder(t, registry, networkID, "trace_block", []string{"upstream-b", "upstream-c", "upstream-a"})
+	})
+
+	t.Run("ErrorRateOutweighsCost", func(t *testing.T) {
+		registry, metricsTracker := createTestRegistry(projectID, &logger, 10*time.Hour)
+		withCosts(registry, map[string]map[string]float64{
+			"upstream-a": {"trace_*": 10},
+			"upstream-b": {"trace_*": 2},
+			"upstream-c": {"trace_*": 10},
+		})
+		_, _ = registry.GetSortedUpstreams(networkID, "trace_block")
+
+		simulateRequests(metricsTracker, networkID, "upstream-a", "trace_block", 100, 0)
+		simulateRequests(metricsTracker, networkID, "upstream-b", "trace_block", 100, 50)
+		simulateRequests(metricsTracker, networkID, "upstream-c", "trace_block", 100, 0)
+
+		registry.RefreshUpstreamNetworkMethodScores()
+		scores := registry.upstreamScores
+		assert.Greater(t, scores["upstream-a"][networkID]["trace_block"], scores["upstream-b"][networkID]["trace_block"])
+		assert.Greater(t, scores["upstream-c"][networkID]["trace_block"], scores["upstream-b"][networkID]["trace_block"])
+	})
+
+	t.Run("MethodCostPrefersExactThenLongestPattern", func(t *testing.T) {
+		registry, _ := createTestRegistry(projectID, &logger, 10*time.Hour)
+		withCosts(registry, map[string]map[string]float64{
+			"upstream-a": {"*": 1.5, "trace_*": 10, "trace_block": 20},
+		})
+		ups, _ := registry.GetUpstream("upstream-a")
+
+		assert.Equal(t, 20.0, ups.MethodCost("trace_block"))
+		assert.Equal(t, 10.0, ups.MethodCost("trace_call"))
+		assert.Equal(t, 1.5, ups.MethodCost("eth_call"))
+
+		other, _ := registry.GetUpstream("upstream-b")
+		assert.Equal(t, 1.0, other.MethodCost("trace_block"))
+	})
+}
+
+func TestUpstreamsRegistry_LocalRegion(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	projectID := "test-project"
+	networkID := "evm:123"
+	method := "eth_call"
+
+	withRegions := func(registry *UpstreamsRegistry, regions map[string]string) {
+		for id, region := range regions {
+			ups, _ := registry.GetUpstream(id)
+			ups.Config().Region = region
+		}
+	}
+	sortedIds := func(t *testing.T, registry *UpstreamsRegistry) []string {
+		sorted, err := registry.GetSortedUpstreams(networkID, method)
+		assert.NoError(t, err)
+		ids := make([]string, len(sorted))
+		for i, ups := range sorted {
+			ids[i] = ups.Config().Id
+		}
+		return ids
+	}
+
+	t.Run("SameRegionUpstreamsComeFirstRegardlessOfScore", func(t *testing.T) {
+		registry, metricsTracker := createTestRegistry(projectID, &logger, 10*time.Hour)
+		withRegions(registry, map[string]string{"upstream-a": "eu-west", "upstream-b": "us-east", "upstream-c": "us-east"})
+		registry.SetLocalRegion("us-east")
+		_, _ = registry.GetSortedUpstreams(networkID, method)
+
+		simulateRequests(metricsTracker, networkID, "upstream-a", method, 100, 0)
+		simulateRequests(metricsTracker, networkID, "upstream-b", method, 100, 30)
+		simulateRequests(metricsTracker, networkID, "upstream-c", method, 100, 10)
+		registry.RefreshUpstreamNetworkMethodScores()
+
+		assert.Greater(t, registry.upstreamScores["upstream-a"][networkID][method], registry.upstreamScores["upstream-c"][networkID][method])
+		assert.Equal(t, []string{"upstream-c", "upstream-b", "upstream-a"}, sortedIds(t, registry))
+	})
+
+	t.Run("ScoreDecidesWithoutLocalRegion", func(t *testing.T) {
+		registry, metricsTracker := createTestRegistry(projectID, &logger, 10*time.Hour)
+		withRegions(registry, map[string]string{"upstream-a": "eu-west", "upstream-b": "us-east", "upstream-c": "us-east"})
+		_, _ = registry.GetSortedUpstreams(networkID, method)
+
+		simulateRequests(metricsTracker, networkID, "upstream-a", method, 100, 0)
+		simulateRequests(metricsTracker, networkID, "upstream-b", method, 100, 30)
+		simulateRequests(metricsTracker, networkID, "upstream-c", method, 100, 10)
+		registry.RefreshUpstreamNetworkMethodScores()
+
+		assert.Equal(t, []string{"upstream-a", "upstream-c", "upstream-b"}, sortedIds(t, registry))
+	})
+}
+
+func TestUpstreamsRegistry_RateLimitHeadroom(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	projectID := "test-project"
+	networkID := "evm:123"
+	method := "eth_call"
+
+	withAutoTuner := func(t *testing.T, registry *UpstreamsRegistry, id string, maxCount uint) *RateLimitRule {
+		rlr, err := NewRateLimitersRegistry(&common.RateLimiterConfig{
+			Budgets: []*common.RateLimitBudgetConfig{
+				{
+					Id:    id + "-budget",
+					Rules: []*common.RateLimitRuleConfig{{Method: "*", MaxCount: maxCount, Period: "1h"}},
+				},
+			},
+		}, &logger)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		budget, err := rlr.GetBudget(id + "-budget")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		ups, _ := registry.GetUpstream(id)
+		ups.rateLimiterAutoTuner = NewRateLimitAutoTuner(&logger, budget, time.Minute, 0.1, 1.05, 0.9, 0, 10_000)
+		return budget.Rules[0]
+	}
+
+	t.Run("ThrottledUpstreamReceivesLessTraffic", func(t *testing.T) {
+		registry, metricsTracker := createTestRegistry(projectID, &logger, 10*time.Hour)
+		ruleA := withAutoTuner(t, registry, "upstream-a", 1000)
+		withAutoTuner(t, registry, "upstream-b", 1000)
+		withAutoTuner(t, registry, "upstream-c", 1000)
+
+		// The tuner cut upstream-a's budget to a fifth after remote rate limits
+		upsA, _ := registry.GetUpstream("upstream-a")
+		upsA.rateLimiterAutoTuner.baselines[ruleA.Config.Method] = 1000
+		assert.NoError(t, upsA.rateLimiterAutoTuner.budget.AdjustBudget(ruleA, 200))
+
+		picks := map[string]int{}
+		for i := 0; i < 300; i++ {
+			registry.RefreshUpstreamNetworkMethodScores()
+			sorted, err := registry.GetSortedUpstreams(networkID, method)
+			if !assert.NoError(t, err) {
+				return
+			}
+			ups := sorted[0]
+			picks[ups.Config().Id]++
+			simulateRequests(metricsTracker, networkID, ups.Config().Id, method, 1, 0)
+			for _, rule := range ups.rateLimiterAutoTuner.budget.GetRulesByMethod(method) {
+				ups.rateLimiterAutoTuner.RecordPermit(rule)
+			}
+		}
+
+		assert.Less(t, picks["upstream-a"], picks["upstream-b"]/2, "picks: %v", picks)
+		assert.Less(t, picks["upstream-a"], picks["upstream-c"]/2, "picks: %v", picks)
+		assert.InDelta(t, picks["upstream-b"], picks["upstream-c"], 30, "upstreams with headroom share traffic, picks: %v", picks)
+	})
+
+	t.Run("UpstreamNearItsLimitIsRankedLast", func(t *testing.T) {
+		registry, metricsTracker := createTestRegistry(projectID, &logger, 10*time.Hour)
+		ruleB := withAutoTuner(t, registry, "upstream-b", 100)
+		upsB, _ := registry.GetUpstream("upstream-b")
+		for i := 0; i < 95; i++ {
+			upsB.rateLimiterAutoTuner.RecordPermit(ruleB)
+		}
+		assert.InDelta(t, 0.05, upsB.RateLimitHeadroom(method), 0.001)
+
+		upsA, _ := registry.GetUpstream("upstream-a")
+		assert.Equal(t, 1.0, upsA.RateLimitHeadroom(method), "upstreams without auto-tuning have full headroom")
+
+		_, _ = registry.GetSortedUpstreams(networkID, method)
+		for _, id := range []string{"upstream-a", "upstream-b", "upstream-c"} {
+			simulateRequests(metricsTracker, networkID, id, method, 100, 0)
+		}
+		registry.RefreshUpstreamNetworkMethodScores()
+		scores := registry.upstreamScores
+		assert.Greater(t, scores["upstream-a"][networkID][method], scores["upstream-b"][networkID][method])
+		assert.Greater(t, scores["upstream-c"][networkID][method], scores["upstream-b"][networkID][method])
+	})
+
+	t.Run("ConcurrentPermitsAreAllCounted", func(t *testing.T) {
+		registry, _ := createTestRegistry(projectID, &logger, 10*time.Hour)
+		ruleB := withAutoTuner(t, registry, "upstream-b", 1000)
+		upsB, _ := registry.GetUpstream("upstream-b")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					upsB.rateLimiterAutoTuner.RecordPermit(ruleB)
+					_ = upsB.RateLimitHeadroom(method)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.InDelta(t, 0.5, upsB.RateLimitHeadroom(method), 0.001)
+	})
+}
+
+func TestUpstreamsRegistry_UpstreamGroups(t *testing.T) {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
+	networkID := "evm:123"
+	method := "eth_call"
+
+	rlr, err := NewRateLimitersRegistry(&common.RateLimiterConfig{
+		Budgets: []*common.RateLimitBudgetConfig{
+			{
+				Id:    "provider-account",
+				Rules: []*common.RateLimitRuleConfig{{Method: "*", MaxCount: 3, Period: "1h"}},
+			},
+		},
+	}, &logger)
+	require.NoError(t, err)
+
+	metricsTracker := health.NewTracker("test-project", 10*time.Hour)
+	registry := NewUpstreamsRegistry(
+		&logger,
+		"test-project",
+		[]*common.UpstreamConfig{
+			{Id: "upstream-a", Endpoint: "http://upstream-a.localhost", Group: "provider", Evm: &common.EvmUpstreamConfig{ChainId: 123}},
+			{Id: "upstream-b", Endpoint: "http://upstream-b.localhost", Group: "provider", Evm: &common.EvmUpstreamConfig{ChainId: 123}},
+			{Id: "upstream-c", Endpoint: "http://upstream-c.localhost", Evm: &common.EvmUpstreamConfig{ChainId: 123}},
+		},
+		rlr,
+		vendors.NewVendorsRegistry(),
+		metricsTracker,
+		1*time.Second,
+	)
+	registry.SetUpstreamGroups([]*common.UpstreamGroupConfig{{Id: "provider", RateLimitBudget: "provider-account"}})
+	require.NoError(t, registry.Bootstrap(context.Background()))
+	require.NoError(t, registry.PrepareUpstreamsForNetwork(networkID))
+
+	upsA, _ := registry.GetUpstream("upstream-a")
+	upsB, _ := registry.GetUpstream("upstream-b")
+	upsC, _ := registry.GetUpstream("upstream-c")
+	for _, ups := range []*Upstream{upsA, upsB, upsC} {
+		ups.Client = NewMockHttpJsonRpcClient(networkID).OnResult(method, "0x1")
+	}
+	assert.Same(t, upsA.Group(), upsB.Group())
+	assert.Nil(t, upsC.Group())
+
+	forward := func(ups *Upstream) error {
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[{"to":"0x1"},"latest"]}`))
+		_, err := ups.Forward(context.Background(), req)
+		return err
+	}
+
+	t.Run("MembersShareAndExhaustOneBudget", func(t *testing.T) {
+		assert.NoError(t, forward(upsA))
+		assert.NoError(t, forward(upsB))
+		assert.NoError(t, forward(upsA))
+		assert.False(t, upsA.Group().IsSaturated(method))
+
+		// Neither member has its own budget, yet the fourth request of the group is over the account limit
+		err := forward(upsB)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeUpstreamRateLimitRuleExceeded), "unexpected error: %v", err)
+		err = forward(upsA)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeUpstreamRateLimitRuleExceeded), "unexpected error: %v", err)
+		assert.NoError(t, forward(upsC), "upstreams outside the group are not limited")
+		assert.True(t, upsA.Group().IsSaturated(method))
+	})
+
+	t.Run("SaturatedGroupMembersAreRankedLast", func(t *testing.T) {
+		sorted, err := registry.GetSortedUpstreams(networkID, method)
+		require.NoError(t, err)
+		require.Len(t, sorted, 3)
+		assert.Equal(t, "upstream-c", sorted[0].Config().Id)
+	})
+
+	t.Run("RequestRejectedByGroupDoesNotUseMemberBudget", func(t *testing.T) {
+		rlr, err := NewRateLimitersRegistry(&common.RateLimiterConfig{
+			Budgets: []*common.RateLimitBudgetConfig{
+				{Id: "account", Rules: []*common.RateLimitRuleConfig{{Method: "*", MaxCount: 1, Period: "1h"}}},
+				{Id: "member", Rules: []*common.RateLimitRuleConfig{{Method: "*", MaxCount: 2, Period: "1h"}}},
+			},
+		}, &logger)
+		require.NoError(t, err)
+		r := NewUpstreamsRegistry(
+			&logger,
+			"test-project",
+			[]*common.UpstreamConfig{{Id: "upstream-d", Endpoint: "http://upstream-d.localhost", Group: "provider", RateLimitBudget: "member", Evm: &common.EvmUpstreamConfig{ChainId: 123}}},
+			rlr,
+			vendors.NewVendorsRegistry(),
+			metricsTracker,
+			1*time.Second,
+		)
+		r.SetUpstreamGroups([]*common.UpstreamGroupConfig{{Id: "provider", RateLimitBudget: "account"}})
+		require.NoError(t, r.Bootstrap(context.Background()))
+		require.NoError(t, r.PrepareUpstreamsForNetwork(networkID))
+		upsD, _ := r.GetUpstream("upstream-d")
+		upsD.Client = NewMockHttpJsonRpcClient(networkID).OnResult(method, "0x1")
+
+		assert.NoError(t, forward(upsD))
+		err = forward(upsD)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeUpstreamRateLimitRuleExceeded), "unexpected error: %v", err)
+
+		// The second request was rejected by the group, so the member still has one permit of its own
+		budget, err := rlr.GetBudget("member")
+		require.NoError(t, err)
+		assert.True(t, budget.GetRulesByMethod(method)[0].Limiter.TryAcquirePermit())
+	})
+
+	t.Run("UndefinedGroupIsRejected", func(t *testing.T) {
+		r := NewUpstreamsRegistry(
+			&logger,
+			"test-project",
+			[]*common.UpstreamConfig{{Id: "upstream-a", Endpoint: "http://upstream-a.localhost", Group: "missing", Evm: &common.EvmUpstreamConfig{ChainId: 123}}},
+			rlr,
+			vendors.NewVendorsRegistry(),
+			metricsTracker,
+			1*time.Second,
+		)
+		err := r.Bootstrap(context.Background())
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeInvalidConfig), "unexpected error: %v", err)
+	})
+}
+
 func createTestRegistry(projectID string, logger *zerolog.Logger, windowSize time.Duration) (*UpstreamsRegistry, *health.Tracker) {
 	metricsTracker := health.NewTracker(projectID, windowSize)
 	metricsTracker.Bootstrap(context.Background())
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/erpc/erpc/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
 )
 
 func TestNormalizedRequest_BodyCannotBeDecoded(t *testing.T) {
@@ -22,3 +24,35 @@ func TestNormalizedRequest_BodyCannotBeDecoded(t *testing.T) {
 		t.Error("Expected an error, got nil")
 	}
 }
+
+func TestNormalizedRequest_Priority(t *testing.T) {
+	t.Run("DerivedFromMethod", func(t *testing.T) {
+		logs := common.NewNormalizedRequest([]byte(`{"method":"eth_getLogs","params":[]}`))
+		trace := common.NewNormalizedRequest([]byte(`{"method":"trace_block","params":[]}`))
+		call := common.NewNormalizedRequest([]byte(`{"method":"eth_call","params":[]}`))
+
+		assert.Equal(t, common.RequestPriorityLow, logs.Priority())
+		assert.Equal(t, common.RequestPriorityLow, trace.Priority())
+		assert.Equal(t, common.RequestPriorityNormal, call.Priority())
+	})
+
+	t.Run("HeaderOverridesMethodDefault", func(t *testing.T) {
+		req := common.NewNormalizedRequest([]byte(`{"method":"eth_getLogs","params":[]}`))
+		headers := &fasthttp.RequestHeader{}
+		headers.Set("X-ERPC-Priority", "high")
+		req.ApplyDirectivesFromHttp(headers, &fasthttp.Args{})
+
+		assert.Equal(t, common.RequestPriorityHigh, req.Priority())
+	})
+
+	t.Run("QueryParamOverridesHeader", func(t *testing.T) {
+		req := common.NewNormalizedRequest([]byte(`{"method":"eth_call","params":[]}`))
+		headers := &fasthttp.RequestHeader{}
+		headers.Set("X-ERPC-Priority", "high")
+		args := &fasthttp.Args{}
+		args.Set("priority", "low")
+		req.ApplyDirectivesFromHttp(headers, args)
+
+		assert.Equal(t, common.RequestPriorityLow, req.Priority())
+	})
+}
@@ -0,0 +1,86 @@
+package upstream
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/erpc/erpc/common"
+)
+
+const defaultSigV4Service = "managedblockchain"
+
+type sigV4Signer struct {
+	signer  *v4.Signer
+	region  string
+	service string
+}
+
+// newSigV4Signer resolves credentials once so that invalid or missing credentials fail on startup
+// instead of on the first request, they are refreshed by the sdk when they expire afterwards.
+func newSigV4Signer(upstreamId string, cfg *common.SigV4Config) (*sigV4Signer, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.Region == "" {
+		return nil, common.NewErrInvalidConfig(fmt.Sprintf("missing jsonRpc.sigv4.region for upstream %s", upstreamId))
+	}
+
+	creds, err := resolveSigV4Credentials(cfg)
+	if err != nil {
+		return nil, common.NewErrInvalidConfig(fmt.Sprintf("invalid jsonRpc.sigv4 config for upstream %s: %v", upstreamId, err))
+	}
+	if _, err := creds.Get(); err != nil {
+		return nil, common.NewErrInvalidConfig(fmt.Sprintf("invalid jsonRpc.sigv4 credentials for upstream %s: %v", upstreamId, err))
+	}
+
+	service := cfg.Service
+	if service == "" {
+		service = defaultSigV4Service
+	}
+
+	return &sigV4Signer{
+		signer:  v4.NewSigner(creds),
+		region:  cfg.Region,
+		service: service,
+	}, nil
+}
+
+func resolveSigV4Credentials(cfg *common.SigV4Config) (*credentials.Credentials, error) {
+	mode := "role"
+	if cfg.Auth != nil {
+		mode = cfg.Auth.Mode
+	}
+
+	switch mode {
+	case "file":
+		return credentials.NewSharedCredentials(cfg.Auth.CredentialsFile, cfg.Auth.Profile), nil
+	case "env":
+		return credentials.NewEnvCredentials(), nil
+	case "secret":
+		return credentials.NewStaticCredentials(cfg.Auth.AccessKeyID, cfg.Auth.SecretAccessKey, ""), nil
+	case "role":
+		sess, err := session.NewSession(&aws.Config{Region: aws.String(cfg.Region)})
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Auth != nil && cfg.Auth.RoleArn != "" {
+			return stscreds.NewCredentials(sess, cfg.Auth.RoleArn), nil
+		}
+		return sess.Config.Credentials, nil
+	default:
+		return nil, fmt.Errorf("unsupported auth.mode: %s", mode)
+	}
+}
+
+// sign computes a fresh signature of the request and its body, it must be called after all other headers are set.
+func (s *sigV4Signer) sign(req *http.Request, body []byte, signTime time.Time) error {
+	_, err := s.signer.Sign(req, bytes.NewReader(body), s.service, s.region, signTime)
+	return err
+}
@@ -0,0 +1,128 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigV4Signer(t *testing.T) {
+	secretCfg := func() *common.SigV4Config {
+		return &common.SigV4Config{
+			Region: "us-east-1",
+			Auth: &common.AwsAuthConfig{
+				Mode:            "secret",
+				AccessKeyID:     "AKIDEXAMPLE",
+				SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			},
+		}
+	}
+
+	t.Run("KnownSignature", func(t *testing.T) {
+		signer, err := newSigV4Signer("test", secretCfg())
+		assert.NoError(t, err)
+
+		body := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+		req, err := http.NewRequest("POST", "https://nd-123.ethereum.managedblockchain.us-east-1.amazonaws.com/", bytes.NewBuffer(body))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		err = signer.sign(req, body, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+		assert.NoError(t, err)
+
+		assert.Equal(t, "20240102T030405Z", req.Header.Get("X-Amz-Date"))
+		assert.Equal(t,
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240102/us-east-1/managedblockchain/aws4_request, "+
+				"SignedHeaders=content-type;host;x-amz-date, "+
+				"Signature=4122260cc76ecae520089a2d9c21a59543a5d7f1d239c0727bef0b9faca44cde",
+			req.Header.Get("Authorization"),
+		)
+	})
+
+	t.Run("EachRequestIsSignedByTheClient", func(t *testing.T) {
+		var mu sync.Mutex
+		var auths []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			auths = append(auths, r.Header.Get("Authorization"))
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+		}))
+		defer server.Close()
+
+		logger := zerolog.Nop()
+		u, _ := url.Parse(server.URL)
+		client, err := NewGenericHttpJsonRpcClient(&logger, &Upstream{
+			config: &common.UpstreamConfig{
+				Id:       "test",
+				Endpoint: server.URL,
+				JsonRpc:  &common.JsonRpcUpstreamConfig{SigV4: secretCfg()},
+			},
+		}, u)
+		assert.NoError(t, err)
+
+		for _, body := range []string{
+			`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`,
+			`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`,
+		} {
+			_, err := client.SendRequest(context.Background(), common.NewNormalizedRequest([]byte(body)))
+			assert.NoError(t, err)
+		}
+
+		if assert.Len(t, auths, 2) {
+			for _, auth := range auths {
+				assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"), auth)
+				assert.Contains(t, auth, "/us-east-1/managedblockchain/aws4_request")
+			}
+			assert.NotEqual(t, auths[0], auths[1])
+		}
+	})
+
+	t.Run("EmptySecretFailsFast", func(t *testing.T) {
+		cfg := secretCfg()
+		cfg.Auth.SecretAccessKey = ""
+		_, err := newSigV4Signer("test", cfg)
+		assert.Error(t, err)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeInvalidConfig))
+	})
+
+	t.Run("MissingEnvCredentialsFailFast", func(t *testing.T) {
+		t.Setenv("AWS_ACCESS_KEY_ID", "")
+		t.Setenv("AWS_ACCESS_KEY", "")
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+		t.Setenv("AWS_SECRET_KEY", "")
+		_, err := newSigV4Signer("test", &common.SigV4Config{
+			Region: "us-east-1",
+			Auth:   &common.AwsAuthConfig{Mode: "env"},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("EnvCredentials", func(t *testing.T) {
+		t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+		_, err := newSigV4Signer("test", &common.SigV4Config{
+			Region: "us-east-1",
+			Auth:   &common.AwsAuthConfig{Mode: "env"},
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("MissingRegionFailsFast", func(t *testing.T) {
+		cfg := secretCfg()
+		cfg.Region = ""
+		_, err := newSigV4Signer("test", cfg)
+		assert.Error(t, err)
+	})
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bytedance/sonic"
@@ -15,6 +18,7 @@ import (
 	"github.com/erpc/erpc/util"
 	"github.com/erpc/erpc/vendors"
 	"github.com/failsafe-go/failsafe-go"
+	"github.com/failsafe-go/failsafe-go/circuitbreaker"
 	"github.com/rs/zerolog"
 )
 
@@ -31,11 +35,20 @@ type Upstream struct {
 	failsafeExecutor     failsafe.Executor[*common.NormalizedResponse]
 	rateLimitersRegistry *RateLimitersRegistry
 	rateLimiterAutoTuner *RateLimitAutoTuner
+	concurrencyLimiter   *ConcurrencyLimiter
+	quarantined          atomic.Bool
+	healthCheckFailing   atomic.Bool
+	// Unix nanos of when the circuit breaker closed again after being half-open, 0 when not ramping up
+	recoveredAt     atomic.Int64
+	slowStartWindow time.Duration
 
 	methodCheckResults    map[string]bool
 	methodCheckResultsMu  sync.RWMutex
 	supportedNetworkIds   map[string]bool
 	supportedNetworkIdsMu sync.RWMutex
+	capabilities          *capabilityCache
+	debugBundles          *debugBundleRecorder
+	group                 *UpstreamGroup
 }
 
 func NewUpstream(
@@ -49,11 +62,6 @@ func NewUpstream(
 ) (*Upstream, error) {
 	lg := logger.With().Str("upstreamId", cfg.Id).Logger()
 
-	policies, err := CreateFailSafePolicies(&lg, ScopeUpstream, cfg.Id, cfg.Failsafe)
-	if err != nil {
-		return nil, err
-	}
-
 	vn := vr.LookupByUpstream(cfg)
 
 	pup := &Upstream{
@@ -63,15 +71,42 @@ func NewUpstream(
 		config:               cfg,
 		vendor:               vn,
 		metricsTracker:       mt,
-		failsafePolicies:     policies,
-		failsafeExecutor:     failsafe.NewExecutor[*common.NormalizedResponse](policies...),
 		rateLimitersRegistry: rlr,
 		methodCheckResults:   map[string]bool{},
 		supportedNetworkIds:  map[string]bool{},
 	}
 
+	if cfg.Failsafe != nil && cfg.Failsafe.CircuitBreaker != nil && cfg.Failsafe.CircuitBreaker.SlowStartWindow != "" {
+		window, err := time.ParseDuration(cfg.Failsafe.CircuitBreaker.SlowStartWindow)
+		if err != nil {
+			return nil, common.NewErrFailsafeConfiguration(fmt.Errorf("failed to parse circuitBreaker.slowStartWindow: %v", err), map[string]interface{}{
+				"component": cfg.Id,
+				"policy":    cfg.Failsafe.CircuitBreaker,
+			})
+		}
+		pup.slowStartWindow = window
+	}
+
+	policies, err := CreateFailSafePolicies(&lg, ScopeUpstream, cfg.Id, cfg.Failsafe, pup.markRecovered)
+	if err != nil {
+		return nil, err
+	}
+	pup.failsafePolicies = policies
+	pup.failsafeExecutor = failsafe.NewExecutor[*common.NormalizedResponse](policies...)
+
 	pup.initRateLimitAutoTuner()
 
+	pup.capabilities, err = newCapabilityCache(cfg.CapabilityProbe, pup.probeCapability)
+	if err != nil {
+		return nil, err
+	}
+
+	pup.debugBundles = newDebugBundleRecorder(cfg.DebugBundle)
+
+	if cfg.Concurrency != nil && cfg.Concurrency.MaxConcurrent > 0 {
+		pup.concurrencyLimiter = NewConcurrencyLimiter(cfg.Id, cfg.Concurrency)
+	}
+
 	if vn != nil {
 		err = vn.OverrideConfig(cfg)
 		if err != nil {
@@ -96,11 +131,26 @@ func NewUpstream(
 		}
 	}()
 
+	if cfg.JsonRpc != nil && cfg.JsonRpc.WarmupConnections > 0 {
+		if gc, ok := pup.Client.(*GenericHttpJsonRpcClient); ok {
+			go func() {
+				if err := gc.WarmupConnections(context.Background(), cfg.JsonRpc.WarmupConnections); err != nil {
+					lg.Warn().Err(err).Msgf("could not warm up all connections for upstream")
+				}
+			}()
+		}
+	}
+
 	lg.Debug().Msgf("prepared upstream")
 
 	return pup, nil
 }
 
+// IsQuarantined reports whether an operator pulled this upstream out of rotation.
+func (u *Upstream) IsQuarantined() bool {
+	return u.quarantined.Load()
+}
+
 func (u *Upstream) Config() *common.UpstreamConfig {
 	return u.config
 }
@@ -140,6 +190,9 @@ func (u *Upstream) prepareRequest(nr *common.NormalizedRequest) error {
 			u.Client.GetType() == ClientTypeEtherspotHttpJsonRpc {
 			jsonRpcReq, err := nr.JsonRpcRequest()
 			if err != nil {
+				if common.HasErrorCode(err, common.ErrCodeJsonRpcRequestInvalid) {
+					return common.TranslateToJsonRpcException(err)
+				}
 				return common.NewErrJsonRpcExceptionInternal(
 					0,
 					common.JsonRpcErrorParseException,
@@ -182,13 +235,35 @@ func (u *Upstream) prepareRequest(nr *common.NormalizedRequest) error {
 
 // Forward is used during lifecycle of a proxied request, it uses writers and readers for better performance
 func (u *Upstream) Forward(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error) {
-	startTime := time.Now()
-	cfg := u.Config()
-
 	if reason, skip := u.shouldSkip(req); skip {
-		return nil, common.NewErrUpstreamRequestSkipped(reason, cfg.Id)
+		return nil, common.NewErrUpstreamRequestSkipped(reason, u.Config().Id)
+	}
+
+	// Each sub-range goes through Forward again, so rate limits and concurrency slots apply per sub-query
+	// Other upstreams may allow a wider range per sub-query, so a range too wide for this one is skipped
+	ranges, filter, err := u.getLogsSubRanges(req)
+	if err != nil {
+		return nil, common.NewErrUpstreamRequestSkipped(err, u.Config().Id)
+	}
+	if len(ranges) > 1 {
+		return u.forwardGetLogsSplit(ctx, req, ranges, filter)
 	}
 
+	// A single block cannot be split further, too many logs are fetched for subsets of the addresses instead
+	if addresses, filter := getLogsAddressSplitCandidate(req); len(addresses) > 1 {
+		resp, err := u.forward(ctx, req)
+		if err != nil && common.HasErrorCode(err, common.ErrCodeEndpointEvmLargeRange) {
+			return u.forwardGetLogsByAddress(ctx, req, addresses, filter)
+		}
+		return resp, err
+	}
+
+	return u.forward(ctx, req)
+}
+
+func (u *Upstream) forward(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error) {
+	startTime := time.Now()
+	cfg := u.Config()
 	clientType := u.Client.GetType()
 
 	//
@@ -220,6 +295,24 @@ func (u *Upstream) Forward(ctx context.Context, req *common.NormalizedRequest) (
 
 	lg := u.Logger.With().Str("method", method).Str("networkId", netId).Logger()
 
+	// Budget shared with other members of the group, e.g. an account-wide limit of a provider. It is checked first
+	// since permits cannot be given back, so that a request rejected by the group does not use the member's own budget.
+	if u.group != nil {
+		if rule := u.group.tryAcquirePermit(method); rule != nil {
+			lg.Warn().Str("group", u.group.Id).Str("budget", u.group.budgetId).Msgf("upstream group rate limit '%v' exceeded", rule.Config)
+			u.metricsTracker.RecordUpstreamSelfRateLimited(
+				netId,
+				cfg.Id,
+				method,
+			)
+			return nil, common.NewErrUpstreamRateLimitRuleExceeded(
+				cfg.Id,
+				u.group.budgetId,
+				fmt.Sprintf("%+v", rule.Config),
+			)
+		}
+	}
+
 	if limitersBudget != nil {
 		lg.Trace().Str("budget", cfg.RateLimitBudget).Msgf("checking upstream-level rate limiters budget")
 		rules := limitersBudget.GetRulesByMethod(method)
@@ -239,11 +332,28 @@ func (u *Upstream) Forward(ctx context.Context, req *common.NormalizedRequest) (
 					)
 				} else {
 					lg.Trace().Str("budget", cfg.RateLimitBudget).Object("rule", rule.Config).Msgf("upstream-level rate limit passed")
+					if u.rateLimiterAutoTuner != nil {
+						u.rateLimiterAutoTuner.RecordPermit(rule)
+					}
 				}
 			}
 		}
 	}
 
+	//
+	// Wait for a concurrency slot, higher priority requests are served first
+	//
+	if u.concurrencyLimiter != nil {
+		if err := u.concurrencyLimiter.Acquire(ctx, req.Priority()); err != nil {
+			if common.HasErrorCode(err, common.ErrCodeUpstreamConcurrencyQueueFull) {
+				lg.Warn().Msgf("upstream concurrency limit reached and queue is full")
+				return nil, err
+			}
+			return nil, common.NewErrUpstreamRequest(err, cfg.Id, netId, method, time.Since(startTime), 0, 0, 0)
+		}
+		defer u.concurrencyLimiter.Release()
+	}
+
 	//
 	// Prepare and normalize the request object
 	//
@@ -288,12 +398,19 @@ func (u *Upstream) Forward(ctx context.Context, req *common.NormalizedRequest) (
 				netId,
 				method,
 			)
+			if cfg.CostWeights != nil {
+				health.MetricUpstreamRequestCostTotal.WithLabelValues(u.ProjectId, netId, cfg.Id, method).Add(u.MethodCost(method))
+			}
 			timer := u.metricsTracker.RecordUpstreamDurationStart(cfg.Id, netId, method)
 			defer timer.ObserveDuration()
 			resp, errCall := jsonRpcClient.SendRequest(ctx, req)
+			if errCall == nil && resp != nil && cfg.Evm != nil && cfg.Evm.ValidateResponses {
+				jrr, _ := resp.JsonRpcResponse()
+				errCall = common.ValidateEvmJsonRpcResponse(method, jrr)
+			}
 			if resp != nil {
 				jrr, _ := resp.JsonRpcResponse()
-				if jrr != nil && jrr.Error == nil {
+				if jrr != nil && jrr.Error == nil && errCall == nil {
 					req.SetLastValidResponse(resp)
 				}
 				lg.Debug().Err(errCall).Str("response", resp.String()).Msgf("upstream call result received")
@@ -354,6 +471,9 @@ func (u *Upstream) Forward(ctx context.Context, req *common.NormalizedRequest) (
 
 		if u.failsafePolicies != nil && len(u.failsafePolicies) > 0 {
 			executor := u.failsafeExecutor
+			if ex := ExecutorForRequestTimeout(u.failsafePolicies, cfg.Failsafe, req.Timeout()); ex != nil {
+				executor = ex
+			}
 			resp, execErr := executor.
 				WithContext(ctx).
 				GetWithExecution(func(exec failsafe.Execution[*common.NormalizedResponse]) (*common.NormalizedResponse, error) {
@@ -380,8 +500,28 @@ func (u *Upstream) Executor() failsafe.Executor[*common.NormalizedResponse] {
 	return u.failsafeExecutor
 }
 
+// HealthCheck asks the upstream client to send its architecture's health check request, bypassing failsafe policies.
+// The outcome is kept until the next check, see IsHealthCheckFailing.
+func (u *Upstream) HealthCheck(ctx context.Context) error {
+	hc, ok := u.Client.(HttpJsonRpcClient)
+	if !ok {
+		return fmt.Errorf("client of upstream %s does not support health checks", u.config.Id)
+	}
+	err := hc.HealthCheck(ctx)
+	u.healthCheckFailing.Store(err != nil)
+	return err
+}
+
+// IsHealthCheckFailing reports whether the last health check of this upstream failed, it is false until one ran.
+func (u *Upstream) IsHealthCheckFailing() bool {
+	return u.healthCheckFailing.Load()
+}
+
 func (u *Upstream) EvmGetChainId(ctx context.Context) (string, error) {
-	pr := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":75412,"method":"eth_chainId","params":[]}`))
+	pr, err := common.NewInternalRequest("eth_chainId", nil)
+	if err != nil {
+		return "", err
+	}
 	resp, err := u.Forward(ctx, pr)
 	if err != nil {
 		return "", err
@@ -431,6 +571,52 @@ func (u *Upstream) SupportsNetwork(networkId string) (bool, error) {
 	return supports, nil
 }
 
+func (u *Upstream) ActiveNetworks() []string {
+	u.supportedNetworkIdsMu.RLock()
+	defer u.supportedNetworkIdsMu.RUnlock()
+
+	var activeNetworks []string
+	for netId, supported := range u.supportedNetworkIds {
+		if supported {
+			activeNetworks = append(activeNetworks, netId)
+		}
+	}
+	sort.Strings(activeNetworks)
+
+	return activeNetworks
+}
+
+func (u *Upstream) markRecovered() {
+	if u.slowStartWindow > 0 {
+		u.recoveredAt.Store(time.Now().UnixNano())
+	}
+}
+
+// SlowStartShare is the share of traffic (between 0 and 1) the upstream should get while it ramps up after its
+// circuit breaker closed again, it is 1 when slow-start is disabled or the window has passed.
+func (u *Upstream) SlowStartShare() float64 {
+	since := u.recoveredAt.Load()
+	if since == 0 || u.slowStartWindow <= 0 {
+		return 1
+	}
+	elapsed := time.Since(time.Unix(0, since))
+	if elapsed >= u.slowStartWindow {
+		u.recoveredAt.CompareAndSwap(since, 0)
+		return 1
+	}
+	return float64(elapsed) / float64(u.slowStartWindow)
+}
+
+// CircuitBreakerState returns "closed", "open" or "half-open", or empty string when no circuit breaker is configured.
+func (u *Upstream) CircuitBreakerState() string {
+	for _, p := range u.failsafePolicies {
+		if cb, ok := p.(circuitbreaker.CircuitBreaker[*common.NormalizedResponse]); ok {
+			return cb.State().String()
+		}
+	}
+	return ""
+}
+
 func (u *Upstream) IgnoreMethod(method string) {
 	ai := u.config.AutoIgnoreUnsupportedMethods
 	if ai == nil || !*ai {
@@ -477,6 +663,24 @@ func (u *Upstream) recordRequestSuccess(method string) {
 	if u.rateLimiterAutoTuner != nil {
 		u.rateLimiterAutoTuner.RecordSuccess(method)
 	}
+	if u.group != nil && u.group.autoTuner != nil {
+		u.group.autoTuner.RecordSuccess(method)
+	}
+}
+
+// RateLimitHeadroom tells how much of the auto-tuned rate limit budget is left for a method (0 to 1),
+// the lowest of the upstream's own and its group's. Upstreams without auto-tuning always report full headroom.
+func (u *Upstream) RateLimitHeadroom(method string) float64 {
+	headroom := u.group.headroom(method)
+	if u.rateLimiterAutoTuner != nil {
+		headroom = math.Min(headroom, u.rateLimiterAutoTuner.Headroom(method))
+	}
+	return headroom
+}
+
+// Group returns the upstream group this upstream is a member of, nil when it is not in any group.
+func (u *Upstream) Group() *UpstreamGroup {
+	return u.group
 }
 
 func (u *Upstream) recordRemoteRateLimit(netId, method string) {
@@ -489,6 +693,9 @@ func (u *Upstream) recordRemoteRateLimit(netId, method string) {
 	if u.rateLimiterAutoTuner != nil {
 		u.rateLimiterAutoTuner.RecordError(method)
 	}
+	if u.group != nil && u.group.autoTuner != nil {
+		u.group.autoTuner.RecordError(method)
+	}
 }
 
 func (u *Upstream) shouldHandleMethod(method string) (v bool) {
@@ -538,6 +745,22 @@ func (u *Upstream) shouldHandleMethod(method string) (v bool) {
 	return v
 }
 
+// MethodCost returns the configured billing cost of a request for the method, an exact method match wins
+// over wildcards and the longest matching wildcard wins over shorter ones. Defaults to 1 when not configured.
+func (u *Upstream) MethodCost(method string) float64 {
+	weights := u.Config().CostWeights
+	if cost, ok := weights[method]; ok {
+		return cost
+	}
+	cost, matched := 1.0, ""
+	for pattern, c := range weights {
+		if len(pattern) > len(matched) && common.WildcardMatch(pattern, method) {
+			cost, matched = c, pattern
+		}
+	}
+	return cost
+}
+
 func (u *Upstream) guessUpstreamType() error {
 	cfg := u.Config()
 
@@ -631,12 +854,20 @@ func (u *Upstream) shouldSkip(req *common.NormalizedRequest) (reason error, skip
 		return common.NewErrUpstreamMethodIgnored(method, u.config.Id), true
 	}
 
+	if capability, ok := methodCapability(method); ok && u.Capability(capability) == CapabilityUnsupported {
+		u.Logger.Debug().Str("method", method).Str("capability", string(capability)).Msg("upstream was probed to not support method capability")
+		return common.NewErrUpstreamMethodIgnored(method, u.config.Id), true
+	}
+
 	dirs := req.Directives()
 	if dirs.UseUpstream != "" {
 		if !common.WildcardMatch(dirs.UseUpstream, u.config.Id) {
 			return common.NewErrUpstreamNotAllowed(u.config.Id), true
 		}
 	}
+	if dirs.ExcludeUpstream != "" && common.WildcardMatch(dirs.ExcludeUpstream, u.config.Id) {
+		return common.NewErrUpstreamNotAllowed(u.config.Id), true
+	}
 
 	// TODO evm: if block can be determined from request and upstream is only full-node and block is historical skip
 
@@ -648,6 +879,7 @@ func (u *Upstream) MarshalJSON() ([]byte, error) {
 		Id             string                            `json:"id"`
 		Metrics        map[string]*health.TrackedMetrics `json:"metrics"`
 		ActiveNetworks []string                          `json:"activeNetworks"`
+		Quarantined    bool                              `json:"quarantined"`
 	}
 
 	var activeNetworks []string
@@ -663,6 +895,7 @@ func (u *Upstream) MarshalJSON() ([]byte, error) {
 		Id:             u.config.Id,
 		Metrics:        metrics,
 		ActiveNetworks: activeNetworks,
+		Quarantined:    u.IsQuarantined(),
 	}
 
 	return sonic.Marshal(uppub)
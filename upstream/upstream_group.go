@@ -0,0 +1,137 @@
+package upstream
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/erpc/erpc/common"
+	"github.com/rs/zerolog"
+)
+
+// UpstreamGroup is a set of upstreams sharing one rate limit budget, e.g. several endpoints of the same
+// provider account. Members check the group budget on top of their own, and once it is exhausted for a
+// method they are ranked after other upstreams until the budget replenishes.
+type UpstreamGroup struct {
+	Id        string
+	budgetId  string
+	budget    *RateLimiterBudget
+	autoTuner *RateLimitAutoTuner
+
+	mu sync.Mutex
+	// method -> when the rule that rejected a permit for it replenishes
+	saturatedUntil map[string]time.Time
+}
+
+func newUpstreamGroup(logger *zerolog.Logger, cfg *common.UpstreamGroupConfig, rlr *RateLimitersRegistry) (*UpstreamGroup, error) {
+	if cfg.Id == "" {
+		return nil, common.NewErrInvalidConfig("upstream group must have an id")
+	}
+	g := &UpstreamGroup{
+		Id:             cfg.Id,
+		budgetId:       cfg.RateLimitBudget,
+		saturatedUntil: make(map[string]time.Time),
+	}
+	if cfg.RateLimitBudget == "" {
+		return g, nil
+	}
+	if rlr == nil {
+		return nil, common.NewErrInvalidConfig(fmt.Sprintf("upstream group %s has a rateLimitBudget but no rate limiters are configured", cfg.Id))
+	}
+	budget, err := rlr.GetBudget(cfg.RateLimitBudget)
+	if err != nil {
+		return nil, err
+	}
+	g.budget = budget
+
+	if at := cfg.RateLimitAutoTune; at != nil && at.Enabled {
+		dur, err := time.ParseDuration(at.AdjustmentPeriod)
+		if err != nil {
+			return nil, common.NewErrInvalidConfig(fmt.Sprintf("invalid rateLimitAutoTune.adjustmentPeriod for upstream group %s: %v", cfg.Id, err))
+		}
+		g.autoTuner = NewRateLimitAutoTuner(
+			logger,
+			budget,
+			dur,
+			at.ErrorRateThreshold,
+			at.IncreaseFactor,
+			at.DecreaseFactor,
+			at.MinBudget,
+			at.MaxBudget,
+		)
+	}
+
+	return g, nil
+}
+
+// tryAcquirePermit takes a permit from every group rule matching the method, returning the rule that
+// rejected the request (nil when all of them granted a permit).
+func (g *UpstreamGroup) tryAcquirePermit(method string) *RateLimitRule {
+	if g.budget == nil {
+		return nil
+	}
+	for _, rule := range g.budget.GetRulesByMethod(method) {
+		if !rule.Limiter.TryAcquirePermit() {
+			resetIn := rule.ResetIn()
+			if resetIn <= 0 {
+				resetIn = rulePeriod(rule)
+			}
+			g.mu.Lock()
+			g.saturatedUntil[method] = time.Now().Add(resetIn)
+			g.mu.Unlock()
+			return rule
+		}
+		if g.autoTuner != nil {
+			g.autoTuner.RecordPermit(rule)
+		}
+	}
+	return nil
+}
+
+// IsSaturated tells if the group budget recently rejected a request for the method, or has no headroom left.
+func (g *UpstreamGroup) IsSaturated(method string) bool {
+	if g == nil {
+		return false
+	}
+	g.mu.Lock()
+	until, ok := g.saturatedUntil[method]
+	if ok && time.Now().After(until) {
+		delete(g.saturatedUntil, method)
+		ok = false
+	}
+	g.mu.Unlock()
+	if ok {
+		return true
+	}
+	return g.autoTuner != nil && g.autoTuner.Headroom(method) <= 0
+}
+
+func (g *UpstreamGroup) headroom(method string) float64 {
+	if g == nil || g.autoTuner == nil {
+		return 1
+	}
+	return g.autoTuner.Headroom(method)
+}
+
+// deprioritizeSaturatedGroups moves members of groups whose budget is exhausted for the method to the end of
+// the list, keeping the order otherwise. They are still tried last in case other upstreams fail.
+func deprioritizeSaturatedGroups(upsList []*Upstream, method string) []*Upstream {
+	var kept, demoted []*Upstream
+	for i, ups := range upsList {
+		if ups.group.IsSaturated(method) {
+			if kept == nil {
+				kept = make([]*Upstream, i, len(upsList))
+				copy(kept, upsList[:i])
+			}
+			demoted = append(demoted, ups)
+			continue
+		}
+		if kept != nil {
+			kept = append(kept, ups)
+		}
+	}
+	if kept == nil {
+		return upsList
+	}
+	return append(kept, demoted...)
+}
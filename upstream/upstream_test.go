@@ -1,9 +1,17 @@
 package upstream
 
 import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/bytedance/sonic"
 	"github.com/erpc/erpc/common"
+	"github.com/erpc/erpc/health"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -251,3 +259,358 @@ func TestUpstream_SkipLogic(t *testing.T) {
 		assert.ErrorIs(t, reason, common.NewErrUpstreamMethodIgnored("eth_get_block_by_number", "test"))
 	})
 }
+
+func TestUpstream_ConcurrencyPriority(t *testing.T) {
+	t.Run("HighPriorityDispatchedAheadOfLowWhenSaturated", func(t *testing.T) {
+		limiter := NewConcurrencyLimiter("test", &common.ConcurrencyConfig{MaxConcurrent: 1})
+		assert.NoError(t, limiter.Acquire(context.Background(), common.RequestPriorityNormal))
+
+		var mu sync.Mutex
+		order := []string{}
+		var wg sync.WaitGroup
+		enqueue := func(name string, priority common.RequestPriority) {
+			wg.Add(1)
+			queued := limiter.Queued()
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, limiter.Acquire(context.Background(), priority))
+				mu.Lock()
+				order = append(order, name)
+				mu.Unlock()
+				limiter.Release()
+			}()
+			assert.Eventually(t, func() bool { return limiter.Queued() == queued+1 }, time.Second, time.Millisecond)
+		}
+
+		enqueue("low-1", common.RequestPriorityLow)
+		enqueue("low-2", common.RequestPriorityLow)
+		enqueue("normal", common.RequestPriorityNormal)
+		enqueue("high", common.RequestPriorityHigh)
+
+		limiter.Release()
+		wg.Wait()
+
+		assert.Equal(t, []string{"high", "normal", "low-1", "low-2"}, order)
+	})
+
+	t.Run("RejectWhenQueueIsFull", func(t *testing.T) {
+		limiter := NewConcurrencyLimiter("test", &common.ConcurrencyConfig{MaxConcurrent: 1, MaxQueueSize: 1})
+		assert.NoError(t, limiter.Acquire(context.Background(), common.RequestPriorityNormal))
+
+		go func() {
+			_ = limiter.Acquire(context.Background(), common.RequestPriorityLow)
+			limiter.Release()
+		}()
+		assert.Eventually(t, func() bool { return limiter.Queued() == 1 }, time.Second, time.Millisecond)
+
+		err := limiter.Acquire(context.Background(), common.RequestPriorityHigh)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeUpstreamConcurrencyQueueFull))
+
+		limiter.Release()
+	})
+
+	t.Run("CancelledWaiterLeavesQueue", func(t *testing.T) {
+		limiter := NewConcurrencyLimiter("test", &common.ConcurrencyConfig{MaxConcurrent: 1})
+		assert.NoError(t, limiter.Acquire(context.Background(), common.RequestPriorityNormal))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := limiter.Acquire(ctx, common.RequestPriorityHigh)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 0, limiter.Queued())
+
+		limiter.Release()
+		assert.NoError(t, limiter.Acquire(context.Background(), common.RequestPriorityLow))
+		limiter.Release()
+	})
+
+	t.Run("ForwardWaitsForSlotByPriority", func(t *testing.T) {
+		mt := health.NewTracker("prjA", 100*time.Second)
+		client := NewMockHttpJsonRpcClient("evm:123").
+			On("eth_getLogs", &MockHttpJsonRpcResponse{Result: []interface{}{}, Delay: 50 * time.Millisecond}).
+			On("eth_call", &MockHttpJsonRpcResponse{Result: "0x1", Delay: 50 * time.Millisecond})
+		ups := &Upstream{
+			Logger: zerolog.Nop(),
+			Client: client,
+			config: &common.UpstreamConfig{
+				Id:          "test",
+				Type:        common.UpstreamTypeEvm,
+				Concurrency: &common.ConcurrencyConfig{MaxConcurrent: 1},
+			},
+			metricsTracker:     mt,
+			concurrencyLimiter: NewConcurrencyLimiter("test", &common.ConcurrencyConfig{MaxConcurrent: 1}),
+			methodCheckResults: map[string]bool{},
+		}
+
+		var mu sync.Mutex
+		done := []string{}
+		var wg sync.WaitGroup
+		send := func(method string) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":[]}`))
+				_, err := ups.Forward(context.Background(), req)
+				assert.NoError(t, err)
+				mu.Lock()
+				done = append(done, method)
+				mu.Unlock()
+			}()
+		}
+
+		send("eth_getLogs")
+		assert.Eventually(t, func() bool { return client.Calls("eth_getLogs") == 1 }, time.Second, time.Millisecond)
+		send("eth_getLogs")
+		assert.Eventually(t, func() bool { return ups.concurrencyLimiter.Queued() == 1 }, time.Second, time.Millisecond)
+		send("eth_call")
+		assert.Eventually(t, func() bool { return ups.concurrencyLimiter.Queued() == 2 }, time.Second, time.Millisecond)
+		wg.Wait()
+
+		assert.Equal(t, []string{"eth_getLogs", "eth_call", "eth_getLogs"}, done)
+	})
+}
+
+// inFlightClient tracks how many requests are being served at the same time.
+type inFlightClient struct {
+	*MockHttpJsonRpcClient
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (c *inFlightClient) SendRequest(ctx context.Context, req *common.NormalizedRequest) (*common.NormalizedResponse, error) {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		m := c.maxInFlight.Load()
+		if n <= m || c.maxInFlight.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	return c.MockHttpJsonRpcClient.SendRequest(ctx, req)
+}
+
+func TestUpstream_GetLogsSplit(t *testing.T) {
+	newUpstream := func(client HttpJsonRpcClient, maxRange, concurrency int) *Upstream {
+		return &Upstream{
+			Logger: zerolog.Nop(),
+			Client: client,
+			config: &common.UpstreamConfig{
+				Id:   "test",
+				Type: common.UpstreamTypeEvm,
+				Evm: &common.EvmUpstreamConfig{
+					GetLogsSplitEnabled:      true,
+					GetLogsMaxBlockRange:     maxRange,
+					GetLogsSplitConcurrency:  concurrency,
+					GetLogsSplitMaxSubRanges: 1000,
+				},
+			},
+			metricsTracker:     health.NewTracker("prjA", 100*time.Second),
+			methodCheckResults: map[string]bool{},
+		}
+	}
+
+	t.Run("LargeSplitStaysWithinConcurrencyBound", func(t *testing.T) {
+		client := &inFlightClient{
+			MockHttpJsonRpcClient: NewMockHttpJsonRpcClient("evm:123").
+				On("eth_getLogs", &MockHttpJsonRpcResponse{
+					Result: []interface{}{map[string]interface{}{"logIndex": "0x0"}},
+					Delay:  2 * time.Millisecond,
+				}),
+		}
+		ups := newUpstream(client, 1000, 3)
+
+		// 1M blocks over a 1000 blocks max range is a thousand sub-queries
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":9,"method":"eth_getLogs","params":[{"fromBlock":"0x1","toBlock":"0xf4240"}]}`))
+		resp, err := ups.Forward(context.Background(), req)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, 1000, client.Calls("eth_getLogs"))
+		assert.LessOrEqual(t, client.maxInFlight.Load(), int32(3))
+		assert.Equal(t, int32(3), client.maxInFlight.Load(), "bound should be fully used")
+
+		jrr, err := resp.JsonRpcResponse()
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.EqualValues(t, 9, jrr.ID)
+		var logs []interface{}
+		assert.NoError(t, sonic.Unmarshal(jrr.Result, &logs))
+		assert.Len(t, logs, 1000)
+	})
+
+	t.Run("RangeOverMaxSubRangesIsRejected", func(t *testing.T) {
+		client := NewMockHttpJsonRpcClient("evm:123").OnResult("eth_getLogs", []interface{}{})
+		ups := newUpstream(client, 10, 2)
+		ups.config.Evm.GetLogsSplitMaxSubRanges = 5
+
+		// 100 blocks over a 10 blocks max range is 10 sub-queries
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"fromBlock":"0x1","toBlock":"0x64"}]}`))
+		_, err := ups.Forward(context.Background(), req)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeUpstreamRequestSkipped), err)
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeGetLogsTooManySubRanges), err)
+		assert.Equal(t, 0, client.Calls("eth_getLogs"))
+	})
+
+	t.Run("SplitIsDisabledByDefault", func(t *testing.T) {
+		client := NewMockHttpJsonRpcClient("evm:123").OnResult("eth_getLogs", []interface{}{})
+		ups := newUpstream(client, 10, 2)
+		ups.config.Evm.GetLogsSplitEnabled = false
+
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"fromBlock":"0x1","toBlock":"0x64"}]}`))
+		_, err := ups.Forward(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, client.Calls("eth_getLogs"))
+	})
+
+	t.Run("RangeWithinMaxIsNotSplit", func(t *testing.T) {
+		client := NewMockHttpJsonRpcClient("evm:123").OnResult("eth_getLogs", []interface{}{})
+		ups := newUpstream(client, 1000, 3)
+
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"fromBlock":"0x1","toBlock":"0x3e8"}]}`))
+		_, err := ups.Forward(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, client.Calls("eth_getLogs"))
+	})
+
+	t.Run("SubRangeFailureFailsWholeRequest", func(t *testing.T) {
+		client := NewMockHttpJsonRpcClient("evm:123").
+			On("eth_getLogs", &MockHttpJsonRpcResponse{
+				Error: common.NewErrEndpointServerSideException(errors.New("boom"), nil),
+				Delay: 5 * time.Millisecond,
+			})
+		ups := newUpstream(client, 10, 2)
+
+		req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"fromBlock":"0x1","toBlock":"0x64"}]}`))
+		_, err := ups.Forward(context.Background(), req)
+		assert.Error(t, err)
+		assert.Less(t, client.Calls("eth_getLogs"), 10, "remaining sub-queries must be cancelled")
+	})
+
+	t.Run("TimedOutSubRangeIsReportedAsMissingWhenPartialAllowed", func(t *testing.T) {
+		slow := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"address":"0xabc","fromBlock":"0xb","toBlock":"0x14"}]}`))
+		jrq, err := slow.JsonRpcRequest()
+		assert.NoError(t, err)
+		slowHash, err := jrq.CacheHash()
+		assert.NoError(t, err)
+
+		client := NewMockHttpJsonRpcClient("evm:123").
+			OnResult("eth_getLogs", []interface{}{map[string]interface{}{"logIndex": "0x0"}}).
+			OnError(slowHash, common.NewErrEndpointRequestTimeout(time.Second))
+		ups := newUpstream(client, 10, 2)
+
+		newRequest := func() *common.NormalizedRequest {
+			return common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"address":"0xabc","fromBlock":"0x1","toBlock":"0x1e"}]}`))
+		}
+
+		_, err = ups.Forward(context.Background(), newRequest())
+		assert.True(t, common.HasErrorCode(err, common.ErrCodeEndpointRequestTimeout), "without the directive the whole request must fail: %v", err)
+
+		req := newRequest().WithDirectives(&common.RequestDirectives{AllowPartialLogs: true})
+		resp, err := ups.Forward(context.Background(), req)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, resp.IsPartial())
+		assert.Equal(t, []string{"0xb-0x14"}, resp.MissingRanges())
+
+		jrr, err := resp.JsonRpcResponse()
+		if !assert.NoError(t, err) {
+			return
+		}
+		var logs []interface{}
+		assert.NoError(t, sonic.Unmarshal(jrr.Result, &logs))
+		assert.Len(t, logs, 2, "logs of the other sub-ranges are kept")
+	})
+
+	t.Run("SingleBlockWithTooManyLogsIsSplitByAddress", func(t *testing.T) {
+		hashOf := func(filter string) string {
+			jrq, err := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[` + filter + `]}`)).JsonRpcRequest()
+			assert.NoError(t, err)
+			hash, err := jrq.CacheHash()
+			assert.NoError(t, err)
+			return hash
+		}
+		tooLarge := common.NewErrEndpointEvmLargeRange(errors.New("query returned more than 10000 results"))
+		newClient := func(block string) *MockHttpJsonRpcClient {
+			filter := func(addresses string) string {
+				return `{"fromBlock":"` + block + `","toBlock":"` + block + `","address":[` + addresses + `]}`
+			}
+			return NewMockHttpJsonRpcClient("evm:123").
+				OnError(hashOf(filter(`"0xa","0xb","0xc"`)), tooLarge).
+				OnError(hashOf(filter(`"0xb","0xc"`)), tooLarge).
+				OnResult(hashOf(filter(`"0xa"`)), []interface{}{map[string]interface{}{"address": "0xa", "logIndex": "0x1"}}).
+				OnResult(hashOf(filter(`"0xb"`)), []interface{}{
+					map[string]interface{}{"address": "0xb", "logIndex": "0x0"},
+					map[string]interface{}{"address": "0xb", "logIndex": "0x2"},
+				}).
+				OnResult(hashOf(filter(`"0xc"`)), []interface{}{map[string]interface{}{"address": "0xc", "logIndex": "0x3"}})
+		}
+		logIndexes := func(t *testing.T, resp *common.NormalizedResponse) []string {
+			jrr, err := resp.JsonRpcResponse()
+			if !assert.NoError(t, err) {
+				return nil
+			}
+			var logs []map[string]interface{}
+			assert.NoError(t, sonic.Unmarshal(jrr.Result, &logs))
+			var indexes []string
+			for _, l := range logs {
+				indexes = append(indexes, l["address"].(string)+"@"+l["logIndex"].(string))
+			}
+			return indexes
+		}
+
+		t.Run("MergedInLogIndexOrder", func(t *testing.T) {
+			client := newClient("0x5")
+			ups := newUpstream(client, 0, 0)
+
+			req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":7,"method":"eth_getLogs","params":[{"fromBlock":"0x5","toBlock":"0x5","address":["0xa","0xb","0xc"]}]}`))
+			resp, err := ups.Forward(context.Background(), req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			// full query, [a], [b,c] still too large, then [b] and [c]
+			assert.Equal(t, 5, client.Calls("eth_getLogs"))
+			assert.Equal(t, []string{"0xb@0x0", "0xa@0x1", "0xb@0x2", "0xc@0x3"}, logIndexes(t, resp))
+
+			jrr, err := resp.JsonRpcResponse()
+			assert.NoError(t, err)
+			assert.EqualValues(t, 7, jrr.ID)
+		})
+
+		t.Run("AfterRangeSplittingIsExhausted", func(t *testing.T) {
+			client := newClient("0x6").
+				OnResult("eth_getLogs", []interface{}{map[string]interface{}{"address": "0xa", "logIndex": "0x9"}})
+			ups := newUpstream(client, 1, 1)
+
+			req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"fromBlock":"0x5","toBlock":"0x6","address":["0xa","0xb","0xc"]}]}`))
+			resp, err := ups.Forward(context.Background(), req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			// block 0x5 is served at once, block 0x6 is split by address
+			assert.Equal(t, []string{"0xa@0x9", "0xb@0x0", "0xa@0x1", "0xb@0x2", "0xc@0x3"}, logIndexes(t, resp))
+		})
+
+		t.Run("MultiBlockRangeIsNotSplitByAddress", func(t *testing.T) {
+			client := NewMockHttpJsonRpcClient("evm:123").OnError("eth_getLogs", tooLarge)
+			ups := newUpstream(client, 0, 0)
+
+			req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"fromBlock":"0x5","toBlock":"0x6","address":["0xa","0xb"]}]}`))
+			_, err := ups.Forward(context.Background(), req)
+			assert.True(t, common.HasErrorCode(err, common.ErrCodeEndpointEvmLargeRange))
+			assert.Equal(t, 1, client.Calls("eth_getLogs"))
+		})
+
+		t.Run("SingleAddressIsNotSplit", func(t *testing.T) {
+			client := NewMockHttpJsonRpcClient("evm:123").OnError("eth_getLogs", tooLarge)
+			ups := newUpstream(client, 0, 0)
+
+			req := common.NewNormalizedRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"fromBlock":"0x5","toBlock":"0x5","address":["0xa"]}]}`))
+			_, err := ups.Forward(context.Background(), req)
+			assert.True(t, common.HasErrorCode(err, common.ErrCodeEndpointEvmLargeRange))
+			assert.Equal(t, 1, client.Calls("eth_getLogs"))
+		})
+	})
+}